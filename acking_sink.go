@@ -0,0 +1,38 @@
+package go_streams
+
+// bindSinks binds the acking sinks and the operators that stop the source (e.g: Take) to the source of the stream,
+// the sinks that emit events to the stream and the limits of the engine to the sinks and the async operators,
+// and applies the timeout of the stream to its filters, maps and sinks.
+// It returns the handlers the stream should use and whether the processor should leave commits to those sinks.
+func bindSinks(stream Stream) (handlers []interface{}, acking bool) {
+	var limits *governor
+	if managed, ok := stream.(*managedStream); ok {
+		limits = managed.source.governor
+	}
+
+	handlers = make([]interface{}, len(stream.GetHandlers()))
+	for idx, declared := range stream.GetHandlers() {
+		handler := declared
+		if binder, ok := handler.(eventBinder); ok {
+			binder.bindEvents(stream)
+		}
+		if binder, ok := handler.(sourceBinder); ok {
+			binder.bindSource(stream.GetSource())
+		}
+		if sink, ok := handler.(AckingSink); ok {
+			handler = sink.Bind(stream.GetSource().CommitEntry)
+			acking = true
+		}
+		handler = withTimeout(handler, stream.GetTimeout())
+		if limits != nil {
+			if binder, ok := handler.(poolBinder); ok && limits.pool != nil {
+				binder.bindPool(limits.pool)
+			}
+			if sink, ok := handler.(Sink); ok {
+				handler = limits.limitSink(declared, sink)
+			}
+		}
+		handlers[idx] = handler
+	}
+	return handlers, acking
+}
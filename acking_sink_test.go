@@ -0,0 +1,35 @@
+package go_streams
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestProcessors_LeaveCommitsToAckingSinks(t *testing.T) {
+	processors := []Processor{NewDirectProcessor(), NewBufferedProcessor(2, 50*time.Millisecond)}
+	for _, processor := range processors {
+		source := NewSequentialIntegerSource(5, time.Millisecond).(*sequentialIntegerSource)
+		sink := &ackingArraySink{ArraySink: NewArraySink()}
+
+		NewStream(source).Sink(sink).Process(processor, make(ErrorChannel, 10))
+
+		assert.EqualValues(t, 6, len(sink.Array()))
+		assert.Empty(t, source.latestCommit)
+		assert.NotNil(t, sink.commit)
+
+		assert.Nil(t, sink.commit("5"))
+		assert.EqualValues(t, "5", source.latestCommit)
+	}
+}
+
+// ackingArraySink appends entries to the array and leaves commits to the test
+type ackingArraySink struct {
+	*ArraySink
+	commit Committer
+}
+
+func (this *ackingArraySink) Bind(commit Committer) Sink {
+	this.commit = commit
+	return this.ArraySink
+}
@@ -0,0 +1,164 @@
+package go_streams
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// NextFunc returns the next value of an iterator, ok is false once the iterator is exhausted
+type NextFunc func() (value interface{}, ok bool)
+
+// iteratorSource emits the values of an iterator until it is exhausted, then the stream completes.
+// Entries are keyed by their position (starting at 1), the values can't be replayed so commits do nothing.
+type iteratorSource struct {
+	name string
+	next func(stop <-chan struct{}) (interface{}, bool)
+
+	closeCh chan struct{}
+	once    *sync.Once
+}
+
+func newIteratorSource(kind string, next func(stop <-chan struct{}) (interface{}, bool)) *iteratorSource {
+	name := fmt.Sprintf("%sSource-%d", kind, time.Now().UnixNano())
+	return &iteratorSource{name: name, next: next, closeCh: make(chan struct{}), once: &sync.Once{}}
+}
+
+// FromChannel creates a source emitting the values received from a channel (of any element type) until it
+// is closed, it panics when ch isn't a channel that can be received from.
+func FromChannel(ch interface{}) Source {
+	value := reflect.ValueOf(ch)
+	if value.Kind() != reflect.Chan || value.Type().ChanDir()&reflect.RecvDir == 0 {
+		panic(fmt.Sprintf("FromChannel requires a receivable channel, got: %T", ch))
+	}
+	return newIteratorSource("channel", func(stop <-chan struct{}) (interface{}, bool) {
+		chosen, received, ok := reflect.Select([]reflect.SelectCase{
+			{Dir: reflect.SelectRecv, Chan: value},
+			{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(stop)},
+		})
+		if chosen != 0 || !ok {
+			return nil, false
+		}
+		return received.Interface(), true
+	})
+}
+
+// FromSlice creates a source emitting the elements of a slice (or an array), it panics when values isn't one.
+func FromSlice(values interface{}) Source {
+	value := reflect.ValueOf(values)
+	if value.Kind() != reflect.Slice && value.Kind() != reflect.Array {
+		panic(fmt.Sprintf("FromSlice requires a slice, got: %T", values))
+	}
+	idx := 0
+	return newIteratorSource("slice", func(stop <-chan struct{}) (interface{}, bool) {
+		if idx >= value.Len() {
+			return nil, false
+		}
+		idx++
+		return value.Index(idx - 1).Interface(), true
+	})
+}
+
+// FromIterator creates a source emitting the values returned by next until it is exhausted,
+// next is called by a single goroutine.
+func FromIterator(next NextFunc) Source {
+	return newIteratorSource("iterator", func(stop <-chan struct{}) (interface{}, bool) {
+		return next()
+	})
+}
+
+func (this *iteratorSource) Start(channel EntryChannel, errorChannel ErrorChannel) {
+	defer func() {
+		close(channel)
+		errorChannel <- NewEofError(this)
+	}()
+
+	for seq := 1; ; seq++ {
+		select {
+		case <-this.closeCh:
+			return
+		default:
+		}
+		value, ok := this.next(this.closeCh)
+		if !ok {
+			return
+		}
+		select {
+		case <-this.closeCh:
+			return
+		case channel <- Entry{Key: strconv.Itoa(seq), Value: value}:
+		}
+	}
+}
+
+func (this *iteratorSource) Stop() error {
+	this.once.Do(func() { close(this.closeCh) })
+	return nil
+}
+
+func (this *iteratorSource) Ping() error {
+	return nil
+}
+
+func (this *iteratorSource) CommitEntry(keys ...string) error {
+	return nil
+}
+
+func (this *iteratorSource) Name() string {
+	return this.name
+}
+
+// ChannelSink sends the values of the entries to a channel, writes block until the values are received.
+// The channel is closed once the sink is closed (e.g: when its stream completed) so it can be ranged over.
+type ChannelSink struct {
+	ch       reflect.Value
+	elemType reflect.Type
+	once     *sync.Once
+}
+
+// ToChannel creates a sink sending to a channel (of any element type), it panics when ch isn't a channel
+// that can be sent to. Values that can't be assigned to the element type fail the write.
+func ToChannel(ch interface{}) *ChannelSink {
+	value := reflect.ValueOf(ch)
+	if value.Kind() != reflect.Chan || value.Type().ChanDir()&reflect.SendDir == 0 {
+		panic(fmt.Sprintf("ToChannel requires a sendable channel, got: %T", ch))
+	}
+	return &ChannelSink{ch: value, elemType: value.Type().Elem(), once: &sync.Once{}}
+}
+
+func (this *ChannelSink) Single(entry Entry) error {
+	value := reflect.ValueOf(entry.Value)
+	if !value.IsValid() {
+		switch this.elemType.Kind() {
+		case reflect.Interface, reflect.Ptr, reflect.Map, reflect.Slice, reflect.Chan, reflect.Func:
+			value = reflect.Zero(this.elemType)
+		default:
+			return fmt.Errorf("nil value of entry '%s' can't be sent to a channel of %s", entry.Key, this.elemType)
+		}
+	}
+	if !value.Type().AssignableTo(this.elemType) {
+		return fmt.Errorf("value of entry '%s' (%s) can't be sent to a channel of %s", entry.Key, value.Type(), this.elemType)
+	}
+	this.ch.Send(value)
+	return nil
+}
+
+func (this *ChannelSink) Batch(entry ...Entry) error {
+	errs := NewSinkBatchError()
+	for idx := range entry {
+		errs.Add(entry[idx].Key, this.Single(entry[idx]))
+	}
+	return errs.AsError()
+}
+
+func (this *ChannelSink) Ping() error {
+	return nil
+}
+
+// Close closes the channel
+func (this *ChannelSink) Close() error {
+	this.once.Do(func() { this.ch.Close() })
+	return nil
+}
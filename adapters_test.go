@@ -0,0 +1,82 @@
+package go_streams
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFromSlice(t *testing.T) {
+	sink := NewArraySink()
+	NewStream(FromSlice([]string{"a", "b", "c"})).Sink(sink).Process(NewDirectProcessor(), make(ErrorChannel, 10))
+	assert.EqualValues(t, []interface{}{"a", "b", "c"}, sink.Array())
+}
+
+func TestFromIterator(t *testing.T) {
+	n := 0
+	source := FromIterator(func() (interface{}, bool) {
+		n++
+		return n * 10, n <= 3
+	})
+	channel := make(EntryChannel, 10)
+	source.Start(channel, make(ErrorChannel, 10))
+
+	var keys []string
+	var values []interface{}
+	for entry := range channel {
+		keys = append(keys, entry.Key)
+		values = append(values, entry.Value)
+	}
+	assert.EqualValues(t, []string{"1", "2", "3"}, keys)
+	assert.EqualValues(t, []interface{}{10, 20, 30}, values)
+}
+
+func TestFromChannel_ToChannel(t *testing.T) {
+	in := make(chan int)
+	out := make(chan int, 10)
+	engine := NewEngine(NewDirectProcessorFactory(), 10*time.Second)
+	assert.Nil(t, engine.Add(NewStream(FromChannel((<-chan int)(in))).
+		Map(func(entry interface{}) interface{} { return entry.(int) * 2 }).
+		Sink(ToChannel(out))))
+
+	go func() {
+		for i := 1; i <= 3; i++ {
+			in <- i
+		}
+		close(in)
+	}()
+	engine.Start()
+
+	// the channel is closed once the stream completed
+	var received []int
+	for value := range out {
+		received = append(received, value)
+	}
+	assert.EqualValues(t, []int{2, 4, 6}, received)
+}
+
+func TestFromChannel_Stop(t *testing.T) {
+	source := FromChannel(make(chan string))
+	channel := make(EntryChannel)
+	done := make(chan bool)
+	go func() {
+		source.Start(channel, make(ErrorChannel, 1))
+		done <- true
+	}()
+	assert.Nil(t, source.Stop())
+	<-done
+	_, open := <-channel
+	assert.False(t, open)
+}
+
+func TestToChannel_Errors(t *testing.T) {
+	sink := ToChannel(make(chan int, 1))
+	assert.NotNil(t, sink.Single(Entry{Key: "1", Value: "a"}))
+	assert.NotNil(t, sink.Single(Entry{Key: "2"}))
+	assert.IsType(t, &SinkBatchError{}, sink.Batch(Entry{Key: "3", Value: 3}, Entry{Key: "4", Value: "b"}))
+
+	assert.Panics(t, func() { ToChannel(make(<-chan int)) })
+	assert.Panics(t, func() { FromChannel(1) })
+	assert.Panics(t, func() { FromSlice("a") })
+}
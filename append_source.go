@@ -2,6 +2,7 @@ package go_streams
 
 import (
 	"fmt"
+	"sync/atomic"
 	"time"
 )
 
@@ -10,6 +11,7 @@ const appendSourceName = "appendSource"
 type AppendSource struct {
 	name         string
 	latestCommit string
+	emitted      uint64
 
 	appendCh   EntryChannel
 	closeCh    chan bool
@@ -41,6 +43,7 @@ Loop:
 				break Loop
 			}
 			channel <- elem
+			atomic.AddUint64(&this.emitted, 1)
 		}
 	}
 	errorChannel <- NewEofError(this)
@@ -74,6 +77,12 @@ func (this *AppendSource) Ping() error {
 	return nil
 }
 
+// Progress returns the entries emitted and the entries appended, the lag is the depth of the append buffer
+func (this *AppendSource) Progress() (Progress, error) {
+	emitted := atomic.LoadUint64(&this.emitted)
+	return Progress{Position: emitted, End: emitted + uint64(len(this.appendCh)), Unit: "entries"}, nil
+}
+
 func (this *AppendSource) LatestCommit() string {
 	return this.latestCommit
 }
@@ -1,12 +1,26 @@
 package go_streams
 
+import (
+	"context"
+	"time"
+)
+
 type baseStream struct {
-	source Source
-	ops    []interface{}
+	source         Source
+	ops            []interface{}
+	errorHandler   ErrorHandler
+	restartPolicy  *RestartPolicy
+	bufferConfig   *BufferConfig
+	commitStrategy *CommitStrategy
+	deadLetter     Sink
+	priority       int
+	maxInFlight    int
+	timeout        time.Duration
+	sideOutputs    *sideOutputs
 }
 
 func NewStream(source Source) *baseStream {
-	return &baseStream{source: source}
+	return &baseStream{source: source, sideOutputs: newSideOutputs()}
 }
 
 func (this *baseStream) Filter(fn FilterFunc) Stream {
@@ -19,11 +33,129 @@ func (this *baseStream) Map(fn MapFunc) Stream {
 	return this
 }
 
+func (this *baseStream) MapBytes(fn BytesMapFunc) Stream {
+	return this.Map(bytesMap(fn))
+}
+
+func (this *baseStream) MapAsync(fn MapErrFunc, concurrency int, preserveOrder bool) Stream {
+	return this.Via(NewMapAsync(fn, concurrency, preserveOrder))
+}
+
+func (this *baseStream) MapOutputs(fn SideOutputFunc) Stream {
+	return this.Via(&sideOutputStage{fn: fn, outputs: this.sideOutputs})
+}
+
+func (this *baseStream) SideOutput(name string, sink Sink) Stream {
+	this.sideOutputs.set(name, sink)
+	return this
+}
+
+func (this *baseStream) Validate(schema Schema, output string) Stream {
+	return this.Via(&validateStage{schema: schema, output: output, stream: this})
+}
+
+func (this *baseStream) Scan(init interface{}, fn ScanFunc) Stream {
+	return this.Via(NewScan(init, fn))
+}
+
+func (this *baseStream) Via(operator Operator) Stream {
+	this.ops = append(this.ops, operator)
+	return this
+}
+
+func (this *baseStream) Throttle(n int, per time.Duration) Stream {
+	return this.Via(NewThrottle(n, per, nil))
+}
+
+func (this *baseStream) Debounce(d time.Duration) Stream {
+	return this.Via(NewDebounce(d, nil))
+}
+
+func (this *baseStream) Reorder(maxDelay time.Duration, byTimestamp TimestampFunc) Stream {
+	return this.Via(NewReorder(maxDelay, byTimestamp))
+}
+
+func (this *baseStream) Dedupe(key KeyFunc, ttl time.Duration) Stream {
+	return this.Via(NewDedupe(key, ttl, NewMemoryStateStore(defaultDedupeKeys)))
+}
+
+func (this *baseStream) Enrich(loader Loader, config EnrichConfig) Stream {
+	return this.Via(NewEnricher(loader, config))
+}
+
+func (this *baseStream) Sample(rate float64) Stream {
+	return this.Via(NewSample(rate, nil))
+}
+
+func (this *baseStream) SampleEvery(n int) Stream {
+	return this.Via(NewSampleEvery(n))
+}
+
+func (this *baseStream) Take(n int) Stream {
+	return this.Via(NewTake(n))
+}
+
+func (this *baseStream) Skip(n int) Stream {
+	return this.Via(NewSkip(n))
+}
+
+func (this *baseStream) TakeWhile(fn FilterFunc) Stream {
+	return this.Via(NewTakeWhile(fn))
+}
+
+func (this *baseStream) TakeUntil(ctx context.Context) Stream {
+	return this.Via(NewTakeUntil(ctx))
+}
+
+func (this *baseStream) TakeFor(d time.Duration) Stream {
+	return this.Via(NewTakeFor(d))
+}
+
 func (this *baseStream) Sink(sink Sink) Stream {
 	this.ops = append(this.ops, sink)
 	return this
 }
 
+func (this *baseStream) OnError(handler ErrorHandler) Stream {
+	this.errorHandler = handler
+	return this
+}
+
+func (this *baseStream) Supervise(policy RestartPolicy) Stream {
+	this.restartPolicy = &policy
+	return this
+}
+
+func (this *baseStream) CommitWith(strategy CommitStrategy) Stream {
+	this.commitStrategy = &strategy
+	return this
+}
+
+func (this *baseStream) Buffer(config BufferConfig) Stream {
+	this.bufferConfig = &config
+	return this
+}
+
+func (this *baseStream) Priority(weight int) Stream {
+	this.priority = weight
+	return this
+}
+
+func (this *baseStream) MaxInFlight(n int) Stream {
+	this.maxInFlight = n
+	return this
+}
+
+func (this *baseStream) WithTimeout(d time.Duration) Stream {
+	this.timeout = d
+	return this
+}
+
+func (this *baseStream) DeadLetter(sink Sink) Stream {
+	this.deadLetter = sink
+	return this
+}
+
 func (this *baseStream) GetHandlers() []interface{} {
 	return this.ops
 }
@@ -35,3 +167,55 @@ func (this *baseStream) Process(processor Processor, errs ErrorChannel) {
 func (this *baseStream) GetSource() Source {
 	return this.source
 }
+
+func (this *baseStream) GetErrorHandler() ErrorHandler {
+	return this.errorHandler
+}
+
+func (this *baseStream) GetRestartPolicy() *RestartPolicy {
+	return this.restartPolicy
+}
+
+func (this *baseStream) GetCommitStrategy() *CommitStrategy {
+	return this.commitStrategy
+}
+
+func (this *baseStream) GetBufferConfig() *BufferConfig {
+	return this.bufferConfig
+}
+
+func (this *baseStream) GetPriority() int {
+	return this.priority
+}
+
+func (this *baseStream) GetMaxInFlight() int {
+	return this.maxInFlight
+}
+
+func (this *baseStream) GetTimeout() time.Duration {
+	return this.timeout
+}
+
+func (this *baseStream) GetDeadLetter() Sink {
+	return this.deadLetter
+}
+
+func (this *baseStream) GetSideOutputs() map[string]Sink {
+	return this.sideOutputs.all()
+}
+
+// Pause pauses the source when it implements Pausable
+func (this *baseStream) Pause() error {
+	if source, ok := this.source.(Pausable); ok {
+		return source.Pause()
+	}
+	return nil
+}
+
+// Resume resumes the source when it implements Pausable
+func (this *baseStream) Resume() error {
+	if source, ok := this.source.(Pausable); ok {
+		return source.Resume()
+	}
+	return nil
+}
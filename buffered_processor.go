@@ -6,20 +6,26 @@ import (
 )
 
 type bufferedProcessor struct {
-	entryCh    EntryChannel
-	size       int
-	timeout    time.Duration
-	buffer     []Entry
-	bufferKeys []string
+	entryCh   EntryChannel
+	size      int
+	timeout   time.Duration
+	buffer    []Entry
+	committer *committer
+	slot      *scheduledStream
+
+	// the batches passed between the stages are reused by the next batches (see scratch)
+	streamLogger Logger
+	sinkBatch    []Entry
+	sinkKeys     []string
+	outputs      [][]Entry
 }
 
 func NewBufferedProcessor(size int, timeout time.Duration) *bufferedProcessor {
 	return &bufferedProcessor{
-		timeout:    timeout,
-		size:       size,
-		entryCh:    make(EntryChannel, size),
-		buffer:     make([]Entry, size),
-		bufferKeys: make([]string, size),
+		timeout: timeout,
+		size:    size,
+		entryCh: make(EntryChannel, size),
+		buffer:  make([]Entry, size),
 	}
 }
 
@@ -30,43 +36,81 @@ func NewBufferedProcessorFactory(size int, timeout time.Duration) ProcessorFacto
 }
 
 func (this *bufferedProcessor) Process(stream Stream, errs ErrorChannel) {
-	logger.Info("Starting to process stream with buffered processor (flushing buffer on: %d entries or %d seconds)", this.size, this.timeout.Seconds())
-	handlers := stream.GetHandlers()
+	this.streamLogger = streamLog(stream)
+	this.streamLogger.Info("Starting to process stream with buffered processor (flushing buffer on: %d entries or %d seconds)", this.size, this.timeout.Seconds())
+	handlers, acking := bindSinks(stream)
+	reporter := newErrorReporter(stream, errs)
+	this.committer = newCommitter(stream, reporter)
+	this.slot = scheduled(stream)
+	this.sinkBatch = make([]Entry, 0, this.size)
+	this.sinkKeys = make([]string, 0, this.size)
+	this.outputs = make([][]Entry, len(handlers))
+	inFlight := inFlightOf(stream)
 	bufferIdx := 0
-	go stream.GetSource().Start(this.entryCh, errs)
+	reporter.startSource(stream.GetSource(), this.entryCh)
 	timeoutCh := time.Tick(this.timeout)
+	ticks, stopTicks := tickChannel(handlers)
+	defer stopTicks()
 
 Loop:
 	for {
 		if bufferIdx == this.size {
-			this.processBuffer(stream.GetSource(), this.buffer, this.bufferKeys, handlers, errs)
+			this.processBuffer(stream, this.buffer, handlers, acking, reporter)
+			inFlight.release(bufferIdx)
 			bufferIdx = 0
 		}
 		select {
 		case <-timeoutCh:
-			this.processBuffer(stream.GetSource(), this.buffer[0:bufferIdx], this.bufferKeys[0:bufferIdx], handlers, errs)
+			this.processBuffer(stream, this.buffer[0:bufferIdx], handlers, acking, reporter)
+			inFlight.release(bufferIdx)
 			bufferIdx = 0
 
+		case now := <-ticks:
+			this.tick(stream, handlers, acking, reporter, func(op TimedOperator) ([]Entry, error) { return op.Tick(now) })
+
 		case entry, ok := <-this.entryCh:
 			if !ok {
 				break Loop
 			}
 			this.buffer[bufferIdx] = entry
-			this.bufferKeys[bufferIdx] = entry.Key
+			if !acking {
+				this.committer.track(entry.Key)
+			}
 			bufferIdx++
 		}
 	}
-	this.processBuffer(stream.GetSource(), this.buffer[0:bufferIdx], this.bufferKeys[0:bufferIdx], handlers, errs)
+	this.processBuffer(stream, this.buffer[0:bufferIdx], handlers, acking, reporter)
+	inFlight.release(bufferIdx)
 	bufferIdx = 0
-	logger.Info("Done processing stream with buffered processor")
+	this.tick(stream, handlers, acking, reporter, TimedOperator.Drain)
+	flushSinks(handlers, reporter)
+	this.committer.close()
+	reporter.wait()
+	this.streamLogger.Info("Done processing stream with buffered processor")
+}
+
+// tick passes the entries emitted by the timed operators to the handlers that come after them
+func (this *bufferedProcessor) tick(stream Stream, handlers []interface{}, acking bool, reporter *errorReporter, emit func(TimedOperator) ([]Entry, error)) {
+	for idx := range handlers {
+		if op, ok := handlers[idx].(TimedOperator); ok {
+			entries, err := emit(op)
+			reporter.report(OperatorStage, "", err)
+			this.processBuffer(stream, entries, handlers[idx+1:], acking, reporter)
+		}
+	}
 }
 
-func (this *bufferedProcessor) processBuffer(source Source, entries []Entry, keys []string, handlers []interface{}, errs ErrorChannel) {
+// processBuffer passes the entries through the handlers, each sink commits the keys of the entries it wrote
+// (the entries held by timed operators are committed once they are emitted and written)
+func (this *bufferedProcessor) processBuffer(stream Stream, entries []Entry, handlers []interface{}, acking bool, reporter *errorReporter) {
 	if len(entries) == 0 {
 		return
 	}
+	this.slot.acquire()
+	defer this.slot.release()
 
-	logger.Debug("Processing batch on %d entries", len(entries))
+	this.streamLogger.Debug("Processing batch on %d entries", len(entries))
+	count := len(entries)
 	filteredCount := 0
 	for idx := range handlers {
 		switch handler := handlers[idx].(type) {
@@ -75,9 +119,13 @@ func (this *bufferedProcessor) processBuffer(source Source, entries []Entry, key
 				if entries[idx].Filtered {
 					continue
 				}
-				if !RecoverFilter(handler, entries[idx], errs) {
+				keep, err := recoverFilter(handler, entries[idx])
+				reporter.report(FilterStage, entries[idx].Key, err)
+				dead := deadLetter(stream, FilterStage, err, reporter, entries[idx])
+				if !keep || dead {
 					entries[idx].Filtered = true
 					filteredCount++
+					this.drop(entries[idx], dead, acking, reporter)
 				}
 			}
 
@@ -86,32 +134,101 @@ func (this *bufferedProcessor) processBuffer(source Source, entries []Entry, key
 				if entries[idx].Filtered {
 					continue
 				}
-				entries[idx].Value = RecoverMap(handler, entries[idx], errs)
+				value, err := recoverMap(handler, entries[idx])
+				reporter.report(MapStage, entries[idx].Key, err)
+				if deadLetter(stream, MapStage, err, reporter, entries[idx]) {
+					entries[idx].Filtered = true
+					filteredCount++
+					this.drop(entries[idx], true, acking, reporter)
+					continue
+				}
+				entries[idx].Value = value
+			}
+
+		case Operator:
+			// the timed operators pass their entries to the tail of the handlers
+			at := len(this.outputs) - len(handlers) + idx
+			out := this.scratch(at, len(entries))
+			_, timed := handler.(TimedOperator)
+			for idx := range entries {
+				if entries[idx].Filtered {
+					continue
+				}
+				emitted, err := recoverOperator(handler, entries[idx])
+				reporter.report(OperatorStage, entries[idx].Key, err)
+				if dead := deadLetter(stream, OperatorStage, err, reporter, entries[idx]); dead || (!timed && len(emitted) == 0) {
+					this.drop(entries[idx], dead, acking, reporter)
+				}
+				out = append(out, emitted...)
 			}
+			this.outputs[at] = out
+			entries, filteredCount = out, 0
 
 		case Sink:
-			arr := make([]Entry, len(entries)-filteredCount)
-			arrIdx := 0
+			arr := this.sinkBatch[:0]
 			for idx := range entries {
 				if !entries[idx].Filtered {
-					arr[arrIdx] = entries[idx]
-					arrIdx++
+					arr = append(arr, entries[idx])
 				}
 			}
+			this.sinkBatch = arr
 			if len(arr) > 0 {
-				if err := RecoverSinkBatch(handler, arr, errs); err != nil {
-					errs <- err
-				} else {
-					if err := source.CommitEntry(keys...); err != nil {
-						errs <- err
+				_, err := recoverSinkBatch(handler, arr)
+				reporter.report(SinkStage, "", err)
+				if err == nil {
+					markSinked(stream)
+				}
+				if err != nil && deadLetter(stream, SinkStage, err, reporter, arr...) {
+					// the dead-lettered entries are skipped by the handlers that come after the sink
+					for idx := range entries {
+						entries[idx].Filtered = true
 					}
+					filteredCount = len(entries)
+					err = nil
+				}
+				if err == nil && !acking {
+					reporter.report(CommitStage, "", this.committer.complete(this.keysOf(arr)...))
 				}
 			}
 
 		default:
-			_ = source.Stop()
+			_ = stream.GetSource().Stop()
 			log.Fatalf("unknown handler type: %+v", handler)
 		}
 	}
-	logger.Debug("Done processing batch of %d entries. %d entries was filtered out", len(entries), filteredCount)
+	this.streamLogger.Debug("Done processing batch of %d entries. %d entries was filtered out", len(entries), filteredCount)
+	emitEvent(stream, Event{Type: BatchFlushed, Count: count})
+}
+
+// drop completes the key of an entry that won't reach the sinks, a dead-lettered entry is committed
+// the same way a written entry is and a filtered entry is skipped (see committer.skip)
+func (this *bufferedProcessor) drop(entry Entry, deadLettered bool, acking bool, reporter *errorReporter) {
+	if deadLettered && !acking {
+		reporter.report(CommitStage, entry.Key, this.committer.complete(entry.Key))
+		return
+	}
+	this.committer.skip(entry.Key)
+}
+
+// keysOf returns the keys of the entries, the keys are reused by the next batches
+func (this *bufferedProcessor) keysOf(entries []Entry) []string {
+	keys := this.sinkKeys[:0]
+	for idx := range entries {
+		keys = append(keys, entries[idx].Key)
+	}
+	this.sinkKeys = keys
+	return keys
+}
+
+// scratch returns the emptied output batch of the operator at the index of the handlers, the batches
+// handed to the stages are only valid until the stages return as they are reused by the next batches.
+func (this *bufferedProcessor) scratch(at int, size int) []Entry {
+	out := this.outputs[at]
+	for idx := range out {
+		out[idx] = Entry{}
+	}
+	if cap(out) < size {
+		return make([]Entry, 0, size)
+	}
+	return out[:0]
 }
@@ -1,7 +1,9 @@
 package go_streams
 
 import (
+	"errors"
 	"github.com/stretchr/testify/assert"
+	"sync"
 	"testing"
 	"time"
 )
@@ -39,8 +41,8 @@ func TestBufferedProcessor_Process_FilterPanics(t *testing.T) {
 
 	err := <-errs
 	assert.NotNil(t, err)
-	_, ok := err.(*FilterError)
-	assert.True(t, ok)
+	var filterErr *FilterError
+	assert.True(t, errors.As(err, &filterErr))
 }
 
 func TestBufferedProcessor_Process_MapPanics(t *testing.T) {
@@ -52,6 +54,67 @@ func TestBufferedProcessor_Process_MapPanics(t *testing.T) {
 
 	err := <-errs
 	assert.NotNil(t, err)
-	_, ok := err.(*MapError)
-	assert.True(t, ok)
+	var mapErr *MapError
+	assert.True(t, errors.As(err, &mapErr))
+}
+
+func TestBufferedProcessor_Process_DeadLettersSinkPanics(t *testing.T) {
+	errs := make(ErrorChannel, 100)
+	source := NewSequentialIntegerSource(3, 1*time.Millisecond)
+	var letters []Entry
+	stream := NewStream(source).Sink(&panicSink{}).DeadLetter(NewCallbackSink(func(entries ...Entry) error {
+		letters = append(letters, entries...)
+		return nil
+	}))
+	stream.Process(NewBufferedProcessor(10, 1*time.Second), errs)
+
+	assert.NotEmpty(t, letters)
+	assert.EqualValues(t, 0, letters[0].Value)
+	assert.EqualValues(t, SinkStage, letters[0].Metadata[MetadataDeadLetterStage])
+	assert.EqualValues(t, "batch error", letters[0].Metadata[MetadataDeadLetterError])
+}
+
+// writtenKeysSource records the keys committed before their entries were written
+type writtenKeysSource struct {
+	Source
+	mutex     *sync.Mutex
+	written   map[string]bool
+	committed []string
+	early     []string
+}
+
+func (this *writtenKeysSource) CommitEntry(keys ...string) error {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	for _, key := range keys {
+		if !this.written[key] {
+			this.early = append(this.early, key)
+		}
+		this.committed = append(this.committed, key)
+	}
+	return nil
+}
+
+func (this *writtenKeysSource) write(entries ...Entry) error {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	for _, entry := range entries {
+		this.written[entry.Key] = true
+	}
+	return nil
+}
+
+func TestBufferedProcessor_Process_CommitsWrittenEntries(t *testing.T) {
+	source := &writtenKeysSource{Source: NewSequentialIntegerSource(9, 0), mutex: &sync.Mutex{}, written: make(map[string]bool)}
+	stream := NewStream(source).MapAsync(func(entry interface{}) (interface{}, error) {
+		// the last entries are still held by MapAsync when the batch reaches the sink
+		if entry.(int) >= 6 {
+			time.Sleep(20 * time.Millisecond)
+		}
+		return entry, nil
+	}, 4, true).Sink(NewCallbackSink(source.write))
+	stream.Process(NewBufferedProcessor(10, time.Second), make(ErrorChannel, 100))
+
+	assert.Empty(t, source.early)
+	assert.ElementsMatch(t, []string{"0", "1", "2", "3", "4", "5", "6", "7", "8", "9"}, source.committed)
 }
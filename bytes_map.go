@@ -0,0 +1,24 @@
+package go_streams
+
+// bytesMap adapts a BytesMapFunc to a MapFunc, the value keeps its interface when the
+// function returned the slice it was given (e.g: transformed in place) so it isn't boxed again.
+func bytesMap(fn BytesMapFunc) MapFunc {
+	return func(value interface{}) interface{} {
+		in, ok := value.([]byte)
+		if !ok {
+			return value
+		}
+		out := fn(in)
+		if sameBytes(in, out) {
+			return value
+		}
+		return out
+	}
+}
+
+func sameBytes(a, b []byte) bool {
+	if len(a) != len(b) || cap(a) != cap(b) {
+		return false
+	}
+	return len(a) == 0 || &a[0] == &b[0]
+}
@@ -0,0 +1,42 @@
+package go_streams
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStream_MapBytes(t *testing.T) {
+	source := newBenchSource(0)
+	source.entries = []Entry{
+		{Key: "1", Value: []byte("in place")},
+		{Key: "2", Value: []byte("copied")},
+		{Key: "3", Value: 3},
+	}
+	sink := NewArraySink()
+
+	stream := NewStream(source).MapBytes(func(value []byte) []byte {
+		if bytes.HasPrefix(value, []byte("copied")) {
+			return append([]byte("new "), value...)
+		}
+		copy(value, bytes.ToUpper(value))
+		return value
+	}).Sink(sink)
+	stream.Process(NewDirectProcessor(), make(ErrorChannel, 10))
+
+	assert.EqualValues(t, []interface{}{[]byte("IN PLACE"), []byte("new copied"), 3}, sink.array)
+}
+
+func TestBytesMap_KeepsTheValueOfInPlaceTransforms(t *testing.T) {
+	value := interface{}([]byte("abc"))
+	fn := bytesMap(func(value []byte) []byte {
+		value[0] = 'A'
+		return value
+	})
+	allocs := testing.AllocsPerRun(100, func() {
+		value = fn(value)
+	})
+	assert.EqualValues(t, 0, allocs)
+	assert.EqualValues(t, []byte("Abc"), value)
+}
@@ -0,0 +1,167 @@
+package go_streams
+
+import (
+	"fmt"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const defaultChaosMaxDelay = 100 * time.Millisecond
+
+// ChaosConfig configures the faults injected by a Chaos, the probabilities are between 0 and 1
+type ChaosConfig struct {
+	// Seed of the random faults, streams fed with the same entries in the same order get the same faults (0 uses the time)
+	Seed int64
+
+	// DelayProbability delays the entries of the source by a random duration up to MaxDelay (defaults to 100ms)
+	DelayProbability float64
+	MaxDelay         time.Duration
+
+	// DuplicateProbability delivers the entries of the source twice, like a redelivery of an at-least-once source
+	DuplicateProbability float64
+
+	// DropCommitProbability drops the commits of the source: they succeed but never reach it,
+	// so the entries are delivered again once the stream restarts
+	DropCommitProbability float64
+
+	// SinkFailureProbability fails the writes of the sink with one of SinkErrors (defaults to a ChaosError)
+	SinkFailureProbability float64
+	SinkErrors             []error
+
+	// SinkPanicProbability makes the writes of the sink panic with a ChaosError
+	SinkPanicProbability float64
+}
+
+// ChaosStats counts the faults injected by a Chaos
+type ChaosStats struct {
+	Delayed        uint64 `json:"delayed"`
+	Duplicated     uint64 `json:"duplicated"`
+	DroppedCommits uint64 `json:"droppedCommits"`
+	SinkFailures   uint64 `json:"sinkFailures"`
+	SinkPanics     uint64 `json:"sinkPanics"`
+}
+
+// Chaos injects faults in sources and sinks through middlewares (see WrapSource and WrapSink), e.g: to verify
+// in tests or staging that a pipeline is idempotent and doesn't lose entries when they are redelivered.
+type Chaos struct {
+	config ChaosConfig
+	mutex  *sync.Mutex
+	random *rand.Rand
+	stats  ChaosStats
+}
+
+func NewChaos(config ChaosConfig) (*Chaos, error) {
+	probabilities := map[string]float64{
+		"delay":        config.DelayProbability,
+		"duplicate":    config.DuplicateProbability,
+		"drop commit":  config.DropCommitProbability,
+		"sink failure": config.SinkFailureProbability,
+		"sink panic":   config.SinkPanicProbability,
+	}
+	for name, probability := range probabilities {
+		if probability < 0 || probability > 1 {
+			return nil, fmt.Errorf("the %s probability should be between 0 and 1", name)
+		}
+	}
+	if config.Seed == 0 {
+		config.Seed = time.Now().UnixNano()
+	}
+	if config.MaxDelay <= 0 {
+		config.MaxDelay = defaultChaosMaxDelay
+	}
+	if len(config.SinkErrors) == 0 {
+		config.SinkErrors = []error{NewChaosError("sink failure")}
+	}
+	return &Chaos{config: config, mutex: &sync.Mutex{}, random: rand.New(rand.NewSource(config.Seed))}, nil
+}
+
+// Source returns a middleware that delays and duplicates the entries of the source and drops its commits
+func (this *Chaos) Source() SourceMiddleware {
+	return func(next Source) Source {
+		return &interceptedSource{
+			next: next,
+			intercept: func(entry Entry) ([]Entry, error) {
+				if this.roll(this.config.DelayProbability) {
+					atomic.AddUint64(&this.stats.Delayed, 1)
+					time.Sleep(this.delay())
+				}
+				if this.roll(this.config.DuplicateProbability) {
+					atomic.AddUint64(&this.stats.Duplicated, 1)
+					return []Entry{entry, entry}, nil
+				}
+				return []Entry{entry}, nil
+			},
+			commit: func(keys ...string) error {
+				if this.roll(this.config.DropCommitProbability) {
+					atomic.AddUint64(&this.stats.DroppedCommits, 1)
+					return nil
+				}
+				return next.CommitEntry(keys...)
+			},
+		}
+	}
+}
+
+// Sink returns a middleware that fails the writes of the sink or makes them panic
+func (this *Chaos) Sink() SinkMiddleware {
+	return func(next Sink) Sink {
+		return &sinkFuncs{
+			next: next,
+			single: func(entry Entry) error {
+				if err := this.fault(); err != nil {
+					return err
+				}
+				return next.Single(entry)
+			},
+			batch: func(entry ...Entry) error {
+				if err := this.fault(); err != nil {
+					return err
+				}
+				return next.Batch(entry...)
+			},
+		}
+	}
+}
+
+// Stats returns the number of faults injected so far
+func (this *Chaos) Stats() ChaosStats {
+	return ChaosStats{
+		Delayed:        atomic.LoadUint64(&this.stats.Delayed),
+		Duplicated:     atomic.LoadUint64(&this.stats.Duplicated),
+		DroppedCommits: atomic.LoadUint64(&this.stats.DroppedCommits),
+		SinkFailures:   atomic.LoadUint64(&this.stats.SinkFailures),
+		SinkPanics:     atomic.LoadUint64(&this.stats.SinkPanics),
+	}
+}
+
+// fault returns the failure injected in a write (if any), it panics when the write should panic
+func (this *Chaos) fault() error {
+	if this.roll(this.config.SinkPanicProbability) {
+		atomic.AddUint64(&this.stats.SinkPanics, 1)
+		panic(NewChaosError("sink panic"))
+	}
+	if !this.roll(this.config.SinkFailureProbability) {
+		return nil
+	}
+	atomic.AddUint64(&this.stats.SinkFailures, 1)
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	return this.config.SinkErrors[this.random.Intn(len(this.config.SinkErrors))]
+}
+
+func (this *Chaos) roll(probability float64) bool {
+	if probability <= 0 {
+		return false
+	}
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	return this.random.Float64() < probability
+}
+
+func (this *Chaos) delay() time.Duration {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	return time.Duration(this.random.Int63n(int64(this.config.MaxDelay)) + 1)
+}
@@ -0,0 +1,70 @@
+package go_streams
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChaos_DuplicatesEntriesAndDropsCommits(t *testing.T) {
+	chaos, err := NewChaos(ChaosConfig{DuplicateProbability: 1, DropCommitProbability: 1, DelayProbability: 1, MaxDelay: time.Millisecond})
+	assert.Nil(t, err)
+	source := NewSequentialIntegerSource(2, 0).(*sequentialIntegerSource)
+	sink := NewArraySink()
+
+	NewStream(WrapSource(source, chaos.Source())).Sink(sink).Process(NewDirectProcessor(), make(ErrorChannel, 10))
+
+	assert.EqualValues(t, []interface{}{0, 0, 1, 1, 2, 2}, sink.Array())
+	assert.Empty(t, source.latestCommit)
+	assert.EqualValues(t, ChaosStats{Delayed: 3, Duplicated: 3, DroppedCommits: 6}, chaos.Stats())
+}
+
+func TestChaos_FailsSinks(t *testing.T) {
+	timeout := fmt.Errorf("i/o timeout")
+	chaos, err := NewChaos(ChaosConfig{SinkFailureProbability: 1, SinkErrors: []error{timeout}})
+	assert.Nil(t, err)
+	sink := NewArraySink()
+	errs := make(ErrorChannel, 10)
+
+	NewStream(NewSequentialIntegerSource(2, 0)).Sink(WrapSink(sink, chaos.Sink())).Process(NewDirectProcessor(), errs)
+
+	assert.Empty(t, sink.Array())
+	assert.EqualValues(t, 3, chaos.Stats().SinkFailures)
+	assert.True(t, errors.Is(<-errs, timeout))
+}
+
+func TestChaos_SinkPanicsAreDeadLettered(t *testing.T) {
+	chaos, err := NewChaos(ChaosConfig{SinkPanicProbability: 1})
+	assert.Nil(t, err)
+	var letters []Entry
+
+	NewStream(NewSequentialIntegerSource(1, 0)).Sink(WrapSink(NewArraySink(), chaos.Sink())).DeadLetter(NewCallbackSink(func(entries ...Entry) error {
+		letters = append(letters, entries...)
+		return nil
+	})).Process(NewBufferedProcessor(10, 50*time.Millisecond), make(ErrorChannel, 10))
+
+	assert.Len(t, letters, 2)
+	assert.EqualValues(t, "chaos: injected sink panic", letters[0].Metadata[MetadataDeadLetterError])
+}
+
+func TestChaos_SameSeedInjectsSameFaults(t *testing.T) {
+	run := func() []interface{} {
+		chaos, err := NewChaos(ChaosConfig{Seed: 42, DuplicateProbability: 0.5})
+		assert.Nil(t, err)
+		sink := NewArraySink()
+		NewStream(WrapSource(NewSequentialIntegerSource(20, 0), chaos.Source())).Sink(sink).
+			Process(NewDirectProcessor(), make(ErrorChannel, 10))
+		return sink.Array()
+	}
+	first := run()
+	assert.True(t, len(first) > 21)
+	assert.EqualValues(t, first, run())
+}
+
+func TestNewChaos_Validation(t *testing.T) {
+	_, err := NewChaos(ChaosConfig{DuplicateProbability: 1.5})
+	assert.EqualError(t, err, "the duplicate probability should be between 0 and 1")
+}
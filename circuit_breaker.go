@@ -0,0 +1,230 @@
+package go_streams
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// CircuitState is the state of a CircuitBreaker
+type CircuitState string
+
+const (
+	// CircuitClosed lets every call through
+	CircuitClosed CircuitState = "closed"
+
+	// CircuitOpen fails the calls fast until OpenFor elapsed
+	CircuitOpen CircuitState = "open"
+
+	// CircuitHalfOpen lets trial calls through, the circuit closes once they succeed and opens again if one fails
+	CircuitHalfOpen CircuitState = "half-open"
+)
+
+// CircuitBreakerOptions configures a CircuitBreaker
+type CircuitBreakerOptions struct {
+	// Failures is the number of consecutive failures opening the circuit (defaults to 5)
+	Failures int
+
+	// OpenFor is the time the circuit stays open before the trial calls (defaults to 30s)
+	OpenFor time.Duration
+
+	// HalfOpenTrials is the number of trial calls let through while half open,
+	// they all have to succeed to close the circuit (defaults to 1)
+	HalfOpenTrials int
+
+	// OnStateChange is called on every state change
+	OnStateChange func(from CircuitState, to CircuitState)
+}
+
+// CircuitOpenError is returned by the calls failed fast while the circuit is open
+type CircuitOpenError struct {
+	Until time.Time
+}
+
+func (e *CircuitOpenError) Error() string {
+	return fmt.Sprintf("circuit breaker is open until %s", e.Until.Format(time.RFC3339))
+}
+
+// CircuitBreaker stops calling a failing downstream service for a while instead of hammering it with retries
+type CircuitBreaker struct {
+	options CircuitBreakerOptions
+
+	mutex     *sync.Mutex
+	state     CircuitState
+	failures  int
+	trials    int
+	successes int
+	openedAt  time.Time
+	listeners map[string]func(Event)
+}
+
+func NewCircuitBreaker(options CircuitBreakerOptions) *CircuitBreaker {
+	if options.Failures <= 0 {
+		options.Failures = 5
+	}
+	if options.OpenFor <= 0 {
+		options.OpenFor = 30 * time.Second
+	}
+	if options.HalfOpenTrials <= 0 {
+		options.HalfOpenTrials = 1
+	}
+	return &CircuitBreaker{
+		options:   options,
+		mutex:     &sync.Mutex{},
+		state:     CircuitClosed,
+		listeners: make(map[string]func(Event)),
+	}
+}
+
+func (this *CircuitBreaker) State() CircuitState {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	return this.state
+}
+
+// Execute calls the function unless the circuit is open, its error counts as a failure
+func (this *CircuitBreaker) Execute(fn func() error) error {
+	if err := this.allow(); err != nil {
+		return err
+	}
+	err := fn()
+	this.done(err)
+	return err
+}
+
+// MapErr wraps a map function (e.g: of MapAsync) with the circuit breaker
+func (this *CircuitBreaker) MapErr(fn MapErrFunc) MapErrFunc {
+	return func(entry interface{}) (result interface{}, err error) {
+		err = this.Execute(func() error {
+			result, err = fn(entry)
+			return err
+		})
+		return result, err
+	}
+}
+
+func (this *CircuitBreaker) allow() error {
+	this.mutex.Lock()
+	var from CircuitState
+	defer func() {
+		state := this.state
+		this.mutex.Unlock()
+		this.notify(from, state)
+	}()
+
+	if this.state == CircuitOpen {
+		until := this.openedAt.Add(this.options.OpenFor)
+		if time.Now().Before(until) {
+			return &CircuitOpenError{Until: until}
+		}
+		from = this.transition(CircuitHalfOpen)
+	}
+	if this.state == CircuitHalfOpen {
+		if this.trials >= this.options.HalfOpenTrials {
+			return &CircuitOpenError{Until: time.Now()}
+		}
+		this.trials++
+	}
+	return nil
+}
+
+func (this *CircuitBreaker) done(err error) {
+	this.mutex.Lock()
+	var from CircuitState
+	switch {
+	case this.state == CircuitHalfOpen && err != nil:
+		from = this.transition(CircuitOpen)
+	case this.state == CircuitHalfOpen:
+		if this.successes++; this.successes >= this.options.HalfOpenTrials {
+			from = this.transition(CircuitClosed)
+		}
+	case err != nil:
+		if this.failures++; this.failures >= this.options.Failures {
+			from = this.transition(CircuitOpen)
+		}
+	default:
+		this.failures = 0
+	}
+	state := this.state
+	this.mutex.Unlock()
+	this.notify(from, state)
+}
+
+// transition changes the state and returns the previous one, the caller holds the mutex
+func (this *CircuitBreaker) transition(state CircuitState) CircuitState {
+	from := this.state
+	this.state = state
+	this.failures, this.trials, this.successes = 0, 0, 0
+	if state == CircuitOpen {
+		this.openedAt = time.Now()
+	}
+	return from
+}
+
+// notify calls the state change callback and emits a CircuitStateChanged event to the bound streams
+func (this *CircuitBreaker) notify(from CircuitState, to CircuitState) {
+	if from == "" || from == to {
+		return
+	}
+	logger.Info("Circuit breaker changed from %s to %s", from, to)
+	if this.options.OnStateChange != nil {
+		this.options.OnStateChange(from, to)
+	}
+
+	this.mutex.Lock()
+	listeners := make([]func(Event), 0, len(this.listeners))
+	for _, listener := range this.listeners {
+		listeners = append(listeners, listener)
+	}
+	this.mutex.Unlock()
+	for _, listener := range listeners {
+		listener(Event{Type: CircuitStateChanged, Circuit: to})
+	}
+}
+
+// bindEvents emits the state changes to the events of a stream, streams are rebound when they restart
+func (this *CircuitBreaker) bindEvents(stream Stream) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.listeners[stream.GetSource().Name()] = func(event Event) { emitEvent(stream, event) }
+}
+
+// CircuitBreakerSink writes to its sink through a circuit breaker, writes fail fast with a
+// CircuitOpenError while the circuit is open. Wrap it with a SpillSink to buffer the entries
+// meanwhile, the replays of the SpillSink probe the circuit. AckingSinks shouldn't be wrapped.
+type CircuitBreakerSink struct {
+	*CircuitBreaker
+	sink Sink
+}
+
+func WithCircuitBreaker(sink Sink, options CircuitBreakerOptions) *CircuitBreakerSink {
+	return &CircuitBreakerSink{CircuitBreaker: NewCircuitBreaker(options), sink: sink}
+}
+
+func (this *CircuitBreakerSink) Single(entry Entry) error {
+	return this.Execute(func() error { return this.sink.Single(entry) })
+}
+
+func (this *CircuitBreakerSink) Batch(entry ...Entry) error {
+	return this.Execute(func() error { return this.sink.Batch(entry...) })
+}
+
+func (this *CircuitBreakerSink) Ping() error {
+	return this.sink.Ping()
+}
+
+// Flush flushes the sink when it implements Flusher
+func (this *CircuitBreakerSink) Flush() error {
+	if flusher, ok := this.sink.(Flusher); ok {
+		return flusher.Flush()
+	}
+	return nil
+}
+
+// Close closes the sink when it implements Closer
+func (this *CircuitBreakerSink) Close() error {
+	if closer, ok := this.sink.(Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package go_streams
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCircuitBreaker_OpensAndProbes(t *testing.T) {
+	var changes []string
+	breaker := NewCircuitBreaker(CircuitBreakerOptions{Failures: 2, OpenFor: 20 * time.Millisecond, OnStateChange: func(from, to CircuitState) {
+		changes = append(changes, fmt.Sprintf("%s->%s", from, to))
+	}})
+	calls := 0
+	failing := func() error {
+		calls++
+		return fmt.Errorf("service is down")
+	}
+
+	assert.NotNil(t, breaker.Execute(failing))
+	assert.EqualValues(t, CircuitClosed, breaker.State())
+	assert.NotNil(t, breaker.Execute(failing))
+	assert.EqualValues(t, CircuitOpen, breaker.State())
+
+	// fails fast while open
+	var openErr *CircuitOpenError
+	assert.True(t, errors.As(breaker.Execute(failing), &openErr))
+	assert.EqualValues(t, 2, calls)
+
+	// the trial failed, the circuit opens again
+	time.Sleep(25 * time.Millisecond)
+	assert.NotNil(t, breaker.Execute(failing))
+	assert.EqualValues(t, CircuitOpen, breaker.State())
+	assert.EqualValues(t, 3, calls)
+
+	time.Sleep(25 * time.Millisecond)
+	assert.Nil(t, breaker.Execute(func() error { return nil }))
+	assert.EqualValues(t, CircuitClosed, breaker.State())
+	assert.EqualValues(t, []string{"closed->open", "open->half-open", "half-open->open", "open->half-open", "half-open->closed"}, changes)
+}
+
+func TestCircuitBreaker_MapErr(t *testing.T) {
+	breaker := NewCircuitBreaker(CircuitBreakerOptions{Failures: 1, OpenFor: time.Hour})
+	fn := breaker.MapErr(func(entry interface{}) (interface{}, error) {
+		if entry.(int) == 1 {
+			return nil, fmt.Errorf("service is down")
+		}
+		return entry.(int) * 10, nil
+	})
+
+	_, err := fn(1)
+	assert.EqualValues(t, "service is down", err.Error())
+	_, err = fn(2)
+	assert.IsType(t, &CircuitOpenError{}, err)
+}
+
+func TestCircuitBreakerSink_EmitsStateChanges(t *testing.T) {
+	engine := NewEngine(NewDirectProcessorFactory(), 10*time.Second)
+	engine.SetErrorHandler(func(err error) {})
+	events := &eventRecorder{mutex: &sync.Mutex{}}
+	engine.Subscribe(events.record, CircuitStateChanged)
+
+	sink := WithCircuitBreaker(NewCallbackSink(func(entries ...Entry) error {
+		return fmt.Errorf("sink is down")
+	}), CircuitBreakerOptions{Failures: 2, OpenFor: time.Hour})
+	source := NewSequentialIntegerSource(5, 0)
+	assert.Nil(t, engine.Add(NewStream(source).Sink(sink)))
+	engine.Start()
+
+	assert.EqualValues(t, CircuitOpen, sink.State())
+	assert.Len(t, events.all(), 1)
+	assert.EqualValues(t, CircuitOpen, events.all()[0].Circuit)
+	assert.EqualValues(t, source.Name(), events.all()[0].Stream)
+}
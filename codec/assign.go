@@ -0,0 +1,195 @@
+package codec
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// assign copies a generic decoded value (nil, bool, numbers, string, []byte,
+// []interface{} and maps) into dst, converting types where possible.
+// It is shared by the codecs that decode into a generic representation first.
+func assign(dst reflect.Value, src interface{}, tag string) error {
+	if dst.Kind() == reflect.Interface && dst.NumMethod() == 0 {
+		if src == nil {
+			dst.Set(reflect.Zero(dst.Type()))
+		} else {
+			dst.Set(reflect.ValueOf(src))
+		}
+		return nil
+	}
+
+	if src == nil {
+		dst.Set(reflect.Zero(dst.Type()))
+		return nil
+	}
+
+	sv := reflect.ValueOf(src)
+	switch dst.Kind() {
+	case reflect.Ptr:
+		if dst.IsNil() {
+			dst.Set(reflect.New(dst.Type().Elem()))
+		}
+		return assign(dst.Elem(), src, tag)
+
+	case reflect.Bool:
+		if sv.Kind() == reflect.Bool {
+			dst.SetBool(sv.Bool())
+			return nil
+		}
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		switch sv.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			dst.SetInt(sv.Int())
+			return nil
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			dst.SetInt(int64(sv.Uint()))
+			return nil
+		case reflect.Float32, reflect.Float64:
+			dst.SetInt(int64(sv.Float()))
+			return nil
+		}
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		switch sv.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			dst.SetUint(uint64(sv.Int()))
+			return nil
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			dst.SetUint(sv.Uint())
+			return nil
+		case reflect.Float32, reflect.Float64:
+			dst.SetUint(uint64(sv.Float()))
+			return nil
+		}
+
+	case reflect.Float32, reflect.Float64:
+		switch sv.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			dst.SetFloat(float64(sv.Int()))
+			return nil
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			dst.SetFloat(float64(sv.Uint()))
+			return nil
+		case reflect.Float32, reflect.Float64:
+			dst.SetFloat(sv.Float())
+			return nil
+		}
+
+	case reflect.String:
+		switch s := src.(type) {
+		case string:
+			dst.SetString(s)
+			return nil
+		case []byte:
+			dst.SetString(string(s))
+			return nil
+		}
+
+	case reflect.Slice:
+		if dst.Type().Elem().Kind() == reflect.Uint8 {
+			switch s := src.(type) {
+			case []byte:
+				dst.SetBytes(append([]byte{}, s...))
+				return nil
+			case string:
+				dst.SetBytes([]byte(s))
+				return nil
+			}
+		}
+		if items, ok := src.([]interface{}); ok {
+			out := reflect.MakeSlice(dst.Type(), len(items), len(items))
+			for idx := range items {
+				if err := assign(out.Index(idx), items[idx], tag); err != nil {
+					return err
+				}
+			}
+			dst.Set(out)
+			return nil
+		}
+
+	case reflect.Array:
+		if items, ok := src.([]interface{}); ok && len(items) == dst.Len() {
+			for idx := range items {
+				if err := assign(dst.Index(idx), items[idx], tag); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+
+	case reflect.Map:
+		if sv.Kind() == reflect.Map {
+			out := reflect.MakeMapWithSize(dst.Type(), sv.Len())
+			for _, k := range sv.MapKeys() {
+				key := reflect.New(dst.Type().Key()).Elem()
+				if err := assign(key, k.Interface(), tag); err != nil {
+					return err
+				}
+				value := reflect.New(dst.Type().Elem()).Elem()
+				if err := assign(value, sv.MapIndex(k).Interface(), tag); err != nil {
+					return err
+				}
+				out.SetMapIndex(key, value)
+			}
+			dst.Set(out)
+			return nil
+		}
+
+	case reflect.Struct:
+		if dst.Type() == timeType {
+			if s, ok := src.(string); ok {
+				t, err := time.Parse(time.RFC3339Nano, s)
+				if err != nil {
+					return err
+				}
+				dst.Set(reflect.ValueOf(t))
+				return nil
+			}
+			break
+		}
+
+		fields, ok := src.(map[string]interface{})
+		if !ok {
+			break
+		}
+		t := dst.Type()
+		for idx := 0; idx < t.NumField(); idx++ {
+			name, skip := fieldName(t.Field(idx), tag)
+			if skip {
+				continue
+			}
+			if value, found := fields[name]; found {
+				if err := assign(dst.Field(idx), value, tag); err != nil {
+					return fmt.Errorf("field '%s': %s", name, err.Error())
+				}
+			}
+		}
+		return nil
+	}
+
+	return fmt.Errorf("can't assign value of type %T to %s", src, dst.Type())
+}
+
+// fieldName returns the serialized name of a struct field according to the given tag,
+// unexported fields and fields tagged with "-" are skipped.
+func fieldName(field reflect.StructField, tag string) (string, bool) {
+	if field.PkgPath != "" {
+		return "", true
+	}
+
+	value := field.Tag.Get(tag)
+	if value == "-" {
+		return "", true
+	}
+
+	name := strings.Split(value, ",")[0]
+	if name == "" {
+		name = field.Name
+	}
+	return name, false
+}
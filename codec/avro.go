@@ -0,0 +1,563 @@
+package codec
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+)
+
+// avroSchema is a parsed Avro schema node
+type avroSchema struct {
+	kind     string
+	name     string
+	fields   []avroField
+	symbols  []string
+	items    *avroSchema
+	values   *avroSchema
+	branches []*avroSchema
+	size     int
+}
+
+type avroField struct {
+	name   string
+	schema *avroSchema
+}
+
+type avroCodec struct {
+	schema *avroSchema
+}
+
+// Avro encodes values using the Avro binary encoding of the given schema (JSON).
+// Records are encoded from maps or structs (matched by field names or `avro` tags),
+// unions are resolved by the first branch that accepts the value.
+// Values decoded into an interface{} are represented by: nil, bool, int32, int64, float32, float64,
+// string, []byte (bytes and fixed), string (enums), []interface{} and map[string]interface{} (maps and records).
+func Avro(schema string) (Codec, error) {
+	var raw interface{}
+	if err := json.Unmarshal([]byte(schema), &raw); err != nil {
+		return nil, fmt.Errorf("invalid avro schema: %s", err.Error())
+	}
+
+	parsed, err := parseAvroSchema(raw, "", make(map[string]*avroSchema))
+	if err != nil {
+		return nil, err
+	}
+	return &avroCodec{schema: parsed}, nil
+}
+
+func (this *avroCodec) Encode(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := avroEncode(&buf, this.schema, reflect.ValueOf(v)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (this *avroCodec) Decode(data []byte, v interface{}) error {
+	dst := reflect.ValueOf(v)
+	if dst.Kind() != reflect.Ptr || dst.IsNil() {
+		return fmt.Errorf("avro can't decode into non pointer %T", v)
+	}
+
+	r := &avroReader{data: data}
+	generic, err := r.value(this.schema)
+	if err != nil {
+		return err
+	}
+	return assign(dst.Elem(), generic, "avro")
+}
+
+func parseAvroSchema(raw interface{}, namespace string, named map[string]*avroSchema) (*avroSchema, error) {
+	switch s := raw.(type) {
+	case string:
+		switch s {
+		case "null", "boolean", "int", "long", "float", "double", "bytes", "string":
+			return &avroSchema{kind: s}, nil
+		}
+		if ref, found := named[fullName(s, namespace)]; found {
+			return ref, nil
+		}
+		if ref, found := named[s]; found {
+			return ref, nil
+		}
+		return nil, fmt.Errorf("unknown avro type: '%s'", s)
+
+	case []interface{}:
+		union := &avroSchema{kind: "union"}
+		for _, branch := range s {
+			parsed, err := parseAvroSchema(branch, namespace, named)
+			if err != nil {
+				return nil, err
+			}
+			union.branches = append(union.branches, parsed)
+		}
+		return union, nil
+
+	case map[string]interface{}:
+		kind, _ := s["type"].(string)
+		if ns, ok := s["namespace"].(string); ok {
+			namespace = ns
+		}
+
+		switch kind {
+		case "record", "error":
+			name, _ := s["name"].(string)
+			record := &avroSchema{kind: "record", name: fullName(name, namespace)}
+			named[record.name] = record
+			fields, _ := s["fields"].([]interface{})
+			for _, f := range fields {
+				field, _ := f.(map[string]interface{})
+				fieldName, _ := field["name"].(string)
+				parsed, err := parseAvroSchema(field["type"], namespace, named)
+				if err != nil {
+					return nil, fmt.Errorf("field '%s': %s", fieldName, err.Error())
+				}
+				record.fields = append(record.fields, avroField{name: fieldName, schema: parsed})
+			}
+			return record, nil
+
+		case "enum":
+			name, _ := s["name"].(string)
+			enum := &avroSchema{kind: "enum", name: fullName(name, namespace)}
+			symbols, _ := s["symbols"].([]interface{})
+			for _, sym := range symbols {
+				str, _ := sym.(string)
+				enum.symbols = append(enum.symbols, str)
+			}
+			named[enum.name] = enum
+			return enum, nil
+
+		case "fixed":
+			name, _ := s["name"].(string)
+			size, _ := s["size"].(float64)
+			fixed := &avroSchema{kind: "fixed", name: fullName(name, namespace), size: int(size)}
+			named[fixed.name] = fixed
+			return fixed, nil
+
+		case "array":
+			items, err := parseAvroSchema(s["items"], namespace, named)
+			if err != nil {
+				return nil, err
+			}
+			return &avroSchema{kind: "array", items: items}, nil
+
+		case "map":
+			values, err := parseAvroSchema(s["values"], namespace, named)
+			if err != nil {
+				return nil, err
+			}
+			return &avroSchema{kind: "map", values: values}, nil
+
+		default:
+			// Primitive types with attributes (e.g: logical types)
+			return parseAvroSchema(s["type"], namespace, named)
+		}
+	}
+	return nil, fmt.Errorf("invalid avro schema node: %v", raw)
+}
+
+func fullName(name, namespace string) string {
+	if namespace == "" || strings.Contains(name, ".") {
+		return name
+	}
+	return namespace + "." + name
+}
+
+func avroEncode(buf *bytes.Buffer, schema *avroSchema, v reflect.Value) error {
+	for v.IsValid() && (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) {
+		if v.IsNil() {
+			v = reflect.Value{}
+			break
+		}
+		v = v.Elem()
+	}
+
+	if schema.kind == "union" {
+		for idx, branch := range schema.branches {
+			if avroAccepts(branch, v) {
+				avroLong(buf, int64(idx))
+				return avroEncode(buf, branch, v)
+			}
+		}
+		return fmt.Errorf("no union branch accepts value %v", valueString(v))
+	}
+
+	if !v.IsValid() {
+		if schema.kind == "null" {
+			return nil
+		}
+		return fmt.Errorf("avro %s can't be null", schema.kind)
+	}
+
+	switch schema.kind {
+	case "null":
+		return fmt.Errorf("avro null can't encode %v", valueString(v))
+
+	case "boolean":
+		if v.Kind() != reflect.Bool {
+			break
+		}
+		if v.Bool() {
+			buf.WriteByte(1)
+		} else {
+			buf.WriteByte(0)
+		}
+		return nil
+
+	case "int", "long":
+		n, ok := asInt(v)
+		if !ok {
+			break
+		}
+		avroLong(buf, n)
+		return nil
+
+	case "float", "double":
+		f, ok := asFloat(v)
+		if !ok {
+			break
+		}
+		if schema.kind == "float" {
+			_ = binary.Write(buf, binary.LittleEndian, math.Float32bits(float32(f)))
+		} else {
+			_ = binary.Write(buf, binary.LittleEndian, math.Float64bits(f))
+		}
+		return nil
+
+	case "string", "bytes":
+		data, ok := asBytes(v)
+		if !ok {
+			break
+		}
+		avroLong(buf, int64(len(data)))
+		buf.Write(data)
+		return nil
+
+	case "fixed":
+		data, ok := asBytes(v)
+		if !ok || len(data) != schema.size {
+			break
+		}
+		buf.Write(data)
+		return nil
+
+	case "enum":
+		if v.Kind() != reflect.String {
+			break
+		}
+		for idx, sym := range schema.symbols {
+			if sym == v.String() {
+				avroLong(buf, int64(idx))
+				return nil
+			}
+		}
+		return fmt.Errorf("'%s' isn't a symbol of enum %s", v.String(), schema.name)
+
+	case "array":
+		if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+			break
+		}
+		if v.Len() > 0 {
+			avroLong(buf, int64(v.Len()))
+			for idx := 0; idx < v.Len(); idx++ {
+				if err := avroEncode(buf, schema.items, v.Index(idx)); err != nil {
+					return err
+				}
+			}
+		}
+		buf.WriteByte(0)
+		return nil
+
+	case "map":
+		if v.Kind() != reflect.Map || v.Type().Key().Kind() != reflect.String {
+			break
+		}
+		if v.Len() > 0 {
+			avroLong(buf, int64(v.Len()))
+			for _, k := range v.MapKeys() {
+				avroLong(buf, int64(len(k.String())))
+				buf.WriteString(k.String())
+				if err := avroEncode(buf, schema.values, v.MapIndex(k)); err != nil {
+					return err
+				}
+			}
+		}
+		buf.WriteByte(0)
+		return nil
+
+	case "record":
+		for _, field := range schema.fields {
+			fv, err := recordField(v, field.name)
+			if err != nil {
+				return fmt.Errorf("%s.%s: %s", schema.name, field.name, err.Error())
+			}
+			if err := avroEncode(buf, field.schema, fv); err != nil {
+				return fmt.Errorf("%s.%s: %s", schema.name, field.name, err.Error())
+			}
+		}
+		return nil
+	}
+
+	return fmt.Errorf("avro %s can't encode %v", schema.kind, valueString(v))
+}
+
+// avroAccepts checks if a union branch can encode the given value
+func avroAccepts(schema *avroSchema, v reflect.Value) bool {
+	if !v.IsValid() {
+		return schema.kind == "null"
+	}
+
+	switch schema.kind {
+	case "boolean":
+		return v.Kind() == reflect.Bool
+	case "int", "long":
+		_, ok := asInt(v)
+		return ok
+	case "float", "double":
+		_, ok := asFloat(v)
+		return ok
+	case "string", "enum":
+		return v.Kind() == reflect.String
+	case "bytes":
+		return v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8
+	case "fixed":
+		data, ok := asBytes(v)
+		return ok && v.Kind() != reflect.String && len(data) == schema.size
+	case "array":
+		return (v.Kind() == reflect.Slice && v.Type().Elem().Kind() != reflect.Uint8) || v.Kind() == reflect.Array
+	case "map", "record":
+		return v.Kind() == reflect.Map || v.Kind() == reflect.Struct
+	}
+	return false
+}
+
+func recordField(v reflect.Value, name string) (reflect.Value, error) {
+	switch v.Kind() {
+	case reflect.Map:
+		value := v.MapIndex(reflect.ValueOf(name))
+		if !value.IsValid() {
+			return reflect.Value{}, nil
+		}
+		return value, nil
+	case reflect.Struct:
+		t := v.Type()
+		for idx := 0; idx < t.NumField(); idx++ {
+			if fieldName, skip := fieldName(t.Field(idx), "avro"); !skip && fieldName == name {
+				return v.Field(idx), nil
+			}
+		}
+		return reflect.Value{}, nil
+	}
+	return reflect.Value{}, fmt.Errorf("record can't be encoded from %s", v.Type())
+}
+
+func asInt(v reflect.Value) (int64, bool) {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int(), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int64(v.Uint()), true
+	}
+	return 0, false
+}
+
+func asFloat(v reflect.Value) (float64, bool) {
+	switch v.Kind() {
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	}
+	if n, ok := asInt(v); ok {
+		return float64(n), true
+	}
+	return 0, false
+}
+
+func asBytes(v reflect.Value) ([]byte, bool) {
+	switch {
+	case v.Kind() == reflect.String:
+		return []byte(v.String()), true
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8:
+		return v.Bytes(), true
+	case v.Kind() == reflect.Array && v.Type().Elem().Kind() == reflect.Uint8:
+		out := make([]byte, v.Len())
+		reflect.Copy(reflect.ValueOf(out), v)
+		return out, true
+	}
+	return nil, false
+}
+
+func valueString(v reflect.Value) string {
+	if !v.IsValid() {
+		return "null"
+	}
+	return fmt.Sprintf("%v (%s)", v.Interface(), v.Type())
+}
+
+func avroLong(buf *bytes.Buffer, n int64) {
+	var tmp [binary.MaxVarintLen64]byte
+	size := binary.PutVarint(tmp[:], n)
+	buf.Write(tmp[:size])
+}
+
+type avroReader struct {
+	data []byte
+	pos  int
+}
+
+func (this *avroReader) long() (int64, error) {
+	n, size := binary.Varint(this.data[this.pos:])
+	if size <= 0 {
+		return 0, fmt.Errorf("avro: invalid varint at offset %d", this.pos)
+	}
+	this.pos += size
+	return n, nil
+}
+
+func (this *avroReader) next(n int) ([]byte, error) {
+	if n < 0 || this.pos+n > len(this.data) {
+		return nil, fmt.Errorf("avro: unexpected end of data")
+	}
+	out := this.data[this.pos : this.pos+n]
+	this.pos += n
+	return out, nil
+}
+
+func (this *avroReader) bytes() ([]byte, error) {
+	n, err := this.long()
+	if err != nil {
+		return nil, err
+	}
+	data, err := this.next(int(n))
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte{}, data...), nil
+}
+
+// blocks reads array/map blocks calling fn for every item
+func (this *avroReader) blocks(fn func() error) error {
+	for {
+		count, err := this.long()
+		if err != nil {
+			return err
+		}
+		if count == 0 {
+			return nil
+		}
+		if count < 0 {
+			count = -count
+			if _, err := this.long(); err != nil { // block size in bytes
+				return err
+			}
+		}
+		for i := int64(0); i < count; i++ {
+			if err := fn(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (this *avroReader) value(schema *avroSchema) (interface{}, error) {
+	switch schema.kind {
+	case "null":
+		return nil, nil
+
+	case "boolean":
+		b, err := this.next(1)
+		if err != nil {
+			return nil, err
+		}
+		return b[0] != 0, nil
+
+	case "int":
+		n, err := this.long()
+		return int32(n), err
+
+	case "long":
+		return this.long()
+
+	case "float":
+		b, err := this.next(4)
+		if err != nil {
+			return nil, err
+		}
+		return math.Float32frombits(binary.LittleEndian.Uint32(b)), nil
+
+	case "double":
+		b, err := this.next(8)
+		if err != nil {
+			return nil, err
+		}
+		return math.Float64frombits(binary.LittleEndian.Uint64(b)), nil
+
+	case "bytes":
+		return this.bytes()
+
+	case "string":
+		b, err := this.bytes()
+		return string(b), err
+
+	case "fixed":
+		b, err := this.next(schema.size)
+		return append([]byte{}, b...), err
+
+	case "enum":
+		idx, err := this.long()
+		if err != nil {
+			return nil, err
+		}
+		if idx < 0 || int(idx) >= len(schema.symbols) {
+			return nil, fmt.Errorf("avro: invalid symbol index %d of enum %s", idx, schema.name)
+		}
+		return schema.symbols[idx], nil
+
+	case "union":
+		idx, err := this.long()
+		if err != nil {
+			return nil, err
+		}
+		if idx < 0 || int(idx) >= len(schema.branches) {
+			return nil, fmt.Errorf("avro: invalid union branch %d", idx)
+		}
+		return this.value(schema.branches[idx])
+
+	case "array":
+		out := []interface{}{}
+		err := this.blocks(func() error {
+			item, err := this.value(schema.items)
+			out = append(out, item)
+			return err
+		})
+		return out, err
+
+	case "map":
+		out := make(map[string]interface{})
+		err := this.blocks(func() error {
+			key, err := this.bytes()
+			if err != nil {
+				return err
+			}
+			value, err := this.value(schema.values)
+			out[string(key)] = value
+			return err
+		})
+		return out, err
+
+	case "record":
+		out := make(map[string]interface{}, len(schema.fields))
+		for _, field := range schema.fields {
+			value, err := this.value(field.schema)
+			if err != nil {
+				return nil, fmt.Errorf("%s.%s: %s", schema.name, field.name, err.Error())
+			}
+			out[field.name] = value
+		}
+		return out, nil
+	}
+	return nil, fmt.Errorf("avro: unsupported schema type %s", schema.kind)
+}
@@ -0,0 +1,107 @@
+package codec
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const userSchema = `{
+  "type": "record", "name": "User", "namespace": "com.example",
+  "fields": [
+    {"name": "id", "type": "long"},
+    {"name": "name", "type": "string"},
+    {"name": "email", "type": ["null", "string"]},
+    {"name": "tags", "type": {"type": "array", "items": "string"}},
+    {"name": "attrs", "type": {"type": "map", "values": "int"}},
+    {"name": "status", "type": {"type": "enum", "name": "Status", "symbols": ["ACTIVE", "BLOCKED"]}},
+    {"name": "manager", "type": ["null", "User"]}
+  ]
+}`
+
+func TestAvro_Encode_KnownBytes(t *testing.T) {
+	c, err := Avro(`{"type":"record","name":"r","fields":[{"name":"a","type":"long"},{"name":"b","type":"string"}]}`)
+	assert.Nil(t, err)
+
+	data, err := c.Encode(map[string]interface{}{"a": 1, "b": "foo"})
+	assert.Nil(t, err)
+	assert.EqualValues(t, []byte{0x02, 0x06, 'f', 'o', 'o'}, data)
+
+	data, err = c.Encode(map[string]interface{}{"a": -64, "b": ""})
+	assert.Nil(t, err)
+	assert.EqualValues(t, []byte{0x7f, 0x00}, data)
+}
+
+func TestAvro_RoundTrip_Generic(t *testing.T) {
+	c, err := Avro(userSchema)
+	assert.Nil(t, err)
+
+	in := map[string]interface{}{
+		"id":     int64(1),
+		"name":   "john",
+		"email":  nil,
+		"tags":   []string{"a", "b"},
+		"attrs":  map[string]int{"x": 1},
+		"status": "BLOCKED",
+		"manager": map[string]interface{}{
+			"id": 2, "name": "jane", "email": "jane@example.com", "tags": []string{}, "attrs": map[string]int{}, "status": "ACTIVE", "manager": nil,
+		},
+	}
+	data, err := c.Encode(in)
+	assert.Nil(t, err)
+
+	var out map[string]interface{}
+	assert.Nil(t, c.Decode(data, &out))
+	assert.EqualValues(t, int64(1), out["id"])
+	assert.EqualValues(t, "john", out["name"])
+	assert.Nil(t, out["email"])
+	assert.EqualValues(t, []interface{}{"a", "b"}, out["tags"])
+	assert.EqualValues(t, map[string]interface{}{"x": int32(1)}, out["attrs"])
+	assert.EqualValues(t, "BLOCKED", out["status"])
+
+	manager := out["manager"].(map[string]interface{})
+	assert.EqualValues(t, "jane@example.com", manager["email"])
+	assert.EqualValues(t, "ACTIVE", manager["status"])
+}
+
+type avroUser struct {
+	Id     int64             `avro:"id"`
+	Name   string            `avro:"name"`
+	Email  *string           `avro:"email"`
+	Tags   []string          `avro:"tags"`
+	Attrs  map[string]int    `avro:"attrs"`
+	Status string            `avro:"status"`
+	Boss   *avroUser         `avro:"manager"`
+	Extra  map[string]string `avro:"-"`
+}
+
+func TestAvro_RoundTrip_Struct(t *testing.T) {
+	c, err := Avro(userSchema)
+	assert.Nil(t, err)
+
+	email := "john@example.com"
+	in := avroUser{Id: 3, Name: "john", Email: &email, Tags: []string{"t"}, Attrs: map[string]int{"k": 2}, Status: "ACTIVE"}
+	data, err := c.Encode(in)
+	assert.Nil(t, err)
+
+	var out avroUser
+	assert.Nil(t, c.Decode(data, &out))
+	assert.EqualValues(t, in, out)
+}
+
+func TestAvro_Errors(t *testing.T) {
+	_, err := Avro(`{"type":"record","name":"r","fields":[{"name":"a","type":"Unknown"}]}`)
+	assert.NotNil(t, err)
+
+	c, err := Avro(userSchema)
+	assert.Nil(t, err)
+
+	_, err = c.Encode(map[string]interface{}{"id": "not a number"})
+	assert.NotNil(t, err)
+
+	_, err = c.Encode(map[string]interface{}{"id": 1, "name": "n", "email": nil, "tags": nil, "attrs": nil, "status": "UNKNOWN"})
+	assert.NotNil(t, err)
+
+	var out interface{}
+	assert.NotNil(t, c.Decode([]byte{0x02}, &out))
+}
@@ -0,0 +1,99 @@
+package codec
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	streams "github.com/matang28/go-streams"
+)
+
+const defaultClaimCheckMaxSize = 512 << 10
+
+// claimReference prefixes the references of the offloaded payloads
+var claimReference = []byte("go-streams/claim-check:")
+
+// ObjectStore stores the payloads offloaded by a ClaimCheck (e.g: an object storage bucket, see s3.NewObjectStore)
+type ObjectStore interface {
+	Put(key string, data []byte) error
+	Get(key string) ([]byte, error)
+}
+
+// ClaimCheckConfig configures a ClaimCheck
+type ClaimCheckConfig struct {
+	Store ObjectStore
+
+	// MaxSize is the size in bytes of the largest value passed as it is, larger values are offloaded (defaults to 512KB)
+	MaxSize int
+
+	// Prefix is prepended to the keys of the offloaded payloads (e.g: "claims/")
+	Prefix string
+}
+
+// ClaimCheck offloads the values too large for a broker to an object store and replaces them by a reference,
+// the consumers retrieve the payload of the references. Payloads are stored by their hash so an entry
+// offloaded again (e.g: replayed after a crash) overwrites the same object. Compress the values
+// before Offload (see CompressMap) to offload fewer and smaller payloads.
+type ClaimCheck struct {
+	config ClaimCheckConfig
+}
+
+func NewClaimCheck(config ClaimCheckConfig) (*ClaimCheck, error) {
+	if config.Store == nil {
+		return nil, fmt.Errorf("claim check requires an object store")
+	}
+	if config.MaxSize <= 0 {
+		config.MaxSize = defaultClaimCheckMaxSize
+	}
+	return &ClaimCheck{config: config}, nil
+}
+
+// Offload returns a map function that replaces the []byte values larger than the max size by a reference,
+// storage failures panics and are reported by the processor as map errors.
+func (this *ClaimCheck) Offload() streams.MapFunc {
+	return func(entry interface{}) interface{} {
+		data, ok := entry.([]byte)
+		if !ok {
+			panic(fmt.Errorf("can't offload value of type %T, expected []byte", entry))
+		}
+		if len(data) <= this.config.MaxSize {
+			return entry
+		}
+
+		sum := sha256.Sum256(data)
+		key := this.config.Prefix + hex.EncodeToString(sum[:])
+		if err := this.config.Store.Put(key, data); err != nil {
+			panic(fmt.Errorf("failed to offload payload '%s': %s", key, err.Error()))
+		}
+		return append(append([]byte{}, claimReference...), key...)
+	}
+}
+
+// Retrieve returns a map function that replaces the references by their payload, other values are passed as they are.
+// Storage failures panics and are reported by the processor as map errors.
+func (this *ClaimCheck) Retrieve() streams.MapFunc {
+	return func(entry interface{}) interface{} {
+		data, ok := entry.([]byte)
+		if !ok {
+			panic(fmt.Errorf("can't retrieve value of type %T, expected []byte", entry))
+		}
+		key, claimed := ClaimedKey(data)
+		if !claimed {
+			return entry
+		}
+		payload, err := this.config.Store.Get(key)
+		if err != nil {
+			panic(fmt.Errorf("failed to retrieve payload '%s': %s", key, err.Error()))
+		}
+		return payload
+	}
+}
+
+// ClaimedKey returns the key of the payload a value references, claimed is false when it isn't a reference
+func ClaimedKey(data []byte) (key string, claimed bool) {
+	if !bytes.HasPrefix(data, claimReference) {
+		return "", false
+	}
+	return string(data[len(claimReference):]), true
+}
@@ -0,0 +1,72 @@
+package codec
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClaimCheck_OffloadsLargePayloads(t *testing.T) {
+	store := newMemoryObjectStore()
+	claims, err := NewClaimCheck(ClaimCheckConfig{Store: store, MaxSize: 10, Prefix: "claims/"})
+	assert.Nil(t, err)
+	offload, retrieve := claims.Offload(), claims.Retrieve()
+
+	small := []byte("small")
+	assert.EqualValues(t, small, offload(small))
+	assert.Empty(t, store.objects)
+
+	large := bytes.Repeat([]byte("large"), 10)
+	reference := offload(large).([]byte)
+	key, claimed := ClaimedKey(reference)
+	assert.True(t, claimed)
+	assert.Contains(t, key, "claims/")
+	assert.EqualValues(t, large, store.objects[key])
+
+	// the same payload is offloaded to the same object
+	assert.EqualValues(t, reference, offload(large))
+	assert.Len(t, store.objects, 1)
+
+	assert.EqualValues(t, large, retrieve(reference))
+	assert.EqualValues(t, small, retrieve(small))
+}
+
+func TestClaimCheck_StoreFailures(t *testing.T) {
+	claims, err := NewClaimCheck(ClaimCheckConfig{Store: newMemoryObjectStore(), MaxSize: 1})
+	assert.Nil(t, err)
+
+	assert.Panics(t, func() { claims.Retrieve()(append(append([]byte{}, claimReference...), "missing"...)) })
+	assert.Panics(t, func() { claims.Offload()("not bytes") })
+
+	_, err = NewClaimCheck(ClaimCheckConfig{})
+	assert.NotNil(t, err)
+}
+
+type memoryObjectStore struct {
+	mutex   *sync.Mutex
+	objects map[string][]byte
+}
+
+func newMemoryObjectStore() *memoryObjectStore {
+	return &memoryObjectStore{mutex: &sync.Mutex{}, objects: make(map[string][]byte)}
+}
+
+func (this *memoryObjectStore) Put(key string, data []byte) error {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.objects[key] = data
+	return nil
+}
+
+func (this *memoryObjectStore) Get(key string) ([]byte, error) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	data, found := this.objects[key]
+	if !found {
+		return nil, fmt.Errorf("no such key '%s'", key)
+	}
+	return data, nil
+}
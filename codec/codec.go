@@ -0,0 +1,122 @@
+package codec
+
+import (
+	"encoding/json"
+	"fmt"
+
+	streams "github.com/matang28/go-streams"
+)
+
+// Codec serializes values to bytes and back, connectors use codecs
+// so raw bytes sources can produce typed values and sinks serialize consistently.
+type Codec interface {
+	// Encode serializes the given value
+	Encode(v interface{}) ([]byte, error)
+
+	// Decode deserializes data into v (which should be a pointer)
+	Decode(data []byte, v interface{}) error
+}
+
+type jsonCodec struct{}
+
+// JSON encodes values using encoding/json
+func JSON() Codec {
+	return &jsonCodec{}
+}
+
+func (this *jsonCodec) Encode(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (this *jsonCodec) Decode(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+type funcsCodec struct {
+	encode func(v interface{}) ([]byte, error)
+	decode func(data []byte, v interface{}) error
+}
+
+// Funcs adapts a pair of functions into a codec, for example to use the
+// official protobuf runtime:
+//
+//	codec.Funcs(
+//	    func(v interface{}) ([]byte, error) { return proto.Marshal(v.(proto.Message)) },
+//	    func(data []byte, v interface{}) error { return proto.Unmarshal(data, v.(proto.Message)) })
+func Funcs(encode func(v interface{}) ([]byte, error), decode func(data []byte, v interface{}) error) Codec {
+	return &funcsCodec{encode: encode, decode: decode}
+}
+
+func (this *funcsCodec) Encode(v interface{}) ([]byte, error) {
+	return this.encode(v)
+}
+
+func (this *funcsCodec) Decode(data []byte, v interface{}) error {
+	return this.decode(data, v)
+}
+
+// ProtoMessage is implemented by generated protobuf messages that carry their own
+// marshaling methods (e.g: gogo/protobuf, vtprotobuf).
+type ProtoMessage interface {
+	Marshal() ([]byte, error)
+	Unmarshal(data []byte) error
+}
+
+type protobufCodec struct{}
+
+// Protobuf encodes values that implements ProtoMessage, use Funcs to plug a different protobuf runtime.
+func Protobuf() Codec {
+	return &protobufCodec{}
+}
+
+func (this *protobufCodec) Encode(v interface{}) ([]byte, error) {
+	msg, ok := v.(ProtoMessage)
+	if !ok {
+		return nil, fmt.Errorf("protobuf codec can't encode %T, it doesn't implement ProtoMessage", v)
+	}
+	return msg.Marshal()
+}
+
+func (this *protobufCodec) Decode(data []byte, v interface{}) error {
+	msg, ok := v.(ProtoMessage)
+	if !ok {
+		return fmt.Errorf("protobuf codec can't decode into %T, it doesn't implement ProtoMessage", v)
+	}
+	return msg.Unmarshal(data)
+}
+
+// DecodeMap returns a map function that decodes []byte values into a new value created by factory,
+// decoding failures panics and are reported by the processor as map errors.
+func DecodeMap(codec Codec, factory func() interface{}) streams.MapFunc {
+	return func(entry interface{}) interface{} {
+		data, ok := entry.([]byte)
+		if !ok {
+			panic(fmt.Errorf("can't decode value of type %T, expected []byte", entry))
+		}
+
+		out := factory()
+		if err := codec.Decode(data, out); err != nil {
+			panic(err)
+		}
+		return out
+	}
+}
+
+// EncodeMap returns a map function that encodes values into []byte,
+// encoding failures panics and are reported by the processor as map errors.
+func EncodeMap(codec Codec) streams.MapFunc {
+	return func(entry interface{}) interface{} {
+		data, err := codec.Encode(entry)
+		if err != nil {
+			panic(err)
+		}
+		return data
+	}
+}
+
+// EntryEncoder adapts a codec to the entry encoders used by the sinks (e.g: http, elasticsearch).
+func EntryEncoder(codec Codec) func(entry streams.Entry) ([]byte, error) {
+	return func(entry streams.Entry) ([]byte, error) {
+		return codec.Encode(entry.Value)
+	}
+}
@@ -0,0 +1,100 @@
+package codec
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	streams "github.com/matang28/go-streams"
+	"github.com/stretchr/testify/assert"
+)
+
+type order struct {
+	Id    string  `json:"id" msgpack:"id" avro:"id"`
+	Price float64 `json:"price" msgpack:"price" avro:"price"`
+}
+
+func TestJSON_RoundTrip(t *testing.T) {
+	c := JSON()
+	data, err := c.Encode(order{Id: "1", Price: 2.5})
+	assert.Nil(t, err)
+	assert.EqualValues(t, `{"id":"1","price":2.5}`, string(data))
+
+	var out order
+	assert.Nil(t, c.Decode(data, &out))
+	assert.EqualValues(t, order{Id: "1", Price: 2.5}, out)
+}
+
+type fakeProto struct {
+	value string
+}
+
+func (this *fakeProto) Marshal() ([]byte, error) {
+	return []byte(this.value), nil
+}
+
+func (this *fakeProto) Unmarshal(data []byte) error {
+	this.value = string(data)
+	return nil
+}
+
+func TestProtobuf(t *testing.T) {
+	c := Protobuf()
+	data, err := c.Encode(&fakeProto{value: "hello"})
+	assert.Nil(t, err)
+
+	out := &fakeProto{}
+	assert.Nil(t, c.Decode(data, out))
+	assert.EqualValues(t, "hello", out.value)
+
+	_, err = c.Encode(order{})
+	assert.NotNil(t, err)
+}
+
+func TestFuncs(t *testing.T) {
+	c := Funcs(func(v interface{}) ([]byte, error) {
+		return nil, errors.New("encode")
+	}, func(data []byte, v interface{}) error {
+		return errors.New("decode")
+	})
+	_, err := c.Encode(1)
+	assert.EqualValues(t, "encode", err.Error())
+	assert.EqualValues(t, "decode", c.Decode(nil, nil).Error())
+}
+
+func TestDecodeMapAndEncodeMap_InStream(t *testing.T) {
+	source := streams.NewAppendSource(3)
+	sink := streams.NewArraySink()
+	errs := make(streams.ErrorChannel, 10)
+
+	source.Append("1", []byte(`{"id":"1","price":1}`))
+	source.Append("2", []byte(`not json`))
+	source.Append("3", []byte(`{"id":"3","price":3}`))
+
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		_ = source.Stop()
+	}()
+
+	streams.NewStream(source).
+		Map(DecodeMap(JSON(), func() interface{} { return &order{} })).
+		Filter(func(entry interface{}) bool { return entry != nil }).
+		Map(EncodeMap(MsgPack())).
+		Sink(sink).
+		Process(streams.NewDirectProcessor(), errs)
+
+	assert.EqualValues(t, 2, len(sink.Array()))
+	var out order
+	assert.Nil(t, MsgPack().Decode(sink.Array()[1].([]byte), &out))
+	assert.EqualValues(t, order{Id: "3", Price: 3}, out)
+
+	err := <-errs
+	var mapErr *streams.MapError
+	assert.True(t, errors.As(err, &mapErr))
+}
+
+func TestEntryEncoder(t *testing.T) {
+	data, err := EntryEncoder(JSON())(streams.Entry{Key: "k", Value: []int{1, 2}})
+	assert.Nil(t, err)
+	assert.EqualValues(t, "[1,2]", string(data))
+}
@@ -0,0 +1,134 @@
+package codec
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io/ioutil"
+
+	streams "github.com/matang28/go-streams"
+)
+
+// Compression compresses serialized values, the compression of a source should match the one of the sink that wrote the values
+type Compression interface {
+	Compress(data []byte) ([]byte, error)
+	Decompress(data []byte) ([]byte, error)
+}
+
+type gzipCompression struct {
+	level int
+}
+
+// Gzip compresses values using compress/gzip at the given level (e.g: gzip.BestSpeed),
+// an invalid level uses the default compression.
+func Gzip(level int) Compression {
+	if level < gzip.HuffmanOnly || level > gzip.BestCompression {
+		level = gzip.DefaultCompression
+	}
+	return &gzipCompression{level: level}
+}
+
+func (this *gzipCompression) Compress(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w, err := gzip.NewWriterLevel(&buf, this.level)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (this *gzipCompression) Decompress(data []byte) ([]byte, error) {
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return ioutil.ReadAll(r)
+}
+
+type funcsCompression struct {
+	compress   func(data []byte) ([]byte, error)
+	decompress func(data []byte) ([]byte, error)
+}
+
+// CompressionFuncs adapts a pair of functions into a compression, for example to use zstd or snappy:
+//
+//	codec.CompressionFuncs(
+//	    func(data []byte) ([]byte, error) { return snappy.Encode(nil, data), nil },
+//	    func(data []byte) ([]byte, error) { return snappy.Decode(nil, data) })
+func CompressionFuncs(compress, decompress func(data []byte) ([]byte, error)) Compression {
+	return &funcsCompression{compress: compress, decompress: decompress}
+}
+
+func (this *funcsCompression) Compress(data []byte) ([]byte, error) {
+	return this.compress(data)
+}
+
+func (this *funcsCompression) Decompress(data []byte) ([]byte, error) {
+	return this.decompress(data)
+}
+
+type compressedCodec struct {
+	codec       Codec
+	compression Compression
+}
+
+// Compressed compresses the values encoded by the codec and decompresses them before decoding,
+// e.g: to use it with the codec based connectors.
+func Compressed(codec Codec, compression Compression) Codec {
+	return &compressedCodec{codec: codec, compression: compression}
+}
+
+func (this *compressedCodec) Encode(v interface{}) ([]byte, error) {
+	data, err := this.codec.Encode(v)
+	if err != nil {
+		return nil, err
+	}
+	return this.compression.Compress(data)
+}
+
+func (this *compressedCodec) Decode(data []byte, v interface{}) error {
+	data, err := this.compression.Decompress(data)
+	if err != nil {
+		return err
+	}
+	return this.codec.Decode(data, v)
+}
+
+// CompressMap returns a map function that compresses []byte values,
+// compression failures panics and are reported by the processor as map errors.
+func CompressMap(compression Compression) streams.MapFunc {
+	return func(entry interface{}) interface{} {
+		data, ok := entry.([]byte)
+		if !ok {
+			panic(fmt.Errorf("can't compress value of type %T, expected []byte", entry))
+		}
+		out, err := compression.Compress(data)
+		if err != nil {
+			panic(err)
+		}
+		return out
+	}
+}
+
+// DecompressMap returns a map function that decompresses []byte values,
+// decompression failures panics and are reported by the processor as map errors.
+func DecompressMap(compression Compression) streams.MapFunc {
+	return func(entry interface{}) interface{} {
+		data, ok := entry.([]byte)
+		if !ok {
+			panic(fmt.Errorf("can't decompress value of type %T, expected []byte", entry))
+		}
+		out, err := compression.Decompress(data)
+		if err != nil {
+			panic(err)
+		}
+		return out
+	}
+}
@@ -0,0 +1,59 @@
+package codec
+
+import (
+	"bytes"
+	"compress/gzip"
+	"testing"
+	"time"
+
+	streams "github.com/matang28/go-streams"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGzip_RoundTrip(t *testing.T) {
+	c := Gzip(gzip.BestSpeed)
+	payload := bytes.Repeat([]byte("go-streams "), 100)
+
+	data, err := c.Compress(payload)
+	assert.Nil(t, err)
+	assert.True(t, len(data) < len(payload))
+
+	out, err := c.Decompress(data)
+	assert.Nil(t, err)
+	assert.EqualValues(t, payload, out)
+
+	_, err = c.Decompress([]byte("not gzip"))
+	assert.NotNil(t, err)
+}
+
+func TestCompressed(t *testing.T) {
+	c := Compressed(JSON(), Gzip(gzip.DefaultCompression))
+	data, err := c.Encode(order{Id: "1", Price: 2.5})
+	assert.Nil(t, err)
+
+	var out order
+	assert.Nil(t, c.Decode(data, &out))
+	assert.EqualValues(t, order{Id: "1", Price: 2.5}, out)
+}
+
+func TestCompressMapAndDecompressMap_InStream(t *testing.T) {
+	source := streams.NewAppendSource(2)
+	sink := streams.NewArraySink()
+
+	source.Append("1", []byte("first"))
+	source.Append("2", []byte("second"))
+
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		_ = source.Stop()
+	}()
+
+	compression := Gzip(gzip.BestSpeed)
+	streams.NewStream(source).
+		Map(CompressMap(compression)).
+		Map(DecompressMap(compression)).
+		Sink(sink).
+		Process(streams.NewDirectProcessor(), make(streams.ErrorChannel, 10))
+
+	assert.EqualValues(t, []interface{}{[]byte("first"), []byte("second")}, sink.Array())
+}
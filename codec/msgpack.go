@@ -0,0 +1,410 @@
+package codec
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"time"
+)
+
+type msgPackCodec struct{}
+
+// MsgPack encodes values using the MessagePack format, structs are encoded
+// as maps keyed by their field names (or their `msgpack` tags) and extension types aren't supported.
+// Values decoded into an interface{} are represented by: nil, bool, int64, uint64 (only above MaxInt64),
+// float64, string, []byte, []interface{}, map[string]interface{} and map[interface{}]interface{}.
+func MsgPack() Codec {
+	return &msgPackCodec{}
+}
+
+func (this *msgPackCodec) Encode(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := msgPackEncode(&buf, reflect.ValueOf(v)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (this *msgPackCodec) Decode(data []byte, v interface{}) error {
+	dst := reflect.ValueOf(v)
+	if dst.Kind() != reflect.Ptr || dst.IsNil() {
+		return fmt.Errorf("msgpack can't decode into non pointer %T", v)
+	}
+
+	r := &msgPackReader{data: data}
+	generic, err := r.value()
+	if err != nil {
+		return err
+	}
+	if r.pos != len(data) {
+		return fmt.Errorf("msgpack: %d trailing bytes", len(data)-r.pos)
+	}
+	return assign(dst.Elem(), generic, "msgpack")
+}
+
+func msgPackEncode(buf *bytes.Buffer, v reflect.Value) error {
+	if !v.IsValid() {
+		buf.WriteByte(0xc0)
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			buf.WriteByte(0xc0)
+			return nil
+		}
+		return msgPackEncode(buf, v.Elem())
+
+	case reflect.Bool:
+		if v.Bool() {
+			buf.WriteByte(0xc3)
+		} else {
+			buf.WriteByte(0xc2)
+		}
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		msgPackInt(buf, v.Int())
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		msgPackUint(buf, v.Uint())
+
+	case reflect.Float32:
+		buf.WriteByte(0xca)
+		_ = binary.Write(buf, binary.BigEndian, math.Float32bits(float32(v.Float())))
+
+	case reflect.Float64:
+		buf.WriteByte(0xcb)
+		_ = binary.Write(buf, binary.BigEndian, math.Float64bits(v.Float()))
+
+	case reflect.String:
+		msgPackString(buf, v.String())
+
+	case reflect.Slice, reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			var data []byte
+			if v.Kind() == reflect.Slice {
+				if v.IsNil() {
+					buf.WriteByte(0xc0)
+					return nil
+				}
+				data = v.Bytes()
+			} else {
+				data = make([]byte, v.Len())
+				reflect.Copy(reflect.ValueOf(data), v)
+			}
+			msgPackHeader(buf, len(data), 0, 0xc4, 0xc5, 0xc6)
+			buf.Write(data)
+			return nil
+		}
+
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			buf.WriteByte(0xc0)
+			return nil
+		}
+		msgPackHeader(buf, v.Len(), 0x90, 0, 0xdc, 0xdd)
+		for idx := 0; idx < v.Len(); idx++ {
+			if err := msgPackEncode(buf, v.Index(idx)); err != nil {
+				return err
+			}
+		}
+
+	case reflect.Map:
+		if v.IsNil() {
+			buf.WriteByte(0xc0)
+			return nil
+		}
+		keys := v.MapKeys()
+		if v.Type().Key().Kind() == reflect.String {
+			sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+		}
+		msgPackHeader(buf, len(keys), 0x80, 0, 0xde, 0xdf)
+		for _, k := range keys {
+			if err := msgPackEncode(buf, k); err != nil {
+				return err
+			}
+			if err := msgPackEncode(buf, v.MapIndex(k)); err != nil {
+				return err
+			}
+		}
+
+	case reflect.Struct:
+		if v.Type() == timeType {
+			msgPackString(buf, v.Interface().(time.Time).Format(time.RFC3339Nano))
+			return nil
+		}
+
+		t := v.Type()
+		var names []string
+		var values []reflect.Value
+		for idx := 0; idx < t.NumField(); idx++ {
+			name, skip := fieldName(t.Field(idx), "msgpack")
+			if skip {
+				continue
+			}
+			names = append(names, name)
+			values = append(values, v.Field(idx))
+		}
+		msgPackHeader(buf, len(names), 0x80, 0, 0xde, 0xdf)
+		for idx := range names {
+			msgPackString(buf, names[idx])
+			if err := msgPackEncode(buf, values[idx]); err != nil {
+				return err
+			}
+		}
+
+	default:
+		return fmt.Errorf("msgpack can't encode values of type %s", v.Type())
+	}
+	return nil
+}
+
+func msgPackInt(buf *bytes.Buffer, n int64) {
+	switch {
+	case n >= 0:
+		msgPackUint(buf, uint64(n))
+	case n >= -32:
+		buf.WriteByte(byte(int8(n)))
+	case n >= math.MinInt8:
+		buf.WriteByte(0xd0)
+		buf.WriteByte(byte(int8(n)))
+	case n >= math.MinInt16:
+		buf.WriteByte(0xd1)
+		_ = binary.Write(buf, binary.BigEndian, int16(n))
+	case n >= math.MinInt32:
+		buf.WriteByte(0xd2)
+		_ = binary.Write(buf, binary.BigEndian, int32(n))
+	default:
+		buf.WriteByte(0xd3)
+		_ = binary.Write(buf, binary.BigEndian, n)
+	}
+}
+
+func msgPackUint(buf *bytes.Buffer, n uint64) {
+	switch {
+	case n <= 0x7f:
+		buf.WriteByte(byte(n))
+	case n <= math.MaxUint8:
+		buf.WriteByte(0xcc)
+		buf.WriteByte(byte(n))
+	case n <= math.MaxUint16:
+		buf.WriteByte(0xcd)
+		_ = binary.Write(buf, binary.BigEndian, uint16(n))
+	case n <= math.MaxUint32:
+		buf.WriteByte(0xce)
+		_ = binary.Write(buf, binary.BigEndian, uint32(n))
+	default:
+		buf.WriteByte(0xcf)
+		_ = binary.Write(buf, binary.BigEndian, n)
+	}
+}
+
+func msgPackString(buf *bytes.Buffer, s string) {
+	if len(s) < 32 {
+		buf.WriteByte(0xa0 | byte(len(s)))
+	} else {
+		msgPackHeader(buf, len(s), 0, 0xd9, 0xda, 0xdb)
+	}
+	buf.WriteString(s)
+}
+
+// msgPackHeader writes a length header using the fix format when possible (fix != 0),
+// then the 8 bit (when b8 != 0), 16 bit and 32 bit formats.
+func msgPackHeader(buf *bytes.Buffer, n int, fix, b8, b16, b32 byte) {
+	switch {
+	case fix != 0 && n < 16:
+		buf.WriteByte(fix | byte(n))
+	case b8 != 0 && n <= math.MaxUint8:
+		buf.WriteByte(b8)
+		buf.WriteByte(byte(n))
+	case n <= math.MaxUint16:
+		buf.WriteByte(b16)
+		_ = binary.Write(buf, binary.BigEndian, uint16(n))
+	default:
+		buf.WriteByte(b32)
+		_ = binary.Write(buf, binary.BigEndian, uint32(n))
+	}
+}
+
+type msgPackReader struct {
+	data []byte
+	pos  int
+}
+
+func (this *msgPackReader) next(n int) ([]byte, error) {
+	if n < 0 || this.pos+n > len(this.data) {
+		return nil, fmt.Errorf("msgpack: unexpected end of data")
+	}
+	out := this.data[this.pos : this.pos+n]
+	this.pos += n
+	return out, nil
+}
+
+func (this *msgPackReader) uint(size int) (uint64, error) {
+	b, err := this.next(size)
+	if err != nil {
+		return 0, err
+	}
+	switch size {
+	case 1:
+		return uint64(b[0]), nil
+	case 2:
+		return uint64(binary.BigEndian.Uint16(b)), nil
+	case 4:
+		return uint64(binary.BigEndian.Uint32(b)), nil
+	default:
+		return binary.BigEndian.Uint64(b), nil
+	}
+}
+
+func (this *msgPackReader) value() (interface{}, error) {
+	b, err := this.next(1)
+	if err != nil {
+		return nil, err
+	}
+	c := b[0]
+
+	switch {
+	case c <= 0x7f:
+		return int64(c), nil
+	case c >= 0xe0:
+		return int64(int8(c)), nil
+	case c&0xf0 == 0x80:
+		return this.mapOf(int(c & 0x0f))
+	case c&0xf0 == 0x90:
+		return this.array(int(c & 0x0f))
+	case c&0xe0 == 0xa0:
+		return this.str(int(c & 0x1f))
+	}
+
+	switch c {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+	case 0xc4, 0xc5, 0xc6:
+		n, err := this.uint(1 << (c - 0xc4))
+		if err != nil {
+			return nil, err
+		}
+		data, err := this.next(int(n))
+		if err != nil {
+			return nil, err
+		}
+		return append([]byte{}, data...), nil
+	case 0xca:
+		n, err := this.uint(4)
+		return float64(math.Float32frombits(uint32(n))), err
+	case 0xcb:
+		n, err := this.uint(8)
+		return math.Float64frombits(n), err
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		n, err := this.uint(1 << (c - 0xcc))
+		if err != nil {
+			return nil, err
+		}
+		if n > math.MaxInt64 {
+			return n, nil
+		}
+		return int64(n), nil
+	case 0xd0:
+		n, err := this.uint(1)
+		return int64(int8(n)), err
+	case 0xd1:
+		n, err := this.uint(2)
+		return int64(int16(n)), err
+	case 0xd2:
+		n, err := this.uint(4)
+		return int64(int32(n)), err
+	case 0xd3:
+		n, err := this.uint(8)
+		return int64(n), err
+	case 0xd9, 0xda, 0xdb:
+		n, err := this.uint(1 << (c - 0xd9))
+		if err != nil {
+			return nil, err
+		}
+		return this.str(int(n))
+	case 0xdc, 0xdd:
+		n, err := this.uint(2 << (c - 0xdc))
+		if err != nil {
+			return nil, err
+		}
+		return this.array(int(n))
+	case 0xde, 0xdf:
+		n, err := this.uint(2 << (c - 0xde))
+		if err != nil {
+			return nil, err
+		}
+		return this.mapOf(int(n))
+	}
+	return nil, fmt.Errorf("msgpack: unsupported type 0x%x", c)
+}
+
+func (this *msgPackReader) str(n int) (interface{}, error) {
+	data, err := this.next(n)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+func (this *msgPackReader) array(n int) (interface{}, error) {
+	if n > len(this.data)-this.pos {
+		return nil, fmt.Errorf("msgpack: unexpected end of data")
+	}
+	out := make([]interface{}, n)
+	for idx := range out {
+		v, err := this.value()
+		if err != nil {
+			return nil, err
+		}
+		out[idx] = v
+	}
+	return out, nil
+}
+
+func (this *msgPackReader) mapOf(n int) (interface{}, error) {
+	if n > len(this.data)-this.pos {
+		return nil, fmt.Errorf("msgpack: unexpected end of data")
+	}
+	keys := make([]interface{}, n)
+	values := make([]interface{}, n)
+	stringKeys := true
+	for idx := 0; idx < n; idx++ {
+		k, err := this.value()
+		if err != nil {
+			return nil, err
+		}
+		v, err := this.value()
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := k.(string); !ok {
+			stringKeys = false
+		}
+		keys[idx], values[idx] = k, v
+	}
+
+	if stringKeys {
+		out := make(map[string]interface{}, n)
+		for idx := range keys {
+			out[keys[idx].(string)] = values[idx]
+		}
+		return out, nil
+	}
+
+	out := make(map[interface{}]interface{}, n)
+	for idx := range keys {
+		if k := reflect.ValueOf(keys[idx]); k.IsValid() && !k.Type().Comparable() {
+			return nil, fmt.Errorf("msgpack: unsupported map key of type %T", keys[idx])
+		}
+		out[keys[idx]] = values[idx]
+	}
+	return out, nil
+}
@@ -0,0 +1,103 @@
+package codec
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMsgPack_Encode_KnownBytes(t *testing.T) {
+	c := MsgPack()
+	cases := []struct {
+		value    interface{}
+		expected []byte
+	}{
+		{nil, []byte{0xc0}},
+		{true, []byte{0xc3}},
+		{7, []byte{0x07}},
+		{-1, []byte{0xff}},
+		{200, []byte{0xcc, 0xc8}},
+		{-200, []byte{0xd1, 0xff, 0x38}},
+		{"abc", []byte{0xa3, 'a', 'b', 'c'}},
+		{[]byte{1, 2}, []byte{0xc4, 0x02, 0x01, 0x02}},
+		{[]int{1, 2}, []byte{0x92, 0x01, 0x02}},
+		{map[string]int{"a": 1}, []byte{0x81, 0xa1, 'a', 0x01}},
+		{1.5, []byte{0xcb, 0x3f, 0xf8, 0, 0, 0, 0, 0, 0}},
+	}
+
+	for _, tc := range cases {
+		data, err := c.Encode(tc.value)
+		assert.Nil(t, err)
+		assert.EqualValues(t, tc.expected, data, "%v", tc.value)
+	}
+}
+
+func TestMsgPack_RoundTrip_Generic(t *testing.T) {
+	c := MsgPack()
+	in := map[string]interface{}{
+		"nil":    nil,
+		"bool":   false,
+		"int":    -70000,
+		"uint":   uint64(math.MaxUint64),
+		"float":  float32(1.25),
+		"string": strings.Repeat("x", 300),
+		"list":   []interface{}{1, "two", []byte{3}},
+		"nested": map[int]string{1: "one"},
+	}
+
+	data, err := c.Encode(in)
+	assert.Nil(t, err)
+
+	var out interface{}
+	assert.Nil(t, c.Decode(data, &out))
+	assert.EqualValues(t, map[string]interface{}{
+		"nil":    nil,
+		"bool":   false,
+		"int":    int64(-70000),
+		"uint":   uint64(math.MaxUint64),
+		"float":  float64(1.25),
+		"string": strings.Repeat("x", 300),
+		"list":   []interface{}{int64(1), "two", []byte{3}},
+		"nested": map[interface{}]interface{}{int64(1): "one"},
+	}, out)
+}
+
+type msgPackStruct struct {
+	Name    string `msgpack:"name"`
+	Tags    []string
+	Created time.Time
+	Ptr     *int
+	Ignored string `msgpack:"-"`
+	private int
+}
+
+func TestMsgPack_RoundTrip_Struct(t *testing.T) {
+	c := MsgPack()
+	n := 5
+	in := msgPackStruct{Name: "n", Tags: []string{"a"}, Created: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC), Ptr: &n, Ignored: "x"}
+
+	data, err := c.Encode(in)
+	assert.Nil(t, err)
+
+	var out msgPackStruct
+	assert.Nil(t, c.Decode(data, &out))
+	assert.EqualValues(t, "n", out.Name)
+	assert.EqualValues(t, []string{"a"}, out.Tags)
+	assert.True(t, in.Created.Equal(out.Created))
+	assert.EqualValues(t, 5, *out.Ptr)
+	assert.Empty(t, out.Ignored)
+}
+
+func TestMsgPack_Decode_Errors(t *testing.T) {
+	c := MsgPack()
+	var out interface{}
+	assert.NotNil(t, c.Decode([]byte{0xa3, 'a'}, &out))
+	assert.NotNil(t, c.Decode([]byte{0x01, 0x02}, &out))
+	assert.NotNil(t, c.Decode([]byte{0x01}, out))
+
+	var s string
+	assert.NotNil(t, c.Decode([]byte{0x01}, &s))
+}
@@ -0,0 +1,309 @@
+package codec
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SchemaType is the type of the schemas stored in the schema registry
+type SchemaType string
+
+const (
+	AvroSchema     SchemaType = "AVRO"
+	ProtobufSchema SchemaType = "PROTOBUF"
+	JSONSchema     SchemaType = "JSON"
+)
+
+// Registry is a (Confluent compatible) schema registry client
+type Registry interface {
+	// Schema returns the schema registered with the given id
+	Schema(id int) (string, error)
+
+	// Register registers the schema under the given subject and returns its id,
+	// registering an existing schema returns the id of the existing schema.
+	Register(subject, schema string, schemaType SchemaType) (int, error)
+}
+
+type httpRegistry struct {
+	url    string
+	header http.Header
+	client *http.Client
+
+	mutex    *sync.Mutex
+	byId     map[int]string
+	bySchema map[string]int
+}
+
+// NewRegistry creates a client for the schema registry REST API, results are cached in memory.
+// The header is added to every request (e.g: basic authentication).
+func NewRegistry(url string, header http.Header, client *http.Client) Registry {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &httpRegistry{
+		url:      strings.TrimRight(url, "/"),
+		header:   header,
+		client:   client,
+		mutex:    &sync.Mutex{},
+		byId:     make(map[int]string),
+		bySchema: make(map[string]int),
+	}
+}
+
+type registrySchema struct {
+	Id         int        `json:"id,omitempty"`
+	Schema     string     `json:"schema,omitempty"`
+	SchemaType SchemaType `json:"schemaType,omitempty"`
+}
+
+func (this *httpRegistry) Schema(id int) (string, error) {
+	this.mutex.Lock()
+	schema, found := this.byId[id]
+	this.mutex.Unlock()
+	if found {
+		return schema, nil
+	}
+
+	var res registrySchema
+	if err := this.do(http.MethodGet, fmt.Sprintf("/schemas/ids/%d", id), nil, &res); err != nil {
+		return "", err
+	}
+
+	this.mutex.Lock()
+	this.byId[id] = res.Schema
+	this.mutex.Unlock()
+	return res.Schema, nil
+}
+
+func (this *httpRegistry) Register(subject, schema string, schemaType SchemaType) (int, error) {
+	cacheKey := subject + "\x00" + schema
+	this.mutex.Lock()
+	id, found := this.bySchema[cacheKey]
+	this.mutex.Unlock()
+	if found {
+		return id, nil
+	}
+
+	req := registrySchema{Schema: schema}
+	if schemaType != AvroSchema {
+		req.SchemaType = schemaType
+	}
+
+	var res registrySchema
+	if err := this.do(http.MethodPost, fmt.Sprintf("/subjects/%s/versions", url.PathEscape(subject)), &req, &res); err != nil {
+		return 0, err
+	}
+
+	this.mutex.Lock()
+	this.bySchema[cacheKey] = res.Id
+	this.byId[res.Id] = schema
+	this.mutex.Unlock()
+	return res.Id, nil
+}
+
+func (this *httpRegistry) do(method, path string, body interface{}, out interface{}) error {
+	var payload []byte
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		payload = data
+	}
+
+	req, err := http.NewRequest(method, this.url+path, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	for name, values := range this.header {
+		for _, v := range values {
+			req.Header.Add(name, v)
+		}
+	}
+	req.Header.Set("Content-Type", "application/vnd.schemaregistry.v1+json")
+
+	res, err := this.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	data, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+	if res.StatusCode >= 300 {
+		return fmt.Errorf("schema registry request %s %s failed with status %d: %s", method, path, res.StatusCode, string(data))
+	}
+	return json.Unmarshal(data, out)
+}
+
+// RegistryConfig configures a codec that uses the schema registry wire format
+type RegistryConfig struct {
+	Registry Registry
+
+	// Subject the writer schema is registered under (e.g: "orders-value")
+	Subject string
+
+	// Schema is the writer schema used by Encode, it is registered on the first Encode call.
+	Schema string
+
+	// SchemaType defaults to AVRO
+	SchemaType SchemaType
+
+	// Factory creates the codec of a schema, it defaults to Avro for AVRO schemas
+	// and JSON for JSON schemas, PROTOBUF schemas requires a factory (e.g: returning Protobuf()).
+	Factory func(schema string) (Codec, error)
+}
+
+type registryCodec struct {
+	config RegistryConfig
+
+	mutex  *sync.Mutex
+	id     int
+	codecs map[int]Codec
+}
+
+// NewRegistryCodec creates a codec that prefixes encoded payloads with the schema id
+// (magic byte, 4 bytes schema id and for protobuf the message indexes) and resolves
+// the schema of decoded payloads from the registry.
+func NewRegistryCodec(config RegistryConfig) (Codec, error) {
+	if config.Registry == nil {
+		return nil, fmt.Errorf("registry codec requires a registry")
+	}
+	if config.SchemaType == "" {
+		config.SchemaType = AvroSchema
+	}
+	if config.Factory == nil {
+		switch config.SchemaType {
+		case AvroSchema:
+			config.Factory = Avro
+		case JSONSchema:
+			config.Factory = func(string) (Codec, error) { return JSON(), nil }
+		default:
+			return nil, fmt.Errorf("registry codec requires a factory for %s schemas", config.SchemaType)
+		}
+	}
+	return &registryCodec{config: config, mutex: &sync.Mutex{}, id: -1, codecs: make(map[int]Codec)}, nil
+}
+
+func (this *registryCodec) Encode(v interface{}) ([]byte, error) {
+	id, codec, err := this.writer()
+	if err != nil {
+		return nil, err
+	}
+
+	payload, err := codec.Encode(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	buf.WriteByte(0)
+	_ = binary.Write(&buf, binary.BigEndian, uint32(id))
+	if this.config.SchemaType == ProtobufSchema {
+		// message indexes of the first message in the schema
+		buf.WriteByte(0)
+	}
+	buf.Write(payload)
+	return buf.Bytes(), nil
+}
+
+func (this *registryCodec) Decode(data []byte, v interface{}) error {
+	if len(data) < 5 || data[0] != 0 {
+		return fmt.Errorf("payload isn't in the schema registry wire format")
+	}
+	id := int(binary.BigEndian.Uint32(data[1:5]))
+	payload := data[5:]
+
+	if this.config.SchemaType == ProtobufSchema {
+		rest, err := skipMessageIndexes(payload)
+		if err != nil {
+			return err
+		}
+		payload = rest
+	}
+
+	codec, err := this.reader(id)
+	if err != nil {
+		return err
+	}
+	return codec.Decode(payload, v)
+}
+
+func (this *registryCodec) writer() (int, Codec, error) {
+	this.mutex.Lock()
+	id := this.id
+	this.mutex.Unlock()
+
+	if id < 0 {
+		registered, err := this.config.Registry.Register(this.config.Subject, this.config.Schema, this.config.SchemaType)
+		if err != nil {
+			return 0, nil, err
+		}
+		id = registered
+		this.mutex.Lock()
+		this.id = id
+		this.mutex.Unlock()
+	}
+
+	codec, err := this.codec(id, this.config.Schema)
+	return id, codec, err
+}
+
+func (this *registryCodec) reader(id int) (Codec, error) {
+	this.mutex.Lock()
+	codec, found := this.codecs[id]
+	this.mutex.Unlock()
+	if found {
+		return codec, nil
+	}
+
+	schema, err := this.config.Registry.Schema(id)
+	if err != nil {
+		return nil, err
+	}
+	return this.codec(id, schema)
+}
+
+func (this *registryCodec) codec(id int, schema string) (Codec, error) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	if codec, found := this.codecs[id]; found {
+		return codec, nil
+	}
+
+	codec, err := this.config.Factory(schema)
+	if err != nil {
+		return nil, err
+	}
+	this.codecs[id] = codec
+	return codec, nil
+}
+
+// skipMessageIndexes skips the protobuf message indexes (zigzag varint count followed by the indexes)
+func skipMessageIndexes(data []byte) ([]byte, error) {
+	count, size := binary.Varint(data)
+	if size <= 0 || count < 0 {
+		return nil, fmt.Errorf("invalid protobuf message indexes")
+	}
+	data = data[size:]
+
+	for i := int64(0); i < count; i++ {
+		_, size := binary.Varint(data)
+		if size <= 0 {
+			return nil, fmt.Errorf("invalid protobuf message indexes")
+		}
+		data = data[size:]
+	}
+	return data, nil
+}
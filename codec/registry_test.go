@@ -0,0 +1,106 @@
+package codec
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegistryCodec_Avro(t *testing.T) {
+	var registrations, lookups int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/subjects/users-value/versions":
+			atomic.AddInt32(&registrations, 1)
+			var req map[string]string
+			assert.Nil(t, json.NewDecoder(r.Body).Decode(&req))
+			assert.EqualValues(t, userSchema, req["schema"])
+			_, _ = w.Write([]byte(`{"id":42}`))
+		case r.Method == http.MethodGet && r.URL.Path == "/schemas/ids/7":
+			atomic.AddInt32(&lookups, 1)
+			schema, _ := json.Marshal(map[string]string{"schema": `{"type":"record","name":"r","fields":[{"name":"id","type":"long"}]}`})
+			_, _ = w.Write(schema)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	c, err := NewRegistryCodec(RegistryConfig{
+		Registry: NewRegistry(server.URL, nil, nil),
+		Subject:  "users-value",
+		Schema:   userSchema,
+	})
+	assert.Nil(t, err)
+
+	user := avroUser{Id: 1, Name: "john", Tags: []string{}, Attrs: map[string]int{}, Status: "ACTIVE"}
+	first, err := c.Encode(user)
+	assert.Nil(t, err)
+	_, err = c.Encode(user)
+	assert.Nil(t, err)
+	assert.EqualValues(t, []byte{0, 0, 0, 0, 42}, first[:5])
+	assert.EqualValues(t, 1, atomic.LoadInt32(&registrations))
+
+	// Decoding our own payload uses the cached writer schema
+	var out avroUser
+	assert.Nil(t, c.Decode(first, &out))
+	assert.EqualValues(t, user, out)
+
+	// Decoding a payload written with a different schema id fetches its schema
+	var other map[string]interface{}
+	assert.Nil(t, c.Decode([]byte{0, 0, 0, 0, 7, 0x08}, &other))
+	assert.Nil(t, c.Decode([]byte{0, 0, 0, 0, 7, 0x08}, &other))
+	assert.EqualValues(t, map[string]interface{}{"id": int64(4)}, other)
+	assert.EqualValues(t, 1, atomic.LoadInt32(&lookups))
+
+	assert.NotNil(t, c.Decode([]byte{1, 2}, &other))
+	assert.NotNil(t, c.Decode([]byte{0, 0, 0, 0, 9, 0x08}, &other))
+}
+
+func TestRegistryCodec_Protobuf(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]string
+		assert.Nil(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.EqualValues(t, "PROTOBUF", req["schemaType"])
+		_, _ = w.Write([]byte(`{"id":1}`))
+	}))
+	defer server.Close()
+
+	_, err := NewRegistryCodec(RegistryConfig{Registry: NewRegistry(server.URL, nil, nil), SchemaType: ProtobufSchema})
+	assert.NotNil(t, err)
+
+	c, err := NewRegistryCodec(RegistryConfig{
+		Registry:   NewRegistry(server.URL, nil, nil),
+		Subject:    "events-value",
+		Schema:     `syntax = "proto3"; message Event { string value = 1; }`,
+		SchemaType: ProtobufSchema,
+		Factory:    func(string) (Codec, error) { return Protobuf(), nil },
+	})
+	assert.Nil(t, err)
+
+	data, err := c.Encode(&fakeProto{value: "hi"})
+	assert.Nil(t, err)
+	assert.EqualValues(t, []byte{0, 0, 0, 0, 1, 0, 'h', 'i'}, data)
+
+	out := &fakeProto{}
+	assert.Nil(t, c.Decode(data, out))
+	assert.EqualValues(t, "hi", out.value)
+}
+
+func TestRegistry_Register_EscapesSubject(t *testing.T) {
+	var path string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.EscapedPath()
+		_, _ = w.Write([]byte(`{"id":1}`))
+	}))
+	defer server.Close()
+
+	id, err := NewRegistry(server.URL, nil, nil).Register("orders/v1?x", userSchema, AvroSchema)
+	assert.Nil(t, err)
+	assert.EqualValues(t, 1, id)
+	assert.EqualValues(t, "/subjects/orders%2Fv1%3Fx/versions", path)
+}
@@ -0,0 +1,227 @@
+package go_streams
+
+import (
+	"sync"
+	"time"
+)
+
+// CommitStrategy decides when the processors commit the entries of a stream,
+// by default every entry is committed as soon as it was written to the sinks.
+type CommitStrategy struct {
+	// Every commits the keys once every N entries were written (0 disables it)
+	Every int
+
+	// Interval commits the written keys periodically (0 disables it)
+	Interval time.Duration
+
+	// Async commits in the background, a key is committed only once all the keys received
+	// before it were processed (high watermark) so entries completed out of order (e.g: by
+	// an unordered MapAsync) are never committed before the ones that came first.
+	// Entries dropped by timed operators (e.g: superseded by Debounce) are never completed,
+	// they hold back the watermark so Async shouldn't be used with them.
+	Async bool
+}
+
+func CommitEveryN(n int) CommitStrategy {
+	return CommitStrategy{Every: n}
+}
+
+func CommitEveryInterval(interval time.Duration) CommitStrategy {
+	return CommitStrategy{Interval: interval}
+}
+
+func CommitAsync() CommitStrategy {
+	return CommitStrategy{Async: true}
+}
+
+// committer commits the keys of a stream according to its commit strategy
+type committer struct {
+	source   Source
+	strategy CommitStrategy
+	reporter *errorReporter
+
+	mutex      *sync.Mutex
+	pending    []string
+	lastCommit time.Time
+
+	// async only, the keys received in order and the count of received and completed keys
+	order     []string
+	tracked   map[string]int
+	completed map[string]int
+	commits   chan []string
+	stop      chan struct{}
+	ticking   *sync.WaitGroup
+	done      *sync.WaitGroup
+}
+
+func newCommitter(stream Stream, reporter *errorReporter) *committer {
+	var strategy CommitStrategy
+	if s := stream.GetCommitStrategy(); s != nil {
+		strategy = *s
+	}
+	return newStrategyCommitter(stream.GetSource(), strategy, reporter)
+}
+
+func newStrategyCommitter(source Source, strategy CommitStrategy, reporter *errorReporter) *committer {
+	this := &committer{
+		source:     source,
+		strategy:   strategy,
+		reporter:   reporter,
+		mutex:      &sync.Mutex{},
+		lastCommit: time.Now(),
+		tracked:    make(map[string]int),
+		completed:  make(map[string]int),
+		stop:       make(chan struct{}),
+		ticking:    &sync.WaitGroup{},
+		done:       &sync.WaitGroup{},
+	}
+	if this.strategy.Async {
+		this.commits = make(chan []string, 16)
+		this.done.Add(1)
+		go this.commitInBackground()
+	}
+	if this.strategy.Interval > 0 {
+		this.ticking.Add(1)
+		go this.commitPeriodically()
+	}
+	return this
+}
+
+func (this *committer) async() bool {
+	return this.strategy.Async
+}
+
+// track registers a key received from the source (async only)
+func (this *committer) track(keys ...string) {
+	if !this.strategy.Async {
+		return
+	}
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.order = append(this.order, keys...)
+	for _, key := range keys {
+		this.tracked[key]++
+	}
+}
+
+// complete commits the keys of written entries according to the strategy, it returns
+// the commit error when the keys were committed synchronously.
+func (this *committer) complete(keys ...string) error {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	immediate := this.strategy.Every == 0 && this.strategy.Interval == 0
+	if this.strategy.Async {
+		this.markCompleted(keys)
+	} else if immediate && len(this.pending) == 0 {
+		// nothing is held back, the keys are committed without being copied to the pending keys
+		this.lastCommit = time.Now()
+		return this.source.CommitEntry(keys...)
+	} else {
+		this.pending = append(this.pending, keys...)
+	}
+
+	due := immediate
+	due = due || (this.strategy.Every > 0 && len(this.pending) >= this.strategy.Every)
+	due = due || (this.strategy.Interval > 0 && time.Since(this.lastCommit) >= this.strategy.Interval)
+	if !due {
+		return nil
+	}
+	return this.commit()
+}
+
+// skip completes the keys of entries that won't be written (e.g: filtered), they are
+// committed with the watermark of async strategies and ignored otherwise.
+func (this *committer) skip(keys ...string) {
+	if !this.strategy.Async {
+		return
+	}
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.markCompleted(keys)
+}
+
+// markCompleted advances the watermark, the keys below it are pending
+func (this *committer) markCompleted(keys []string) {
+	for _, key := range keys {
+		// entries written by several sinks are completed several times
+		if this.completed[key] < this.tracked[key] {
+			this.completed[key]++
+		}
+	}
+	idx := 0
+	for ; idx < len(this.order); idx++ {
+		key := this.order[idx]
+		if this.completed[key] == 0 {
+			break
+		}
+		if this.completed[key]--; this.completed[key] == 0 {
+			delete(this.completed, key)
+		}
+		if this.tracked[key]--; this.tracked[key] == 0 {
+			delete(this.tracked, key)
+		}
+		this.pending = append(this.pending, key)
+	}
+	this.order = this.order[idx:]
+}
+
+// commit commits the pending keys, the caller holds the mutex
+func (this *committer) commit() error {
+	this.lastCommit = time.Now()
+	if len(this.pending) == 0 {
+		return nil
+	}
+	keys := this.pending
+	this.pending = nil
+
+	if this.strategy.Async {
+		this.commits <- keys
+		return nil
+	}
+	return this.source.CommitEntry(keys...)
+}
+
+func (this *committer) commitInBackground() {
+	defer this.done.Done()
+	for keys := range this.commits {
+		this.reporter.report(CommitStage, keys[len(keys)-1], this.source.CommitEntry(keys...))
+	}
+}
+
+func (this *committer) commitPeriodically() {
+	defer this.ticking.Done()
+	ticker := time.NewTicker(this.strategy.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-this.stop:
+			return
+		case <-ticker.C:
+			this.mutex.Lock()
+			var err error
+			if time.Since(this.lastCommit) >= this.strategy.Interval {
+				err = this.commit()
+			}
+			this.mutex.Unlock()
+			this.reporter.report(CommitStage, "", err)
+		}
+	}
+}
+
+// close commits the pending keys and waits for the background commits
+func (this *committer) close() {
+	close(this.stop)
+	this.ticking.Wait()
+
+	this.mutex.Lock()
+	err := this.commit()
+	this.mutex.Unlock()
+	this.reporter.report(CommitStage, "", err)
+
+	if this.strategy.Async {
+		close(this.commits)
+	}
+	this.done.Wait()
+}
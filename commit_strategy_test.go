@@ -0,0 +1,110 @@
+package go_streams
+
+import (
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"testing"
+	"time"
+)
+
+// commitRecordingSource records the CommitEntry calls of its source
+type commitRecordingSource struct {
+	Source
+	mutex   *sync.Mutex
+	commits [][]string
+}
+
+func newCommitRecordingSource(source Source) *commitRecordingSource {
+	return &commitRecordingSource{Source: source, mutex: &sync.Mutex{}}
+}
+
+func (this *commitRecordingSource) CommitEntry(keys ...string) error {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.commits = append(this.commits, append([]string{}, keys...))
+	return nil
+}
+
+func (this *commitRecordingSource) calls() [][]string {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	return this.commits
+}
+
+func (this *commitRecordingSource) keys() []string {
+	var keys []string
+	for _, call := range this.calls() {
+		keys = append(keys, call...)
+	}
+	return keys
+}
+
+func TestCommitStrategy_Default_CommitsEveryEntry(t *testing.T) {
+	source := newCommitRecordingSource(NewSequentialIntegerSource(4, 0))
+	NewStream(source).Sink(NewArraySink()).Process(NewDirectProcessor(), make(ErrorChannel, 10))
+
+	assert.Len(t, source.calls(), 5)
+}
+
+func TestCommitStrategy_EveryN(t *testing.T) {
+	source := newCommitRecordingSource(NewSequentialIntegerSource(6, 0))
+	NewStream(source).CommitWith(CommitEveryN(3)).Sink(NewArraySink()).
+		Process(NewDirectProcessor(), make(ErrorChannel, 10))
+
+	// 7 entries: two batches of 3 and the remaining one once the stream ended
+	assert.EqualValues(t, [][]string{{"0", "1", "2"}, {"3", "4", "5"}, {"6"}}, source.calls())
+}
+
+func TestCommitStrategy_Interval(t *testing.T) {
+	source := newCommitRecordingSource(NewSequentialIntegerSource(10, 5*time.Millisecond))
+	NewStream(source).CommitWith(CommitEveryInterval(20*time.Millisecond)).Sink(NewArraySink()).
+		Process(NewDirectProcessor(), make(ErrorChannel, 10))
+
+	calls := source.calls()
+	assert.True(t, len(calls) > 1 && len(calls) < 11)
+	assert.Len(t, source.keys(), 11)
+}
+
+func TestCommitStrategy_Async_CommitsContiguousKeys(t *testing.T) {
+	source := newCommitRecordingSource(NewSequentialIntegerSource(9, 0))
+	NewStream(source).CommitWith(CommitAsync()).
+		Filter(func(entry interface{}) bool { return entry.(int) != 4 }).
+		MapAsync(func(entry interface{}) (interface{}, error) {
+			// later entries finish first
+			time.Sleep(time.Duration(10-entry.(int)) * time.Millisecond)
+			return entry, nil
+		}, 4, false).
+		Sink(NewArraySink()).
+		Process(NewDirectProcessor(), make(ErrorChannel, 10))
+
+	// keys are committed in the order they were received even though they completed out of order
+	assert.EqualValues(t, []string{"0", "1", "2", "3", "4", "5", "6", "7", "8", "9"}, source.keys())
+}
+
+func TestCommitStrategy_Async_SkipsFilteredEntries(t *testing.T) {
+	source := newCommitRecordingSource(NewSequentialIntegerSource(9, 0))
+	sink := NewArraySink()
+	NewStream(source).CommitWith(CommitAsync()).
+		// the second batch is filtered out entirely
+		Filter(func(entry interface{}) bool { return entry.(int) < 5 && entry.(int)%2 == 0 }).
+		Sink(sink).
+		Process(NewBufferedProcessor(5, time.Second), make(ErrorChannel, 10))
+
+	assert.EqualValues(t, []interface{}{0, 2, 4}, sink.Array())
+	assert.EqualValues(t, []string{"0", "1", "2", "3", "4", "5", "6", "7", "8", "9"}, source.keys())
+}
+
+func TestCommitter_Watermark(t *testing.T) {
+	source := newCommitRecordingSource(NewSequentialIntegerSource(0, 0))
+	stream := NewStream(source).CommitWith(CommitStrategy{Async: true, Every: 1})
+	committer := newCommitter(stream, newErrorReporter(stream, make(ErrorChannel, 10)))
+
+	committer.track("a", "b", "c")
+	assert.Nil(t, committer.complete("b"))
+	assert.Nil(t, committer.complete("c"))
+	assert.Empty(t, committer.pending)
+
+	assert.Nil(t, committer.complete("a"))
+	committer.close()
+	assert.EqualValues(t, []string{"a", "b", "c"}, source.keys())
+}
@@ -0,0 +1,419 @@
+package go_streams
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	defaultGroupInterval = time.Second
+	defaultLeaseTTL      = 10 * time.Second
+)
+
+// Coordinator splits the partitions of a group between its members, the instances of a horizontally scaled
+// process running the same streams (see NewGroup)
+type Coordinator interface {
+	// Assign renews the membership of the member and returns the partitions it owns, it is called periodically
+	Assign(group, member string, partitions int) ([]int, error)
+
+	// Release gives up the partitions revoked from the member once their streams stopped
+	Release(group, member string, partitions []int) error
+
+	// Leave removes the member from the group, its partitions are assigned to the other members
+	Leave(group, member string) error
+}
+
+type passthroughCoordinator struct{}
+
+// Passthrough assigns all the partitions to every member, for sources that split the partitions
+// between the instances on their own (e.g: a Kafka consumer group) so the group runs a single stream per instance.
+func Passthrough() Coordinator {
+	return &passthroughCoordinator{}
+}
+
+func (this *passthroughCoordinator) Assign(group, member string, partitions int) ([]int, error) {
+	assigned := make([]int, partitions)
+	for p := range assigned {
+		assigned[p] = p
+	}
+	return assigned, nil
+}
+
+func (this *passthroughCoordinator) Release(group, member string, partitions []int) error {
+	return nil
+}
+
+func (this *passthroughCoordinator) Leave(group, member string) error {
+	return nil
+}
+
+// LeaseStore holds the leases of a lease coordinator, e.g: Redis (SET NX PX and a ZSET of member expiries)
+// or etcd (keys attached to leases and transactions). See NewMemoryLeaseStore for a single process.
+type LeaseStore interface {
+	// Acquire takes the lease of the key for the owner or renews it, it returns false when another owner holds it
+	Acquire(key, owner string, ttl time.Duration) (bool, error)
+
+	// Release gives up the lease of the key when the owner holds it
+	Release(key, owner string) error
+
+	// Heartbeat registers the member in the group for the ttl
+	Heartbeat(group, member string, ttl time.Duration) error
+
+	// Members returns the members of the group whose heartbeat didn't expire
+	Members(group string) ([]string, error)
+
+	// Leave removes the member from the group
+	Leave(group, member string) error
+}
+
+type leaseCoordinator struct {
+	store LeaseStore
+	ttl   time.Duration
+}
+
+// NewLeaseCoordinator creates a coordinator whose members heartbeat in the store and hold a lease on each of
+// their partitions: partitions are assigned by rendezvous hashing of the live members (only the partitions of the
+// members that joined or left move), and a member only owns a partition once its previous owner released
+// the lease or it expired. A member that crashed loses its partitions after the ttl (defaults to 10s),
+// Assign should be called at least every third of it.
+func NewLeaseCoordinator(store LeaseStore, ttl time.Duration) Coordinator {
+	if ttl <= 0 {
+		ttl = defaultLeaseTTL
+	}
+	return &leaseCoordinator{store: store, ttl: ttl}
+}
+
+func (this *leaseCoordinator) Assign(group, member string, partitions int) ([]int, error) {
+	if err := this.store.Heartbeat(group, member, this.ttl); err != nil {
+		return nil, err
+	}
+	members, err := this.store.Members(group)
+	if err != nil {
+		return nil, err
+	}
+	if !containsString(members, member) {
+		members = append(members, member)
+	}
+
+	var owned []int
+	for p := 0; p < partitions; p++ {
+		if rendezvous(members, p) != member {
+			continue
+		}
+		held, err := this.store.Acquire(partitionLease(group, p), member, this.ttl)
+		if err != nil {
+			return nil, err
+		}
+		if held {
+			owned = append(owned, p)
+		}
+	}
+	return owned, nil
+}
+
+func (this *leaseCoordinator) Release(group, member string, partitions []int) error {
+	for _, p := range partitions {
+		if err := this.store.Release(partitionLease(group, p), member); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (this *leaseCoordinator) Leave(group, member string) error {
+	return this.store.Leave(group, member)
+}
+
+func partitionLease(group string, partition int) string {
+	return group + "/partitions/" + strconv.Itoa(partition)
+}
+
+// rendezvous returns the member with the highest hash for the partition
+func rendezvous(members []string, partition int) string {
+	var owner string
+	var highest uint64
+	for _, member := range members {
+		h := fnv.New64a()
+		_, _ = h.Write([]byte(member + "/" + strconv.Itoa(partition)))
+		if sum := mix(h.Sum64()); owner == "" || sum > highest || (sum == highest && member < owner) {
+			owner, highest = member, sum
+		}
+	}
+	return owner
+}
+
+// mix spreads the bits of a hash (the finalizer of murmur3), fnv hashes of keys differing by their
+// last bytes are close
+func mix(h uint64) uint64 {
+	h ^= h >> 33
+	h *= 0xff51afd7ed558ccd
+	h ^= h >> 33
+	h *= 0xc4ceb9fe1a85ec53
+	h ^= h >> 33
+	return h
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+type memoryLease struct {
+	owner   string
+	expires time.Time
+}
+
+// memoryLeaseStore keeps the leases in memory, e.g: for the groups of a single process or tests
+type memoryLeaseStore struct {
+	mutex   *sync.Mutex
+	leases  map[string]memoryLease
+	members map[string]map[string]time.Time
+}
+
+func NewMemoryLeaseStore() LeaseStore {
+	return &memoryLeaseStore{mutex: &sync.Mutex{}, leases: make(map[string]memoryLease), members: make(map[string]map[string]time.Time)}
+}
+
+func (this *memoryLeaseStore) Acquire(key, owner string, ttl time.Duration) (bool, error) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	now := time.Now()
+	if lease, found := this.leases[key]; found && lease.owner != owner && now.Before(lease.expires) {
+		return false, nil
+	}
+	this.leases[key] = memoryLease{owner: owner, expires: now.Add(ttl)}
+	return true, nil
+}
+
+func (this *memoryLeaseStore) Release(key, owner string) error {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	if lease, found := this.leases[key]; found && lease.owner == owner {
+		delete(this.leases, key)
+	}
+	return nil
+}
+
+func (this *memoryLeaseStore) Heartbeat(group, member string, ttl time.Duration) error {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	if this.members[group] == nil {
+		this.members[group] = make(map[string]time.Time)
+	}
+	this.members[group][member] = time.Now().Add(ttl)
+	return nil
+}
+
+func (this *memoryLeaseStore) Members(group string) ([]string, error) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	now := time.Now()
+	var members []string
+	for member, expires := range this.members[group] {
+		if now.Before(expires) {
+			members = append(members, member)
+		} else {
+			delete(this.members[group], member)
+		}
+	}
+	sort.Strings(members)
+	return members, nil
+}
+
+func (this *memoryLeaseStore) Leave(group, member string) error {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	delete(this.members[group], member)
+	return nil
+}
+
+// GroupConfig configures a Group
+type GroupConfig struct {
+	// Name of the group, shared by all its members
+	Name string
+
+	// Member identifies this instance in the group (e.g: the pod name)
+	Member string
+
+	// Partitions is the number of partitions of the source
+	Partitions int
+
+	// Stream creates the stream of a partition, its source should only read the partition and have a name unique to it
+	Stream func(partition int) Stream
+
+	Coordinator Coordinator
+
+	// Interval is the time between the assignments of the coordinator (defaults to 1s)
+	Interval time.Duration
+}
+
+// Group runs the streams of the partitions the coordinator assigns to this instance on an engine: the streams of
+// new partitions are added and the ones of revoked partitions are removed (their entries are processed) before
+// the partition is released to its next owner. Streams that completed aren't restarted while they stay assigned.
+type Group struct {
+	engine Engine
+	config GroupConfig
+
+	mutex   *sync.Mutex
+	running map[int]string
+
+	startOnce *sync.Once
+	stopOnce  *sync.Once
+	closeCh   chan struct{}
+	done      chan struct{}
+}
+
+func NewGroup(engine Engine, config GroupConfig) (*Group, error) {
+	if config.Name == "" || config.Member == "" || config.Partitions <= 0 {
+		return nil, fmt.Errorf("group requires a name, a member and partitions")
+	}
+	if config.Stream == nil || config.Coordinator == nil {
+		return nil, fmt.Errorf("group requires a stream factory and a coordinator")
+	}
+	if config.Interval <= 0 {
+		config.Interval = defaultGroupInterval
+	}
+	return &Group{
+		engine:    engine,
+		config:    config,
+		mutex:     &sync.Mutex{},
+		running:   make(map[int]string),
+		startOnce: &sync.Once{},
+		stopOnce:  &sync.Once{},
+		closeCh:   make(chan struct{}),
+		done:      make(chan struct{}),
+	}, nil
+}
+
+// Start joins the group and rebalances the partitions every interval in the background,
+// the engine should keep alive (see Engine.SetKeepAlive) while it has no partition.
+func (this *Group) Start() {
+	this.startOnce.Do(func() {
+		go this.coordinate()
+	})
+}
+
+// Stop removes the streams of the partitions, releases them and leaves the group
+func (this *Group) Stop() error {
+	var err error
+	this.stopOnce.Do(func() {
+		close(this.closeCh)
+		this.startOnce.Do(func() { close(this.done) })
+		<-this.done
+
+		this.mutex.Lock()
+		defer this.mutex.Unlock()
+		err = this.revoke(this.sortedRunning())
+		if leaveErr := this.config.Coordinator.Leave(this.config.Name, this.config.Member); err == nil {
+			err = leaveErr
+		}
+	})
+	return err
+}
+
+// Assigned returns the partitions whose streams run on this instance
+func (this *Group) Assigned() []int {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	return this.sortedRunning()
+}
+
+func (this *Group) coordinate() {
+	defer close(this.done)
+	ticker := time.NewTicker(this.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := this.Rebalance(); err != nil {
+			this.logger().Error("Failed to rebalance: %s", err.Error())
+		}
+		select {
+		case <-this.closeCh:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Rebalance applies the assignment of the coordinator right away, it is called every interval once started
+func (this *Group) Rebalance() error {
+	owned, err := this.config.Coordinator.Assign(this.config.Name, this.config.Member, this.config.Partitions)
+	if err != nil {
+		return err
+	}
+
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	assigned := make(map[int]bool, len(owned))
+	for _, p := range owned {
+		assigned[p] = true
+	}
+	var revoked []int
+	for _, p := range this.sortedRunning() {
+		if !assigned[p] {
+			revoked = append(revoked, p)
+		}
+	}
+	if err := this.revoke(revoked); err != nil {
+		return err
+	}
+
+	for _, p := range owned {
+		if _, found := this.running[p]; found {
+			continue
+		}
+		stream := this.config.Stream(p)
+		if err := this.engine.Add(stream); err != nil {
+			return err
+		}
+		this.running[p] = stream.GetSource().Name()
+		this.logger().Info("Partition %d assigned", p)
+	}
+	return nil
+}
+
+// revoke removes the streams of the partitions and releases them, the caller holds the mutex
+func (this *Group) revoke(partitions []int) error {
+	var released []int
+	var failed error
+	for _, p := range partitions {
+		err := this.engine.Remove(this.running[p])
+		if _, unknown := err.(*UnknownStreamError); err != nil && !unknown {
+			if failed == nil {
+				failed = err
+			}
+			continue
+		}
+		delete(this.running, p)
+		released = append(released, p)
+		this.logger().Info("Partition %d revoked", p)
+	}
+	if len(released) > 0 {
+		if err := this.config.Coordinator.Release(this.config.Name, this.config.Member, released); err != nil && failed == nil {
+			failed = err
+		}
+	}
+	return failed
+}
+
+// sortedRunning returns the partitions whose streams run, the caller holds the mutex
+func (this *Group) sortedRunning() []int {
+	partitions := make([]int, 0, len(this.running))
+	for p := range this.running {
+		partitions = append(partitions, p)
+	}
+	sort.Ints(partitions)
+	return partitions
+}
+
+func (this *Group) logger() Logger {
+	return LogWith(Fields{"group": this.config.Name, "member": this.config.Member})
+}
@@ -0,0 +1,126 @@
+package go_streams
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLeaseCoordinator_SplitsAndFailsOver(t *testing.T) {
+	coordinator := NewLeaseCoordinator(NewMemoryLeaseStore(), 100*time.Millisecond)
+	assign := func(member string) []int {
+		owned, err := coordinator.Assign("group", member, 8)
+		assert.Nil(t, err)
+		return owned
+	}
+
+	assert.EqualValues(t, []int{0, 1, 2, 3, 4, 5, 6, 7}, assign("a"))
+	// the partitions of b are still leased to a
+	assert.Empty(t, assign("b"))
+
+	kept := assign("a")
+	assert.NotEmpty(t, kept)
+	assert.True(t, len(kept) < 8)
+	assert.Nil(t, coordinator.Release("group", "a", without([]int{0, 1, 2, 3, 4, 5, 6, 7}, kept)))
+	assert.EqualValues(t, without([]int{0, 1, 2, 3, 4, 5, 6, 7}, kept), assign("b"))
+
+	// b crashed, its heartbeat and leases expire
+	time.Sleep(150 * time.Millisecond)
+	assert.EqualValues(t, []int{0, 1, 2, 3, 4, 5, 6, 7}, assign("a"))
+
+	assert.Nil(t, coordinator.Leave("group", "a"))
+}
+
+func TestPassthrough_AssignsAllPartitions(t *testing.T) {
+	owned, err := Passthrough().Assign("group", "a", 3)
+	assert.Nil(t, err)
+	assert.EqualValues(t, []int{0, 1, 2}, owned)
+}
+
+func TestGroup_RebalancesStreamsBetweenEngines(t *testing.T) {
+	store := NewMemoryLeaseStore()
+	first, second := newGroupEngine(t, store, "first"), newGroupEngine(t, store, "second")
+
+	assert.Nil(t, first.group.Rebalance())
+	assert.EqualValues(t, []int{0, 1, 2, 3}, first.group.Assigned())
+	assert.Nil(t, second.group.Rebalance())
+	assert.Empty(t, second.group.Assigned())
+
+	// first releases the partitions of second, which takes them on its next rebalance
+	assert.Nil(t, first.group.Rebalance())
+	assert.Nil(t, second.group.Rebalance())
+	all := append(first.group.Assigned(), second.group.Assigned()...)
+	sort.Ints(all)
+	assert.EqualValues(t, []int{0, 1, 2, 3}, all)
+	assert.NotEmpty(t, first.group.Assigned())
+	assert.NotEmpty(t, second.group.Assigned())
+	assert.Len(t, first.engine.Streams(), len(first.group.Assigned()))
+
+	assert.Nil(t, second.group.Stop())
+	assert.Empty(t, second.engine.Streams())
+	assert.Nil(t, first.group.Rebalance())
+	assert.EqualValues(t, []int{0, 1, 2, 3}, first.group.Assigned())
+	assert.Len(t, first.engine.Streams(), 4)
+
+	assert.Nil(t, first.group.Stop())
+	first.engine.Stop()
+	second.engine.Stop()
+}
+
+func TestNewGroup_Validation(t *testing.T) {
+	engine := NewEngine(NewDirectProcessorFactory(), time.Second)
+	_, err := NewGroup(engine, GroupConfig{Name: "group", Member: "a", Coordinator: Passthrough()})
+	assert.NotNil(t, err)
+	_, err = NewGroup(engine, GroupConfig{Name: "group", Member: "a", Partitions: 1})
+	assert.NotNil(t, err)
+}
+
+type groupEngine struct {
+	engine *engine
+	group  *Group
+}
+
+func newGroupEngine(t *testing.T, store LeaseStore, member string) *groupEngine {
+	engine := NewEngine(NewDirectProcessorFactory(), 10*time.Second)
+	engine.SetKeepAlive(true)
+	go engine.Start()
+
+	group, err := NewGroup(engine, GroupConfig{
+		Name:        "group",
+		Member:      member,
+		Partitions:  4,
+		Coordinator: NewLeaseCoordinator(store, time.Minute),
+		Stream: func(partition int) Stream {
+			return NewStream(&partitionSource{AppendSource: NewAppendSource(1), name: fmt.Sprintf("%s-%d", member, partition)}).Sink(NewArraySink())
+		},
+	})
+	assert.Nil(t, err)
+	return &groupEngine{engine: engine, group: group}
+}
+
+// partitionSource is an append source named after its partition
+type partitionSource struct {
+	*AppendSource
+	name string
+}
+
+func (this *partitionSource) Name() string {
+	return this.name
+}
+
+func without(values []int, excluded []int) []int {
+	var out []int
+	for _, v := range values {
+		found := false
+		for _, e := range excluded {
+			found = found || e == v
+		}
+		if !found {
+			out = append(out, v)
+		}
+	}
+	return out
+}
@@ -0,0 +1,40 @@
+package go_streams
+
+// Metadata keys attached to the entries written to the dead letter sink of a stream
+const (
+	MetadataDeadLetterStage = "deadLetterStage"
+	MetadataDeadLetterError = "deadLetterError"
+)
+
+// deadLetter writes the entries whose handler panicked or timed out to the dead letter sink of the stream,
+// it returns true when they were written and should be skipped by the rest of the pipeline.
+func deadLetter(stream Stream, stage Stage, cause error, reporter *errorReporter, entries ...Entry) bool {
+	sink := stream.GetDeadLetter()
+	if sink == nil || len(entries) == 0 || (!isPanic(cause) && !isTimeout(cause)) {
+		return false
+	}
+
+	letters := make([]Entry, len(entries))
+	for idx, entry := range entries {
+		metadata := make(map[string]string, len(entry.Metadata)+2)
+		for k, v := range entry.Metadata {
+			metadata[k] = v
+		}
+		metadata[MetadataDeadLetterStage] = string(stage)
+		metadata[MetadataDeadLetterError] = cause.Error()
+		entry.Metadata = metadata
+		letters[idx] = entry
+	}
+
+	var err error
+	if len(letters) == 1 {
+		_, err = recoverSinkSingle(sink, letters[0])
+	} else {
+		_, err = recoverSinkBatch(sink, letters)
+	}
+	if err != nil {
+		reporter.report(DeadLetterStage, letters[0].Key, err)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,49 @@
+package go_streams
+
+import "time"
+
+// defaultDedupeKeys bounds the memory store of Stream.Dedupe
+const defaultDedupeKeys = 100000
+
+// dedupe drops the entries whose key was already seen within the ttl
+type dedupe struct {
+	key   KeyFunc
+	ttl   time.Duration
+	store StateStore
+}
+
+// NewDedupe creates an operator that drops entries whose key was seen within the ttl,
+// the seen keys are kept in the given store (use a persistent store to survive restarts).
+func NewDedupe(key KeyFunc, ttl time.Duration, store StateStore) Operator {
+	return &dedupe{key: key, ttl: ttl, store: store}
+}
+
+func (this *dedupe) Apply(entry Entry) ([]Entry, error) {
+	key := this.key(entry.Value)
+	_, seen, err := this.store.Get(key)
+	if err != nil {
+		// let the entry pass, delivering it twice is better than losing it
+		return []Entry{entry}, err
+	}
+	if seen {
+		return nil, nil
+	}
+	return []Entry{entry}, this.store.Put(key, true, this.ttl)
+}
+
+// Snapshot encodes the seen keys when the store is a Snapshotter (e.g: the memory store),
+// persistent stores keep them already.
+func (this *dedupe) Snapshot() ([]byte, error) {
+	if store, ok := this.store.(Snapshotter); ok {
+		return store.Snapshot()
+	}
+	return nil, nil
+}
+
+// Restore replaces the seen keys by a snapshot
+func (this *dedupe) Restore(data []byte) error {
+	if store, ok := this.store.(Snapshotter); ok && data != nil {
+		return store.Restore(data)
+	}
+	return nil
+}
@@ -3,7 +3,8 @@ package go_streams
 import "log"
 
 type directProcessor struct {
-	entryCh EntryChannel
+	entryCh   EntryChannel
+	committer *committer
 }
 
 func NewDirectProcessor() *directProcessor {
@@ -17,45 +18,124 @@ func NewDirectProcessorFactory() ProcessorFactory {
 }
 
 func (this *directProcessor) Process(stream Stream, errs ErrorChannel) {
-	logger.Info("Starting to process stream with direct processor")
-	handlers := stream.GetHandlers()
+	streamLogger := streamLog(stream)
+	streamLogger.Info("Starting to process stream with direct processor")
+	handlers, acking := bindSinks(stream)
+	reporter := newErrorReporter(stream, errs)
+	this.committer = newCommitter(stream, reporter)
+	slot, inFlight := scheduled(stream), inFlightOf(stream)
 
 	// Notify the source to start sending entries to the channel:
-	go stream.GetSource().Start(this.entryCh, errs)
+	reporter.startSource(stream.GetSource(), this.entryCh)
+	ticks, stopTicks := tickChannel(handlers)
+	defer stopTicks()
 
-EntryLoop:
 	for {
-		entry, ok := <-this.entryCh
-		if !ok {
-			break
+		select {
+		case now := <-ticks:
+			this.tick(stream, handlers, acking, reporter, func(op TimedOperator) ([]Entry, error) { return op.Tick(now) })
+			continue
+		case entry, ok := <-this.entryCh:
+			if !ok {
+				this.tick(stream, handlers, acking, reporter, TimedOperator.Drain)
+				flushSinks(handlers, reporter)
+				this.committer.close()
+				reporter.wait()
+				streamLogger.Info("Done processing stream with direct processor")
+				return
+			}
+			if !acking {
+				this.committer.track(entry.Key)
+			}
+			slot.acquire()
+			this.process(stream, entry, handlers, acking, true, reporter)
+			slot.release()
+			inFlight.release(1)
 		}
+	}
+}
 
-		// Process the entry:
-		for idx := range handlers {
-			switch handler := handlers[idx].(type) {
-			case FilterFunc:
-				if !RecoverFilter(handler, entry, errs) {
-					entry.Filtered = true
-					continue EntryLoop
-				}
+// tick passes the entries emitted by the timed operators to the handlers that come after them,
+// they are committed only by async commit strategies (the watermark keeps the commits in order)
+func (this *directProcessor) tick(stream Stream, handlers []interface{}, acking bool, reporter *errorReporter, emit func(TimedOperator) ([]Entry, error)) {
+	for idx := range handlers {
+		if op, ok := handlers[idx].(TimedOperator); ok {
+			entries, err := emit(op)
+			reporter.report(OperatorStage, "", err)
+			for _, entry := range entries {
+				this.process(stream, entry, handlers[idx+1:], acking, this.committer.async(), reporter)
+			}
+		}
+	}
+}
 
-			case MapFunc:
-				entry.Value = RecoverMap(handler, entry, errs)
+// process passes the entry through the handlers, the entry is committed by each sink when commit is true
+func (this *directProcessor) process(stream Stream, entry Entry, handlers []interface{}, acking bool, commit bool, reporter *errorReporter) {
+	for idx := range handlers {
+		switch handler := handlers[idx].(type) {
+		case FilterFunc:
+			keep, err := recoverFilter(handler, entry)
+			reporter.report(FilterStage, entry.Key, err)
+			if deadLetter(stream, FilterStage, err, reporter, entry) {
+				this.commitDeadLetter(entry, acking, commit, reporter)
+				return
+			}
+			if !keep {
+				entry.Filtered = true
+				this.committer.skip(entry.Key)
+				return
+			}
 
-			case Sink:
-				if err := RecoverSinkSingle(handler, entry, errs); err != nil {
-					errs <- err
-				} else {
-					if err := stream.GetSource().CommitEntry(entry.Key); err != nil {
-						errs <- err
-					}
-				}
+		case MapFunc:
+			value, err := recoverMap(handler, entry)
+			reporter.report(MapStage, entry.Key, err)
+			if deadLetter(stream, MapStage, err, reporter, entry) {
+				this.commitDeadLetter(entry, acking, commit, reporter)
+				return
+			}
+			entry.Value = value
+
+		case Operator:
+			entries, err := recoverOperator(handler, entry)
+			reporter.report(OperatorStage, entry.Key, err)
+			if deadLetter(stream, OperatorStage, err, reporter, entry) {
+				this.commitDeadLetter(entry, acking, commit, reporter)
+				return
+			}
+			if _, timed := handler.(TimedOperator); !timed && len(entries) == 0 {
+				this.committer.skip(entry.Key)
+			}
+			for _, e := range entries {
+				this.process(stream, e, handlers[idx+1:], acking, commit, reporter)
+			}
+			return
 
-			default:
-				_ = stream.GetSource().Stop()
-				log.Fatalf("unknown handler type: %+v", handler)
+		case Sink:
+			if _, err := recoverSinkSingle(handler, entry); err != nil {
+				reporter.report(SinkStage, entry.Key, err)
+				if deadLetter(stream, SinkStage, err, reporter, entry) {
+					this.commitDeadLetter(entry, acking, commit, reporter)
+					return
+				}
+				continue
 			}
+			markSinked(stream)
+			if commit && !acking {
+				reporter.report(CommitStage, entry.Key, this.committer.complete(entry.Key))
+			}
+
+		default:
+			_ = stream.GetSource().Stop()
+			log.Fatalf("unknown handler type: %+v", handler)
 		}
 	}
-	logger.Info("Done processing stream with direct processor")
+}
+
+// commitDeadLetter commits a dead-lettered entry the same way a written entry is committed
+func (this *directProcessor) commitDeadLetter(entry Entry, acking bool, commit bool, reporter *errorReporter) {
+	if commit && !acking {
+		reporter.report(CommitStage, entry.Key, this.committer.complete(entry.Key))
+	} else {
+		this.committer.skip(entry.Key)
+	}
 }
@@ -1,6 +1,8 @@
 package go_streams
 
 import (
+	"errors"
+	"fmt"
 	"github.com/stretchr/testify/assert"
 	"testing"
 	"time"
@@ -27,8 +29,8 @@ func TestDirectProcessor_Process_FilterPanics(t *testing.T) {
 
 	err := <-errs
 	assert.NotNil(t, err)
-	_, ok := err.(*FilterError)
-	assert.True(t, ok)
+	var filterErr *FilterError
+	assert.True(t, errors.As(err, &filterErr))
 }
 
 func TestDirectProcessor_Process_MapPanics(t *testing.T) {
@@ -40,8 +42,8 @@ func TestDirectProcessor_Process_MapPanics(t *testing.T) {
 
 	err := <-errs
 	assert.NotNil(t, err)
-	_, ok := err.(*MapError)
-	assert.True(t, ok)
+	var mapErr *MapError
+	assert.True(t, errors.As(err, &mapErr))
 }
 
 func addOneFilterOddsStream(source Source, sink Sink) Stream {
@@ -69,3 +71,48 @@ func filterPanicsStream(source Source) Stream {
 		panic("demo")
 	}).Sink(NewConsoleSink())
 }
+
+func TestDirectProcessor_Process_ErrorsAreStreamErrors(t *testing.T) {
+	errs := make(ErrorChannel, 100)
+	source := NewSequentialIntegerSource(2, 1*time.Millisecond)
+	stream := NewStream(source).Sink(NewCallbackSink(func(entries ...Entry) error {
+		return fmt.Errorf("sink is down")
+	}))
+	stream.Process(NewDirectProcessor(), errs)
+
+	err := <-errs
+	se, ok := err.(*StreamError)
+	assert.True(t, ok)
+	assert.EqualValues(t, source.Name(), se.Stream)
+	assert.EqualValues(t, SinkStage, se.Stage)
+	assert.EqualValues(t, "0", se.Key)
+	assert.EqualValues(t, "sink is down", se.Unwrap().Error())
+}
+
+func TestDirectProcessor_Process_DeadLettersPanics(t *testing.T) {
+	errs := make(ErrorChannel, 100)
+	source := NewSequentialIntegerSource(5, 1*time.Millisecond)
+	sink := NewArraySink()
+	var letters []Entry
+	stream := NewStream(source).Map(func(entry interface{}) interface{} {
+		if entry.(int) == 2 {
+			panic("demo")
+		}
+		return entry
+	}).Sink(sink).DeadLetter(NewCallbackSink(func(entries ...Entry) error {
+		letters = append(letters, entries...)
+		return nil
+	}))
+	stream.Process(NewDirectProcessor(), errs)
+
+	assert.NotContains(t, sink.Array(), 2)
+	assert.Contains(t, sink.Array(), 3)
+	assert.Len(t, letters, 1)
+	assert.EqualValues(t, 2, letters[0].Value)
+	assert.EqualValues(t, MapStage, letters[0].Metadata[MetadataDeadLetterStage])
+	assert.EqualValues(t, "demo", letters[0].Metadata[MetadataDeadLetterError])
+
+	var pe *PanicError
+	assert.True(t, errors.As(<-errs, &pe))
+	assert.Contains(t, string(pe.Stack), "TestDirectProcessor_Process_DeadLettersPanics")
+}
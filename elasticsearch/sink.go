@@ -0,0 +1,349 @@
+package elasticsearch
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"text/template"
+	"time"
+
+	streams "github.com/matang28/go-streams"
+)
+
+// MetadataError is the metadata key that holds the failure reason of dead-lettered entries
+const MetadataError = "elasticsearch.error"
+
+// Config describes how entries are indexed, the same sink works with OpenSearch clusters.
+type Config struct {
+	// URL of the cluster (e.g: http://localhost:9200)
+	URL string
+
+	// Index is a text/template of the target index name evaluated per entry,
+	// (e.g: `logs-{{.Metadata.service}}-{{.Timestamp.Format "2006.01.02"}}`),
+	// entries without a timestamp are evaluated with the current time.
+	Index string
+
+	// Action is the bulk action, "index" (default) or "create".
+	Action string
+
+	// DocumentID extracts the document id, by default the entry key is used,
+	// return an empty string to let the cluster generate an id.
+	DocumentID streams.KeyExtractor
+
+	// Encode serializes the document, by default the entry value is JSON encoded.
+	Encode func(entry streams.Entry) ([]byte, error)
+
+	// Header is added to every request (e.g: Authorization).
+	Header http.Header
+
+	// MaxRetries is the number of attempts for rejected (429) or unavailable (5xx) items,
+	// defaults to 3, use a negative value to disable retries.
+	MaxRetries int
+
+	// Backoff is the initial delay between retries, it is doubled on each attempt.
+	Backoff time.Duration
+
+	// DeadLetter receives entries that failed with a non retryable error (e.g: mapping errors),
+	// when nil those entries are reported as errors.
+	DeadLetter streams.Sink
+
+	Client *http.Client
+}
+
+// RejectedError is returned when the cluster keeps rejecting requests after all retries,
+// it signals that the cluster can't keep up (backpressure). When other entries of the
+// same batch failed too they are all reported together in a SinkBatchError.
+type RejectedError struct {
+	Rejected int
+}
+
+func (e *RejectedError) Error() string {
+	return fmt.Sprintf("elasticsearch rejected %d documents after all retries", e.Rejected)
+}
+
+// Sink indexes entries with the _bulk API, Batch blocks while the cluster
+// rejects requests (exponential backoff) so a slow cluster slows down the stream.
+type Sink struct {
+	config Config
+	index  *template.Template
+	client *http.Client
+}
+
+func NewSink(config Config) (*Sink, error) {
+	if config.URL == "" || config.Index == "" {
+		return nil, fmt.Errorf("elasticsearch sink requires a url and an index")
+	}
+
+	index, err := template.New("index").Option("missingkey=zero").Parse(config.Index)
+	if err != nil {
+		return nil, err
+	}
+
+	if config.Action == "" {
+		config.Action = "index"
+	}
+	if config.DocumentID == nil {
+		config.DocumentID = func(entry streams.Entry) string {
+			return entry.Key
+		}
+	}
+	if config.Encode == nil {
+		config.Encode = func(entry streams.Entry) ([]byte, error) {
+			return json.Marshal(entry.Value)
+		}
+	}
+	if config.Backoff == 0 {
+		config.Backoff = 100 * time.Millisecond
+	}
+	if config.MaxRetries == 0 {
+		config.MaxRetries = 3
+	}
+
+	client := config.Client
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+
+	return &Sink{config: config, index: index, client: client}, nil
+}
+
+func (this *Sink) Ping() error {
+	req, err := this.request(http.MethodGet, "/", nil)
+	if err != nil {
+		return err
+	}
+
+	res, err := this.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode >= 300 {
+		return fmt.Errorf("elasticsearch ping failed with status: %d", res.StatusCode)
+	}
+	return nil
+}
+
+func (this *Sink) Single(entry streams.Entry) error {
+	return this.Batch(entry)
+}
+
+func (this *Sink) Batch(entry ...streams.Entry) error {
+	errs := streams.NewSinkBatchError()
+	var deadLetters []streams.Entry
+
+	var failure error
+	pending := entry
+	backoff := this.config.Backoff
+	for attempt := 0; len(pending) > 0; attempt++ {
+		if attempt > 0 {
+			if attempt > this.config.MaxRetries {
+				failure = &RejectedError{Rejected: len(pending)}
+				break
+			}
+			streams.LogWith(streams.Fields{streams.FieldStage: streams.SinkStage, "sink": "elasticsearch"}).Warn("Elasticsearch rejected %d documents, retrying in %s", len(pending), backoff)
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+
+		results, err := this.bulk(pending, errs)
+		if err != nil {
+			failure = err
+			break
+		}
+
+		var retry []streams.Entry
+		for idx, res := range results {
+			switch {
+			case res.ok:
+			case res.retryable:
+				retry = append(retry, pending[idx])
+			default:
+				if this.config.DeadLetter != nil {
+					deadLetters = append(deadLetters, deadLetter(pending[idx], res.reason))
+				} else {
+					errs.Add(pending[idx].Key, fmt.Errorf("%s", res.reason))
+				}
+			}
+		}
+		pending = retry
+	}
+
+	if len(deadLetters) > 0 {
+		if err := this.config.DeadLetter.Batch(deadLetters...); err != nil {
+			for idx := range deadLetters {
+				errs.Add(deadLetters[idx].Key, err)
+			}
+		}
+	}
+
+	if failure != nil {
+		if len(errs.Errors) == 0 {
+			return failure
+		}
+		for idx := range pending {
+			errs.Add(pending[idx].Key, failure)
+		}
+	}
+	return errs.AsError()
+}
+
+type itemResult struct {
+	ok        bool
+	retryable bool
+	reason    string
+}
+
+type bulkResponse struct {
+	Items []map[string]struct {
+		Status int `json:"status"`
+		Error  *struct {
+			Type   string `json:"type"`
+			Reason string `json:"reason"`
+		} `json:"error"`
+	} `json:"items"`
+}
+
+// bulk sends the entries in a single bulk request, entries that can't be encoded are
+// reported to errs and marked as done, a rejection of the whole request marks all the items as retryable.
+func (this *Sink) bulk(entries []streams.Entry, errs *streams.SinkBatchError) ([]itemResult, error) {
+	results := make([]itemResult, len(entries))
+	sent := make([]int, 0, len(entries))
+
+	var body bytes.Buffer
+	for idx := range entries {
+		if err := this.writeItem(&body, entries[idx]); err != nil {
+			errs.Add(entries[idx].Key, err)
+			results[idx].ok = true
+			continue
+		}
+		sent = append(sent, idx)
+	}
+	if len(sent) == 0 {
+		return results, nil
+	}
+
+	req, err := this.request(http.MethodPost, "/_bulk", &body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+
+	res, err := this.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if retryableStatus(res.StatusCode) {
+		for _, idx := range sent {
+			results[idx].retryable = true
+		}
+		return results, nil
+	}
+
+	payload, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode >= 300 {
+		return nil, fmt.Errorf("elasticsearch bulk request failed with status %d: %s", res.StatusCode, string(payload))
+	}
+
+	var parsed bulkResponse
+	if err := json.Unmarshal(payload, &parsed); err != nil {
+		return nil, err
+	}
+	if len(parsed.Items) != len(sent) {
+		return nil, fmt.Errorf("elasticsearch bulk response has %d items, expected %d", len(parsed.Items), len(sent))
+	}
+
+	for i, item := range parsed.Items {
+		idx := sent[i]
+		for _, status := range item {
+			switch {
+			case status.Status < 300:
+				results[idx].ok = true
+			case retryableStatus(status.Status):
+				results[idx].retryable = true
+			default:
+				results[idx].reason = fmt.Sprintf("status %d", status.Status)
+				if status.Error != nil {
+					results[idx].reason = fmt.Sprintf("%s: %s", status.Error.Type, status.Error.Reason)
+				}
+			}
+		}
+	}
+	return results, nil
+}
+
+func (this *Sink) writeItem(body *bytes.Buffer, entry streams.Entry) error {
+	if entry.Timestamp.IsZero() {
+		entry.Timestamp = time.Now()
+	}
+
+	var index strings.Builder
+	if err := this.index.Execute(&index, entry); err != nil {
+		return err
+	}
+
+	doc, err := this.config.Encode(entry)
+	if err != nil {
+		return err
+	}
+
+	meta := map[string]string{"_index": index.String()}
+	if id := this.config.DocumentID(entry); id != "" {
+		meta["_id"] = id
+	}
+	action, err := json.Marshal(map[string]interface{}{this.config.Action: meta})
+	if err != nil {
+		return err
+	}
+
+	body.Write(action)
+	body.WriteByte('\n')
+	body.Write(doc)
+	body.WriteByte('\n')
+	return nil
+}
+
+func (this *Sink) request(method, path string, body *bytes.Buffer) (*http.Request, error) {
+	url := strings.TrimRight(this.config.URL, "/") + path
+
+	var req *http.Request
+	var err error
+	if body != nil {
+		req, err = http.NewRequest(method, url, body)
+	} else {
+		req, err = http.NewRequest(method, url, nil)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for name, values := range this.config.Header {
+		for _, v := range values {
+			req.Header.Add(name, v)
+		}
+	}
+	return req, nil
+}
+
+func retryableStatus(status int) bool {
+	return status == http.StatusTooManyRequests || status >= 500
+}
+
+func deadLetter(entry streams.Entry, reason string) streams.Entry {
+	metadata := make(map[string]string, len(entry.Metadata)+1)
+	for k, v := range entry.Metadata {
+		metadata[k] = v
+	}
+	metadata[MetadataError] = reason
+	entry.Metadata = metadata
+	return entry
+}
@@ -0,0 +1,191 @@
+package elasticsearch
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	streams "github.com/matang28/go-streams"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSink_Batch_IndexTemplateAndHeaders(t *testing.T) {
+	var lines []map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.EqualValues(t, "/_bulk", r.URL.Path)
+		assert.EqualValues(t, "ApiKey secret", r.Header.Get("Authorization"))
+		lines = readLines(t, r)
+		_, _ = w.Write([]byte(`{"errors":false,"items":[{"index":{"status":201}},{"index":{"status":201}}]}`))
+	}))
+	defer server.Close()
+
+	sink, err := NewSink(Config{
+		URL:    server.URL,
+		Index:  `logs-{{.Metadata.service}}-{{.Timestamp.Format "2006.01.02"}}`,
+		Header: http.Header{"Authorization": []string{"ApiKey secret"}},
+	})
+	assert.Nil(t, err)
+
+	ts := time.Date(2024, 5, 1, 13, 0, 0, 0, time.UTC)
+	err = sink.Batch(
+		streams.Entry{Key: "1", Value: map[string]int{"a": 1}, Metadata: map[string]string{"service": "api"}, Timestamp: ts},
+		streams.Entry{Key: "2", Value: map[string]int{"a": 2}, Metadata: map[string]string{"service": "web"}, Timestamp: ts},
+	)
+	assert.Nil(t, err)
+
+	assert.EqualValues(t, 4, len(lines))
+	assert.EqualValues(t, map[string]interface{}{"index": map[string]interface{}{"_index": "logs-api-2024.05.01", "_id": "1"}}, lines[0])
+	assert.EqualValues(t, map[string]interface{}{"a": float64(1)}, lines[1])
+	assert.EqualValues(t, "logs-web-2024.05.01", lines[2]["index"].(map[string]interface{})["_index"])
+}
+
+func TestSink_Batch_RetriesRejectedItemsAndDeadLettersMappingErrors(t *testing.T) {
+	mutex := &sync.Mutex{}
+	var requests [][]map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		requests = append(requests, readLines(t, r))
+		if len(requests) == 1 {
+			_, _ = w.Write([]byte(`{"errors":true,"items":[` +
+				`{"index":{"status":201}},` +
+				`{"index":{"status":429,"error":{"type":"es_rejected_execution_exception","reason":"queue full"}}},` +
+				`{"index":{"status":400,"error":{"type":"mapper_parsing_exception","reason":"bad field"}}}]}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"errors":false,"items":[{"index":{"status":201}}]}`))
+	}))
+	defer server.Close()
+
+	var dead []streams.Entry
+	dlq := streams.NewCallbackSink(func(entries ...streams.Entry) error {
+		dead = append(dead, entries...)
+		return nil
+	})
+
+	sink, err := NewSink(Config{URL: server.URL, Index: "idx", MaxRetries: 2, Backoff: time.Millisecond, DeadLetter: dlq})
+	assert.Nil(t, err)
+
+	err = sink.Batch(
+		streams.Entry{Key: "ok", Value: map[string]int{"a": 1}},
+		streams.Entry{Key: "rejected", Value: map[string]int{"a": 2}},
+		streams.Entry{Key: "mapping", Value: map[string]int{"a": 3}},
+	)
+	assert.Nil(t, err)
+
+	assert.EqualValues(t, 2, len(requests))
+	assert.EqualValues(t, "rejected", requests[1][0]["index"].(map[string]interface{})["_id"])
+	assert.EqualValues(t, 1, len(dead))
+	assert.EqualValues(t, "mapping", dead[0].Key)
+	assert.EqualValues(t, "mapper_parsing_exception: bad field", dead[0].Metadata[MetadataError])
+}
+
+func TestSink_Batch_Backpressure(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	sink, err := NewSink(Config{URL: server.URL, Index: "idx", MaxRetries: 2, Backoff: time.Millisecond})
+	assert.Nil(t, err)
+
+	err = sink.Batch(streams.Entry{Key: "1", Value: 1}, streams.Entry{Key: "2", Value: 2})
+	rejected, ok := err.(*RejectedError)
+	assert.True(t, ok)
+	assert.EqualValues(t, 2, rejected.Rejected)
+	assert.EqualValues(t, 3, calls)
+}
+
+func TestSink_Batch_RetriesExhausted_FlushesDeadLetters(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls > 1 {
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		_, _ = w.Write([]byte(`{"errors":true,"items":[` +
+			`{"index":{"status":429,"error":{"type":"es_rejected_execution_exception","reason":"queue full"}}},` +
+			`{"index":{"status":400,"error":{"type":"mapper_parsing_exception","reason":"bad field"}}}]}`))
+	}))
+	defer server.Close()
+
+	var dead []streams.Entry
+	dlq := streams.NewCallbackSink(func(entries ...streams.Entry) error {
+		dead = append(dead, entries...)
+		return nil
+	})
+
+	// MaxRetries defaults to 3
+	sink, err := NewSink(Config{URL: server.URL, Index: "idx", Backoff: time.Millisecond, DeadLetter: dlq})
+	assert.Nil(t, err)
+
+	err = sink.Batch(streams.Entry{Key: "rejected", Value: map[string]int{"a": 1}}, streams.Entry{Key: "mapping", Value: map[string]int{"a": 2}})
+	_, ok := err.(*RejectedError)
+	assert.True(t, ok)
+	assert.EqualValues(t, 4, calls)
+	assert.EqualValues(t, 1, len(dead))
+	assert.EqualValues(t, "mapping", dead[0].Key)
+}
+
+func TestSink_Batch_RetriesExhausted_MergesItemErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"errors":true,"items":[` +
+			`{"index":{"status":429,"error":{"type":"es_rejected_execution_exception","reason":"queue full"}}},` +
+			`{"index":{"status":400,"error":{"type":"mapper_parsing_exception","reason":"bad field"}}}]}`))
+	}))
+	defer server.Close()
+
+	sink, err := NewSink(Config{URL: server.URL, Index: "idx", MaxRetries: -1})
+	assert.Nil(t, err)
+
+	err = sink.Batch(streams.Entry{Key: "rejected", Value: map[string]int{"a": 1}}, streams.Entry{Key: "mapping", Value: map[string]int{"a": 2}})
+	batchErr, ok := err.(*streams.SinkBatchError)
+	assert.True(t, ok)
+	assert.IsType(t, &RejectedError{}, batchErr.Errors["rejected"])
+	assert.NotNil(t, batchErr.Errors["mapping"])
+}
+
+func TestSink_Batch_MappingErrorsWithoutDeadLetter(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"errors":true,"items":[{"create":{"status":409,"error":{"type":"version_conflict_engine_exception","reason":"exists"}}}]}`))
+	}))
+	defer server.Close()
+
+	sink, err := NewSink(Config{URL: server.URL, Index: "idx", Action: "create"})
+	assert.Nil(t, err)
+
+	err = sink.Single(streams.Entry{Key: "1", Value: 1})
+	batchErr, ok := err.(*streams.SinkBatchError)
+	assert.True(t, ok)
+	assert.NotNil(t, batchErr.Errors["1"])
+}
+
+func TestSink_Ping(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	sink, err := NewSink(Config{URL: server.URL, Index: "idx"})
+	assert.Nil(t, err)
+	assert.Nil(t, sink.Ping())
+
+	server.Close()
+	assert.NotNil(t, sink.Ping())
+}
+
+func readLines(t *testing.T, r *http.Request) []map[string]interface{} {
+	var out []map[string]interface{}
+	scanner := bufio.NewScanner(r.Body)
+	for scanner.Scan() {
+		var line map[string]interface{}
+		assert.Nil(t, json.Unmarshal(scanner.Bytes(), &line))
+		out = append(out, line)
+	}
+	return out
+}
@@ -2,23 +2,43 @@ package go_streams
 
 import (
 	"fmt"
+	"net/http"
 	"reflect"
+	"sort"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
 type streamAndProcessor struct {
-	stream    Stream
-	processor Processor
+	stream        Stream
+	processor     Processor
+	source        *managedSource
+	status        StreamStatus
+	stopRequested bool
+	done          chan struct{}
 }
 
 type engine struct {
-	streams          map[string]streamAndProcessor
+	streams          map[string]*streamAndProcessor
 	processorFactory ProcessorFactory
+	processorType    string
 	errorHandler     ErrorHandler
+	eventHandler     EventHandler
+	bus              *eventBus
+	restartPolicy    RestartPolicy
+	scheduler        *Scheduler
+	governor         *governor
 	errorChannel     ErrorChannel
 	stopChannel      chan bool
-	stoppedStreams   int
+	monitorInterval  time.Duration
 	monitorTicker    *time.Ticker
+	mutex            *sync.RWMutex
+	running          bool
+	finished         bool
+	keepAlive        bool
+	processing       *sync.WaitGroup
+	servers          []*http.Server
 }
 
 func NewEngine(processor ProcessorFactory, monitorInterval time.Duration) *engine {
@@ -26,59 +46,524 @@ func NewEngine(processor ProcessorFactory, monitorInterval time.Duration) *engin
 		processorFactory: processor,
 		errorChannel:     make(ErrorChannel),
 		stopChannel:      make(chan bool),
-		streams:          make(map[string]streamAndProcessor),
+		streams:          make(map[string]*streamAndProcessor),
+		monitorInterval:  monitorInterval,
 		monitorTicker:    time.NewTicker(monitorInterval),
+		mutex:            &sync.RWMutex{},
+		processing:       &sync.WaitGroup{},
+		restartPolicy:    NeverRestart(),
+		bus:              newEventBus(),
 	}
 }
 
+// AddOption is an option of a stream added with AddWith
+type AddOption func(options *addOptions)
+
+type addOptions struct {
+	processorFactory ProcessorFactory
+}
+
+// WithProcessor processes the stream with processors of the factory instead of the engine's one,
+// e.g: to run a low latency stream with a direct processor next to a buffered stream.
+func WithProcessor(factory ProcessorFactory) AddOption {
+	return func(options *addOptions) {
+		options.processorFactory = factory
+	}
+}
+
+// Add attaches streams to the engine, streams added to a running engine are started right away
 func (this *engine) Add(streams ...Stream) error {
+	return this.add(streams, nil)
+}
+
+// AddWith attaches a stream with options, the stream is started right away when the engine is running
+func (this *engine) AddWith(stream Stream, options ...AddOption) error {
+	var opts addOptions
+	for _, option := range options {
+		option(&opts)
+	}
+	return this.add([]Stream{stream}, opts.processorFactory)
+}
+
+// add attaches the streams, their processors are created by the factory (the engine's one when it is nil)
+func (this *engine) add(streams []Stream, factory ProcessorFactory) error {
+	this.mutex.Lock()
+	if this.finished {
+		this.mutex.Unlock()
+		return fmt.Errorf("streams can't be added to a stopping engine")
+	}
+
+	added := make([]*streamAndProcessor, 0, len(streams))
 	for _, stream := range streams {
 		_, found := this.streams[stream.GetSource().Name()]
 		if found {
+			this.mutex.Unlock()
+			this.startStreams(added)
 			return NewSameSourceError(stream.GetSource())
 		}
-		this.streams[stream.GetSource().Name()] = streamAndProcessor{
+		source := newManagedSource(stream.GetSource())
+		if config := stream.GetBufferConfig(); config != nil {
+			queue, err := newBoundedQueue(stream.GetSource().Name(), *config)
+			if err != nil {
+				this.mutex.Unlock()
+				this.startStreams(added)
+				return err
+			}
+			source.buffer = queue
+		}
+		var processor Processor
+		if factory != nil {
+			processor = factory()
+		} else {
+			processor = this.processorFactory()
+			if this.processorType == "" {
+				this.processorType = typeName(processor)
+			}
+		}
+		s := &streamAndProcessor{
 			stream:    stream,
-			processor: this.processorFactory(),
+			processor: processor,
+			source:    source,
+			status:    StreamIdle,
+			done:      make(chan struct{}),
+		}
+		this.streams[stream.GetSource().Name()] = s
+		if this.running {
+			this.prepareStream(s)
+			added = append(added, s)
 		}
 	}
+	this.mutex.Unlock()
 
+	this.startStreams(added)
 	return nil
 }
 
+// AddTopology builds the topology and attaches its streams to the engine
+func (this *engine) AddTopology(topology *Topology) error {
+	streams, err := topology.Build()
+	if err != nil {
+		return err
+	}
+	return this.Add(streams...)
+}
+
+// Remove stops a stream (when it is running), waits until it finished processing the entries
+// it already received and detaches it from the engine. Its sinks are closed unless other streams use them.
+func (this *engine) Remove(name string) error {
+	this.mutex.Lock()
+	s, found := this.streams[name]
+	if !found {
+		this.mutex.Unlock()
+		return NewUnknownStreamError(name)
+	}
+	running := s.status == StreamRunning
+	if running {
+		s.stopRequested = true
+	}
+	this.mutex.Unlock()
+
+	if running {
+		s.source.resume()
+		if err := s.source.Stop(); err != nil {
+			return err
+		}
+		<-s.done
+	}
+
+	this.mutex.Lock()
+	delete(this.streams, name)
+	var remaining []interface{}
+	for _, other := range this.streams {
+		remaining = append(remaining, other.stream.GetHandlers()...)
+	}
+	this.mutex.Unlock()
+
+	if s.source.scheduled != nil {
+		s.source.scheduled.scheduler.unregister(name)
+	}
+	closeHandlers(name, s.stream.GetHandlers(), remaining)
+	LogWith(Fields{FieldStream: name}).Info("Stream removed")
+	this.finishIfIdle()
+	return nil
+}
+
+// SetKeepAlive keeps a started engine running when it has no running stream (e.g: streams
+// are added and removed dynamically), the engine then runs until Stop is called.
+func (this *engine) SetKeepAlive(keepAlive bool) {
+	this.mutex.Lock()
+	this.keepAlive = keepAlive
+	this.mutex.Unlock()
+	this.finishIfIdle()
+}
+
+// prepareStream marks the stream as running, the caller holds the engine's lock
+func (this *engine) prepareStream(s *streamAndProcessor) {
+	s.status = StreamRunning
+	policy := this.restartPolicy
+	if p := s.stream.GetRestartPolicy(); p != nil {
+		policy = *p
+	}
+	s.source.supervise(policy, this.emit)
+	if this.scheduler != nil {
+		s.source.scheduled = this.scheduler.register(s.stream.GetSource().Name(), s.stream.GetPriority())
+	}
+	if this.governor != nil {
+		s.source.governor = this.governor
+		maxInFlight := s.stream.GetMaxInFlight()
+		if maxInFlight == 0 {
+			maxInFlight = this.governor.limits.MaxInFlight
+		}
+		s.source.inFlight = newInFlightLimit(maxInFlight)
+	} else {
+		s.source.inFlight = newInFlightLimit(s.stream.GetMaxInFlight())
+	}
+	this.processing.Add(1)
+}
+
+// startStreams starts processing the prepared streams, the handlers of the events
+// are called without holding the engine's lock
+func (this *engine) startStreams(streams []*streamAndProcessor) {
+	for _, s := range streams {
+		s.source.emit(Event{Type: StreamStarted})
+		go func(s *streamAndProcessor) {
+			defer this.processing.Done()
+			defer close(s.done)
+			s.processor.Process(&managedStream{Stream: s.stream, source: s.source}, this.errorChannel)
+		}(s)
+	}
+}
+
 func (this *engine) SetErrorHandler(handler ErrorHandler) {
 	this.errorHandler = handler
 }
 
+// SetRestartPolicy sets the restart policy of streams that don't have their own (see Stream.Supervise)
+func (this *engine) SetRestartPolicy(policy RestartPolicy) {
+	this.restartPolicy = policy
+}
+
+// SetProcessorFactory sets the factory of the processors of the streams added afterwards,
+// the streams added with WithProcessor keep their own.
+func (this *engine) SetProcessorFactory(factory ProcessorFactory) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.processorFactory = factory
+	this.processorType = ""
+}
+
+// SetScheduler shares the capacity of the scheduler between the streams according to their priority
+// (see Stream.Priority), it applies to the streams started afterwards.
+func (this *engine) SetScheduler(scheduler *Scheduler) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.scheduler = scheduler
+}
+
+// SetLimits bounds the resources used by the streams started afterwards
+func (this *engine) SetLimits(limits Limits) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.governor = newGovernor(limits)
+}
+
+// OnEvent sets the handler of the supervision events (source failures and restarts), it is called
+// synchronously by the stream that emitted the event so it shouldn't block. See Subscribe for the other events.
+func (this *engine) OnEvent(handler EventHandler) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.eventHandler = handler
+}
+
+func (this *engine) emit(event Event) {
+	this.mutex.RLock()
+	handler := this.eventHandler
+	this.mutex.RUnlock()
+
+	if !isSupervisionEvent(event.Type) {
+		this.bus.publish(event)
+		return
+	}
+	if event.Err != nil {
+		LogWith(Fields{FieldStream: event.Stream}).Warn("%s: %s", event.Type, event.Err.Error())
+	}
+	if handler != nil {
+		handler(event)
+	}
+	this.bus.publish(event)
+}
+
 func (this *engine) Start() {
 	logger.Info("Starting engine...")
 	go this.monitor()
 
-	if len(this.streams) == 0 {
+	this.mutex.Lock()
+	if len(this.streams) == 0 && !this.keepAlive {
+		this.mutex.Unlock()
 		return
 	}
-
-	go this.consumeErrors()
-
+	this.running = true
+	this.finished = false
+	started := make([]*streamAndProcessor, 0, len(this.streams))
 	for _, s := range this.streams {
-		go s.stream.Process(s.processor, this.errorChannel)
+		this.prepareStream(s)
+		started = append(started, s)
 	}
+	this.mutex.Unlock()
+
+	go this.consumeErrors()
+	this.startStreams(started)
 
 	<-this.stopChannel
+	this.processing.Wait()
+	this.closeSinks()
+	this.mutex.Lock()
+	this.running = false
+	this.mutex.Unlock()
 	logger.Info("Engine stopped")
 }
 
 func (this *engine) Stop() {
 	logger.Info("Stopping engine...")
 	this.monitorTicker.Stop()
+
+	this.mutex.Lock()
+	for _, s := range this.servers {
+		_ = s.Close()
+	}
+	this.servers = nil
+	streams := make([]*streamAndProcessor, 0, len(this.streams))
 	for _, s := range this.streams {
-		err := s.stream.GetSource().Stop()
+		if s.stopRequested || s.status == StreamCompleted || s.status == StreamFailed {
+			continue
+		}
+		s.stopRequested = true
+		streams = append(streams, s)
+	}
+	running := this.running
+	this.mutex.Unlock()
+
+	for _, s := range streams {
+		// paused sources are blocked on their channel, let them drain before stopping
+		s.source.resume()
+		err := s.source.Stop()
 		if err != nil {
-			this.errorChannel <- err
+			this.errorChannel <- NewStreamError(s.stream.GetSource().Name(), SourceStage, "", err)
+		}
+	}
+
+	// Start isn't waiting on the stop channel when the engine wasn't started
+	if running {
+		this.finish()
+	}
+}
+
+// finish signals Start that the engine should stop, only the first call signals it
+func (this *engine) finish() {
+	this.mutex.Lock()
+	signal := this.running && !this.finished
+	this.finished = true
+	this.mutex.Unlock()
+
+	if signal {
+		this.stopChannel <- true
+		this.monitorTicker.Stop()
+	}
+}
+
+// finishIfIdle stops the engine once none of its streams is running, unless it is kept alive
+func (this *engine) finishIfIdle() {
+	this.mutex.RLock()
+	idle := this.running && !this.keepAlive
+	for _, s := range this.streams {
+		if s.status == StreamRunning {
+			idle = false
 		}
 	}
+	this.mutex.RUnlock()
 
-	this.stopChannel <- true
+	if idle {
+		this.finish()
+	}
+}
+
+// StopStream stops the source of a single stream, the stream finishes processing
+// the entries it already received. Stopped streams cannot be started again.
+func (this *engine) StopStream(name string) error {
+	s, err := this.runningStream(name)
+	if err != nil {
+		return err
+	}
+
+	this.mutex.Lock()
+	s.stopRequested = true
+	this.mutex.Unlock()
+
+	s.source.resume()
+	return s.source.Stop()
+}
+
+// Pause holds back the entries of the stream until Resume is called, the stream's
+// Pause hook is called when the stream implements Pausable (streams created by NewStream
+// delegate it to their source).
+func (this *engine) Pause(name string) error {
+	s, err := this.runningStream(name)
+	if err != nil {
+		return err
+	}
+
+	s.source.pause()
+	if p, ok := s.stream.(Pausable); ok {
+		if err := p.Pause(); err != nil {
+			return err
+		}
+	}
+	LogWith(Fields{FieldStream: name}).Info("Stream paused")
+	return nil
+}
+
+// Resume continues a paused stream
+func (this *engine) Resume(name string) error {
+	s, err := this.runningStream(name)
+	if err != nil {
+		return err
+	}
+
+	if p, ok := s.stream.(Pausable); ok {
+		if err := p.Resume(); err != nil {
+			return err
+		}
+	}
+	s.source.resume()
+	LogWith(Fields{FieldStream: name}).Info("Stream resumed")
+	return nil
+}
+
+// SeekToTimestamp moves the source of a stream to the first entry at or after the time
+func (this *engine) SeekToTimestamp(name string, at time.Time) error {
+	return this.seek(name, fmt.Sprintf("to %s", at.Format(time.RFC3339)), func(source SeekableSource) error {
+		return source.SeekToTimestamp(at)
+	})
+}
+
+// SeekToKey moves the source of a stream to the entry of the key
+func (this *engine) SeekToKey(name string, key string) error {
+	return this.seek(name, fmt.Sprintf("to key '%s'", key), func(source SeekableSource) error {
+		return source.SeekToKey(key)
+	})
+}
+
+// Rewind moves the source of a stream to its first entry
+func (this *engine) Rewind(name string) error {
+	return this.seek(name, "to its first entry", SeekableSource.Rewind)
+}
+
+// seek seeks the source of a stream whatever its status, e.g: before the engine starts
+func (this *engine) seek(name string, position string, fn func(SeekableSource) error) error {
+	this.mutex.RLock()
+	s, found := this.streams[name]
+	this.mutex.RUnlock()
+	if !found {
+		return NewUnknownStreamError(name)
+	}
+
+	source, ok := s.stream.GetSource().(SeekableSource)
+	if !ok {
+		return NewUnseekableSourceError(name)
+	}
+	if err := fn(source); err != nil {
+		return err
+	}
+	LogWith(Fields{FieldStream: name}).Info("Stream seeked %s", position)
+	return nil
+}
+
+func (this *engine) runningStream(name string) (*streamAndProcessor, error) {
+	this.mutex.RLock()
+	defer this.mutex.RUnlock()
+
+	s, found := this.streams[name]
+	if !found {
+		return nil, NewUnknownStreamError(name)
+	}
+	if s.status != StreamRunning || s.stopRequested {
+		return nil, NewStreamStateError(name, this.statusOf(s))
+	}
+	return s, nil
+}
+
+// Streams returns the status of every stream sorted by name
+func (this *engine) Streams() []StreamInfo {
+	this.mutex.RLock()
+	defer this.mutex.RUnlock()
+
+	infos := make([]StreamInfo, 0, len(this.streams))
+	for name, s := range this.streams {
+		infos = append(infos, StreamInfo{
+			Name:       name,
+			Status:     this.statusOf(s),
+			Received:   atomic.LoadUint64(&s.source.received),
+			Committed:  atomic.LoadUint64(&s.source.committed),
+			Throughput: s.source.throughput(),
+			Buffer:     s.source.bufferStats(),
+			Scheduler:  this.schedulerStats(name),
+			InFlight:   len(s.source.inFlight),
+		})
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
+	return infos
+}
+
+func (this *engine) schedulerStats(name string) *SchedulerStats {
+	if this.scheduler == nil {
+		return nil
+	}
+	return this.scheduler.statsOf(name)
+}
+
+// Config returns a description of the engine and its streams
+func (this *engine) Config() EngineConfig {
+	this.mutex.RLock()
+	defer this.mutex.RUnlock()
+
+	config := EngineConfig{
+		Processor:       this.processorType,
+		MonitorInterval: this.monitorInterval.String(),
+		Streams:         make([]StreamConfig, 0, len(this.streams)),
+	}
+	for name, s := range this.streams {
+		handlers := make([]string, len(s.stream.GetHandlers()))
+		for idx, handler := range s.stream.GetHandlers() {
+			handlers[idx] = describeHandler(handler)
+		}
+		config.Streams = append(config.Streams, StreamConfig{
+			Name:         name,
+			Source:       typeName(s.stream.GetSource()),
+			Handlers:     handlers,
+			ErrorHandler: s.stream.GetErrorHandler() != nil,
+			Buffer:       s.stream.GetBufferConfig(),
+			Priority:     s.stream.GetPriority(),
+			MaxInFlight:  s.stream.GetMaxInFlight(),
+			Timeout:      durationString(s.stream.GetTimeout()),
+			Processor:    typeName(s.processor),
+		})
+	}
+	sort.Slice(config.Streams, func(i, j int) bool { return config.Streams[i].Name < config.Streams[j].Name })
+	return config
+}
+
+// Running returns true while the engine is started
+func (this *engine) Running() bool {
+	this.mutex.RLock()
+	defer this.mutex.RUnlock()
+	return this.running
+}
+
+func (this *engine) statusOf(s *streamAndProcessor) StreamStatus {
+	if s.status == StreamRunning && s.source.paused() {
+		return StreamPaused
+	}
+	return s.status
 }
 
 func (this *engine) consumeErrors() {
@@ -88,24 +573,93 @@ func (this *engine) consumeErrors() {
 		case *EofError:
 			this.handleSourceEof(e.source)
 		default:
-			logger.Error(e.Error())
-			if this.errorHandler != nil && e != nil {
-				go this.errorHandler(e)
+			if se, ok := e.(*StreamError); ok {
+				LogWith(Fields{FieldStream: se.Stream, FieldStage: se.Stage, FieldKey: se.Key}).Error("%s", se.Error())
+				this.recordError(se)
+				this.emit(Event{Stream: se.Stream, Type: StreamErrored, Time: time.Now(), Err: se})
+			} else if e != nil {
+				logger.Error("%s", e.Error())
+				this.emit(Event{Type: StreamErrored, Time: time.Now(), Err: e})
+			}
+			if handler := this.errorHandlerOf(e); handler != nil && e != nil {
+				go handler(e)
+			}
+		}
+	}
+}
+
+// errorHandlerOf returns the handler of the stream that reported the error,
+// falling back to the engine's error handler.
+func (this *engine) errorHandlerOf(err error) ErrorHandler {
+	this.mutex.RLock()
+	defer this.mutex.RUnlock()
+
+	if se, ok := err.(*StreamError); ok {
+		if s, found := this.streams[se.Stream]; found && s.stream.GetErrorHandler() != nil {
+			return s.stream.GetErrorHandler()
+		}
+	}
+	return this.errorHandler
+}
+
+// closeSinks closes the sinks of all the streams, sinks shared by several streams are closed once
+func (this *engine) closeSinks() {
+	this.mutex.RLock()
+	defer this.mutex.RUnlock()
+
+	var closed []interface{}
+	for name, s := range this.streams {
+		closed = append(closed, closeHandlers(name, s.stream.GetHandlers(), closed)...)
+	}
+}
+
+// closeHandlers closes the handlers that implement Closer except the ones in skip (compared by
+// identity when their type is comparable), it returns the handlers it closed.
+func closeHandlers(stream string, handlers []interface{}, skip []interface{}) []interface{} {
+	var closed []interface{}
+Handlers:
+	for _, handler := range handlers {
+		closer, ok := handler.(Closer)
+		if !ok {
+			continue
+		}
+		if reflect.TypeOf(handler).Comparable() {
+			for _, others := range [][]interface{}{skip, closed} {
+				for _, c := range others {
+					if reflect.TypeOf(c).Comparable() && c == handler {
+						continue Handlers
+					}
+				}
 			}
+			closed = append(closed, handler)
+		}
+		if err := closer.Close(); err != nil {
+			LogWith(Fields{FieldStream: stream, FieldStage: SinkStage}).Error("Failed to close sink %s: %s", typeName(handler), err.Error())
 		}
 	}
+	return closed
 }
 
 func (this *engine) handleSourceEof(source Source) {
-	_, found := this.streams[source.Name()]
+	this.mutex.Lock()
+	s, found := this.streams[source.Name()]
+	var status StreamStatus
 	if found {
-		this.stoppedStreams += 1
+		if s.stopRequested {
+			s.status = StreamStopped
+		} else if s.source.hasFailed() {
+			s.status = StreamFailed
+		} else {
+			s.status = StreamCompleted
+		}
+		status = s.status
 	}
+	this.mutex.Unlock()
 
-	if this.stoppedStreams == len(this.streams) {
-		this.stopChannel <- true
-		this.monitorTicker.Stop()
+	if found {
+		s.source.emit(Event{Type: StreamEnded, Status: status})
 	}
+	this.finishIfIdle()
 }
 
 func (this *engine) monitor() {
@@ -115,7 +669,14 @@ func (this *engine) monitor() {
 			break
 		}
 
+		this.mutex.RLock()
+		streams := make([]*streamAndProcessor, 0, len(this.streams))
 		for _, stream := range this.streams {
+			streams = append(streams, stream)
+		}
+		this.mutex.RUnlock()
+
+		for _, stream := range streams {
 			if err := checkSource(stream.stream.GetSource(), 3, 1*time.Second); err != nil {
 				panic(err)
 			}
@@ -127,6 +688,33 @@ func (this *engine) monitor() {
 	}
 }
 
+func describeHandler(handler interface{}) string {
+	switch handler.(type) {
+	case FilterFunc:
+		return "filter"
+	case MapFunc:
+		return "map"
+	case Operator:
+		return fmt.Sprintf("operator(%s)", typeName(handler))
+	case Sink:
+		return fmt.Sprintf("sink(%s)", typeName(handler))
+	default:
+		return typeName(handler)
+	}
+}
+
+func typeName(v interface{}) string {
+	return reflect.TypeOf(v).String()
+}
+
+// durationString formats the duration, empty when it isn't set
+func durationString(d time.Duration) string {
+	if d == 0 {
+		return ""
+	}
+	return d.String()
+}
+
 func checkSinks(handlers []interface{}, retries int, backoff time.Duration) error {
 	for _, handler := range handlers {
 		switch sink := handler.(type) {
@@ -1,7 +1,9 @@
 package go_streams
 
 import (
+	"errors"
 	"github.com/stretchr/testify/assert"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -43,6 +45,39 @@ func TestEngine_HappyCase_MultipleStreamsDifferentSource(t *testing.T) {
 	assert.EqualValues(t, []interface{}{2, 4, 6, 8, 10, 12, 14, 16, 18, 20}, sink2.Array())
 }
 
+func TestEngine_AddWith_ProcessorPerStream(t *testing.T) {
+	engine := NewEngine(NewDirectProcessorFactory(), 10*time.Second)
+	direct := NewSequentialIntegerSource(10, time.Millisecond)
+	buffered := NewSequentialIntegerSource(20, time.Millisecond)
+	directSink := NewArraySink()
+	bufferedSink := NewArraySink()
+
+	assert.Nil(t, engine.Add(addOneFilterOddsStream(direct, directSink)))
+	assert.Nil(t, engine.AddWith(addOneFilterOddsStream(buffered, bufferedSink), WithProcessor(NewBufferedProcessorFactory(5, 10*time.Millisecond))))
+
+	config := engine.Config()
+	assert.EqualValues(t, "*go_streams.directProcessor", config.Processor)
+	processors := map[string]string{}
+	for _, stream := range config.Streams {
+		processors[stream.Name] = stream.Processor
+	}
+	assert.EqualValues(t, map[string]string{
+		direct.Name():   "*go_streams.directProcessor",
+		buffered.Name(): "*go_streams.bufferedProcessor",
+	}, processors)
+
+	engine.Start()
+	assert.EqualValues(t, []interface{}{2, 4, 6, 8, 10}, directSink.Array())
+	assert.EqualValues(t, []interface{}{2, 4, 6, 8, 10, 12, 14, 16, 18, 20}, bufferedSink.Array())
+}
+
+func TestEngine_SetProcessorFactory(t *testing.T) {
+	engine := NewEngine(NewDirectProcessorFactory(), 10*time.Second)
+	engine.SetProcessorFactory(NewBufferedProcessorFactory(5, 10*time.Millisecond))
+	assert.Nil(t, engine.Add(addOneFilterOddsStream(NewSequentialIntegerSource(10, time.Millisecond), NewArraySink())))
+	assert.EqualValues(t, "*go_streams.bufferedProcessor", engine.Config().Processor)
+}
+
 func TestEngine_MultipleStreamsSameSource_ShouldReturnError(t *testing.T) {
 	engine := NewEngine(NewDirectProcessorFactory(), 10*time.Second)
 	source := NewSequentialIntegerSource(10, time.Millisecond)
@@ -80,8 +115,8 @@ func TestEngine_ErrorHandler_ShouldCatchErrors(t *testing.T) {
 
 	handledErr := <-blocker
 	assert.NotNil(t, handledErr)
-	_, ok := handledErr.(*FilterError)
-	assert.True(t, ok)
+	var filterErr *FilterError
+	assert.True(t, errors.As(handledErr, &filterErr))
 }
 
 func TestEngine_Stop_ShouldStopAllStreams(t *testing.T) {
@@ -116,3 +151,190 @@ func TestEngine_Stop_ShouldStopAllStreams(t *testing.T) {
 	assert.NotEmpty(t, sink2.Array())
 	assert.True(t, len(sink1.Array()) < 10)
 }
+
+func TestEngine_StreamErrorHandler_OverridesEngineHandler(t *testing.T) {
+	engine := NewEngine(NewDirectProcessorFactory(), 10*time.Second)
+	failing := NewSequentialIntegerSource(10, time.Millisecond)
+	healthy := NewSequentialIntegerSource(10, time.Millisecond)
+	blocker := make(chan error, 100)
+
+	e := engine.Add(
+		filterPanicsStream(failing).OnError(func(err error) {
+			blocker <- err
+		}),
+		addOneFilterOddsStream(healthy, NewArraySink()),
+	)
+	assert.Nil(t, e)
+
+	engine.SetErrorHandler(func(err error) {
+		assert.Fail(t, "engine handler shouldn't be called", err.Error())
+	})
+
+	engine.Start()
+
+	handledErr := <-blocker
+	se, ok := handledErr.(*StreamError)
+	assert.True(t, ok)
+	assert.EqualValues(t, failing.Name(), se.Stream)
+	assert.EqualValues(t, FilterStage, se.Stage)
+	assert.NotEmpty(t, se.Key)
+}
+
+func TestEngine_StopStream_ShouldStopOnlyThatStream(t *testing.T) {
+	engine := NewEngine(NewDirectProcessorFactory(), 10*time.Second)
+	stopped := NewSequentialIntegerSource(0, time.Millisecond)
+	completed := NewSequentialIntegerSource(10, time.Millisecond)
+
+	e := engine.Add(addOneFilterOddsStream(stopped, NewArraySink()), addOneFilterOddsStream(completed, NewArraySink()))
+	assert.Nil(t, e)
+
+	assert.IsType(t, &StreamStateError{}, engine.StopStream(stopped.Name()))
+	assert.IsType(t, &UnknownStreamError{}, engine.StopStream("missing"))
+
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		assert.Nil(t, engine.StopStream(stopped.Name()))
+	}()
+
+	engine.Start()
+
+	statuses := make(map[string]StreamStatus)
+	for _, info := range engine.Streams() {
+		statuses[info.Name] = info.Status
+		assert.True(t, info.Received > 0)
+		assert.True(t, info.Committed > 0)
+	}
+	assert.EqualValues(t, StreamStopped, statuses[stopped.Name()])
+	assert.EqualValues(t, StreamCompleted, statuses[completed.Name()])
+}
+
+func TestEngine_PauseResume_ShouldHoldBackEntries(t *testing.T) {
+	engine := NewEngine(NewDirectProcessorFactory(), 10*time.Second)
+	source := NewSequentialIntegerSource(50, time.Millisecond)
+	sink := NewArraySink()
+
+	e := engine.Add(NewStream(source).Sink(sink))
+	assert.Nil(t, e)
+	assert.IsType(t, &StreamStateError{}, engine.Pause(source.Name()))
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		assert.Nil(t, engine.Pause(source.Name()))
+		assert.EqualValues(t, StreamPaused, engine.Streams()[0].Status)
+
+		time.Sleep(50 * time.Millisecond)
+		paused := len(sink.Array())
+		time.Sleep(100 * time.Millisecond)
+		assert.EqualValues(t, paused, len(sink.Array()))
+
+		assert.Nil(t, engine.Resume(source.Name()))
+	}()
+
+	engine.Start()
+
+	assert.Len(t, sink.Array(), 51)
+	assert.EqualValues(t, StreamCompleted, engine.Streams()[0].Status)
+}
+
+func TestEngine_Pause_CallsSourceHooks(t *testing.T) {
+	engine := NewEngine(NewDirectProcessorFactory(), 10*time.Second)
+	var polls int32
+	source := NewPollingSource(5*time.Millisecond, func(latestCommit string) ([]Entry, error) {
+		atomic.AddInt32(&polls, 1)
+		return nil, nil
+	})
+	e := engine.Add(NewStream(source).Sink(NewArraySink()))
+	assert.Nil(t, e)
+
+	go func() {
+		time.Sleep(30 * time.Millisecond)
+		assert.Nil(t, engine.Pause(source.Name()))
+		time.Sleep(10 * time.Millisecond)
+		paused := atomic.LoadInt32(&polls)
+		time.Sleep(50 * time.Millisecond)
+		assert.EqualValues(t, paused, atomic.LoadInt32(&polls))
+
+		assert.Nil(t, engine.Resume(source.Name()))
+		time.Sleep(30 * time.Millisecond)
+		assert.True(t, atomic.LoadInt32(&polls) > paused)
+		engine.Stop()
+	}()
+
+	engine.Start()
+}
+
+func TestEngine_AddRemove_WhileRunning(t *testing.T) {
+	engine := NewEngine(NewDirectProcessorFactory(), 10*time.Second)
+	engine.SetKeepAlive(true)
+	first := NewSequentialIntegerSource(0, time.Millisecond)
+	second := NewSequentialIntegerSource(0, time.Millisecond)
+	firstSink := newLifecycleSink()
+	secondSink := NewArraySink()
+	assert.Nil(t, engine.Add(NewStream(first).Sink(firstSink)))
+
+	done := make(chan struct{})
+	go func() {
+		engine.Start()
+		close(done)
+	}()
+
+	time.Sleep(30 * time.Millisecond)
+	assert.Nil(t, engine.Add(NewStream(second).Sink(secondSink)))
+	assert.IsType(t, &SameSourceError{}, engine.Add(NewStream(second)))
+
+	time.Sleep(30 * time.Millisecond)
+	assert.Nil(t, engine.Remove(first.Name()))
+	assert.IsType(t, &UnknownStreamError{}, engine.Remove(first.Name()))
+	assert.EqualValues(t, 1, firstSink.closed)
+	assert.Len(t, engine.Streams(), 1)
+
+	// the engine keeps running without the removed stream
+	received := len(secondSink.Array())
+	time.Sleep(30 * time.Millisecond)
+	assert.True(t, len(secondSink.Array()) > received)
+	assert.NotEmpty(t, firstSink.flushed)
+
+	assert.Nil(t, engine.Remove(second.Name()))
+	assert.True(t, engine.Running())
+	engine.Stop()
+	<-done
+	assert.False(t, engine.Running())
+}
+
+// seekRecorder is an append source recording its seeks
+type seekRecorder struct {
+	*AppendSource
+	seeks []string
+}
+
+func (this *seekRecorder) SeekToTimestamp(at time.Time) error {
+	this.seeks = append(this.seeks, at.UTC().Format(time.RFC3339))
+	return nil
+}
+
+func (this *seekRecorder) SeekToKey(key string) error {
+	this.seeks = append(this.seeks, key)
+	return nil
+}
+
+func (this *seekRecorder) Rewind() error {
+	this.seeks = append(this.seeks, "rewind")
+	return nil
+}
+
+func TestEngine_Seek(t *testing.T) {
+	engine := NewEngine(NewDirectProcessorFactory(), 10*time.Second)
+	seekable := &seekRecorder{AppendSource: NewAppendSource(1)}
+	other := NewSequentialIntegerSource(1, 0)
+	assert.Nil(t, engine.Add(NewStream(seekable).Sink(NewArraySink()), NewStream(other).Sink(NewArraySink())))
+
+	// streams can seek before they start
+	at := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	assert.Nil(t, engine.SeekToTimestamp(seekable.Name(), at))
+	assert.Nil(t, engine.SeekToKey(seekable.Name(), "42"))
+	assert.Nil(t, engine.Rewind(seekable.Name()))
+	assert.EqualValues(t, []string{"2026-01-02T03:04:05Z", "42", "rewind"}, seekable.seeks)
+
+	assert.IsType(t, &UnseekableSourceError{}, engine.Rewind(other.Name()))
+	assert.IsType(t, &UnknownStreamError{}, engine.SeekToKey("unknown", "42"))
+}
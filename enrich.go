@@ -0,0 +1,216 @@
+package go_streams
+
+import (
+	"container/list"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const defaultEnrichCacheSize = 1000
+
+// Loader looks up the reference data of a key (e.g: in a database or an http service)
+type Loader func(key string) (interface{}, error)
+
+// Enriched is the value of enriched entries when no merge function is set
+type Enriched struct {
+	Value     interface{}
+	Reference interface{}
+}
+
+// CacheOptions configures the cache of the reference data
+type CacheOptions struct {
+	// Size is the number of keys kept, the least recently used are evicted (defaults to 1000)
+	Size int
+
+	// TTL is the time loaded values are kept (0 keeps them until they are evicted)
+	TTL time.Duration
+
+	// NegativeTTL is the time failed lookups and nil values are kept (0 doesn't cache them)
+	NegativeTTL time.Duration
+}
+
+// EnrichConfig configures an Enricher
+type EnrichConfig struct {
+	// Key returns the lookup key of an entry value, the entry key is used when it isn't set
+	Key KeyFunc
+
+	// Merge combines the value of an entry with its reference data, the value becomes an Enriched when it isn't set
+	Merge func(value interface{}, reference interface{}) interface{}
+
+	// PassOnError passes the entries whose lookup failed as they are instead of dropping them, the errors are reported either way
+	PassOnError bool
+
+	Cache CacheOptions
+}
+
+// Enricher is an operator that looks up the reference data of every entry through a cache,
+// concurrent lookups of the same key are made once.
+type Enricher struct {
+	loader  Loader
+	config  EnrichConfig
+	cache   *lruCache
+	flights *flightGroup
+}
+
+func NewEnricher(loader Loader, config EnrichConfig) *Enricher {
+	if config.Merge == nil {
+		config.Merge = func(value interface{}, reference interface{}) interface{} {
+			return Enriched{Value: value, Reference: reference}
+		}
+	}
+	if config.Cache.Size <= 0 {
+		config.Cache.Size = defaultEnrichCacheSize
+	}
+	return &Enricher{loader: loader, config: config, cache: newLruCache(config.Cache.Size), flights: newFlightGroup()}
+}
+
+func (this *Enricher) Apply(entry Entry) ([]Entry, error) {
+	key := entry.Key
+	if this.config.Key != nil {
+		key = this.config.Key(entry.Value)
+	}
+
+	reference, err := this.Lookup(key)
+	if err != nil {
+		err = NewMapError(fmt.Errorf("enrich lookup failed for '%s': %s", key, err.Error()))
+		if this.config.PassOnError {
+			return []Entry{entry}, err
+		}
+		return nil, err
+	}
+	entry.Value = this.config.Merge(entry.Value, reference)
+	return []Entry{entry}, nil
+}
+
+// MapErr returns the enrichment as a map function, e.g: to look up several entries at once with MapAsync
+func (this *Enricher) MapErr() MapErrFunc {
+	return func(value interface{}) (interface{}, error) {
+		if this.config.Key == nil {
+			return nil, fmt.Errorf("enriching with a map function requires a key function")
+		}
+		reference, err := this.Lookup(this.config.Key(value))
+		if err != nil {
+			return nil, err
+		}
+		return this.config.Merge(value, reference), nil
+	}
+}
+
+// Lookup returns the cached reference data of the key or loads it
+func (this *Enricher) Lookup(key string) (interface{}, error) {
+	if cached, found := this.cache.get(key, time.Now()); found {
+		return cached.value, cached.err
+	}
+
+	return this.flights.do(key, func() (interface{}, error) {
+		value, err := this.loader(key)
+		negative := err != nil || value == nil
+		if !negative || this.config.Cache.NegativeTTL > 0 {
+			ttl := this.config.Cache.TTL
+			if negative {
+				ttl = this.config.Cache.NegativeTTL
+			}
+			expires := time.Time{}
+			if ttl > 0 {
+				expires = time.Now().Add(ttl)
+			}
+			this.cache.set(key, cachedValue{value: value, err: err, expires: expires})
+		}
+		return value, err
+	})
+}
+
+type cachedValue struct {
+	value   interface{}
+	err     error
+	expires time.Time
+}
+
+type lruItem struct {
+	key    string
+	cached cachedValue
+}
+
+// lruCache keeps up to size keys and evicts the least recently used ones
+type lruCache struct {
+	mutex *sync.Mutex
+	size  int
+	order *list.List
+	items map[string]*list.Element
+}
+
+func newLruCache(size int) *lruCache {
+	return &lruCache{mutex: &sync.Mutex{}, size: size, order: list.New(), items: make(map[string]*list.Element)}
+}
+
+func (this *lruCache) get(key string, now time.Time) (cachedValue, bool) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	element, found := this.items[key]
+	if !found {
+		return cachedValue{}, false
+	}
+	item := element.Value.(*lruItem)
+	if !item.cached.expires.IsZero() && !now.Before(item.cached.expires) {
+		this.order.Remove(element)
+		delete(this.items, key)
+		return cachedValue{}, false
+	}
+	this.order.MoveToFront(element)
+	return item.cached, true
+}
+
+func (this *lruCache) set(key string, cached cachedValue) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	if element, found := this.items[key]; found {
+		element.Value.(*lruItem).cached = cached
+		this.order.MoveToFront(element)
+		return
+	}
+	this.items[key] = this.order.PushFront(&lruItem{key: key, cached: cached})
+	for this.order.Len() > this.size {
+		oldest := this.order.Back()
+		this.order.Remove(oldest)
+		delete(this.items, oldest.Value.(*lruItem).key)
+	}
+}
+
+type flight struct {
+	done  chan struct{}
+	value interface{}
+	err   error
+}
+
+// flightGroup runs the function of a key once at a time, concurrent callers wait for its result
+type flightGroup struct {
+	mutex   *sync.Mutex
+	flights map[string]*flight
+}
+
+func newFlightGroup() *flightGroup {
+	return &flightGroup{mutex: &sync.Mutex{}, flights: make(map[string]*flight)}
+}
+
+func (this *flightGroup) do(key string, fn func() (interface{}, error)) (interface{}, error) {
+	this.mutex.Lock()
+	if f, found := this.flights[key]; found {
+		this.mutex.Unlock()
+		<-f.done
+		return f.value, f.err
+	}
+	// the waiters get this error when the function panicked
+	f := &flight{done: make(chan struct{}), err: fmt.Errorf("lookup of '%s' panicked", key)}
+	this.flights[key] = f
+	this.mutex.Unlock()
+
+	defer func() {
+		this.mutex.Lock()
+		delete(this.flights, key)
+		this.mutex.Unlock()
+		close(f.done)
+	}()
+	f.value, f.err = fn()
+	return f.value, f.err
+}
@@ -0,0 +1,92 @@
+package go_streams
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStream_Enrich(t *testing.T) {
+	loads := map[string]int{}
+	loader := func(key string) (interface{}, error) {
+		loads[key]++
+		if key == "odd" {
+			return nil, fmt.Errorf("not found")
+		}
+		return "even number", nil
+	}
+	sink := NewArraySink()
+	NewStream(NewSequentialIntegerSource(5, 0)).Enrich(loader, EnrichConfig{
+		Key: func(value interface{}) string {
+			if value.(int)%2 == 0 {
+				return "even"
+			}
+			return "odd"
+		},
+		Merge: func(value interface{}, reference interface{}) interface{} {
+			return fmt.Sprintf("%d is an %s", value, reference)
+		},
+		Cache: CacheOptions{NegativeTTL: time.Hour},
+	}).Sink(sink).Process(NewDirectProcessor(), make(ErrorChannel, 10))
+
+	assert.EqualValues(t, []interface{}{"0 is an even number", "2 is an even number", "4 is an even number"}, sink.array)
+	assert.EqualValues(t, map[string]int{"even": 1, "odd": 1}, loads)
+}
+
+func TestEnricher_Cache(t *testing.T) {
+	loads := 0
+	enricher := NewEnricher(func(key string) (interface{}, error) {
+		loads++
+		return key, nil
+	}, EnrichConfig{Cache: CacheOptions{Size: 2, TTL: 20 * time.Millisecond}})
+
+	for _, key := range []string{"a", "b", "a", "c", "a", "b"} {
+		_, err := enricher.Lookup(key)
+		assert.Nil(t, err)
+	}
+	// b was evicted by c as a was used more recently
+	assert.EqualValues(t, 4, loads)
+
+	time.Sleep(25 * time.Millisecond)
+	_, _ = enricher.Lookup("a")
+	assert.EqualValues(t, 5, loads)
+}
+
+func TestEnricher_DeduplicatesConcurrentLookups(t *testing.T) {
+	var loads int32
+	release := make(chan struct{})
+	enricher := NewEnricher(func(key string) (interface{}, error) {
+		atomic.AddInt32(&loads, 1)
+		<-release
+		return "value", nil
+	}, EnrichConfig{})
+
+	wg := &sync.WaitGroup{}
+	for idx := 0; idx < 10; idx++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			value, err := enricher.Lookup("key")
+			assert.Nil(t, err)
+			assert.EqualValues(t, "value", value)
+		}()
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	assert.EqualValues(t, 1, atomic.LoadInt32(&loads))
+}
+
+func TestEnricher_PassOnError(t *testing.T) {
+	enricher := NewEnricher(func(key string) (interface{}, error) {
+		return nil, fmt.Errorf("service is down")
+	}, EnrichConfig{PassOnError: true})
+
+	entries, err := enricher.Apply(Entry{Key: "1", Value: 1})
+	assert.IsType(t, &MapError{}, err)
+	assert.EqualValues(t, []Entry{{Key: "1", Value: 1}}, entries)
+}
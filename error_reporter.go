@@ -0,0 +1,57 @@
+package go_streams
+
+// errorReporter wraps the errors of a stream with a StreamError before reporting them
+type errorReporter struct {
+	stream string
+	errs   ErrorChannel
+
+	// drained is closed once the errors of the source channel were reported
+	drained chan struct{}
+}
+
+func newErrorReporter(stream Stream, errs ErrorChannel) *errorReporter {
+	return &errorReporter{stream: stream.GetSource().Name(), errs: errs}
+}
+
+func (this *errorReporter) report(stage Stage, key string, err error) {
+	if err != nil {
+		this.errs <- NewStreamError(this.stream, stage, key, err)
+	}
+}
+
+// sourceChannel returns the error channel handed to the source, errors sent by the source
+// are wrapped as source stage errors while the EOF error is passed as is. The channel is drained
+// until it is closed so errors sent after the EOF error are reported too and never block the source.
+func (this *errorReporter) sourceChannel() ErrorChannel {
+	ch := make(ErrorChannel)
+	this.drained = make(chan struct{})
+	go func() {
+		defer close(this.drained)
+		for err := range ch {
+			if _, ok := err.(*EofError); ok {
+				this.errs <- err
+				continue
+			}
+			this.report(SourceStage, "", err)
+		}
+	}()
+	return ch
+}
+
+// startSource starts the source in the background, its error channel is closed once Start returned
+// so sources shouldn't send errors after it (see wait)
+func (this *errorReporter) startSource(source Source, channel EntryChannel) {
+	errs := this.sourceChannel()
+	go func() {
+		defer close(errs)
+		source.Start(channel, errs)
+	}()
+}
+
+// wait waits for the source to return and for its errors to be reported, the processors
+// wait before returning so the errors of the source (and its EOF) are never reported later
+func (this *errorReporter) wait() {
+	if this.drained != nil {
+		<-this.drained
+	}
+}
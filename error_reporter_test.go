@@ -0,0 +1,60 @@
+package go_streams
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestErrorReporter_SourceChannel_DrainsAfterEof(t *testing.T) {
+	source := NewSequentialIntegerSource(1, time.Millisecond)
+	errs := make(ErrorChannel, 10)
+	ch := newErrorReporter(NewStream(source), errs).sourceChannel()
+
+	ch <- NewEofError(source)
+	ch <- errors.New("late error")
+	close(ch)
+
+	assert.IsType(t, &EofError{}, <-errs)
+	se, ok := (<-errs).(*StreamError)
+	assert.True(t, ok)
+	assert.EqualValues(t, SourceStage, se.Stage)
+}
+
+// erroringSource sends errors after closing its channel, like sources reporting why they ended
+type erroringSource struct {
+	Source
+	errors int
+}
+
+func (this *erroringSource) Start(channel EntryChannel, errorChannel ErrorChannel) {
+	close(channel)
+	for idx := 0; idx < this.errors; idx++ {
+		errorChannel <- errors.New("unavailable")
+	}
+	errorChannel <- NewEofError(this)
+}
+
+func TestErrorReporter_ProcessorsWaitForSourceErrors(t *testing.T) {
+	for name, processor := range map[string]func() Processor{
+		"direct":   func() Processor { return NewDirectProcessor() },
+		"buffered": func() Processor { return NewBufferedProcessor(10, time.Second) },
+		"sharded":  func() Processor { return NewShardedProcessor(2, nil) },
+	} {
+		t.Run(name, func(t *testing.T) {
+			errs := make(ErrorChannel, 10)
+			source := &erroringSource{Source: NewSequentialIntegerSource(0, 0), errors: 3}
+			NewStream(source).Sink(NewArraySink()).Process(processor(), errs)
+			close(errs)
+
+			var received []error
+			for err := range errs {
+				received = append(received, err)
+			}
+			if assert.Len(t, received, 4) {
+				assert.IsType(t, &EofError{}, received[3])
+			}
+		})
+	}
+}
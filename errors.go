@@ -1,6 +1,10 @@
 package go_streams
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+	"time"
+)
 
 type EofError struct {
 	source Source
@@ -29,6 +33,11 @@ func (s *SinkError) Error() string {
 	return s.err.Error()
 }
 
+// Unwrap returns the cause of the error
+func (s *SinkError) Unwrap() error {
+	return s.err
+}
+
 type FilterError struct {
 	err error
 }
@@ -44,6 +53,11 @@ func (f *FilterError) Error() string {
 	return f.err.Error()
 }
 
+// Unwrap returns the cause of the error
+func (f *FilterError) Unwrap() error {
+	return f.err
+}
+
 type MapError struct {
 	err error
 }
@@ -59,6 +73,50 @@ func (m *MapError) Error() string {
 	return m.err.Error()
 }
 
+// Unwrap returns the cause of the error
+func (m *MapError) Unwrap() error {
+	return m.err
+}
+
+type OperatorError struct {
+	err error
+}
+
+func NewOperatorError(err error) *OperatorError {
+	if err != nil {
+		return &OperatorError{err: err}
+	}
+	return nil
+}
+
+func (o *OperatorError) Error() string {
+	return o.err.Error()
+}
+
+// Unwrap returns the cause of the error
+func (o *OperatorError) Unwrap() error {
+	return o.err
+}
+
+// PanicError is a recovered panic, it holds the panic value and the stack trace of the goroutine that panicked
+type PanicError struct {
+	Value interface{}
+	Stack []byte
+}
+
+func (pe *PanicError) Error() string {
+	if err, ok := pe.Value.(error); ok {
+		return err.Error()
+	}
+	return fmt.Sprintf("%v", pe.Value)
+}
+
+// Unwrap returns the panic value when it is an error
+func (pe *PanicError) Unwrap() error {
+	err, _ := pe.Value.(error)
+	return err
+}
+
 type SinkBatchError struct {
 	Errors map[string]error
 }
@@ -95,3 +153,187 @@ func NewSameSourceError(source Source) *SameSourceError {
 func (sse *SameSourceError) Error() string {
 	return fmt.Sprintf("Multiple streams with the same source ('%s') found. Sharing sources between different streams aren't allowed at the moment", sse.source.Name())
 }
+
+// FatalError is sent by sources that can't continue (e.g: the connection can't be restored),
+// a source sending a FatalError should return from Start without closing its channel so the
+// engine can restart it according to the stream's restart policy.
+type FatalError struct {
+	Err error
+}
+
+func NewFatalError(err error) *FatalError {
+	return &FatalError{Err: err}
+}
+
+func (fe *FatalError) Error() string {
+	return fmt.Sprintf("fatal source error: %s", fe.Err.Error())
+}
+
+// Unwrap returns the cause of the error
+func (fe *FatalError) Unwrap() error {
+	return fe.Err
+}
+
+type UnknownStreamError struct {
+	name string
+}
+
+func NewUnknownStreamError(name string) *UnknownStreamError {
+	return &UnknownStreamError{name: name}
+}
+
+func (use *UnknownStreamError) Error() string {
+	return fmt.Sprintf("stream '%s' doesn't exist", use.name)
+}
+
+type StreamStateError struct {
+	name   string
+	status StreamStatus
+}
+
+func NewStreamStateError(name string, status StreamStatus) *StreamStateError {
+	return &StreamStateError{name: name, status: status}
+}
+
+func (sse *StreamStateError) Error() string {
+	return fmt.Sprintf("stream '%s' isn't running (status: %s)", sse.name, sse.status)
+}
+
+type UnseekableSourceError struct {
+	name string
+}
+
+func NewUnseekableSourceError(name string) *UnseekableSourceError {
+	return &UnseekableSourceError{name: name}
+}
+
+func (use *UnseekableSourceError) Error() string {
+	return fmt.Sprintf("the source of stream '%s' can't seek", use.name)
+}
+
+// ValidationError is reported for the entries that don't match the schema of Stream.Validate
+type ValidationError struct {
+	Key        string
+	Violations []Violation
+}
+
+func NewValidationError(key string, violations []Violation) *ValidationError {
+	return &ValidationError{Key: key, Violations: violations}
+}
+
+func (ve *ValidationError) Error() string {
+	return fmt.Sprintf("entry '%s' is invalid: %s", ve.Key, ve.violations())
+}
+
+func (ve *ValidationError) violations() string {
+	descriptions := make([]string, len(ve.Violations))
+	for idx := range ve.Violations {
+		descriptions[idx] = ve.Violations[idx].String()
+	}
+	return strings.Join(descriptions, "; ")
+}
+
+// TopologyError is returned by the operator running a topology when one of its nodes failed,
+// the errors of the other nodes reached by the same entry are logged.
+type TopologyError struct {
+	Node string
+	Err  error
+}
+
+func NewTopologyError(node string, err error) *TopologyError {
+	return &TopologyError{Node: node, Err: err}
+}
+
+func (te *TopologyError) Error() string {
+	return fmt.Sprintf("topology node '%s' failed: %s", te.Node, te.Err.Error())
+}
+
+// Unwrap returns the error of the node
+func (te *TopologyError) Unwrap() error {
+	return te.Err
+}
+
+// TimeoutError is reported when a filter, map or sink call didn't return before its deadline (see Stream.WithTimeout),
+// the entry is handled like an entry whose stage panicked.
+type TimeoutError struct {
+	Stage   Stage
+	Timeout time.Duration
+}
+
+func NewTimeoutError(stage Stage, timeout time.Duration) *TimeoutError {
+	return &TimeoutError{Stage: stage, Timeout: timeout}
+}
+
+func (te *TimeoutError) Error() string {
+	return fmt.Sprintf("%s step timed out after %s", te.Stage, te.Timeout)
+}
+
+// ChaosError is the default failure injected in the sinks by a Chaos (see ChaosConfig.SinkErrors)
+type ChaosError struct {
+	Fault string
+}
+
+func NewChaosError(fault string) *ChaosError {
+	return &ChaosError{Fault: fault}
+}
+
+func (ce *ChaosError) Error() string {
+	return fmt.Sprintf("chaos: injected %s", ce.Fault)
+}
+
+// SnapshotError is returned when the state of a stream can't be snapshotted or restored
+type SnapshotError struct {
+	Stream string
+	Err    error
+}
+
+func NewSnapshotError(stream string, err error) *SnapshotError {
+	return &SnapshotError{Stream: stream, Err: err}
+}
+
+func (se *SnapshotError) Error() string {
+	return fmt.Sprintf("snapshot of stream '%s' failed: %s", se.Stream, se.Err.Error())
+}
+
+// Unwrap returns the cause of the error
+func (se *SnapshotError) Unwrap() error {
+	return se.Err
+}
+
+// Stage is the part of the stream that reported an error
+type Stage string
+
+const (
+	SourceStage     Stage = "source"
+	FilterStage     Stage = "filter"
+	MapStage        Stage = "map"
+	OperatorStage   Stage = "operator"
+	SinkStage       Stage = "sink"
+	CommitStage     Stage = "commit"
+	DeadLetterStage Stage = "deadLetter"
+)
+
+// StreamError wraps every error reported by a stream, it tells which stream and stage failed
+// and the key of the entry being processed (empty when the error isn't related to a single entry).
+type StreamError struct {
+	Stream string
+	Stage  Stage
+	Key    string
+	Err    error
+}
+
+func NewStreamError(stream string, stage Stage, key string, err error) *StreamError {
+	return &StreamError{Stream: stream, Stage: stage, Key: key, Err: err}
+}
+
+func (se *StreamError) Error() string {
+	if se.Key == "" {
+		return fmt.Sprintf("stream '%s' failed at %s stage: %s", se.Stream, se.Stage, se.Err.Error())
+	}
+	return fmt.Sprintf("stream '%s' failed at %s stage (key: '%s'): %s", se.Stream, se.Stage, se.Key, se.Err.Error())
+}
+
+// Unwrap returns the cause of the error
+func (se *StreamError) Unwrap() error {
+	return se.Err
+}
@@ -0,0 +1,110 @@
+package go_streams
+
+import "sync"
+
+type subscription struct {
+	id      uint64
+	types   map[EventType]bool
+	handler EventHandler
+}
+
+// eventBus dispatches the engine events to the handlers subscribed to their type
+type eventBus struct {
+	mutex         *sync.RWMutex
+	subscriptions []*subscription
+	nextId        uint64
+}
+
+func newEventBus() *eventBus {
+	return &eventBus{mutex: &sync.RWMutex{}}
+}
+
+func (this *eventBus) subscribe(handler EventHandler, types ...EventType) func() {
+	sub := &subscription{handler: handler}
+	if len(types) > 0 {
+		sub.types = make(map[EventType]bool, len(types))
+		for _, t := range types {
+			sub.types[t] = true
+		}
+	}
+
+	this.mutex.Lock()
+	this.nextId++
+	sub.id = this.nextId
+	this.subscriptions = append(this.subscriptions, sub)
+	this.mutex.Unlock()
+
+	once := &sync.Once{}
+	return func() {
+		once.Do(func() { this.unsubscribe(sub.id) })
+	}
+}
+
+func (this *eventBus) unsubscribe(id uint64) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	for idx, sub := range this.subscriptions {
+		if sub.id == id {
+			this.subscriptions = append(this.subscriptions[:idx:idx], this.subscriptions[idx+1:]...)
+			return
+		}
+	}
+}
+
+func (this *eventBus) publish(event Event) {
+	this.mutex.RLock()
+	subscriptions := this.subscriptions
+	this.mutex.RUnlock()
+
+	for _, sub := range subscriptions {
+		if sub.types == nil || sub.types[event.Type] {
+			sub.handler(event)
+		}
+	}
+}
+
+// Subscribe calls the handler with the events of the given types (all the events when no type is given),
+// handlers are called synchronously by the stream that emitted the event so they shouldn't block.
+// The returned function unsubscribes the handler.
+func (this *engine) Subscribe(handler EventHandler, types ...EventType) func() {
+	return this.bus.subscribe(handler, types...)
+}
+
+// OnStreamStart calls the handler when the engine starts processing a stream
+func (this *engine) OnStreamStart(handler EventHandler) {
+	this.bus.subscribe(handler, StreamStarted)
+}
+
+// OnStreamStop calls the handler when a stream ended (see Event.Status)
+func (this *engine) OnStreamStop(handler EventHandler) {
+	this.bus.subscribe(handler, StreamEnded)
+}
+
+// OnStreamError calls the handler with the errors of the streams, unlike the error handlers
+// it is called synchronously with every error
+func (this *engine) OnStreamError(handler EventHandler) {
+	this.bus.subscribe(handler, StreamErrored)
+}
+
+// OnCommit calls the handler with the keys committed by the streams
+func (this *engine) OnCommit(handler EventHandler) {
+	this.bus.subscribe(handler, EntriesCommitted)
+}
+
+// OnBatchFlush calls the handler with the batches processed by buffered processors
+func (this *engine) OnBatchFlush(handler EventHandler) {
+	this.bus.subscribe(handler, BatchFlushed)
+}
+
+// emitEvent emits an event of a stream processed by the engine, streams processed
+// outside of an engine have no event handlers.
+// eventBinder is implemented by the sinks that emit events to the streams they are bound to (see bindSinks)
+type eventBinder interface {
+	bindEvents(stream Stream)
+}
+
+func emitEvent(stream Stream, event Event) {
+	if source, ok := stream.GetSource().(*managedSource); ok {
+		source.emit(event)
+	}
+}
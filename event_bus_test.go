@@ -0,0 +1,71 @@
+package go_streams
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEngine_Subscribe_LifecycleEvents(t *testing.T) {
+	engine := NewEngine(NewBufferedProcessorFactory(2, time.Second), 10*time.Second)
+	source := NewSequentialIntegerSource(3, 0)
+	all := &eventRecorder{mutex: &sync.Mutex{}}
+	engine.Subscribe(all.record)
+	commits := &eventRecorder{mutex: &sync.Mutex{}}
+	engine.OnCommit(commits.record)
+	flushes := &eventRecorder{mutex: &sync.Mutex{}}
+	engine.OnBatchFlush(flushes.record)
+	errs := &eventRecorder{mutex: &sync.Mutex{}}
+	engine.OnStreamError(errs.record)
+	engine.SetErrorHandler(func(err error) {})
+
+	failed := false
+	assert.Nil(t, engine.Add(NewStream(source).Sink(NewCallbackSink(func(entries ...Entry) error {
+		if !failed {
+			failed = true
+			return fmt.Errorf("sink is down")
+		}
+		return nil
+	}))))
+	engine.Start()
+
+	types := all.types()
+	assert.EqualValues(t, StreamStarted, types[0])
+	assert.EqualValues(t, StreamEnded, types[len(types)-1])
+	assert.EqualValues(t, StreamCompleted, all.all()[len(types)-1].Status)
+	assert.EqualValues(t, source.Name(), all.all()[0].Stream)
+
+	// the first batch failed, the others were committed
+	var keys []string
+	for _, event := range commits.all() {
+		keys = append(keys, event.Keys...)
+	}
+	assert.EqualValues(t, []string{"2", "3"}, keys)
+
+	count := 0
+	for _, event := range flushes.all() {
+		count += event.Count
+	}
+	assert.EqualValues(t, 4, count)
+
+	time.Sleep(10 * time.Millisecond)
+	assert.Len(t, errs.all(), 1)
+	assert.Contains(t, errs.all()[0].Err.Error(), "sink is down")
+}
+
+func TestEngine_Subscribe_Unsubscribes(t *testing.T) {
+	engine := NewEngine(NewDirectProcessorFactory(), 10*time.Second)
+	events := &eventRecorder{mutex: &sync.Mutex{}}
+	unsubscribe := engine.Subscribe(events.record, StreamStarted)
+	unsubscribe()
+	unsubscribe()
+	engine.OnStreamStop(events.record)
+
+	assert.Nil(t, engine.Add(NewStream(NewSequentialIntegerSource(1, 0)).Sink(NewArraySink())))
+	engine.Start()
+
+	assert.EqualValues(t, []EventType{StreamEnded}, events.types())
+}
@@ -0,0 +1,156 @@
+package go_streams
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// FanOutMode decides how a FanOutSink writes to its sinks
+type FanOutMode string
+
+const (
+	// Sequential writes to the sinks one after the other
+	Sequential FanOutMode = "sequential"
+
+	// Parallel writes to all the sinks at once
+	Parallel FanOutMode = "parallel"
+)
+
+// CommitPolicy decides when a FanOutSink write succeeds (and the entries are committed)
+type CommitPolicy string
+
+const (
+	// CommitAll requires every sink to succeed
+	CommitAll CommitPolicy = "all"
+
+	// CommitAny requires at least one sink to succeed
+	CommitAny CommitPolicy = "any"
+)
+
+// FanOutError holds the errors of the failed sinks of a FanOutSink by their index
+type FanOutError struct {
+	Errors map[int]error
+	sinks  []Sink
+}
+
+func (e *FanOutError) Error() string {
+	indexes := make([]int, 0, len(e.Errors))
+	for idx := range e.Errors {
+		indexes = append(indexes, idx)
+	}
+	sort.Ints(indexes)
+
+	failures := make([]string, len(indexes))
+	for i, idx := range indexes {
+		failures[i] = fmt.Sprintf("sink #%d (%s): %s", idx, typeName(e.sinks[idx]), e.Errors[idx].Error())
+	}
+	return fmt.Sprintf("fan out failed for %d of %d sinks: %s", len(indexes), len(e.sinks), strings.Join(failures, "; "))
+}
+
+// FanOutSink writes every entry to all of its sinks, sinks are written even if others failed.
+// Sinks that are AckingSinks aren't bound so they are used as regular sinks.
+type FanOutSink struct {
+	sinks   []Sink
+	mode    FanOutMode
+	policy  CommitPolicy
+	onError ErrorHandler
+}
+
+func NewFanOutSink(mode FanOutMode, policy CommitPolicy, sinks ...Sink) *FanOutSink {
+	return &FanOutSink{sinks: sinks, mode: mode, policy: policy}
+}
+
+// OnError sets the handler of the failures that don't fail the write, i.e: some
+// of the sinks failed with the CommitAny policy. They are logged when no handler is set.
+func (this *FanOutSink) OnError(handler ErrorHandler) *FanOutSink {
+	this.onError = handler
+	return this
+}
+
+func (this *FanOutSink) Single(entry Entry) error {
+	return this.write(func(sink Sink) error { return sink.Single(entry) })
+}
+
+func (this *FanOutSink) Batch(entry ...Entry) error {
+	return this.write(func(sink Sink) error { return sink.Batch(entry...) })
+}
+
+func (this *FanOutSink) Ping() error {
+	return this.each(Sink.Ping)
+}
+
+// Flush flushes the sinks that implement Flusher
+func (this *FanOutSink) Flush() error {
+	return this.each(func(sink Sink) error {
+		if flusher, ok := sink.(Flusher); ok {
+			return flusher.Flush()
+		}
+		return nil
+	})
+}
+
+// Close closes the sinks that implement Closer
+func (this *FanOutSink) Close() error {
+	return this.each(func(sink Sink) error {
+		if closer, ok := sink.(Closer); ok {
+			return closer.Close()
+		}
+		return nil
+	})
+}
+
+func (this *FanOutSink) write(fn func(Sink) error) error {
+	errs := this.run(fn)
+	if len(errs) == 0 {
+		return nil
+	}
+
+	err := &FanOutError{Errors: errs, sinks: this.sinks}
+	if this.policy == CommitAny && len(errs) < len(this.sinks) {
+		if this.onError != nil {
+			this.onError(err)
+		} else {
+			logger.Warn("%s", err.Error())
+		}
+		return nil
+	}
+	return err
+}
+
+// each runs the function on all the sinks and fails if one of them failed
+func (this *FanOutSink) each(fn func(Sink) error) error {
+	if errs := this.run(fn); len(errs) > 0 {
+		return &FanOutError{Errors: errs, sinks: this.sinks}
+	}
+	return nil
+}
+
+func (this *FanOutSink) run(fn func(Sink) error) map[int]error {
+	errs := make(map[int]error)
+	if this.mode != Parallel {
+		for idx, sink := range this.sinks {
+			if err := fn(sink); err != nil {
+				errs[idx] = err
+			}
+		}
+		return errs
+	}
+
+	mutex := &sync.Mutex{}
+	wg := &sync.WaitGroup{}
+	for idx, sink := range this.sinks {
+		wg.Add(1)
+		go func(idx int, sink Sink) {
+			defer wg.Done()
+			if err := fn(sink); err != nil {
+				mutex.Lock()
+				errs[idx] = err
+				mutex.Unlock()
+			}
+		}(idx, sink)
+	}
+	wg.Wait()
+	return errs
+}
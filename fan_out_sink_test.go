@@ -0,0 +1,63 @@
+package go_streams
+
+import (
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func failingSink() Sink {
+	return NewCallbackSink(func(entries ...Entry) error {
+		return fmt.Errorf("unavailable")
+	})
+}
+
+func TestFanOutSink_WritesAllSinks(t *testing.T) {
+	for _, mode := range []FanOutMode{Sequential, Parallel} {
+		sink1, sink2 := NewArraySink(), NewArraySink()
+		sink := NewFanOutSink(mode, CommitAll, sink1, sink2)
+
+		assert.Nil(t, sink.Single(Entry{Key: "1", Value: 1}))
+		assert.Nil(t, sink.Batch(Entry{Key: "2", Value: 2}, Entry{Key: "3", Value: 3}))
+		assert.EqualValues(t, []interface{}{1, 2, 3}, sink1.Array())
+		assert.EqualValues(t, []interface{}{1, 2, 3}, sink2.Array())
+	}
+}
+
+func TestFanOutSink_CommitAll_FailsWithFailedSinks(t *testing.T) {
+	healthy := NewArraySink()
+	sink := NewFanOutSink(Parallel, CommitAll, failingSink(), healthy)
+
+	err := sink.Single(Entry{Key: "1", Value: 1})
+	var fanOutErr *FanOutError
+	assert.True(t, errors.As(err, &fanOutErr))
+	assert.Len(t, fanOutErr.Errors, 1)
+	assert.NotNil(t, fanOutErr.Errors[0])
+	assert.Contains(t, err.Error(), "sink #0")
+	// the other sinks are still written
+	assert.EqualValues(t, []interface{}{1}, healthy.Array())
+}
+
+func TestFanOutSink_CommitAny(t *testing.T) {
+	var partial []error
+	sink := NewFanOutSink(Sequential, CommitAny, failingSink(), NewArraySink()).
+		OnError(func(err error) { partial = append(partial, err) })
+
+	assert.Nil(t, sink.Single(Entry{Key: "1", Value: 1}))
+	assert.Len(t, partial, 1)
+
+	sink = NewFanOutSink(Sequential, CommitAny, failingSink(), failingSink())
+	assert.NotNil(t, sink.Single(Entry{Key: "1", Value: 1}))
+}
+
+func TestFanOutSink_ForwardsLifecycle(t *testing.T) {
+	inner := newLifecycleSink()
+	sink := NewFanOutSink(Sequential, CommitAll, inner, NewArraySink())
+
+	assert.Nil(t, sink.Single(Entry{Key: "1", Value: 1}))
+	assert.Nil(t, sink.Flush())
+	assert.Nil(t, sink.Close())
+	assert.Len(t, inner.flushed, 1)
+	assert.EqualValues(t, 1, inner.closed)
+}
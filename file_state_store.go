@@ -0,0 +1,225 @@
+package go_streams
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// compactThreshold is the minimal number of records in the log before it is compacted
+const compactThreshold = 1024
+
+type fileRecord struct {
+	Key     string
+	Value   interface{}
+	Expires time.Time
+	Deleted bool
+}
+
+// fileStateStore is a persistent store, the state is kept in memory and every change
+// is appended to a log file that is replayed when the store is opened again.
+// Values are encoded with encoding/gob so their types should be registered with gob.Register.
+type fileStateStore struct {
+	path    string
+	file    *os.File
+	values  map[string]*storedValue
+	records int
+	mutex   *sync.Mutex
+}
+
+// NewFileStateStore opens (or creates) the store persisted at the given path
+func NewFileStateStore(path string) (*fileStateStore, error) {
+	store := &fileStateStore{path: path, values: make(map[string]*storedValue), mutex: &sync.Mutex{}}
+	if err := store.replay(); err != nil {
+		return nil, err
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	store.file = file
+	return store, nil
+}
+
+func (this *fileStateStore) Get(key string) (interface{}, bool, error) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	stored, found := this.values[key]
+	if !found || stored.expired(time.Now()) {
+		return nil, false, nil
+	}
+	return stored.value, true, nil
+}
+
+func (this *fileStateStore) Put(key string, value interface{}, ttl time.Duration) error {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	record := fileRecord{Key: key, Value: value, Expires: expiryOf(ttl, time.Now())}
+	if err := this.append(record); err != nil {
+		return err
+	}
+	this.values[key] = &storedValue{key: key, value: value, expires: record.Expires}
+	return this.compactIfNeeded()
+}
+
+func (this *fileStateStore) Delete(key string) error {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	if _, found := this.values[key]; !found {
+		return nil
+	}
+	if err := this.append(fileRecord{Key: key, Deleted: true}); err != nil {
+		return err
+	}
+	delete(this.values, key)
+	return this.compactIfNeeded()
+}
+
+// Compact rewrites the log with the keys that didn't expire
+func (this *fileStateStore) Compact() error {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	return this.compact()
+}
+
+// Close closes the log file
+func (this *fileStateStore) Close() error {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	return this.file.Close()
+}
+
+func (this *fileStateStore) append(record fileRecord) error {
+	data, err := encodeRecord(record)
+	if err != nil {
+		return err
+	}
+	if _, err := this.file.Write(data); err != nil {
+		return err
+	}
+	this.records++
+	return nil
+}
+
+func (this *fileStateStore) compactIfNeeded() error {
+	if this.records < compactThreshold || this.records < 2*len(this.values) {
+		return nil
+	}
+	return this.compact()
+}
+
+func (this *fileStateStore) compact() error {
+	tmp := this.path + ".compact"
+	file, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	writer := bufio.NewWriter(file)
+	records := 0
+	for key, stored := range this.values {
+		if stored.expired(now) {
+			delete(this.values, key)
+			continue
+		}
+		data, err := encodeRecord(fileRecord{Key: key, Value: stored.value, Expires: stored.expires})
+		if err == nil {
+			_, err = writer.Write(data)
+		}
+		if err != nil {
+			_ = file.Close()
+			return err
+		}
+		records++
+	}
+	if err := writer.Flush(); err != nil {
+		_ = file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	_ = this.file.Close()
+	if err := os.Rename(tmp, this.path); err != nil {
+		return err
+	}
+	this.file, err = os.OpenFile(this.path, os.O_WRONLY|os.O_APPEND, 0644)
+	this.records = records
+	return err
+}
+
+func (this *fileStateStore) replay() error {
+	file, err := os.Open(this.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	now := time.Now()
+	reader := bufio.NewReader(file)
+	header := make([]byte, 4)
+	// offset is the end of the last complete record
+	var offset int64
+	for {
+		if _, err := io.ReadFull(reader, header); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			if err == io.ErrUnexpectedEOF {
+				return this.truncate(offset)
+			}
+			return err
+		}
+		data := make([]byte, binary.BigEndian.Uint32(header))
+		if _, err := io.ReadFull(reader, data); err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				return this.truncate(offset)
+			}
+			return err
+		}
+		offset += int64(len(header) + len(data))
+
+		var record fileRecord
+		if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&record); err != nil {
+			return err
+		}
+		this.records++
+		if record.Deleted || (!record.Expires.IsZero() && !now.Before(record.Expires)) {
+			delete(this.values, record.Key)
+			continue
+		}
+		this.values[record.Key] = &storedValue{key: record.Key, value: record.Value, expires: record.Expires}
+	}
+}
+
+// truncate drops a partial record, it is the tail of a write that didn't complete and
+// the records appended after it couldn't be replayed
+func (this *fileStateStore) truncate(offset int64) error {
+	logger.Warn("Dropping the partial record at the end of state store '%s' (offset: %d)", this.path, offset)
+	return os.Truncate(this.path, offset)
+}
+
+func encodeRecord(record fileRecord) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.Write(make([]byte, 4))
+	if err := gob.NewEncoder(&buf).Encode(&record); err != nil {
+		return nil, err
+	}
+	data := buf.Bytes()
+	binary.BigEndian.PutUint32(data, uint32(len(data)-4))
+	return data, nil
+}
@@ -1,5 +1,8 @@
 module github.com/matang28/go-streams
 
-go 1.11
+go 1.13
 
-require github.com/stretchr/testify v1.5.1
+require (
+	github.com/stretchr/testify v1.5.1
+	gopkg.in/yaml.v2 v2.2.2
+)
@@ -0,0 +1,263 @@
+package grpc
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	streams "github.com/matang28/go-streams"
+)
+
+const (
+	defaultAckEvery    = 1000
+	defaultAckInterval = 5 * time.Second
+	defaultMaxRetries  = 3
+)
+
+// SenderStream is the client side of a client-streaming RPC (e.g: the generated Service_UploadClient),
+// CloseAndRecv ends the stream and returns the response of the server acknowledging the sent messages.
+type SenderStream interface {
+	Send(message interface{}) error
+	CloseAndRecv() (interface{}, error)
+}
+
+// OpenSendFunc starts the RPC, the context is cancelled when the sink is closed
+type OpenSendFunc func(ctx context.Context) (SenderStream, error)
+
+// SinkConfig configures a Sink
+type SinkConfig struct {
+	// Encode converts an entry to the request message (defaults to the entry value)
+	Encode func(entry streams.Entry) (interface{}, error)
+
+	// AckEvery is the number of messages sent before the stream is closed and acknowledged (defaults to 1000)
+	AckEvery int
+
+	// AckInterval is the time after which the sent messages are acknowledged anyway (defaults to 5s)
+	AckInterval time.Duration
+
+	// Ack checks the response of the server, an error fails the acknowledgement and the messages are sent again
+	Ack func(response interface{}) error
+
+	// MaxRetries is the number of times the stream is reopened after a failure before the write fails (defaults to 3),
+	// Backoff is the delay before the first reopen (defaults to 100ms), it doubles on every retry.
+	MaxRetries int
+	Backoff    time.Duration
+}
+
+type sentMessage struct {
+	binding *binding
+	key     string
+	message interface{}
+}
+
+// binding is the committer of a stream bound to the sink
+type binding struct {
+	commit streams.Committer
+}
+
+// Sink writes entries over a long-lived client-streaming RPC, the stream is closed every AckEvery messages
+// or AckInterval and the response acknowledges the messages sent over it. Sent messages are kept until they
+// are acknowledged and are sent again over a new stream when it fails, so the server may receive them twice.
+// Streams dumping into the sink commit their entries once they are acknowledged (see streams.AckingSink).
+type Sink struct {
+	config SinkConfig
+	open   OpenSendFunc
+
+	mutex   *sync.Mutex
+	stream  SenderStream
+	pending []sentMessage
+
+	ctx       context.Context
+	cancel    context.CancelFunc
+	closeOnce sync.Once
+}
+
+func NewSink(open OpenSendFunc, config SinkConfig) (*Sink, error) {
+	if open == nil {
+		return nil, fmt.Errorf("grpc sink requires an open function")
+	}
+	if config.Encode == nil {
+		config.Encode = func(entry streams.Entry) (interface{}, error) { return entry.Value, nil }
+	}
+	if config.AckEvery <= 0 {
+		config.AckEvery = defaultAckEvery
+	}
+	if config.AckInterval <= 0 {
+		config.AckInterval = defaultAckInterval
+	}
+	if config.MaxRetries <= 0 {
+		config.MaxRetries = defaultMaxRetries
+	}
+	if config.Backoff <= 0 {
+		config.Backoff = defaultBackoff
+	}
+
+	sink := &Sink{config: config, open: open, mutex: &sync.Mutex{}}
+	sink.ctx, sink.cancel = context.WithCancel(context.Background())
+	go sink.ackPeriodically()
+	return sink, nil
+}
+
+func (this *Sink) Single(entry streams.Entry) error {
+	return this.write(nil, []streams.Entry{entry})
+}
+
+func (this *Sink) Batch(entry ...streams.Entry) error {
+	return this.write(nil, entry)
+}
+
+func (this *Sink) Ping() error {
+	return nil
+}
+
+// Bind returns the sink used by a single stream, its entries are committed once the server acknowledged them
+func (this *Sink) Bind(commit streams.Committer) streams.Sink {
+	return &boundSink{Sink: this, binding: &binding{commit: commit}}
+}
+
+// Flush acknowledges the sent messages and commits their entries
+func (this *Sink) Flush() error {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	return this.ack()
+}
+
+// Close stops the periodic acknowledgements and acknowledges the sent messages
+func (this *Sink) Close() error {
+	err := this.Flush()
+	this.closeOnce.Do(this.cancel)
+	return err
+}
+
+func (this *Sink) write(binding *binding, entries []streams.Entry) error {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	for idx := range entries {
+		message, err := this.config.Encode(entries[idx])
+		if err != nil {
+			return err
+		}
+		this.pending = append(this.pending, sentMessage{binding: binding, key: entries[idx].Key, message: message})
+		if this.stream == nil || this.stream.Send(message) != nil {
+			if err := this.reopen(); err != nil {
+				return err
+			}
+		}
+		if len(this.pending) >= this.config.AckEvery {
+			if err := this.ack(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// reopen opens a new stream and sends the pending messages over it, the caller holds the mutex
+func (this *Sink) reopen() error {
+	this.stream = nil
+	backoff := this.config.Backoff
+	for attempt := 0; ; attempt++ {
+		err := this.resend()
+		if err == nil {
+			return nil
+		}
+		if attempt >= this.config.MaxRetries {
+			return fmt.Errorf("failed to send over grpc stream after %d retries: %s", attempt, err.Error())
+		}
+		streams.LogWith(streams.Fields{streams.FieldStage: streams.SinkStage, "sink": "grpc"}).Warn("Grpc stream failed, reopening in %s: %v", backoff, err)
+		select {
+		case <-this.ctx.Done():
+			return this.ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+func (this *Sink) resend() error {
+	stream, err := this.open(this.ctx)
+	if err != nil {
+		return err
+	}
+	for idx := range this.pending {
+		if err := stream.Send(this.pending[idx].message); err != nil {
+			return err
+		}
+	}
+	this.stream = stream
+	return nil
+}
+
+// ack closes the stream and commits the entries of the acknowledged messages, the caller holds the mutex
+func (this *Sink) ack() error {
+	if len(this.pending) == 0 {
+		return nil
+	}
+	if this.stream == nil {
+		if err := this.reopen(); err != nil {
+			return err
+		}
+	}
+
+	response, err := this.stream.CloseAndRecv()
+	this.stream = nil
+	if err == nil && this.config.Ack != nil {
+		err = this.config.Ack(response)
+	}
+	if err != nil {
+		return fmt.Errorf("grpc stream wasn't acknowledged: %s", err.Error())
+	}
+
+	// commits are cumulative, only the latest key of every stream is committed
+	var bindings []*binding
+	latest := make(map[*binding]string)
+	for _, sent := range this.pending {
+		if sent.binding == nil {
+			continue
+		}
+		if _, found := latest[sent.binding]; !found {
+			bindings = append(bindings, sent.binding)
+		}
+		latest[sent.binding] = sent.key
+	}
+	this.pending = nil
+
+	for _, b := range bindings {
+		if err := b.commit(latest[b]); err != nil {
+			streams.LogWith(streams.Fields{streams.FieldStage: streams.SinkStage, "sink": "grpc"}).Error("Failed to commit entries acknowledged by grpc sink: %s", err.Error())
+		}
+	}
+	return nil
+}
+
+func (this *Sink) ackPeriodically() {
+	ticker := time.NewTicker(this.config.AckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-this.ctx.Done():
+			return
+		case <-ticker.C:
+			if err := this.Flush(); err != nil {
+				streams.LogWith(streams.Fields{streams.FieldStage: streams.SinkStage, "sink": "grpc"}).Error("Failed to acknowledge grpc stream: %s", err.Error())
+			}
+		}
+	}
+}
+
+// boundSink is the sink of a single stream
+type boundSink struct {
+	*Sink
+	binding *binding
+}
+
+func (this *boundSink) Single(entry streams.Entry) error {
+	return this.Batch(entry)
+}
+
+func (this *boundSink) Batch(entry ...streams.Entry) error {
+	return this.Sink.write(this.binding, entry)
+}
@@ -0,0 +1,168 @@
+package grpc
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	streams "github.com/matang28/go-streams"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeReceiverServer keeps the messages of the acknowledged streams, the next `failSends` sends fail
+type fakeReceiverServer struct {
+	mutex     *sync.Mutex
+	opens     int
+	failSends int
+	failAcks  int
+	received  []interface{}
+}
+
+func newFakeReceiverServer() *fakeReceiverServer {
+	return &fakeReceiverServer{mutex: &sync.Mutex{}}
+}
+
+func (this *fakeReceiverServer) open(ctx context.Context) (SenderStream, error) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.opens++
+	return &fakeSender{server: this}, nil
+}
+
+func (this *fakeReceiverServer) messages() []interface{} {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	return append([]interface{}{}, this.received...)
+}
+
+type fakeSender struct {
+	server *fakeReceiverServer
+	sent   []interface{}
+}
+
+func (this *fakeSender) Send(message interface{}) error {
+	this.server.mutex.Lock()
+	defer this.server.mutex.Unlock()
+	if this.server.failSends > 0 {
+		this.server.failSends--
+		return fmt.Errorf("stream reset")
+	}
+	this.sent = append(this.sent, message)
+	return nil
+}
+
+func (this *fakeSender) CloseAndRecv() (interface{}, error) {
+	this.server.mutex.Lock()
+	defer this.server.mutex.Unlock()
+	if this.server.failAcks > 0 {
+		this.server.failAcks--
+		return nil, fmt.Errorf("unavailable")
+	}
+	this.server.received = append(this.server.received, this.sent...)
+	return len(this.sent), nil
+}
+
+type commitRecorder struct {
+	mutex *sync.Mutex
+	keys  []string
+}
+
+func (this *commitRecorder) commit(keys ...string) error {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.keys = append(this.keys, keys...)
+	return nil
+}
+
+func (this *commitRecorder) committed() []string {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	return append([]string{}, this.keys...)
+}
+
+func TestSink_Bind_CommitsAcknowledgedEntries(t *testing.T) {
+	server := newFakeReceiverServer()
+	var responses []interface{}
+	sink, err := NewSink(server.open, SinkConfig{AckEvery: 3, AckInterval: time.Hour, Ack: func(response interface{}) error {
+		responses = append(responses, response)
+		return nil
+	}})
+	assert.Nil(t, err)
+	defer sink.Close()
+
+	a, b := &commitRecorder{mutex: &sync.Mutex{}}, &commitRecorder{mutex: &sync.Mutex{}}
+	boundA, boundB := sink.Bind(a.commit), sink.Bind(b.commit)
+	assert.Nil(t, boundA.Batch(streams.Entry{Key: "a1", Value: 1}, streams.Entry{Key: "a2", Value: 2}))
+	assert.Empty(t, a.committed())
+	assert.Nil(t, boundB.Batch(streams.Entry{Key: "b1", Value: 3}, streams.Entry{Key: "b2", Value: 4}))
+
+	assert.EqualValues(t, []string{"a2"}, a.committed())
+	assert.EqualValues(t, []string{"b1"}, b.committed())
+	assert.EqualValues(t, []interface{}{1, 2, 3}, server.messages())
+
+	assert.Nil(t, sink.Flush())
+	assert.EqualValues(t, []string{"b1", "b2"}, b.committed())
+	assert.EqualValues(t, []interface{}{1, 2, 3, 4}, server.messages())
+	assert.EqualValues(t, []interface{}{3, 1}, responses)
+}
+
+func TestSink_ResendsOverANewStreamAfterFailures(t *testing.T) {
+	server := newFakeReceiverServer()
+	sink, err := NewSink(server.open, SinkConfig{AckInterval: time.Hour, Backoff: time.Millisecond})
+	assert.Nil(t, err)
+	defer sink.Close()
+
+	commits := &commitRecorder{mutex: &sync.Mutex{}}
+	bound := sink.Bind(commits.commit)
+	assert.Nil(t, bound.Single(streams.Entry{Key: "1", Value: 1}))
+	server.failSends = 1
+	assert.Nil(t, bound.Single(streams.Entry{Key: "2", Value: 2}))
+
+	// the failed acknowledgement keeps the messages, they are sent again over the next stream
+	server.failAcks = 1
+	assert.NotNil(t, sink.Flush())
+	assert.Empty(t, commits.committed())
+	assert.Nil(t, sink.Flush())
+
+	assert.EqualValues(t, []string{"2"}, commits.committed())
+	assert.EqualValues(t, []interface{}{1, 2}, server.messages())
+	assert.EqualValues(t, 3, server.opens)
+}
+
+func TestSink_FailsOnceTheRetriesAreExhausted(t *testing.T) {
+	server := newFakeReceiverServer()
+	sink, err := NewSink(server.open, SinkConfig{AckInterval: time.Hour, MaxRetries: 2, Backoff: time.Millisecond})
+	assert.Nil(t, err)
+	defer sink.Close()
+
+	server.failSends = 3
+	assert.NotNil(t, sink.Single(streams.Entry{Key: "1", Value: 1}))
+	assert.EqualValues(t, 3, server.opens)
+	assert.Nil(t, sink.Flush())
+	assert.EqualValues(t, []interface{}{1}, server.messages())
+}
+
+func TestSink_AcknowledgesPeriodically(t *testing.T) {
+	server := newFakeReceiverServer()
+	sink, err := NewSink(server.open, SinkConfig{AckInterval: 10 * time.Millisecond})
+	assert.Nil(t, err)
+	defer sink.Close()
+
+	commits := &commitRecorder{mutex: &sync.Mutex{}}
+	assert.Nil(t, sink.Bind(commits.commit).Single(streams.Entry{Key: "1", Value: 1}))
+	assert.Eventually(t, func() bool { return len(commits.committed()) == 1 }, time.Second, 5*time.Millisecond)
+}
+
+func TestSink_InEngine(t *testing.T) {
+	server := newFakeReceiverServer()
+	sink, err := NewSink(server.open, SinkConfig{AckEvery: 4, AckInterval: time.Hour})
+	assert.Nil(t, err)
+
+	engine := streams.NewEngine(streams.NewDirectProcessorFactory(), 10*time.Second)
+	assert.Nil(t, engine.Add(streams.NewStream(streams.NewSequentialIntegerSource(10, 0)).Sink(sink)))
+	engine.Start()
+	assert.Nil(t, sink.Close())
+	assert.Len(t, server.messages(), 11)
+}
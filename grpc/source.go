@@ -0,0 +1,256 @@
+// Package grpc holds a source reading server-streaming RPCs and a sink writing client-streaming RPCs,
+// they work with the generated clients of any service through small adapters (no dependency on grpc-go).
+package grpc
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	streams "github.com/matang28/go-streams"
+)
+
+const (
+	defaultBackoff    = 100 * time.Millisecond
+	defaultMaxBackoff = 30 * time.Second
+	resumeKey         = "resume"
+)
+
+// Metadata are the resume attributes of a stream, sent as outgoing metadata of the RPC (e.g: the last offset)
+type Metadata map[string]string
+
+// Receiver is the client side of a server-streaming RPC (e.g: the generated Service_SubscribeClient),
+// Recv returns io.EOF once the server ended the stream.
+type Receiver interface {
+	Recv() (interface{}, error)
+}
+
+// OpenFunc starts the RPC, the resume metadata is empty on the first call.
+// The context is cancelled when the source is stopped.
+type OpenFunc func(ctx context.Context, resume Metadata) (Receiver, error)
+
+type SourceConfig struct {
+	// Name of the source (defaults to a generated name)
+	Name string
+
+	// Resume returns the metadata the stream can be resumed after the message with (nil when it has none)
+	Resume func(message interface{}) Metadata
+
+	// Store persists the resume metadata of the committed entries so a restarted source resumes after them
+	Store streams.StateStore
+
+	// Retryable tells whether the RPC should be reopened after the error (defaults to always),
+	// the source fails with a FatalError otherwise.
+	Retryable func(err error) bool
+
+	// EndOnEOF completes the source once the server ended the stream instead of reopening it
+	EndOnEOF bool
+
+	// Backoff is the delay before the first reopen (defaults to 100ms), it doubles on every
+	// consecutive failure up to MaxBackoff (defaults to 30s).
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+}
+
+type pendingResume struct {
+	seq    uint64
+	resume Metadata
+}
+
+// Source emits the messages of a server-streaming RPC and reopens it with the resume metadata
+// of the latest received message, committing an entry persists its resume metadata.
+type Source struct {
+	config SourceConfig
+	open   OpenFunc
+	name   string
+
+	mutex   *sync.Mutex
+	seq     uint64
+	resume  Metadata
+	pending []pendingResume
+
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func NewSource(open OpenFunc, config SourceConfig) (*Source, error) {
+	if open == nil {
+		return nil, fmt.Errorf("grpc source requires an open function")
+	}
+	if config.Retryable == nil {
+		config.Retryable = func(error) bool { return true }
+	}
+	if config.Backoff <= 0 {
+		config.Backoff = defaultBackoff
+	}
+	if config.MaxBackoff < config.Backoff {
+		config.MaxBackoff = defaultMaxBackoff
+	}
+	name := config.Name
+	if name == "" {
+		name = fmt.Sprintf("grpcSource-%d", time.Now().UnixNano())
+	}
+
+	source := &Source{config: config, open: open, name: name, mutex: &sync.Mutex{}}
+	source.ctx, source.cancel = context.WithCancel(context.Background())
+	if config.Store != nil {
+		stored, found, err := config.Store.Get(name + "/" + resumeKey)
+		if err != nil {
+			return nil, err
+		}
+		if encoded, ok := stored.(string); found && ok {
+			if source.resume, err = decodeMetadata(encoded); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return source, nil
+}
+
+func (this *Source) Start(channel streams.EntryChannel, errorChannel streams.ErrorChannel) {
+	log := streams.LogWith(streams.Fields{streams.FieldStream: this.name})
+	log.Info("Starting grpc source")
+
+	backoff := this.config.Backoff
+	for {
+		received, err := this.receive(channel)
+		if this.ctx.Err() != nil || (err == io.EOF && this.config.EndOnEOF) {
+			break
+		}
+		if received {
+			backoff = this.config.Backoff
+		}
+		if err != io.EOF && !this.config.Retryable(err) {
+			errorChannel <- streams.NewFatalError(err)
+			return
+		}
+		if err != io.EOF {
+			errorChannel <- err
+		}
+		log.Warn("Grpc stream ended, reopening in %s: %v", backoff, err)
+		select {
+		case <-this.ctx.Done():
+		case <-time.After(backoff):
+		}
+		if this.ctx.Err() != nil {
+			break
+		}
+		if backoff *= 2; backoff > this.config.MaxBackoff {
+			backoff = this.config.MaxBackoff
+		}
+	}
+	close(channel)
+	errorChannel <- streams.NewEofError(this)
+	log.Info("Grpc source stopped")
+}
+
+// receive opens the RPC and emits its messages until it fails, it tells whether messages were received
+func (this *Source) receive(channel streams.EntryChannel) (bool, error) {
+	this.mutex.Lock()
+	resume := this.resume
+	this.mutex.Unlock()
+
+	receiver, err := this.open(this.ctx, resume)
+	if err != nil {
+		return false, err
+	}
+	received := false
+	for {
+		message, err := receiver.Recv()
+		if err != nil {
+			return received, err
+		}
+		received = true
+		channel <- this.newEntry(message)
+	}
+}
+
+func (this *Source) newEntry(message interface{}) streams.Entry {
+	var resume Metadata
+	if this.config.Resume != nil {
+		resume = this.config.Resume(message)
+	}
+
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.seq++
+	if resume != nil {
+		this.resume = resume
+		this.pending = append(this.pending, pendingResume{seq: this.seq, resume: resume})
+	}
+	return streams.Entry{Key: strconv.FormatUint(this.seq, 10), Value: message, Timestamp: time.Now()}
+}
+
+func (this *Source) Stop() error {
+	streams.LogWith(streams.Fields{streams.FieldStream: this.name}).Info("Stopping grpc source")
+	this.cancel()
+	return nil
+}
+
+func (this *Source) Ping() error {
+	return nil
+}
+
+// CommitEntry persists the resume metadata of the latest of the keys
+func (this *Source) CommitEntry(keys ...string) error {
+	var latest uint64
+	for _, key := range keys {
+		seq, err := strconv.ParseUint(key, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid grpc entry key: '%s'", key)
+		}
+		if seq > latest {
+			latest = seq
+		}
+	}
+
+	this.mutex.Lock()
+	var resume Metadata
+	done := 0
+	for done < len(this.pending) && this.pending[done].seq <= latest {
+		resume = this.pending[done].resume
+		done++
+	}
+	this.pending = this.pending[done:]
+	this.mutex.Unlock()
+
+	if resume == nil || this.config.Store == nil {
+		return nil
+	}
+	return this.config.Store.Put(this.name+"/"+resumeKey, encodeMetadata(resume), 0)
+}
+
+// Resume returns the resume metadata of the latest received entry
+func (this *Source) Resume() Metadata {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	return this.resume
+}
+
+func (this *Source) Name() string {
+	return this.name
+}
+
+func encodeMetadata(metadata Metadata) string {
+	values := url.Values{}
+	for k, v := range metadata {
+		values.Set(k, v)
+	}
+	return values.Encode()
+}
+
+func decodeMetadata(encoded string) (Metadata, error) {
+	values, err := url.ParseQuery(encoded)
+	if err != nil {
+		return nil, err
+	}
+	metadata := make(Metadata, len(values))
+	for k := range values {
+		metadata[k] = values.Get(k)
+	}
+	return metadata, nil
+}
@@ -0,0 +1,146 @@
+package grpc
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	streams "github.com/matang28/go-streams"
+	"github.com/stretchr/testify/assert"
+)
+
+type offsetMessage struct {
+	Offset int
+}
+
+// fakeServer streams offsets starting after the resume metadata, every stream fails after `per` messages
+type fakeServer struct {
+	mutex   *sync.Mutex
+	per     int
+	total   int
+	resumes []Metadata
+}
+
+func (this *fakeServer) open(ctx context.Context, resume Metadata) (Receiver, error) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.resumes = append(this.resumes, resume)
+	next := 1
+	if resume != nil {
+		fmt.Sscan(resume["offset"], &next)
+		next++
+	}
+	return &fakeReceiver{server: this, next: next, left: this.per}, nil
+}
+
+func (this *fakeServer) opened() []Metadata {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	return append([]Metadata{}, this.resumes...)
+}
+
+type fakeReceiver struct {
+	server *fakeServer
+	next   int
+	left   int
+}
+
+func (this *fakeReceiver) Recv() (interface{}, error) {
+	if this.next > this.server.total {
+		return nil, io.EOF
+	}
+	if this.left == 0 {
+		return nil, fmt.Errorf("connection reset")
+	}
+	this.left--
+	this.next++
+	return &offsetMessage{Offset: this.next - 1}, nil
+}
+
+func resumeOffset(message interface{}) Metadata {
+	return Metadata{"offset": fmt.Sprint(message.(*offsetMessage).Offset)}
+}
+
+func TestSource_ReopensWithTheResumeMetadata(t *testing.T) {
+	server := &fakeServer{mutex: &sync.Mutex{}, per: 2, total: 5}
+	source, err := NewSource(server.open, SourceConfig{Name: "orders", Resume: resumeOffset, EndOnEOF: true, Backoff: time.Millisecond})
+	assert.Nil(t, err)
+
+	channel := make(streams.EntryChannel, 10)
+	errs := make(streams.ErrorChannel, 10)
+	go source.Start(channel, errs)
+
+	var offsets []int
+	for entry := range channel {
+		offsets = append(offsets, entry.Value.(*offsetMessage).Offset)
+	}
+	assert.EqualValues(t, []int{1, 2, 3, 4, 5}, offsets)
+	assert.EqualValues(t, []Metadata{nil, {"offset": "2"}, {"offset": "4"}}, server.opened())
+	assert.EqualValues(t, Metadata{"offset": "5"}, source.Resume())
+
+	assert.EqualValues(t, "connection reset", (<-errs).Error())
+	assert.EqualValues(t, "connection reset", (<-errs).Error())
+	assert.IsType(t, &streams.EofError{}, <-errs)
+}
+
+func TestSource_CommitPersistsTheResumeMetadata(t *testing.T) {
+	store := streams.NewMemoryStateStore(0)
+	server := &fakeServer{mutex: &sync.Mutex{}, per: 10, total: 3}
+	source, err := NewSource(server.open, SourceConfig{Name: "orders", Resume: resumeOffset, Store: store, EndOnEOF: true})
+	assert.Nil(t, err)
+
+	channel := make(streams.EntryChannel, 10)
+	go source.Start(channel, make(streams.ErrorChannel, 10))
+	var keys []string
+	for entry := range channel {
+		keys = append(keys, entry.Key)
+	}
+	assert.Nil(t, source.CommitEntry(keys[0], keys[1]))
+
+	// a restarted source resumes after the committed entries
+	server.total = 4
+	source, err = NewSource(server.open, SourceConfig{Name: "orders", Resume: resumeOffset, Store: store, EndOnEOF: true})
+	assert.Nil(t, err)
+	assert.EqualValues(t, Metadata{"offset": "2"}, source.Resume())
+	channel = make(streams.EntryChannel, 10)
+	go source.Start(channel, make(streams.ErrorChannel, 10))
+	var offsets []int
+	for entry := range channel {
+		offsets = append(offsets, entry.Value.(*offsetMessage).Offset)
+	}
+	assert.EqualValues(t, []int{3, 4}, offsets)
+}
+
+func TestSource_FailsOnNonRetryableErrors(t *testing.T) {
+	opens := 0
+	source, err := NewSource(func(ctx context.Context, resume Metadata) (Receiver, error) {
+		opens++
+		return nil, fmt.Errorf("permission denied")
+	}, SourceConfig{Retryable: func(err error) bool { return err.Error() != "permission denied" }})
+	assert.Nil(t, err)
+
+	errs := make(streams.ErrorChannel, 10)
+	source.Start(make(streams.EntryChannel, 10), errs)
+	assert.IsType(t, &streams.FatalError{}, <-errs)
+	assert.EqualValues(t, 1, opens)
+}
+
+func TestSource_Stop(t *testing.T) {
+	source, err := NewSource(func(ctx context.Context, resume Metadata) (Receiver, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}, SourceConfig{})
+	assert.Nil(t, err)
+
+	channel := make(streams.EntryChannel, 10)
+	errs := make(streams.ErrorChannel, 10)
+	go source.Start(channel, errs)
+	assert.Nil(t, source.Stop())
+
+	_, open := <-channel
+	assert.False(t, open)
+	assert.IsType(t, &streams.EofError{}, <-errs)
+}
@@ -0,0 +1,148 @@
+package go_streams
+
+import (
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// StreamHealth is the health of a single stream, a stream is failing when its latest
+// error is more recent than its latest successful sink write.
+type StreamHealth struct {
+	Name    string       `json:"name"`
+	Status  StreamStatus `json:"status"`
+	Failing bool         `json:"failing"`
+
+	// Lag estimates the entries received but not committed yet (including the buffered ones)
+	Lag uint64 `json:"lag"`
+
+	LastError   string     `json:"lastError,omitempty"`
+	LastErrorAt *time.Time `json:"lastErrorAt,omitempty"`
+	LastSinkAt  *time.Time `json:"lastSinkAt,omitempty"`
+}
+
+// Health tells whether the engine is alive (running without failed streams) and ready
+// (alive and none of its streams is failing).
+type Health struct {
+	Live    bool           `json:"live"`
+	Ready   bool           `json:"ready"`
+	Streams []StreamHealth `json:"streams"`
+}
+
+// Health returns the health of the engine and its streams sorted by name
+func (this *engine) Health() Health {
+	infos := this.Streams()
+
+	this.mutex.RLock()
+	health := Health{Live: this.running && !this.finished, Streams: make([]StreamHealth, 0, len(infos))}
+	sources := make(map[string]*managedSource, len(this.streams))
+	for name, s := range this.streams {
+		sources[name] = s.source
+	}
+	this.mutex.RUnlock()
+
+	health.Ready = health.Live
+	for _, info := range infos {
+		source, found := sources[info.Name]
+		if !found {
+			continue
+		}
+		sh := source.health()
+		sh.Name, sh.Status = info.Name, info.Status
+		if info.Received > info.Committed {
+			sh.Lag = info.Received - info.Committed
+		}
+		if sh.Status == StreamFailed {
+			health.Live = false
+		}
+		if sh.Failing {
+			health.Ready = false
+		}
+		health.Streams = append(health.Streams, sh)
+	}
+	health.Ready = health.Ready && health.Live
+	return health
+}
+
+// recordError keeps the latest error of the stream for its health
+func (this *engine) recordError(se *StreamError) {
+	this.mutex.RLock()
+	s, found := this.streams[se.Stream]
+	this.mutex.RUnlock()
+	if found {
+		s.source.recordError(se.Err)
+	}
+}
+
+func (this *managedSource) recordError(err error) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.lastError = err
+	this.lastErrorAt = time.Now()
+}
+
+func (this *managedSource) health() StreamHealth {
+	var health StreamHealth
+	sinked := atomic.LoadInt64(&this.lastSinkAt)
+	if sinked > 0 {
+		at := time.Unix(0, sinked)
+		health.LastSinkAt = &at
+	}
+
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	if this.lastError != nil {
+		at := this.lastErrorAt
+		health.LastError = this.lastError.Error()
+		health.LastErrorAt = &at
+		health.Failing = health.LastSinkAt == nil || at.After(*health.LastSinkAt)
+	}
+	return health
+}
+
+// markSinked records a successful sink write of a stream processed by the engine
+func markSinked(stream Stream) {
+	if source, ok := stream.GetSource().(*managedSource); ok {
+		atomic.StoreInt64(&source.lastSinkAt, time.Now().UnixNano())
+	}
+}
+
+type healthHandler struct {
+	engine interface{ Health() Health }
+}
+
+// NewHealthHandler creates an http handler for the probes of the engine (e.g: kubernetes):
+//
+//	GET /live     200 while the engine is alive, 503 otherwise
+//	GET /ready    200 while the engine is ready, 503 otherwise
+//	GET /health   the health of the engine and its streams
+func NewHealthHandler(engine interface{ Health() Health }) http.Handler {
+	return &healthHandler{engine: engine}
+}
+
+func (this *healthHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodGet) {
+		return
+	}
+
+	health := this.engine.Health()
+	ok := false
+	switch strings.Trim(r.URL.Path, "/") {
+	case "live":
+		ok = health.Live
+	case "ready":
+		ok = health.Ready
+	case "health":
+		ok = health.Live
+	default:
+		http.NotFound(w, r)
+		return
+	}
+
+	status := http.StatusOK
+	if !ok {
+		status = http.StatusServiceUnavailable
+	}
+	writeJSON(w, status, health)
+}
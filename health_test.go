@@ -0,0 +1,80 @@
+package go_streams
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEngine_Health(t *testing.T) {
+	engine := NewEngine(NewDirectProcessorFactory(), 10*time.Second)
+	healthy := NewSequentialIntegerSource(0, time.Millisecond)
+	failing := NewSequentialIntegerSource(0, time.Millisecond)
+	var broken int32 = 1
+	engine.SetErrorHandler(func(err error) {})
+	assert.Nil(t, engine.Add(
+		NewStream(healthy).Sink(NewArraySink()),
+		NewStream(failing).Sink(NewCallbackSink(func(entries ...Entry) error {
+			if atomic.LoadInt32(&broken) == 1 {
+				return fmt.Errorf("sink is down")
+			}
+			return nil
+		})),
+	))
+	assert.False(t, engine.Health().Live)
+
+	go engine.Start()
+	defer engine.Stop()
+	time.Sleep(50 * time.Millisecond)
+
+	health := engine.Health()
+	assert.True(t, health.Live)
+	assert.False(t, health.Ready)
+	byName := make(map[string]StreamHealth)
+	for _, sh := range health.Streams {
+		byName[sh.Name] = sh
+	}
+	assert.False(t, byName[healthy.Name()].Failing)
+	assert.NotNil(t, byName[healthy.Name()].LastSinkAt)
+	assert.True(t, byName[failing.Name()].Failing)
+	assert.EqualValues(t, "sink is down", byName[failing.Name()].LastError)
+	assert.True(t, byName[failing.Name()].Lag > 0)
+
+	// the stream recovers once the sink writes again
+	atomic.StoreInt32(&broken, 0)
+	time.Sleep(20 * time.Millisecond)
+	assert.True(t, engine.Health().Ready)
+}
+
+func TestHealthHandler(t *testing.T) {
+	health := Health{Live: true}
+	handler := NewHealthHandler(healthFunc(func() Health { return health }))
+
+	for path, status := range map[string]int{"/live": http.StatusOK, "/ready": http.StatusServiceUnavailable, "/health": http.StatusOK, "/other": http.StatusNotFound} {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		assert.EqualValues(t, status, rec.Code, path)
+	}
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+	var body Health
+	assert.Nil(t, json.NewDecoder(rec.Body).Decode(&body))
+	assert.True(t, body.Live)
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/live", nil))
+	assert.EqualValues(t, http.StatusMethodNotAllowed, rec.Code)
+}
+
+type healthFunc func() Health
+
+func (fn healthFunc) Health() Health {
+	return fn()
+}
@@ -0,0 +1,354 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	nethttp "net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	streams "github.com/matang28/go-streams"
+)
+
+// Mode decides how entries are packed into requests
+type Mode int
+
+const (
+	// PerEntry sends a request per entry
+	PerEntry Mode = iota
+
+	// JSONArray sends the entries of a batch (with the same URL) as a JSON array
+	JSONArray
+
+	// NDJSON sends the entries of a batch (with the same URL) as newline delimited JSON
+	NDJSON
+)
+
+// Config describes the requests sent by the sink
+type Config struct {
+	// URL is a text/template evaluated per entry (e.g: `https://api/tenants/{{.Metadata.tenant}}/events`)
+	URL string
+
+	// Method defaults to POST
+	Method string
+
+	Mode Mode
+
+	// Header is added to every request
+	Header nethttp.Header
+
+	// Authorize is called for every request (including retries) and may inject
+	// authentication headers (e.g: refreshed bearer tokens).
+	Authorize func(req *nethttp.Request) error
+
+	// Encode serializes a single entry, by default the entry value is JSON encoded.
+	Encode func(entry streams.Entry) ([]byte, error)
+
+	// Concurrency is the maximal number of in flight requests (defaults to 1)
+	Concurrency int
+
+	// MaxRetries is the number of retries of throttled (429) or failed (5xx) requests
+	MaxRetries int
+
+	// Backoff is the initial delay between retries when the server doesn't send a Retry-After header,
+	// it is doubled on each attempt.
+	Backoff time.Duration
+
+	// MaxBackoff caps the delay between retries, Retry-After headers included (defaults to 1m)
+	MaxBackoff time.Duration
+
+	// PingURL is requested (GET) by Ping with the configured headers and authorizer,
+	// when empty Ping always succeeds.
+	PingURL string
+
+	Client *nethttp.Client
+}
+
+// StatusError is returned when the server responded with a non successful status code
+type StatusError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("request failed with status %d: %s", e.StatusCode, e.Body)
+}
+
+// Sink sends entries to an HTTP endpoint, at most Concurrency requests are
+// in flight at once even when the sink is shared by several streams.
+type Sink struct {
+	config    Config
+	url       *template.Template
+	client    *nethttp.Client
+	semaphore chan bool
+}
+
+func NewSink(config Config) (*Sink, error) {
+	if config.URL == "" {
+		return nil, fmt.Errorf("http sink requires a url")
+	}
+
+	url, err := template.New("url").Option("missingkey=zero").Parse(config.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	if config.Method == "" {
+		config.Method = nethttp.MethodPost
+	}
+	if config.Encode == nil {
+		config.Encode = func(entry streams.Entry) ([]byte, error) {
+			return json.Marshal(entry.Value)
+		}
+	}
+	if config.Concurrency <= 0 {
+		config.Concurrency = 1
+	}
+	if config.Backoff == 0 {
+		config.Backoff = 100 * time.Millisecond
+	}
+	if config.MaxBackoff <= 0 {
+		config.MaxBackoff = time.Minute
+	}
+	if config.MaxBackoff < config.Backoff {
+		config.MaxBackoff = config.Backoff
+	}
+
+	client := config.Client
+	if client == nil {
+		client = &nethttp.Client{Timeout: 30 * time.Second}
+	}
+
+	return &Sink{config: config, url: url, client: client, semaphore: make(chan bool, config.Concurrency)}, nil
+}
+
+func (this *Sink) Ping() error {
+	if this.config.PingURL == "" {
+		return nil
+	}
+
+	req, err := this.newRequest(nethttp.MethodGet, this.config.PingURL, nil, "")
+	if err != nil {
+		return err
+	}
+
+	res, err := this.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode >= 300 {
+		return &StatusError{StatusCode: res.StatusCode}
+	}
+	return nil
+}
+
+func (this *Sink) Single(entry streams.Entry) error {
+	return this.Batch(entry)
+}
+
+type request struct {
+	url     string
+	entries []streams.Entry
+}
+
+func (this *Sink) Batch(entry ...streams.Entry) error {
+	requests, err := this.group(entry)
+	if err != nil {
+		return err
+	}
+
+	errs := streams.NewSinkBatchError()
+	mutex := &sync.Mutex{}
+	wg := &sync.WaitGroup{}
+
+	for idx := range requests {
+		this.semaphore <- true
+		wg.Add(1)
+		go func(req request) {
+			defer func() {
+				<-this.semaphore
+				wg.Done()
+			}()
+
+			if err := this.send(req); err != nil {
+				mutex.Lock()
+				for _, e := range req.entries {
+					errs.Add(e.Key, err)
+				}
+				mutex.Unlock()
+			}
+		}(requests[idx])
+	}
+	wg.Wait()
+
+	return errs.AsError()
+}
+
+// group renders the URL of each entry and packs the entries into requests according to the mode
+func (this *Sink) group(entries []streams.Entry) ([]request, error) {
+	var out []request
+	byUrl := make(map[string]int)
+
+	for idx := range entries {
+		var sb strings.Builder
+		if err := this.url.Execute(&sb, entries[idx]); err != nil {
+			return nil, err
+		}
+		url := sb.String()
+
+		if this.config.Mode == PerEntry {
+			out = append(out, request{url: url, entries: entries[idx : idx+1]})
+			continue
+		}
+
+		if pos, found := byUrl[url]; found {
+			out[pos].entries = append(out[pos].entries, entries[idx])
+		} else {
+			byUrl[url] = len(out)
+			out = append(out, request{url: url, entries: []streams.Entry{entries[idx]}})
+		}
+	}
+	return out, nil
+}
+
+func (this *Sink) body(entries []streams.Entry) ([]byte, string, error) {
+	var buf bytes.Buffer
+	contentType := "application/json"
+
+	switch this.config.Mode {
+	case JSONArray:
+		buf.WriteByte('[')
+	case NDJSON:
+		contentType = "application/x-ndjson"
+	}
+
+	for idx := range entries {
+		doc, err := this.config.Encode(entries[idx])
+		if err != nil {
+			return nil, "", err
+		}
+		if this.config.Mode == JSONArray && idx > 0 {
+			buf.WriteByte(',')
+		}
+		buf.Write(doc)
+		if this.config.Mode == NDJSON {
+			buf.WriteByte('\n')
+		}
+	}
+
+	if this.config.Mode == JSONArray {
+		buf.WriteByte(']')
+	}
+	return buf.Bytes(), contentType, nil
+}
+
+func (this *Sink) send(req request) error {
+	body, contentType, err := this.body(req.entries)
+	if err != nil {
+		return err
+	}
+
+	backoff := this.config.Backoff
+	for attempt := 0; ; attempt++ {
+		delay, err := this.do(req.url, body, contentType)
+		if err == nil {
+			return nil
+		}
+		if delay < 0 || attempt >= this.config.MaxRetries {
+			return err
+		}
+
+		if delay == 0 {
+			delay = backoff
+			backoff *= 2
+		}
+		// a server asking to wait for hours would stall the stream
+		if delay > this.config.MaxBackoff {
+			delay = this.config.MaxBackoff
+		}
+		streams.LogWith(streams.Fields{streams.FieldStage: streams.SinkStage, "sink": "http"}).Warn("Request to %s failed (%s), retrying in %s", req.url, err.Error(), delay)
+		time.Sleep(delay)
+	}
+}
+
+// do sends a single request, on failure it returns the delay before the next retry,
+// zero means the default backoff and a negative delay means the request shouldn't be retried.
+func (this *Sink) do(url string, body []byte, contentType string) (time.Duration, error) {
+	req, err := this.newRequest(this.config.Method, url, body, contentType)
+	if err != nil {
+		return -1, err
+	}
+
+	res, err := this.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 300 {
+		_, _ = io.Copy(ioutil.Discard, res.Body)
+		return 0, nil
+	}
+
+	payload, _ := ioutil.ReadAll(io.LimitReader(res.Body, 1024))
+	statusErr := &StatusError{StatusCode: res.StatusCode, Body: string(payload)}
+
+	if res.StatusCode == nethttp.StatusTooManyRequests || res.StatusCode >= 500 {
+		return retryAfter(res.Header.Get("Retry-After")), statusErr
+	}
+	return -1, statusErr
+}
+
+// newRequest creates an authorized request with the configured headers
+func (this *Sink) newRequest(method, url string, body []byte, contentType string) (*nethttp.Request, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := nethttp.NewRequest(method, url, reader)
+	if err != nil {
+		return nil, err
+	}
+
+	for name, values := range this.config.Header {
+		for _, v := range values {
+			req.Header.Add(name, v)
+		}
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	if this.config.Authorize != nil {
+		if err := this.config.Authorize(req); err != nil {
+			return nil, err
+		}
+	}
+	return req, nil
+}
+
+// retryAfter parses the Retry-After header (delay in seconds or an HTTP date)
+func retryAfter(header string) time.Duration {
+	if header == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(header); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second
+	}
+
+	if date, err := nethttp.ParseTime(header); err == nil {
+		if delay := time.Until(date); delay > 0 {
+			return delay
+		}
+	}
+	return 0
+}
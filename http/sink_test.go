@@ -0,0 +1,211 @@
+package http
+
+import (
+	"io/ioutil"
+	nethttp "net/http"
+	"net/http/httptest"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	streams "github.com/matang28/go-streams"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSink_Batch_TemplatedUrlsAndModes(t *testing.T) {
+	mutex := &sync.Mutex{}
+	bodies := make(map[string]string)
+	server := httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		mutex.Lock()
+		bodies[r.URL.Path] = string(body)
+		mutex.Unlock()
+		assert.EqualValues(t, "Bearer token", r.Header.Get("Authorization"))
+	}))
+	defer server.Close()
+
+	entries := []streams.Entry{
+		{Key: "1", Value: 1, Metadata: map[string]string{"tenant": "a"}},
+		{Key: "2", Value: 2, Metadata: map[string]string{"tenant": "b"}},
+		{Key: "3", Value: 3, Metadata: map[string]string{"tenant": "a"}},
+	}
+
+	authorize := func(req *nethttp.Request) error {
+		req.Header.Set("Authorization", "Bearer token")
+		return nil
+	}
+
+	sink, err := NewSink(Config{URL: server.URL + "/{{.Metadata.tenant}}", Mode: JSONArray, Authorize: authorize})
+	assert.Nil(t, err)
+	assert.Nil(t, sink.Batch(entries...))
+	assert.EqualValues(t, map[string]string{"/a": "[1,3]", "/b": "[2]"}, bodies)
+
+	sink, err = NewSink(Config{URL: server.URL + "/{{.Metadata.tenant}}", Mode: NDJSON, Authorize: authorize, Concurrency: 2})
+	assert.Nil(t, err)
+	assert.Nil(t, sink.Batch(entries...))
+	assert.EqualValues(t, map[string]string{"/a": "1\n3\n", "/b": "2\n"}, bodies)
+}
+
+func TestSink_Batch_PerEntryConcurrencyLimit(t *testing.T) {
+	var inFlight, maxInFlight int32
+	mutex := &sync.Mutex{}
+	var received []string
+	server := httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		current := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			old := atomic.LoadInt32(&maxInFlight)
+			if current <= old || atomic.CompareAndSwapInt32(&maxInFlight, old, current) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+
+		body, _ := ioutil.ReadAll(r.Body)
+		mutex.Lock()
+		received = append(received, string(body))
+		mutex.Unlock()
+	}))
+	defer server.Close()
+
+	sink, err := NewSink(Config{URL: server.URL, Concurrency: 2})
+	assert.Nil(t, err)
+
+	var entries []streams.Entry
+	for _, v := range []string{"a", "b", "c", "d", "e"} {
+		entries = append(entries, streams.Entry{Key: v, Value: v})
+	}
+	assert.Nil(t, sink.Batch(entries...))
+
+	sort.Strings(received)
+	assert.EqualValues(t, []string{`"a"`, `"b"`, `"c"`, `"d"`, `"e"`}, received)
+	assert.EqualValues(t, 2, atomic.LoadInt32(&maxInFlight))
+}
+
+func TestSink_Batch_ConcurrencyLimitIsSharedBetweenCalls(t *testing.T) {
+	var inFlight, maxInFlight int32
+	server := httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		current := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			old := atomic.LoadInt32(&maxInFlight)
+			if current <= old || atomic.CompareAndSwapInt32(&maxInFlight, old, current) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	sink, err := NewSink(Config{URL: server.URL, Concurrency: 2})
+	assert.Nil(t, err)
+
+	wg := &sync.WaitGroup{}
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.Nil(t, sink.Batch(streams.Entry{Key: "a", Value: "a"}, streams.Entry{Key: "b", Value: "b"}))
+		}()
+	}
+	wg.Wait()
+	assert.EqualValues(t, 2, atomic.LoadInt32(&maxInFlight))
+}
+
+func TestSink_Ping_UsesHeadersAndAuthorizer(t *testing.T) {
+	server := httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		if r.Header.Get("X-Tenant") != "acme" || r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(nethttp.StatusUnauthorized)
+		}
+	}))
+	defer server.Close()
+
+	sink, err := NewSink(Config{URL: server.URL, PingURL: server.URL + "/health", Header: nethttp.Header{"X-Tenant": {"acme"}}})
+	assert.Nil(t, err)
+	assert.NotNil(t, sink.Ping())
+
+	sink, err = NewSink(Config{
+		URL:     server.URL,
+		PingURL: server.URL + "/health",
+		Header:  nethttp.Header{"X-Tenant": {"acme"}},
+		Authorize: func(req *nethttp.Request) error {
+			req.Header.Set("Authorization", "Bearer token")
+			return nil
+		},
+	})
+	assert.Nil(t, err)
+	assert.Nil(t, sink.Ping())
+}
+
+func TestSink_Batch_HonorsRetryAfter(t *testing.T) {
+	var calls int32
+	var first time.Time
+	var elapsed time.Duration
+	server := httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			first = time.Now()
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(nethttp.StatusTooManyRequests)
+			return
+		}
+		elapsed = time.Since(first)
+	}))
+	defer server.Close()
+
+	sink, err := NewSink(Config{URL: server.URL, MaxRetries: 1, Backoff: time.Millisecond})
+	assert.Nil(t, err)
+	assert.Nil(t, sink.Single(streams.Entry{Key: "1", Value: 1}))
+	assert.EqualValues(t, 2, atomic.LoadInt32(&calls))
+	assert.True(t, elapsed >= time.Second)
+}
+
+func TestSink_Batch_CapsRetryAfter(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.Header().Set("Retry-After", "3600")
+			w.WriteHeader(nethttp.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	sink, err := NewSink(Config{URL: server.URL, MaxRetries: 1, Backoff: time.Millisecond, MaxBackoff: 10 * time.Millisecond})
+	assert.Nil(t, err)
+	started := time.Now()
+	assert.Nil(t, sink.Single(streams.Entry{Key: "1", Value: 1}))
+	assert.EqualValues(t, 2, atomic.LoadInt32(&calls))
+	assert.True(t, time.Since(started) < time.Second)
+}
+
+func TestSink_Batch_ClientErrorsAreNotRetried(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(nethttp.StatusBadRequest)
+		_, _ = w.Write([]byte("invalid payload"))
+	}))
+	defer server.Close()
+
+	sink, err := NewSink(Config{URL: server.URL, MaxRetries: 3, Backoff: time.Millisecond})
+	assert.Nil(t, err)
+
+	err = sink.Single(streams.Entry{Key: "1", Value: 1})
+	batchErr, ok := err.(*streams.SinkBatchError)
+	assert.True(t, ok)
+	statusErr, ok := batchErr.Errors["1"].(*StatusError)
+	assert.True(t, ok)
+	assert.EqualValues(t, nethttp.StatusBadRequest, statusErr.StatusCode)
+	assert.EqualValues(t, "invalid payload", statusErr.Body)
+	assert.EqualValues(t, 1, atomic.LoadInt32(&calls))
+}
+
+func TestRetryAfter(t *testing.T) {
+	assert.EqualValues(t, 0, retryAfter(""))
+	assert.EqualValues(t, 3*time.Second, retryAfter("3"))
+	assert.EqualValues(t, 0, retryAfter("garbage"))
+
+	date := time.Now().Add(time.Hour).UTC().Format(nethttp.TimeFormat)
+	assert.True(t, retryAfter(date) > 59*time.Minute)
+}
@@ -0,0 +1,186 @@
+package go_streams
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// defaultIdempotencyKeys bounds the memory store of an IdempotentSink without a store
+const defaultIdempotencyKeys = 100000
+
+// MetadataDuplicate is attached to the duplicates written by the DuplicateOverwrite policy
+const MetadataDuplicate = "duplicate"
+
+// DuplicatePolicy decides what happens to the entries whose idempotency key was already written
+type DuplicatePolicy string
+
+const (
+	// DuplicateSkip drops the duplicates
+	DuplicateSkip DuplicatePolicy = "skip"
+
+	// DuplicateOverwrite writes the duplicates again (e.g: to upserting sinks), they carry the duplicate metadata
+	DuplicateOverwrite DuplicatePolicy = "overwrite"
+)
+
+// IdempotencyConfig configures an IdempotentSink
+type IdempotencyConfig struct {
+	// Key derives the idempotency key of an entry (defaults to the entry key)
+	Key KeyExtractor
+
+	// Store keeps the written keys (defaults to a memory store of 100000 keys), use a persistent store
+	// to survive restarts and a store of its own since the keys aren't prefixed.
+	Store StateStore
+
+	// TTL is how long the written keys are remembered (0 keeps them until the store evicts them)
+	TTL time.Duration
+
+	// Duplicates is the policy of the duplicates (defaults to DuplicateSkip)
+	Duplicates DuplicatePolicy
+}
+
+// IdempotentSink tracks the idempotency keys of the entries written by its sink so the entries the source
+// delivers again (e.g: after a restart, sources are at least once) don't create duplicates downstream.
+// A key is recorded once its entry was written, the entries of a failed write can be written again.
+// AckingSinks shouldn't be wrapped since their writes don't tell whether the entries were written.
+type IdempotentSink struct {
+	sink    Sink
+	config  IdempotencyConfig
+	skipped uint64
+}
+
+func NewIdempotentSink(sink Sink, config IdempotencyConfig) (*IdempotentSink, error) {
+	if config.Key == nil {
+		config.Key = defaultKeyExtractor
+	}
+	if config.Store == nil {
+		config.Store = NewMemoryStateStore(defaultIdempotencyKeys)
+	}
+	switch config.Duplicates {
+	case "":
+		config.Duplicates = DuplicateSkip
+	case DuplicateSkip, DuplicateOverwrite:
+	default:
+		return nil, fmt.Errorf("unknown duplicate policy: '%s'", config.Duplicates)
+	}
+	return &IdempotentSink{sink: sink, config: config}, nil
+}
+
+func (this *IdempotentSink) Single(entry Entry) error {
+	entries, keys := this.unwritten([]Entry{entry})
+	if len(entries) == 0 {
+		return nil
+	}
+	if err := this.sink.Single(entries[0]); err != nil {
+		return err
+	}
+	return this.record(keys)
+}
+
+// Batch writes the entries whose key wasn't written yet, the duplicates within the batch count too.
+// The keys of the entries that failed in a SinkBatchError aren't recorded.
+func (this *IdempotentSink) Batch(entry ...Entry) error {
+	entries, keys := this.unwritten(entry)
+	if len(entries) == 0 {
+		return nil
+	}
+
+	err := this.sink.Batch(entries...)
+	if err != nil {
+		batchErr, ok := err.(*SinkBatchError)
+		if !ok {
+			return err
+		}
+		written := keys[:0]
+		for idx := range entries {
+			if _, failed := batchErr.Errors[entries[idx].Key]; !failed {
+				written = append(written, keys[idx])
+			}
+		}
+		keys = written
+	}
+	if recordErr := this.record(keys); err == nil {
+		err = recordErr
+	}
+	return err
+}
+
+// unwritten applies the duplicate policy, it returns the entries to write and their idempotency keys.
+// Entries whose key can't be looked up are written, a duplicate is better than a lost entry.
+func (this *IdempotentSink) unwritten(entries []Entry) ([]Entry, []string) {
+	out := make([]Entry, 0, len(entries))
+	keys := make([]string, 0, len(entries))
+	batch := make(map[string]bool, len(entries))
+	for idx := range entries {
+		key := this.config.Key(entries[idx])
+		_, seen, err := this.config.Store.Get(key)
+		if err != nil {
+			logger.Warn("Failed to look up idempotency key '%s': %s", key, err.Error())
+		}
+		if !seen && !batch[key] {
+			batch[key] = true
+			out = append(out, entries[idx])
+			keys = append(keys, key)
+			continue
+		}
+
+		if this.config.Duplicates == DuplicateSkip {
+			atomic.AddUint64(&this.skipped, 1)
+			continue
+		}
+		out = append(out, duplicateOf(entries[idx]))
+		keys = append(keys, key)
+	}
+	return out, keys
+}
+
+func (this *IdempotentSink) record(keys []string) error {
+	for _, key := range keys {
+		if err := this.config.Store.Put(key, true, this.config.TTL); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Skipped returns the number of duplicates dropped by the DuplicateSkip policy
+func (this *IdempotentSink) Skipped() uint64 {
+	return atomic.LoadUint64(&this.skipped)
+}
+
+func (this *IdempotentSink) Ping() error {
+	return this.sink.Ping()
+}
+
+// Flush flushes the sink when it implements Flusher
+func (this *IdempotentSink) Flush() error {
+	if flusher, ok := this.sink.(Flusher); ok {
+		return flusher.Flush()
+	}
+	return nil
+}
+
+// Close closes the sink when it implements Closer
+func (this *IdempotentSink) Close() error {
+	if closer, ok := this.sink.(Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+func (this *IdempotentSink) bindEvents(stream Stream) {
+	if binder, ok := this.sink.(eventBinder); ok {
+		binder.bindEvents(stream)
+	}
+}
+
+// duplicateOf copies the entry with the duplicate metadata
+func duplicateOf(entry Entry) Entry {
+	metadata := make(map[string]string, len(entry.Metadata)+1)
+	for k, v := range entry.Metadata {
+		metadata[k] = v
+	}
+	metadata[MetadataDuplicate] = "true"
+	entry.Metadata = metadata
+	return entry
+}
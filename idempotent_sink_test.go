@@ -0,0 +1,72 @@
+package go_streams
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIdempotentSink_SkipsDuplicates(t *testing.T) {
+	sink := NewArraySink()
+	idempotent, err := NewIdempotentSink(sink, IdempotencyConfig{})
+	assert.Nil(t, err)
+
+	assert.Nil(t, idempotent.Single(Entry{Key: "1", Value: 1}))
+	assert.Nil(t, idempotent.Single(Entry{Key: "1", Value: 1}))
+	assert.Nil(t, idempotent.Batch(Entry{Key: "1", Value: 1}, Entry{Key: "2", Value: 2}, Entry{Key: "2", Value: 2}))
+	assert.EqualValues(t, []interface{}{1, 2}, sink.Array())
+	assert.EqualValues(t, 3, idempotent.Skipped())
+}
+
+func TestIdempotentSink_OverwritesDuplicates(t *testing.T) {
+	var written []Entry
+	idempotent, err := NewIdempotentSink(NewCallbackSink(func(entries ...Entry) error {
+		written = append(written, entries...)
+		return nil
+	}), IdempotencyConfig{
+		Key:        func(entry Entry) string { return fmt.Sprintf("order-%v", entry.Value) },
+		Duplicates: DuplicateOverwrite,
+	})
+	assert.Nil(t, err)
+
+	assert.Nil(t, idempotent.Single(Entry{Key: "1", Value: 7}))
+	assert.Nil(t, idempotent.Single(Entry{Key: "2", Value: 7}))
+	assert.Len(t, written, 2)
+	assert.Empty(t, written[0].Metadata[MetadataDuplicate])
+	assert.EqualValues(t, "true", written[1].Metadata[MetadataDuplicate])
+	assert.EqualValues(t, 0, idempotent.Skipped())
+}
+
+func TestIdempotentSink_RecordsOnlyWrittenKeys(t *testing.T) {
+	store := NewMemoryStateStore(10)
+	failing := true
+	var written []interface{}
+	idempotent, err := NewIdempotentSink(NewCallbackSink(func(entries ...Entry) error {
+		batchErr := NewSinkBatchError()
+		for _, entry := range entries {
+			if failing && entry.Key == "2" {
+				batchErr.Add(entry.Key, fmt.Errorf("rejected"))
+				continue
+			}
+			written = append(written, entry.Value)
+		}
+		return batchErr.AsError()
+	}), IdempotencyConfig{Store: store, TTL: time.Minute})
+	assert.Nil(t, err)
+
+	assert.IsType(t, &SinkBatchError{}, idempotent.Batch(Entry{Key: "1", Value: 1}, Entry{Key: "2", Value: 2}))
+	_, found, _ := store.Get("2")
+	assert.False(t, found)
+
+	// the redelivered batch only writes the failed entry
+	failing = false
+	assert.Nil(t, idempotent.Batch(Entry{Key: "1", Value: 1}, Entry{Key: "2", Value: 2}))
+	assert.EqualValues(t, []interface{}{1, 2}, written)
+}
+
+func TestNewIdempotentSink_UnknownPolicy(t *testing.T) {
+	_, err := NewIdempotentSink(NewArraySink(), IdempotencyConfig{Duplicates: "merge"})
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,105 @@
+package io
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	goio "io"
+	"os"
+	"sync"
+
+	streams "github.com/matang28/go-streams"
+)
+
+// SinkConfig configures a Sink
+type SinkConfig struct {
+	// Encode serializes an entry, by default []byte and string values are written as they are and other values are JSON encoded
+	Encode func(entry streams.Entry) ([]byte, error)
+
+	// Delimiter is written after every entry (defaults to a new line)
+	Delimiter []byte
+}
+
+// Sink writes every entry followed by the delimiter, the entries of a batch are written at once.
+// The writer belongs to the caller, the sink doesn't close it.
+type Sink struct {
+	config SinkConfig
+
+	mutex  *sync.Mutex
+	writer *bufio.Writer
+}
+
+func NewSink(writer goio.Writer, config SinkConfig) (*Sink, error) {
+	if writer == nil {
+		return nil, fmt.Errorf("io sink requires a writer")
+	}
+	if config.Encode == nil {
+		config.Encode = encode
+	}
+	if config.Delimiter == nil {
+		config.Delimiter = []byte("\n")
+	}
+	return &Sink{config: config, mutex: &sync.Mutex{}, writer: bufio.NewWriter(writer)}, nil
+}
+
+// Stdout creates a sink writing to the standard output
+func Stdout(config SinkConfig) (*Sink, error) {
+	return NewSink(os.Stdout, config)
+}
+
+func (this *Sink) Single(entry streams.Entry) error {
+	data, err := this.config.Encode(entry)
+	if err != nil {
+		return err
+	}
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	if err := this.write(data); err != nil {
+		return err
+	}
+	return this.writer.Flush()
+}
+
+// Batch writes the entries that were encoded, the entries that failed to are reported in a SinkBatchError
+func (this *Sink) Batch(entry ...streams.Entry) error {
+	errs := streams.NewSinkBatchError()
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	for idx := range entry {
+		data, err := this.config.Encode(entry[idx])
+		if err != nil {
+			errs.Add(entry[idx].Key, err)
+			continue
+		}
+		if err := this.write(data); err != nil {
+			return err
+		}
+	}
+	if err := this.writer.Flush(); err != nil {
+		return err
+	}
+	return errs.AsError()
+}
+
+func (this *Sink) Ping() error {
+	return nil
+}
+
+func (this *Sink) write(data []byte) error {
+	if _, err := this.writer.Write(data); err != nil {
+		return err
+	}
+	_, err := this.writer.Write(this.config.Delimiter)
+	return err
+}
+
+func encode(entry streams.Entry) ([]byte, error) {
+	switch value := entry.Value.(type) {
+	case []byte:
+		return value, nil
+	case string:
+		return []byte(value), nil
+	default:
+		return json.Marshal(value)
+	}
+}
@@ -0,0 +1,40 @@
+package io
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	streams "github.com/matang28/go-streams"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSink_WritesDelimitedEntries(t *testing.T) {
+	var out bytes.Buffer
+	sink, err := NewSink(&out, SinkConfig{})
+	assert.Nil(t, err)
+
+	assert.Nil(t, sink.Single(streams.Entry{Key: "1", Value: "text"}))
+	assert.Nil(t, sink.Batch(streams.Entry{Key: "2", Value: []byte("raw")}, streams.Entry{Key: "3", Value: map[string]int{"n": 3}}))
+	assert.EqualValues(t, "text\nraw\n{\"n\":3}\n", out.String())
+}
+
+func TestSink_ReportsEncodingFailures(t *testing.T) {
+	var out bytes.Buffer
+	sink, err := NewSink(&out, SinkConfig{
+		Delimiter: []byte(","),
+		Encode: func(entry streams.Entry) ([]byte, error) {
+			if entry.Value == nil {
+				return nil, fmt.Errorf("nil value")
+			}
+			return []byte(entry.Value.(string)), nil
+		},
+	})
+	assert.Nil(t, err)
+
+	err = sink.Batch(streams.Entry{Key: "1", Value: "a"}, streams.Entry{Key: "2"}, streams.Entry{Key: "3", Value: "c"})
+	assert.IsType(t, &streams.SinkBatchError{}, err)
+	assert.Len(t, err.(*streams.SinkBatchError).Errors, 1)
+	assert.Contains(t, err.(*streams.SinkBatchError).Errors, "2")
+	assert.EqualValues(t, "a,c,", out.String())
+}
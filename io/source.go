@@ -0,0 +1,218 @@
+// Package io holds a source reading records from an io.Reader and a sink writing entries to an io.Writer,
+// e.g: to run a stream in a CLI pipeline over stdin and stdout or to compose it with other processes.
+package io
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	goio "io"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	streams "github.com/matang28/go-streams"
+)
+
+const defaultMaxRecordSize = bufio.MaxScanTokenSize
+
+// SourceConfig configures a Source
+type SourceConfig struct {
+	// Name of the source (defaults to a generated name)
+	Name string
+
+	// Split splits the input into records (defaults to bufio.ScanLines), e.g: bufio.ScanWords or Delimited
+	Split bufio.SplitFunc
+
+	// MaxRecordSize is the size in bytes of the largest record (defaults to 64KB), a larger record fails the source
+	MaxRecordSize int
+
+	// Decode turns a record into an entry value (defaults to the raw record), a nil value skips the record
+	Decode func(record []byte) (interface{}, error)
+}
+
+// Source emits the records of a reader until it ends, then the stream completes. Entries are keyed by their
+// record number (starting at 1). A reader can't be replayed so committing an entry does nothing.
+type Source struct {
+	config SourceConfig
+	reader goio.Reader
+	name   string
+
+	closeCh chan bool
+	once    *sync.Once
+
+	// position is the offset after the latest record handled by Start
+	position uint64
+}
+
+func NewSource(reader goio.Reader, config SourceConfig) (*Source, error) {
+	if reader == nil {
+		return nil, fmt.Errorf("io source requires a reader")
+	}
+	if config.Split == nil {
+		config.Split = bufio.ScanLines
+	}
+	if config.MaxRecordSize <= 0 {
+		config.MaxRecordSize = defaultMaxRecordSize
+	}
+	if config.Decode == nil {
+		config.Decode = func(record []byte) (interface{}, error) { return record, nil }
+	}
+
+	name := config.Name
+	if name == "" {
+		name = fmt.Sprintf("ioSource-%d", time.Now().UnixNano())
+	}
+	return &Source{config: config, reader: reader, name: name, closeCh: make(chan bool), once: &sync.Once{}}, nil
+}
+
+// Stdin creates a source reading the standard input
+func Stdin(config SourceConfig) (*Source, error) {
+	return NewSource(os.Stdin, config)
+}
+
+type record struct {
+	data []byte
+	err  error
+
+	// end is the offset after the record (and its delimiter)
+	end uint64
+}
+
+func (this *Source) Start(channel streams.EntryChannel, errorChannel streams.ErrorChannel) {
+	log := streams.LogWith(streams.Fields{streams.FieldStream: this.name})
+	log.Info("Starting io source")
+	defer func() {
+		close(channel)
+		errorChannel <- streams.NewEofError(this)
+		log.Info("Io source stopped")
+	}()
+
+	records := make(chan record)
+	go this.scan(records)
+
+	var seq uint64
+	for {
+		select {
+		case <-this.closeCh:
+			return
+		case rec, ok := <-records:
+			if !ok {
+				return
+			}
+			if rec.err != nil {
+				errorChannel <- rec.err
+				return
+			}
+			seq++
+			atomic.StoreUint64(&this.position, rec.end)
+			value, err := this.config.Decode(rec.data)
+			if err != nil {
+				errorChannel <- fmt.Errorf("failed to decode record %d: %s", seq, err.Error())
+				continue
+			}
+			if value == nil {
+				continue
+			}
+			select {
+			case <-this.closeCh:
+				return
+			case channel <- streams.Entry{Key: strconv.FormatUint(seq, 10), Value: value, Timestamp: time.Now()}:
+			}
+		}
+	}
+}
+
+// scan reads the records until the reader ends, it keeps blocking on a read the source stopped during
+// unless the reader is closed (see Stop)
+func (this *Source) scan(records chan<- record) {
+	defer close(records)
+	scanner := bufio.NewScanner(this.reader)
+	// the max size is only enforced once the initial buffer is too small
+	initial := 4096
+	if initial > this.config.MaxRecordSize {
+		initial = this.config.MaxRecordSize
+	}
+	scanner.Buffer(make([]byte, 0, initial), this.config.MaxRecordSize)
+	var offset uint64
+	scanner.Split(func(data []byte, atEOF bool) (int, []byte, error) {
+		advance, token, err := this.config.Split(data, atEOF)
+		if advance > 0 {
+			offset += uint64(advance)
+		}
+		return advance, token, err
+	})
+	for scanner.Scan() {
+		// the scanner reuses its buffer between records
+		data := append([]byte{}, scanner.Bytes()...)
+		select {
+		case <-this.closeCh:
+			return
+		case records <- record{data: data, end: offset}:
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		select {
+		case <-this.closeCh:
+		case records <- record{err: err}:
+		}
+	}
+}
+
+// Stop stops the source, a reader that is an io.Closer (e.g: a pipe or a file but not the standard input) is closed
+// to interrupt its pending read.
+func (this *Source) Stop() error {
+	var err error
+	this.once.Do(func() {
+		streams.LogWith(streams.Fields{streams.FieldStream: this.name}).Info("Stopping io source")
+		close(this.closeCh)
+		if closer, ok := this.reader.(goio.Closer); ok && this.reader != os.Stdin {
+			err = closer.Close()
+		}
+	})
+	return err
+}
+
+// Progress returns the bytes read, the end is known for files and readers with a size (e.g: bytes.Reader)
+func (this *Source) Progress() (streams.Progress, error) {
+	progress := streams.Progress{Position: atomic.LoadUint64(&this.position), Unit: "bytes"}
+	switch reader := this.reader.(type) {
+	case interface{ Size() int64 }:
+		progress.End = uint64(reader.Size())
+	case *os.File:
+		if info, err := reader.Stat(); err == nil && info.Mode().IsRegular() {
+			progress.End = uint64(info.Size())
+		}
+	}
+	return progress, nil
+}
+
+func (this *Source) Ping() error {
+	return nil
+}
+
+func (this *Source) CommitEntry(keys ...string) error {
+	return nil
+}
+
+func (this *Source) Name() string {
+	return this.name
+}
+
+// Delimited splits the input into records ending with the delimiter (e.g: '\x00'), the last record may not end with it
+func Delimited(delimiter byte) bufio.SplitFunc {
+	return func(data []byte, atEOF bool) (advance int, token []byte, err error) {
+		if atEOF && len(data) == 0 {
+			return 0, nil, nil
+		}
+		if i := bytes.IndexByte(data, delimiter); i >= 0 {
+			return i + 1, data[:i], nil
+		}
+		if atEOF {
+			return len(data), data, nil
+		}
+		return 0, nil, nil
+	}
+}
@@ -0,0 +1,95 @@
+package io
+
+import (
+	"fmt"
+	goio "io"
+	"strings"
+	"testing"
+	"time"
+
+	streams "github.com/matang28/go-streams"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSource_EmitsRecordsUntilEof(t *testing.T) {
+	source, err := NewSource(strings.NewReader("a\x00\x00bc\x00skip\x00d"), SourceConfig{
+		Name:  "records",
+		Split: Delimited(0),
+		Decode: func(record []byte) (interface{}, error) {
+			switch string(record) {
+			case "":
+				return nil, fmt.Errorf("empty record")
+			case "skip":
+				return nil, nil
+			}
+			return string(record), nil
+		},
+	})
+	assert.Nil(t, err)
+
+	channel := make(streams.EntryChannel, 10)
+	errs := make(streams.ErrorChannel, 10)
+	source.Start(channel, errs)
+
+	var keys, values []string
+	for entry := range channel {
+		keys = append(keys, entry.Key)
+		values = append(values, entry.Value.(string))
+	}
+	assert.EqualValues(t, []string{"1", "3", "5"}, keys)
+	assert.EqualValues(t, []string{"a", "bc", "d"}, values)
+	assert.EqualError(t, <-errs, "failed to decode record 2: empty record")
+	assert.IsType(t, &streams.EofError{}, <-errs)
+}
+
+func TestSource_ReportsTooLongRecords(t *testing.T) {
+	source, err := NewSource(strings.NewReader("ok\n"+strings.Repeat("x", 32)+"\n"), SourceConfig{MaxRecordSize: 16})
+	assert.Nil(t, err)
+
+	channel := make(streams.EntryChannel, 10)
+	errs := make(streams.ErrorChannel, 10)
+	source.Start(channel, errs)
+
+	assert.EqualValues(t, []byte("ok"), (<-channel).Value)
+	_, open := <-channel
+	assert.False(t, open)
+	assert.Contains(t, (<-errs).Error(), "too long")
+	assert.IsType(t, &streams.EofError{}, <-errs)
+}
+
+func TestSource_StopClosesTheReader(t *testing.T) {
+	reader, writer := goio.Pipe()
+	source, err := NewSource(reader, SourceConfig{})
+	assert.Nil(t, err)
+
+	channel := make(streams.EntryChannel)
+	errs := make(streams.ErrorChannel, 10)
+	go source.Start(channel, errs)
+	go func() { _, _ = writer.Write([]byte("line\n")) }()
+	assert.EqualValues(t, []byte("line"), (<-channel).Value)
+
+	assert.Nil(t, source.Stop())
+	select {
+	case err := <-errs:
+		assert.IsType(t, &streams.EofError{}, err)
+	case <-time.After(time.Second):
+		t.Fatal("the source didn't stop")
+	}
+	_, err = writer.Write([]byte("more\n"))
+	assert.Equal(t, goio.ErrClosedPipe, err)
+}
+
+func TestSource_Progress(t *testing.T) {
+	source, err := NewSource(strings.NewReader("ab\ncd\ne"), SourceConfig{})
+	assert.Nil(t, err)
+	progress, err := source.Progress()
+	assert.Nil(t, err)
+	assert.EqualValues(t, streams.Progress{Position: 0, End: 7, Unit: "bytes"}, progress)
+
+	channel := make(streams.EntryChannel, 10)
+	source.Start(channel, make(streams.ErrorChannel, 10))
+	progress, err = source.Progress()
+	assert.Nil(t, err)
+	assert.EqualValues(t, 7, progress.Position)
+	assert.EqualValues(t, 0, progress.Lag())
+}
@@ -0,0 +1,190 @@
+package go_streams
+
+import (
+	"sync"
+	"time"
+)
+
+// JoinFunc combines the values of two joined entries, right is nil for unmatched entries of left joins
+type JoinFunc func(left, right interface{}) interface{}
+
+// Table is a sink that materializes a stream into a lookup table, other streams are
+// enriched against it with Join. The table is kept in a StateStore so a persistent store
+// keeps the table across restarts.
+type Table struct {
+	key   KeyFunc
+	store StateStore
+}
+
+// NewTable creates a table keyed by the given key function, entries with a nil value (tombstones)
+// have no value to key so they delete the row of their entry key.
+func NewTable(key KeyFunc, store StateStore) *Table {
+	return &Table{key: key, store: store}
+}
+
+func (this *Table) Single(entry Entry) error {
+	if entry.Value == nil {
+		return this.store.Delete(entry.Key)
+	}
+	return this.store.Put(this.key(entry.Value), entry.Value, 0)
+}
+
+func (this *Table) Batch(entry ...Entry) error {
+	errs := NewSinkBatchError()
+	for idx := range entry {
+		errs.Add(entry[idx].Key, this.Single(entry[idx]))
+	}
+	return errs.AsError()
+}
+
+func (this *Table) Ping() error {
+	return nil
+}
+
+// Lookup returns the value of the key
+func (this *Table) Lookup(key string) (interface{}, bool, error) {
+	return this.store.Get(key)
+}
+
+// Join creates an operator that joins the entries of a stream with the rows of the table,
+// key returns the table key of each entry. Entries without a row are dropped unless left is true
+// (they are joined with a nil right value).
+func (this *Table) Join(key KeyFunc, fn JoinFunc, left bool) Operator {
+	return &tableJoin{table: this, key: key, fn: fn, left: left}
+}
+
+type tableJoin struct {
+	table *Table
+	key   KeyFunc
+	fn    JoinFunc
+	left  bool
+}
+
+func (this *tableJoin) Apply(entry Entry) ([]Entry, error) {
+	row, found, err := this.table.Lookup(this.key(entry.Value))
+	if err != nil {
+		return nil, err
+	}
+	if !found && !this.left {
+		return nil, nil
+	}
+	entry.Value = this.fn(entry.Value, row)
+	return []Entry{entry}, nil
+}
+
+type windowedEntry struct {
+	value interface{}
+	at    time.Time
+}
+
+// WindowJoin joins the entries of two streams that share the same join key and whose
+// timestamps are at most window apart. Each stream passes its entries through its side
+// of the join (see Left and Right), the joined entries are emitted by the stream whose
+// entry completed the match. Entries without a timestamp are stamped with their arrival time.
+type WindowJoin struct {
+	window   time.Duration
+	fn       JoinFunc
+	mutex    *sync.Mutex
+	sides    [2]map[string][]windowedEntry
+	keys     [2]KeyFunc
+	latest   time.Time
+	inserted int
+}
+
+// NewWindowJoin creates a stream-stream join, leftKey and rightKey return the join key of each side
+func NewWindowJoin(window time.Duration, leftKey, rightKey KeyFunc, fn JoinFunc) *WindowJoin {
+	return &WindowJoin{
+		window: window,
+		fn:     fn,
+		mutex:  &sync.Mutex{},
+		sides:  [2]map[string][]windowedEntry{make(map[string][]windowedEntry), make(map[string][]windowedEntry)},
+		keys:   [2]KeyFunc{leftKey, rightKey},
+	}
+}
+
+// Left returns the operator of the left stream
+func (this *WindowJoin) Left() Operator {
+	return &joinSide{join: this, side: 0}
+}
+
+// Right returns the operator of the right stream
+func (this *WindowJoin) Right() Operator {
+	return &joinSide{join: this, side: 1}
+}
+
+type joinSide struct {
+	join *WindowJoin
+	side int
+}
+
+func (this *joinSide) Apply(entry Entry) ([]Entry, error) {
+	return this.join.apply(this.side, entry), nil
+}
+
+func (this *WindowJoin) apply(side int, entry Entry) []Entry {
+	at := entry.Timestamp
+	if at.IsZero() {
+		at = time.Now()
+	}
+	key := this.keys[side](entry.Value)
+
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	if at.After(this.latest) {
+		this.latest = at
+	}
+
+	var joined []Entry
+	for _, other := range this.sides[1-side][key] {
+		if abs(at.Sub(other.at)) > this.window {
+			continue
+		}
+		out := entry
+		if side == 0 {
+			out.Value = this.fn(entry.Value, other.value)
+		} else {
+			out.Value = this.fn(other.value, entry.Value)
+		}
+		if other.at.After(at) {
+			out.Timestamp = other.at
+		} else {
+			out.Timestamp = at
+		}
+		joined = append(joined, out)
+	}
+
+	this.sides[side][key] = append(this.sides[side][key], windowedEntry{value: entry.Value, at: at})
+	this.inserted++
+	if this.inserted%1024 == 0 {
+		this.expire()
+	}
+	return joined
+}
+
+// expire removes the entries that can't be matched anymore
+func (this *WindowJoin) expire() {
+	deadline := this.latest.Add(-this.window)
+	for _, entries := range this.sides {
+		for key, values := range entries {
+			kept := values[:0]
+			for _, value := range values {
+				if !value.at.Before(deadline) {
+					kept = append(kept, value)
+				}
+			}
+			if len(kept) == 0 {
+				delete(entries, key)
+			} else {
+				entries[key] = kept
+			}
+		}
+	}
+}
+
+func abs(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
@@ -0,0 +1,78 @@
+package go_streams
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestTable_JoinEnrichesEntries(t *testing.T) {
+	errs := make(ErrorChannel, 100)
+	table := NewTable(func(value interface{}) string {
+		return fmt.Sprintf("%d", value.(int))
+	}, NewMemoryStateStore(0))
+
+	NewStream(NewSequentialIntegerSource(5, 0)).
+		Filter(func(entry interface{}) bool { return entry.(int)%2 == 0 }).
+		Sink(table).
+		Process(NewDirectProcessor(), errs)
+
+	sink := NewArraySink()
+	join := func(left, right interface{}) interface{} {
+		return fmt.Sprintf("%v:%v", left, right)
+	}
+	key := func(value interface{}) string { return fmt.Sprintf("%d", value.(int)) }
+	NewStream(NewSequentialIntegerSource(3, 0)).
+		Via(table.Join(key, join, false)).
+		Sink(sink).
+		Process(NewDirectProcessor(), errs)
+	assert.EqualValues(t, []interface{}{"0:0", "2:2"}, sink.Array())
+
+	sink = NewArraySink()
+	NewStream(NewSequentialIntegerSource(1, 0)).
+		Via(table.Join(key, join, true)).
+		Sink(sink).
+		Process(NewDirectProcessor(), errs)
+	assert.EqualValues(t, []interface{}{"0:0", "1:<nil>"}, sink.Array())
+}
+
+func TestTable_TombstonesDeleteRows(t *testing.T) {
+	table := NewTable(func(value interface{}) string {
+		return value.(map[string]string)["id"]
+	}, NewMemoryStateStore(0))
+
+	assert.Nil(t, table.Batch(
+		Entry{Key: "a", Value: map[string]string{"id": "a"}},
+		Entry{Key: "b", Value: map[string]string{"id": "b"}},
+		Entry{Key: "a", Value: nil},
+	))
+	_, found, _ := table.Lookup("a")
+	assert.False(t, found)
+	_, found, _ = table.Lookup("b")
+	assert.True(t, found)
+}
+
+func TestWindowJoin_JoinsEntriesWithinWindow(t *testing.T) {
+	join := NewWindowJoin(time.Minute,
+		func(value interface{}) string { return value.(string)[:1] },
+		func(value interface{}) string { return value.(string)[:1] },
+		func(left, right interface{}) interface{} { return left.(string) + "+" + right.(string) })
+
+	now := time.Now()
+	left, right := join.Left(), join.Right()
+
+	entries, _ := left.Apply(Entry{Value: "a-order", Timestamp: now})
+	assert.Empty(t, entries)
+	entries, _ = right.Apply(Entry{Value: "b-payment", Timestamp: now})
+	assert.Empty(t, entries)
+
+	entries, _ = right.Apply(Entry{Value: "a-payment", Timestamp: now.Add(time.Second)})
+	assert.Len(t, entries, 1)
+	assert.EqualValues(t, "a-order+a-payment", entries[0].Value)
+	assert.EqualValues(t, now.Add(time.Second), entries[0].Timestamp)
+
+	// out of the window
+	entries, _ = left.Apply(Entry{Value: "b-order", Timestamp: now.Add(2 * time.Minute)})
+	assert.Empty(t, entries)
+}
@@ -2,6 +2,8 @@ package go_streams
 
 import (
 	"fmt"
+	"sort"
+	"strings"
 	"time"
 )
 
@@ -21,30 +23,52 @@ type Logger interface {
 	Error(message string, args ...interface{})
 }
 
+// Fields are the contextual attributes of log messages (e.g: the stream, stage and entry key)
+type Fields map[string]interface{}
+
+// Field names attached by the engine and the processors
+const (
+	FieldStream = "stream"
+	FieldStage  = "stage"
+	FieldKey    = "key"
+)
+
+// FieldLogger is a Logger that handles contextual fields (e.g: structured loggers),
+// loggers that don't implement it get the fields appended to their messages.
+type FieldLogger interface {
+	Logger
+	WithFields(fields Fields) Logger
+}
+
 type defaultLogger struct {
 	level LogLevel
 }
 
-func (l *defaultLogger) write(level LogLevel, levelStr, str string) {
-	if level >= l.level {
-		fmt.Printf("%s [%s] - %s\n", time.Now().Format(time.RFC3339), levelStr, str)
+func (l *defaultLogger) write(level LogLevel, levelStr, message string, args []interface{}) {
+	if l.enabled(level) {
+		fmt.Printf("%s [%s] - %s\n", time.Now().Format(time.RFC3339), levelStr, fmt.Sprintf(message, args...))
 	}
 }
 
+// enabled tells whether messages of the level are written, disabled messages aren't formatted
+func (l *defaultLogger) enabled(level LogLevel) bool {
+	return level >= l.level
+}
+
 func (l *defaultLogger) Debug(message string, args ...interface{}) {
-	l.write(Debug, "DEBUG", fmt.Sprintf(message, args...))
+	l.write(Debug, "DEBUG", message, args)
 }
 
 func (l *defaultLogger) Info(message string, args ...interface{}) {
-	l.write(Info, "INFO", fmt.Sprintf(message, args...))
+	l.write(Info, "INFO", message, args)
 }
 
 func (l *defaultLogger) Warn(message string, args ...interface{}) {
-	l.write(Warn, "WARN", fmt.Sprintf(message, args...))
+	l.write(Warn, "WARN", message, args)
 }
 
 func (l *defaultLogger) Error(message string, args ...interface{}) {
-	l.write(Error, "ERROR", fmt.Sprintf(message, args...))
+	l.write(Error, "ERROR", message, args)
 }
 
 var logger Logger = &defaultLogger{Debug}
@@ -53,6 +77,8 @@ func Log() Logger {
 	return logger
 }
 
+// SetLogger replaces the logger of the engine, the processors and the connectors,
+// see the logging package for the adapters of structured loggers.
 func SetLogger(l Logger) {
 	logger = l
 }
@@ -63,3 +89,77 @@ func SetLogLevel(level LogLevel) {
 		l.level = level
 	}
 }
+
+// LogWith returns the logger with the contextual fields attached
+func LogWith(fields Fields) Logger {
+	if l, ok := logger.(FieldLogger); ok {
+		return l.WithFields(fields)
+	}
+	return &fieldsLogger{logger: logger, fields: fields}
+}
+
+// streamLog returns the logger of a stream
+func streamLog(stream Stream) Logger {
+	return LogWith(Fields{FieldStream: stream.GetSource().Name()})
+}
+
+// fieldsLogger appends the fields to the messages of a logger as key=value pairs
+type fieldsLogger struct {
+	logger Logger
+	fields Fields
+}
+
+func (this *fieldsLogger) WithFields(fields Fields) Logger {
+	merged := make(Fields, len(this.fields)+len(fields))
+	for k, v := range this.fields {
+		merged[k] = v
+	}
+	for k, v := range fields {
+		merged[k] = v
+	}
+	return &fieldsLogger{logger: this.logger, fields: merged}
+}
+
+func (this *fieldsLogger) format(message string, args []interface{}) string {
+	keys := make([]string, 0, len(this.fields))
+	for k := range this.fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, len(keys))
+	for idx, k := range keys {
+		pairs[idx] = fmt.Sprintf("%s=%v", k, this.fields[k])
+	}
+	return fmt.Sprintf(message, args...) + " " + strings.Join(pairs, " ")
+}
+
+// enabled skips the formatting of the messages the default logger won't write
+func (this *fieldsLogger) enabled(level LogLevel) bool {
+	l, ok := this.logger.(*defaultLogger)
+	return !ok || l.enabled(level)
+}
+
+func (this *fieldsLogger) Debug(message string, args ...interface{}) {
+	if this.enabled(Debug) {
+		this.logger.Debug("%s", this.format(message, args))
+	}
+}
+
+func (this *fieldsLogger) Info(message string, args ...interface{}) {
+	if this.enabled(Info) {
+		this.logger.Info("%s", this.format(message, args))
+	}
+}
+
+func (this *fieldsLogger) Warn(message string, args ...interface{}) {
+	if this.enabled(Warn) {
+		this.logger.Warn("%s", this.format(message, args))
+	}
+}
+
+func (this *fieldsLogger) Error(message string, args ...interface{}) {
+	if this.enabled(Error) {
+		this.logger.Error("%s", this.format(message, args))
+	}
+}
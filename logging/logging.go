@@ -0,0 +1,118 @@
+// Package logging adapts structured loggers to the streams Logger, the contextual fields
+// attached by the engine and the processors (stream, stage, key) become fields of the logger.
+package logging
+
+import (
+	"fmt"
+	"sort"
+
+	streams "github.com/matang28/go-streams"
+)
+
+// keyValues flattens the fields into sorted key/value pairs
+func keyValues(fields streams.Fields) []interface{} {
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	kv := make([]interface{}, 0, 2*len(keys))
+	for _, k := range keys {
+		kv = append(kv, k, fields[k])
+	}
+	return kv
+}
+
+func merge(fields streams.Fields, more streams.Fields) streams.Fields {
+	merged := make(streams.Fields, len(fields)+len(more))
+	for k, v := range fields {
+		merged[k] = v
+	}
+	for k, v := range more {
+		merged[k] = v
+	}
+	return merged
+}
+
+// SugaredLogger is the logger of zap adapters (e.g: *zap.SugaredLogger)
+type SugaredLogger interface {
+	Debugw(msg string, keysAndValues ...interface{})
+	Infow(msg string, keysAndValues ...interface{})
+	Warnw(msg string, keysAndValues ...interface{})
+	Errorw(msg string, keysAndValues ...interface{})
+}
+
+type zapLogger struct {
+	logger SugaredLogger
+	fields streams.Fields
+}
+
+// Zap adapts a zap sugared logger, e.g: streams.SetLogger(logging.Zap(zap.L().Sugar()))
+func Zap(logger SugaredLogger) streams.FieldLogger {
+	return &zapLogger{logger: logger}
+}
+
+func (this *zapLogger) WithFields(fields streams.Fields) streams.Logger {
+	return &zapLogger{logger: this.logger, fields: merge(this.fields, fields)}
+}
+
+func (this *zapLogger) Debug(message string, args ...interface{}) {
+	this.logger.Debugw(fmt.Sprintf(message, args...), keyValues(this.fields)...)
+}
+
+func (this *zapLogger) Info(message string, args ...interface{}) {
+	this.logger.Infow(fmt.Sprintf(message, args...), keyValues(this.fields)...)
+}
+
+func (this *zapLogger) Warn(message string, args ...interface{}) {
+	this.logger.Warnw(fmt.Sprintf(message, args...), keyValues(this.fields)...)
+}
+
+func (this *zapLogger) Error(message string, args ...interface{}) {
+	this.logger.Errorw(fmt.Sprintf(message, args...), keyValues(this.fields)...)
+}
+
+// FormatLogger is the logger of logrus adapters (e.g: *logrus.Logger and *logrus.Entry)
+type FormatLogger interface {
+	Debugf(format string, args ...interface{})
+	Infof(format string, args ...interface{})
+	Warnf(format string, args ...interface{})
+	Errorf(format string, args ...interface{})
+}
+
+type logrusLogger struct {
+	logger     FormatLogger
+	withFields func(fields map[string]interface{}) FormatLogger
+	fields     streams.Fields
+}
+
+// Logrus adapts a logrus logger, withFields attaches the fields to it, e.g:
+//
+//	streams.SetLogger(logging.Logrus(logrus.StandardLogger(), func(fields map[string]interface{}) logging.FormatLogger {
+//		return logrus.WithFields(fields)
+//	}))
+func Logrus(logger FormatLogger, withFields func(fields map[string]interface{}) FormatLogger) streams.FieldLogger {
+	return &logrusLogger{logger: logger, withFields: withFields}
+}
+
+func (this *logrusLogger) WithFields(fields streams.Fields) streams.Logger {
+	merged := merge(this.fields, fields)
+	return &logrusLogger{logger: this.withFields(merged), withFields: this.withFields, fields: merged}
+}
+
+func (this *logrusLogger) Debug(message string, args ...interface{}) {
+	this.logger.Debugf(message, args...)
+}
+
+func (this *logrusLogger) Info(message string, args ...interface{}) {
+	this.logger.Infof(message, args...)
+}
+
+func (this *logrusLogger) Warn(message string, args ...interface{}) {
+	this.logger.Warnf(message, args...)
+}
+
+func (this *logrusLogger) Error(message string, args ...interface{}) {
+	this.logger.Errorf(message, args...)
+}
@@ -0,0 +1,66 @@
+package logging
+
+import (
+	"fmt"
+	"testing"
+
+	streams "github.com/matang28/go-streams"
+	"github.com/stretchr/testify/assert"
+)
+
+type recordingLogger struct {
+	fields map[string]interface{}
+	lines  *[]string
+}
+
+func (this *recordingLogger) record(level string, msg string, kv []interface{}) {
+	*this.lines = append(*this.lines, fmt.Sprintf("%s %s %v", level, msg, kv))
+}
+
+func (this *recordingLogger) Debugw(msg string, kv ...interface{}) { this.record("debug", msg, kv) }
+func (this *recordingLogger) Infow(msg string, kv ...interface{})  { this.record("info", msg, kv) }
+func (this *recordingLogger) Warnw(msg string, kv ...interface{})  { this.record("warn", msg, kv) }
+func (this *recordingLogger) Errorw(msg string, kv ...interface{}) { this.record("error", msg, kv) }
+
+func (this *recordingLogger) Debugf(format string, args ...interface{}) {
+	this.record("debug", fmt.Sprintf(format, args...), []interface{}{this.fields})
+}
+func (this *recordingLogger) Infof(format string, args ...interface{}) {
+	this.record("info", fmt.Sprintf(format, args...), []interface{}{this.fields})
+}
+func (this *recordingLogger) Warnf(format string, args ...interface{}) {
+	this.record("warn", fmt.Sprintf(format, args...), []interface{}{this.fields})
+}
+func (this *recordingLogger) Errorf(format string, args ...interface{}) {
+	this.record("error", fmt.Sprintf(format, args...), []interface{}{this.fields})
+}
+
+func TestZap(t *testing.T) {
+	var lines []string
+	logger := Zap(&recordingLogger{lines: &lines})
+	logger.Info("started %d", 1)
+	logger.WithFields(streams.Fields{streams.FieldStream: "orders"}).(streams.FieldLogger).
+		WithFields(streams.Fields{streams.FieldKey: "1"}).Error("failed")
+
+	assert.EqualValues(t, []string{"info started 1 []", "error failed [key 1 stream orders]"}, lines)
+}
+
+func TestLogrus(t *testing.T) {
+	var lines []string
+	logger := Logrus(&recordingLogger{lines: &lines}, func(fields map[string]interface{}) FormatLogger {
+		return &recordingLogger{lines: &lines, fields: fields}
+	})
+	logger.WithFields(streams.Fields{streams.FieldStage: streams.SinkStage}).Warn("retrying in %s", "1s")
+
+	assert.EqualValues(t, []string{"warn retrying in 1s [map[stage:sink]]"}, lines)
+}
+
+func TestSetLogger_FieldsFromTheEngine(t *testing.T) {
+	var lines []string
+	previous := streams.Log()
+	streams.SetLogger(Zap(&recordingLogger{lines: &lines}))
+	defer streams.SetLogger(previous)
+
+	streams.LogWith(streams.Fields{streams.FieldStream: "orders"}).Debug("done")
+	assert.EqualValues(t, []string{"debug done [stream orders]"}, lines)
+}
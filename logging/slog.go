@@ -0,0 +1,40 @@
+//go:build go1.21
+// +build go1.21
+
+package logging
+
+import (
+	"fmt"
+	"log/slog"
+
+	streams "github.com/matang28/go-streams"
+)
+
+type slogLogger struct {
+	logger *slog.Logger
+}
+
+// Slog adapts a log/slog logger, e.g: streams.SetLogger(logging.Slog(slog.Default()))
+func Slog(logger *slog.Logger) streams.FieldLogger {
+	return &slogLogger{logger: logger}
+}
+
+func (this *slogLogger) WithFields(fields streams.Fields) streams.Logger {
+	return &slogLogger{logger: this.logger.With(keyValues(fields)...)}
+}
+
+func (this *slogLogger) Debug(message string, args ...interface{}) {
+	this.logger.Debug(fmt.Sprintf(message, args...))
+}
+
+func (this *slogLogger) Info(message string, args ...interface{}) {
+	this.logger.Info(fmt.Sprintf(message, args...))
+}
+
+func (this *slogLogger) Warn(message string, args ...interface{}) {
+	this.logger.Warn(fmt.Sprintf(message, args...))
+}
+
+func (this *slogLogger) Error(message string, args ...interface{}) {
+	this.logger.Error(fmt.Sprintf(message, args...))
+}
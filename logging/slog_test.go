@@ -0,0 +1,21 @@
+//go:build go1.21
+// +build go1.21
+
+package logging
+
+import (
+	"bytes"
+	"log/slog"
+	"testing"
+
+	streams "github.com/matang28/go-streams"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSlog(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	logger := Slog(slog.New(slog.NewTextHandler(buffer, &slog.HandlerOptions{Level: slog.LevelDebug})))
+	logger.WithFields(streams.Fields{streams.FieldStream: "orders", streams.FieldKey: "1"}).Warn("failed %d times", 2)
+
+	assert.Contains(t, buffer.String(), `level=WARN msg="failed 2 times" key=1 stream=orders`)
+}
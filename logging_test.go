@@ -0,0 +1,63 @@
+package go_streams
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type linesLogger struct {
+	lines []string
+}
+
+func (this *linesLogger) Debug(message string, args ...interface{}) {
+	this.lines = append(this.lines, "DEBUG "+fmt.Sprintf(message, args...))
+}
+
+func (this *linesLogger) Info(message string, args ...interface{}) {
+	this.lines = append(this.lines, "INFO "+fmt.Sprintf(message, args...))
+}
+
+func (this *linesLogger) Warn(message string, args ...interface{}) {
+	this.lines = append(this.lines, "WARN "+fmt.Sprintf(message, args...))
+}
+
+func (this *linesLogger) Error(message string, args ...interface{}) {
+	this.lines = append(this.lines, "ERROR "+fmt.Sprintf(message, args...))
+}
+
+func TestLogWith_AppendsFieldsToPlainLoggers(t *testing.T) {
+	previous := Log()
+	plain := &linesLogger{}
+	SetLogger(plain)
+	defer SetLogger(previous)
+
+	log := LogWith(Fields{FieldStream: "orders", FieldStage: MapStage})
+	log.Warn("100%% done in %s", "1s")
+	log.(FieldLogger).WithFields(Fields{FieldKey: "42"}).Error("failed")
+
+	assert.EqualValues(t, []string{
+		"WARN 100% done in 1s stage=map stream=orders",
+		"ERROR failed key=42 stage=map stream=orders",
+	}, plain.lines)
+}
+
+type countingStringer struct {
+	calls int
+}
+
+func (this *countingStringer) String() string {
+	this.calls++
+	return "value"
+}
+
+func TestLogWith_DisabledLevelsAreNotFormatted(t *testing.T) {
+	SetLogLevel(Warn)
+	defer SetLogLevel(Debug)
+
+	value := &countingStringer{}
+	LogWith(Fields{FieldStream: "orders"}).Debug("%s", value)
+	Log().Info("%s", value)
+	assert.EqualValues(t, 0, value.calls)
+}
@@ -0,0 +1,375 @@
+package go_streams
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// managedSource wraps the source of a stream added to the engine,
+// it counts the entries sent and committed, holds back entries while the stream is paused
+// restarts the source when it fails according to the restart policy and buffers entries
+// according to the buffer configuration of the stream.
+type managedSource struct {
+	Source
+	received   uint64
+	committed  uint64
+	lastSinkAt int64
+
+	mutex     *sync.Mutex
+	resumeCh  chan struct{}
+	startedAt time.Time
+	stoppedAt time.Time
+	running   bool
+	failed    bool
+
+	policy   RestartPolicy
+	events   func(Event)
+	stopCh   chan struct{}
+	stopOnce *sync.Once
+
+	buffer *boundedQueue
+
+	// scheduled is the handle of the stream on the engine's scheduler (nil when it has none)
+	scheduled *scheduledStream
+
+	// governor holds the limits of the engine and inFlight the entries sent to the processor
+	// that weren't processed yet (nil when they are unbounded)
+	governor *governor
+	inFlight inFlightLimit
+
+	lastError   error
+	lastErrorAt time.Time
+}
+
+func newManagedSource(source Source) *managedSource {
+	return &managedSource{
+		Source:   source,
+		mutex:    &sync.Mutex{},
+		policy:   NeverRestart(),
+		events:   func(Event) {},
+		stopCh:   make(chan struct{}),
+		stopOnce: &sync.Once{},
+	}
+}
+
+// supervise sets the restart policy of the source and the handler of its events
+func (this *managedSource) supervise(policy RestartPolicy, events func(Event)) {
+	this.policy = policy
+	this.events = events
+}
+
+// Start starts the source and restarts it according to the restart policy, the channel is closed
+// and the EOF error is sent once the source ended and won't be restarted.
+func (this *managedSource) Start(channel EntryChannel, errorChannel ErrorChannel) {
+	this.mutex.Lock()
+	this.startedAt = time.Now()
+	this.mutex.Unlock()
+
+	errs := make(ErrorChannel)
+	fatal := make(chan error, 1)
+	go this.forwardErrors(errs, errorChannel, fatal)
+
+	out := channel
+	drained := make(chan struct{})
+	if this.buffer != nil {
+		out = make(EntryChannel)
+		go this.drain(out, channel, errorChannel, drained)
+	} else {
+		close(drained)
+	}
+
+	for attempt := 1; ; attempt++ {
+		failure := this.run(out, errs)
+		if failure == nil {
+			// the source may have reported why it stopped sending entries
+			select {
+			case err := <-fatal:
+				failure = err
+			default:
+			}
+		}
+		if failure != nil {
+			this.emit(Event{Type: SourceFailed, Err: failure})
+		}
+
+		if this.stopped() || !this.policy.shouldRestart(failure != nil, attempt) {
+			if failure != nil {
+				this.mutex.Lock()
+				this.failed = true
+				this.mutex.Unlock()
+				this.emit(Event{Type: SourceGaveUp, Err: failure})
+			}
+			break
+		}
+
+		delay := this.policy.delay(attempt)
+		this.emit(Event{Type: SourceRestarting, Attempt: attempt, Delay: delay, Err: failure})
+		select {
+		case <-this.stopCh:
+		case <-time.After(delay):
+		}
+		if this.stopped() {
+			break
+		}
+	}
+
+	if this.buffer != nil {
+		close(out)
+	}
+	<-drained
+
+	this.mutex.Lock()
+	this.stoppedAt = time.Now()
+	this.mutex.Unlock()
+	close(channel)
+	errorChannel <- NewEofError(this.Source)
+}
+
+// run starts the source once and forwards its entries until it ends, it returns the failure
+// when the source panicked or returned without closing its channel.
+func (this *managedSource) run(channel EntryChannel, errs ErrorChannel) error {
+	inner := make(EntryChannel)
+	done := make(chan error, 1)
+
+	this.mutex.Lock()
+	this.running = true
+	this.mutex.Unlock()
+	defer func() {
+		this.mutex.Lock()
+		this.running = false
+		this.mutex.Unlock()
+	}()
+
+	go func() {
+		defer func() {
+			if p := recover(); p != nil {
+				done <- NewFatalError(panicError(p))
+			}
+			close(done)
+		}()
+		this.Source.Start(inner, errs)
+	}()
+
+	for {
+		select {
+		case entry, ok := <-inner:
+			if !ok {
+				return nil
+			}
+			this.forward(channel, entry)
+
+		case err := <-done:
+			// Start returned, a closed channel means it ended normally
+			select {
+			case entry, ok := <-inner:
+				if !ok {
+					return err
+				}
+				this.forward(channel, entry)
+				continue
+			default:
+			}
+			if err == nil {
+				err = NewFatalError(fmt.Errorf("source returned without closing its channel"))
+			}
+			return err
+		}
+	}
+}
+
+func (this *managedSource) forward(channel EntryChannel, entry Entry) {
+	if resume := this.gate(); resume != nil {
+		<-resume
+	}
+	atomic.AddUint64(&this.received, 1)
+	// buffered entries take their in flight slot once they leave the buffer (see drain)
+	if this.buffer == nil {
+		this.inFlight.acquire()
+	}
+	channel <- entry
+}
+
+// drain moves the entries sent by the source through the buffer to the pipeline, reading spilled
+// entries is retried with a backoff until it succeeds or the source is stopped
+func (this *managedSource) drain(in EntryChannel, channel EntryChannel, errorChannel ErrorChannel, drained chan struct{}) {
+	defer close(drained)
+
+	pushed := make(chan struct{})
+	go func() {
+		defer close(pushed)
+		for entry := range in {
+			if err := this.buffer.push(entry); err != nil {
+				errorChannel <- NewStreamError(this.Source.Name(), SourceStage, entry.Key, err)
+			}
+		}
+		this.buffer.close()
+	}()
+	defer func() { <-pushed }()
+
+	backoff := spillReadBackoff
+	for {
+		entry, ok, err := this.buffer.pop()
+		if err != nil {
+			errorChannel <- NewStreamError(this.Source.Name(), SourceStage, "", err)
+			select {
+			case <-this.stopCh:
+			case <-time.After(backoff):
+			}
+			if this.stopped() {
+				// the buffered entries weren't committed, the source delivers them again once restarted
+				LogWith(Fields{FieldStream: this.Source.Name()}).Warn("Dropping %d buffered entries, the spill file can't be read: %s", this.buffer.stats().Buffered, err.Error())
+				this.buffer.close()
+				break
+			}
+			if backoff *= 2; backoff > spillReadMaxBackoff {
+				backoff = spillReadMaxBackoff
+			}
+			continue
+		}
+		backoff = spillReadBackoff
+		if !ok {
+			break
+		}
+		if resume := this.gate(); resume != nil {
+			<-resume
+		}
+		this.inFlight.acquire()
+		channel <- entry
+	}
+
+	if err := this.buffer.remove(); err != nil {
+		LogWith(Fields{FieldStream: this.Source.Name()}).Warn("Failed to remove the spill file: %s", err.Error())
+	}
+}
+
+func (this *managedSource) bufferStats() *BufferStats {
+	if this.buffer == nil {
+		return nil
+	}
+	stats := this.buffer.stats()
+	return &stats
+}
+
+// forwardErrors forwards the errors of the source, EOF errors are dropped (the managed source
+// sends its own once it ends) and fatal errors are kept as the failure reason.
+func (this *managedSource) forwardErrors(errs ErrorChannel, errorChannel ErrorChannel, fatal chan error) {
+	for err := range errs {
+		switch e := err.(type) {
+		case *EofError:
+			continue
+		case *FatalError:
+			select {
+			case <-fatal:
+			default:
+			}
+			fatal <- e
+		}
+		errorChannel <- err
+	}
+}
+
+// Stop stops the source, a source waiting to be restarted isn't restarted
+func (this *managedSource) Stop() error {
+	this.stopOnce.Do(func() {
+		close(this.stopCh)
+	})
+
+	this.mutex.Lock()
+	running := this.running
+	this.mutex.Unlock()
+	if !running {
+		return nil
+	}
+	return this.Source.Stop()
+}
+
+func (this *managedSource) stopped() bool {
+	select {
+	case <-this.stopCh:
+		return true
+	default:
+		return false
+	}
+}
+
+func (this *managedSource) hasFailed() bool {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	return this.failed
+}
+
+func (this *managedSource) emit(event Event) {
+	event.Stream = this.Source.Name()
+	event.Time = time.Now()
+	this.events(event)
+}
+
+func (this *managedSource) CommitEntry(keys ...string) error {
+	err := this.Source.CommitEntry(keys...)
+	if err == nil {
+		atomic.AddUint64(&this.committed, uint64(len(keys)))
+		this.emit(Event{Type: EntriesCommitted, Keys: keys})
+	}
+	return err
+}
+
+// pause holds back the entries sent by the source until resume is called,
+// the source is blocked on its channel in the meantime.
+func (this *managedSource) pause() {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	if this.resumeCh == nil {
+		this.resumeCh = make(chan struct{})
+	}
+}
+
+func (this *managedSource) resume() {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	if this.resumeCh != nil {
+		close(this.resumeCh)
+		this.resumeCh = nil
+	}
+}
+
+func (this *managedSource) paused() bool {
+	return this.gate() != nil
+}
+
+func (this *managedSource) gate() chan struct{} {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	return this.resumeCh
+}
+
+// throughput returns the average number of entries sent per second while the source was running
+func (this *managedSource) throughput() float64 {
+	this.mutex.Lock()
+	started, stopped := this.startedAt, this.stoppedAt
+	this.mutex.Unlock()
+
+	if started.IsZero() {
+		return 0
+	}
+	if stopped.IsZero() {
+		stopped = time.Now()
+	}
+
+	elapsed := stopped.Sub(started).Seconds()
+	if elapsed <= 0 {
+		return 0
+	}
+	return float64(atomic.LoadUint64(&this.received)) / elapsed
+}
+
+// managedStream replaces the source of the stream with its managed source
+type managedStream struct {
+	Stream
+	source *managedSource
+}
+
+func (this *managedStream) GetSource() Source {
+	return this.source
+}
@@ -0,0 +1,236 @@
+package go_streams
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Authorizer is called before every management request, returning an error rejects the request with 401.
+type Authorizer func(r *http.Request) error
+
+type managementHandler struct {
+	engine    Engine
+	authorize Authorizer
+}
+
+// NewManagementHandler creates an http handler exposing the management API of the engine:
+//
+//	GET  /streams                  list the streams with their status and throughput
+//	GET  /streams/{name}           a single stream status
+//	POST /streams/{name}/pause     pause a stream
+//	POST /streams/{name}/resume    resume a paused stream
+//	POST /streams/{name}/stop      stop a stream
+//	POST /streams/{name}/seek      seek the source to ?timestamp= (RFC3339) or ?key=
+//	POST /streams/{name}/rewind    rewind the source
+//	GET  /config                   dump the engine and streams configuration
+//	GET  /progress                 the position and lag of the source of every stream
+//	GET  /health                   200 while the engine is running, 503 otherwise
+//
+// The authorizer is optional, without one anyone that reaches the handler can stop streams
+// so it must not be exposed publicly.
+func NewManagementHandler(engine Engine, authorize Authorizer) http.Handler {
+	return &managementHandler{engine: engine, authorize: authorize}
+}
+
+// ServeManagement starts serving the management API on the given address (e.g: "127.0.0.1:8080"),
+// the server is closed when the engine is stopped. See NewManagementHandler for the authorizer.
+func (this *engine) ServeManagement(addr string, authorize Authorizer) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	server := &http.Server{Handler: NewManagementHandler(this, authorize)}
+	this.mutex.Lock()
+	this.servers = append(this.servers, server)
+	this.mutex.Unlock()
+
+	go func() {
+		if err := server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			logger.Error("Management server stopped: %s", err.Error())
+		}
+	}()
+	logger.Info("Serving management API on %s", listener.Addr().String())
+	return nil
+}
+
+func (this *managementHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if this.authorize != nil {
+		if err := this.authorize(r); err != nil {
+			writeError(w, http.StatusUnauthorized, err)
+			return
+		}
+	}
+
+	path := strings.Trim(r.URL.EscapedPath(), "/")
+	parts := strings.Split(path, "/")
+
+	switch {
+	case path == "health":
+		this.health(w, r)
+
+	case path == "config":
+		if !allowMethod(w, r, http.MethodGet) {
+			return
+		}
+		writeJSON(w, http.StatusOK, this.engine.Config())
+
+	case path == "progress":
+		if !allowMethod(w, r, http.MethodGet) {
+			return
+		}
+		writeJSON(w, http.StatusOK, this.engine.Progress())
+
+	case path == "streams":
+		if !allowMethod(w, r, http.MethodGet) {
+			return
+		}
+		writeJSON(w, http.StatusOK, this.engine.Streams())
+
+	case parts[0] == "streams" && len(parts) <= 3:
+		name, err := url.PathUnescape(parts[1])
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		if len(parts) == 2 {
+			this.stream(w, r, name)
+		} else {
+			this.action(w, r, name, parts[2])
+		}
+
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (this *managementHandler) health(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodGet) {
+		return
+	}
+
+	up := true
+	if e, ok := this.engine.(interface{ Running() bool }); ok {
+		up = e.Running()
+	}
+
+	counts := make(map[StreamStatus]int)
+	for _, info := range this.engine.Streams() {
+		counts[info.Status]++
+	}
+
+	body := map[string]interface{}{"status": "up", "streams": counts}
+	if !up {
+		body["status"] = "down"
+		writeJSON(w, http.StatusServiceUnavailable, body)
+		return
+	}
+	writeJSON(w, http.StatusOK, body)
+}
+
+func (this *managementHandler) stream(w http.ResponseWriter, r *http.Request, name string) {
+	if !allowMethod(w, r, http.MethodGet) {
+		return
+	}
+
+	for _, info := range this.engine.Streams() {
+		if info.Name == name {
+			writeJSON(w, http.StatusOK, info)
+			return
+		}
+	}
+	writeError(w, http.StatusNotFound, NewUnknownStreamError(name))
+}
+
+func (this *managementHandler) action(w http.ResponseWriter, r *http.Request, name, action string) {
+	var fn func(string) error
+	switch action {
+	case "pause":
+		fn = this.engine.Pause
+	case "resume":
+		fn = this.engine.Resume
+	case "stop":
+		fn = this.engine.StopStream
+	case "seek":
+		fn = func(name string) error { return this.seek(name, r.URL.Query()) }
+	case "rewind":
+		fn = this.engine.Rewind
+	default:
+		http.NotFound(w, r)
+		return
+	}
+
+	if !allowMethod(w, r, http.MethodPost) {
+		return
+	}
+
+	if err := fn(name); err != nil {
+		switch err.(type) {
+		case *UnknownStreamError:
+			writeError(w, http.StatusNotFound, err)
+		case *StreamStateError:
+			writeError(w, http.StatusConflict, err)
+		case *UnseekableSourceError:
+			writeError(w, http.StatusNotImplemented, err)
+		case *badRequestError:
+			writeError(w, http.StatusBadRequest, err)
+		default:
+			writeError(w, http.StatusInternalServerError, err)
+		}
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (this *managementHandler) seek(name string, query url.Values) error {
+	if key := query.Get("key"); key != "" {
+		return this.engine.SeekToKey(name, key)
+	}
+	if timestamp := query.Get("timestamp"); timestamp != "" {
+		at, err := time.Parse(time.RFC3339, timestamp)
+		if err != nil {
+			return &badRequestError{err: err}
+		}
+		return this.engine.SeekToTimestamp(name, at)
+	}
+	return &badRequestError{err: fmt.Errorf("seek requires a key or a timestamp")}
+}
+
+// badRequestError is returned by the actions whose parameters are invalid
+type badRequestError struct {
+	err error
+}
+
+func (e *badRequestError) Error() string {
+	return e.err.Error()
+}
+
+func allowMethod(w http.ResponseWriter, r *http.Request, method string) bool {
+	if r.Method != method {
+		w.Header().Set("Allow", method)
+		writeError(w, http.StatusMethodNotAllowed, nil)
+		return false
+	}
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		logger.Error("Failed to write management response: %s", err.Error())
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	message := http.StatusText(status)
+	if err != nil {
+		message = err.Error()
+	}
+	writeJSON(w, status, map[string]string{"error": message})
+}
@@ -0,0 +1,140 @@
+package go_streams
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestManagementHandler_StreamsAndConfig(t *testing.T) {
+	engine := NewEngine(NewDirectProcessorFactory(), 10*time.Second)
+	source := NewSequentialIntegerSource(0, time.Millisecond)
+	assert.Nil(t, engine.Add(addOneFilterOddsStream(source, NewArraySink())))
+	server := httptest.NewServer(NewManagementHandler(engine, nil))
+	defer server.Close()
+
+	res, err := http.Get(server.URL + "/health")
+	assert.Nil(t, err)
+	assert.EqualValues(t, http.StatusServiceUnavailable, res.StatusCode)
+
+	done := make(chan bool)
+	go func() {
+		engine.Start()
+		done <- true
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	res, err = http.Get(server.URL + "/health")
+	assert.Nil(t, err)
+	assert.EqualValues(t, http.StatusOK, res.StatusCode)
+
+	var streams []StreamInfo
+	getJSON(t, server.URL+"/streams", &streams)
+	assert.Len(t, streams, 1)
+	assert.EqualValues(t, source.Name(), streams[0].Name)
+	assert.EqualValues(t, StreamRunning, streams[0].Status)
+	assert.True(t, streams[0].Received > 0)
+
+	var config EngineConfig
+	getJSON(t, server.URL+"/config", &config)
+	assert.EqualValues(t, "*go_streams.directProcessor", config.Processor)
+	assert.EqualValues(t, "10s", config.MonitorInterval)
+	assert.EqualValues(t, []string{"map", "filter", "sink(*go_streams.ArraySink)"}, config.Streams[0].Handlers)
+
+	var progress []StreamProgress
+	getJSON(t, server.URL+"/progress", &progress)
+	assert.Len(t, progress, 1)
+	assert.EqualValues(t, "entries", progress[0].Source.Unit)
+	assert.True(t, progress[0].Source.Position > 0)
+
+	assert.EqualValues(t, http.StatusNoContent, post(t, server.URL+"/streams/"+source.Name()+"/pause"))
+	var info StreamInfo
+	getJSON(t, server.URL+"/streams/"+source.Name(), &info)
+	assert.EqualValues(t, StreamPaused, info.Status)
+
+	assert.EqualValues(t, http.StatusNoContent, post(t, server.URL+"/streams/"+source.Name()+"/resume"))
+	getJSON(t, server.URL+"/streams/"+source.Name(), &info)
+	assert.EqualValues(t, StreamRunning, info.Status)
+
+	assert.EqualValues(t, http.StatusNoContent, post(t, server.URL+"/streams/"+source.Name()+"/stop"))
+	<-done
+
+	assert.EqualValues(t, http.StatusConflict, post(t, server.URL+"/streams/"+source.Name()+"/stop"))
+	assert.EqualValues(t, http.StatusNotFound, post(t, server.URL+"/streams/missing/pause"))
+
+	res, err = http.Get(server.URL + "/streams/" + source.Name() + "/stop")
+	assert.Nil(t, err)
+	assert.EqualValues(t, http.StatusMethodNotAllowed, res.StatusCode)
+
+	getJSON(t, server.URL+"/streams/"+source.Name(), &info)
+	assert.EqualValues(t, StreamStopped, info.Status)
+}
+
+func TestManagementHandler_Seek(t *testing.T) {
+	engine := NewEngine(NewDirectProcessorFactory(), 10*time.Second)
+	seekable := &seekRecorder{AppendSource: NewAppendSource(1)}
+	other := NewSequentialIntegerSource(1, 0)
+	assert.Nil(t, engine.Add(NewStream(seekable).Sink(NewArraySink()), NewStream(other).Sink(NewArraySink())))
+	server := httptest.NewServer(NewManagementHandler(engine, nil))
+	defer server.Close()
+
+	url := server.URL + "/streams/" + seekable.Name()
+	assert.EqualValues(t, http.StatusNoContent, post(t, url+"/seek?timestamp=2026-01-02T03:04:05Z"))
+	assert.EqualValues(t, http.StatusNoContent, post(t, url+"/seek?key=42"))
+	assert.EqualValues(t, http.StatusNoContent, post(t, url+"/rewind"))
+	assert.EqualValues(t, []string{"2026-01-02T03:04:05Z", "42", "rewind"}, seekable.seeks)
+
+	assert.EqualValues(t, http.StatusBadRequest, post(t, url+"/seek"))
+	assert.EqualValues(t, http.StatusBadRequest, post(t, url+"/seek?timestamp=yesterday"))
+	assert.EqualValues(t, http.StatusNotImplemented, post(t, server.URL+"/streams/"+other.Name()+"/rewind"))
+}
+
+func TestManagementHandler_Authorizer(t *testing.T) {
+	engine := NewEngine(NewDirectProcessorFactory(), 10*time.Second)
+	server := httptest.NewServer(NewManagementHandler(engine, func(r *http.Request) error {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			return fmt.Errorf("invalid token")
+		}
+		return nil
+	}))
+	defer server.Close()
+
+	res, err := http.Get(server.URL + "/config")
+	assert.Nil(t, err)
+	assert.EqualValues(t, http.StatusUnauthorized, res.StatusCode)
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL+"/config", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	res, err = http.DefaultClient.Do(req)
+	assert.Nil(t, err)
+	assert.EqualValues(t, http.StatusOK, res.StatusCode)
+}
+
+func TestEngine_ServeManagement(t *testing.T) {
+	engine := NewEngine(NewDirectProcessorFactory(), 10*time.Second)
+	assert.Nil(t, engine.ServeManagement("127.0.0.1:0", nil))
+	assert.Len(t, engine.servers, 1)
+
+	// Stop shouldn't block when the engine wasn't started
+	engine.Stop()
+	assert.Empty(t, engine.servers)
+}
+
+func getJSON(t *testing.T, url string, out interface{}) {
+	res, err := http.Get(url)
+	assert.Nil(t, err)
+	defer res.Body.Close()
+	assert.EqualValues(t, http.StatusOK, res.StatusCode)
+	assert.Nil(t, json.NewDecoder(res.Body).Decode(out))
+}
+
+func post(t *testing.T, url string) int {
+	res, err := http.Post(url, "application/json", nil)
+	assert.Nil(t, err)
+	defer res.Body.Close()
+	return res.StatusCode
+}
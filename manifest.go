@@ -1,5 +1,11 @@
 package go_streams
 
+import (
+	"context"
+	"io"
+	"time"
+)
+
 // Entry is the data model that go-streams passes between
 // different operators although the user never handle it directly
 // when creating new streams.
@@ -12,6 +18,14 @@ type Entry struct {
 	// Value is the actual model that the user transforms
 	Value interface{}
 
+	// Metadata holds optional source specific attributes of this entry
+	// (e.g: the table and operation of a change data capture event).
+	Metadata map[string]string
+
+	// Timestamp is the event time of this entry, sources that
+	// doesn't track event time may leave it empty.
+	Timestamp time.Time
+
 	// Filtered indicates that this row should be filtered the filter,
 	// in order to decrease array mutation operations.
 	Filtered bool
@@ -24,6 +38,9 @@ type KeyExtractor func(entry Entry) string
 // MapFunc is a function which transforms its input
 type MapFunc func(entry interface{}) interface{}
 
+// BytesMapFunc transforms a []byte value, see Stream.MapBytes
+type BytesMapFunc func(value []byte) []byte
+
 // FilterFunc is a function that takes an entry an decided
 // if this entry should be filtered out
 // return true to keep the record or false to filter it out.
@@ -48,10 +65,110 @@ type Stream interface {
 	// Map entries
 	Map(fn MapFunc) Stream
 
+	// MapBytes maps the []byte values of the entries (other values are passed as is), values
+	// transformed in place (the same slice is returned) aren't boxed again in the entries.
+	MapBytes(fn BytesMapFunc) Stream
+
+	// MapAsync maps up to concurrency entries at once, see NewMapAsync
+	MapAsync(fn MapErrFunc, concurrency int, preserveOrder bool) Stream
+
+	// MapOutputs maps entries with a function that may emit values to the side outputs of the stream
+	// (e.g: invalid records), entries are dropped from the main flow when the function doesn't keep them.
+	MapOutputs(fn SideOutputFunc) Stream
+
+	// SideOutput attaches a sink to the named side output, the sink is written as soon as
+	// a value is emitted and the entry is committed with the main flow.
+	SideOutput(name string, sink Sink) Stream
+
+	// Validate rejects the entries whose value doesn't match the schema to the named side output, or to the
+	// dead letter sink of the stream when output is empty, with their violations in their metadata (see MetadataViolations).
+	// Rejected entries are committed with the main flow, a ValidationError is reported when they can't be written.
+	Validate(schema Schema, output string) Stream
+
+	// Scan maps every entry with a function that carries a state forward from the initial state, see NewScan
+	Scan(init interface{}, fn ScanFunc) Stream
+
+	// Via passes the stream entries through the operator
+	Via(operator Operator) Stream
+
+	// Throttle lets at most n entries pass per interval, the stream waits for the next entry to be allowed
+	Throttle(n int, per time.Duration) Stream
+
+	// Debounce collapses bursts of entries into their latest entry, emitted once no entry came for d
+	Debounce(d time.Duration) Stream
+
+	// Reorder holds back the entries for up to maxDelay to emit them sorted by their event time, see NewReorder
+	Reorder(maxDelay time.Duration, byTimestamp TimestampFunc) Stream
+
+	// Dedupe drops the entries whose key was already seen within the ttl (keys are kept in memory)
+	Dedupe(key KeyFunc, ttl time.Duration) Stream
+
+	// Enrich looks up the reference data of every entry through a cache (see NewEnricher)
+	Enrich(loader Loader, config EnrichConfig) Stream
+
+	// Sample keeps each entry with the probability of the rate (between 0 and 1), see NewSample for per key sampling
+	Sample(rate float64) Stream
+
+	// SampleEvery keeps one entry every n entries
+	SampleEvery(n int) Stream
+
+	// Take lets the first n entries pass, then it stops the source and the stream completes
+	Take(n int) Stream
+
+	// Skip drops the first n entries
+	Skip(n int) Stream
+
+	// TakeWhile lets entries pass while the function keeps them, the source is stopped at the first entry it doesn't keep
+	TakeWhile(fn FilterFunc) Stream
+
+	// TakeUntil lets entries pass until the context is done, then it stops the source
+	TakeUntil(ctx context.Context) Stream
+
+	// TakeFor lets entries pass for the duration since the stream started, then it stops the source
+	TakeFor(d time.Duration) Stream
+
 	// Sink (or dump) the stream entries to this Sink implementation
 	// such as file, database, memory, etc...
+	// Sink can be called several times, the sinks are written one after the other and
+	// the entries are committed after each successful write. Use a FanOutSink to write
+	// several sinks and commit once.
 	Sink(sink Sink) Stream
 
+	// OnError sets an error handler for this stream only, when set
+	// it is called instead of the engine's error handler.
+	OnError(handler ErrorHandler) Stream
+
+	// Supervise sets the restart policy of this stream only, when set
+	// it is used instead of the engine's restart policy.
+	Supervise(policy RestartPolicy) Stream
+
+	// CommitWith sets the commit strategy of this stream, by default every entry is committed once written
+	CommitWith(strategy CommitStrategy) Stream
+
+	// Buffer sets the buffer between the source and the pipeline of this stream,
+	// by default the source is blocked until the pipeline takes each entry.
+	Buffer(config BufferConfig) Stream
+
+	// Priority sets the weight of this stream in the engine's scheduler (see Engine.SetScheduler),
+	// competing streams get a share of the capacity proportional to their weight (defaults to 1).
+	Priority(weight int) Stream
+
+	// MaxInFlight bounds the entries taken from the source before they were passed through the pipeline,
+	// the source waits beyond it (0 uses the limits of the engine, see Engine.SetLimits). Buffered processors
+	// with a bigger buffer flush on their timeout.
+	MaxInFlight(n int) Stream
+
+	// WithTimeout sets the deadline of every filter, map and sink call of this stream, a call that didn't return
+	// in time is reported as a TimeoutError and its entry is handled like one whose stage panicked
+	// (see TimeoutMap and NewTimeoutSink for a single stage). Operators aren't timed.
+	WithTimeout(d time.Duration) Stream
+
+	// DeadLetter sets the sink of the entries whose filter, map, operator or sink panicked (or timed out),
+	// dead-lettered entries are skipped by the rest of the pipeline and committed. The stage and
+	// the panic are attached to their metadata (see MetadataDeadLetterStage). Without a dead letter
+	// sink panics are only reported (e.g: the entry of a map that panicked gets a nil value).
+	DeadLetter(sink Sink) Stream
+
 	// Process takes a processor implementation and an error channel
 	// and will start processing the stream.
 	Process(processor Processor, errs ErrorChannel)
@@ -61,6 +178,45 @@ type Stream interface {
 
 	// Will return the source of the stream.
 	GetSource() Source
+
+	// Will return the error handler of the stream (nil if not set).
+	GetErrorHandler() ErrorHandler
+
+	// Will return the restart policy of the stream (nil if not set).
+	GetRestartPolicy() *RestartPolicy
+
+	// Will return the commit strategy of the stream (nil if not set).
+	GetCommitStrategy() *CommitStrategy
+
+	// Will return the buffer configuration of the stream (nil if not set).
+	GetBufferConfig() *BufferConfig
+
+	// Will return the priority of the stream (0 if not set).
+	GetPriority() int
+
+	// Will return the max in flight entries of the stream (0 if not set).
+	GetMaxInFlight() int
+
+	// Will return the deadline of the handler calls of the stream (0 if not set).
+	GetTimeout() time.Duration
+
+	// Will return the dead letter sink of the stream (nil if not set).
+	GetDeadLetter() Sink
+
+	// Will return the side output sinks of the stream by their name.
+	GetSideOutputs() map[string]Sink
+}
+
+// Flusher are sinks that buffer entries across calls, Flush is called by the processors
+// when the stream ends so partially filled batches are written.
+type Flusher interface {
+	Flush() error
+}
+
+// Closer are sinks that hold resources (e.g: connections), Close is called once
+// by the engine after all the streams ended.
+type Closer interface {
+	Close() error
 }
 
 // Processor is responsible of the processing strategy,
@@ -104,6 +260,33 @@ type Source interface {
 	Name() string
 }
 
+// Pausable is implemented by sources (and streams) that can release resources while paused
+// (e.g: stop polling or pause the partitions of a consumer). The engine holds back the entries
+// of paused streams regardless, so implementing it is optional.
+type Pausable interface {
+	// Pause is called when the stream is paused, entries sent meanwhile are held back by the engine.
+	Pause() error
+
+	// Resume is called when the stream is resumed.
+	Resume() error
+}
+
+// SeekableSource is implemented by sources that can replay their entries from a position (e.g: to reprocess
+// a time range after a bug fix, see Engine.SeekToTimestamp). The entries sent after a seek start at the position,
+// the entries sent before it are still processed so pause the stream around the seek for a clean cut.
+type SeekableSource interface {
+	Source
+
+	// SeekToTimestamp moves to the first entry at or after the time
+	SeekToTimestamp(at time.Time) error
+
+	// SeekToKey moves to the entry of the key (an offset, a cursor...), its meaning is up to the source
+	SeekToKey(key string) error
+
+	// Rewind moves to the first entry the source can replay
+	Rewind() error
+}
+
 // Sink is responsible for dumping entries into sinks such as: files, databases, memory, etc...
 type Sink interface {
 	Pingable
@@ -111,18 +294,42 @@ type Sink interface {
 	// Single will dump a single entry to the sink
 	Single(entry Entry) error
 
-	// Batch will dump a batch of entries to the sink
+	// Batch will dump a batch of entries to the sink, the processors reuse the slice
+	// for the next batches so sinks that keep entries should copy them.
 	Batch(entry ...Entry) error
 }
 
+// Committer commits the given keys on the source of a stream
+type Committer func(keys ...string) error
+
+// AckingSink is a sink that commits entries itself once they are durable instead of
+// when Single/Batch return (e.g: sinks that buffer entries across calls into bigger objects).
+// Processors don't commit entries of streams that dump into an AckingSink.
+type AckingSink interface {
+	Sink
+
+	// Bind is called once per stream before processing starts with the committer of its source,
+	// the stream dumps its entries to the returned sink.
+	Bind(commit Committer) Sink
+}
+
 // Engine is responsible for managing one or more streams
 // it allows you to start/stop groups of streams and provide
 // central error handling for your streams.
 type Engine interface {
 	// Add new stream, NOTICE that streams with the same source cannot be added.
+	// Streams added to a running engine are started right away.
 	Add(stream ...Stream) error
 
-	// Sets an error handler that will be called whenever an error is reported.
+	// AddWith adds a single stream with options, e.g: WithProcessor picks the processing strategy of the stream.
+	AddWith(stream Stream, options ...AddOption) error
+
+	// AddTopology builds the topology (see Topology.Build) and adds its streams, one per source.
+	AddTopology(topology *Topology) error
+
+	// Sets an error handler that will be called whenever an error is reported,
+	// errors of streams with their own error handler (see Stream.OnError) aren't passed to it.
+	// Errors reported by streams are wrapped with a StreamError.
 	SetErrorHandler(handler ErrorHandler)
 
 	// Will start all attached streams
@@ -130,4 +337,125 @@ type Engine interface {
 
 	// Will start all stop streams
 	Stop()
+
+	// Stops a single stream, stopped streams cannot be started again.
+	StopStream(name string) error
+
+	// Remove stops a stream and detaches it from the engine, streams can be added
+	// (see Add) and removed while the engine is running.
+	Remove(name string) error
+
+	// SetKeepAlive keeps a started engine running until Stop is called, even when it has no running stream.
+	SetKeepAlive(keepAlive bool)
+
+	// Pause holds back the entries of a running stream until Resume is called,
+	// unlike Stop the source isn't torn down.
+	Pause(name string) error
+
+	// Resume continues a paused stream.
+	Resume(name string) error
+
+	// Seek the source of a stream, it fails with an UnseekableSourceError unless the source is a SeekableSource.
+	SeekToTimestamp(name string, at time.Time) error
+	SeekToKey(name string, key string) error
+	Rewind(name string) error
+
+	// Snapshot writes the state of the operators (e.g: windows, scans, dedupe sets) and the committed position
+	// of the sources implementing Snapshotter, the running streams are paused while it is taken.
+	Snapshot(w io.Writer) error
+
+	// Restore replaces the state of the operators and the position of the sources by a snapshot
+	// (see Snapshot), it should be called before the engine starts.
+	Restore(r io.Reader) error
+
+	// Streams returns the status and throughput of all streams.
+	Streams() []StreamInfo
+
+	// Config returns a description of the engine and its streams.
+	Config() EngineConfig
+
+	// Progress returns the position and lag of the source of every stream (see ProgressReporter).
+	Progress() []StreamProgress
+
+	// Health returns the liveness and readiness of the engine and the health of its streams.
+	Health() Health
+
+	// Sets the processor factory of the streams added afterwards without their own (see WithProcessor).
+	SetProcessorFactory(factory ProcessorFactory)
+
+	// Sets the restart policy of streams without their own (see Stream.Supervise),
+	// by default failed sources aren't restarted.
+	SetRestartPolicy(policy RestartPolicy)
+
+	// Sets the scheduler sharing the processing capacity between the streams started afterwards,
+	// by default the streams don't wait for each other.
+	SetScheduler(scheduler *Scheduler)
+
+	// Sets the resource limits shared by the streams started afterwards (worker pool, in flight entries and sink requests).
+	SetLimits(limits Limits)
+
+	// Sets a handler that will be called with the supervision events (source failures and restarts).
+	OnEvent(handler EventHandler)
+
+	// Subscribe calls the handler with the engine events of the given types (all of them when
+	// no type is given) and returns a function that unsubscribes it.
+	Subscribe(handler EventHandler, types ...EventType) func()
+
+	// Hooks of the lifecycle of the streams, they subscribe the handler to a single event type.
+	OnStreamStart(handler EventHandler)
+	OnStreamStop(handler EventHandler)
+	OnStreamError(handler EventHandler)
+	OnCommit(handler EventHandler)
+	OnBatchFlush(handler EventHandler)
+}
+
+// StreamStatus is the lifecycle status of a stream managed by the engine
+type StreamStatus string
+
+const (
+	StreamIdle      StreamStatus = "idle"
+	StreamRunning   StreamStatus = "running"
+	StreamPaused    StreamStatus = "paused"
+	StreamStopped   StreamStatus = "stopped"
+	StreamCompleted StreamStatus = "completed"
+	StreamFailed    StreamStatus = "failed"
+)
+
+// StreamInfo is a snapshot of the status of a stream,
+// Throughput is the average number of entries received per second.
+type StreamInfo struct {
+	Name       string       `json:"name"`
+	Status     StreamStatus `json:"status"`
+	Received   uint64       `json:"received"`
+	Committed  uint64       `json:"committed"`
+	Throughput float64      `json:"throughput"`
+	Buffer     *BufferStats `json:"buffer,omitempty"`
+
+	// Scheduler holds the scheduling counters when the engine has a scheduler
+	Scheduler *SchedulerStats `json:"scheduler,omitempty"`
+
+	// InFlight is the number of entries taken from the source that weren't processed yet, when they are limited
+	InFlight int `json:"inFlight,omitempty"`
+}
+
+// EngineConfig describes the engine and the streams attached to it
+type EngineConfig struct {
+	Processor       string         `json:"processor"`
+	MonitorInterval string         `json:"monitorInterval"`
+	Streams         []StreamConfig `json:"streams"`
+}
+
+// StreamConfig describes the source and handlers of a stream
+type StreamConfig struct {
+	Name         string        `json:"name"`
+	Source       string        `json:"source"`
+	Handlers     []string      `json:"handlers"`
+	ErrorHandler bool          `json:"errorHandler"`
+	Buffer       *BufferConfig `json:"buffer,omitempty"`
+	Priority     int           `json:"priority,omitempty"`
+	MaxInFlight  int           `json:"maxInFlight,omitempty"`
+	Timeout      string        `json:"timeout,omitempty"`
+
+	// Processor is the type of the processor of the stream (see WithProcessor)
+	Processor string `json:"processor"`
 }
@@ -0,0 +1,151 @@
+package go_streams
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MapErrFunc is a function which transforms its input or fails
+type MapErrFunc func(entry interface{}) (interface{}, error)
+
+type asyncResult struct {
+	seq   uint64
+	entry Entry
+	err   error
+}
+
+// mapAsync runs the map function of up to concurrency entries at once, results are emitted
+// as soon as they are ready or in the order of their entries when preserveOrder is set.
+type mapAsync struct {
+	fn            MapErrFunc
+	preserveOrder bool
+	slots         chan struct{}
+	wg            *sync.WaitGroup
+	pool          *WorkerPool
+
+	mutex   *sync.Mutex
+	next    uint64
+	emitted uint64
+	ready   map[uint64]*asyncResult
+}
+
+// NewMapAsync creates an operator that maps entries concurrently, entries whose map failed are dropped
+// and their errors reported as MapErrors. Results are emitted by later entries or by the processor ticks,
+// sources that commits their offset cumulatively should preserve the order.
+func NewMapAsync(fn MapErrFunc, concurrency int, preserveOrder bool) TimedOperator {
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	return &mapAsync{
+		fn:            fn,
+		preserveOrder: preserveOrder,
+		slots:         make(chan struct{}, concurrency),
+		wg:            &sync.WaitGroup{},
+		mutex:         &sync.Mutex{},
+		ready:         make(map[uint64]*asyncResult),
+	}
+}
+
+// Apply starts mapping the entry, it waits for a free slot when concurrency entries are mapped already
+func (this *mapAsync) Apply(entry Entry) ([]Entry, error) {
+	this.slots <- struct{}{}
+
+	this.mutex.Lock()
+	seq := this.next
+	this.next++
+	this.mutex.Unlock()
+
+	this.wg.Add(1)
+	this.spawn(func() {
+		defer this.wg.Done()
+		value, err := this.run(entry)
+		entry.Value = value
+
+		this.mutex.Lock()
+		this.ready[seq] = &asyncResult{seq: seq, entry: entry, err: err}
+		this.mutex.Unlock()
+		<-this.slots
+	})
+
+	return this.collect()
+}
+
+// bindPool runs the map functions on the worker pool of the engine
+func (this *mapAsync) bindPool(pool *WorkerPool) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.pool = pool
+}
+
+func (this *mapAsync) spawn(fn func()) {
+	this.mutex.Lock()
+	pool := this.pool
+	this.mutex.Unlock()
+	if pool != nil {
+		pool.Go(fn)
+		return
+	}
+	go fn()
+}
+
+func (this *mapAsync) run(entry Entry) (value interface{}, err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			logger.Debug("Recovering from panic in async map step for entry: %+v", entry)
+			err = panicError(p)
+		}
+	}()
+	return this.fn(entry.Value)
+}
+
+func (this *mapAsync) Tick(time.Time) ([]Entry, error) {
+	return this.collect()
+}
+
+// Drain waits for the entries being mapped
+func (this *mapAsync) Drain() ([]Entry, error) {
+	this.wg.Wait()
+	return this.collect()
+}
+
+// collect returns the entries that are ready to be emitted and the errors of the failed ones
+func (this *mapAsync) collect() ([]Entry, error) {
+	this.mutex.Lock()
+	var results []*asyncResult
+	if this.preserveOrder {
+		for {
+			result, found := this.ready[this.emitted]
+			if !found {
+				break
+			}
+			delete(this.ready, this.emitted)
+			this.emitted++
+			results = append(results, result)
+		}
+	} else {
+		for seq, result := range this.ready {
+			delete(this.ready, seq)
+			results = append(results, result)
+		}
+		sort.Slice(results, func(i, j int) bool { return results[i].seq < results[j].seq })
+	}
+	this.mutex.Unlock()
+
+	var entries []Entry
+	var failures []string
+	for _, result := range results {
+		if result.err != nil {
+			failures = append(failures, fmt.Sprintf("'%s': %s", result.entry.Key, result.err.Error()))
+			continue
+		}
+		entries = append(entries, result.entry)
+	}
+
+	if len(failures) > 0 {
+		return entries, NewMapError(fmt.Errorf("async map failed for %s", strings.Join(failures, ", ")))
+	}
+	return entries, nil
+}
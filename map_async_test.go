@@ -0,0 +1,66 @@
+package go_streams
+
+import (
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMapAsync_PreservesOrder(t *testing.T) {
+	errs := make(ErrorChannel, 100)
+	source := NewSequentialIntegerSource(9, 0)
+	sink := NewArraySink()
+	var inFlight, maxInFlight int32
+
+	stream := NewStream(source).MapAsync(func(entry interface{}) (interface{}, error) {
+		current := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			max := atomic.LoadInt32(&maxInFlight)
+			if current <= max || atomic.CompareAndSwapInt32(&maxInFlight, max, current) {
+				break
+			}
+		}
+		// later entries finish first
+		time.Sleep(time.Duration(10-entry.(int)) * time.Millisecond)
+		return entry.(int) * 2, nil
+	}, 4, true).Sink(sink)
+
+	started := time.Now()
+	stream.Process(NewDirectProcessor(), errs)
+
+	assert.EqualValues(t, []interface{}{0, 2, 4, 6, 8, 10, 12, 14, 16, 18}, sink.Array())
+	assert.EqualValues(t, 4, atomic.LoadInt32(&maxInFlight))
+	assert.True(t, time.Since(started) < 50*time.Millisecond)
+}
+
+func TestMapAsync_DropsFailedEntries(t *testing.T) {
+	errs := make(ErrorChannel, 100)
+	source := NewSequentialIntegerSource(5, 0)
+	sink := NewArraySink()
+
+	stream := NewStream(source).MapAsync(func(entry interface{}) (interface{}, error) {
+		if entry.(int) == 3 {
+			return nil, fmt.Errorf("unavailable")
+		}
+		return entry, nil
+	}, 2, false).Sink(sink)
+	stream.Process(NewBufferedProcessor(10, time.Second), errs)
+
+	assert.ElementsMatch(t, []interface{}{0, 1, 2, 4, 5}, sink.Array())
+	// the EOF of the source may be reported before the map error
+	var mapErr *MapError
+	timeout := time.After(time.Second)
+	for mapErr == nil {
+		select {
+		case err := <-errs:
+			errors.As(err, &mapErr)
+		case <-timeout:
+			t.Fatal("the map error wasn't reported")
+		}
+	}
+	assert.Contains(t, mapErr.Error(), "unavailable")
+}
@@ -0,0 +1,258 @@
+package go_streams
+
+import "time"
+
+// SinkMiddleware decorates the writes of a sink (e.g: to log, retry or rate limit them), see WrapSink
+type SinkMiddleware func(next Sink) Sink
+
+// SourceMiddleware decorates a source (e.g: to transform or rate limit its entries), see WrapSource
+type SourceMiddleware func(next Source) Source
+
+// WrapSink decorates the sink with the middlewares, the first middleware is the outermost one (it sees the writes first).
+// Ping, Flush, Close and the event bindings go to the sink itself. An AckingSink stays one: the sink it binds
+// for a stream is decorated by the middlewares again.
+func WrapSink(sink Sink, middlewares ...SinkMiddleware) Sink {
+	wrapped := &wrappedSink{sink: sink, decorated: decorateSink(sink, middlewares)}
+	if _, ok := sink.(AckingSink); ok {
+		return &wrappedAckingSink{wrappedSink: wrapped, middlewares: middlewares}
+	}
+	return wrapped
+}
+
+func decorateSink(sink Sink, middlewares []SinkMiddleware) Sink {
+	for idx := len(middlewares) - 1; idx >= 0; idx-- {
+		sink = middlewares[idx](sink)
+	}
+	return sink
+}
+
+type wrappedSink struct {
+	sink      Sink
+	decorated Sink
+}
+
+func (this *wrappedSink) Single(entry Entry) error {
+	return this.decorated.Single(entry)
+}
+
+func (this *wrappedSink) Batch(entry ...Entry) error {
+	return this.decorated.Batch(entry...)
+}
+
+func (this *wrappedSink) Ping() error {
+	return this.sink.Ping()
+}
+
+// Flush flushes the sink when it implements Flusher
+func (this *wrappedSink) Flush() error {
+	if flusher, ok := this.sink.(Flusher); ok {
+		return flusher.Flush()
+	}
+	return nil
+}
+
+// Close closes the sink when it implements Closer
+func (this *wrappedSink) Close() error {
+	if closer, ok := this.sink.(Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+func (this *wrappedSink) bindEvents(stream Stream) {
+	if binder, ok := this.sink.(eventBinder); ok {
+		binder.bindEvents(stream)
+	}
+}
+
+type wrappedAckingSink struct {
+	*wrappedSink
+	middlewares []SinkMiddleware
+}
+
+func (this *wrappedAckingSink) Bind(commit Committer) Sink {
+	return WrapSink(this.sink.(AckingSink).Bind(commit), this.middlewares...)
+}
+
+// sinkFuncs is a sink decorated by a middleware, the writes it doesn't decorate go to the next sink
+type sinkFuncs struct {
+	next   Sink
+	single func(entry Entry) error
+	batch  func(entry ...Entry) error
+}
+
+func (this *sinkFuncs) Single(entry Entry) error {
+	if this.single == nil {
+		return this.next.Single(entry)
+	}
+	return this.single(entry)
+}
+
+func (this *sinkFuncs) Batch(entry ...Entry) error {
+	if this.batch == nil {
+		return this.next.Batch(entry...)
+	}
+	return this.batch(entry...)
+}
+
+func (this *sinkFuncs) Ping() error {
+	return this.next.Ping()
+}
+
+// WrapSource decorates the source with the middlewares, the first middleware is the outermost one (it sees the
+// entries last and the calls of the engine first). The name, the pauses and the seeks go to the source itself.
+func WrapSource(source Source, middlewares ...SourceMiddleware) Source {
+	decorated := source
+	for idx := len(middlewares) - 1; idx >= 0; idx-- {
+		decorated = middlewares[idx](decorated)
+	}
+	wrapped := &wrappedSource{source: source, decorated: decorated}
+	if _, ok := source.(SeekableSource); ok {
+		return &wrappedSeekableSource{wrappedSource: wrapped}
+	}
+	return wrapped
+}
+
+// unwrapSource returns the source decorated by WrapSource, other sources are returned as is
+func unwrapSource(source Source) Source {
+	switch s := source.(type) {
+	case *wrappedSource:
+		return s.source
+	case *wrappedSeekableSource:
+		return s.source
+	}
+	return source
+}
+
+type wrappedSource struct {
+	source    Source
+	decorated Source
+}
+
+func (this *wrappedSource) Start(channel EntryChannel, errorChannel ErrorChannel) {
+	this.decorated.Start(channel, errorChannel)
+}
+
+func (this *wrappedSource) Stop() error {
+	return this.decorated.Stop()
+}
+
+func (this *wrappedSource) CommitEntry(keys ...string) error {
+	return this.decorated.CommitEntry(keys...)
+}
+
+func (this *wrappedSource) Ping() error {
+	return this.decorated.Ping()
+}
+
+func (this *wrappedSource) Name() string {
+	return this.source.Name()
+}
+
+// Pause pauses the source when it implements Pausable
+func (this *wrappedSource) Pause() error {
+	if source, ok := this.source.(Pausable); ok {
+		return source.Pause()
+	}
+	return nil
+}
+
+// Resume resumes the source when it implements Pausable
+func (this *wrappedSource) Resume() error {
+	if source, ok := this.source.(Pausable); ok {
+		return source.Resume()
+	}
+	return nil
+}
+
+type wrappedSeekableSource struct {
+	*wrappedSource
+}
+
+func (this *wrappedSeekableSource) SeekToTimestamp(at time.Time) error {
+	return this.source.(SeekableSource).SeekToTimestamp(at)
+}
+
+func (this *wrappedSeekableSource) SeekToKey(key string) error {
+	return this.source.(SeekableSource).SeekToKey(key)
+}
+
+func (this *wrappedSeekableSource) Rewind() error {
+	return this.source.(SeekableSource).Rewind()
+}
+
+// interceptedSource is a source decorated by a middleware, its entries go through the intercept function that
+// returns the entries to emit instead (the entry is dropped and the error reported when it fails) and the calls
+// it doesn't decorate go to the next source
+type interceptedSource struct {
+	next      Source
+	intercept func(entry Entry) ([]Entry, error)
+	stop      func() error
+	commit    func(keys ...string) error
+}
+
+func (this *interceptedSource) Start(channel EntryChannel, errorChannel ErrorChannel) {
+	if this.intercept == nil {
+		this.next.Start(channel, errorChannel)
+		return
+	}
+
+	inner := make(EntryChannel)
+	returned := make(chan struct{})
+	forwarded := make(chan struct{})
+	go func() {
+		defer close(forwarded)
+		for {
+			select {
+			case entry, ok := <-inner:
+				if !ok {
+					close(channel)
+					return
+				}
+				entries, err := this.intercept(entry)
+				if err != nil {
+					errorChannel <- err
+					continue
+				}
+				for idx := range entries {
+					channel <- entries[idx]
+				}
+			case <-returned:
+				// a source that failed returns without closing its channel (see FatalError)
+				select {
+				case _, ok := <-inner:
+					if !ok {
+						close(channel)
+					}
+				default:
+				}
+				return
+			}
+		}
+	}()
+	this.next.Start(inner, errorChannel)
+	close(returned)
+	<-forwarded
+}
+
+func (this *interceptedSource) Stop() error {
+	if this.stop == nil {
+		return this.next.Stop()
+	}
+	return this.stop()
+}
+
+func (this *interceptedSource) CommitEntry(keys ...string) error {
+	if this.commit == nil {
+		return this.next.CommitEntry(keys...)
+	}
+	return this.commit(keys...)
+}
+
+func (this *interceptedSource) Ping() error {
+	return this.next.Ping()
+}
+
+func (this *interceptedSource) Name() string {
+	return this.next.Name()
+}
@@ -0,0 +1,128 @@
+package go_streams
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func double(entry interface{}) interface{} {
+	return entry.(int) * 2
+}
+
+func TestWrapSink_AppliesMiddlewaresInOrder(t *testing.T) {
+	sink := NewArraySink()
+	outer, inner := NewMetrics(), NewMetrics()
+	wrapped := WrapSink(sink, SinkMetrics(outer), SinkTransform(func(entry interface{}) interface{} {
+		if entry.(int) == 3 {
+			panic("demo")
+		}
+		return double(entry)
+	}), SinkMetrics(inner))
+
+	errs := make(ErrorChannel, 100)
+	NewStream(NewSequentialIntegerSource(4, 0)).Sink(wrapped).Process(NewDirectProcessor(), errs)
+
+	assert.EqualValues(t, []interface{}{0, 2, 4, 8}, sink.Array())
+	assert.EqualValues(t, 5, outer.Snapshot().Entries)
+	assert.EqualValues(t, 4, inner.Snapshot().Entries)
+	assert.EqualValues(t, 4, inner.Snapshot().Calls)
+	assert.EqualValues(t, 1, outer.Snapshot().Failures)
+
+	var pe *PanicError
+	assert.True(t, errors.As(<-errs, &pe))
+}
+
+func TestWrapSink_KeepsTheSinkLifecycle(t *testing.T) {
+	sink := newLifecycleSink()
+	wrapped := WrapSink(sink, SinkLogging())
+	assert.Nil(t, wrapped.Batch(Entry{Key: "1"}))
+	assert.Nil(t, wrapped.(Flusher).Flush())
+	assert.Nil(t, wrapped.(Closer).Close())
+	assert.Len(t, sink.flushed, 1)
+	assert.EqualValues(t, 1, sink.closed)
+
+	_, acking := wrapped.(AckingSink)
+	assert.False(t, acking)
+}
+
+func TestWrapSink_DecoratesBoundAckingSinks(t *testing.T) {
+	source := NewSequentialIntegerSource(2, 0).(*sequentialIntegerSource)
+	sink := &ackingArraySink{ArraySink: NewArraySink()}
+	metrics := NewMetrics()
+
+	NewStream(source).Sink(WrapSink(sink, SinkMetrics(metrics), SinkTransform(double))).
+		Process(NewDirectProcessor(), make(ErrorChannel, 10))
+
+	assert.EqualValues(t, []interface{}{0, 2, 4}, sink.Array())
+	assert.EqualValues(t, 3, metrics.Snapshot().Entries)
+	assert.Empty(t, source.latestCommit)
+	assert.NotNil(t, sink.commit)
+}
+
+func TestSinkRetry_RetriesFailedEntries(t *testing.T) {
+	var batches [][]string
+	failures := map[string]int{"a": 1, "b": 2}
+	sink := WrapSink(NewCallbackSink(func(entries ...Entry) error {
+		keys := make([]string, len(entries))
+		errs := NewSinkBatchError()
+		for idx := range entries {
+			keys[idx] = entries[idx].Key
+			if failures[entries[idx].Key] > 0 {
+				failures[entries[idx].Key]--
+				errs.Add(entries[idx].Key, fmt.Errorf("failed"))
+			}
+		}
+		batches = append(batches, keys)
+		return errs.AsError()
+	}), SinkRetry(3, time.Millisecond))
+
+	assert.Nil(t, sink.Batch(Entry{Key: "a"}, Entry{Key: "b"}, Entry{Key: "c"}))
+	assert.EqualValues(t, [][]string{{"a", "b", "c"}, {"a", "b"}, {"b"}}, batches)
+
+	failures["c"] = 5
+	err := sink.Single(Entry{Key: "c"})
+	assert.IsType(t, &SinkBatchError{}, err)
+	assert.EqualValues(t, 1, failures["c"])
+}
+
+func TestWrapSource_TransformsAndCountsEntries(t *testing.T) {
+	inner := NewSequentialIntegerSource(4, 0)
+	metrics := NewMetrics()
+	source := WrapSource(inner, SourceMetrics(metrics), SourceTransform(func(entry interface{}) interface{} {
+		if entry.(int) == 1 {
+			panic("demo")
+		}
+		return double(entry)
+	}), SourceLogging())
+	assert.EqualValues(t, inner.Name(), source.Name())
+	_, seekable := source.(SeekableSource)
+	assert.False(t, seekable)
+
+	sink := NewArraySink()
+	errs := make(ErrorChannel, 100)
+	NewStream(source).Sink(sink).Process(NewDirectProcessor(), errs)
+
+	assert.EqualValues(t, []interface{}{0, 4, 6, 8}, sink.Array())
+	snapshot := metrics.Snapshot()
+	assert.EqualValues(t, 4, snapshot.Entries)
+	assert.EqualValues(t, 4, snapshot.Calls)
+	assert.EqualValues(t, 0, snapshot.Failures)
+
+	var mapErr *MapError
+	assert.True(t, errors.As(<-errs, &mapErr))
+}
+
+func TestSourceRateLimit(t *testing.T) {
+	source := WrapSource(NewSequentialIntegerSource(5, 0), SourceRateLimit(3, 100*time.Millisecond))
+	sink := NewArraySink()
+	started := time.Now()
+	NewStream(source).Sink(sink).Process(NewDirectProcessor(), make(ErrorChannel, 100))
+
+	// a burst of 3 entries then 1 entry every 33ms
+	assert.Len(t, sink.Array(), 6)
+	assert.True(t, time.Since(started) >= 90*time.Millisecond)
+}
@@ -0,0 +1,262 @@
+package go_streams
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Metrics counts the calls that went through the metrics middlewares (see SinkMetrics and SourceMetrics),
+// it can be shared by several middlewares.
+type Metrics struct {
+	entries  uint64
+	calls    uint64
+	failures uint64
+	latency  int64
+}
+
+// MetricsSnapshot is a snapshot of the counters of a Metrics
+type MetricsSnapshot struct {
+	// Entries is the number of entries written by the sinks or emitted by the sources
+	Entries uint64 `json:"entries"`
+
+	// Calls is the number of writes of the sinks or commits of the sources, Failures is the number of them that failed
+	Calls    uint64 `json:"calls"`
+	Failures uint64 `json:"failures"`
+
+	// Latency is the average duration of the calls
+	Latency time.Duration `json:"latency"`
+}
+
+func NewMetrics() *Metrics {
+	return &Metrics{}
+}
+
+// Snapshot returns the current counters
+func (this *Metrics) Snapshot() MetricsSnapshot {
+	snapshot := MetricsSnapshot{
+		Entries:  atomic.LoadUint64(&this.entries),
+		Calls:    atomic.LoadUint64(&this.calls),
+		Failures: atomic.LoadUint64(&this.failures),
+	}
+	if snapshot.Calls > 0 {
+		snapshot.Latency = time.Duration(atomic.LoadInt64(&this.latency) / int64(snapshot.Calls))
+	}
+	return snapshot
+}
+
+// observe runs and counts a call, calls that panicked count as failures
+func (this *Metrics) observe(entries int, call func() error) error {
+	started := time.Now()
+	atomic.AddUint64(&this.entries, uint64(entries))
+	failed := true
+	defer func() {
+		atomic.AddUint64(&this.calls, 1)
+		atomic.AddInt64(&this.latency, int64(time.Since(started)))
+		if failed {
+			atomic.AddUint64(&this.failures, 1)
+		}
+	}()
+	err := call()
+	failed = err != nil
+	return err
+}
+
+// SinkLogging logs the writes of the sink at debug level and its failures at warn level
+func SinkLogging() SinkMiddleware {
+	return func(next Sink) Sink {
+		logger := LogWith(Fields{FieldStage: SinkStage, "sink": typeName(next)})
+		logged := func(entries int, write func() error) error {
+			started := time.Now()
+			err := write()
+			if err != nil {
+				logger.Warn("Failed to write %d entries: %s", entries, err.Error())
+			} else {
+				logger.Debug("Wrote %d entries in %s", entries, time.Since(started))
+			}
+			return err
+		}
+		return &sinkFuncs{
+			next: next,
+			single: func(entry Entry) error {
+				return logged(1, func() error { return next.Single(entry) })
+			},
+			batch: func(entry ...Entry) error {
+				return logged(len(entry), func() error { return next.Batch(entry...) })
+			},
+		}
+	}
+}
+
+// SinkMetrics counts the writes of the sink and their entries, failed writes count their entries too
+func SinkMetrics(metrics *Metrics) SinkMiddleware {
+	return func(next Sink) Sink {
+		return &sinkFuncs{
+			next: next,
+			single: func(entry Entry) error {
+				return metrics.observe(1, func() error { return next.Single(entry) })
+			},
+			batch: func(entry ...Entry) error {
+				return metrics.observe(len(entry), func() error { return next.Batch(entry...) })
+			},
+		}
+	}
+}
+
+// SinkRetry retries the failed writes of the sink up to the given number of times, the backoff between the
+// attempts doubles after each of them. When a batch fails with a SinkBatchError only its failed entries are
+// written again. The sink should be idempotent since a failed write may have written some entries.
+func SinkRetry(retries int, backoff time.Duration) SinkMiddleware {
+	retry := func(write func() error) error {
+		delay := backoff
+		err := write()
+		for attempt := 0; err != nil && attempt < retries; attempt++ {
+			time.Sleep(delay)
+			delay *= 2
+			err = write()
+		}
+		return err
+	}
+	return func(next Sink) Sink {
+		return &sinkFuncs{
+			next: next,
+			single: func(entry Entry) error {
+				return retry(func() error { return next.Single(entry) })
+			},
+			batch: func(entry ...Entry) error {
+				pending := entry
+				return retry(func() error {
+					err := next.Batch(pending...)
+					if batchErr, ok := err.(*SinkBatchError); ok {
+						pending = failedEntries(pending, batchErr)
+					}
+					return err
+				})
+			},
+		}
+	}
+}
+
+// failedEntries returns the entries whose key failed in the batch error
+func failedEntries(entries []Entry, err *SinkBatchError) []Entry {
+	failed := make([]Entry, 0, len(err.Errors))
+	for idx := range entries {
+		if _, found := err.Errors[entries[idx].Key]; found {
+			failed = append(failed, entries[idx])
+		}
+	}
+	return failed
+}
+
+// SinkRateLimit lets the sink write at most n entries per interval (with bursts up to n), the writes wait
+// for the entries to be allowed. The limit is shared by all the sinks wrapped by the middleware.
+func SinkRateLimit(n int, per time.Duration) SinkMiddleware {
+	limiter := NewThrottle(n, per, nil)
+	return func(next Sink) Sink {
+		return &sinkFuncs{
+			next: next,
+			single: func(entry Entry) error {
+				_, _ = limiter.Apply(entry)
+				return next.Single(entry)
+			},
+			batch: func(entry ...Entry) error {
+				for idx := range entry {
+					_, _ = limiter.Apply(entry[idx])
+				}
+				return next.Batch(entry...)
+			},
+		}
+	}
+}
+
+// SinkTransform maps the values of the entries before they are written (e.g: to encode them for this sink only),
+// the entries of the stream aren't modified. Panics are recovered by the processor like panics of the sink.
+func SinkTransform(fn MapFunc) SinkMiddleware {
+	return func(next Sink) Sink {
+		return &sinkFuncs{
+			next: next,
+			single: func(entry Entry) error {
+				entry.Value = fn(entry.Value)
+				return next.Single(entry)
+			},
+			batch: func(entry ...Entry) error {
+				mapped := make([]Entry, len(entry))
+				for idx := range entry {
+					mapped[idx] = entry[idx]
+					mapped[idx].Value = fn(entry[idx].Value)
+				}
+				return next.Batch(mapped...)
+			},
+		}
+	}
+}
+
+// SourceLogging logs the stops of the source and its commits at debug level and the failed commits at warn level
+func SourceLogging() SourceMiddleware {
+	return func(next Source) Source {
+		logger := LogWith(Fields{FieldStream: next.Name(), FieldStage: SourceStage})
+		return &interceptedSource{
+			next: next,
+			stop: func() error {
+				logger.Debug("Stopping source")
+				return next.Stop()
+			},
+			commit: func(keys ...string) error {
+				err := next.CommitEntry(keys...)
+				if err != nil {
+					logger.Warn("Failed to commit %d keys: %s", len(keys), err.Error())
+				} else {
+					logger.Debug("Committed %d keys", len(keys))
+				}
+				return err
+			},
+		}
+	}
+}
+
+// SourceMetrics counts the entries emitted by the source and its commits
+func SourceMetrics(metrics *Metrics) SourceMiddleware {
+	return func(next Source) Source {
+		return &interceptedSource{
+			next: next,
+			intercept: func(entry Entry) ([]Entry, error) {
+				atomic.AddUint64(&metrics.entries, 1)
+				return []Entry{entry}, nil
+			},
+			commit: func(keys ...string) error {
+				return metrics.observe(0, func() error { return next.CommitEntry(keys...) })
+			},
+		}
+	}
+}
+
+// SourceRateLimit lets the source emit at most n entries per interval (with bursts up to n), the source is
+// backpressured meanwhile. The limit is shared by all the sources wrapped by the middleware.
+func SourceRateLimit(n int, per time.Duration) SourceMiddleware {
+	limiter := NewThrottle(n, per, nil)
+	return func(next Source) Source {
+		return &interceptedSource{
+			next: next,
+			intercept: func(entry Entry) ([]Entry, error) {
+				return limiter.Apply(entry)
+			},
+		}
+	}
+}
+
+// SourceTransform maps the values of the entries emitted by the source (e.g: to decode them),
+// the entries whose map panicked are dropped and the panic is reported as a MapError.
+func SourceTransform(fn MapFunc) SourceMiddleware {
+	return func(next Source) Source {
+		return &interceptedSource{
+			next: next,
+			intercept: func(entry Entry) ([]Entry, error) {
+				value, err := recoverMap(fn, entry)
+				if err != nil {
+					return nil, err
+				}
+				entry.Value = value
+				return []Entry{entry}, nil
+			},
+		}
+	}
+}
@@ -0,0 +1,41 @@
+// Package mqtt holds an MQTT (v3.1.1 and v5) source and sink, they work with any client
+// library through the Subscriber and Publisher interfaces (e.g: adapters of eclipse/paho).
+package mqtt
+
+import streams "github.com/matang28/go-streams"
+
+// Message is an MQTT publish packet
+type Message struct {
+	Topic    string
+	Payload  []byte
+	QoS      byte
+	Retained bool
+
+	// ID is the packet identifier of QoS 1 messages
+	ID uint16
+
+	// Properties are the user properties of MQTT v5 messages (nil with v3.1.1)
+	Properties map[string]string
+}
+
+// Subscriber abstracts a connected client that receives messages, it should acknowledge
+// QoS 1 messages only when Ack is called (i.e: manual acknowledgements).
+type Subscriber interface {
+	streams.Pingable
+
+	// Subscribe subscribes to the topic filters, the messages are passed to the handler until Unsubscribe is called
+	Subscribe(filters []string, qos byte, handler func(message *Message)) error
+
+	Unsubscribe(filters ...string) error
+
+	// Ack acknowledges a QoS 1 message (PUBACK)
+	Ack(message *Message) error
+}
+
+// Publisher abstracts a connected client that publishes messages
+type Publisher interface {
+	streams.Pingable
+
+	// Publish blocks until the message was sent (and acknowledged by the broker for QoS 1 and 2)
+	Publish(message *Message) error
+}
@@ -0,0 +1,97 @@
+package mqtt
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/template"
+
+	streams "github.com/matang28/go-streams"
+)
+
+// SinkConfig configures a Sink
+type SinkConfig struct {
+	// Topic is a text/template evaluated per entry (e.g: `devices/{{.Metadata.device}}/commands`)
+	Topic string
+
+	QoS      byte
+	Retained bool
+
+	// Encode serializes an entry, by default []byte and string values are sent as they are and other values are JSON encoded
+	Encode func(entry streams.Entry) ([]byte, error)
+
+	// Properties returns the user properties of an entry (MQTT v5 only)
+	Properties func(entry streams.Entry) map[string]string
+}
+
+// Sink publishes every entry as a message
+type Sink struct {
+	config SinkConfig
+	topic  *template.Template
+	client Publisher
+}
+
+func NewSink(client Publisher, config SinkConfig) (*Sink, error) {
+	if config.Topic == "" {
+		return nil, fmt.Errorf("mqtt sink requires a topic")
+	}
+	if config.QoS > 2 {
+		return nil, fmt.Errorf("invalid QoS: %d", config.QoS)
+	}
+	topic, err := template.New("topic").Option("missingkey=zero").Parse(config.Topic)
+	if err != nil {
+		return nil, err
+	}
+	if config.Encode == nil {
+		config.Encode = encode
+	}
+	return &Sink{config: config, topic: topic, client: client}, nil
+}
+
+func (this *Sink) Single(entry streams.Entry) error {
+	message, err := this.message(entry)
+	if err != nil {
+		return err
+	}
+	return this.client.Publish(message)
+}
+
+func (this *Sink) Batch(entry ...streams.Entry) error {
+	errs := streams.NewSinkBatchError()
+	for idx := range entry {
+		errs.Add(entry[idx].Key, this.Single(entry[idx]))
+	}
+	return errs.AsError()
+}
+
+func (this *Sink) Ping() error {
+	return this.client.Ping()
+}
+
+func (this *Sink) message(entry streams.Entry) (*Message, error) {
+	var topic strings.Builder
+	if err := this.topic.Execute(&topic, entry); err != nil {
+		return nil, err
+	}
+	payload, err := this.config.Encode(entry)
+	if err != nil {
+		return nil, err
+	}
+
+	message := &Message{Topic: topic.String(), Payload: payload, QoS: this.config.QoS, Retained: this.config.Retained}
+	if this.config.Properties != nil {
+		message.Properties = this.config.Properties(entry)
+	}
+	return message, nil
+}
+
+func encode(entry streams.Entry) ([]byte, error) {
+	switch value := entry.Value.(type) {
+	case []byte:
+		return value, nil
+	case string:
+		return []byte(value), nil
+	default:
+		return json.Marshal(value)
+	}
+}
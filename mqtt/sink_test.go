@@ -0,0 +1,27 @@
+package mqtt
+
+import (
+	"testing"
+
+	streams "github.com/matang28/go-streams"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSink_PublishesWithTopicTemplates(t *testing.T) {
+	client := newFakeClient()
+	sink, err := NewSink(client, SinkConfig{
+		Topic:      "devices/{{.Metadata.device}}/commands",
+		QoS:        1,
+		Properties: func(entry streams.Entry) map[string]string { return map[string]string{"key": entry.Key} },
+	})
+	assert.Nil(t, err)
+
+	assert.Nil(t, sink.Batch(
+		streams.Entry{Key: "1", Value: "reboot", Metadata: map[string]string{"device": "a"}},
+		streams.Entry{Key: "2", Value: map[string]int{"level": 3}, Metadata: map[string]string{"device": "b"}},
+	))
+	assert.EqualValues(t, []*Message{
+		{Topic: "devices/a/commands", Payload: []byte("reboot"), QoS: 1, Properties: map[string]string{"key": "1"}},
+		{Topic: "devices/b/commands", Payload: []byte(`{"level":3}`), QoS: 1, Properties: map[string]string{"key": "2"}},
+	}, client.published)
+}
@@ -0,0 +1,188 @@
+package mqtt
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+
+	streams "github.com/matang28/go-streams"
+)
+
+// Metadata keys attached to each entry emitted by the Source
+const (
+	MetadataTopic    = "topic"
+	MetadataQoS      = "qos"
+	MetadataRetained = "retained"
+
+	// MetadataProperty prefixes the user properties of MQTT v5 messages
+	MetadataProperty = "property."
+)
+
+const defaultSourceBuffer = 100
+
+// SourceConfig configures a Source
+type SourceConfig struct {
+	Name string
+
+	// Filters are the topic filters subscribed to (e.g: `devices/+/telemetry`)
+	Filters []string
+
+	// QoS is the subscription QoS, 0 or 1 (QoS 1 messages are acknowledged once their entries are committed)
+	QoS byte
+
+	// SharedGroup subscribes to the filters as a shared subscription (`$share/{group}/{filter}`)
+	// so the messages are load balanced between the sources of the group.
+	SharedGroup string
+
+	// Buffer is the number of messages received ahead of the stream (defaults to 100)
+	Buffer int
+}
+
+type pendingAck struct {
+	seq     uint64
+	message *Message
+}
+
+// Source emits the messages of its subscriptions as entries whose value is the payload,
+// committing an entry acknowledges its message and all the messages received before it.
+type Source struct {
+	config   SourceConfig
+	client   Subscriber
+	filters  []string
+	messages chan *Message
+
+	mutex   *sync.Mutex
+	seq     uint64
+	pending []pendingAck
+
+	closeCh chan bool
+	once    *sync.Once
+}
+
+func NewSource(client Subscriber, config SourceConfig) (*Source, error) {
+	if len(config.Filters) == 0 {
+		return nil, fmt.Errorf("mqtt source requires topic filters")
+	}
+	if config.QoS > 1 {
+		return nil, fmt.Errorf("mqtt source supports QoS 0 and 1, got: %d", config.QoS)
+	}
+	if config.Buffer <= 0 {
+		config.Buffer = defaultSourceBuffer
+	}
+	if config.Name == "" {
+		config.Name = fmt.Sprintf("mqtt-%v", config.Filters)
+	}
+
+	filters := make([]string, len(config.Filters))
+	for idx, filter := range config.Filters {
+		filters[idx] = filter
+		if config.SharedGroup != "" {
+			filters[idx] = fmt.Sprintf("$share/%s/%s", config.SharedGroup, filter)
+		}
+	}
+	return &Source{
+		config:   config,
+		client:   client,
+		filters:  filters,
+		messages: make(chan *Message, config.Buffer),
+		mutex:    &sync.Mutex{},
+		closeCh:  make(chan bool),
+		once:     &sync.Once{},
+	}, nil
+}
+
+func (this *Source) Start(channel streams.EntryChannel, errorChannel streams.ErrorChannel) {
+	log := streams.LogWith(streams.Fields{streams.FieldStream: this.config.Name})
+	log.Info("Starting mqtt source, subscribing to: %v", this.filters)
+	if err := this.client.Subscribe(this.filters, this.config.QoS, this.receive); err != nil {
+		errorChannel <- streams.NewFatalError(err)
+		return
+	}
+
+Loop:
+	for {
+		select {
+		case <-this.closeCh:
+			close(channel)
+			break Loop
+		case message := <-this.messages:
+			channel <- this.newEntry(message)
+		}
+	}
+	errorChannel <- streams.NewEofError(this)
+	log.Info("Mqtt source stopped")
+}
+
+func (this *Source) receive(message *Message) {
+	select {
+	case this.messages <- message:
+	case <-this.closeCh:
+	}
+}
+
+func (this *Source) newEntry(message *Message) streams.Entry {
+	this.mutex.Lock()
+	this.seq++
+	seq := this.seq
+	if message.QoS > 0 {
+		this.pending = append(this.pending, pendingAck{seq: seq, message: message})
+	}
+	this.mutex.Unlock()
+
+	metadata := make(map[string]string, 3+len(message.Properties))
+	metadata[MetadataTopic] = message.Topic
+	metadata[MetadataQoS] = strconv.Itoa(int(message.QoS))
+	metadata[MetadataRetained] = strconv.FormatBool(message.Retained)
+	for k, v := range message.Properties {
+		metadata[MetadataProperty+k] = v
+	}
+	return streams.Entry{Key: strconv.FormatUint(seq, 10), Value: message.Payload, Metadata: metadata}
+}
+
+func (this *Source) Stop() error {
+	var err error
+	this.once.Do(func() {
+		streams.LogWith(streams.Fields{streams.FieldStream: this.config.Name}).Info("Stopping mqtt source")
+		close(this.closeCh)
+		err = this.client.Unsubscribe(this.filters...)
+	})
+	return err
+}
+
+func (this *Source) Ping() error {
+	return this.client.Ping()
+}
+
+// CommitEntry acknowledges the QoS 1 messages up to the latest of the keys
+func (this *Source) CommitEntry(keys ...string) error {
+	var latest uint64
+	for _, key := range keys {
+		seq, err := strconv.ParseUint(key, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid mqtt entry key: '%s'", key)
+		}
+		if seq > latest {
+			latest = seq
+		}
+	}
+
+	this.mutex.Lock()
+	done := 0
+	for done < len(this.pending) && this.pending[done].seq <= latest {
+		done++
+	}
+	acks := this.pending[:done]
+	this.pending = this.pending[done:]
+	this.mutex.Unlock()
+
+	for _, ack := range acks {
+		if err := this.client.Ack(ack.message); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (this *Source) Name() string {
+	return this.config.Name
+}
@@ -0,0 +1,105 @@
+package mqtt
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	streams "github.com/matang28/go-streams"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeClient delivers the published messages to its subscription
+type fakeClient struct {
+	mutex     *sync.Mutex
+	filters   []string
+	handler   func(message *Message)
+	acked     []uint16
+	published []*Message
+}
+
+func newFakeClient() *fakeClient {
+	return &fakeClient{mutex: &sync.Mutex{}}
+}
+
+func (this *fakeClient) Ping() error {
+	return nil
+}
+
+func (this *fakeClient) Subscribe(filters []string, qos byte, handler func(message *Message)) error {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.filters, this.handler = filters, handler
+	return nil
+}
+
+func (this *fakeClient) Unsubscribe(filters ...string) error {
+	return nil
+}
+
+func (this *fakeClient) Ack(message *Message) error {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.acked = append(this.acked, message.ID)
+	return nil
+}
+
+func (this *fakeClient) Publish(message *Message) error {
+	this.mutex.Lock()
+	this.published = append(this.published, message)
+	handler := this.handler
+	this.mutex.Unlock()
+	if handler != nil {
+		handler(message)
+	}
+	return nil
+}
+
+func (this *fakeClient) subscribed() bool {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	return this.handler != nil
+}
+
+func (this *fakeClient) ackedIds() []uint16 {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	return append([]uint16{}, this.acked...)
+}
+
+func TestSource_AcksCommittedMessages(t *testing.T) {
+	client := newFakeClient()
+	source, err := NewSource(client, SourceConfig{Name: "telemetry", Filters: []string{"devices/+/telemetry"}, QoS: 1, SharedGroup: "ingest"})
+	assert.Nil(t, err)
+
+	channel := make(streams.EntryChannel, 10)
+	errs := make(streams.ErrorChannel, 10)
+	go source.Start(channel, errs)
+	assert.Eventually(t, client.subscribed, time.Second, time.Millisecond)
+	assert.EqualValues(t, []string{"$share/ingest/devices/+/telemetry"}, client.filters)
+
+	for id := uint16(1); id <= 3; id++ {
+		assert.Nil(t, client.Publish(&Message{Topic: "devices/a/telemetry", Payload: []byte{byte(id)}, QoS: 1, ID: id, Properties: map[string]string{"unit": "c"}}))
+	}
+	first, second := <-channel, <-channel
+	assert.EqualValues(t, []byte{1}, first.Value)
+	assert.EqualValues(t, map[string]string{MetadataTopic: "devices/a/telemetry", MetadataQoS: "1", MetadataRetained: "false", "property.unit": "c"}, first.Metadata)
+
+	// commits are cumulative
+	assert.Nil(t, source.CommitEntry(second.Key))
+	assert.EqualValues(t, []uint16{1, 2}, client.ackedIds())
+	assert.Nil(t, source.CommitEntry((<-channel).Key))
+	assert.EqualValues(t, []uint16{1, 2, 3}, client.ackedIds())
+
+	assert.Nil(t, source.Stop())
+	_, open := <-channel
+	assert.False(t, open)
+	assert.IsType(t, &streams.EofError{}, <-errs)
+}
+
+func TestNewSource_Validation(t *testing.T) {
+	_, err := NewSource(newFakeClient(), SourceConfig{})
+	assert.NotNil(t, err)
+	_, err = NewSource(newFakeClient(), SourceConfig{Filters: []string{"a"}, QoS: 2})
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,44 @@
+package go_streams
+
+import "time"
+
+// Operator is a stage of a stream that can drop, hold back or emit several entries,
+// Apply returns the entries passed to the next stages of the stream.
+type Operator interface {
+	Apply(entry Entry) ([]Entry, error)
+}
+
+// TimedOperator are operators that emit entries on their own, e.g: after a delay.
+// Tick is called periodically by the processors and Drain is called once the source ended,
+// the entries they return are passed to the next stages but their keys aren't committed
+// (sources are expected to commit them with the entries that came after).
+type TimedOperator interface {
+	Operator
+	Tick(now time.Time) ([]Entry, error)
+	Drain() ([]Entry, error)
+}
+
+// KeyFunc returns the key of an entry value, used by operators that keep a state per key
+type KeyFunc func(value interface{}) string
+
+// tickInterval is the interval between the Tick calls of timed operators
+var tickInterval = 10 * time.Millisecond
+
+// hasTimedOperators returns true if one of the handlers is a TimedOperator
+func hasTimedOperators(handlers []interface{}) bool {
+	for _, handler := range handlers {
+		if _, ok := handler.(TimedOperator); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// tickChannel returns a ticker channel when the handlers have timed operators, nil otherwise
+func tickChannel(handlers []interface{}) (<-chan time.Time, func()) {
+	if !hasTimedOperators(handlers) {
+		return nil, func() {}
+	}
+	ticker := time.NewTicker(tickInterval)
+	return ticker.C, ticker.Stop
+}
@@ -0,0 +1,294 @@
+package go_streams
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// the delays between the attempts to read a spill file that failed, the delay is doubled after each attempt
+const (
+	spillReadBackoff    = 10 * time.Millisecond
+	spillReadMaxBackoff = time.Second
+)
+
+// OverflowStrategy decides what happens when the buffer of a stream is full,
+// i.e: when the pipeline is slower than the source.
+type OverflowStrategy string
+
+const (
+	// Block waits until the pipeline catches up, slowing down the source (backpressure)
+	Block OverflowStrategy = "block"
+
+	// DropOldest drops the oldest buffered entry to make room for the new one
+	DropOldest OverflowStrategy = "drop-oldest"
+
+	// DropNewest drops the new entry
+	DropNewest OverflowStrategy = "drop-newest"
+
+	// Spill writes entries to a file on disk until the pipeline catches up
+	Spill OverflowStrategy = "spill"
+)
+
+// BufferConfig configures the buffer between the source of a stream and its pipeline.
+// Dropped entries are never committed on their own, committing a later entry commits them too.
+type BufferConfig struct {
+	// Size is the number of entries buffered in memory
+	Size int `json:"size" yaml:"size"`
+
+	// Overflow defaults to Block
+	Overflow OverflowStrategy `json:"overflow" yaml:"overflow"`
+
+	// SpillDir is the directory of spill files (defaults to the temp directory)
+	SpillDir string `json:"spillDir,omitempty" yaml:"spillDir,omitempty"`
+
+	// Encode and Decode serialize spilled entries, they default to encoding/gob
+	// (the types of entry values should be registered with gob.Register).
+	Encode func(entry Entry) ([]byte, error) `json:"-" yaml:"-"`
+	Decode func(data []byte) (Entry, error)  `json:"-" yaml:"-"`
+}
+
+// BufferStats are the counters of a stream buffer
+type BufferStats struct {
+	Buffered int           `json:"buffered"`
+	Dropped  uint64        `json:"dropped"`
+	Spilled  uint64        `json:"spilled"`
+	Blocked  time.Duration `json:"blocked"`
+}
+
+// boundedQueue is the buffer of a stream, push never blocks unless the strategy is Block
+type boundedQueue struct {
+	config BufferConfig
+	mutex  *sync.Mutex
+	cond   *sync.Cond
+	items  []Entry
+	spill  *spillFile
+	closed bool
+
+	dropped uint64
+	spilled uint64
+	blocked int64
+}
+
+func newBoundedQueue(name string, config BufferConfig) (*boundedQueue, error) {
+	if config.Size <= 0 {
+		return nil, fmt.Errorf("buffer size must be positive")
+	}
+	if config.Overflow == "" {
+		config.Overflow = Block
+	}
+
+	queue := &boundedQueue{config: config, mutex: &sync.Mutex{}}
+	queue.cond = sync.NewCond(queue.mutex)
+
+	if config.Overflow == Spill {
+		spill, err := newSpillFile(config.SpillDir, name, config.Encode, config.Decode)
+		if err != nil {
+			return nil, err
+		}
+		queue.spill = spill
+	}
+	return queue, nil
+}
+
+// push adds the entry according to the overflow strategy, it returns spill errors (the entry is kept in memory)
+func (this *boundedQueue) push(entry Entry) error {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	defer this.cond.Broadcast()
+
+	// once entries are spilled new entries are spilled too to keep the order
+	if this.spill != nil && (len(this.items) >= this.config.Size || this.spill.len() > 0) {
+		err := this.spill.write(entry)
+		if err == nil {
+			atomic.AddUint64(&this.spilled, 1)
+			return nil
+		}
+		if this.spill.len() == 0 {
+			this.waitForRoom()
+		}
+		this.items = append(this.items, entry)
+		return err
+	}
+
+	if len(this.items) < this.config.Size {
+		this.items = append(this.items, entry)
+		return nil
+	}
+
+	switch this.config.Overflow {
+	case DropNewest:
+		atomic.AddUint64(&this.dropped, 1)
+	case DropOldest:
+		atomic.AddUint64(&this.dropped, 1)
+		this.items = append(this.items[1:], entry)
+	default:
+		this.waitForRoom()
+		this.items = append(this.items, entry)
+	}
+	return nil
+}
+
+func (this *boundedQueue) waitForRoom() {
+	started := time.Now()
+	for len(this.items) >= this.config.Size && !this.closed {
+		this.cond.Wait()
+	}
+	atomic.AddInt64(&this.blocked, int64(time.Since(started)))
+}
+
+// pop returns the next entry, it blocks until an entry is available or returns false once the queue is closed and empty
+func (this *boundedQueue) pop() (Entry, bool, error) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	defer this.cond.Broadcast()
+
+	for {
+		if len(this.items) > 0 {
+			entry := this.items[0]
+			this.items = this.items[1:]
+			return entry, true, nil
+		}
+		if this.spill != nil && this.spill.len() > 0 {
+			entry, err := this.spill.read()
+			return entry, err == nil, err
+		}
+		if this.closed {
+			return Entry{}, false, nil
+		}
+		this.cond.Wait()
+	}
+}
+
+func (this *boundedQueue) close() {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.closed = true
+	this.cond.Broadcast()
+}
+
+func (this *boundedQueue) remove() error {
+	if this.spill != nil {
+		return this.spill.remove()
+	}
+	return nil
+}
+
+func (this *boundedQueue) stats() BufferStats {
+	this.mutex.Lock()
+	buffered := len(this.items)
+	if this.spill != nil {
+		buffered += this.spill.len()
+	}
+	this.mutex.Unlock()
+
+	return BufferStats{
+		Buffered: buffered,
+		Dropped:  atomic.LoadUint64(&this.dropped),
+		Spilled:  atomic.LoadUint64(&this.spilled),
+		Blocked:  time.Duration(atomic.LoadInt64(&this.blocked)),
+	}
+}
+
+// spillFile is an append only file of length prefixed entries, it is truncated once fully read
+type spillFile struct {
+	file   *os.File
+	encode func(entry Entry) ([]byte, error)
+	decode func(data []byte) (Entry, error)
+	read_  int64
+	write_ int64
+	count  int
+}
+
+func newSpillFile(dir, name string, encode func(Entry) ([]byte, error), decode func([]byte) (Entry, error)) (*spillFile, error) {
+	if encode == nil {
+		encode = gobEncodeEntry
+	}
+	if decode == nil {
+		decode = gobDecodeEntry
+	}
+
+	file, err := ioutil.TempFile(dir, fmt.Sprintf("%s-*.spill", sanitizeFileName(name)))
+	if err != nil {
+		return nil, err
+	}
+	return &spillFile{file: file, encode: encode, decode: decode}, nil
+}
+
+func (this *spillFile) len() int {
+	return this.count
+}
+
+func (this *spillFile) write(entry Entry) error {
+	data, err := this.encode(entry)
+	if err != nil {
+		return err
+	}
+
+	record := make([]byte, 4+len(data))
+	binary.BigEndian.PutUint32(record, uint32(len(data)))
+	copy(record[4:], data)
+	if _, err := this.file.WriteAt(record, this.write_); err != nil {
+		return err
+	}
+	this.write_ += int64(len(record))
+	this.count++
+	return nil
+}
+
+func (this *spillFile) read() (Entry, error) {
+	header := make([]byte, 4)
+	if _, err := this.file.ReadAt(header, this.read_); err != nil {
+		return Entry{}, err
+	}
+	data := make([]byte, binary.BigEndian.Uint32(header))
+	if _, err := this.file.ReadAt(data, this.read_+4); err != nil && err != io.EOF {
+		return Entry{}, err
+	}
+	this.read_ += int64(4 + len(data))
+	this.count--
+
+	if this.count == 0 {
+		this.read_, this.write_ = 0, 0
+		if err := this.file.Truncate(0); err != nil {
+			return Entry{}, err
+		}
+	}
+	return this.decode(data)
+}
+
+func (this *spillFile) remove() error {
+	_ = this.file.Close()
+	return os.Remove(this.file.Name())
+}
+
+func gobEncodeEntry(entry Entry) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(&entry); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func gobDecodeEntry(data []byte) (Entry, error) {
+	var entry Entry
+	err := gob.NewDecoder(bytes.NewReader(data)).Decode(&entry)
+	return entry, err
+}
+
+func sanitizeFileName(name string) string {
+	out := []byte(name)
+	for idx, c := range out {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_') {
+			out[idx] = '_'
+		}
+	}
+	return string(out)
+}
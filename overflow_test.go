@@ -0,0 +1,139 @@
+package go_streams
+
+import (
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestBoundedQueue_DropNewest(t *testing.T) {
+	queue, err := newBoundedQueue("drop-newest", BufferConfig{Size: 2, Overflow: DropNewest})
+	assert.Nil(t, err)
+
+	for _, key := range []string{"1", "2", "3"} {
+		assert.Nil(t, queue.push(Entry{Key: key}))
+	}
+	queue.close()
+
+	assert.EqualValues(t, []string{"1", "2"}, popKeys(t, queue))
+	assert.EqualValues(t, 1, queue.stats().Dropped)
+}
+
+func TestBoundedQueue_DropOldest(t *testing.T) {
+	queue, err := newBoundedQueue("drop-oldest", BufferConfig{Size: 2, Overflow: DropOldest})
+	assert.Nil(t, err)
+
+	for _, key := range []string{"1", "2", "3"} {
+		assert.Nil(t, queue.push(Entry{Key: key}))
+	}
+	queue.close()
+
+	assert.EqualValues(t, []string{"2", "3"}, popKeys(t, queue))
+	assert.EqualValues(t, 1, queue.stats().Dropped)
+}
+
+func TestBoundedQueue_Block_MeasuresBlockingTime(t *testing.T) {
+	queue, err := newBoundedQueue("block", BufferConfig{Size: 1})
+	assert.Nil(t, err)
+	assert.Nil(t, queue.push(Entry{Key: "1"}))
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		_, _, _ = queue.pop()
+	}()
+	assert.Nil(t, queue.push(Entry{Key: "2"}))
+	queue.close()
+
+	assert.EqualValues(t, []string{"2"}, popKeys(t, queue))
+	assert.True(t, queue.stats().Blocked >= 10*time.Millisecond)
+	assert.EqualValues(t, 0, queue.stats().Dropped)
+}
+
+func TestBoundedQueue_Spill_KeepsOrder(t *testing.T) {
+	dir, err := ioutil.TempDir("", "spill")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	queue, err := newBoundedQueue("spill", BufferConfig{Size: 2, Overflow: Spill, SpillDir: dir})
+	assert.Nil(t, err)
+	defer queue.remove()
+
+	for _, key := range []string{"1", "2", "3", "4"} {
+		assert.Nil(t, queue.push(Entry{Key: key, Value: key}))
+	}
+	assert.EqualValues(t, 2, queue.stats().Spilled)
+	assert.EqualValues(t, 4, queue.stats().Buffered)
+
+	entry, ok, err := queue.pop()
+	assert.True(t, ok)
+	assert.Nil(t, err)
+	assert.EqualValues(t, "1", entry.Key)
+
+	// new entries go after the spilled ones
+	assert.Nil(t, queue.push(Entry{Key: "5", Value: "5"}))
+	queue.close()
+
+	assert.EqualValues(t, []string{"2", "3", "4", "5"}, popKeys(t, queue))
+}
+
+func TestEngine_Buffer_DropsEntriesOfSlowPipelines(t *testing.T) {
+	engine := NewEngine(NewDirectProcessorFactory(), 10*time.Second)
+	source := NewSequentialIntegerSource(20, 0)
+	sink := NewCallbackSink(func(entries ...Entry) error {
+		time.Sleep(5 * time.Millisecond)
+		return nil
+	})
+
+	stream := NewStream(source).Buffer(BufferConfig{Size: 2, Overflow: DropNewest}).Sink(sink)
+	assert.Nil(t, engine.Add(stream))
+	engine.Start()
+
+	info := engine.Streams()[0]
+	assert.EqualValues(t, StreamCompleted, info.Status)
+	assert.NotNil(t, info.Buffer)
+	assert.True(t, info.Buffer.Dropped > 0)
+	assert.EqualValues(t, 2, engine.Config().Streams[0].Buffer.Size)
+}
+
+func TestManagedSource_Drain_BacksOffFailedSpillReads(t *testing.T) {
+	dir, err := ioutil.TempDir("", "spill")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	source := newManagedSource(NewSequentialIntegerSource(0, 0))
+	source.buffer, err = newBoundedQueue("spill", BufferConfig{Size: 1, Overflow: Spill, SpillDir: dir})
+	assert.Nil(t, err)
+	for _, key := range []string{"1", "2", "3"} {
+		assert.Nil(t, source.buffer.push(Entry{Key: key, Value: key}))
+	}
+	// the spilled entries can't be read anymore
+	assert.Nil(t, source.buffer.spill.file.Close())
+
+	in, channel, errs, drained := make(EntryChannel), make(EntryChannel, 10), make(ErrorChannel, 100), make(chan struct{})
+	go source.drain(in, channel, errs, drained)
+	time.Sleep(100 * time.Millisecond)
+	assert.EqualValues(t, "1", (<-channel).Key)
+	assert.True(t, len(errs) > 0 && len(errs) < 10)
+
+	assert.Nil(t, source.Stop())
+	close(in)
+	select {
+	case <-drained:
+	case <-time.After(time.Second):
+		t.Fatal("drain didn't give up once the source was stopped")
+	}
+}
+
+func popKeys(t *testing.T, queue *boundedQueue) []string {
+	var keys []string
+	for {
+		entry, ok, err := queue.pop()
+		assert.Nil(t, err)
+		if !ok {
+			return keys
+		}
+		keys = append(keys, entry.Key)
+	}
+}
@@ -0,0 +1,342 @@
+package go_streams
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// ComponentConfig refers to a registered component by its type
+type ComponentConfig struct {
+	Type   string `json:"type" yaml:"type"`
+	Params Params `json:"params,omitempty" yaml:"params,omitempty"`
+}
+
+// StageConfig is a single stage of a pipeline, exactly one of its fields should be set
+type StageConfig struct {
+	Filter   string           `json:"filter,omitempty" yaml:"filter,omitempty"`
+	Map      string           `json:"map,omitempty" yaml:"map,omitempty"`
+	Operator *ComponentConfig `json:"operator,omitempty" yaml:"operator,omitempty"`
+	Sink     *ComponentConfig `json:"sink,omitempty" yaml:"sink,omitempty"`
+}
+
+type RestartConfig struct {
+	Mode       RestartMode `json:"mode" yaml:"mode"`
+	MaxRetries int         `json:"maxRetries,omitempty" yaml:"maxRetries,omitempty"`
+	Backoff    string      `json:"backoff,omitempty" yaml:"backoff,omitempty"`
+	MaxBackoff string      `json:"maxBackoff,omitempty" yaml:"maxBackoff,omitempty"`
+}
+
+type CommitConfig struct {
+	Every    int    `json:"every,omitempty" yaml:"every,omitempty"`
+	Interval string `json:"interval,omitempty" yaml:"interval,omitempty"`
+	Async    bool   `json:"async,omitempty" yaml:"async,omitempty"`
+}
+
+// StreamDefinition declares a stream: its source, its stages and its settings
+type StreamDefinition struct {
+	Source   ComponentConfig `json:"source" yaml:"source"`
+	Pipeline []StageConfig   `json:"pipeline" yaml:"pipeline"`
+	Buffer   *BufferConfig   `json:"buffer,omitempty" yaml:"buffer,omitempty"`
+	Commit   *CommitConfig   `json:"commit,omitempty" yaml:"commit,omitempty"`
+	Restart  *RestartConfig  `json:"restart,omitempty" yaml:"restart,omitempty"`
+	Priority int             `json:"priority,omitempty" yaml:"priority,omitempty"`
+
+	// MaxInFlight bounds the entries taken from the source that weren't processed yet (see Stream.MaxInFlight)
+	MaxInFlight int `json:"maxInFlight,omitempty" yaml:"maxInFlight,omitempty"`
+
+	// Timeout is the deadline of the filter, map and sink calls (see Stream.WithTimeout)
+	Timeout string `json:"timeout,omitempty" yaml:"timeout,omitempty"`
+
+	// DeadLetter is the sink of the entries whose stages panicked (see Stream.DeadLetter)
+	DeadLetter *ComponentConfig `json:"deadLetter,omitempty" yaml:"deadLetter,omitempty"`
+}
+
+type EngineDefinition struct {
+	Processor       *ComponentConfig `json:"processor,omitempty" yaml:"processor,omitempty"`
+	MonitorInterval string           `json:"monitorInterval,omitempty" yaml:"monitorInterval,omitempty"`
+	Restart         *RestartConfig   `json:"restart,omitempty" yaml:"restart,omitempty"`
+
+	// Capacity sets a scheduler sharing this capacity between the streams (see Engine.SetScheduler)
+	Capacity int `json:"capacity,omitempty" yaml:"capacity,omitempty"`
+
+	// Limits bounds the resources used by the streams (see Engine.SetLimits)
+	Limits *Limits `json:"limits,omitempty" yaml:"limits,omitempty"`
+}
+
+// PipelineConfig is a declarative definition of an engine and its streams, e.g:
+//
+//	engine:
+//	  processor: {type: buffered, params: {size: 100, timeout: 1s}}
+//	streams:
+//	  - source: {type: sequential, params: {limit: 100}}
+//	    pipeline:
+//	      - filter: even
+//	      - operator: {type: throttle, params: {n: 10, per: 1s}}
+//	      - sink: {type: console}
+//
+// Components are referred to by the names they were registered with (see Registry).
+type PipelineConfig struct {
+	Engine  EngineDefinition   `json:"engine" yaml:"engine"`
+	Streams []StreamDefinition `json:"streams" yaml:"streams"`
+}
+
+// ReadPipelineConfig reads a YAML (.yaml, .yml) or JSON (.json) configuration file
+func ReadPipelineConfig(path string) (*PipelineConfig, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParsePipelineConfig(data, strings.TrimPrefix(filepath.Ext(path), "."))
+}
+
+// ParsePipelineConfig parses a configuration in the given format ("yaml", "yml" or "json")
+func ParsePipelineConfig(data []byte, format string) (*PipelineConfig, error) {
+	config := &PipelineConfig{}
+	var err error
+	switch strings.ToLower(format) {
+	case "yaml", "yml":
+		err = yaml.UnmarshalStrict(data, config)
+	case "json":
+		decoder := json.NewDecoder(strings.NewReader(string(data)))
+		decoder.DisallowUnknownFields()
+		err = decoder.Decode(config)
+	default:
+		return nil, fmt.Errorf("unknown configuration format: '%s'", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// Build creates the streams of the configuration
+func (this *PipelineConfig) Build(registry *Registry) ([]Stream, error) {
+	streams := make([]Stream, len(this.Streams))
+	for idx, def := range this.Streams {
+		stream, err := def.build(registry, nil)
+		if err != nil {
+			return nil, fmt.Errorf("stream #%d: %s", idx, err.Error())
+		}
+		streams[idx] = stream
+	}
+	return streams, nil
+}
+
+// build creates the stream, its sinks (not the dead letter sink) are decorated by the middleware when it isn't nil
+func (this *StreamDefinition) build(registry *Registry, sinks SinkMiddleware) (Stream, error) {
+	factory, found := registry.source(this.Source.Type)
+	if !found {
+		return nil, fmt.Errorf("unknown source: '%s'", this.Source.Type)
+	}
+	source, err := factory(this.Source.Params)
+	if err != nil {
+		return nil, fmt.Errorf("source '%s': %s", this.Source.Type, err.Error())
+	}
+
+	stream := Stream(NewStream(source))
+	for idx, stage := range this.Pipeline {
+		if stream, err = stage.apply(stream, registry, sinks); err != nil {
+			return nil, fmt.Errorf("stage #%d: %s", idx, err.Error())
+		}
+	}
+
+	if this.Buffer != nil {
+		stream = stream.Buffer(*this.Buffer)
+	}
+	if this.Commit != nil {
+		interval, err := parseDuration(this.Commit.Interval)
+		if err != nil {
+			return nil, fmt.Errorf("commit interval: %s", err.Error())
+		}
+		stream = stream.CommitWith(CommitStrategy{Every: this.Commit.Every, Interval: interval, Async: this.Commit.Async})
+	}
+	if this.DeadLetter != nil {
+		factory, found := registry.sink(this.DeadLetter.Type)
+		if !found {
+			return nil, fmt.Errorf("unknown dead letter sink: '%s'", this.DeadLetter.Type)
+		}
+		sink, err := factory(this.DeadLetter.Params)
+		if err != nil {
+			return nil, fmt.Errorf("dead letter sink '%s': %s", this.DeadLetter.Type, err.Error())
+		}
+		stream = stream.DeadLetter(sink)
+	}
+	if this.Restart != nil {
+		policy, err := this.Restart.policy()
+		if err != nil {
+			return nil, err
+		}
+		stream = stream.Supervise(policy)
+	}
+	if this.Priority != 0 {
+		stream = stream.Priority(this.Priority)
+	}
+	if this.MaxInFlight != 0 {
+		stream = stream.MaxInFlight(this.MaxInFlight)
+	}
+	if this.Timeout != "" {
+		timeout, err := parseDuration(this.Timeout)
+		if err != nil {
+			return nil, fmt.Errorf("timeout: %s", err.Error())
+		}
+		stream = stream.WithTimeout(timeout)
+	}
+	return stream, nil
+}
+
+func (this *StageConfig) apply(stream Stream, registry *Registry, sinks SinkMiddleware) (Stream, error) {
+	set := 0
+	for _, isSet := range []bool{this.Filter != "", this.Map != "", this.Operator != nil, this.Sink != nil} {
+		if isSet {
+			set++
+		}
+	}
+	if set != 1 {
+		return nil, fmt.Errorf("a stage should have exactly one of filter, map, operator or sink")
+	}
+
+	switch {
+	case this.Filter != "":
+		fn, found := registry.filter(this.Filter)
+		if !found {
+			return nil, fmt.Errorf("unknown filter: '%s'", this.Filter)
+		}
+		return stream.Filter(fn), nil
+
+	case this.Map != "":
+		fn, found := registry.mapFunc(this.Map)
+		if !found {
+			return nil, fmt.Errorf("unknown map: '%s'", this.Map)
+		}
+		return stream.Map(fn), nil
+
+	case this.Operator != nil:
+		factory, found := registry.operator(this.Operator.Type)
+		if !found {
+			return nil, fmt.Errorf("unknown operator: '%s'", this.Operator.Type)
+		}
+		operator, err := factory(this.Operator.Params, registry)
+		if err != nil {
+			return nil, fmt.Errorf("operator '%s': %s", this.Operator.Type, err.Error())
+		}
+		return stream.Via(operator), nil
+
+	default:
+		factory, found := registry.sink(this.Sink.Type)
+		if !found {
+			return nil, fmt.Errorf("unknown sink: '%s'", this.Sink.Type)
+		}
+		sink, err := factory(this.Sink.Params)
+		if err != nil {
+			return nil, fmt.Errorf("sink '%s': %s", this.Sink.Type, err.Error())
+		}
+		if sinks != nil {
+			sink = WrapSink(sink, sinks)
+		}
+		return stream.Sink(sink), nil
+	}
+}
+
+func (this *RestartConfig) policy() (RestartPolicy, error) {
+	backoff, err := parseDuration(this.Backoff)
+	if err != nil {
+		return RestartPolicy{}, fmt.Errorf("restart backoff: %s", err.Error())
+	}
+	maxBackoff, err := parseDuration(this.MaxBackoff)
+	if err != nil {
+		return RestartPolicy{}, fmt.Errorf("restart max backoff: %s", err.Error())
+	}
+	switch this.Mode {
+	case RestartNever, RestartOnFailure, RestartAlways:
+	default:
+		return RestartPolicy{}, fmt.Errorf("unknown restart mode: '%s'", this.Mode)
+	}
+	return RestartPolicy{Mode: this.Mode, MaxRetries: this.MaxRetries, Backoff: backoff, MaxBackoff: maxBackoff}, nil
+}
+
+func parseDuration(value string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+	return time.ParseDuration(value)
+}
+
+// LoadEngine creates an engine from a configuration file, a nil registry uses the built-in components only
+func LoadEngine(path string, registry *Registry) (*engine, error) {
+	engine := NewEngine(NewDirectProcessorFactory(), 10*time.Second)
+	if err := engine.LoadConfig(path, registry); err != nil {
+		return nil, err
+	}
+	return engine, nil
+}
+
+// LoadConfig reads a configuration file (see PipelineConfig), applies its engine settings and adds its streams,
+// it should be called before the engine is started. A nil registry uses the built-in components only.
+func (this *engine) LoadConfig(path string, registry *Registry) error {
+	config, err := ReadPipelineConfig(path)
+	if err != nil {
+		return err
+	}
+	if registry == nil {
+		registry = NewRegistry()
+	}
+	if this.Running() {
+		return fmt.Errorf("the configuration should be loaded before the engine is started")
+	}
+
+	if err := this.applyEngineDefinition(config.Engine, registry); err != nil {
+		return err
+	}
+	streams, err := config.Build(registry)
+	if err != nil {
+		return err
+	}
+	return this.Add(streams...)
+}
+
+func (this *engine) applyEngineDefinition(def EngineDefinition, registry *Registry) error {
+	if def.Processor != nil {
+		builder, found := registry.processor(def.Processor.Type)
+		if !found {
+			return fmt.Errorf("unknown processor: '%s'", def.Processor.Type)
+		}
+		factory, err := builder(def.Processor.Params)
+		if err != nil {
+			return fmt.Errorf("processor '%s': %s", def.Processor.Type, err.Error())
+		}
+		this.SetProcessorFactory(factory)
+	}
+
+	if def.MonitorInterval != "" {
+		interval, err := time.ParseDuration(def.MonitorInterval)
+		if err != nil {
+			return fmt.Errorf("monitor interval: %s", err.Error())
+		}
+		this.mutex.Lock()
+		this.monitorTicker.Stop()
+		this.monitorInterval = interval
+		this.monitorTicker = time.NewTicker(interval)
+		this.mutex.Unlock()
+	}
+
+	if def.Restart != nil {
+		policy, err := def.Restart.policy()
+		if err != nil {
+			return err
+		}
+		this.SetRestartPolicy(policy)
+	}
+	if def.Capacity > 0 {
+		this.SetScheduler(NewScheduler(def.Capacity))
+	}
+	if def.Limits != nil {
+		this.SetLimits(*def.Limits)
+	}
+	return nil
+}
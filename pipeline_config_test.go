@@ -0,0 +1,115 @@
+package go_streams
+
+import (
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const yamlPipeline = `
+engine:
+  processor: {type: buffered, params: {size: 4, timeout: 100ms}}
+  monitorInterval: 5s
+  restart: {mode: on-failure, maxRetries: 3, backoff: 10ms}
+  capacity: 2
+  limits: {maxWorkers: 8, maxSinkRequests: 2}
+streams:
+  - source: {type: sequential, params: {limit: 10}}
+    priority: 3
+    maxInFlight: 4
+    timeout: 1s
+    pipeline:
+      - filter: even
+      - map: double
+      - operator: {type: dedupe, params: {key: identity, ttl: 1m}}
+      - sink: {type: memory}
+    commit: {every: 2}
+`
+
+const jsonPipeline = `{
+  "streams": [{
+    "source": {"type": "sequential", "params": {"limit": 3}},
+    "pipeline": [{"map": "double"}, {"sink": {"type": "memory"}}],
+    "buffer": {"size": 2, "overflow": "block"}
+  }]
+}`
+
+func testRegistry(sink Sink) *Registry {
+	registry := NewRegistry()
+	registry.RegisterFilter("even", func(entry interface{}) bool { return entry.(int)%2 == 0 })
+	registry.RegisterMap("double", func(entry interface{}) interface{} { return entry.(int) * 2 })
+	registry.RegisterKey("identity", func(value interface{}) string { return string(rune(value.(int))) })
+	registry.RegisterSink("memory", func(params Params) (Sink, error) { return sink, nil })
+	return registry
+}
+
+func writeConfig(t *testing.T, name, content string) string {
+	dir, err := ioutil.TempDir("", "pipeline")
+	assert.Nil(t, err)
+	path := filepath.Join(dir, name)
+	assert.Nil(t, ioutil.WriteFile(path, []byte(content), 0644))
+	return path
+}
+
+func TestLoadEngine_Yaml(t *testing.T) {
+	path := writeConfig(t, "pipeline.yaml", yamlPipeline)
+	defer os.RemoveAll(filepath.Dir(path))
+
+	sink := NewArraySink()
+	engine, err := LoadEngine(path, testRegistry(sink))
+	assert.Nil(t, err)
+	assert.EqualValues(t, 5*time.Second, engine.monitorInterval)
+	assert.EqualValues(t, RestartOnFailure, engine.restartPolicy.Mode)
+	assert.EqualValues(t, 2, engine.scheduler.capacity)
+	assert.EqualValues(t, Limits{MaxWorkers: 8, MaxSinkRequests: 2}, engine.governor.limits)
+
+	config := engine.Config()
+	assert.EqualValues(t, "*go_streams.bufferedProcessor", config.Processor)
+	assert.Len(t, config.Streams, 1)
+	assert.EqualValues(t, []string{"filter", "map", "operator(*go_streams.dedupe)", "sink(*go_streams.ArraySink)"}, config.Streams[0].Handlers)
+	assert.EqualValues(t, 3, config.Streams[0].Priority)
+	assert.EqualValues(t, 4, config.Streams[0].MaxInFlight)
+	assert.EqualValues(t, "1s", config.Streams[0].Timeout)
+
+	engine.Start()
+	assert.EqualValues(t, []interface{}{0, 4, 8, 12, 16, 20}, sink.Array())
+}
+
+func TestEngine_LoadConfig_Json(t *testing.T) {
+	path := writeConfig(t, "pipeline.json", jsonPipeline)
+	defer os.RemoveAll(filepath.Dir(path))
+
+	sink := NewArraySink()
+	engine := NewEngine(NewDirectProcessorFactory(), 10*time.Second)
+	assert.Nil(t, engine.LoadConfig(path, testRegistry(sink)))
+	assert.EqualValues(t, 2, engine.Config().Streams[0].Buffer.Size)
+
+	engine.Start()
+	assert.EqualValues(t, []interface{}{0, 2, 4, 6}, sink.Array())
+}
+
+func TestParsePipelineConfig_Errors(t *testing.T) {
+	registry := testRegistry(NewArraySink())
+
+	_, err := ParsePipelineConfig([]byte(`{"streams": [], "unknown": 1}`), "json")
+	assert.NotNil(t, err)
+
+	_, err = ParsePipelineConfig([]byte(`streams: []`), "toml")
+	assert.NotNil(t, err)
+
+	for _, content := range []string{
+		`streams: [{source: {type: kafka}}]`,
+		`streams: [{source: {type: sequential}, pipeline: [{filter: odd}]}]`,
+		`streams: [{source: {type: sequential}, pipeline: [{filter: even, map: double}]}]`,
+		`streams: [{source: {type: sequential, params: {limit: ten}}}]`,
+		`streams: [{source: {type: sequential}, pipeline: [{operator: {type: throttle}}]}]`,
+	} {
+		config, err := ParsePipelineConfig([]byte(content), "yaml")
+		assert.Nil(t, err)
+		_, err = config.Build(registry)
+		assert.NotNil(t, err, content)
+	}
+}
@@ -0,0 +1,302 @@
+package polling
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	streams "github.com/matang28/go-streams"
+)
+
+// FetchFunc fetches the entries that come after the cursor and returns the cursor of the next fetch,
+// the cursor is empty on the first fetch. The context is cancelled when the source is stopped.
+type FetchFunc func(ctx context.Context, cursor string) (entries []streams.Entry, next string, err error)
+
+const (
+	defaultMaxBackoff = time.Minute
+	cursorKey         = "cursor"
+)
+
+type Config struct {
+	// Name of the source (defaults to a generated name)
+	Name string
+
+	// Interval between fetches
+	Interval time.Duration
+
+	// Jitter randomizes each interval by up to +/- Jitter of it (e.g: 0.1 for 10%)
+	Jitter float64
+
+	// Backoff is the delay after the first failed fetch (defaults to Interval), it doubles on
+	// every consecutive failure up to MaxBackoff (defaults to 1 minute).
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+
+	// UntilEmpty fetches again right away while fetches return entries (e.g: to drain pages)
+	UntilEmpty bool
+
+	// Store persists the committed cursor so a restarted source resumes from it,
+	// without a store the source starts with an empty cursor.
+	Store streams.StateStore
+
+	// CursorAt returns the cursor of the first entries at or after the time, it is required by SeekToTimestamp
+	CursorAt func(at time.Time) (string, error)
+}
+
+type batch struct {
+	lastKey string
+	cursor  string
+}
+
+// Source is a source that polls a fetch function, the cursor returned by a fetch is committed
+// once the last entry of that fetch is committed so a restarted source never skips entries.
+type Source struct {
+	config Config
+	fetch  FetchFunc
+	name   string
+
+	mutex     *sync.Mutex
+	cursor    string
+	committed string
+	pending   []batch
+	paused    int32
+
+	// seeks is incremented by every seek, fetches started before a seek are discarded
+	seeks uint64
+
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func NewSource(fetch FetchFunc, config Config) (*Source, error) {
+	if config.Interval <= 0 {
+		return nil, fmt.Errorf("polling interval should be positive")
+	}
+	if config.Jitter < 0 || config.Jitter >= 1 {
+		return nil, fmt.Errorf("jitter should be in [0, 1)")
+	}
+	if config.Backoff <= 0 {
+		config.Backoff = config.Interval
+	}
+	if config.MaxBackoff <= 0 {
+		config.MaxBackoff = defaultMaxBackoff
+	}
+
+	name := config.Name
+	if name == "" {
+		name = fmt.Sprintf("pollingSource-%d", time.Now().UnixNano())
+	}
+
+	source := &Source{config: config, fetch: fetch, name: name, mutex: &sync.Mutex{}}
+	source.ctx, source.cancel = context.WithCancel(context.Background())
+
+	if config.Store != nil {
+		cursor, found, err := config.Store.Get(name + "/" + cursorKey)
+		if err != nil {
+			return nil, err
+		}
+		if found {
+			source.cursor, _ = cursor.(string)
+			source.committed = source.cursor
+		}
+	}
+	return source, nil
+}
+
+func (this *Source) Start(channel streams.EntryChannel, errorChannel streams.ErrorChannel) {
+	defer func() {
+		close(channel)
+		errorChannel <- streams.NewEofError(this)
+	}()
+
+	failures := 0
+	var wait time.Duration
+	for {
+		select {
+		case <-this.ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+
+		if atomic.LoadInt32(&this.paused) == 1 {
+			wait = this.interval()
+			continue
+		}
+
+		entries, err := this.poll()
+		if err != nil {
+			failures++
+			wait = this.backoff(failures)
+			errorChannel <- err
+			continue
+		}
+		failures = 0
+
+		for idx := range entries {
+			select {
+			case <-this.ctx.Done():
+				return
+			case channel <- entries[idx]:
+			}
+		}
+
+		if len(entries) > 0 && this.config.UntilEmpty {
+			wait = 0
+		} else {
+			wait = this.interval()
+		}
+	}
+}
+
+// poll fetches the next entries and advances the cursor
+func (this *Source) poll() ([]streams.Entry, error) {
+	this.mutex.Lock()
+	cursor, seeks := this.cursor, this.seeks
+	this.mutex.Unlock()
+
+	entries, next, err := this.fetch(this.ctx, cursor)
+	if err != nil {
+		if this.ctx.Err() != nil {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	if seeks != this.seeks {
+		return nil, nil
+	}
+	this.cursor = next
+	if len(entries) > 0 {
+		this.pending = append(this.pending, batch{lastKey: entries[len(entries)-1].Key, cursor: next})
+		return entries, nil
+	}
+	// nothing to process, the cursor can be committed right away when no fetch is pending
+	if len(this.pending) == 0 && next != this.committed {
+		return nil, this.save(next)
+	}
+	return nil, nil
+}
+
+func (this *Source) interval() time.Duration {
+	interval := this.config.Interval
+	if this.config.Jitter > 0 {
+		delta := (rand.Float64()*2 - 1) * this.config.Jitter * float64(interval)
+		interval += time.Duration(delta)
+	}
+	return interval
+}
+
+func (this *Source) backoff(failures int) time.Duration {
+	delay := this.config.Backoff
+	for i := 1; i < failures && delay < this.config.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > this.config.MaxBackoff {
+		delay = this.config.MaxBackoff
+	}
+	return delay
+}
+
+// CommitEntry commits the cursor of the fetches whose last entry is committed
+func (this *Source) CommitEntry(keys ...string) error {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	cursor := ""
+	for _, key := range keys {
+		for idx, b := range this.pending {
+			if b.lastKey == key {
+				cursor = b.cursor
+				this.pending = this.pending[idx+1:]
+				break
+			}
+		}
+	}
+	if cursor == "" || cursor == this.committed {
+		return nil
+	}
+	return this.save(cursor)
+}
+
+// save persists the committed cursor, the caller holds the mutex
+func (this *Source) save(cursor string) error {
+	this.committed = cursor
+	if this.config.Store == nil {
+		return nil
+	}
+	return this.config.Store.Put(this.name+"/"+cursorKey, cursor, 0)
+}
+
+// SeekToTimestamp fetches from the cursor returned by CursorAt for the time
+func (this *Source) SeekToTimestamp(at time.Time) error {
+	if this.config.CursorAt == nil {
+		return fmt.Errorf("polling source '%s' can't seek to a timestamp without CursorAt", this.name)
+	}
+	cursor, err := this.config.CursorAt(at)
+	if err != nil {
+		return err
+	}
+	return this.SeekToKey(cursor)
+}
+
+// SeekToKey fetches from the cursor, it is committed right away so a restarted source
+// replays from it too. Commits of the entries fetched before the seek are ignored.
+func (this *Source) SeekToKey(cursor string) error {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.seeks++
+	this.cursor = cursor
+	this.pending = nil
+	return this.save(cursor)
+}
+
+// Rewind fetches from the empty cursor
+func (this *Source) Rewind() error {
+	return this.SeekToKey("")
+}
+
+// Cursor returns the latest committed cursor
+func (this *Source) Cursor() string {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	return this.committed
+}
+
+// Snapshot returns the committed cursor, see streams.Snapshotter
+func (this *Source) Snapshot() ([]byte, error) {
+	return []byte(this.Cursor()), nil
+}
+
+// Restore fetches from the cursor of a snapshot (see SeekToKey)
+func (this *Source) Restore(data []byte) error {
+	return this.SeekToKey(string(data))
+}
+
+func (this *Source) Stop() error {
+	this.cancel()
+	return nil
+}
+
+func (this *Source) Ping() error {
+	return nil
+}
+
+// Pause skips fetches until Resume is called
+func (this *Source) Pause() error {
+	atomic.StoreInt32(&this.paused, 1)
+	return nil
+}
+
+func (this *Source) Resume() error {
+	atomic.StoreInt32(&this.paused, 0)
+	return nil
+}
+
+func (this *Source) Name() string {
+	return this.name
+}
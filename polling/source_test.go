@@ -0,0 +1,181 @@
+package polling
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	streams "github.com/matang28/go-streams"
+	"github.com/stretchr/testify/assert"
+)
+
+// pages returns a fetch function that serves the pages in order, the cursor is the index of the next page
+func pages(pages ...[]int) (FetchFunc, *[]string) {
+	mutex := &sync.Mutex{}
+	var cursors []string
+	return func(ctx context.Context, cursor string) ([]streams.Entry, string, error) {
+		mutex.Lock()
+		cursors = append(cursors, cursor)
+		mutex.Unlock()
+
+		page := 0
+		if cursor != "" {
+			page, _ = strconv.Atoi(cursor)
+		}
+		if page >= len(pages) {
+			return nil, cursor, nil
+		}
+		var entries []streams.Entry
+		for _, value := range pages[page] {
+			entries = append(entries, streams.Entry{Key: strconv.Itoa(value), Value: value})
+		}
+		return entries, strconv.Itoa(page + 1), nil
+	}, &cursors
+}
+
+func TestSource_CommitsCursors(t *testing.T) {
+	fetch, _ := pages([]int{1, 2}, []int{3})
+	store := streams.NewMemoryStateStore(10)
+	source, err := NewSource(fetch, Config{Name: "orders", Interval: 5 * time.Millisecond, UntilEmpty: true, Store: store})
+	assert.Nil(t, err)
+
+	sink := streams.NewArraySink()
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		_ = source.Stop()
+	}()
+	streams.NewStream(source).Sink(sink).Process(streams.NewDirectProcessor(), make(streams.ErrorChannel, 10))
+
+	assert.EqualValues(t, []interface{}{1, 2, 3}, sink.Array())
+	assert.EqualValues(t, "2", source.Cursor())
+
+	// a new source resumes from the stored cursor
+	fetch, cursors := pages([]int{1, 2}, []int{3})
+	source, err = NewSource(fetch, Config{Name: "orders", Interval: 5 * time.Millisecond, Store: store})
+	assert.Nil(t, err)
+	entries, err := source.poll()
+	assert.Nil(t, err)
+	assert.Empty(t, entries)
+	assert.EqualValues(t, []string{"2"}, *cursors)
+}
+
+func TestSource_CommitsOnlyFetchedBatches(t *testing.T) {
+	fetch, _ := pages([]int{1, 2}, []int{3, 4})
+	source, err := NewSource(fetch, Config{Interval: time.Second})
+	assert.Nil(t, err)
+
+	_, _ = source.poll()
+	_, _ = source.poll()
+
+	// the first entry of a fetch doesn't commit its cursor
+	assert.Nil(t, source.CommitEntry("1"))
+	assert.EqualValues(t, "", source.Cursor())
+
+	assert.Nil(t, source.CommitEntry("2", "3"))
+	assert.EqualValues(t, "1", source.Cursor())
+
+	assert.Nil(t, source.CommitEntry("4"))
+	assert.EqualValues(t, "2", source.Cursor())
+}
+
+func TestSource_BacksOffOnErrors(t *testing.T) {
+	mutex := &sync.Mutex{}
+	var calls []time.Time
+	source, err := NewSource(func(ctx context.Context, cursor string) ([]streams.Entry, string, error) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		calls = append(calls, time.Now())
+		return nil, cursor, fmt.Errorf("api unavailable")
+	}, Config{Interval: time.Millisecond, Backoff: 10 * time.Millisecond, MaxBackoff: 40 * time.Millisecond})
+	assert.Nil(t, err)
+
+	errs := make(streams.ErrorChannel, 100)
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		_ = source.Stop()
+	}()
+	source.Start(make(streams.EntryChannel, 10), errs)
+
+	// 0, 10, 30, 70 ms
+	mutex.Lock()
+	defer mutex.Unlock()
+	assert.True(t, len(calls) >= 3 && len(calls) <= 5, "calls: %d", len(calls))
+	assert.True(t, calls[2].Sub(calls[1]) >= 20*time.Millisecond)
+	assert.Contains(t, (<-errs).Error(), "api unavailable")
+}
+
+func TestSource_Jitter(t *testing.T) {
+	source, err := NewSource(nil, Config{Interval: 100 * time.Millisecond, Jitter: 0.2})
+	assert.Nil(t, err)
+	for i := 0; i < 100; i++ {
+		interval := source.interval()
+		assert.True(t, interval >= 80*time.Millisecond && interval <= 120*time.Millisecond)
+	}
+	assert.EqualValues(t, 40*time.Millisecond, (&Source{config: Config{Backoff: 10 * time.Millisecond, MaxBackoff: 40 * time.Millisecond}}).backoff(5))
+}
+
+func TestNewSource_Errors(t *testing.T) {
+	_, err := NewSource(nil, Config{})
+	assert.NotNil(t, err)
+	_, err = NewSource(nil, Config{Interval: time.Second, Jitter: 1})
+	assert.NotNil(t, err)
+}
+
+func TestSource_Seek(t *testing.T) {
+	fetch, cursors := pages([]int{1, 2}, []int{3}, []int{4})
+	store := streams.NewMemoryStateStore(10)
+	source, err := NewSource(fetch, Config{Name: "orders", Interval: time.Hour, Store: store, CursorAt: func(at time.Time) (string, error) {
+		return strconv.Itoa(at.Hour()), nil
+	}})
+	assert.Nil(t, err)
+
+	entries, err := source.poll()
+	assert.Nil(t, err)
+	assert.Len(t, entries, 2)
+	assert.Nil(t, source.SeekToKey("2"))
+	assert.EqualValues(t, "2", source.Cursor())
+	// the entries fetched before the seek don't move the cursor
+	assert.Nil(t, source.CommitEntry("2"))
+	assert.EqualValues(t, "2", source.Cursor())
+
+	entries, err = source.poll()
+	assert.Nil(t, err)
+	assert.EqualValues(t, 4, entries[0].Value)
+
+	assert.Nil(t, source.SeekToTimestamp(time.Date(2026, 1, 1, 1, 0, 0, 0, time.UTC)))
+	entries, err = source.poll()
+	assert.Nil(t, err)
+	assert.EqualValues(t, 3, entries[0].Value)
+
+	assert.Nil(t, source.Rewind())
+	entries, err = source.poll()
+	assert.Nil(t, err)
+	assert.EqualValues(t, 1, entries[0].Value)
+	assert.EqualValues(t, []string{"", "2", "1", ""}, *cursors)
+
+	stored, _, err := store.Get("orders/" + cursorKey)
+	assert.Nil(t, err)
+	assert.EqualValues(t, "", stored)
+}
+
+func TestSource_Snapshot(t *testing.T) {
+	fetch, cursors := pages([]int{1, 2}, []int{3})
+	source, err := NewSource(fetch, Config{Name: "orders", Interval: time.Hour})
+	assert.Nil(t, err)
+	_, err = source.poll()
+	assert.Nil(t, err)
+	assert.Nil(t, source.CommitEntry("2"))
+	data, err := source.Snapshot()
+	assert.Nil(t, err)
+
+	restored, err := NewSource(fetch, Config{Name: "orders", Interval: time.Hour})
+	assert.Nil(t, err)
+	assert.Nil(t, restored.Restore(data))
+	entries, err := restored.poll()
+	assert.Nil(t, err)
+	assert.EqualValues(t, 3, entries[0].Value)
+	assert.EqualValues(t, []string{"", "1"}, *cursors)
+}
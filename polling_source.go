@@ -2,6 +2,7 @@ package go_streams
 
 import (
 	"fmt"
+	"sync/atomic"
 	"time"
 )
 
@@ -14,7 +15,8 @@ type PollingSource struct {
 	lastCommit string
 	cb         OnPoll
 
-	timer *time.Ticker
+	timer  *time.Ticker
+	paused int32
 
 	closeCh chan bool
 }
@@ -37,6 +39,9 @@ Loop:
 			close(channel)
 			break Loop
 		case <-this.timer.C:
+			if atomic.LoadInt32(&this.paused) == 1 {
+				continue
+			}
 			if arr, err := this.cb(this.lastCommit); err != nil {
 				errorChannel <- err
 			} else {
@@ -59,6 +64,17 @@ func (this *PollingSource) Stop() error {
 	return nil
 }
 
+// Pause skips polls until Resume is called
+func (this *PollingSource) Pause() error {
+	atomic.StoreInt32(&this.paused, 1)
+	return nil
+}
+
+func (this *PollingSource) Resume() error {
+	atomic.StoreInt32(&this.paused, 0)
+	return nil
+}
+
 func (this *PollingSource) CommitEntry(keys ...string) error {
 	this.lastCommit = keys[len(keys)-1]
 	logger.Debug("Committing entry: %s", this.lastCommit)
@@ -0,0 +1,255 @@
+package postgres
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	streams "github.com/matang28/go-streams"
+)
+
+// Operation is the type of a row change
+type Operation string
+
+const (
+	Insert Operation = "insert"
+	Update Operation = "update"
+	Delete Operation = "delete"
+)
+
+// Metadata keys attached to each entry emitted by the CdcSource
+const (
+	MetadataSchema    = "schema"
+	MetadataTable     = "table"
+	MetadataOperation = "operation"
+	MetadataLSN       = "lsn"
+	MetadataXid       = "xid"
+)
+
+// Change is a single row change decoded from the replication stream,
+// it is used as the Value of the entries emitted by the CdcSource.
+type Change struct {
+	LSN       LSN
+	Xid       uint32
+	Schema    string
+	Table     string
+	Operation Operation
+
+	// Columns holds the new row (empty for deletes)
+	Columns map[string]interface{}
+
+	// OldKeys holds the replica identity of the old row (updates and deletes only)
+	OldKeys map[string]interface{}
+}
+
+// Message is a raw XLogData message received from a logical replication slot
+type Message struct {
+	WALStart LSN
+	Data     []byte
+}
+
+// ReplicationConn abstracts a connection in replication mode that already
+// started streaming from a logical replication slot (e.g: on top of pglogrepl),
+// this way go-streams doesn't depend on a specific PostgreSQL driver.
+type ReplicationConn interface {
+	streams.Pingable
+
+	// Receive blocks until the next XLogData message arrives,
+	// keepalive messages should be handled by the implementation.
+	Receive() (*Message, error)
+
+	// Flush reports the given LSN as flushed (standby status update)
+	// allowing the server to advance the replication slot.
+	Flush(lsn LSN) error
+
+	// Close closes the connection, a blocked Receive call should return an error.
+	Close() error
+}
+
+// Decoder decodes the output plugin payload of a message into row changes.
+type Decoder interface {
+	Decode(msg *Message) ([]Change, error)
+}
+
+// receiveBackoff is the initial delay between failed Receive calls, it doubles
+// on every consecutive failure up to maxReceiveBackoff.
+var (
+	receiveBackoff    = 100 * time.Millisecond
+	maxReceiveBackoff = 10 * time.Second
+)
+
+type pendingMessage struct {
+	lsn     LSN
+	changes int
+}
+
+// CdcSource consumes a PostgreSQL logical replication stream and emits
+// every insert/update/delete as an entry, committing an entry advances the replication slot.
+// Commits are cumulative, committing an entry acknowledges all the entries that came before it.
+type CdcSource struct {
+	name    string
+	conn    ReplicationConn
+	decoder Decoder
+
+	mutex   *sync.Mutex
+	pending []pendingMessage
+	flushed LSN
+
+	closeCh chan bool
+	once    *sync.Once
+}
+
+func NewCdcSource(name string, conn ReplicationConn, decoder Decoder) *CdcSource {
+	return &CdcSource{
+		name:    name,
+		conn:    conn,
+		decoder: decoder,
+		mutex:   &sync.Mutex{},
+		closeCh: make(chan bool),
+		once:    &sync.Once{},
+	}
+}
+
+func (this *CdcSource) Start(channel streams.EntryChannel, errorChannel streams.ErrorChannel) {
+	streams.LogWith(streams.Fields{streams.FieldStream: this.name}).Info("Starting postgres cdc source")
+	backoff := receiveBackoff
+Loop:
+	for {
+		msg, err := this.conn.Receive()
+
+		select {
+		case <-this.closeCh:
+			close(channel)
+			break Loop
+		default:
+		}
+
+		if err != nil {
+			errorChannel <- err
+			select {
+			case <-this.closeCh:
+				close(channel)
+				break Loop
+			case <-time.After(backoff):
+			}
+			if backoff *= 2; backoff > maxReceiveBackoff {
+				backoff = maxReceiveBackoff
+			}
+			continue
+		}
+		backoff = receiveBackoff
+
+		changes, err := this.decoder.Decode(msg)
+		if err != nil {
+			errorChannel <- err
+			continue
+		}
+
+		this.track(msg.WALStart, len(changes))
+		for idx := range changes {
+			channel <- newEntry(changes[idx], idx)
+		}
+	}
+	errorChannel <- streams.NewEofError(this)
+	streams.LogWith(streams.Fields{streams.FieldStream: this.name}).Info("Postgres cdc source stopped")
+}
+
+func (this *CdcSource) Ping() error {
+	return this.conn.Ping()
+}
+
+func (this *CdcSource) Stop() error {
+	var err error
+	this.once.Do(func() {
+		streams.LogWith(streams.Fields{streams.FieldStream: this.name}).Info("Stopping postgres cdc source")
+		close(this.closeCh)
+		err = this.conn.Close()
+	})
+	return err
+}
+
+func (this *CdcSource) CommitEntry(keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	lsn, idx, err := parseKey(keys[len(keys)-1])
+	if err != nil {
+		return err
+	}
+
+	this.mutex.Lock()
+	flushTo := this.flushed
+	done := 0
+	for _, p := range this.pending {
+		if p.changes > 0 && (p.lsn > lsn || (p.lsn == lsn && idx < p.changes-1)) {
+			break
+		}
+		flushTo = p.lsn
+		done++
+	}
+	this.pending = this.pending[done:]
+	advanced := flushTo > this.flushed
+	this.flushed = flushTo
+	this.mutex.Unlock()
+
+	if !advanced {
+		return nil
+	}
+	streams.LogWith(streams.Fields{streams.FieldStream: this.name}).Debug("Flushing replication slot to LSN: %s", flushTo)
+	return this.conn.Flush(flushTo)
+}
+
+func (this *CdcSource) Name() string {
+	return this.name
+}
+
+// FlushedLSN returns the latest LSN reported to the server
+func (this *CdcSource) FlushedLSN() LSN {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	return this.flushed
+}
+
+func (this *CdcSource) track(lsn LSN, changes int) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	// Messages without changes (begin, commit, etc...) are acknowledged
+	// together with the entries committed before them.
+	this.pending = append(this.pending, pendingMessage{lsn: lsn, changes: changes})
+}
+
+func newEntry(change Change, idx int) streams.Entry {
+	return streams.Entry{
+		Key:   fmt.Sprintf("%s:%d", change.LSN, idx),
+		Value: &change,
+		Metadata: map[string]string{
+			MetadataSchema:    change.Schema,
+			MetadataTable:     change.Table,
+			MetadataOperation: string(change.Operation),
+			MetadataLSN:       change.LSN.String(),
+			MetadataXid:       strconv.FormatUint(uint64(change.Xid), 10),
+		},
+	}
+}
+
+func parseKey(key string) (LSN, int, error) {
+	sep := strings.LastIndex(key, ":")
+	if sep < 0 {
+		return 0, 0, fmt.Errorf("invalid cdc entry key: '%s'", key)
+	}
+
+	lsn, err := ParseLSN(key[:sep])
+	if err != nil {
+		return 0, 0, err
+	}
+
+	idx, err := strconv.Atoi(key[sep+1:])
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid cdc entry key: '%s'", key)
+	}
+	return lsn, idx, nil
+}
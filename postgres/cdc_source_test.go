@@ -0,0 +1,164 @@
+package postgres
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	streams "github.com/matang28/go-streams"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseLSN(t *testing.T) {
+	lsn, err := ParseLSN("16/B374D848")
+	assert.Nil(t, err)
+	assert.EqualValues(t, uint64(0x16B374D848), lsn)
+	assert.EqualValues(t, "16/B374D848", lsn.String())
+
+	_, err = ParseLSN("16B374D848")
+	assert.NotNil(t, err)
+}
+
+func TestCdcSource_Wal2Json(t *testing.T) {
+	conn := newFakeConn(
+		&Message{WALStart: 10, Data: []byte(`{"xid":1,"change":[` +
+			`{"kind":"insert","schema":"public","table":"users","columnnames":["id","name"],"columnvalues":[1,"john"]},` +
+			`{"kind":"update","schema":"public","table":"users","columnnames":["id","name"],"columnvalues":[1,"jane"],"oldkeys":{"keynames":["id"],"keyvalues":[1]}}]}`)},
+		&Message{WALStart: 20, Data: []byte(`{"action":"B","xid":2}`)},
+		&Message{WALStart: 30, Data: []byte(`{"action":"D","xid":2,"schema":"public","table":"users","identity":[{"name":"id","value":1}]}`)},
+	)
+	source := NewCdcSource("cdc", conn, NewWal2JsonDecoder())
+	sink := streams.NewArraySink()
+
+	go func() {
+		conn.waitDrained()
+		time.Sleep(50 * time.Millisecond)
+		assert.Nil(t, source.Stop())
+	}()
+
+	streams.NewStream(source).Sink(sink).Process(streams.NewDirectProcessor(), make(streams.ErrorChannel, 10))
+
+	changes := sink.Array()
+	assert.EqualValues(t, 3, len(changes))
+	assert.EqualValues(t, Insert, changes[0].(*Change).Operation)
+	assert.EqualValues(t, "john", changes[0].(*Change).Columns["name"])
+	assert.EqualValues(t, Update, changes[1].(*Change).Operation)
+	assert.EqualValues(t, 1, changes[1].(*Change).OldKeys["id"])
+	assert.EqualValues(t, Delete, changes[2].(*Change).Operation)
+	assert.EqualValues(t, "users", changes[2].(*Change).Table)
+	assert.EqualValues(t, LSN(30), source.FlushedLSN())
+	assert.EqualValues(t, LSN(30), conn.lastFlush())
+}
+
+func TestCdcSource_CommitEntry_IsCumulative(t *testing.T) {
+	conn := newFakeConn()
+	source := NewCdcSource("cdc", conn, NewWal2JsonDecoder())
+	source.track(10, 2)
+	source.track(20, 0)
+	source.track(30, 1)
+
+	assert.Nil(t, source.CommitEntry("0/A:0"))
+	assert.EqualValues(t, LSN(0), source.FlushedLSN())
+
+	assert.Nil(t, source.CommitEntry("0/A:1"))
+	assert.EqualValues(t, LSN(20), source.FlushedLSN())
+
+	assert.Nil(t, source.CommitEntry("0/1E:0"))
+	assert.EqualValues(t, LSN(30), source.FlushedLSN())
+
+	assert.NotNil(t, source.CommitEntry("invalid"))
+}
+
+func TestCdcSource_Metadata(t *testing.T) {
+	entry := newEntry(Change{LSN: 30, Xid: 7, Schema: "public", Table: "users", Operation: Delete}, 2)
+	assert.EqualValues(t, "0/1E:2", entry.Key)
+	assert.EqualValues(t, "users", entry.Metadata[MetadataTable])
+	assert.EqualValues(t, "delete", entry.Metadata[MetadataOperation])
+	assert.EqualValues(t, "7", entry.Metadata[MetadataXid])
+}
+
+func TestCdcSource_Receive_BacksOffOnErrors(t *testing.T) {
+	defer func(initial time.Duration) { receiveBackoff = initial }(receiveBackoff)
+	receiveBackoff = 20 * time.Millisecond
+
+	source := NewCdcSource("cdc", &failingConn{}, NewWal2JsonDecoder())
+	errs := make(streams.ErrorChannel, 1000)
+	done := make(chan bool)
+	go func() {
+		source.Start(make(streams.EntryChannel), errs)
+		done <- true
+	}()
+
+	time.Sleep(200 * time.Millisecond)
+	assert.Nil(t, source.Stop())
+	assert.Nil(t, source.Stop())
+	<-done
+
+	// 20ms, 40ms, 80ms... only a handful of attempts fit in 200ms
+	assert.True(t, len(errs) <= 6, "got %d errors", len(errs))
+}
+
+type failingConn struct{}
+
+func (c *failingConn) Receive() (*Message, error) {
+	return nil, errors.New("connection reset")
+}
+
+func (c *failingConn) Flush(lsn LSN) error { return nil }
+
+func (c *failingConn) Ping() error { return nil }
+
+func (c *failingConn) Close() error { return nil }
+
+type fakeConn struct {
+	messages chan *Message
+	closeCh  chan bool
+	mutex    *sync.Mutex
+	flushed  LSN
+}
+
+func newFakeConn(messages ...*Message) *fakeConn {
+	conn := &fakeConn{messages: make(chan *Message, len(messages)), closeCh: make(chan bool), mutex: &sync.Mutex{}}
+	for _, m := range messages {
+		conn.messages <- m
+	}
+	return conn
+}
+
+func (c *fakeConn) waitDrained() {
+	for len(c.messages) > 0 {
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func (c *fakeConn) Receive() (*Message, error) {
+	select {
+	case m := <-c.messages:
+		return m, nil
+	case <-c.closeCh:
+		return nil, errors.New("closed")
+	}
+}
+
+func (c *fakeConn) Flush(lsn LSN) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.flushed = lsn
+	return nil
+}
+
+func (c *fakeConn) lastFlush() LSN {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.flushed
+}
+
+func (c *fakeConn) Ping() error {
+	return nil
+}
+
+func (c *fakeConn) Close() error {
+	close(c.closeCh)
+	return nil
+}
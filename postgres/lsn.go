@@ -0,0 +1,34 @@
+package postgres
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// LSN is a position in the PostgreSQL write-ahead log.
+type LSN uint64
+
+// ParseLSN parses the textual representation of an LSN (e.g: "16/B374D848").
+func ParseLSN(str string) (LSN, error) {
+	parts := strings.Split(str, "/")
+	if len(parts) != 2 {
+		return 0, fmt.Errorf("invalid LSN: '%s'", str)
+	}
+
+	hi, err := strconv.ParseUint(parts[0], 16, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid LSN: '%s': %s", str, err.Error())
+	}
+
+	lo, err := strconv.ParseUint(parts[1], 16, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid LSN: '%s': %s", str, err.Error())
+	}
+
+	return LSN(hi<<32 | lo), nil
+}
+
+func (l LSN) String() string {
+	return fmt.Sprintf("%X/%X", uint32(l>>32), uint32(l))
+}
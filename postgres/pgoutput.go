@@ -0,0 +1,190 @@
+package postgres
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+)
+
+type relation struct {
+	schema  string
+	table   string
+	columns []string
+}
+
+type pgOutputDecoder struct {
+	relations map[uint32]relation
+	xid       uint32
+}
+
+// NewPgOutputDecoder creates a decoder for the binary pgoutput plugin (protocol version 1),
+// column values are reported in their text representation.
+// The decoder is stateful (it caches relation messages) and shouldn't be shared between sources.
+func NewPgOutputDecoder() Decoder {
+	return &pgOutputDecoder{relations: make(map[uint32]relation)}
+}
+
+func (this *pgOutputDecoder) Decode(msg *Message) ([]Change, error) {
+	if len(msg.Data) == 0 {
+		return nil, nil
+	}
+
+	r := &pgReader{buf: bytes.NewBuffer(msg.Data[1:])}
+	var change *Change
+
+	switch msg.Data[0] {
+	case 'B':
+		r.uint64() // final LSN
+		r.uint64() // commit timestamp
+		this.xid = r.uint32()
+
+	case 'R':
+		relId := r.uint32()
+		rel := relation{schema: r.string(), table: r.string()}
+		r.byte() // replica identity
+		columns := int(r.uint16())
+		for i := 0; i < columns; i++ {
+			r.byte() // flags
+			rel.columns = append(rel.columns, r.string())
+			r.uint32() // type oid
+			r.uint32() // type modifier
+		}
+		this.relations[relId] = rel
+
+	case 'I':
+		rel, err := this.relation(r.uint32())
+		if err != nil {
+			return nil, err
+		}
+		r.byte() // 'N'
+		change = this.newChange(msg, rel, Insert)
+		change.Columns = r.tuple(rel)
+
+	case 'U':
+		rel, err := this.relation(r.uint32())
+		if err != nil {
+			return nil, err
+		}
+		change = this.newChange(msg, rel, Update)
+		kind := r.byte()
+		if kind == 'K' || kind == 'O' {
+			change.OldKeys = r.tuple(rel)
+			kind = r.byte()
+		}
+		if kind != 'N' {
+			return nil, fmt.Errorf("malformed pgoutput update message at %s", msg.WALStart)
+		}
+		change.Columns = r.tuple(rel)
+
+	case 'D':
+		rel, err := this.relation(r.uint32())
+		if err != nil {
+			return nil, err
+		}
+		r.byte() // 'K' or 'O'
+		change = this.newChange(msg, rel, Delete)
+		change.OldKeys = r.tuple(rel)
+
+	default:
+		// Commit, origin, type and truncate messages doesn't produce changes
+		return nil, nil
+	}
+
+	if r.err != nil {
+		return nil, fmt.Errorf("malformed pgoutput message at %s: %s", msg.WALStart, r.err.Error())
+	}
+
+	if change == nil {
+		return nil, nil
+	}
+	return []Change{*change}, nil
+}
+
+func (this *pgOutputDecoder) relation(id uint32) (relation, error) {
+	rel, found := this.relations[id]
+	if !found {
+		return rel, fmt.Errorf("pgoutput change references unknown relation: %d", id)
+	}
+	return rel, nil
+}
+
+func (this *pgOutputDecoder) newChange(msg *Message, rel relation, op Operation) *Change {
+	return &Change{
+		LSN:       msg.WALStart,
+		Xid:       this.xid,
+		Schema:    rel.schema,
+		Table:     rel.table,
+		Operation: op,
+	}
+}
+
+// pgReader reads big endian values from a pgoutput message,
+// the first error is kept and all subsequent reads are ignored.
+type pgReader struct {
+	buf *bytes.Buffer
+	err error
+}
+
+func (r *pgReader) read(n int) []byte {
+	if r.err != nil {
+		return make([]byte, n)
+	}
+	b := r.buf.Next(n)
+	if len(b) < n {
+		r.err = fmt.Errorf("unexpected end of message")
+		return make([]byte, n)
+	}
+	return b
+}
+
+func (r *pgReader) byte() byte {
+	return r.read(1)[0]
+}
+
+func (r *pgReader) uint16() uint16 {
+	return binary.BigEndian.Uint16(r.read(2))
+}
+
+func (r *pgReader) uint32() uint32 {
+	return binary.BigEndian.Uint32(r.read(4))
+}
+
+func (r *pgReader) uint64() uint64 {
+	return binary.BigEndian.Uint64(r.read(8))
+}
+
+func (r *pgReader) string() string {
+	if r.err != nil {
+		return ""
+	}
+	s, err := r.buf.ReadString(0)
+	if err != nil {
+		r.err = fmt.Errorf("unterminated string")
+		return ""
+	}
+	return s[:len(s)-1]
+}
+
+func (r *pgReader) tuple(rel relation) map[string]interface{} {
+	columns := int(r.uint16())
+	out := make(map[string]interface{}, columns)
+	for i := 0; i < columns && r.err == nil; i++ {
+		var name string
+		if i < len(rel.columns) {
+			name = rel.columns[i]
+		} else {
+			name = fmt.Sprintf("column_%d", i)
+		}
+
+		switch r.byte() {
+		case 'n':
+			out[name] = nil
+		case 'u':
+			// unchanged toasted value, the actual value isn't sent
+		case 't', 'b':
+			size := int(r.uint32())
+			out[name] = string(r.read(size))
+		}
+	}
+	return out
+}
@@ -0,0 +1,103 @@
+package postgres
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPgOutputDecoder_Decode(t *testing.T) {
+	decoder := NewPgOutputDecoder()
+
+	begin := &pgWriter{}
+	begin.byte('B').uint64(100).uint64(0).uint32(42)
+	changes, err := decoder.Decode(&Message{WALStart: 1, Data: begin.Bytes()})
+	assert.Nil(t, err)
+	assert.Empty(t, changes)
+
+	rel := &pgWriter{}
+	rel.byte('R').uint32(7).string("public").string("users").byte('d').uint16(2)
+	rel.byte(1).string("id").uint32(23).uint32(0)
+	rel.byte(0).string("name").uint32(25).uint32(0)
+	changes, err = decoder.Decode(&Message{WALStart: 2, Data: rel.Bytes()})
+	assert.Nil(t, err)
+	assert.Empty(t, changes)
+
+	insert := &pgWriter{}
+	insert.byte('I').uint32(7).byte('N').uint16(2).text("1").byte('n')
+	changes, err = decoder.Decode(&Message{WALStart: 3, Data: insert.Bytes()})
+	assert.Nil(t, err)
+	assert.EqualValues(t, 1, len(changes))
+	assert.EqualValues(t, Insert, changes[0].Operation)
+	assert.EqualValues(t, "users", changes[0].Table)
+	assert.EqualValues(t, "public", changes[0].Schema)
+	assert.EqualValues(t, 42, changes[0].Xid)
+	assert.EqualValues(t, map[string]interface{}{"id": "1", "name": nil}, changes[0].Columns)
+
+	update := &pgWriter{}
+	update.byte('U').uint32(7).byte('K').uint16(2).text("1").byte('n').byte('N').uint16(2).text("2").text("john")
+	changes, err = decoder.Decode(&Message{WALStart: 4, Data: update.Bytes()})
+	assert.Nil(t, err)
+	assert.EqualValues(t, Update, changes[0].Operation)
+	assert.EqualValues(t, "1", changes[0].OldKeys["id"])
+	assert.EqualValues(t, "john", changes[0].Columns["name"])
+
+	del := &pgWriter{}
+	del.byte('D').uint32(7).byte('K').uint16(2).text("2").byte('n')
+	changes, err = decoder.Decode(&Message{WALStart: 5, Data: del.Bytes()})
+	assert.Nil(t, err)
+	assert.EqualValues(t, Delete, changes[0].Operation)
+	assert.EqualValues(t, "2", changes[0].OldKeys["id"])
+}
+
+func TestPgOutputDecoder_Errors(t *testing.T) {
+	decoder := NewPgOutputDecoder()
+
+	unknown := &pgWriter{}
+	unknown.byte('I').uint32(99).byte('N').uint16(0)
+	_, err := decoder.Decode(&Message{Data: unknown.Bytes()})
+	assert.NotNil(t, err)
+
+	truncated := &pgWriter{}
+	truncated.byte('R').uint32(7).string("public")
+	_, err = decoder.Decode(&Message{Data: truncated.Bytes()})
+	assert.NotNil(t, err)
+}
+
+type pgWriter struct {
+	bytes.Buffer
+}
+
+func (w *pgWriter) byte(b byte) *pgWriter {
+	w.WriteByte(b)
+	return w
+}
+
+func (w *pgWriter) uint16(v uint16) *pgWriter {
+	_ = binary.Write(w, binary.BigEndian, v)
+	return w
+}
+
+func (w *pgWriter) uint32(v uint32) *pgWriter {
+	_ = binary.Write(w, binary.BigEndian, v)
+	return w
+}
+
+func (w *pgWriter) uint64(v uint64) *pgWriter {
+	_ = binary.Write(w, binary.BigEndian, v)
+	return w
+}
+
+func (w *pgWriter) string(s string) *pgWriter {
+	w.WriteString(s)
+	w.WriteByte(0)
+	return w
+}
+
+func (w *pgWriter) text(s string) *pgWriter {
+	w.byte('t').uint32(uint32(len(s)))
+	w.WriteString(s)
+	return w
+}
@@ -0,0 +1,153 @@
+package postgres
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+type wal2JsonDecoder struct{}
+
+// NewWal2JsonDecoder creates a decoder for the wal2json output plugin,
+// both format-version 1 (a message per transaction) and format-version 2
+// (a message per change) are supported.
+func NewWal2JsonDecoder() Decoder {
+	return &wal2JsonDecoder{}
+}
+
+type wal2JsonV1Message struct {
+	Xid     uint32             `json:"xid"`
+	Changes []wal2JsonV1Change `json:"change"`
+}
+
+type wal2JsonV1Change struct {
+	Kind         string        `json:"kind"`
+	Schema       string        `json:"schema"`
+	Table        string        `json:"table"`
+	ColumnNames  []string      `json:"columnnames"`
+	ColumnValues []interface{} `json:"columnvalues"`
+	OldKeys      struct {
+		KeyNames  []string      `json:"keynames"`
+		KeyValues []interface{} `json:"keyvalues"`
+	} `json:"oldkeys"`
+}
+
+type wal2JsonV2Message struct {
+	Action   string             `json:"action"`
+	Xid      uint32             `json:"xid"`
+	Schema   string             `json:"schema"`
+	Table    string             `json:"table"`
+	Columns  []wal2JsonV2Column `json:"columns"`
+	Identity []wal2JsonV2Column `json:"identity"`
+}
+
+type wal2JsonV2Column struct {
+	Name  string      `json:"name"`
+	Value interface{} `json:"value"`
+}
+
+func (this *wal2JsonDecoder) Decode(msg *Message) ([]Change, error) {
+	var probe map[string]json.RawMessage
+	if err := json.Unmarshal(msg.Data, &probe); err != nil {
+		return nil, fmt.Errorf("failed to decode wal2json message at %s: %s", msg.WALStart, err.Error())
+	}
+
+	if _, found := probe["action"]; found {
+		return this.decodeV2(msg)
+	}
+	return this.decodeV1(msg)
+}
+
+func (this *wal2JsonDecoder) decodeV1(msg *Message) ([]Change, error) {
+	var m wal2JsonV1Message
+	if err := json.Unmarshal(msg.Data, &m); err != nil {
+		return nil, fmt.Errorf("failed to decode wal2json message at %s: %s", msg.WALStart, err.Error())
+	}
+
+	out := make([]Change, 0, len(m.Changes))
+	for idx := range m.Changes {
+		c := m.Changes[idx]
+		op, err := wal2JsonOperation(c.Kind)
+		if err != nil {
+			return nil, err
+		}
+		if op == "" {
+			continue
+		}
+		out = append(out, Change{
+			LSN:       msg.WALStart,
+			Xid:       m.Xid,
+			Schema:    c.Schema,
+			Table:     c.Table,
+			Operation: op,
+			Columns:   zip(c.ColumnNames, c.ColumnValues),
+			OldKeys:   zip(c.OldKeys.KeyNames, c.OldKeys.KeyValues),
+		})
+	}
+	return out, nil
+}
+
+func (this *wal2JsonDecoder) decodeV2(msg *Message) ([]Change, error) {
+	var m wal2JsonV2Message
+	if err := json.Unmarshal(msg.Data, &m); err != nil {
+		return nil, fmt.Errorf("failed to decode wal2json message at %s: %s", msg.WALStart, err.Error())
+	}
+
+	op, err := wal2JsonOperation(m.Action)
+	if err != nil {
+		return nil, err
+	}
+	if op == "" {
+		return nil, nil
+	}
+
+	return []Change{{
+		LSN:       msg.WALStart,
+		Xid:       m.Xid,
+		Schema:    m.Schema,
+		Table:     m.Table,
+		Operation: op,
+		Columns:   columnsToMap(m.Columns),
+		OldKeys:   columnsToMap(m.Identity),
+	}}, nil
+}
+
+// wal2JsonOperation maps wal2json kinds/actions to operations,
+// an empty operation is returned for messages that should be skipped (e.g: begin, commit).
+func wal2JsonOperation(kind string) (Operation, error) {
+	switch kind {
+	case "insert", "I":
+		return Insert, nil
+	case "update", "U":
+		return Update, nil
+	case "delete", "D":
+		return Delete, nil
+	case "message", "truncate", "B", "C", "M", "T":
+		return "", nil
+	default:
+		return "", fmt.Errorf("unknown wal2json change kind: '%s'", kind)
+	}
+}
+
+func zip(names []string, values []interface{}) map[string]interface{} {
+	if len(names) == 0 {
+		return nil
+	}
+	out := make(map[string]interface{}, len(names))
+	for idx := range names {
+		if idx < len(values) {
+			out[names[idx]] = values[idx]
+		}
+	}
+	return out
+}
+
+func columnsToMap(columns []wal2JsonV2Column) map[string]interface{} {
+	if len(columns) == 0 {
+		return nil
+	}
+	out := make(map[string]interface{}, len(columns))
+	for idx := range columns {
+		out[columns[idx].Name] = columns[idx].Value
+	}
+	return out
+}
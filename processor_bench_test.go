@@ -0,0 +1,117 @@
+package go_streams
+
+import (
+	"strconv"
+	"testing"
+)
+
+// benchSource sends n entries as fast as the processor takes them
+type benchSource struct {
+	entries []Entry
+}
+
+func newBenchSource(n int) *benchSource {
+	entries := make([]Entry, n)
+	for idx := range entries {
+		entries[idx] = Entry{Key: strconv.Itoa(idx), Value: idx}
+	}
+	return &benchSource{entries: entries}
+}
+
+func (this *benchSource) Start(channel EntryChannel, errorChannel ErrorChannel) {
+	for idx := range this.entries {
+		channel <- this.entries[idx]
+	}
+	close(channel)
+	errorChannel <- NewEofError(this)
+}
+
+func (this *benchSource) Stop() error                      { return nil }
+func (this *benchSource) Ping() error                      { return nil }
+func (this *benchSource) CommitEntry(keys ...string) error { return nil }
+func (this *benchSource) Name() string                     { return "bench" }
+
+type discardSink struct {
+	count int
+}
+
+func (this *discardSink) Single(entry Entry) error {
+	this.count++
+	return nil
+}
+
+func (this *discardSink) Batch(entry ...Entry) error {
+	this.count += len(entry)
+	return nil
+}
+
+func (this *discardSink) Ping() error { return nil }
+
+func benchmarkProcessor(b *testing.B, factory ProcessorFactory, build func(Stream) Stream) {
+	const entries = 10000
+	SetLogLevel(Error)
+	defer SetLogLevel(Debug)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		source := newBenchSource(entries)
+		sink := &discardSink{}
+		stream := build(NewStream(source)).Sink(sink)
+		errs := make(ErrorChannel, 10)
+		b.StartTimer()
+
+		stream.Process(factory(), errs)
+		if sink.count == 0 {
+			b.Fatal("nothing was written")
+		}
+	}
+}
+
+func mapFilter(stream Stream) Stream {
+	return stream.Map(func(value interface{}) interface{} {
+		return value.(int) + 1
+	}).Filter(func(value interface{}) bool {
+		return value.(int)%2 == 0
+	})
+}
+
+func BenchmarkDirectProcessor(b *testing.B) {
+	benchmarkProcessor(b, NewDirectProcessorFactory(), mapFilter)
+}
+
+func BenchmarkBufferedProcessor(b *testing.B) {
+	benchmarkProcessor(b, NewBufferedProcessorFactory(100, 0), mapFilter)
+}
+
+func BenchmarkBufferedProcessor_Operator(b *testing.B) {
+	benchmarkProcessor(b, NewBufferedProcessorFactory(100, 0), func(stream Stream) Stream {
+		return mapFilter(stream).MapOutputs(func(value interface{}, outputs Outputs) (interface{}, bool) {
+			return value, true
+		})
+	})
+}
+
+func bytesValues(stream Stream) Stream {
+	return stream.Map(func(value interface{}) interface{} {
+		return []byte(strconv.Itoa(value.(int)))
+	})
+}
+
+func BenchmarkBufferedProcessor_Map(b *testing.B) {
+	benchmarkProcessor(b, NewBufferedProcessorFactory(100, 0), func(stream Stream) Stream {
+		return bytesValues(stream).Map(func(value interface{}) interface{} {
+			out := value.([]byte)
+			out[0] = 'x'
+			return out
+		})
+	})
+}
+
+func BenchmarkBufferedProcessor_MapBytes(b *testing.B) {
+	benchmarkProcessor(b, NewBufferedProcessorFactory(100, 0), func(stream Stream) Stream {
+		return bytesValues(stream).MapBytes(func(value []byte) []byte {
+			value[0] = 'x'
+			return value
+		})
+	})
+}
@@ -0,0 +1,86 @@
+package go_streams
+
+import "sort"
+
+// Progress is the position of a source in its input, e.g: the offset of a partition and its latest offset,
+// the bytes read from a file and its size or the entries dequeued and enqueued.
+type Progress struct {
+	// Position is the position of the next entry the source will emit
+	Position uint64 `json:"position"`
+
+	// End is the position after the latest entry of the input, 0 when it isn't known
+	End uint64 `json:"end,omitempty"`
+
+	// Unit of the positions, e.g: "offsets", "bytes" or "entries"
+	Unit string `json:"unit,omitempty"`
+}
+
+// Lag returns the distance between the position and the end, 0 when the end isn't known
+func (this Progress) Lag() uint64 {
+	if this.End > this.Position {
+		return this.End - this.Position
+	}
+	return 0
+}
+
+// ProgressReporter is implemented by the sources that know their position in their input (see Engine.Progress)
+type ProgressReporter interface {
+	Progress() (Progress, error)
+}
+
+// StreamProgress is the progress of a stream's source
+type StreamProgress struct {
+	Name   string       `json:"name"`
+	Status StreamStatus `json:"status"`
+
+	// Source is the progress reported by the source, nil when it isn't a ProgressReporter
+	Source *Progress `json:"source,omitempty"`
+
+	// Lag is the lag reported by the source, it is the pending entries when the source doesn't know its end
+	Lag uint64 `json:"lag"`
+
+	// Pending is the number of entries received from the source but not committed yet
+	Pending uint64 `json:"pending"`
+
+	// Error is the reason the source failed to report its progress
+	Error string `json:"error,omitempty"`
+}
+
+// Progress returns the progress of every stream sorted by name
+func (this *engine) Progress() []StreamProgress {
+	infos := this.Streams()
+
+	this.mutex.RLock()
+	sources := make(map[string]Source, len(this.streams))
+	for name, s := range this.streams {
+		sources[name] = s.stream.GetSource()
+	}
+	this.mutex.RUnlock()
+
+	progress := make([]StreamProgress, 0, len(infos))
+	for _, info := range infos {
+		source, found := sources[info.Name]
+		if !found {
+			continue
+		}
+		sp := StreamProgress{Name: info.Name, Status: info.Status}
+		if info.Received > info.Committed {
+			sp.Pending = info.Received - info.Committed
+		}
+		sp.Lag = sp.Pending
+
+		if reporter, ok := unwrapSource(source).(ProgressReporter); ok {
+			if p, err := reporter.Progress(); err != nil {
+				sp.Error = err.Error()
+			} else {
+				sp.Source = &p
+				if p.End > 0 {
+					sp.Lag = p.Lag()
+				}
+			}
+		}
+		progress = append(progress, sp)
+	}
+	sort.Slice(progress, func(i, j int) bool { return progress[i].Name < progress[j].Name })
+	return progress
+}
@@ -0,0 +1,38 @@
+package go_streams
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEngine_Progress(t *testing.T) {
+	engine := NewEngine(NewDirectProcessorFactory(), 10*time.Second)
+	queue := NewAppendSource(10)
+	queue.Append("a", 1)
+	queue.Append("b", 2)
+	assert.Nil(t, engine.Add(NewStream(queue).Sink(NewArraySink())))
+	assert.Nil(t, engine.Add(NewStream(&flakySource{name: "unreported"}).Sink(NewArraySink())))
+
+	progress := engine.Progress()
+	assert.Len(t, progress, 2)
+	assert.EqualValues(t, queue.Name(), progress[0].Name)
+	assert.EqualValues(t, &Progress{Position: 0, End: 2, Unit: "entries"}, progress[0].Source)
+	assert.EqualValues(t, 2, progress[0].Lag)
+
+	assert.EqualValues(t, "unreported", progress[1].Name)
+	assert.Nil(t, progress[1].Source)
+	assert.EqualValues(t, 0, progress[1].Lag)
+}
+
+func TestSequentialIntegerSource_Progress(t *testing.T) {
+	source := NewSequentialIntegerSource(4, 0)
+	channel := make(EntryChannel, 10)
+	source.Start(channel, make(ErrorChannel, 10))
+
+	progress, err := source.(ProgressReporter).Progress()
+	assert.Nil(t, err)
+	assert.EqualValues(t, Progress{Position: 5, End: 5, Unit: "entries"}, progress)
+	assert.EqualValues(t, 0, progress.Lag())
+}
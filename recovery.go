@@ -1,67 +1,111 @@
 package go_streams
 
-import "fmt"
+import (
+	"errors"
+	"runtime/debug"
+)
 
 func RecoverFilter(filterFunc FilterFunc, entry Entry, errs ErrorChannel) bool {
+	keep, err := recoverFilter(filterFunc, entry)
+	if err != nil {
+		errs <- err
+	}
+	return keep
+}
+
+func RecoverMap(mapFunc MapFunc, entry Entry, errs ErrorChannel) interface{} {
+	value, err := recoverMap(mapFunc, entry)
+	if err != nil {
+		errs <- err
+	}
+	return value
+}
+
+func RecoverSinkSingle(sink Sink, entry Entry, errs ErrorChannel) error {
+	panicked, err := recoverSinkSingle(sink, entry)
+	if panicked {
+		errs <- err
+		return nil
+	}
+	return err
+}
+
+func RecoverSinkBatch(sink Sink, entry []Entry, errs ErrorChannel) error {
+	panicked, err := recoverSinkBatch(sink, entry)
+	if panicked {
+		errs <- err
+		return nil
+	}
+	return err
+}
+
+// recoverFilter runs the filter and returns the recovered panic (if any) as a FilterError
+func recoverFilter(filterFunc FilterFunc, entry Entry) (keep bool, err error) {
 	defer func() {
 		if p := recover(); p != nil {
-			logger.Debug("Recovering from panic in filter step for entry: %+v", entry)
-			err, ok := p.(error)
-			if ok {
-				errs <- NewFilterError(err)
-			} else {
-				errs <- NewFilterError(fmt.Errorf("%v", p))
-			}
+			LogWith(Fields{FieldStage: FilterStage, FieldKey: entry.Key}).Debug("Recovering from panic in filter step for entry: %+v", entry)
+			err = NewFilterError(panicError(p))
 		}
 	}()
 
-	return filterFunc(entry.Value)
+	return filterFunc(entry.Value), nil
 }
 
-func RecoverMap(mapFunc MapFunc, entry Entry, errs ErrorChannel) interface{} {
+// recoverMap runs the map and returns the recovered panic (if any) as a MapError
+func recoverMap(mapFunc MapFunc, entry Entry) (value interface{}, err error) {
 	defer func() {
 		if p := recover(); p != nil {
-			logger.Debug("Recovering from panic in map step for entry: %+v", entry)
-			err, ok := p.(error)
-			if ok {
-				errs <- NewMapError(err)
-			} else {
-				errs <- NewMapError(fmt.Errorf("%v", p))
-			}
+			LogWith(Fields{FieldStage: MapStage, FieldKey: entry.Key}).Debug("Recovering from panic in map step for entry: %+v", entry)
+			err = NewMapError(panicError(p))
 		}
 	}()
 
-	return mapFunc(entry.Value)
+	return mapFunc(entry.Value), nil
 }
 
-func RecoverSinkSingle(sink Sink, entry Entry, errs ErrorChannel) error {
+// recoverOperator applies the operator and returns the recovered panic (if any) as an OperatorError
+func recoverOperator(operator Operator, entry Entry) (entries []Entry, err error) {
 	defer func() {
 		if p := recover(); p != nil {
-			logger.Debug("Recovering from panic in sink (single) step for entry: %+v", entry)
-			err, ok := p.(error)
-			if ok {
-				errs <- NewSinkError(err)
-			} else {
-				errs <- NewSinkError(fmt.Errorf("%v", p))
-			}
+			LogWith(Fields{FieldStage: OperatorStage, FieldKey: entry.Key}).Debug("Recovering from panic in operator step for entry: %+v", entry)
+			entries, err = nil, NewOperatorError(panicError(p))
 		}
 	}()
 
-	return sink.Single(entry)
+	return operator.Apply(entry)
 }
 
-func RecoverSinkBatch(sink Sink, entry []Entry, errs ErrorChannel) error {
+// recoverSinkSingle dumps the entry and returns the sink error or the recovered panic as a SinkError
+func recoverSinkSingle(sink Sink, entry Entry) (panicked bool, err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			LogWith(Fields{FieldStage: SinkStage, FieldKey: entry.Key}).Debug("Recovering from panic in sink (single) step for entry: %+v", entry)
+			panicked, err = true, NewSinkError(panicError(p))
+		}
+	}()
+
+	return false, sink.Single(entry)
+}
+
+// recoverSinkBatch dumps the entries and returns the sink error or the recovered panic as a SinkError
+func recoverSinkBatch(sink Sink, entry []Entry) (panicked bool, err error) {
 	defer func() {
 		if p := recover(); p != nil {
-			logger.Debug("Recovering from panic in sink (batch) step for entry: %+v", entry)
-			err, ok := p.(error)
-			if ok {
-				errs <- NewSinkError(err)
-			} else {
-				errs <- NewSinkError(fmt.Errorf("%v", p))
-			}
+			LogWith(Fields{FieldStage: SinkStage}).Debug("Recovering from panic in sink (batch) step for entry: %+v", entry)
+			panicked, err = true, NewSinkError(panicError(p))
 		}
 	}()
 
-	return sink.Batch(entry...)
+	return false, sink.Batch(entry...)
+}
+
+// panicError wraps the recovered panic with the stack trace, it should be called by the deferred function
+func panicError(p interface{}) error {
+	return &PanicError{Value: p, Stack: debug.Stack()}
+}
+
+// isPanic tells whether the error is a recovered panic
+func isPanic(err error) bool {
+	var pe *PanicError
+	return errors.As(err, &pe)
 }
@@ -1,6 +1,8 @@
 package go_streams
 
 import (
+	"errors"
+	"fmt"
 	"github.com/stretchr/testify/assert"
 	"testing"
 )
@@ -63,3 +65,15 @@ func (s *panicSink) Batch(entry ...Entry) error {
 func (this *panicSink) Ping() error {
 	return nil
 }
+
+func TestRecoverMap_PanicErrorHasStack(t *testing.T) {
+	_, err := recoverMap(func(entry interface{}) interface{} {
+		panic(fmt.Errorf("map error"))
+	}, Entry{})
+
+	var pe *PanicError
+	assert.True(t, errors.As(err, &pe))
+	assert.EqualValues(t, "map error", pe.Error())
+	assert.EqualValues(t, "map error", errors.Unwrap(pe).Error())
+	assert.Contains(t, string(pe.Stack), "TestRecoverMap_PanicErrorHasStack")
+}
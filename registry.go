@@ -0,0 +1,377 @@
+package go_streams
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Params are the parameters of a component in a pipeline configuration
+type Params map[string]interface{}
+
+// String returns the string parameter or the default value when it isn't set
+func (this Params) String(name string, def string) (string, error) {
+	value, found := this[name]
+	if !found {
+		return def, nil
+	}
+	s, ok := value.(string)
+	if !ok {
+		return "", fmt.Errorf("parameter '%s' should be a string, got: %v", name, value)
+	}
+	return s, nil
+}
+
+// Int returns the integer parameter or the default value when it isn't set
+func (this Params) Int(name string, def int) (int, error) {
+	value, found := this[name]
+	if !found {
+		return def, nil
+	}
+	switch v := value.(type) {
+	case int:
+		return v, nil
+	case int64:
+		return int(v), nil
+	case float64:
+		if v == float64(int(v)) {
+			return int(v), nil
+		}
+	}
+	return 0, fmt.Errorf("parameter '%s' should be an integer, got: %v", name, value)
+}
+
+// Float returns the number parameter or the default value when it isn't set
+func (this Params) Float(name string, def float64) (float64, error) {
+	value, found := this[name]
+	if !found {
+		return def, nil
+	}
+	switch v := value.(type) {
+	case float64:
+		return v, nil
+	case int:
+		return float64(v), nil
+	case int64:
+		return float64(v), nil
+	}
+	return 0, fmt.Errorf("parameter '%s' should be a number, got: %v", name, value)
+}
+
+// Bool returns the boolean parameter or the default value when it isn't set
+func (this Params) Bool(name string, def bool) (bool, error) {
+	value, found := this[name]
+	if !found {
+		return def, nil
+	}
+	b, ok := value.(bool)
+	if !ok {
+		return false, fmt.Errorf("parameter '%s' should be a boolean, got: %v", name, value)
+	}
+	return b, nil
+}
+
+// Duration returns the duration parameter (e.g: "1s", "250ms") or the default value when it isn't set
+func (this Params) Duration(name string, def time.Duration) (time.Duration, error) {
+	value, found := this[name]
+	if !found {
+		return def, nil
+	}
+	s, ok := value.(string)
+	if !ok {
+		return 0, fmt.Errorf("parameter '%s' should be a duration, got: %v", name, value)
+	}
+	return time.ParseDuration(s)
+}
+
+type SourceFactory func(params Params) (Source, error)
+type SinkFactory func(params Params) (Sink, error)
+type OperatorFactory func(params Params, registry *Registry) (Operator, error)
+type ProcessorFactoryBuilder func(params Params) (ProcessorFactory, error)
+
+// Registry holds the components that pipeline configurations refer to by name
+type Registry struct {
+	mutex      *sync.RWMutex
+	sources    map[string]SourceFactory
+	sinks      map[string]SinkFactory
+	operators  map[string]OperatorFactory
+	processors map[string]ProcessorFactoryBuilder
+	filters    map[string]FilterFunc
+	maps       map[string]MapFunc
+	keys       map[string]KeyFunc
+}
+
+// NewRegistry creates a registry with the built-in components:
+//
+//	sources:    sequential (limit, delay)
+//	sinks:      console
+//	operators:  throttle (n, per, key), debounce (duration, key), dedupe (key, ttl, maxKeys),
+//	            take (n), skip (n), takeFor (duration), sample (rate, key), sampleEvery (n)
+//	processors: direct, buffered (size, timeout), sharded (shards, key)
+func NewRegistry() *Registry {
+	registry := &Registry{
+		mutex:      &sync.RWMutex{},
+		sources:    make(map[string]SourceFactory),
+		sinks:      make(map[string]SinkFactory),
+		operators:  make(map[string]OperatorFactory),
+		processors: make(map[string]ProcessorFactoryBuilder),
+		filters:    make(map[string]FilterFunc),
+		maps:       make(map[string]MapFunc),
+		keys:       make(map[string]KeyFunc),
+	}
+	registerBuiltins(registry)
+	return registry
+}
+
+func (this *Registry) RegisterSource(name string, factory SourceFactory) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.sources[name] = factory
+}
+
+func (this *Registry) RegisterSink(name string, factory SinkFactory) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.sinks[name] = factory
+}
+
+func (this *Registry) RegisterOperator(name string, factory OperatorFactory) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.operators[name] = factory
+}
+
+func (this *Registry) RegisterProcessor(name string, builder ProcessorFactoryBuilder) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.processors[name] = builder
+}
+
+func (this *Registry) RegisterFilter(name string, fn FilterFunc) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.filters[name] = fn
+}
+
+func (this *Registry) RegisterMap(name string, fn MapFunc) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.maps[name] = fn
+}
+
+// RegisterKey registers a key function used by operators (e.g: the key of dedupe)
+func (this *Registry) RegisterKey(name string, fn KeyFunc) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.keys[name] = fn
+}
+
+// Key returns the registered key function, an empty name returns nil
+func (this *Registry) Key(name string) (KeyFunc, error) {
+	if name == "" {
+		return nil, nil
+	}
+	this.mutex.RLock()
+	defer this.mutex.RUnlock()
+	fn, found := this.keys[name]
+	if !found {
+		return nil, fmt.Errorf("unknown key function: '%s'", name)
+	}
+	return fn, nil
+}
+
+func (this *Registry) source(name string) (SourceFactory, bool) {
+	this.mutex.RLock()
+	defer this.mutex.RUnlock()
+	factory, found := this.sources[name]
+	return factory, found
+}
+
+func (this *Registry) sink(name string) (SinkFactory, bool) {
+	this.mutex.RLock()
+	defer this.mutex.RUnlock()
+	factory, found := this.sinks[name]
+	return factory, found
+}
+
+func (this *Registry) operator(name string) (OperatorFactory, bool) {
+	this.mutex.RLock()
+	defer this.mutex.RUnlock()
+	factory, found := this.operators[name]
+	return factory, found
+}
+
+func (this *Registry) processor(name string) (ProcessorFactoryBuilder, bool) {
+	this.mutex.RLock()
+	defer this.mutex.RUnlock()
+	builder, found := this.processors[name]
+	return builder, found
+}
+
+func (this *Registry) filter(name string) (FilterFunc, bool) {
+	this.mutex.RLock()
+	defer this.mutex.RUnlock()
+	fn, found := this.filters[name]
+	return fn, found
+}
+
+func (this *Registry) mapFunc(name string) (MapFunc, bool) {
+	this.mutex.RLock()
+	defer this.mutex.RUnlock()
+	fn, found := this.maps[name]
+	return fn, found
+}
+
+func registerBuiltins(registry *Registry) {
+	registry.RegisterSource("sequential", func(params Params) (Source, error) {
+		limit, err := params.Int("limit", 0)
+		if err != nil {
+			return nil, err
+		}
+		delay, err := params.Duration("delay", 0)
+		if err != nil {
+			return nil, err
+		}
+		return NewSequentialIntegerSource(limit, delay), nil
+	})
+
+	registry.RegisterSink("console", func(params Params) (Sink, error) {
+		return NewConsoleSink(), nil
+	})
+
+	registry.RegisterOperator("throttle", func(params Params, registry *Registry) (Operator, error) {
+		n, err := params.Int("n", 0)
+		if err != nil {
+			return nil, err
+		}
+		per, err := params.Duration("per", time.Second)
+		if err != nil {
+			return nil, err
+		}
+		key, err := registryKey(params, registry)
+		if err != nil {
+			return nil, err
+		}
+		if n <= 0 || per <= 0 {
+			return nil, fmt.Errorf("throttle requires a positive rate")
+		}
+		return NewThrottle(n, per, key), nil
+	})
+
+	registry.RegisterOperator("debounce", func(params Params, registry *Registry) (Operator, error) {
+		d, err := params.Duration("duration", 0)
+		if err != nil {
+			return nil, err
+		}
+		key, err := registryKey(params, registry)
+		if err != nil {
+			return nil, err
+		}
+		return NewDebounce(d, key), nil
+	})
+
+	registry.RegisterOperator("dedupe", func(params Params, registry *Registry) (Operator, error) {
+		ttl, err := params.Duration("ttl", 0)
+		if err != nil {
+			return nil, err
+		}
+		maxKeys, err := params.Int("maxKeys", defaultDedupeKeys)
+		if err != nil {
+			return nil, err
+		}
+		key, err := registryKey(params, registry)
+		if err != nil {
+			return nil, err
+		}
+		if key == nil {
+			return nil, fmt.Errorf("dedupe requires a key function")
+		}
+		return NewDedupe(key, ttl, NewMemoryStateStore(maxKeys)), nil
+	})
+
+	registry.RegisterOperator("take", func(params Params, registry *Registry) (Operator, error) {
+		n, err := params.Int("n", 0)
+		if err != nil {
+			return nil, err
+		}
+		return NewTake(n), nil
+	})
+
+	registry.RegisterOperator("skip", func(params Params, registry *Registry) (Operator, error) {
+		n, err := params.Int("n", 0)
+		if err != nil {
+			return nil, err
+		}
+		return NewSkip(n), nil
+	})
+
+	registry.RegisterOperator("takeFor", func(params Params, registry *Registry) (Operator, error) {
+		d, err := params.Duration("duration", 0)
+		if err != nil {
+			return nil, err
+		}
+		if d <= 0 {
+			return nil, fmt.Errorf("takeFor requires a positive duration")
+		}
+		return NewTakeFor(d), nil
+	})
+
+	registry.RegisterOperator("sample", func(params Params, registry *Registry) (Operator, error) {
+		rate, err := params.Float("rate", 1)
+		if err != nil {
+			return nil, err
+		}
+		key, err := registryKey(params, registry)
+		if err != nil {
+			return nil, err
+		}
+		if rate < 0 || rate > 1 {
+			return nil, fmt.Errorf("sample rate should be between 0 and 1")
+		}
+		return NewSample(rate, key), nil
+	})
+
+	registry.RegisterOperator("sampleEvery", func(params Params, registry *Registry) (Operator, error) {
+		n, err := params.Int("n", 1)
+		if err != nil {
+			return nil, err
+		}
+		return NewSampleEvery(n), nil
+	})
+
+	registry.RegisterProcessor("direct", func(params Params) (ProcessorFactory, error) {
+		return NewDirectProcessorFactory(), nil
+	})
+
+	registry.RegisterProcessor("buffered", func(params Params) (ProcessorFactory, error) {
+		size, err := params.Int("size", 100)
+		if err != nil {
+			return nil, err
+		}
+		timeout, err := params.Duration("timeout", time.Second)
+		if err != nil {
+			return nil, err
+		}
+		return NewBufferedProcessorFactory(size, timeout), nil
+	})
+
+	registry.RegisterProcessor("sharded", func(params Params) (ProcessorFactory, error) {
+		shards, err := params.Int("shards", 4)
+		if err != nil {
+			return nil, err
+		}
+		key, err := registryKey(params, registry)
+		if err != nil {
+			return nil, err
+		}
+		return NewShardedProcessorFactory(shards, key), nil
+	})
+}
+
+func registryKey(params Params, registry *Registry) (KeyFunc, error) {
+	name, err := params.String("key", "")
+	if err != nil {
+		return nil, err
+	}
+	return registry.Key(name)
+}
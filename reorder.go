@@ -0,0 +1,128 @@
+package go_streams
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// TimestampFunc returns the event time of an entry
+type TimestampFunc func(entry Entry) time.Time
+
+type reorderedEntry struct {
+	entry   Entry
+	at      time.Time
+	arrival time.Time
+}
+
+// reorder holds back the entries for up to the max delay and emits them sorted by their event time
+type reorder struct {
+	maxDelay  time.Duration
+	timestamp TimestampFunc
+
+	mutex   *sync.Mutex
+	pending []reorderedEntry
+	emitted time.Time
+}
+
+// NewReorder creates an operator that sorts slightly out of order entries (e.g: merged from several partitions)
+// by their event time: an entry is emitted once it waited for the max delay, with the entries that come before it.
+// Entries with the same event time keep their arrival order. Entries older than an entry that was already
+// emitted are late, they are emitted right away. The timestamp defaults to the timestamp of the entries,
+// entries without one are stamped with their arrival time.
+func NewReorder(maxDelay time.Duration, timestamp TimestampFunc) TimedOperator {
+	if timestamp == nil {
+		timestamp = func(entry Entry) time.Time { return entry.Timestamp }
+	}
+	return &reorder{maxDelay: maxDelay, timestamp: timestamp, mutex: &sync.Mutex{}}
+}
+
+func (this *reorder) Apply(entry Entry) ([]Entry, error) {
+	now := time.Now()
+	at := this.timestamp(entry)
+	if at.IsZero() {
+		at = now
+	}
+
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	if at.Before(this.emitted) {
+		return []Entry{entry}, nil
+	}
+	pending := reorderedEntry{entry: entry, at: at, arrival: now}
+	idx := sort.Search(len(this.pending), func(i int) bool { return this.pending[i].at.After(at) })
+	this.pending = append(this.pending, reorderedEntry{})
+	copy(this.pending[idx+1:], this.pending[idx:])
+	this.pending[idx] = pending
+	return nil, nil
+}
+
+func (this *reorder) Tick(now time.Time) ([]Entry, error) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	due := -1
+	for idx := range this.pending {
+		if now.Sub(this.pending[idx].arrival) >= this.maxDelay {
+			due = idx
+		}
+	}
+	return this.emit(due + 1), nil
+}
+
+func (this *reorder) Drain() ([]Entry, error) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	return this.emit(len(this.pending)), nil
+}
+
+// emit returns the first n pending entries, the caller holds the mutex
+func (this *reorder) emit(n int) []Entry {
+	if n == 0 {
+		return nil
+	}
+	entries := make([]Entry, n)
+	for idx := range entries {
+		entries[idx] = this.pending[idx].entry
+	}
+	this.emitted = this.pending[n-1].at
+	this.pending = append(this.pending[:0], this.pending[n:]...)
+	return entries
+}
+
+type reorderState struct {
+	Pending []reorderedEntryState
+	Emitted time.Time
+}
+
+type reorderedEntryState struct {
+	Entry   Entry
+	At      time.Time
+	Arrival time.Time
+}
+
+// Snapshot encodes the entries held back, see Snapshotter
+func (this *reorder) Snapshot() ([]byte, error) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	state := reorderState{Pending: make([]reorderedEntryState, len(this.pending)), Emitted: this.emitted}
+	for idx, pending := range this.pending {
+		state.Pending[idx] = reorderedEntryState{Entry: pending.entry, At: pending.at, Arrival: pending.arrival}
+	}
+	return encodeState(&state)
+}
+
+// Restore replaces the entries held back by a snapshot
+func (this *reorder) Restore(data []byte) error {
+	var state reorderState
+	if err := decodeState(data, &state); err != nil {
+		return err
+	}
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.pending = make([]reorderedEntry, len(state.Pending))
+	for idx, pending := range state.Pending {
+		this.pending[idx] = reorderedEntry{entry: pending.Entry, at: pending.At, arrival: pending.Arrival}
+	}
+	this.emitted = state.Emitted
+	return nil
+}
@@ -0,0 +1,65 @@
+package go_streams
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func entryValues(entries []Entry) []interface{} {
+	out := make([]interface{}, len(entries))
+	for idx := range entries {
+		out[idx] = entries[idx].Value
+	}
+	return out
+}
+
+func TestReorder_EmitsInEventTimeOrder(t *testing.T) {
+	base := time.Now()
+	op := NewReorder(50*time.Millisecond, nil)
+	for _, offset := range []int{3, 1, 2, 2} {
+		entries, err := op.Apply(Entry{Value: offset, Timestamp: base.Add(time.Duration(offset) * time.Second)})
+		assert.Nil(t, err)
+		assert.Empty(t, entries)
+	}
+	ticked, err := op.Tick(time.Now())
+	assert.Nil(t, err)
+	assert.Empty(t, ticked)
+
+	ticked, _ = op.Tick(time.Now().Add(time.Second))
+	assert.EqualValues(t, []interface{}{1, 2, 2, 3}, entryValues(ticked))
+
+	// entries older than the emitted ones are late and pass right away
+	late, _ := op.Apply(Entry{Value: 0, Timestamp: base})
+	assert.EqualValues(t, []interface{}{0}, entryValues(late))
+}
+
+func TestReorder_EmitsEntriesBeforeTheDueOne(t *testing.T) {
+	base := time.Now()
+	op := NewReorder(time.Minute, func(entry Entry) time.Time {
+		return base.Add(time.Duration(entry.Value.(int)) * time.Second)
+	})
+	_, _ = op.Apply(Entry{Value: 5})
+	time.Sleep(20 * time.Millisecond)
+	_, _ = op.Apply(Entry{Value: 2})
+	_, _ = op.Apply(Entry{Value: 9})
+
+	// only the first entry waited for the delay, the one before it in event time goes with it
+	ticked, _ := op.Tick(time.Now().Add(time.Minute - 10*time.Millisecond))
+	assert.EqualValues(t, []interface{}{2, 5}, entryValues(ticked))
+	drained, _ := op.Drain()
+	assert.EqualValues(t, []interface{}{9}, entryValues(drained))
+}
+
+func TestStream_Reorder(t *testing.T) {
+	source := NewSequentialIntegerSource(5, 0)
+	sink := NewArraySink()
+	base := time.Now()
+	NewStream(source).Reorder(time.Hour, func(entry Entry) time.Time {
+		// swaps the entries by pairs
+		return base.Add(time.Duration(entry.Value.(int)^1) * time.Second)
+	}).Sink(sink).Process(NewDirectProcessor(), make(ErrorChannel, 10))
+
+	assert.EqualValues(t, []interface{}{1, 0, 3, 2, 5, 4}, sink.Array())
+}
@@ -0,0 +1,189 @@
+package go_streams
+
+import (
+	"reflect"
+	"sync"
+)
+
+// Limits bounds the resources used by the streams of an engine (see Engine.SetLimits), zero values are unbounded
+type Limits struct {
+	// MaxWorkers is the number of goroutines running the async work of all the streams at once (e.g: MapAsync),
+	// the operators wait for a free worker beyond it.
+	MaxWorkers int `json:"maxWorkers,omitempty" yaml:"maxWorkers,omitempty"`
+
+	// MaxInFlight is the number of entries a stream takes from its source before the processor passed them
+	// through the pipeline, for the streams without their own (see Stream.MaxInFlight).
+	MaxInFlight int `json:"maxInFlight,omitempty" yaml:"maxInFlight,omitempty"`
+
+	// MaxSinkRequests is the number of concurrent writes of every sink, the streams sharing a sink share its limit
+	MaxSinkRequests int `json:"maxSinkRequests,omitempty" yaml:"maxSinkRequests,omitempty"`
+}
+
+// WorkerPool runs functions on a bounded number of goroutines
+type WorkerPool struct {
+	slots chan struct{}
+}
+
+func NewWorkerPool(size int) *WorkerPool {
+	if size <= 0 {
+		size = 1
+	}
+	return &WorkerPool{slots: make(chan struct{}, size)}
+}
+
+// Go runs the function on a new goroutine, it waits until fewer than size functions are running
+func (this *WorkerPool) Go(fn func()) {
+	this.slots <- struct{}{}
+	go func() {
+		defer func() { <-this.slots }()
+		fn()
+	}()
+}
+
+// Running returns the number of functions running
+func (this *WorkerPool) Running() int {
+	return len(this.slots)
+}
+
+// poolBinder is implemented by the operators running async work on the worker pool of the engine (see bindSinks)
+type poolBinder interface {
+	bindPool(pool *WorkerPool)
+}
+
+// inFlightLimit bounds the entries taken from a source before they were processed, a nil limit doesn't wait
+type inFlightLimit chan struct{}
+
+func newInFlightLimit(n int) inFlightLimit {
+	if n <= 0 {
+		return nil
+	}
+	return make(inFlightLimit, n)
+}
+
+func (this inFlightLimit) acquire() {
+	if this != nil {
+		this <- struct{}{}
+	}
+}
+
+func (this inFlightLimit) release(n int) {
+	if this == nil {
+		return
+	}
+	for idx := 0; idx < n; idx++ {
+		<-this
+	}
+}
+
+// inFlightOf returns the in flight limit of a stream processed by the engine (nil when it has none)
+func inFlightOf(stream Stream) inFlightLimit {
+	if managed, ok := stream.(*managedStream); ok {
+		return managed.source.inFlight
+	}
+	return nil
+}
+
+// governor holds the limits shared by the streams of an engine
+type governor struct {
+	limits Limits
+	pool   *WorkerPool
+
+	mutex *sync.Mutex
+	sinks map[interface{}]chan struct{}
+}
+
+func newGovernor(limits Limits) *governor {
+	this := &governor{limits: limits, mutex: &sync.Mutex{}, sinks: make(map[interface{}]chan struct{})}
+	if limits.MaxWorkers > 0 {
+		this.pool = NewWorkerPool(limits.MaxWorkers)
+	}
+	return this
+}
+
+// limitSink returns the sink a stream writes through, handler is the sink as the stream declared it
+// so the streams sharing it share its requests
+func (this *governor) limitSink(handler interface{}, sink Sink) Sink {
+	if this.limits.MaxSinkRequests <= 0 {
+		return sink
+	}
+	if !reflect.TypeOf(handler).Comparable() {
+		return &limitedSink{sink: sink, slots: make(chan struct{}, this.limits.MaxSinkRequests)}
+	}
+
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	slots, found := this.sinks[handler]
+	if !found {
+		slots = make(chan struct{}, this.limits.MaxSinkRequests)
+		this.sinks[handler] = slots
+	}
+	return &limitedSink{sink: sink, slots: slots}
+}
+
+// LimitSink bounds the concurrent writes of a sink (e.g: a sink shared by many streams or written by MapAsync),
+// the writes wait for one of the n requests to complete beyond it.
+func LimitSink(sink Sink, n int) Sink {
+	if n <= 0 {
+		n = 1
+	}
+	limited := &limitedSink{sink: sink, slots: make(chan struct{}, n)}
+	if _, ok := sink.(AckingSink); ok {
+		return &limitedAckingSink{limitedSink: limited}
+	}
+	return limited
+}
+
+type limitedSink struct {
+	sink  Sink
+	slots chan struct{}
+}
+
+func (this *limitedSink) Single(entry Entry) error {
+	this.slots <- struct{}{}
+	defer func() { <-this.slots }()
+	return this.sink.Single(entry)
+}
+
+func (this *limitedSink) Batch(entry ...Entry) error {
+	this.slots <- struct{}{}
+	defer func() { <-this.slots }()
+	return this.sink.Batch(entry...)
+}
+
+func (this *limitedSink) Ping() error {
+	return this.sink.Ping()
+}
+
+// Flush flushes the sink when it implements Flusher, it counts as a request
+func (this *limitedSink) Flush() error {
+	flusher, ok := this.sink.(Flusher)
+	if !ok {
+		return nil
+	}
+	this.slots <- struct{}{}
+	defer func() { <-this.slots }()
+	return flusher.Flush()
+}
+
+// Close closes the sink when it implements Closer
+func (this *limitedSink) Close() error {
+	if closer, ok := this.sink.(Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+func (this *limitedSink) bindEvents(stream Stream) {
+	if binder, ok := this.sink.(eventBinder); ok {
+		binder.bindEvents(stream)
+	}
+}
+
+// limitedAckingSink is the limited sink of an AckingSink, the bound sinks share its requests
+type limitedAckingSink struct {
+	*limitedSink
+}
+
+func (this *limitedAckingSink) Bind(commit Committer) Sink {
+	return &limitedSink{sink: this.sink.(AckingSink).Bind(commit), slots: this.slots}
+}
@@ -0,0 +1,160 @@
+package go_streams
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// concurrencyGauge records the max number of calls running at once
+type concurrencyGauge struct {
+	mutex   *sync.Mutex
+	running int
+	max     int
+	calls   int
+}
+
+func newConcurrencyGauge() *concurrencyGauge {
+	return &concurrencyGauge{mutex: &sync.Mutex{}}
+}
+
+func (this *concurrencyGauge) run(d time.Duration) {
+	this.mutex.Lock()
+	this.running++
+	this.calls++
+	if this.running > this.max {
+		this.max = this.running
+	}
+	this.mutex.Unlock()
+	time.Sleep(d)
+	this.mutex.Lock()
+	this.running--
+	this.mutex.Unlock()
+}
+
+func (this *concurrencyGauge) stats() (max int, calls int) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	return this.max, this.calls
+}
+
+func TestWorkerPool_BoundsTheRunningFunctions(t *testing.T) {
+	pool := NewWorkerPool(2)
+	gauge := newConcurrencyGauge()
+	wg := &sync.WaitGroup{}
+	for idx := 0; idx < 6; idx++ {
+		wg.Add(1)
+		pool.Go(func() {
+			defer wg.Done()
+			gauge.run(5 * time.Millisecond)
+		})
+	}
+	wg.Wait()
+	max, calls := gauge.stats()
+	assert.EqualValues(t, 2, max)
+	assert.EqualValues(t, 6, calls)
+}
+
+func TestLimitSink_BoundsTheConcurrentWrites(t *testing.T) {
+	gauge := newConcurrencyGauge()
+	sink := LimitSink(NewCallbackSink(func(entries ...Entry) error {
+		gauge.run(5 * time.Millisecond)
+		return nil
+	}), 2)
+
+	wg := &sync.WaitGroup{}
+	for idx := 0; idx < 6; idx++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.Nil(t, sink.Single(Entry{Key: "1", Value: 1}))
+		}()
+	}
+	wg.Wait()
+	max, calls := gauge.stats()
+	assert.EqualValues(t, 2, max)
+	assert.EqualValues(t, 6, calls)
+
+	_, acking := LimitSink(NewArraySink(), 1).(AckingSink)
+	assert.False(t, acking)
+	inner := &ackingArraySink{ArraySink: NewArraySink()}
+	limited, acking := LimitSink(inner, 1).(AckingSink)
+	assert.True(t, acking)
+	assert.Nil(t, limited.Bind(func(keys ...string) error { return nil }).Single(Entry{Key: "1", Value: 1}))
+	assert.NotNil(t, inner.commit)
+	assert.EqualValues(t, []interface{}{1}, inner.Array())
+}
+
+func TestEngine_SetLimits_MaxInFlight(t *testing.T) {
+	engine := NewEngine(NewShardedProcessorFactory(4, nil), 10*time.Second)
+	engine.SetLimits(Limits{MaxInFlight: 3})
+	release := make(chan struct{})
+	sink := NewArraySink()
+	assert.Nil(t, engine.Add(NewStream(NewSequentialIntegerSource(10, 0)).
+		Map(func(entry interface{}) interface{} {
+			<-release
+			return entry
+		}).
+		Sink(sink)))
+	go engine.Start()
+
+	assert.Eventually(t, func() bool {
+		infos := engine.Streams()
+		return len(infos) == 1 && infos[0].InFlight == 3
+	}, time.Second, time.Millisecond)
+	time.Sleep(10 * time.Millisecond)
+	assert.True(t, engine.Streams()[0].Received <= 4)
+
+	close(release)
+	assert.Eventually(t, func() bool { return len(sink.Array()) == 11 }, time.Second, time.Millisecond)
+}
+
+func TestEngine_MaxInFlight_WithBuffer(t *testing.T) {
+	engine := NewEngine(NewDirectProcessorFactory(), 10*time.Second)
+	sink := NewArraySink()
+	assert.Nil(t, engine.Add(NewStream(NewSequentialIntegerSource(19, 0)).
+		Buffer(BufferConfig{Size: 5}).
+		MaxInFlight(3).
+		Sink(sink)))
+
+	done := make(chan bool)
+	go func() {
+		engine.Start()
+		done <- true
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the stream is stuck")
+	}
+	assert.Len(t, sink.Array(), 20)
+}
+
+func TestEngine_SetLimits_SharedSinkAndWorkers(t *testing.T) {
+	engine := NewEngine(NewShardedProcessorFactory(4, nil), 10*time.Second)
+	engine.SetLimits(Limits{MaxWorkers: 3, MaxSinkRequests: 1})
+
+	workers, requests := newConcurrencyGauge(), newConcurrencyGauge()
+	sink := NewCallbackSink(func(entries ...Entry) error {
+		requests.run(time.Millisecond)
+		return nil
+	})
+	mapper := func(entry interface{}) (interface{}, error) {
+		workers.run(time.Millisecond)
+		return entry, nil
+	}
+	assert.Nil(t, engine.Add(
+		NewStream(NewSequentialIntegerSource(20, 0)).MapAsync(mapper, 10, false).Sink(sink),
+		NewStream(NewSequentialIntegerSource(20, 0)).MapAsync(mapper, 10, false).Sink(sink),
+	))
+	engine.Start()
+
+	max, calls := workers.stats()
+	assert.True(t, max <= 3)
+	assert.EqualValues(t, 42, calls)
+	max, calls = requests.stats()
+	assert.EqualValues(t, 1, max)
+	assert.EqualValues(t, 42, calls)
+}
@@ -0,0 +1,105 @@
+package s3
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+
+	streams "github.com/matang28/go-streams"
+)
+
+// Format decides how entries are serialized into objects, formats that
+// needs a footer can write it when the encoder is closed. Only JSON lines and CSV
+// are shipped, Parquet is out of scope (it requires a Parquet library) but can be
+// plugged in by implementing a Format on top of one.
+type Format interface {
+	// Extension is the file extension of the objects (e.g: "json")
+	Extension() string
+
+	// NewEncoder creates an encoder for a new object
+	NewEncoder(w io.Writer) Encoder
+}
+
+// Encoder writes entries of a single object
+type Encoder interface {
+	Encode(entry streams.Entry) error
+	Close() error
+}
+
+type jsonLinesFormat struct{}
+
+// JSONLines writes each entry value as a JSON document in its own line
+func JSONLines() Format {
+	return &jsonLinesFormat{}
+}
+
+func (this *jsonLinesFormat) Extension() string {
+	return "json"
+}
+
+func (this *jsonLinesFormat) NewEncoder(w io.Writer) Encoder {
+	return &jsonLinesEncoder{enc: json.NewEncoder(w)}
+}
+
+type jsonLinesEncoder struct {
+	enc *json.Encoder
+}
+
+func (this *jsonLinesEncoder) Encode(entry streams.Entry) error {
+	return this.enc.Encode(entry.Value)
+}
+
+func (this *jsonLinesEncoder) Close() error {
+	return nil
+}
+
+// RecordFunc maps an entry into a CSV record
+type RecordFunc func(entry streams.Entry) ([]string, error)
+
+type csvFormat struct {
+	header []string
+	record RecordFunc
+}
+
+// CSV writes each entry as a CSV record, the header (if not empty) is written at the top of each object
+func CSV(header []string, record RecordFunc) Format {
+	return &csvFormat{header: header, record: record}
+}
+
+func (this *csvFormat) Extension() string {
+	return "csv"
+}
+
+func (this *csvFormat) NewEncoder(w io.Writer) Encoder {
+	return &csvEncoder{w: csv.NewWriter(w), format: this}
+}
+
+type csvEncoder struct {
+	w       *csv.Writer
+	format  *csvFormat
+	started bool
+}
+
+func (this *csvEncoder) Encode(entry streams.Entry) error {
+	if !this.started && len(this.format.header) > 0 {
+		if err := this.w.Write(this.format.header); err != nil {
+			return err
+		}
+	}
+	this.started = true
+
+	record, err := this.format.record(entry)
+	if err != nil {
+		return err
+	}
+	if err := this.w.Write(record); err != nil {
+		return err
+	}
+	this.w.Flush()
+	return this.w.Error()
+}
+
+func (this *csvEncoder) Close() error {
+	this.w.Flush()
+	return this.w.Error()
+}
@@ -0,0 +1,36 @@
+package s3
+
+import "path"
+
+// ObjectClient reads and writes whole objects, usually the same client as the Uploader
+type ObjectClient interface {
+	PutObject(bucket, key string, body []byte) error
+	GetObject(bucket, key string) ([]byte, error)
+}
+
+// ObjectStore stores payloads as the objects of a bucket under a prefix,
+// e.g: the payloads offloaded by a claim check (see codec.NewClaimCheck).
+type ObjectStore struct {
+	client ObjectClient
+	bucket string
+	prefix string
+}
+
+func NewObjectStore(client ObjectClient, bucket, prefix string) *ObjectStore {
+	return &ObjectStore{client: client, bucket: bucket, prefix: prefix}
+}
+
+func (this *ObjectStore) Put(key string, data []byte) error {
+	return this.client.PutObject(this.bucket, this.key(key), data)
+}
+
+func (this *ObjectStore) Get(key string) ([]byte, error) {
+	return this.client.GetObject(this.bucket, this.key(key))
+}
+
+func (this *ObjectStore) key(key string) string {
+	if this.prefix == "" {
+		return key
+	}
+	return path.Join(this.prefix, key)
+}
@@ -0,0 +1,22 @@
+package s3
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestObjectStore(t *testing.T) {
+	uploader := newMemoryUploader()
+	store := NewObjectStore(uploader, "bucket", "claims")
+
+	assert.Nil(t, store.Put("abc", []byte("payload")))
+	assert.EqualValues(t, []string{"claims/abc"}, uploader.keys())
+
+	data, err := store.Get("abc")
+	assert.Nil(t, err)
+	assert.EqualValues(t, "payload", string(data))
+
+	_, err = store.Get("missing")
+	assert.NotNil(t, err)
+}
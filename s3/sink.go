@@ -0,0 +1,505 @@
+package s3
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	streams "github.com/matang28/go-streams"
+)
+
+// Part is an uploaded part of a multipart upload
+type Part struct {
+	Number int
+	ETag   string
+}
+
+// Uploader abstracts the object storage client (e.g: the AWS SDK, MinIO, GCS interoperability),
+// this way go-streams doesn't depend on a specific SDK.
+type Uploader interface {
+	streams.Pingable
+
+	PutObject(bucket, key string, body []byte) error
+
+	CreateMultipartUpload(bucket, key string) (uploadId string, err error)
+	UploadPart(bucket, key, uploadId string, number int, body []byte) (etag string, err error)
+	CompleteMultipartUpload(bucket, key, uploadId string, parts []Part) error
+	AbortMultipartUpload(bucket, key, uploadId string) error
+}
+
+const (
+	defaultMaxObjectSize = 64 << 20
+	defaultPartSize      = 8 << 20
+	defaultFlushInterval = time.Minute
+	minPartSize          = 5 << 20
+)
+
+// Config describes the layout of the written objects
+type Config struct {
+	Bucket string
+
+	// Prefix is prepended to all object keys (e.g: "events")
+	Prefix string
+
+	// Partition is a text/template of the partition path evaluated per entry
+	// (e.g: `dt={{.Timestamp.Format "2006-01-02"}}/hour={{.Timestamp.Format "15"}}`),
+	// entries without a timestamp are evaluated with the current time.
+	Partition string
+
+	// Format of the objects, defaults to JSON lines
+	Format Format
+
+	// Gzip compresses the objects (and appends '.gz' to the object keys)
+	Gzip bool
+
+	// MaxObjectSize is the size in bytes after which a new object is started (defaults to 64MB)
+	MaxObjectSize int
+
+	// MaxObjectEntries is the number of entries after which a new object is started (0 means unlimited)
+	MaxObjectEntries int
+
+	// FlushInterval is the maximal time entries of bound streams are buffered before
+	// their objects are uploaded (defaults to 1 minute).
+	FlushInterval time.Duration
+
+	// PartSize is the part size of multipart uploads, objects that are bigger than
+	// a single part are uploaded with a multipart upload (defaults to 8MB, minimum is 5MB).
+	PartSize int
+}
+
+// Sink writes entries as objects partitioned by the configured template.
+//
+// Streams bind the sink (see streams.AckingSink): their entries are buffered per partition across
+// calls and an object is uploaded once it reaches MaxObjectSize or MaxObjectEntries, or when
+// FlushInterval elapses. Entries are committed only after the object holding them (and every
+// object holding earlier entries of the same stream) was uploaded, so committed entries are always durable.
+//
+// Calling Single/Batch directly (on an unbound sink) uploads the entries before returning.
+type Sink struct {
+	uploader  Uploader
+	config    Config
+	partition *template.Template
+	runId     int64
+
+	seqMutex *sync.Mutex
+	parts    map[string]int
+
+	mutex    *sync.Mutex
+	buffers  map[string]*object
+	failed   []*object
+	trackers []*ackTracker
+
+	startOnce *sync.Once
+	closeOnce *sync.Once
+	closeCh   chan bool
+}
+
+func NewSink(uploader Uploader, config Config) (*Sink, error) {
+	if config.Bucket == "" {
+		return nil, fmt.Errorf("s3 sink requires a bucket")
+	}
+
+	partition, err := template.New("partition").Option("missingkey=zero").Parse(config.Partition)
+	if err != nil {
+		return nil, err
+	}
+
+	if config.Format == nil {
+		config.Format = JSONLines()
+	}
+	if config.MaxObjectSize <= 0 {
+		config.MaxObjectSize = defaultMaxObjectSize
+	}
+	if config.PartSize <= 0 {
+		config.PartSize = defaultPartSize
+	}
+	if config.PartSize < minPartSize {
+		config.PartSize = minPartSize
+	}
+	if config.FlushInterval <= 0 {
+		config.FlushInterval = defaultFlushInterval
+	}
+
+	return &Sink{
+		uploader:  uploader,
+		config:    config,
+		partition: partition,
+		runId:     time.Now().UnixNano() / int64(time.Millisecond),
+		seqMutex:  &sync.Mutex{},
+		parts:     make(map[string]int),
+		mutex:     &sync.Mutex{},
+		buffers:   make(map[string]*object),
+		startOnce: &sync.Once{},
+		closeOnce: &sync.Once{},
+		closeCh:   make(chan bool),
+	}, nil
+}
+
+func (this *Sink) Ping() error {
+	return this.uploader.Ping()
+}
+
+func (this *Sink) Single(entry streams.Entry) error {
+	return this.Batch(entry)
+}
+
+func (this *Sink) Batch(entry ...streams.Entry) error {
+	partitions := make(map[string][]streams.Entry)
+	for idx := range entry {
+		p, err := this.partitionOf(entry[idx])
+		if err != nil {
+			return err
+		}
+		partitions[p] = append(partitions[p], entry[idx])
+	}
+
+	names := make([]string, 0, len(partitions))
+	for p := range partitions {
+		names = append(names, p)
+	}
+	sort.Strings(names)
+
+	for _, p := range names {
+		if err := this.writePartition(p, partitions[p]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Bind returns the sink used by a single stream, its entries are buffered
+// and committed by the sink once they are uploaded.
+func (this *Sink) Bind(commit streams.Committer) streams.Sink {
+	this.startOnce.Do(func() {
+		go this.flushPeriodically()
+	})
+
+	tracker := &ackTracker{commit: commit}
+	this.mutex.Lock()
+	this.trackers = append(this.trackers, tracker)
+	this.mutex.Unlock()
+	return &boundSink{Sink: this, tracker: tracker}
+}
+
+// Flush uploads all the buffered objects and commits their entries
+func (this *Sink) Flush() error {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	err := this.retryFailed()
+	for _, p := range this.sortedBuffers() {
+		obj := this.buffers[p]
+		delete(this.buffers, p)
+		if uploadErr := this.uploadBuffered(obj); uploadErr != nil && err == nil {
+			err = uploadErr
+		}
+	}
+	this.commitAcked()
+	return err
+}
+
+// Close stops the periodic flush and flushes the buffered objects
+func (this *Sink) Close() error {
+	this.closeOnce.Do(func() {
+		close(this.closeCh)
+	})
+	return this.Flush()
+}
+
+func (this *Sink) flushPeriodically() {
+	ticker := time.NewTicker(this.config.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-this.closeCh:
+			return
+		case <-ticker.C:
+			if err := this.Flush(); err != nil {
+				streams.LogWith(streams.Fields{streams.FieldStage: streams.SinkStage, "sink": "s3"}).Error("Failed to flush s3 sink: %s", err.Error())
+			}
+		}
+	}
+}
+
+// buffer adds the entries to the open object of their partition, uploading full objects
+func (this *Sink) buffer(tracker *ackTracker, entries []streams.Entry) error {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	defer this.commitAcked()
+
+	if err := this.retryFailed(); err != nil {
+		return err
+	}
+
+	for idx := range entries {
+		p, err := this.partitionOf(entries[idx])
+		if err != nil {
+			return err
+		}
+
+		obj, found := this.buffers[p]
+		if !found {
+			obj = this.newObject()
+			obj.partition = p
+			this.buffers[p] = obj
+		}
+		if err := obj.encoder.Encode(entries[idx]); err != nil {
+			return err
+		}
+		obj.count++
+		obj.acks = append(obj.acks, ack{tracker: tracker, seq: tracker.add(entries[idx].Key)})
+
+		if this.full(obj) {
+			delete(this.buffers, p)
+			if err := this.uploadBuffered(obj); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// uploadBuffered uploads a buffered object and acknowledges its entries,
+// objects that failed to upload are retried on the next flush.
+func (this *Sink) uploadBuffered(obj *object) error {
+	if err := this.upload(obj.partition, obj); err != nil {
+		this.failed = append(this.failed, obj)
+		return err
+	}
+	for _, a := range obj.acks {
+		a.tracker.ack(a.seq)
+	}
+	return nil
+}
+
+func (this *Sink) retryFailed() error {
+	failed := this.failed
+	this.failed = nil
+	for idx, obj := range failed {
+		if err := this.uploadBuffered(obj); err != nil {
+			this.failed = append(this.failed, failed[idx+1:]...)
+			return err
+		}
+	}
+	return nil
+}
+
+func (this *Sink) commitAcked() {
+	for _, tracker := range this.trackers {
+		if err := tracker.commitAcked(); err != nil {
+			streams.LogWith(streams.Fields{streams.FieldStage: streams.SinkStage, "sink": "s3"}).Error("Failed to commit entries uploaded by s3 sink: %s", err.Error())
+		}
+	}
+}
+
+func (this *Sink) sortedBuffers() []string {
+	names := make([]string, 0, len(this.buffers))
+	for p := range this.buffers {
+		names = append(names, p)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (this *Sink) full(obj *object) bool {
+	return obj.size() >= this.config.MaxObjectSize ||
+		(this.config.MaxObjectEntries > 0 && obj.count >= this.config.MaxObjectEntries)
+}
+
+// boundSink is the sink of a single stream
+type boundSink struct {
+	*Sink
+	tracker *ackTracker
+}
+
+func (this *boundSink) Single(entry streams.Entry) error {
+	return this.Batch(entry)
+}
+
+func (this *boundSink) Batch(entry ...streams.Entry) error {
+	return this.Sink.buffer(this.tracker, entry)
+}
+
+type ack struct {
+	tracker *ackTracker
+	seq     int
+}
+
+// ackTracker tracks the entries of a stream in the order they were received,
+// since commits are cumulative only the longest prefix of uploaded entries is committed.
+type ackTracker struct {
+	commit streams.Committer
+	base   int
+	keys   []string
+	done   []bool
+}
+
+func (this *ackTracker) add(key string) int {
+	this.keys = append(this.keys, key)
+	this.done = append(this.done, false)
+	return this.base + len(this.keys) - 1
+}
+
+func (this *ackTracker) ack(seq int) {
+	this.done[seq-this.base] = true
+}
+
+func (this *ackTracker) commitAcked() error {
+	n := 0
+	for n < len(this.done) && this.done[n] {
+		n++
+	}
+	if n == 0 {
+		return nil
+	}
+
+	key := this.keys[n-1]
+	this.keys = this.keys[n:]
+	this.done = this.done[n:]
+	this.base += n
+	return this.commit(key)
+}
+
+func (this *Sink) partitionOf(entry streams.Entry) (string, error) {
+	if entry.Timestamp.IsZero() {
+		entry.Timestamp = time.Now()
+	}
+
+	var sb strings.Builder
+	if err := this.partition.Execute(&sb, entry); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
+
+func (this *Sink) writePartition(partition string, entries []streams.Entry) error {
+	obj := this.newObject()
+	for idx := range entries {
+		if err := obj.encoder.Encode(entries[idx]); err != nil {
+			return err
+		}
+		obj.count++
+
+		if this.full(obj) {
+			if err := this.upload(partition, obj); err != nil {
+				return err
+			}
+			obj = this.newObject()
+		}
+	}
+
+	if obj.count == 0 {
+		return nil
+	}
+	return this.upload(partition, obj)
+}
+
+func (this *Sink) upload(partition string, obj *object) error {
+	body, err := obj.close()
+	if err != nil {
+		return err
+	}
+
+	key := this.objectKey(partition)
+	streams.LogWith(streams.Fields{streams.FieldStage: streams.SinkStage, "sink": "s3"}).Debug("Uploading object: %s (%d entries, %d bytes)", key, obj.count, len(body))
+
+	if len(body) <= this.config.PartSize {
+		return this.uploader.PutObject(this.config.Bucket, key, body)
+	}
+	return this.multipart(key, body)
+}
+
+func (this *Sink) multipart(key string, body []byte) error {
+	uploadId, err := this.uploader.CreateMultipartUpload(this.config.Bucket, key)
+	if err != nil {
+		return err
+	}
+
+	var parts []Part
+	for offset, number := 0, 1; offset < len(body); offset, number = offset+this.config.PartSize, number+1 {
+		end := offset + this.config.PartSize
+		if end > len(body) {
+			end = len(body)
+		}
+
+		etag, err := this.uploader.UploadPart(this.config.Bucket, key, uploadId, number, body[offset:end])
+		if err != nil {
+			_ = this.uploader.AbortMultipartUpload(this.config.Bucket, key, uploadId)
+			return err
+		}
+		parts = append(parts, Part{Number: number, ETag: etag})
+	}
+
+	if err := this.uploader.CompleteMultipartUpload(this.config.Bucket, key, uploadId, parts); err != nil {
+		_ = this.uploader.AbortMultipartUpload(this.config.Bucket, key, uploadId)
+		return err
+	}
+	return nil
+}
+
+// objectKey generates the next object key of the partition, the run id
+// prevents objects written by different processes (or restarts) from overwriting each other.
+func (this *Sink) objectKey(partition string) string {
+	this.seqMutex.Lock()
+	this.parts[partition]++
+	seq := this.parts[partition]
+	this.seqMutex.Unlock()
+
+	name := fmt.Sprintf("part-%04d-%d.%s", seq, this.runId, this.config.Format.Extension())
+	if this.config.Gzip {
+		name += ".gz"
+	}
+	return path.Join(this.config.Prefix, partition, name)
+}
+
+type object struct {
+	buffer    *bytes.Buffer
+	gzip      *gzip.Writer
+	encoder   Encoder
+	count     int
+	partition string
+	acks      []ack
+	body      []byte
+}
+
+func (this *Sink) newObject() *object {
+	obj := &object{buffer: &bytes.Buffer{}}
+
+	var w io.Writer = obj.buffer
+	if this.config.Gzip {
+		obj.gzip = gzip.NewWriter(obj.buffer)
+		w = obj.gzip
+	}
+	obj.encoder = this.config.Format.NewEncoder(w)
+	return obj
+}
+
+// size returns the (approximated, when compressed) size of the object
+func (this *object) size() int {
+	return this.buffer.Len()
+}
+
+// close finishes the object and returns its body, closing an object again returns the same body
+func (this *object) close() ([]byte, error) {
+	if this.body != nil {
+		return this.body, nil
+	}
+	if err := this.encoder.Close(); err != nil {
+		return nil, err
+	}
+	if this.gzip != nil {
+		if err := this.gzip.Close(); err != nil {
+			return nil, err
+		}
+	}
+	this.body = this.buffer.Bytes()
+	return this.body, nil
+}
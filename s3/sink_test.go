@@ -0,0 +1,285 @@
+package s3
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	streams "github.com/matang28/go-streams"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSink_Batch_PartitionsByTime(t *testing.T) {
+	uploader := newMemoryUploader()
+	sink, err := NewSink(uploader, Config{
+		Bucket:    "bucket",
+		Prefix:    "events",
+		Partition: `dt={{.Timestamp.Format "2006-01-02"}}/hour={{.Timestamp.Format "15"}}`,
+		Gzip:      true,
+	})
+	assert.Nil(t, err)
+
+	first := time.Date(2024, 5, 1, 13, 10, 0, 0, time.UTC)
+	second := time.Date(2024, 5, 1, 14, 10, 0, 0, time.UTC)
+	err = sink.Batch(
+		streams.Entry{Key: "1", Value: map[string]int{"v": 1}, Timestamp: first},
+		streams.Entry{Key: "2", Value: map[string]int{"v": 2}, Timestamp: second},
+		streams.Entry{Key: "3", Value: map[string]int{"v": 3}, Timestamp: first},
+	)
+	assert.Nil(t, err)
+
+	keys := uploader.keys()
+	assert.EqualValues(t, 2, len(keys))
+	assert.True(t, strings.HasPrefix(keys[0], "events/dt=2024-05-01/hour=13/part-0001-"))
+	assert.True(t, strings.HasSuffix(keys[0], ".json.gz"))
+	assert.True(t, strings.HasPrefix(keys[1], "events/dt=2024-05-01/hour=14/part-0001-"))
+
+	assert.EqualValues(t, "{\"v\":1}\n{\"v\":3}\n", gunzip(t, uploader.objects[keys[0]]))
+	assert.EqualValues(t, "{\"v\":2}\n", gunzip(t, uploader.objects[keys[1]]))
+}
+
+func TestSink_Batch_CSVAndEntriesThreshold(t *testing.T) {
+	uploader := newMemoryUploader()
+	sink, err := NewSink(uploader, Config{
+		Bucket:           "bucket",
+		Partition:        `{{.Metadata.tenant}}`,
+		MaxObjectEntries: 2,
+		Format: CSV([]string{"key", "value"}, func(entry streams.Entry) ([]string, error) {
+			return []string{entry.Key, fmt.Sprintf("%v", entry.Value)}, nil
+		}),
+	})
+	assert.Nil(t, err)
+
+	tenant := map[string]string{"tenant": "acme"}
+	err = sink.Batch(
+		streams.Entry{Key: "1", Value: "a", Metadata: tenant},
+		streams.Entry{Key: "2", Value: "b", Metadata: tenant},
+		streams.Entry{Key: "3", Value: "c", Metadata: tenant},
+	)
+	assert.Nil(t, err)
+
+	keys := uploader.keys()
+	assert.EqualValues(t, 2, len(keys))
+	assert.True(t, strings.HasPrefix(keys[0], "acme/part-0001-"))
+	assert.True(t, strings.HasSuffix(keys[0], ".csv"))
+	assert.True(t, strings.HasPrefix(keys[1], "acme/part-0002-"))
+	assert.EqualValues(t, "key,value\n1,a\n2,b\n", string(uploader.objects[keys[0]]))
+	assert.EqualValues(t, "key,value\n3,c\n", string(uploader.objects[keys[1]]))
+}
+
+func TestSink_Batch_MultipartUpload(t *testing.T) {
+	uploader := newMemoryUploader()
+	sink, err := NewSink(uploader, Config{Bucket: "bucket", Partition: "big", PartSize: 5 << 20})
+	assert.Nil(t, err)
+
+	payload := strings.Repeat("x", 1<<20)
+	var entries []streams.Entry
+	for i := 0; i < 6; i++ {
+		entries = append(entries, streams.Entry{Key: fmt.Sprintf("%d", i), Value: payload})
+	}
+	assert.Nil(t, sink.Batch(entries...))
+
+	keys := uploader.keys()
+	assert.EqualValues(t, 1, len(keys))
+	assert.EqualValues(t, 2, uploader.multiparts)
+	assert.EqualValues(t, 6*(len(payload)+3), len(uploader.objects[keys[0]]))
+}
+
+func TestSink_Bind_BuffersAcrossCallsAndCommitsAfterUpload(t *testing.T) {
+	uploader := newMemoryUploader()
+	sink, err := NewSink(uploader, Config{Bucket: "bucket", Partition: `{{.Metadata.p}}`, MaxObjectEntries: 2})
+	assert.Nil(t, err)
+	defer sink.Close()
+
+	commits := &commitRecorder{mutex: &sync.Mutex{}}
+	bound := sink.Bind(commits.commit)
+
+	a, b := map[string]string{"p": "a"}, map[string]string{"p": "b"}
+	assert.Nil(t, bound.Single(streams.Entry{Key: "1", Value: 1, Metadata: a}))
+	assert.Nil(t, bound.Single(streams.Entry{Key: "2", Value: 2, Metadata: b}))
+	assert.Empty(t, uploader.keys())
+	assert.Empty(t, commits.all())
+
+	// partition 'a' is full but entry '2' (partition 'b') isn't uploaded yet
+	assert.Nil(t, bound.Single(streams.Entry{Key: "3", Value: 3, Metadata: a}))
+	assert.EqualValues(t, 1, len(uploader.keys()))
+	assert.EqualValues(t, []string{"1"}, commits.all())
+
+	assert.Nil(t, sink.Flush())
+	assert.EqualValues(t, 2, len(uploader.keys()))
+	assert.EqualValues(t, []string{"1", "3"}, commits.all())
+}
+
+func TestSink_Bind_FlushInterval(t *testing.T) {
+	uploader := newMemoryUploader()
+	sink, err := NewSink(uploader, Config{Bucket: "bucket", Partition: "p", FlushInterval: 20 * time.Millisecond})
+	assert.Nil(t, err)
+	defer sink.Close()
+
+	commits := &commitRecorder{mutex: &sync.Mutex{}}
+	bound := sink.Bind(commits.commit)
+	assert.Nil(t, bound.Batch(streams.Entry{Key: "1", Value: 1}, streams.Entry{Key: "2", Value: 2}))
+
+	time.Sleep(100 * time.Millisecond)
+	assert.EqualValues(t, 1, len(uploader.keys()))
+	assert.EqualValues(t, []string{"2"}, commits.all())
+}
+
+func TestSink_Bind_RetriesFailedUploads(t *testing.T) {
+	uploader := newMemoryUploader()
+	sink, err := NewSink(uploader, Config{Bucket: "bucket", Partition: "p"})
+	assert.Nil(t, err)
+	defer sink.Close()
+
+	commits := &commitRecorder{mutex: &sync.Mutex{}}
+	bound := sink.Bind(commits.commit)
+	assert.Nil(t, bound.Single(streams.Entry{Key: "1", Value: 1}))
+
+	uploader.fail = true
+	assert.NotNil(t, sink.Flush())
+	assert.Empty(t, commits.all())
+
+	uploader.fail = false
+	assert.Nil(t, sink.Flush())
+	assert.EqualValues(t, []string{"1"}, commits.all())
+}
+
+func TestSink_ProcessorDoesntCommitBoundEntries(t *testing.T) {
+	uploader := newMemoryUploader()
+	sink, err := NewSink(uploader, Config{Bucket: "bucket", Partition: "p"})
+	assert.Nil(t, err)
+
+	source := streams.NewSequentialIntegerSource(3, time.Millisecond)
+	commits := &commitRecorder{mutex: &sync.Mutex{}}
+	streams.NewStream(&committingSource{Source: source, commits: commits}).Sink(sink).
+		Process(streams.NewDirectProcessor(), make(streams.ErrorChannel, 10))
+
+	// the processor flushes the sink once the stream ended, entries are committed by the sink only
+	assert.EqualValues(t, []string{"3"}, commits.all())
+	assert.EqualValues(t, 1, len(uploader.keys()))
+
+	assert.Nil(t, sink.Close())
+	assert.EqualValues(t, []string{"3"}, commits.all())
+}
+
+type commitRecorder struct {
+	mutex *sync.Mutex
+	keys  []string
+}
+
+func (c *commitRecorder) commit(keys ...string) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.keys = append(c.keys, keys...)
+	return nil
+}
+
+func (c *commitRecorder) all() []string {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.keys
+}
+
+type committingSource struct {
+	streams.Source
+	commits *commitRecorder
+}
+
+func (s *committingSource) CommitEntry(keys ...string) error {
+	return s.commits.commit(keys...)
+}
+
+func gunzip(t *testing.T, data []byte) string {
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	assert.Nil(t, err)
+	out, err := ioutil.ReadAll(r)
+	assert.Nil(t, err)
+	return string(out)
+}
+
+type memoryUploader struct {
+	fail       bool
+	mutex      *sync.Mutex
+	objects    map[string][]byte
+	uploads    map[string][][]byte
+	multiparts int
+}
+
+func newMemoryUploader() *memoryUploader {
+	return &memoryUploader{mutex: &sync.Mutex{}, objects: make(map[string][]byte), uploads: make(map[string][][]byte)}
+}
+
+func (u *memoryUploader) keys() []string {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	var out []string
+	for k := range u.objects {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return out
+}
+
+func (u *memoryUploader) Ping() error {
+	return nil
+}
+
+func (u *memoryUploader) PutObject(bucket, key string, body []byte) error {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	if u.fail {
+		return fmt.Errorf("service unavailable")
+	}
+	u.objects[key] = append([]byte{}, body...)
+	return nil
+}
+
+func (u *memoryUploader) GetObject(bucket, key string) ([]byte, error) {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	body, found := u.objects[key]
+	if !found {
+		return nil, fmt.Errorf("no such key '%s'", key)
+	}
+	return body, nil
+}
+
+func (u *memoryUploader) CreateMultipartUpload(bucket, key string) (string, error) {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	u.uploads[key] = nil
+	return key, nil
+}
+
+func (u *memoryUploader) UploadPart(bucket, key, uploadId string, number int, body []byte) (string, error) {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	u.uploads[uploadId] = append(u.uploads[uploadId], append([]byte{}, body...))
+	u.multiparts++
+	return fmt.Sprintf("etag-%d", number), nil
+}
+
+func (u *memoryUploader) CompleteMultipartUpload(bucket, key, uploadId string, parts []Part) error {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	var body []byte
+	for _, p := range u.uploads[uploadId] {
+		body = append(body, p...)
+	}
+	u.objects[key] = body
+	delete(u.uploads, uploadId)
+	return nil
+}
+
+func (u *memoryUploader) AbortMultipartUpload(bucket, key, uploadId string) error {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	delete(u.uploads, uploadId)
+	return nil
+}
@@ -0,0 +1,62 @@
+package go_streams
+
+import (
+	"hash/fnv"
+	"math"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+)
+
+// SampleStats are the counters of a Sampler
+type SampleStats struct {
+	In  uint64 `json:"in"`
+	Out uint64 `json:"out"`
+}
+
+// Sampler is an operator that keeps a sample of the entries (e.g: to downsample telemetry before expensive sinks)
+type Sampler struct {
+	keep func(entry Entry) bool
+	in   uint64
+	out  uint64
+}
+
+// NewSample creates a sampler that keeps each entry with the probability of the rate (between 0 and 1),
+// when key is set the decision is deterministic per key: all the entries of a key are kept or dropped.
+func NewSample(rate float64, key KeyFunc) *Sampler {
+	if key == nil {
+		return &Sampler{keep: func(Entry) bool { return rand.Float64() < rate }}
+	}
+	return &Sampler{keep: func(entry Entry) bool {
+		hash := fnv.New32a()
+		_, _ = hash.Write([]byte(key(entry.Value)))
+		return float64(hash.Sum32())/math.MaxUint32 < rate
+	}}
+}
+
+// NewSampleEvery creates a sampler that keeps one entry every n entries (the first, the n+1th and so on)
+func NewSampleEvery(n int) *Sampler {
+	mutex := &sync.Mutex{}
+	count := 0
+	return &Sampler{keep: func(Entry) bool {
+		mutex.Lock()
+		defer mutex.Unlock()
+		keep := n <= 1 || count%n == 0
+		count++
+		return keep
+	}}
+}
+
+func (this *Sampler) Apply(entry Entry) ([]Entry, error) {
+	if !this.keep(entry) {
+		atomic.AddUint64(&this.out, 1)
+		return nil, nil
+	}
+	atomic.AddUint64(&this.in, 1)
+	return []Entry{entry}, nil
+}
+
+// Stats returns the number of entries sampled in (kept) and out (dropped)
+func (this *Sampler) Stats() SampleStats {
+	return SampleStats{In: atomic.LoadUint64(&this.in), Out: atomic.LoadUint64(&this.out)}
+}
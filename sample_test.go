@@ -0,0 +1,40 @@
+package go_streams
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSample_Rate(t *testing.T) {
+	sampler := NewSample(0.25, nil)
+	for idx := 0; idx < 10000; idx++ {
+		_, err := sampler.Apply(Entry{Key: fmt.Sprint(idx), Value: idx})
+		assert.Nil(t, err)
+	}
+	stats := sampler.Stats()
+	assert.EqualValues(t, 10000, stats.In+stats.Out)
+	assert.InDelta(t, 2500, stats.In, 250)
+}
+
+func TestSample_DeterministicPerKey(t *testing.T) {
+	key := func(value interface{}) string { return fmt.Sprint(value.(int) % 10) }
+	first, second := NewSample(0.5, key), NewSample(0.5, key)
+	for idx := 0; idx < 100; idx++ {
+		a, _ := first.Apply(Entry{Value: idx})
+		b, _ := second.Apply(Entry{Value: idx % 10})
+		assert.EqualValues(t, len(a), len(b))
+	}
+	// every key is either always kept or always dropped
+	assert.EqualValues(t, 0, first.Stats().In%10)
+}
+
+func TestStream_SampleEvery(t *testing.T) {
+	sink := NewArraySink()
+	sampler := NewSampleEvery(3)
+	NewStream(NewSequentialIntegerSource(9, 0)).Via(sampler).Sink(sink).
+		Process(NewDirectProcessor(), make(ErrorChannel, 10))
+	assert.EqualValues(t, []interface{}{0, 3, 6, 9}, sink.array)
+	assert.EqualValues(t, SampleStats{In: 4, Out: 6}, sampler.Stats())
+}
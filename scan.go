@@ -0,0 +1,60 @@
+package go_streams
+
+import "sync"
+
+// ScanFunc folds the value of an entry into the state, it returns the new state and the value emitted for the entry
+type ScanFunc func(state, value interface{}) (newState, out interface{})
+
+// Scanner is an operator that maps every entry while carrying a state forward (e.g: running totals,
+// the difference with the previous entry), see Stream.Scan. The state is kept in memory and shared by
+// all the entries of the stream, so it follows the order of the entries only with in order processors.
+type Scanner struct {
+	fn    ScanFunc
+	mutex *sync.Mutex
+	state interface{}
+}
+
+// NewScan creates a scanner starting from the initial state
+func NewScan(init interface{}, fn ScanFunc) *Scanner {
+	return &Scanner{fn: fn, mutex: &sync.Mutex{}, state: init}
+}
+
+// Apply replaces the value of the entry by the output of the function, the state isn't updated when it panics
+func (this *Scanner) Apply(entry Entry) ([]Entry, error) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	state, out := this.fn(this.state, entry.Value)
+	this.state = state
+	entry.Value = out
+	return []Entry{entry}, nil
+}
+
+// State returns the current state
+func (this *Scanner) State() interface{} {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	return this.state
+}
+
+type scanState struct {
+	State interface{}
+}
+
+// Snapshot encodes the state, see Snapshotter
+func (this *Scanner) Snapshot() ([]byte, error) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	return encodeState(&scanState{State: this.state})
+}
+
+// Restore replaces the state by a snapshot
+func (this *Scanner) Restore(data []byte) error {
+	var state scanState
+	if err := decodeState(data, &state); err != nil {
+		return err
+	}
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.state = state.State
+	return nil
+}
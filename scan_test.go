@@ -0,0 +1,43 @@
+package go_streams
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStream_Scan_RunningTotal(t *testing.T) {
+	sink := NewArraySink()
+	NewStream(NewSequentialIntegerSource(4, 0)).Scan(0, func(state, value interface{}) (interface{}, interface{}) {
+		total := state.(int) + value.(int)
+		return total, total
+	}).Sink(sink).Process(NewDirectProcessor(), make(ErrorChannel, 10))
+	assert.EqualValues(t, []interface{}{0, 1, 3, 6, 10}, sink.array)
+}
+
+func TestScan_KeepsStateWhenFunctionPanics(t *testing.T) {
+	// emits the difference with the previous value
+	scanner := NewScan(nil, func(state, value interface{}) (interface{}, interface{}) {
+		if value == nil {
+			panic("nil value")
+		}
+		if state == nil {
+			return value, 0
+		}
+		return value, value.(int) - state.(int)
+	})
+
+	out, err := scanner.Apply(Entry{Key: "1", Value: 5})
+	assert.Nil(t, err)
+	assert.EqualValues(t, 0, out[0].Value)
+
+	_, err = recoverOperator(scanner, Entry{Key: "2"})
+	assert.IsType(t, &OperatorError{}, err)
+	assert.EqualValues(t, 5, scanner.State())
+
+	out, err = scanner.Apply(Entry{Key: "3", Value: 8})
+	assert.Nil(t, err)
+	assert.EqualValues(t, "3", out[0].Key)
+	assert.EqualValues(t, 3, out[0].Value)
+	assert.EqualValues(t, 8, scanner.State())
+}
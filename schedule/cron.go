@@ -0,0 +1,155 @@
+package schedule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Cron is a parsed cron expression with the standard 5 fields:
+//
+//	minute hour day-of-month month day-of-week
+//
+// Fields accept '*', lists (1,2), ranges (1-5) and steps (*/15, 0-30/5),
+// day of week goes from 0 (Sunday) to 6. The descriptors @yearly, @monthly,
+// @weekly, @daily and @hourly are supported too.
+type Cron struct {
+	minute, hour, dom, month, dow uint64
+	domStar, dowStar              bool
+}
+
+var descriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+type bounds struct {
+	min, max int
+}
+
+var fieldBounds = []bounds{{0, 59}, {0, 23}, {1, 31}, {1, 12}, {0, 6}}
+
+func ParseCron(expr string) (*Cron, error) {
+	expr = strings.TrimSpace(expr)
+	if descriptor, found := descriptors[expr]; found {
+		expr = descriptor
+	}
+
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("cron expression should have 5 fields, got: '%s'", expr)
+	}
+
+	bits := make([]uint64, 5)
+	for idx, field := range fields {
+		parsed, err := parseField(field, fieldBounds[idx])
+		if err != nil {
+			return nil, fmt.Errorf("invalid cron field '%s': %s", field, err.Error())
+		}
+		bits[idx] = parsed
+	}
+
+	return &Cron{
+		minute:  bits[0],
+		hour:    bits[1],
+		dom:     bits[2],
+		month:   bits[3],
+		dow:     bits[4],
+		domStar: strings.HasPrefix(fields[2], "*"),
+		dowStar: strings.HasPrefix(fields[4], "*"),
+	}, nil
+}
+
+func parseField(field string, b bounds) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		if idx := strings.Index(part, "/"); idx >= 0 {
+			s, err := strconv.Atoi(part[idx+1:])
+			if err != nil || s <= 0 {
+				return 0, fmt.Errorf("invalid step")
+			}
+			step, part = s, part[:idx]
+		}
+
+		from, to := b.min, b.max
+		switch {
+		case part == "*":
+		case strings.Contains(part, "-"):
+			bounds := strings.SplitN(part, "-", 2)
+			var err error
+			if from, err = strconv.Atoi(bounds[0]); err != nil {
+				return 0, err
+			}
+			if to, err = strconv.Atoi(bounds[1]); err != nil {
+				return 0, err
+			}
+		default:
+			value, err := strconv.Atoi(part)
+			if err != nil {
+				return 0, err
+			}
+			from, to = value, value
+			if step > 1 {
+				to = b.max
+			}
+		}
+
+		if from < b.min || to > b.max || from > to {
+			return 0, fmt.Errorf("out of range [%d-%d]", b.min, b.max)
+		}
+		for value := from; value <= to; value += step {
+			bits |= 1 << uint(value)
+		}
+	}
+	return bits, nil
+}
+
+// maxYears bounds the search of Next, expressions like "0 0 31 2 *" never match
+const maxYears = 5
+
+// Next returns the first time matching the expression strictly after t (zero if there is none)
+func (this *Cron) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(maxYears, 0, 0)
+
+	for t.Before(limit) {
+		if !has(this.month, int(t.Month())) {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !this.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !has(this.hour, t.Hour()) {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if !has(this.minute, t.Minute()) {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+// dayMatches follows cron: when both day fields are restricted either of them should match
+func (this *Cron) dayMatches(t time.Time) bool {
+	dom, dow := has(this.dom, t.Day()), has(this.dow, int(t.Weekday()))
+	if this.domStar || this.dowStar {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+func has(bits uint64, value int) bool {
+	return bits&(1<<uint(value)) != 0
+}
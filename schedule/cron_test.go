@@ -0,0 +1,48 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func at(value string) time.Time {
+	t, err := time.Parse("2006-01-02 15:04", value)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestCron_Next(t *testing.T) {
+	cases := []struct {
+		expr, from, next string
+	}{
+		{"* * * * *", "2024-01-01 10:00", "2024-01-01 10:01"},
+		{"*/15 * * * *", "2024-01-01 10:01", "2024-01-01 10:15"},
+		{"0 9-17/4 * * *", "2024-01-01 13:00", "2024-01-01 17:00"},
+		{"30 2 * * 1", "2024-01-01 03:00", "2024-01-08 02:30"},
+		{"0 0 29 2 *", "2024-03-01 00:00", "2028-02-29 00:00"},
+		{"0 0 1,15 * 5", "2024-01-02 00:00", "2024-01-05 00:00"},
+		{"@monthly", "2024-01-31 23:59", "2024-02-01 00:00"},
+	}
+	for _, c := range cases {
+		cron, err := ParseCron(c.expr)
+		assert.Nil(t, err, c.expr)
+		assert.EqualValues(t, at(c.next), cron.Next(at(c.from)), c.expr)
+	}
+}
+
+func TestCron_NeverMatches(t *testing.T) {
+	cron, err := ParseCron("0 0 31 2 *")
+	assert.Nil(t, err)
+	assert.True(t, cron.Next(at("2024-01-01 00:00")).IsZero())
+}
+
+func TestParseCron_Errors(t *testing.T) {
+	for _, expr := range []string{"* * * *", "60 * * * *", "* * * * 7", "*/0 * * * *", "a * * * *", "5-1 * * * *"} {
+		_, err := ParseCron(expr)
+		assert.NotNil(t, err, expr)
+	}
+}
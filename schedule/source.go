@@ -0,0 +1,155 @@
+package schedule
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	streams "github.com/matang28/go-streams"
+)
+
+// Generator returns the payload of the entry emitted for the given tick
+type Generator func(tick time.Time) (interface{}, error)
+
+type Config struct {
+	// Name of the source (defaults to a generated name)
+	Name string
+
+	// Interval emits an entry every interval, exactly one of Interval and Cron should be set
+	Interval time.Duration
+
+	// Cron emits an entry on every minute matching the expression (see ParseCron)
+	Cron string
+
+	// Location is the time zone of the cron expression (defaults to time.Local)
+	Location *time.Location
+
+	// Generate returns the payload of each entry, the value is the tick time when it isn't set.
+	// Generator errors are reported and the tick is skipped.
+	Generate Generator
+}
+
+// Source emits an entry on every tick of its schedule, the key of each entry is its tick time
+// (RFC3339) so the latest committed tick can be read with LastCommit.
+type Source struct {
+	name     string
+	interval time.Duration
+	cron     *Cron
+	location *time.Location
+	generate Generator
+
+	mutex      *sync.Mutex
+	lastCommit string
+	closeCh    chan struct{}
+	once       *sync.Once
+}
+
+func NewSource(config Config) (*Source, error) {
+	if (config.Interval > 0) == (config.Cron != "") {
+		return nil, fmt.Errorf("exactly one of interval and cron should be set")
+	}
+
+	source := &Source{
+		name:     config.Name,
+		interval: config.Interval,
+		location: config.Location,
+		generate: config.Generate,
+		mutex:    &sync.Mutex{},
+		closeCh:  make(chan struct{}),
+		once:     &sync.Once{},
+	}
+	if source.name == "" {
+		source.name = fmt.Sprintf("scheduleSource-%d", time.Now().UnixNano())
+	}
+	if source.location == nil {
+		source.location = time.Local
+	}
+	if config.Cron != "" {
+		cron, err := ParseCron(config.Cron)
+		if err != nil {
+			return nil, err
+		}
+		source.cron = cron
+	}
+	return source, nil
+}
+
+func (this *Source) Start(channel streams.EntryChannel, errorChannel streams.ErrorChannel) {
+	defer func() {
+		close(channel)
+		errorChannel <- streams.NewEofError(this)
+	}()
+
+	for {
+		now := time.Now().In(this.location)
+		next := this.next(now)
+		if next.IsZero() {
+			errorChannel <- fmt.Errorf("the schedule of '%s' never fires again", this.name)
+			return
+		}
+
+		timer := time.NewTimer(next.Sub(now))
+		select {
+		case <-this.closeCh:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		entry, err := this.entry(next)
+		if err != nil {
+			errorChannel <- err
+			continue
+		}
+		select {
+		case <-this.closeCh:
+			return
+		case channel <- entry:
+		}
+	}
+}
+
+func (this *Source) next(now time.Time) time.Time {
+	if this.cron != nil {
+		return this.cron.Next(now)
+	}
+	return now.Add(this.interval)
+}
+
+func (this *Source) entry(tick time.Time) (streams.Entry, error) {
+	var value interface{} = tick
+	if this.generate != nil {
+		var err error
+		if value, err = this.generate(tick); err != nil {
+			return streams.Entry{}, err
+		}
+	}
+	return streams.Entry{Key: tick.Format(time.RFC3339Nano), Value: value, Timestamp: tick}, nil
+}
+
+func (this *Source) Stop() error {
+	this.once.Do(func() { close(this.closeCh) })
+	return nil
+}
+
+func (this *Source) Ping() error {
+	return nil
+}
+
+func (this *Source) CommitEntry(keys ...string) error {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.lastCommit = keys[len(keys)-1]
+	return nil
+}
+
+// LastCommit returns the key (tick time) of the latest committed entry
+func (this *Source) LastCommit() string {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	return this.lastCommit
+}
+
+func (this *Source) Name() string {
+	return this.name
+}
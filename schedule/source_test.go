@@ -0,0 +1,45 @@
+package schedule
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	streams "github.com/matang28/go-streams"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSource_Interval(t *testing.T) {
+	var ticks int32
+	source, err := NewSource(Config{Interval: 10 * time.Millisecond, Generate: func(tick time.Time) (interface{}, error) {
+		if atomic.AddInt32(&ticks, 1) == 2 {
+			return nil, fmt.Errorf("api unavailable")
+		}
+		return "refresh", nil
+	}})
+	assert.Nil(t, err)
+
+	sink := streams.NewArraySink()
+	errs := make(streams.ErrorChannel, 10)
+	go func() {
+		time.Sleep(55 * time.Millisecond)
+		_ = source.Stop()
+	}()
+	streams.NewStream(source).Sink(sink).Process(streams.NewDirectProcessor(), errs)
+
+	// the second tick failed and was skipped
+	assert.True(t, len(sink.Array()) >= 3)
+	assert.EqualValues(t, "refresh", sink.Array()[0])
+	assert.NotEmpty(t, source.LastCommit())
+	assert.Contains(t, (<-errs).Error(), "api unavailable")
+}
+
+func TestNewSource_Errors(t *testing.T) {
+	_, err := NewSource(Config{})
+	assert.NotNil(t, err)
+	_, err = NewSource(Config{Interval: time.Second, Cron: "* * * * *"})
+	assert.NotNil(t, err)
+	_, err = NewSource(Config{Cron: "bad"})
+	assert.NotNil(t, err)
+}
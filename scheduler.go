@@ -0,0 +1,218 @@
+package go_streams
+
+import (
+	"sync"
+	"time"
+)
+
+// SchedulerStats are the scheduling counters of a stream,
+// Granted is the number of work units (entries or batches) it processed and Waited the time it waited for them.
+type SchedulerStats struct {
+	Weight  int           `json:"weight"`
+	Granted uint64        `json:"granted"`
+	Waiting int           `json:"waiting"`
+	Waited  time.Duration `json:"waited"`
+}
+
+// Scheduler shares a processing capacity between the streams of an engine (see Engine.SetScheduler):
+// at most capacity work units are processed at once (an entry with the direct and sharded processors,
+// a batch with the buffered processor) and while streams compete for it each one gets a share
+// proportional to its weight (see Stream.Priority). A stream alone uses the whole capacity.
+type Scheduler struct {
+	mutex    *sync.Mutex
+	capacity int
+	busy     int
+
+	// clock is the virtual time of the latest grant, streams that were idle start from it
+	// instead of catching up on the share they didn't use
+	clock   float64
+	waiting []*schedulerWaiter
+	streams map[string]*scheduledStream
+}
+
+// schedulerLease is the time the capacity is kept for a stream that released it while it was still the most
+// behind its share, processors usually come back for their next work unit right away
+const schedulerLease = time.Millisecond
+
+type schedulerWaiter struct {
+	stream *scheduledStream
+	since  time.Time
+	ready  chan struct{}
+}
+
+// scheduledStream is the handle of a stream on the scheduler, a nil handle doesn't wait
+type scheduledStream struct {
+	scheduler *Scheduler
+	name      string
+	weight    int
+
+	// pass is the virtual time the stream consumed its share until
+	pass    float64
+	lease   *time.Timer
+	leases  uint64
+	granted uint64
+	waiting int
+	waited  time.Duration
+}
+
+func NewScheduler(capacity int) *Scheduler {
+	if capacity <= 0 {
+		capacity = 1
+	}
+	return &Scheduler{mutex: &sync.Mutex{}, capacity: capacity, streams: make(map[string]*scheduledStream)}
+}
+
+// Stats returns the scheduling counters of the streams by their name
+func (this *Scheduler) Stats() map[string]SchedulerStats {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	stats := make(map[string]SchedulerStats, len(this.streams))
+	for name, s := range this.streams {
+		stats[name] = s.stats()
+	}
+	return stats
+}
+
+func (this *Scheduler) statsOf(name string) *SchedulerStats {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	s, found := this.streams[name]
+	if !found {
+		return nil
+	}
+	stats := s.stats()
+	return &stats
+}
+
+// register returns the handle of a stream, a restarted stream keeps its counters
+func (this *Scheduler) register(name string, weight int) *scheduledStream {
+	if weight <= 0 {
+		weight = 1
+	}
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	s, found := this.streams[name]
+	if !found {
+		s = &scheduledStream{scheduler: this, name: name}
+		this.streams[name] = s
+	}
+	s.weight = weight
+	return s
+}
+
+func (this *Scheduler) unregister(name string) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	delete(this.streams, name)
+}
+
+// grant advances the virtual time of the stream by its share, the caller holds the mutex
+func (this *Scheduler) grant(s *scheduledStream) {
+	this.clock = this.start(s)
+	s.pass = this.clock + 1/float64(s.weight)
+	s.granted++
+}
+
+// start is the virtual time the next work unit of the stream starts at, the caller holds the mutex
+func (this *Scheduler) start(s *scheduledStream) float64 {
+	if s.pass < this.clock {
+		return this.clock
+	}
+	return s.pass
+}
+
+// acquire waits until the stream may process a work unit
+func (this *scheduledStream) acquire() {
+	if this == nil {
+		return
+	}
+	scheduler := this.scheduler
+	scheduler.mutex.Lock()
+	if this.lease != nil {
+		this.lease.Stop()
+		this.lease = nil
+		scheduler.grant(this)
+		scheduler.mutex.Unlock()
+		return
+	}
+	if scheduler.busy < scheduler.capacity && len(scheduler.waiting) == 0 {
+		scheduler.busy++
+		scheduler.grant(this)
+		scheduler.mutex.Unlock()
+		return
+	}
+	waiter := &schedulerWaiter{stream: this, since: time.Now(), ready: make(chan struct{})}
+	scheduler.waiting = append(scheduler.waiting, waiter)
+	this.waiting++
+	scheduler.mutex.Unlock()
+	<-waiter.ready
+}
+
+// release hands the capacity of a processed work unit to the waiting stream that is the most behind its share,
+// the capacity is leased to the releasing stream when it is still the most behind.
+func (this *scheduledStream) release() {
+	if this == nil {
+		return
+	}
+	scheduler := this.scheduler
+	scheduler.mutex.Lock()
+	defer scheduler.mutex.Unlock()
+	next := scheduler.next()
+	if next >= 0 && this.lease == nil && scheduler.start(this) <= scheduler.start(scheduler.waiting[next].stream) {
+		this.leases++
+		id := this.leases
+		this.lease = time.AfterFunc(schedulerLease, func() { this.expire(id) })
+		return
+	}
+	scheduler.handOff(next)
+}
+
+// expire hands off the capacity leased to the stream unless it came back for it
+func (this *scheduledStream) expire(id uint64) {
+	scheduler := this.scheduler
+	scheduler.mutex.Lock()
+	defer scheduler.mutex.Unlock()
+	if this.lease == nil || this.leases != id {
+		return
+	}
+	this.lease = nil
+	scheduler.handOff(scheduler.next())
+}
+
+// next returns the index of the first waiter of the stream the most behind its share (-1 when none waits),
+// the caller holds the mutex
+func (this *Scheduler) next() int {
+	next := -1
+	for idx := range this.waiting {
+		if next < 0 || this.start(this.waiting[idx].stream) < this.start(this.waiting[next].stream) {
+			next = idx
+		}
+	}
+	return next
+}
+
+// handOff grants the released capacity to the waiter (if any), the caller holds the mutex
+func (this *Scheduler) handOff(next int) {
+	if next < 0 {
+		this.busy--
+		return
+	}
+	waiter := this.waiting[next]
+	this.waiting = append(this.waiting[:next], this.waiting[next+1:]...)
+	waiter.stream.waiting--
+	waiter.stream.waited += time.Since(waiter.since)
+	this.grant(waiter.stream)
+	close(waiter.ready)
+}
+
+func (this *scheduledStream) stats() SchedulerStats {
+	return SchedulerStats{Weight: this.weight, Granted: this.granted, Waiting: this.waiting, Waited: this.waited}
+}
+
+// scheduled returns the scheduler handle of a stream processed by the engine (nil when it has none)
+func scheduled(stream Stream) *scheduledStream {
+	if managed, ok := stream.(*managedStream); ok {
+		return managed.source.scheduled
+	}
+	return nil
+}
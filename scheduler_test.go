@@ -0,0 +1,93 @@
+package go_streams
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScheduler_SharesTheCapacityByWeight(t *testing.T) {
+	scheduler := NewScheduler(1)
+	holder := scheduler.register("holder", 1)
+	holder.acquire()
+
+	mutex := &sync.Mutex{}
+	var grants []string
+	wg := &sync.WaitGroup{}
+	for name, weight := range map[string]int{"critical": 3, "backfill": 1} {
+		s := scheduler.register(name, weight)
+		wg.Add(1)
+		go func(name string, s *scheduledStream) {
+			defer wg.Done()
+			for idx := 0; idx < 40; idx++ {
+				s.acquire()
+				mutex.Lock()
+				grants = append(grants, name)
+				mutex.Unlock()
+				s.release()
+			}
+		}(name, s)
+	}
+	assert.Eventually(t, func() bool {
+		stats := scheduler.Stats()
+		return stats["critical"].Waiting == 1 && stats["backfill"].Waiting == 1
+	}, time.Second, time.Millisecond)
+	holder.release()
+	wg.Wait()
+
+	critical := 0
+	for _, name := range grants[:40] {
+		if name == "critical" {
+			critical++
+		}
+	}
+	assert.InDelta(t, 30, critical, 1)
+	assert.EqualValues(t, 40, scheduler.Stats()["backfill"].Granted)
+}
+
+func TestScheduler_IdleStreamsDontCatchUp(t *testing.T) {
+	scheduler := NewScheduler(1)
+	busy, idle := scheduler.register("busy", 1), scheduler.register("idle", 1)
+	for idx := 0; idx < 10; idx++ {
+		busy.acquire()
+		busy.release()
+	}
+
+	// the idle stream starts from the current share instead of getting the next 10 grants
+	idle.acquire()
+	waiting := make(chan struct{})
+	go func() {
+		busy.acquire()
+		close(waiting)
+	}()
+	assert.Eventually(t, func() bool { return scheduler.Stats()["busy"].Waiting == 1 }, time.Second, time.Millisecond)
+	go idle.acquire()
+	assert.Eventually(t, func() bool { return scheduler.Stats()["idle"].Waiting == 1 }, time.Second, time.Millisecond)
+	idle.release()
+	<-waiting
+	assert.EqualValues(t, 11, scheduler.Stats()["busy"].Granted)
+}
+
+func TestEngine_SetScheduler(t *testing.T) {
+	engine := NewEngine(NewDirectProcessorFactory(), 10*time.Second)
+	engine.SetScheduler(NewScheduler(1))
+	critical, backfill := NewSequentialIntegerSource(10, 0), NewSequentialIntegerSource(10, 0)
+	assert.Nil(t, engine.Add(
+		NewStream(critical).Priority(5).Sink(NewArraySink()),
+		NewStream(backfill).Sink(NewArraySink()),
+	))
+	engine.Start()
+
+	infos := engine.Streams()
+	assert.Len(t, infos, 2)
+	for _, info := range infos {
+		assert.EqualValues(t, 11, info.Scheduler.Granted)
+		if info.Name == critical.Name() {
+			assert.EqualValues(t, 5, info.Scheduler.Weight)
+		} else {
+			assert.EqualValues(t, 1, info.Scheduler.Weight)
+		}
+	}
+}
@@ -0,0 +1,588 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	streams "github.com/matang28/go-streams"
+)
+
+// JSONSchema validates entry values against a JSON Schema (draft 7), it supports the type, enum and const keywords,
+// the numeric, string, array and object keywords, allOf/anyOf/oneOf/not and local references
+// (e.g: "#/definitions/address"). The date-time, date, email and uuid formats are checked, other formats are ignored.
+type JSONSchema struct {
+	root *node
+}
+
+// JSON compiles a JSON Schema, []byte values are validated as JSON documents
+// and other values (e.g: maps or structs) by their JSON encoding.
+func JSON(schema []byte) (*JSONSchema, error) {
+	var raw interface{}
+	if err := json.Unmarshal(schema, &raw); err != nil {
+		return nil, fmt.Errorf("invalid JSON schema: %s", err.Error())
+	}
+	c := &compiler{document: raw, refs: make(map[string]*node)}
+	root, err := c.compile(raw, "#")
+	if err != nil {
+		return nil, err
+	}
+	return &JSONSchema{root: root}, nil
+}
+
+func (this *JSONSchema) Validate(value interface{}) []streams.Violation {
+	doc, err := document(value)
+	if err != nil {
+		return []streams.Violation{{Message: err.Error()}}
+	}
+	var violations []streams.Violation
+	this.root.validate(doc, "", &violations)
+	return violations
+}
+
+// document returns the value decoded the way encoding/json decodes JSON documents into interface{}
+func document(value interface{}) (interface{}, error) {
+	data, ok := value.([]byte)
+	if !ok {
+		if raw, isRaw := value.(json.RawMessage); isRaw {
+			data = raw
+		} else {
+			encoded, err := json.Marshal(value)
+			if err != nil {
+				return nil, fmt.Errorf("value of type %T can't be encoded to JSON: %s", value, err.Error())
+			}
+			data = encoded
+		}
+	}
+	var doc interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %s", err.Error())
+	}
+	return doc, nil
+}
+
+type patternNode struct {
+	pattern *regexp.Regexp
+	schema  *node
+}
+
+// node is a compiled schema
+type node struct {
+	// always is set for the boolean schemas (true accepts everything, false nothing)
+	always *bool
+	ref    *node
+
+	types    []string
+	enum     []interface{}
+	hasConst bool
+	constant interface{}
+
+	minimum, maximum, exclusiveMinimum, exclusiveMaximum, multipleOf *float64
+
+	minLength, maxLength *int
+	pattern              *regexp.Regexp
+	format               string
+
+	items               *node
+	tupleItems          []*node
+	minItems, maxItems  *int
+	uniqueItems         bool
+	properties          map[string]*node
+	patternProperties   []patternNode
+	additionalProps     *node
+	required            []string
+	minProps, maxProps  *int
+	allOf, anyOf, oneOf []*node
+	not                 *node
+}
+
+type compiler struct {
+	document interface{}
+	refs     map[string]*node
+}
+
+func (this *compiler) compile(raw interface{}, pointer string) (*node, error) {
+	if b, ok := raw.(bool); ok {
+		return &node{always: &b}, nil
+	}
+	obj, ok := raw.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("schema at '%s' must be an object or a boolean", pointer)
+	}
+	n := &node{}
+	this.refs[pointer] = n
+
+	if ref, found := obj["$ref"]; found {
+		target, ok := ref.(string)
+		if !ok {
+			return nil, fmt.Errorf("$ref at '%s' must be a string", pointer)
+		}
+		resolved, err := this.resolve(target)
+		if err != nil {
+			return nil, err
+		}
+		// draft 7 ignores the keywords next to a $ref
+		n.ref = resolved
+		return n, nil
+	}
+
+	var err error
+	keyword := func(name string) (interface{}, bool) {
+		v, found := obj[name]
+		return v, found && err == nil
+	}
+	fail := func(name, expected string) {
+		if err == nil {
+			err = fmt.Errorf("keyword '%s' at '%s' must be %s", name, pointer, expected)
+		}
+	}
+	number := func(name string) *float64 {
+		v, found := keyword(name)
+		if !found {
+			return nil
+		}
+		f, ok := v.(float64)
+		if !ok {
+			fail(name, "a number")
+			return nil
+		}
+		return &f
+	}
+	count := func(name string) *int {
+		f := number(name)
+		if f == nil {
+			return nil
+		}
+		if *f < 0 || *f != math.Trunc(*f) {
+			fail(name, "a non negative integer")
+			return nil
+		}
+		i := int(*f)
+		return &i
+	}
+	schemas := func(name string) []*node {
+		v, found := keyword(name)
+		if !found {
+			return nil
+		}
+		list, ok := v.([]interface{})
+		if !ok || len(list) == 0 {
+			fail(name, "a non empty array of schemas")
+			return nil
+		}
+		nodes := make([]*node, len(list))
+		for idx := range list {
+			if nodes[idx], err = this.compile(list[idx], fmt.Sprintf("%s/%s/%d", pointer, name, idx)); err != nil {
+				return nil
+			}
+		}
+		return nodes
+	}
+	schema := func(name string) *node {
+		v, found := keyword(name)
+		if !found {
+			return nil
+		}
+		compiled, compileErr := this.compile(v, pointer+"/"+name)
+		if compileErr != nil {
+			err = compileErr
+		}
+		return compiled
+	}
+	regexpOf := func(name, expr string) *regexp.Regexp {
+		compiled, compileErr := regexp.Compile(expr)
+		if compileErr != nil && err == nil {
+			err = fmt.Errorf("keyword '%s' at '%s' has an invalid pattern: %s", name, pointer, compileErr.Error())
+		}
+		return compiled
+	}
+
+	if v, found := keyword("type"); found {
+		switch t := v.(type) {
+		case string:
+			n.types = []string{t}
+		case []interface{}:
+			for _, item := range t {
+				s, ok := item.(string)
+				if !ok {
+					fail("type", "a string or an array of strings")
+				}
+				n.types = append(n.types, s)
+			}
+		default:
+			fail("type", "a string or an array of strings")
+		}
+	}
+	if v, found := keyword("enum"); found {
+		if n.enum, ok = v.([]interface{}); !ok {
+			fail("enum", "an array")
+		}
+	}
+	n.constant, n.hasConst = obj["const"]
+
+	n.minimum, n.maximum = number("minimum"), number("maximum")
+	n.exclusiveMinimum, n.exclusiveMaximum = number("exclusiveMinimum"), number("exclusiveMaximum")
+	n.multipleOf = number("multipleOf")
+	if n.multipleOf != nil && *n.multipleOf <= 0 {
+		fail("multipleOf", "a positive number")
+	}
+
+	n.minLength, n.maxLength = count("minLength"), count("maxLength")
+	if v, found := keyword("pattern"); found {
+		if s, ok := v.(string); ok {
+			n.pattern = regexpOf("pattern", s)
+		} else {
+			fail("pattern", "a string")
+		}
+	}
+	if v, found := keyword("format"); found {
+		if n.format, ok = v.(string); !ok {
+			fail("format", "a string")
+		}
+	}
+
+	if v, found := keyword("items"); found {
+		if _, isTuple := v.([]interface{}); isTuple {
+			n.tupleItems = schemas("items")
+		} else {
+			n.items = schema("items")
+		}
+	}
+	n.minItems, n.maxItems = count("minItems"), count("maxItems")
+	if v, found := keyword("uniqueItems"); found {
+		if n.uniqueItems, ok = v.(bool); !ok {
+			fail("uniqueItems", "a boolean")
+		}
+	}
+
+	if v, found := keyword("properties"); found {
+		props, ok := v.(map[string]interface{})
+		if !ok {
+			fail("properties", "an object")
+		}
+		n.properties = make(map[string]*node, len(props))
+		for name, prop := range props {
+			compiled, compileErr := this.compile(prop, pointer+"/properties/"+escape(name))
+			if compileErr != nil && err == nil {
+				err = compileErr
+			}
+			n.properties[name] = compiled
+		}
+	}
+	if v, found := keyword("patternProperties"); found {
+		props, ok := v.(map[string]interface{})
+		if !ok {
+			fail("patternProperties", "an object")
+		}
+		for _, expr := range sortedKeys(props) {
+			compiled, compileErr := this.compile(props[expr], pointer+"/patternProperties/"+escape(expr))
+			if compileErr != nil && err == nil {
+				err = compileErr
+			}
+			n.patternProperties = append(n.patternProperties, patternNode{pattern: regexpOf("patternProperties", expr), schema: compiled})
+		}
+	}
+	n.additionalProps = schema("additionalProperties")
+	if v, found := keyword("required"); found {
+		list, ok := v.([]interface{})
+		if !ok {
+			fail("required", "an array of strings")
+		}
+		for _, item := range list {
+			s, ok := item.(string)
+			if !ok {
+				fail("required", "an array of strings")
+			}
+			n.required = append(n.required, s)
+		}
+	}
+	n.minProps, n.maxProps = count("minProperties"), count("maxProperties")
+
+	n.allOf, n.anyOf, n.oneOf = schemas("allOf"), schemas("anyOf"), schemas("oneOf")
+	n.not = schema("not")
+	if err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+// resolve compiles the schema a local reference points at, references to schemas being compiled are resolved to them
+func (this *compiler) resolve(ref string) (*node, error) {
+	if !strings.HasPrefix(ref, "#") {
+		return nil, fmt.Errorf("unsupported $ref '%s', only local references are supported", ref)
+	}
+	if n, found := this.refs[ref]; found {
+		return n, nil
+	}
+
+	target := this.document
+	for _, token := range strings.Split(strings.TrimPrefix(ref, "#"), "/")[1:] {
+		token = strings.Replace(strings.Replace(token, "~1", "/", -1), "~0", "~", -1)
+		switch current := target.(type) {
+		case map[string]interface{}:
+			target = current[token]
+		case []interface{}:
+			idx, err := strconv.Atoi(token)
+			if err != nil || idx < 0 || idx >= len(current) {
+				return nil, fmt.Errorf("$ref '%s' doesn't exist", ref)
+			}
+			target = current[idx]
+		default:
+			target = nil
+		}
+		if target == nil {
+			return nil, fmt.Errorf("$ref '%s' doesn't exist", ref)
+		}
+	}
+	return this.compile(target, ref)
+}
+
+func (this *node) valid(value interface{}) bool {
+	var violations []streams.Violation
+	this.validate(value, "", &violations)
+	return len(violations) == 0
+}
+
+func (this *node) validate(value interface{}, path string, violations *[]streams.Violation) {
+	violate := func(format string, args ...interface{}) {
+		*violations = append(*violations, streams.Violation{Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+	if this.always != nil {
+		if !*this.always {
+			violate("no value is allowed")
+		}
+		return
+	}
+	if this.ref != nil {
+		this.ref.validate(value, path, violations)
+		return
+	}
+
+	if len(this.types) > 0 && !matchesType(value, this.types) {
+		violate("expected %s, got %s", strings.Join(this.types, " or "), typeOf(value))
+		return
+	}
+	if this.enum != nil && !contains(this.enum, value) {
+		violate("must be one of %s", encode(this.enum))
+	}
+	if this.hasConst && !reflect.DeepEqual(this.constant, value) {
+		violate("must be %s", encode(this.constant))
+	}
+
+	switch v := value.(type) {
+	case float64:
+		this.validateNumber(v, violate)
+	case string:
+		this.validateString(v, violate)
+	case []interface{}:
+		this.validateArray(v, path, violations, violate)
+	case map[string]interface{}:
+		this.validateObject(v, path, violations, violate)
+	}
+
+	for _, schema := range this.allOf {
+		schema.validate(value, path, violations)
+	}
+	if this.anyOf != nil {
+		matched := false
+		for _, schema := range this.anyOf {
+			if schema.valid(value) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			violate("must match at least one of the anyOf schemas")
+		}
+	}
+	if this.oneOf != nil {
+		matched := 0
+		for _, schema := range this.oneOf {
+			if schema.valid(value) {
+				matched++
+			}
+		}
+		if matched != 1 {
+			violate("must match exactly one of the oneOf schemas (matched %d)", matched)
+		}
+	}
+	if this.not != nil && this.not.valid(value) {
+		violate("must not match the not schema")
+	}
+}
+
+func (this *node) validateNumber(v float64, violate func(string, ...interface{})) {
+	if this.minimum != nil && v < *this.minimum {
+		violate("must be >= %v", *this.minimum)
+	}
+	if this.maximum != nil && v > *this.maximum {
+		violate("must be <= %v", *this.maximum)
+	}
+	if this.exclusiveMinimum != nil && v <= *this.exclusiveMinimum {
+		violate("must be > %v", *this.exclusiveMinimum)
+	}
+	if this.exclusiveMaximum != nil && v >= *this.exclusiveMaximum {
+		violate("must be < %v", *this.exclusiveMaximum)
+	}
+	if this.multipleOf != nil {
+		if q := v / *this.multipleOf; math.Abs(q-math.Round(q)) > 1e-9 {
+			violate("must be a multiple of %v", *this.multipleOf)
+		}
+	}
+}
+
+func (this *node) validateString(v string, violate func(string, ...interface{})) {
+	length := utf8.RuneCountInString(v)
+	if this.minLength != nil && length < *this.minLength {
+		violate("length must be >= %d", *this.minLength)
+	}
+	if this.maxLength != nil && length > *this.maxLength {
+		violate("length must be <= %d", *this.maxLength)
+	}
+	if this.pattern != nil && !this.pattern.MatchString(v) {
+		violate("must match pattern %q", this.pattern.String())
+	}
+	if check, found := formats[this.format]; found && !check(v) {
+		violate("must be a valid %s", this.format)
+	}
+}
+
+func (this *node) validateArray(v []interface{}, path string, violations *[]streams.Violation, violate func(string, ...interface{})) {
+	if this.minItems != nil && len(v) < *this.minItems {
+		violate("must have at least %d items", *this.minItems)
+	}
+	if this.maxItems != nil && len(v) > *this.maxItems {
+		violate("must have at most %d items", *this.maxItems)
+	}
+	if this.uniqueItems {
+		for i := range v {
+			for j := 0; j < i; j++ {
+				if reflect.DeepEqual(v[i], v[j]) {
+					violate("items %d and %d are equal", j, i)
+				}
+			}
+		}
+	}
+	for idx := range v {
+		itemPath := fmt.Sprintf("%s/%d", path, idx)
+		if this.items != nil {
+			this.items.validate(v[idx], itemPath, violations)
+		} else if idx < len(this.tupleItems) {
+			this.tupleItems[idx].validate(v[idx], itemPath, violations)
+		}
+	}
+}
+
+func (this *node) validateObject(v map[string]interface{}, path string, violations *[]streams.Violation, violate func(string, ...interface{})) {
+	for _, name := range this.required {
+		if _, found := v[name]; !found {
+			violate("missing required property %q", name)
+		}
+	}
+	if this.minProps != nil && len(v) < *this.minProps {
+		violate("must have at least %d properties", *this.minProps)
+	}
+	if this.maxProps != nil && len(v) > *this.maxProps {
+		violate("must have at most %d properties", *this.maxProps)
+	}
+
+	for _, name := range sortedKeys(v) {
+		propPath := path + "/" + escape(name)
+		matched := false
+		if schema, found := this.properties[name]; found {
+			schema.validate(v[name], propPath, violations)
+			matched = true
+		}
+		for _, pattern := range this.patternProperties {
+			if pattern.pattern.MatchString(name) {
+				pattern.schema.validate(v[name], propPath, violations)
+				matched = true
+			}
+		}
+		if !matched && this.additionalProps != nil {
+			if this.additionalProps.always != nil && !*this.additionalProps.always {
+				*violations = append(*violations, streams.Violation{Path: propPath, Message: fmt.Sprintf("additional property %q isn't allowed", name)})
+				continue
+			}
+			this.additionalProps.validate(v[name], propPath, violations)
+		}
+	}
+}
+
+var formats = map[string]func(string) bool{
+	"date-time": func(s string) bool {
+		_, err := time.Parse(time.RFC3339, s)
+		return err == nil
+	},
+	"date": func(s string) bool {
+		_, err := time.Parse("2006-01-02", s)
+		return err == nil
+	},
+	"email": regexp.MustCompile(`^[^@\s]+@[^@\s]+\.[^@\s]+$`).MatchString,
+	"uuid":  regexp.MustCompile(`^(?i)[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`).MatchString,
+}
+
+func matchesType(value interface{}, types []string) bool {
+	actual := typeOf(value)
+	for _, t := range types {
+		if t == actual || (t == "number" && actual == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+func typeOf(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		if v == math.Trunc(v) {
+			return "integer"
+		}
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	default:
+		return "object"
+	}
+}
+
+func contains(values []interface{}, value interface{}) bool {
+	for _, v := range values {
+		if reflect.DeepEqual(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func encode(value interface{}) string {
+	data, _ := json.Marshal(value)
+	return string(data)
+}
+
+// escape escapes a JSON pointer token
+func escape(token string) string {
+	return strings.Replace(strings.Replace(token, "~", "~0", -1), "/", "~1", -1)
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
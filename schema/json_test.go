@@ -0,0 +1,121 @@
+package schema
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	streams "github.com/matang28/go-streams"
+	"github.com/stretchr/testify/assert"
+)
+
+const orderSchema = `{
+	"type": "object",
+	"required": ["id", "items"],
+	"additionalProperties": false,
+	"properties": {
+		"id": {"type": "string", "pattern": "^o-[0-9]+$"},
+		"email": {"type": "string", "format": "email"},
+		"status": {"enum": ["new", "paid"]},
+		"items": {"type": "array", "minItems": 1, "items": {"$ref": "#/definitions/item"}}
+	},
+	"definitions": {
+		"item": {
+			"type": "object",
+			"required": ["sku", "quantity"],
+			"properties": {
+				"sku": {"type": "string", "minLength": 3},
+				"quantity": {"type": "integer", "minimum": 1},
+				"price": {"type": "number", "exclusiveMinimum": 0}
+			}
+		}
+	}
+}`
+
+func TestJSON_Validate(t *testing.T) {
+	s, err := JSON([]byte(orderSchema))
+	assert.Nil(t, err)
+
+	assert.Empty(t, s.Validate([]byte(`{"id": "o-1", "status": "new", "items": [{"sku": "abc", "quantity": 2, "price": 1.5}]}`)))
+	assert.Empty(t, s.Validate(map[string]interface{}{"id": "o-2", "items": []interface{}{map[string]interface{}{"sku": "abc", "quantity": 1}}}))
+
+	assert.EqualValues(t, []streams.Violation{
+		{Path: "/email", Message: "must be a valid email"},
+		{Path: "/extra", Message: `additional property "extra" isn't allowed`},
+		{Path: "/id", Message: `must match pattern "^o-[0-9]+$"`},
+		{Path: "/items/0", Message: `missing required property "quantity"`},
+		{Path: "/items/0/sku", Message: "length must be >= 3"},
+		{Path: "/items/1/price", Message: "must be > 0"},
+		{Path: "/items/1/quantity", Message: "expected integer, got number"},
+		{Path: "/status", Message: `must be one of ["new","paid"]`},
+	}, s.Validate([]byte(`{"id": "1", "email": "nope", "status": "lost", "extra": true,
+		"items": [{"sku": "a"}, {"sku": "abc", "quantity": 1.5, "price": 0}]}`)))
+
+	assert.EqualValues(t, []streams.Violation{{Message: `missing required property "items"`}}, s.Validate([]byte(`{"id": "o-1"}`)))
+	assert.EqualValues(t, []streams.Violation{{Message: "expected object, got string"}}, s.Validate("o-1"))
+	assert.Len(t, s.Validate([]byte(`{"id": `)), 1)
+}
+
+func TestJSON_Combinators(t *testing.T) {
+	s, err := JSON([]byte(`{
+		"oneOf": [{"type": "integer"}, {"type": "string", "maxLength": 2}],
+		"not": {"const": "no"}
+	}`))
+	assert.Nil(t, err)
+
+	assert.Empty(t, s.Validate(1))
+	assert.Empty(t, s.Validate("ok"))
+	assert.EqualValues(t, []streams.Violation{{Message: "must match exactly one of the oneOf schemas (matched 0)"}}, s.Validate("long"))
+	assert.EqualValues(t, []streams.Violation{{Message: "must not match the not schema"}}, s.Validate("no"))
+}
+
+func TestJSON_RecursiveReferences(t *testing.T) {
+	s, err := JSON([]byte(`{
+		"$ref": "#/definitions/node",
+		"definitions": {
+			"node": {"type": "object", "properties": {"value": {"type": "integer"}, "children": {"type": "array", "items": {"$ref": "#/definitions/node"}}}}
+		}
+	}`))
+	assert.Nil(t, err)
+
+	assert.Empty(t, s.Validate([]byte(`{"value": 1, "children": [{"value": 2, "children": []}]}`)))
+	assert.EqualValues(t, []streams.Violation{{Path: "/children/0/value", Message: "expected integer, got string"}},
+		s.Validate([]byte(`{"value": 1, "children": [{"value": "2"}]}`)))
+}
+
+func TestJSON_InvalidSchemas(t *testing.T) {
+	for _, schema := range []string{`{`, `1`, `{"type": 1}`, `{"minLength": -1}`, `{"pattern": "("}`, `{"$ref": "#/definitions/missing"}`, `{"$ref": "other.json"}`} {
+		_, err := JSON([]byte(schema))
+		assert.NotNil(t, err, schema)
+	}
+}
+
+func TestJSON_RejectsToSideOutput(t *testing.T) {
+	s, err := JSON([]byte(`{"type": "object", "required": ["id"]}`))
+	assert.Nil(t, err)
+
+	source := streams.NewAppendSource(2)
+	valid, invalid := streams.NewArraySink(), streams.NewArraySink()
+	errs := make(streams.ErrorChannel, 10)
+
+	source.Append("1", []byte(`{"id": 1}`))
+	source.Append("2", []byte(`{"name": "no id"}`))
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		_ = source.Stop()
+	}()
+
+	streams.NewStream(source).
+		SideOutput("invalid", invalid).
+		Validate(s, "invalid").
+		Sink(valid).
+		Process(streams.NewDirectProcessor(), errs)
+
+	assert.EqualValues(t, []interface{}{[]byte(`{"id": 1}`)}, valid.Array())
+	assert.EqualValues(t, []interface{}{[]byte(`{"name": "no id"}`)}, invalid.Array())
+	// errs belongs to the stream, it is drained without being closed
+	for len(errs) > 0 {
+		var invalid *streams.ValidationError
+		assert.False(t, errors.As(<-errs, &invalid))
+	}
+}
@@ -2,6 +2,7 @@ package go_streams
 
 import (
 	"fmt"
+	"sync/atomic"
 	"time"
 )
 
@@ -13,6 +14,7 @@ type sequentialIntegerSource struct {
 	limit        int
 	delay        time.Duration
 	name         string
+	next         uint64
 }
 
 func NewSequentialIntegerSource(limit int, delay time.Duration) Source {
@@ -38,6 +40,7 @@ Loop:
 				Value: num,
 			}
 			num++
+			atomic.StoreUint64(&this.next, uint64(num))
 		}
 	}
 	errorChannel <- NewEofError(this)
@@ -57,6 +60,15 @@ func (this *sequentialIntegerSource) CommitEntry(keys ...string) error {
 	return nil
 }
 
+// Progress returns the next integer, the end is known when the source has a limit
+func (this *sequentialIntegerSource) Progress() (Progress, error) {
+	progress := Progress{Position: atomic.LoadUint64(&this.next), Unit: "entries"}
+	if this.limit > 0 {
+		progress.End = uint64(this.limit) + 1
+	}
+	return progress, nil
+}
+
 func (this *sequentialIntegerSource) Name() string {
 	return this.name
 }
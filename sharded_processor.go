@@ -0,0 +1,108 @@
+package go_streams
+
+import (
+	"hash/fnv"
+	"sync"
+)
+
+const shardQueueSize = 16
+
+// shardedProcessor hashes the entries to a fixed set of workers, entries of the same shard are
+// processed in the order they were received while different shards are processed in parallel.
+type shardedProcessor struct {
+	entryCh EntryChannel
+	shards  int
+	key     KeyFunc
+}
+
+// NewShardedProcessor creates a processor with the given number of workers, entries are sharded by
+// their key or by the key function of their value when it is set. The handlers of the stream are
+// called by several workers at once so they should be safe for concurrent use. Streams without a
+// commit strategy are committed with the async watermark (see CommitStrategy.Async) so the keys
+// completed out of order by different shards are never committed before the ones received first.
+func NewShardedProcessor(shards int, key KeyFunc) *shardedProcessor {
+	if shards <= 0 {
+		shards = 1
+	}
+	return &shardedProcessor{entryCh: make(EntryChannel), shards: shards, key: key}
+}
+
+func NewShardedProcessorFactory(shards int, key KeyFunc) ProcessorFactory {
+	return func() Processor {
+		return NewShardedProcessor(shards, key)
+	}
+}
+
+func (this *shardedProcessor) Process(stream Stream, errs ErrorChannel) {
+	streamLogger := streamLog(stream)
+	streamLogger.Info("Starting to process stream with sharded processor (%d shards)", this.shards)
+	handlers, acking := bindSinks(stream)
+	reporter := newErrorReporter(stream, errs)
+
+	strategy := CommitAsync()
+	if s := stream.GetCommitStrategy(); s != nil {
+		strategy = *s
+	}
+	committer := newStrategyCommitter(stream.GetSource(), strategy, reporter)
+
+	// the workers and the ticks share the committer of the stream
+	workers := make([]chan Entry, this.shards)
+	wg := &sync.WaitGroup{}
+	slot, inFlight := scheduled(stream), inFlightOf(stream)
+	for idx := range workers {
+		workers[idx] = make(chan Entry, shardQueueSize)
+		wg.Add(1)
+		go func(entries chan Entry) {
+			defer wg.Done()
+			worker := &directProcessor{committer: committer}
+			for entry := range entries {
+				slot.acquire()
+				worker.process(stream, entry, handlers, acking, true, reporter)
+				slot.release()
+				inFlight.release(1)
+			}
+		}(workers[idx])
+	}
+	ticker := &directProcessor{committer: committer}
+
+	reporter.startSource(stream.GetSource(), this.entryCh)
+	ticks, stopTicks := tickChannel(handlers)
+	defer stopTicks()
+
+Loop:
+	for {
+		select {
+		case now := <-ticks:
+			ticker.tick(stream, handlers, acking, reporter, func(op TimedOperator) ([]Entry, error) { return op.Tick(now) })
+
+		case entry, ok := <-this.entryCh:
+			if !ok {
+				break Loop
+			}
+			if !acking {
+				committer.track(entry.Key)
+			}
+			workers[this.shard(entry)] <- entry
+		}
+	}
+
+	for idx := range workers {
+		close(workers[idx])
+	}
+	wg.Wait()
+	ticker.tick(stream, handlers, acking, reporter, TimedOperator.Drain)
+	flushSinks(handlers, reporter)
+	committer.close()
+	reporter.wait()
+	streamLogger.Info("Done processing stream with sharded processor")
+}
+
+func (this *shardedProcessor) shard(entry Entry) int {
+	key := entry.Key
+	if this.key != nil {
+		key = this.key(entry.Value)
+	}
+	hash := fnv.New32a()
+	_, _ = hash.Write([]byte(key))
+	return int(hash.Sum32() % uint32(this.shards))
+}
@@ -0,0 +1,58 @@
+package go_streams
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShardedProcessor_KeepsTheOrderOfEachKey(t *testing.T) {
+	source := newCommitRecordingSource(NewSequentialIntegerSource(99, 0))
+	mutex := &sync.Mutex{}
+	byKey := make(map[string][]int)
+	sink := NewCallbackSink(func(entries ...Entry) error {
+		for _, entry := range entries {
+			// later entries of fast shards overtake the slow ones
+			if entry.Value.(int)%2 == 0 {
+				time.Sleep(time.Millisecond)
+			}
+			mutex.Lock()
+			key := strconv.Itoa(entry.Value.(int) % 4)
+			byKey[key] = append(byKey[key], entry.Value.(int))
+			mutex.Unlock()
+		}
+		return nil
+	})
+
+	key := func(value interface{}) string { return strconv.Itoa(value.(int) % 4) }
+	NewStream(source).Sink(sink).Process(NewShardedProcessor(4, key), make(ErrorChannel, 10))
+
+	assert.Len(t, byKey, 4)
+	total := 0
+	for _, values := range byKey {
+		for idx := 1; idx < len(values); idx++ {
+			assert.True(t, values[idx-1] < values[idx])
+		}
+		total += len(values)
+	}
+	assert.EqualValues(t, 100, total)
+
+	// the watermark commits the keys in the order they were received
+	keys := source.keys()
+	assert.Len(t, keys, 100)
+	for idx, key := range keys {
+		assert.EqualValues(t, strconv.Itoa(idx), key)
+	}
+}
+
+func TestShardedProcessor_ShardsByEntryKey(t *testing.T) {
+	processor := NewShardedProcessor(8, nil)
+	assert.EqualValues(t, processor.shard(Entry{Key: "a", Value: 1}), processor.shard(Entry{Key: "a", Value: 2}))
+
+	sink := NewArraySink()
+	NewStream(NewSequentialIntegerSource(9, 0)).Sink(sink).Process(processor, make(ErrorChannel, 10))
+	assert.Len(t, sink.Array(), 10)
+}
@@ -0,0 +1,146 @@
+package go_streams
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Outputs emits values to the named side outputs of a stream
+type Outputs interface {
+	Emit(output string, value interface{})
+}
+
+// SideOutputFunc transforms the value of an entry and may emit values to side outputs,
+// the entry is dropped from the main flow when keep is false.
+type SideOutputFunc func(value interface{}, outputs Outputs) (result interface{}, keep bool)
+
+// sideOutputs holds the sinks of the side outputs of a stream by their name
+type sideOutputs struct {
+	mutex *sync.RWMutex
+	sinks map[string]Sink
+}
+
+func newSideOutputs() *sideOutputs {
+	return &sideOutputs{mutex: &sync.RWMutex{}, sinks: make(map[string]Sink)}
+}
+
+func (this *sideOutputs) set(name string, sink Sink) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.sinks[name] = sink
+}
+
+func (this *sideOutputs) get(name string) (Sink, bool) {
+	this.mutex.RLock()
+	defer this.mutex.RUnlock()
+	sink, found := this.sinks[name]
+	return sink, found
+}
+
+func (this *sideOutputs) all() map[string]Sink {
+	this.mutex.RLock()
+	defer this.mutex.RUnlock()
+	sinks := make(map[string]Sink, len(this.sinks))
+	for name, sink := range this.sinks {
+		sinks[name] = sink
+	}
+	return sinks
+}
+
+type sideEntry struct {
+	output string
+	entry  Entry
+}
+
+// entryOutputs collects the values emitted while an entry is processed
+type entryOutputs struct {
+	entry   Entry
+	emitted []sideEntry
+}
+
+// entryOutputsPool reuses the collectors of the entries, most entries emit nothing
+var entryOutputsPool = sync.Pool{New: func() interface{} { return &entryOutputs{} }}
+
+func (this *entryOutputs) Emit(output string, value interface{}) {
+	entry := this.entry
+	entry.Value = value
+	this.emitted = append(this.emitted, sideEntry{output: output, entry: entry})
+}
+
+// sideOutputStage runs a SideOutputFunc and writes its emitted values to the side output sinks,
+// the values are written once the function returned so a function that panicked emits nothing.
+type sideOutputStage struct {
+	fn      SideOutputFunc
+	outputs *sideOutputs
+}
+
+func (this *sideOutputStage) Apply(entry Entry) ([]Entry, error) {
+	outputs := entryOutputsPool.Get().(*entryOutputs)
+	outputs.entry = entry
+	defer func() {
+		outputs.entry, outputs.emitted = Entry{}, outputs.emitted[:0]
+		entryOutputsPool.Put(outputs)
+	}()
+	value, keep := this.fn(entry.Value, outputs)
+
+	var failures map[string]string
+	for _, side := range outputs.emitted {
+		failure := "unknown side output"
+		if sink, found := this.outputs.get(side.output); found {
+			_, err := recoverSinkSingle(sink, side.entry)
+			if err == nil {
+				continue
+			}
+			failure = err.Error()
+		}
+		if failures == nil {
+			failures = make(map[string]string)
+		}
+		failures[side.output] = failure
+	}
+
+	var err error
+	if len(failures) > 0 {
+		names := make([]string, 0, len(failures))
+		for name := range failures {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for idx, name := range names {
+			names[idx] = fmt.Sprintf("'%s': %s", name, failures[name])
+		}
+		err = fmt.Errorf("side outputs failed: %s", strings.Join(names, ", "))
+	}
+
+	if !keep {
+		return nil, err
+	}
+	entry.Value = value
+	return []Entry{entry}, err
+}
+
+// Flush flushes the side output sinks that implement Flusher
+func (this *sideOutputStage) Flush() error {
+	return NewFanOutSink(Sequential, CommitAll, this.sinks()...).Flush()
+}
+
+// Close closes the side output sinks that implement Closer
+func (this *sideOutputStage) Close() error {
+	return NewFanOutSink(Sequential, CommitAll, this.sinks()...).Close()
+}
+
+func (this *sideOutputStage) sinks() []Sink {
+	all := this.outputs.all()
+	names := make([]string, 0, len(all))
+	for name := range all {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	sinks := make([]Sink, len(names))
+	for idx, name := range names {
+		sinks[idx] = all[name]
+	}
+	return sinks
+}
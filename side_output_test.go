@@ -0,0 +1,43 @@
+package go_streams
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStream_MapOutputs_SplitsTheStream(t *testing.T) {
+	valid := NewArraySink()
+	invalid := NewArraySink()
+	audit := NewArraySink()
+	errs := make(ErrorChannel, 10)
+
+	NewStream(NewSequentialIntegerSource(5, 0)).MapOutputs(func(value interface{}, outputs Outputs) (interface{}, bool) {
+		outputs.Emit("audit", value)
+		if value.(int)%2 == 1 {
+			outputs.Emit("invalid", fmt.Sprintf("odd: %d", value))
+			return nil, false
+		}
+		return value.(int) * 10, true
+	}).Sink(valid).SideOutput("invalid", invalid).SideOutput("audit", audit).Process(NewDirectProcessor(), errs)
+
+	assert.EqualValues(t, []interface{}{0, 20, 40}, valid.Array())
+	assert.EqualValues(t, []interface{}{"odd: 1", "odd: 3", "odd: 5"}, invalid.Array())
+	assert.EqualValues(t, []interface{}{0, 1, 2, 3, 4, 5}, audit.Array())
+}
+
+func TestStream_MapOutputs_ReportsFailedOutputs(t *testing.T) {
+	stream := NewStream(NewSequentialIntegerSource(0, 0)).MapOutputs(func(value interface{}, outputs Outputs) (interface{}, bool) {
+		outputs.Emit("missing", value)
+		outputs.Emit("failing", value)
+		return value, true
+	}).SideOutput("failing", NewCallbackSink(func(entries ...Entry) error {
+		return fmt.Errorf("sink is down")
+	}))
+
+	entries, err := stream.GetHandlers()[0].(Operator).Apply(Entry{Key: "1", Value: 1})
+	assert.Len(t, entries, 1)
+	assert.EqualValues(t, "side outputs failed: 'failing': sink is down, 'missing': unknown side output", err.Error())
+	assert.Len(t, stream.GetSideOutputs(), 1)
+}
@@ -0,0 +1,10 @@
+package go_streams
+
+// flushSinks flushes the sinks that implement Flusher, it is called by the processors once the stream ended
+func flushSinks(handlers []interface{}, reporter *errorReporter) {
+	for _, handler := range handlers {
+		if flusher, ok := handler.(Flusher); ok {
+			reporter.report(SinkStage, "", flusher.Flush())
+		}
+	}
+}
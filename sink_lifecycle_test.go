@@ -0,0 +1,63 @@
+package go_streams
+
+import (
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"testing"
+	"time"
+)
+
+// lifecycleSink buffers entries until it is flushed
+type lifecycleSink struct {
+	mutex    *sync.Mutex
+	buffered []Entry
+	flushed  []Entry
+	closed   int
+}
+
+func newLifecycleSink() *lifecycleSink {
+	return &lifecycleSink{mutex: &sync.Mutex{}}
+}
+
+func (this *lifecycleSink) Ping() error { return nil }
+
+func (this *lifecycleSink) Single(entry Entry) error {
+	return this.Batch(entry)
+}
+
+func (this *lifecycleSink) Batch(entry ...Entry) error {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.buffered = append(this.buffered, entry...)
+	return nil
+}
+
+func (this *lifecycleSink) Flush() error {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.flushed = append(this.flushed, this.buffered...)
+	this.buffered = nil
+	return nil
+}
+
+func (this *lifecycleSink) Close() error {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.closed++
+	return nil
+}
+
+func TestEngine_FlushesAndClosesSinks(t *testing.T) {
+	engine := NewEngine(NewBufferedProcessorFactory(4, time.Second), 10*time.Second)
+	sink := newLifecycleSink()
+	source1 := NewSequentialIntegerSource(5, 0)
+	source2 := NewSequentialIntegerSource(2, 0)
+
+	assert.Nil(t, engine.Add(NewStream(source1).Sink(sink), NewStream(source2).Sink(sink)))
+	engine.Start()
+
+	assert.Empty(t, sink.buffered)
+	assert.Len(t, sink.flushed, 9)
+	// the sink is shared by both streams but closed once
+	assert.EqualValues(t, 1, sink.closed)
+}
@@ -0,0 +1,204 @@
+package go_streams
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// snapshotVersion is the version of the snapshot format written by Engine.Snapshot
+const snapshotVersion = 1
+
+// Snapshotter is implemented by the stateful operators and the sources whose state can be moved
+// between engines (see Engine.Snapshot), NOTICE that operator states are encoded with encoding/gob
+// so the types of the values they hold should be registered with gob.Register.
+type Snapshotter interface {
+	// Snapshot returns the encoded state
+	Snapshot() ([]byte, error)
+
+	// Restore replaces the state by one returned by Snapshot
+	Restore(data []byte) error
+}
+
+type engineSnapshot struct {
+	Version int                        `json:"version"`
+	Streams map[string]*streamSnapshot `json:"streams"`
+}
+
+type streamSnapshot struct {
+	// Source is the state of the source (empty when it isn't a Snapshotter)
+	Source   []byte            `json:"source,omitempty"`
+	Handlers []handlerSnapshot `json:"handlers,omitempty"`
+}
+
+// handlerSnapshot is the state of a handler, index is its position in the handlers of the stream
+type handlerSnapshot struct {
+	Index int    `json:"index"`
+	Type  string `json:"type"`
+	State []byte `json:"state"`
+}
+
+// Snapshot writes the state of the snapshotters of every stream. The position of a source is the one
+// it committed, so the entries that were processed but not committed yet are delivered again after a restore:
+// the snapshot is exact when no entry is in flight (e.g: with sinks that commit every entry on write).
+func (this *engine) Snapshot(w io.Writer) error {
+	snapshot := engineSnapshot{Version: snapshotVersion, Streams: make(map[string]*streamSnapshot)}
+	for _, name := range this.streamNames() {
+		this.mutex.RLock()
+		s, found := this.streams[name]
+		this.mutex.RUnlock()
+		if !found {
+			continue
+		}
+
+		state, err := this.snapshotStream(name, s)
+		if err != nil {
+			return err
+		}
+		if state != nil {
+			snapshot.Streams[name] = state
+		}
+	}
+
+	if err := json.NewEncoder(w).Encode(&snapshot); err != nil {
+		return err
+	}
+	logger.Info("Snapshotted %d streams", len(snapshot.Streams))
+	return nil
+}
+
+// snapshotStream captures the state of a stream while it is held back, it returns nil when the stream is stateless
+func (this *engine) snapshotStream(name string, s *streamAndProcessor) (*streamSnapshot, error) {
+	if !s.source.paused() {
+		s.source.pause()
+		defer s.source.resume()
+	}
+
+	state := &streamSnapshot{}
+	if source, ok := snapshotterOf(s.stream.GetSource()); ok {
+		data, err := source.Snapshot()
+		if err != nil {
+			return nil, NewSnapshotError(name, err)
+		}
+		state.Source = data
+	}
+	for idx, handler := range s.stream.GetHandlers() {
+		snapshotter, ok := handler.(Snapshotter)
+		if !ok {
+			continue
+		}
+		data, err := snapshotter.Snapshot()
+		if err != nil {
+			return nil, NewSnapshotError(name, fmt.Errorf("handler %d (%s): %s", idx, typeName(handler), err.Error()))
+		}
+		state.Handlers = append(state.Handlers, handlerSnapshot{Index: idx, Type: typeName(handler), State: data})
+	}
+
+	if state.Source == nil && len(state.Handlers) == 0 {
+		return nil, nil
+	}
+	return state, nil
+}
+
+// Restore restores the snapshotters of the streams of the snapshot, every stream of the snapshot should
+// have been added with the same handlers. Streams of the engine that aren't in the snapshot keep their state.
+func (this *engine) Restore(r io.Reader) error {
+	var snapshot engineSnapshot
+	if err := json.NewDecoder(r).Decode(&snapshot); err != nil {
+		return err
+	}
+	if snapshot.Version != snapshotVersion {
+		return fmt.Errorf("unsupported snapshot version: %d", snapshot.Version)
+	}
+
+	names := make([]string, 0, len(snapshot.Streams))
+	for name := range snapshot.Streams {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		this.mutex.RLock()
+		s, found := this.streams[name]
+		this.mutex.RUnlock()
+		if !found {
+			return NewUnknownStreamError(name)
+		}
+		if err := this.restoreStream(name, s, snapshot.Streams[name]); err != nil {
+			return err
+		}
+	}
+	logger.Info("Restored %d streams", len(names))
+	return nil
+}
+
+func (this *engine) restoreStream(name string, s *streamAndProcessor, state *streamSnapshot) error {
+	if !s.source.paused() {
+		s.source.pause()
+		defer s.source.resume()
+	}
+
+	// the handlers are checked first so a mismatching stream is left untouched
+	handlers := s.stream.GetHandlers()
+	snapshotters := make([]Snapshotter, len(state.Handlers))
+	for idx, handler := range state.Handlers {
+		if handler.Index < 0 || handler.Index >= len(handlers) || typeName(handlers[handler.Index]) != handler.Type {
+			return NewSnapshotError(name, fmt.Errorf("handler %d isn't a %s", handler.Index, handler.Type))
+		}
+		snapshotter, ok := handlers[handler.Index].(Snapshotter)
+		if !ok {
+			return NewSnapshotError(name, fmt.Errorf("handler %d (%s) can't be restored", handler.Index, handler.Type))
+		}
+		snapshotters[idx] = snapshotter
+	}
+
+	for idx, snapshotter := range snapshotters {
+		if err := snapshotter.Restore(state.Handlers[idx].State); err != nil {
+			return NewSnapshotError(name, fmt.Errorf("handler %d (%s): %s", state.Handlers[idx].Index, state.Handlers[idx].Type, err.Error()))
+		}
+	}
+	if state.Source != nil {
+		source, ok := snapshotterOf(s.stream.GetSource())
+		if !ok {
+			return NewSnapshotError(name, fmt.Errorf("source '%s' can't be restored", s.stream.GetSource().Name()))
+		}
+		if err := source.Restore(state.Source); err != nil {
+			return NewSnapshotError(name, err)
+		}
+	}
+	return nil
+}
+
+// streamNames returns the names of the streams sorted
+func (this *engine) streamNames() []string {
+	this.mutex.RLock()
+	defer this.mutex.RUnlock()
+	names := make([]string, 0, len(this.streams))
+	for name := range this.streams {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// snapshotterOf returns the snapshotter of a source, sources decorated by WrapSource are unwrapped
+func snapshotterOf(source Source) (Snapshotter, bool) {
+	snapshotter, ok := unwrapSource(source).(Snapshotter)
+	return snapshotter, ok
+}
+
+// encodeState encodes the state of a snapshotter with encoding/gob
+func encodeState(state interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(state); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// decodeState decodes a state encoded by encodeState
+func decodeState(data []byte, state interface{}) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(state)
+}
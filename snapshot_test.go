@@ -0,0 +1,122 @@
+package go_streams
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type snapshotPipeline struct {
+	stream  Stream
+	window  *EventTimeWindow
+	scanner *Scanner
+	store   StateStore
+}
+
+func newSnapshotPipeline(t *testing.T) *snapshotPipeline {
+	window, err := NewEventTimeWindow(WindowConfig{Size: 10 * time.Second, Aggregate: sum})
+	assert.Nil(t, err)
+	pipeline := &snapshotPipeline{
+		window: window,
+		scanner: NewScan(0, func(state, value interface{}) (interface{}, interface{}) {
+			return state.(int) + 1, value
+		}),
+		store: NewMemoryStateStore(10),
+	}
+	pipeline.stream = NewStream(&flakySource{name: "orders"}).
+		Map(func(entry interface{}) interface{} { return entry }).
+		Via(pipeline.scanner).
+		Via(NewDedupe(func(value interface{}) string { return value.(string) }, 0, pipeline.store)).
+		Via(pipeline.window).
+		Sink(NewArraySink())
+	return pipeline
+}
+
+func TestEngine_SnapshotAndRestore(t *testing.T) {
+	original := newSnapshotPipeline(t)
+	_, _ = original.scanner.Apply(Entry{Key: "1", Value: 1})
+	_, _ = original.scanner.Apply(Entry{Key: "2", Value: 2})
+	_ = original.store.Put("a", true, 0)
+	_, _ = original.window.Apply(Entry{Key: "3", Value: 3, Timestamp: at(1)})
+	_, _ = original.window.Apply(Entry{Key: "4", Value: 4, Timestamp: at(2)})
+
+	engine := NewEngine(NewDirectProcessorFactory(), time.Second)
+	assert.Nil(t, engine.Add(original.stream))
+	var snapshot bytes.Buffer
+	assert.Nil(t, engine.Snapshot(&snapshot))
+
+	restored := newSnapshotPipeline(t)
+	engine = NewEngine(NewDirectProcessorFactory(), time.Second)
+	assert.Nil(t, engine.Add(restored.stream))
+	assert.Nil(t, engine.Restore(&snapshot))
+
+	assert.EqualValues(t, 2, restored.scanner.State())
+	_, seen, _ := restored.store.Get("a")
+	assert.True(t, seen)
+	out, _ := restored.window.Drain()
+	assert.Len(t, out, 1)
+	assert.EqualValues(t, 7, out[0].Value.(WindowResult).Value)
+	assert.EqualValues(t, 2, out[0].Value.(WindowResult).Count)
+	assert.EqualValues(t, "4", out[0].Key)
+}
+
+func TestEngine_Restore_Errors(t *testing.T) {
+	engine := NewEngine(NewDirectProcessorFactory(), time.Second)
+	assert.Nil(t, engine.Add(newSnapshotPipeline(t).stream))
+	var snapshot bytes.Buffer
+	assert.Nil(t, engine.Snapshot(&snapshot))
+
+	// the scan is restored on a map
+	other := NewEngine(NewDirectProcessorFactory(), time.Second)
+	assert.Nil(t, other.Add(NewStream(&flakySource{name: "orders"}).
+		Via(NewScan(0, nil)).
+		Map(func(entry interface{}) interface{} { return entry }).
+		Sink(NewArraySink())))
+	err := other.Restore(bytes.NewReader(snapshot.Bytes()))
+	assert.IsType(t, &SnapshotError{}, err)
+
+	err = NewEngine(NewDirectProcessorFactory(), time.Second).Restore(bytes.NewReader(snapshot.Bytes()))
+	assert.IsType(t, &UnknownStreamError{}, err)
+
+	assert.NotNil(t, engine.Restore(bytes.NewBufferString(`{"version": 2}`)))
+}
+
+func TestMemoryStateStore_Snapshot(t *testing.T) {
+	store := NewMemoryStateStore(3)
+	_ = store.Put("a", 1, 0)
+	_ = store.Put("b", 2, 0)
+	_ = store.Put("expired", 3, time.Nanosecond)
+	_, _, _ = store.Get("a")
+	time.Sleep(time.Millisecond)
+
+	data, err := store.(Snapshotter).Snapshot()
+	assert.Nil(t, err)
+	restored := NewMemoryStateStore(2)
+	assert.Nil(t, restored.(Snapshotter).Restore(data))
+
+	_, found, _ := restored.Get("expired")
+	assert.False(t, found)
+
+	// the order of the keys is kept, b is the least recently used one
+	_ = restored.Put("c", 4, 0)
+	_, found, _ = restored.Get("b")
+	assert.False(t, found)
+	value, found, _ := restored.Get("a")
+	assert.True(t, found)
+	assert.EqualValues(t, 1, value)
+}
+
+func TestReorder_Snapshot(t *testing.T) {
+	op := NewReorder(time.Hour, nil)
+	_, _ = op.Apply(Entry{Key: "2", Value: 2, Timestamp: at(2)})
+	_, _ = op.Apply(Entry{Key: "1", Value: 1, Timestamp: at(1)})
+
+	data, err := op.(Snapshotter).Snapshot()
+	assert.Nil(t, err)
+	restored := NewReorder(time.Hour, nil)
+	assert.Nil(t, restored.(Snapshotter).Restore(data))
+	out, _ := restored.Drain()
+	assert.EqualValues(t, []interface{}{1, 2}, entryValues(out))
+}
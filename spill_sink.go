@@ -0,0 +1,553 @@
+package go_streams
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	defaultSpillSegmentSize = 16 << 20
+	defaultSpillBatchSize   = 100
+	spillSegmentExt         = ".segment"
+	spillCursorFile         = "cursor"
+)
+
+// SpillConfig configures a SpillSink
+type SpillConfig struct {
+	// Dir holds the segment files of the queue, the entries left there are replayed
+	// once a SpillSink is created on the same directory (e.g: after a restart).
+	Dir string
+
+	// SegmentSize is the size of a segment file in bytes (defaults to 16MB), segments are deleted once replayed
+	SegmentSize int64
+
+	// MaxSize bounds the bytes on disk (0 is unbounded), once reached writes wait for the replay
+	MaxSize int64
+
+	// BatchSize is the number of entries replayed per write (defaults to 100)
+	BatchSize int
+
+	// Backoff is the delay after a failed replay (defaults to 1s), it is doubled up to MaxBackoff (defaults to 1m)
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+
+	// Sync flushes the segment files to the disk after every write
+	Sync bool
+
+	// Encode and Decode serialize spilled entries, they default to encoding/gob
+	// (the types of entry values should be registered with gob.Register).
+	Encode func(entry Entry) ([]byte, error)
+	Decode func(data []byte) (Entry, error)
+}
+
+// SpillStats are the counters of a SpillSink
+type SpillStats struct {
+	Pending  int    `json:"pending"`
+	Bytes    int64  `json:"bytes"`
+	Spilled  uint64 `json:"spilled"`
+	Replayed uint64 `json:"replayed"`
+}
+
+// SpillSink writes to its sink while it succeeds, once a write failed the entries are queued on disk
+// and replayed in order in the background until the queue is empty. Entries are written to the sink
+// at least once: they are committed by the stream once queued, a failed batch is queued as a whole.
+type SpillSink struct {
+	sink   Sink
+	config SpillConfig
+	queue  *segmentQueue
+
+	writing   *sync.Mutex
+	replaying *sync.Mutex
+	wake      chan struct{}
+	stop      chan struct{}
+	stopOnce  *sync.Once
+	done      *sync.WaitGroup
+
+	spilled  uint64
+	replayed uint64
+}
+
+// NewSpillSink opens the queue in the directory of the config and starts replaying the entries left there
+func NewSpillSink(sink Sink, config SpillConfig) (*SpillSink, error) {
+	if config.Dir == "" {
+		return nil, fmt.Errorf("spill sink requires a directory")
+	}
+	if config.SegmentSize <= 0 {
+		config.SegmentSize = defaultSpillSegmentSize
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = defaultSpillBatchSize
+	}
+	if config.Backoff <= 0 {
+		config.Backoff = time.Second
+	}
+	if config.MaxBackoff < config.Backoff {
+		config.MaxBackoff = time.Minute
+	}
+	if config.Encode == nil {
+		config.Encode = gobEncodeEntry
+	}
+	if config.Decode == nil {
+		config.Decode = gobDecodeEntry
+	}
+
+	queue, err := openSegmentQueue(config)
+	if err != nil {
+		return nil, err
+	}
+	this := &SpillSink{
+		sink:      sink,
+		config:    config,
+		queue:     queue,
+		writing:   &sync.Mutex{},
+		replaying: &sync.Mutex{},
+		wake:      make(chan struct{}, 1),
+		stop:      make(chan struct{}),
+		stopOnce:  &sync.Once{},
+		done:      &sync.WaitGroup{},
+	}
+	this.done.Add(1)
+	go this.replayInBackground()
+	if queue.len() > 0 {
+		this.signal()
+	}
+	return this, nil
+}
+
+func (this *SpillSink) Single(entry Entry) error {
+	return this.Batch(entry)
+}
+
+// Batch writes the entries to the sink when nothing is queued, otherwise (or when the write fails) they are queued
+func (this *SpillSink) Batch(entry ...Entry) error {
+	if len(entry) == 0 {
+		return nil
+	}
+	this.writing.Lock()
+	defer this.writing.Unlock()
+
+	// entries are queued behind the queued ones to keep the order
+	if this.queue.len() == 0 {
+		err := this.sink.Batch(entry...)
+		if err == nil {
+			return nil
+		}
+		this.log().Warn("Sink failed, spilling %d entries to disk: %s", len(entry), err.Error())
+	}
+	if err := this.queue.append(entry); err != nil {
+		return err
+	}
+	atomic.AddUint64(&this.spilled, uint64(len(entry)))
+	this.signal()
+	return nil
+}
+
+// Ping doesn't ping the sink, its outages are absorbed by the queue
+func (this *SpillSink) Ping() error {
+	return nil
+}
+
+// Flush replays the queued entries and flushes the sink, the entries that weren't replayed are kept on disk
+func (this *SpillSink) Flush() error {
+	for this.queue.len() > 0 {
+		if err := this.replay(); err != nil {
+			return fmt.Errorf("%d spilled entries weren't replayed: %s", this.queue.len(), err.Error())
+		}
+	}
+	if flusher, ok := this.sink.(Flusher); ok {
+		return flusher.Flush()
+	}
+	return nil
+}
+
+// Close stops the replay and closes the sink, the queued entries are kept on disk
+func (this *SpillSink) Close() error {
+	this.stopOnce.Do(func() { close(this.stop) })
+	this.done.Wait()
+	err := this.queue.close()
+	if closer, ok := this.sink.(Closer); ok {
+		if closeErr := closer.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
+
+// bindEvents binds the sink to the events of the stream when it emits events (e.g: a CircuitBreakerSink)
+func (this *SpillSink) bindEvents(stream Stream) {
+	if binder, ok := this.sink.(eventBinder); ok {
+		binder.bindEvents(stream)
+	}
+}
+
+func (this *SpillSink) Stats() SpillStats {
+	pending, bytes := this.queue.stats()
+	return SpillStats{
+		Pending:  pending,
+		Bytes:    bytes,
+		Spilled:  atomic.LoadUint64(&this.spilled),
+		Replayed: atomic.LoadUint64(&this.replayed),
+	}
+}
+
+func (this *SpillSink) signal() {
+	select {
+	case this.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (this *SpillSink) replayInBackground() {
+	defer this.done.Done()
+	backoff := this.config.Backoff
+	for {
+		select {
+		case <-this.stop:
+			return
+		case <-this.wake:
+		}
+
+		for this.queue.len() > 0 {
+			if err := this.replay(); err != nil {
+				this.log().Warn("Replaying %d spilled entries failed, retrying in %s: %s", this.queue.len(), backoff, err.Error())
+				select {
+				case <-this.stop:
+					return
+				case <-time.After(backoff):
+				}
+				if backoff *= 2; backoff > this.config.MaxBackoff {
+					backoff = this.config.MaxBackoff
+				}
+				continue
+			}
+			backoff = this.config.Backoff
+		}
+	}
+}
+
+// replay writes the next batch of queued entries to the sink and removes them from the queue
+func (this *SpillSink) replay() error {
+	this.replaying.Lock()
+	defer this.replaying.Unlock()
+
+	entries, next, err := this.queue.peek(this.config.BatchSize)
+	if err != nil || len(entries) == 0 {
+		return err
+	}
+	if err := this.sink.Batch(entries...); err != nil {
+		return err
+	}
+	atomic.AddUint64(&this.replayed, uint64(len(entries)))
+	return this.queue.ack(len(entries), next)
+}
+
+func (this *SpillSink) log() Logger {
+	return LogWith(Fields{FieldStage: SinkStage, "sink": "spill"})
+}
+
+// segmentQueue is a queue of length prefixed entries in segment files, the first segment is read
+// from the offset kept in the cursor file and the last one is appended.
+type segmentQueue struct {
+	config SpillConfig
+
+	mutex    *sync.Mutex
+	cond     *sync.Cond
+	segments []int64
+	sizes    []int64
+	writer   *os.File
+	offset   int64
+	bytes    int64
+	count    int
+	closed   bool
+}
+
+func openSegmentQueue(config SpillConfig) (*segmentQueue, error) {
+	if err := os.MkdirAll(config.Dir, 0755); err != nil {
+		return nil, err
+	}
+	this := &segmentQueue{config: config, mutex: &sync.Mutex{}}
+	this.cond = sync.NewCond(this.mutex)
+
+	files, err := ioutil.ReadDir(config.Dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		id, err := strconv.ParseInt(strings.TrimSuffix(file.Name(), spillSegmentExt), 10, 64)
+		if err == nil && strings.HasSuffix(file.Name(), spillSegmentExt) {
+			this.segments = append(this.segments, id)
+		}
+	}
+	sort.Slice(this.segments, func(i, j int) bool { return this.segments[i] < this.segments[j] })
+
+	cursor, offset, err := this.readCursor()
+	if err != nil {
+		return nil, err
+	}
+	// segments before the cursor were replayed already
+	for len(this.segments) > 0 && this.segments[0] < cursor {
+		if err := os.Remove(this.path(this.segments[0])); err != nil {
+			return nil, err
+		}
+		this.segments = this.segments[1:]
+	}
+	if len(this.segments) == 0 || this.segments[0] != cursor {
+		offset = 0
+	} else if info, err := os.Stat(this.path(cursor)); err != nil || offset > info.Size() {
+		// the segment was truncated after the cursor was written
+		offset = 0
+	}
+
+	this.sizes = make([]int64, len(this.segments))
+	for idx, id := range this.segments {
+		from := int64(0)
+		if idx == 0 {
+			from = offset
+		}
+		count, size, err := scanSegment(this.path(id), from)
+		if err != nil {
+			return nil, err
+		}
+		this.sizes[idx] = size
+		this.count += count
+		this.bytes += size - from
+	}
+	this.offset = offset
+
+	if len(this.segments) == 0 {
+		err = this.rotate(cursor)
+	} else {
+		last := this.segments[len(this.segments)-1]
+		this.writer, err = os.OpenFile(this.path(last), os.O_WRONLY|os.O_APPEND, 0644)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return this, nil
+}
+
+// scanSegment counts the complete records of a segment from the offset, an incomplete
+// record (e.g: the process crashed while writing it) is truncated.
+func scanSegment(path string, from int64) (count int, size int64, err error) {
+	file, err := os.OpenFile(path, os.O_RDWR, 0644)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	header := make([]byte, 4)
+	size = from
+	for size < info.Size() {
+		if _, err := file.ReadAt(header, size); err != nil {
+			break
+		}
+		end := size + 4 + int64(binary.BigEndian.Uint32(header))
+		if end > info.Size() {
+			break
+		}
+		size = end
+		count++
+	}
+	if size < info.Size() {
+		err = file.Truncate(size)
+	}
+	return count, size, err
+}
+
+func (this *segmentQueue) path(id int64) string {
+	return filepath.Join(this.config.Dir, fmt.Sprintf("%020d%s", id, spillSegmentExt))
+}
+
+func (this *segmentQueue) len() int {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	return this.count
+}
+
+func (this *segmentQueue) stats() (int, int64) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	return this.count, this.bytes
+}
+
+// append writes the entries, it waits for the replay while the queue is full
+func (this *segmentQueue) append(entries []Entry) error {
+	records := make([][]byte, len(entries))
+	total := int64(0)
+	for idx, entry := range entries {
+		data, err := this.config.Encode(entry)
+		if err != nil {
+			return err
+		}
+		records[idx] = make([]byte, 4+len(data))
+		binary.BigEndian.PutUint32(records[idx], uint32(len(data)))
+		copy(records[idx][4:], data)
+		total += int64(len(records[idx]))
+	}
+
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	for this.config.MaxSize > 0 && this.bytes > 0 && this.bytes+total > this.config.MaxSize && !this.closed {
+		this.cond.Wait()
+	}
+	if this.closed {
+		return fmt.Errorf("the spill queue is closed")
+	}
+
+	for _, record := range records {
+		last := len(this.segments) - 1
+		if this.sizes[last] > 0 && this.sizes[last]+int64(len(record)) > this.config.SegmentSize {
+			if err := this.rotate(this.segments[last] + 1); err != nil {
+				return err
+			}
+			last++
+		}
+		if _, err := this.writer.Write(record); err != nil {
+			return err
+		}
+		this.sizes[last] += int64(len(record))
+		this.bytes += int64(len(record))
+		this.count++
+	}
+	if this.config.Sync {
+		return this.writer.Sync()
+	}
+	return nil
+}
+
+// rotate starts writing a new segment, the caller holds the mutex (if any)
+func (this *segmentQueue) rotate(id int64) error {
+	if this.writer != nil {
+		if err := this.writer.Close(); err != nil {
+			return err
+		}
+	}
+	writer, err := os.OpenFile(this.path(id), os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	this.writer = writer
+	this.segments = append(this.segments, id)
+	this.sizes = append(this.sizes, 0)
+	return nil
+}
+
+// peek reads up to n entries from the first segment, they are removed once acknowledged with the returned offset
+func (this *segmentQueue) peek(n int) ([]Entry, int64, error) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	// the first segment was fully read, the next one is read from its start
+	if this.offset >= this.sizes[0] && len(this.segments) > 1 {
+		if err := this.dropFirst(); err != nil {
+			return nil, this.offset, err
+		}
+	}
+
+	file, err := os.Open(this.path(this.segments[0]))
+	if err != nil {
+		return nil, this.offset, err
+	}
+	defer file.Close()
+
+	var entries []Entry
+	header := make([]byte, 4)
+	offset := this.offset
+	for len(entries) < n && offset < this.sizes[0] {
+		if _, err := file.ReadAt(header, offset); err != nil {
+			return nil, this.offset, err
+		}
+		data := make([]byte, binary.BigEndian.Uint32(header))
+		if _, err := file.ReadAt(data, offset+4); err != nil && err != io.EOF {
+			return nil, this.offset, err
+		}
+		entry, err := this.config.Decode(data)
+		if err != nil {
+			return nil, this.offset, err
+		}
+		entries = append(entries, entry)
+		offset += int64(4 + len(data))
+	}
+	return entries, offset, nil
+}
+
+// ack removes the n entries read by peek and persists the offset of the replay
+func (this *segmentQueue) ack(n int, offset int64) error {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	defer this.cond.Broadcast()
+
+	this.bytes -= offset - this.offset
+	this.count -= n
+	this.offset = offset
+
+	// the only segment was fully read, it is reused from its start
+	if this.offset >= this.sizes[0] && len(this.segments) == 1 {
+		this.offset = 0
+		if err := this.writeCursor(); err != nil {
+			return err
+		}
+		this.sizes[0] = 0
+		return this.writer.Truncate(0)
+	}
+	if this.offset >= this.sizes[0] {
+		return this.dropFirst()
+	}
+	return this.writeCursor()
+}
+
+// dropFirst deletes the first segment once it was fully read, the caller holds the mutex
+func (this *segmentQueue) dropFirst() error {
+	if err := os.Remove(this.path(this.segments[0])); err != nil {
+		return err
+	}
+	this.segments, this.sizes, this.offset = this.segments[1:], this.sizes[1:], 0
+	return this.writeCursor()
+}
+
+func (this *segmentQueue) readCursor() (int64, int64, error) {
+	data, err := ioutil.ReadFile(filepath.Join(this.config.Dir, spillCursorFile))
+	if os.IsNotExist(err) {
+		return 0, 0, nil
+	}
+	if err != nil {
+		return 0, 0, err
+	}
+	var segment, offset int64
+	if _, err := fmt.Sscanf(string(data), "%d %d", &segment, &offset); err != nil {
+		return 0, 0, fmt.Errorf("invalid spill cursor: %s", err.Error())
+	}
+	return segment, offset, nil
+}
+
+// writeCursor replaces the cursor file with the position of the replay
+func (this *segmentQueue) writeCursor() error {
+	path := filepath.Join(this.config.Dir, spillCursorFile)
+	data := []byte(fmt.Sprintf("%d %d", this.segments[0], this.offset))
+	if err := ioutil.WriteFile(path+".tmp", data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+func (this *segmentQueue) close() error {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.closed = true
+	this.cond.Broadcast()
+	return this.writer.Close()
+}
@@ -0,0 +1,161 @@
+package go_streams
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// outageSink fails its writes while it is down
+type outageSink struct {
+	mutex   *sync.Mutex
+	down    bool
+	written []interface{}
+}
+
+func newOutageSink(down bool) *outageSink {
+	return &outageSink{mutex: &sync.Mutex{}, down: down}
+}
+
+func (this *outageSink) Single(entry Entry) error {
+	return this.Batch(entry)
+}
+
+func (this *outageSink) Batch(entry ...Entry) error {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	if this.down {
+		return fmt.Errorf("sink is down")
+	}
+	for _, e := range entry {
+		this.written = append(this.written, e.Value)
+	}
+	return nil
+}
+
+func (this *outageSink) Ping() error {
+	return nil
+}
+
+func (this *outageSink) setDown(down bool) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.down = down
+}
+
+func (this *outageSink) values() []interface{} {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	return append([]interface{}{}, this.written...)
+}
+
+func spillDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "spill")
+	assert.Nil(t, err)
+	return dir
+}
+
+func TestSpillSink_ReplaysOnceTheSinkRecovers(t *testing.T) {
+	dir := spillDir(t)
+	defer os.RemoveAll(dir)
+
+	inner := newOutageSink(false)
+	sink, err := NewSpillSink(inner, SpillConfig{Dir: dir, SegmentSize: 64, BatchSize: 2, Backoff: 10 * time.Millisecond})
+	assert.Nil(t, err)
+	defer sink.Close()
+
+	assert.Nil(t, sink.Single(Entry{Key: "1", Value: 1}))
+	inner.setDown(true)
+	assert.Nil(t, sink.Batch(Entry{Key: "2", Value: 2}, Entry{Key: "3", Value: 3}))
+	inner.setDown(false)
+	// queued behind the spilled entries unless they were replayed already
+	assert.Nil(t, sink.Single(Entry{Key: "4", Value: 4}))
+
+	assert.Eventually(t, func() bool { return sink.Stats().Pending == 0 }, time.Second, 5*time.Millisecond)
+	assert.EqualValues(t, []interface{}{1, 2, 3, 4}, inner.values())
+	stats := sink.Stats()
+	assert.EqualValues(t, 0, stats.Bytes)
+	assert.EqualValues(t, stats.Spilled, stats.Replayed)
+
+	segments, err := filepath.Glob(filepath.Join(dir, "*"+spillSegmentExt))
+	assert.Nil(t, err)
+	assert.Len(t, segments, 1)
+}
+
+func TestSpillSink_ResumesOnRestart(t *testing.T) {
+	dir := spillDir(t)
+	defer os.RemoveAll(dir)
+
+	config := SpillConfig{Dir: dir, SegmentSize: 64, BatchSize: 3, Backoff: time.Hour}
+	sink, err := NewSpillSink(newOutageSink(true), config)
+	assert.Nil(t, err)
+	for idx := 1; idx <= 5; idx++ {
+		assert.Nil(t, sink.Single(Entry{Key: fmt.Sprint(idx), Value: idx}))
+	}
+	assert.NotNil(t, sink.Flush())
+	assert.EqualValues(t, 5, sink.Stats().Pending)
+	assert.Nil(t, sink.Close())
+
+	inner := newOutageSink(false)
+	sink, err = NewSpillSink(inner, config)
+	assert.Nil(t, err)
+	defer sink.Close()
+	assert.Nil(t, sink.Flush())
+	assert.EqualValues(t, []interface{}{1, 2, 3, 4, 5}, inner.values())
+}
+
+func TestSegmentQueue_ResumesFromTheCursor(t *testing.T) {
+	dir := spillDir(t)
+	defer os.RemoveAll(dir)
+
+	config := SpillConfig{Dir: dir, SegmentSize: 1 << 20, Encode: gobEncodeEntry, Decode: gobDecodeEntry}
+	queue, err := openSegmentQueue(config)
+	assert.Nil(t, err)
+	assert.Nil(t, queue.append([]Entry{{Key: "1", Value: 1}, {Key: "2", Value: 2}, {Key: "3", Value: 3}}))
+	entries, offset, err := queue.peek(2)
+	assert.Nil(t, err)
+	assert.Len(t, entries, 2)
+	assert.Nil(t, queue.ack(len(entries), offset))
+	assert.Nil(t, queue.close())
+
+	queue, err = openSegmentQueue(config)
+	assert.Nil(t, err)
+	defer queue.close()
+	assert.EqualValues(t, 1, queue.len())
+	entries, _, err = queue.peek(2)
+	assert.Nil(t, err)
+	assert.EqualValues(t, []Entry{{Key: "3", Value: 3}}, entries)
+}
+
+func TestSpillSink_WaitsWhileTheQueueIsFull(t *testing.T) {
+	dir := spillDir(t)
+	defer os.RemoveAll(dir)
+
+	inner := newOutageSink(true)
+	sink, err := NewSpillSink(inner, SpillConfig{Dir: dir, MaxSize: 1, Backoff: 10 * time.Millisecond})
+	assert.Nil(t, err)
+	defer sink.Close()
+	assert.Nil(t, sink.Single(Entry{Key: "1", Value: 1}))
+
+	written := make(chan struct{})
+	go func() {
+		assert.Nil(t, sink.Single(Entry{Key: "2", Value: 2}))
+		close(written)
+	}()
+	select {
+	case <-written:
+		t.Fatal("the queue should be full")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	inner.setDown(false)
+	<-written
+	assert.Eventually(t, func() bool { return len(inner.values()) == 2 }, time.Second, 5*time.Millisecond)
+	assert.EqualValues(t, []interface{}{1, 2}, inner.values())
+}
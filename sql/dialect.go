@@ -0,0 +1,165 @@
+package sql
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Dialect builds the statements executed by the Sink for a specific database.
+type Dialect interface {
+	// Upsert builds a multi-row insert statement of the given number of rows,
+	// on a conflict on one of the conflict columns the update columns should be overwritten,
+	// an empty list of update columns means that conflicting rows are ignored,
+	// and an empty list of conflict columns means a plain insert.
+	Upsert(table string, columns, conflict, update []string, rows int) string
+
+	// MaxParameters returns the maximal number of bind parameters of a single statement.
+	MaxParameters() int
+}
+
+type postgresDialect struct{}
+
+// Postgres dialect uses $n placeholders and INSERT ... ON CONFLICT DO UPDATE
+func Postgres() Dialect {
+	return &postgresDialect{}
+}
+
+func (this *postgresDialect) Upsert(table string, columns, conflict, update []string, rows int) string {
+	var sb strings.Builder
+	writeInsert(&sb, "INSERT INTO", table, columns, rows, `"`, func(n int) string { return fmt.Sprintf("$%d", n) })
+	writeOnConflict(&sb, conflict, update, `"`, "EXCLUDED")
+	return sb.String()
+}
+
+func (this *postgresDialect) MaxParameters() int {
+	return 65535
+}
+
+type mysqlDialect struct{}
+
+// MySQL dialect uses ? placeholders and INSERT ... ON DUPLICATE KEY UPDATE,
+// since MySQL resolves conflicts by any unique key the conflict columns only
+// decide if the statement is an upsert or a plain insert.
+func MySQL() Dialect {
+	return &mysqlDialect{}
+}
+
+func (this *mysqlDialect) Upsert(table string, columns, conflict, update []string, rows int) string {
+	var sb strings.Builder
+	verb := "INSERT INTO"
+	if len(conflict) > 0 && len(update) == 0 {
+		verb = "INSERT IGNORE INTO"
+	}
+	writeInsert(&sb, verb, table, columns, rows, "`", func(int) string { return "?" })
+
+	if len(conflict) > 0 && len(update) > 0 {
+		sb.WriteString(" ON DUPLICATE KEY UPDATE ")
+		for idx, col := range update {
+			if idx > 0 {
+				sb.WriteString(", ")
+			}
+			c := quote(col, "`")
+			sb.WriteString(fmt.Sprintf("%s = VALUES(%s)", c, c))
+		}
+	}
+	return sb.String()
+}
+
+func (this *mysqlDialect) MaxParameters() int {
+	return 65535
+}
+
+type sqliteDialect struct{}
+
+// SQLite dialect uses ? placeholders and INSERT ... ON CONFLICT DO UPDATE (SQLite 3.24+)
+func SQLite() Dialect {
+	return &sqliteDialect{}
+}
+
+func (this *sqliteDialect) Upsert(table string, columns, conflict, update []string, rows int) string {
+	var sb strings.Builder
+	writeInsert(&sb, "INSERT INTO", table, columns, rows, `"`, func(int) string { return "?" })
+	writeOnConflict(&sb, conflict, update, `"`, "excluded")
+	return sb.String()
+}
+
+func (this *sqliteDialect) MaxParameters() int {
+	return 999
+}
+
+func writeInsert(sb *strings.Builder, verb, table string, columns []string, rows int, q string, placeholder func(n int) string) {
+	sb.WriteString(fmt.Sprintf("%s %s (", verb, quote(table, q)))
+	for idx, col := range columns {
+		if idx > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString(quote(col, q))
+	}
+	sb.WriteString(") VALUES ")
+
+	param := 1
+	for row := 0; row < rows; row++ {
+		if row > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString("(")
+		for idx := range columns {
+			if idx > 0 {
+				sb.WriteString(", ")
+			}
+			sb.WriteString(placeholder(param))
+			param++
+		}
+		sb.WriteString(")")
+	}
+}
+
+func writeOnConflict(sb *strings.Builder, conflict, update []string, q, excluded string) {
+	if len(conflict) == 0 {
+		return
+	}
+
+	sb.WriteString(" ON CONFLICT (")
+	for idx, col := range conflict {
+		if idx > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString(quote(col, q))
+	}
+	sb.WriteString(")")
+
+	if len(update) == 0 {
+		sb.WriteString(" DO NOTHING")
+		return
+	}
+
+	sb.WriteString(" DO UPDATE SET ")
+	for idx, col := range update {
+		if idx > 0 {
+			sb.WriteString(", ")
+		}
+		c := quote(col, q)
+		sb.WriteString(fmt.Sprintf("%s = %s.%s", c, excluded, c))
+	}
+}
+
+// selectTransaction builds the query of the latest transaction of a sink in the transactions table
+func selectTransaction(dialect Dialect, table string) string {
+	q, placeholder := `"`, "?"
+	switch dialect.(type) {
+	case *postgresDialect:
+		placeholder = "$1"
+	case *mysqlDialect:
+		q = "`"
+	}
+	return fmt.Sprintf("SELECT %s FROM %s WHERE %s = %s", quote("txn", q), quote(table, q), quote("sink", q), placeholder)
+}
+
+// quote quotes an identifier, dotted identifiers (e.g: schema.table) are quoted part by part.
+func quote(identifier, q string) string {
+	parts := strings.Split(identifier, ".")
+	for idx := range parts {
+		parts[idx] = q + strings.Replace(parts[idx], q, q+q, -1) + q
+	}
+	return strings.Join(parts, ".")
+}
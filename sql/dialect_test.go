@@ -0,0 +1,38 @@
+package sql
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPostgres_Upsert(t *testing.T) {
+	d := Postgres()
+	assert.EqualValues(t,
+		`INSERT INTO "public"."users" ("id", "name") VALUES ($1, $2), ($3, $4) ON CONFLICT ("id") DO UPDATE SET "name" = EXCLUDED."name"`,
+		d.Upsert("public.users", []string{"id", "name"}, []string{"id"}, []string{"name"}, 2))
+	assert.EqualValues(t,
+		`INSERT INTO "users" ("id") VALUES ($1) ON CONFLICT ("id") DO NOTHING`,
+		d.Upsert("users", []string{"id"}, []string{"id"}, nil, 1))
+	assert.EqualValues(t,
+		`INSERT INTO "users" ("id") VALUES ($1)`,
+		d.Upsert("users", []string{"id"}, nil, nil, 1))
+}
+
+func TestMySQL_Upsert(t *testing.T) {
+	d := MySQL()
+	assert.EqualValues(t,
+		"INSERT INTO `users` (`id`, `name`) VALUES (?, ?), (?, ?) ON DUPLICATE KEY UPDATE `name` = VALUES(`name`)",
+		d.Upsert("users", []string{"id", "name"}, []string{"id"}, []string{"name"}, 2))
+	assert.EqualValues(t,
+		"INSERT IGNORE INTO `users` (`id`) VALUES (?)",
+		d.Upsert("users", []string{"id"}, []string{"id"}, nil, 1))
+}
+
+func TestSQLite_Upsert(t *testing.T) {
+	d := SQLite()
+	assert.EqualValues(t,
+		`INSERT INTO "users" ("id", "name") VALUES (?, ?) ON CONFLICT ("id") DO UPDATE SET "name" = excluded."name"`,
+		d.Upsert("users", []string{"id", "name"}, []string{"id"}, []string{"name"}, 1))
+	assert.EqualValues(t, 999, d.MaxParameters())
+}
@@ -0,0 +1,290 @@
+package sql
+
+import (
+	gosql "database/sql"
+	"fmt"
+	"strings"
+
+	streams "github.com/matang28/go-streams"
+)
+
+// Binder maps an entry to a row, the returned values should
+// be ordered the same as the configured columns.
+type Binder func(entry streams.Entry) ([]interface{}, error)
+
+// Config describes how entries are written to the target table
+type Config struct {
+	// Table is the target table name (may be schema qualified)
+	Table string
+
+	// Columns are the columns written by the sink, in the order returned by the Binder
+	Columns []string
+
+	// ConflictColumns are the columns of the unique constraint used for upserts,
+	// leave empty to use plain inserts.
+	ConflictColumns []string
+
+	// UpdateColumns are the columns overwritten on conflict,
+	// when empty all the columns that aren't conflict columns are updated.
+	UpdateColumns []string
+
+	// IgnoreConflicts will skip conflicting rows instead of updating them.
+	IgnoreConflicts bool
+
+	// BatchSize is the maximal number of rows per statement,
+	// when zero it is derived from the dialect's parameters limit.
+	BatchSize int
+
+	// TransactionsTable is the table recording the latest transaction of the sink, required to write
+	// with a two phase commit sink (see streams.NewTwoPhaseCommitSink). It has a "sink" primary key
+	// column and a "txn" column, both strings.
+	TransactionsTable string
+
+	// TransactionKey identifies the sink in the transactions table (defaults to the table name)
+	TransactionKey string
+
+	Dialect Dialect
+	Binder  Binder
+}
+
+// Sink writes entries to a table using database/sql, each call to Batch
+// is executed as a single transaction of one or more multi-row upsert statements.
+type Sink struct {
+	db        *gosql.DB
+	config    Config
+	update    []string
+	batchSize int
+	conflict  []int
+}
+
+func NewSink(db *gosql.DB, config Config) (*Sink, error) {
+	if config.Table == "" || len(config.Columns) == 0 {
+		return nil, fmt.Errorf("sql sink requires a table and at least one column")
+	}
+	if config.Dialect == nil || config.Binder == nil {
+		return nil, fmt.Errorf("sql sink requires a dialect and a binder")
+	}
+
+	update := config.UpdateColumns
+	if config.IgnoreConflicts {
+		update = nil
+	} else if len(update) == 0 {
+		update = excluding(config.Columns, config.ConflictColumns)
+	}
+
+	batchSize := config.Dialect.MaxParameters() / len(config.Columns)
+	if config.BatchSize > 0 && config.BatchSize < batchSize {
+		batchSize = config.BatchSize
+	}
+	if config.TransactionKey == "" {
+		config.TransactionKey = config.Table
+	}
+	if batchSize == 0 {
+		return nil, fmt.Errorf("table '%s' has more columns than the dialect's parameters limit", config.Table)
+	}
+
+	// Upserts can't affect the same row twice in a single statement (e.g: postgres fails with
+	// "cannot affect row a second time") so rows sharing a conflict key are deduped.
+	var conflict []int
+	if !config.IgnoreConflicts {
+		conflict = indexesOf(config.Columns, config.ConflictColumns)
+	}
+
+	return &Sink{db: db, config: config, update: update, batchSize: batchSize, conflict: conflict}, nil
+}
+
+func (this *Sink) Ping() error {
+	return this.db.Ping()
+}
+
+func (this *Sink) Single(entry streams.Entry) error {
+	return this.Batch(entry)
+}
+
+// Batch writes the rows of the entries that were bound, the entries that failed to bind are
+// returned as a SinkBatchError once the other rows were committed.
+func (this *Sink) Batch(entry ...streams.Entry) error {
+	if len(entry) == 0 {
+		return nil
+	}
+
+	args, bindErr := this.bind(entry)
+	if len(args) == 0 {
+		return bindErr
+	}
+
+	tx, err := this.db.Begin()
+	if err != nil {
+		return err
+	}
+	if err := this.exec(tx, args); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	return bindErr
+}
+
+// Begin starts a transaction of the two phase commit sink, its Prepare records the transaction id
+// in the transactions table so Recover can tell whether it was committed.
+func (this *Sink) Begin(id string) (streams.Transaction, error) {
+	if this.config.TransactionsTable == "" {
+		return nil, fmt.Errorf("sql sink of '%s' requires a transactions table", this.config.Table)
+	}
+	tx, err := this.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	return &transaction{sink: this, tx: tx, id: id}, nil
+}
+
+// Recover returns whether the transaction was committed, an uncommitted transaction
+// was rolled back by the database when its connection was lost.
+func (this *Sink) Recover(id string, commit bool) (bool, error) {
+	if this.config.TransactionsTable == "" {
+		return false, fmt.Errorf("sql sink of '%s' requires a transactions table", this.config.Table)
+	}
+	var latest string
+	err := this.db.QueryRow(selectTransaction(this.config.Dialect, this.config.TransactionsTable), this.config.TransactionKey).Scan(&latest)
+	if err == gosql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return latest == id, nil
+}
+
+// exec writes the rows in chunks of the batch size
+func (this *Sink) exec(tx *gosql.Tx, args []interface{}) error {
+	columns := len(this.config.Columns)
+	rows := len(args) / columns
+
+	for from := 0; from < rows; from += this.batchSize {
+		to := from + this.batchSize
+		if to > rows {
+			to = rows
+		}
+
+		query := this.config.Dialect.Upsert(this.config.Table, this.config.Columns, this.config.ConflictColumns, this.update, to-from)
+		if _, err := tx.Exec(query, args[from*columns:to*columns]...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// transaction is a transaction of the sink, its writes are uncommitted until Commit
+type transaction struct {
+	sink *Sink
+	tx   *gosql.Tx
+	id   string
+}
+
+func (this *transaction) Write(entries ...streams.Entry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	// the transaction is aborted, a SinkBatchError would tell that the other entries were written
+	args, err := this.sink.bind(entries)
+	if err != nil {
+		return fmt.Errorf("failed to bind the entries of the transaction: %s", err.Error())
+	}
+	return this.sink.exec(this.tx, args)
+}
+
+// Prepare records the transaction id in the transactions table, it is committed with the writes
+func (this *transaction) Prepare() error {
+	config := this.sink.config
+	query := config.Dialect.Upsert(config.TransactionsTable, []string{"sink", "txn"}, []string{"sink"}, []string{"txn"}, 1)
+	_, err := this.tx.Exec(query, config.TransactionKey, this.id)
+	return err
+}
+
+func (this *transaction) Commit() error {
+	return this.tx.Commit()
+}
+
+func (this *transaction) Abort() error {
+	return this.tx.Rollback()
+}
+
+// bind returns the arguments of all the rows, rows with the same conflict key
+// are written once with the values of the latest entry.
+func (this *Sink) bind(entries []streams.Entry) ([]interface{}, error) {
+	columns := len(this.config.Columns)
+	args := make([]interface{}, 0, len(entries)*columns)
+	rows := make(map[string]int)
+	errs := streams.NewSinkBatchError()
+
+	for idx := range entries {
+		values, err := this.config.Binder(entries[idx])
+		if err == nil && len(values) != columns {
+			err = fmt.Errorf("binder returned %d values for %d columns", len(values), columns)
+		}
+		if err != nil {
+			errs.Add(entries[idx].Key, err)
+			continue
+		}
+
+		if len(this.conflict) > 0 {
+			key := conflictKey(values, this.conflict)
+			if row, found := rows[key]; found {
+				copy(args[row*columns:(row+1)*columns], values)
+				continue
+			}
+			rows[key] = len(args) / columns
+		}
+		args = append(args, values...)
+	}
+
+	return args, errs.AsError()
+}
+
+func conflictKey(values []interface{}, indexes []int) string {
+	var key strings.Builder
+	for _, idx := range indexes {
+		v := values[idx]
+		if b, ok := v.([]byte); ok {
+			v = string(b)
+		}
+		fmt.Fprintf(&key, "%T:%v\x00", v, v)
+	}
+	return key.String()
+}
+
+// indexesOf returns the indexes of the given columns, or nil if one of them isn't found
+func indexesOf(columns, of []string) []int {
+	indexes := make([]int, 0, len(of))
+	for _, col := range of {
+		found := false
+		for idx := range columns {
+			if columns[idx] == col {
+				indexes = append(indexes, idx)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil
+		}
+	}
+	return indexes
+}
+
+func excluding(columns, excluded []string) []string {
+	set := make(map[string]bool, len(excluded))
+	for _, col := range excluded {
+		set[col] = true
+	}
+
+	var out []string
+	for _, col := range columns {
+		if !set[col] {
+			out = append(out, col)
+		}
+	}
+	return out
+}
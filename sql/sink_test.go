@@ -0,0 +1,315 @@
+package sql
+
+import (
+	gosql "database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	streams "github.com/matang28/go-streams"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSink_Batch_ChunksStatementsInTransaction(t *testing.T) {
+	db, rec := openRecorder(t)
+	sink, err := NewSink(db, Config{
+		Table:           "users",
+		Columns:         []string{"id", "name"},
+		ConflictColumns: []string{"id"},
+		BatchSize:       2,
+		Dialect:         Postgres(),
+		Binder:          userBinder,
+	})
+	assert.Nil(t, err)
+
+	err = sink.Batch(user("1", "a"), user("2", "b"), user("3", "c"))
+	assert.Nil(t, err)
+
+	assert.EqualValues(t, []string{
+		"BEGIN",
+		`INSERT INTO "users" ("id", "name") VALUES ($1, $2), ($3, $4) ON CONFLICT ("id") DO UPDATE SET "name" = EXCLUDED."name"`,
+		`INSERT INTO "users" ("id", "name") VALUES ($1, $2) ON CONFLICT ("id") DO UPDATE SET "name" = EXCLUDED."name"`,
+		"COMMIT",
+	}, rec.log())
+	assert.EqualValues(t, []driver.Value{"1", "a", "2", "b", "3", "c"}, rec.allArgs())
+}
+
+func TestSink_Batch_DedupesConflictKeys(t *testing.T) {
+	db, rec := openRecorder(t)
+	sink, err := NewSink(db, Config{
+		Table:           "users",
+		Columns:         []string{"id", "name"},
+		ConflictColumns: []string{"id"},
+		Dialect:         Postgres(),
+		Binder:          userBinder,
+	})
+	assert.Nil(t, err)
+
+	err = sink.Batch(user("1", "a"), user("2", "b"), user("1", "c"))
+	assert.Nil(t, err)
+
+	assert.EqualValues(t, `INSERT INTO "users" ("id", "name") VALUES ($1, $2), ($3, $4) ON CONFLICT ("id") DO UPDATE SET "name" = EXCLUDED."name"`, rec.log()[1])
+	assert.EqualValues(t, []driver.Value{"1", "c", "2", "b"}, rec.allArgs())
+}
+
+func TestSink_Batch_RollbackOnError(t *testing.T) {
+	db, rec := openRecorder(t)
+	rec.failOn = "INSERT"
+	sink, err := NewSink(db, Config{Table: "users", Columns: []string{"id", "name"}, Dialect: SQLite(), Binder: userBinder})
+	assert.Nil(t, err)
+
+	err = sink.Single(user("1", "a"))
+	assert.NotNil(t, err)
+	assert.EqualValues(t, "ROLLBACK", rec.log()[len(rec.log())-1])
+}
+
+func TestSink_Batch_BinderErrors(t *testing.T) {
+	db, rec := openRecorder(t)
+	sink, err := NewSink(db, Config{Table: "users", Columns: []string{"id", "name"}, Dialect: MySQL(), Binder: userBinder})
+	assert.Nil(t, err)
+
+	err = sink.Batch(user("1", "a"), streams.Entry{Key: "bad", Value: 12})
+	batchErr, ok := err.(*streams.SinkBatchError)
+	assert.True(t, ok)
+	assert.NotNil(t, batchErr.Errors["bad"])
+	assert.Len(t, batchErr.Errors, 1)
+
+	// the entries that were bound are written
+	assert.EqualValues(t, []string{"BEGIN", "INSERT INTO `users` (`id`, `name`) VALUES (?, ?)", "COMMIT"}, rec.log())
+	assert.EqualValues(t, []driver.Value{"1", "a"}, rec.allArgs())
+}
+
+func TestSink_Batch_BinderErrorsOnly(t *testing.T) {
+	db, rec := openRecorder(t)
+	sink, err := NewSink(db, Config{Table: "users", Columns: []string{"id", "name"}, Dialect: MySQL(), Binder: userBinder})
+	assert.Nil(t, err)
+
+	err = sink.Batch(streams.Entry{Key: "bad", Value: 12})
+	assert.IsType(t, &streams.SinkBatchError{}, err)
+	assert.Empty(t, rec.log())
+}
+
+func TestNewSink_Validation(t *testing.T) {
+	db, _ := openRecorder(t)
+	_, err := NewSink(db, Config{Columns: []string{"id"}, Dialect: Postgres(), Binder: userBinder})
+	assert.NotNil(t, err)
+
+	_, err = NewSink(db, Config{Table: "users", Columns: []string{"id"}, Binder: userBinder})
+	assert.NotNil(t, err)
+}
+
+func TestSink_Transaction(t *testing.T) {
+	db, rec := openRecorder(t)
+	sink, err := NewSink(db, Config{
+		Table:             "users",
+		Columns:           []string{"id", "name"},
+		TransactionsTable: "txns",
+		Dialect:           Postgres(),
+		Binder:            userBinder,
+	})
+	assert.Nil(t, err)
+
+	tx, err := sink.Begin("tx-1")
+	assert.Nil(t, err)
+	assert.Nil(t, tx.Write(user("1", "a")))
+	assert.Nil(t, tx.Prepare())
+	assert.Nil(t, tx.Commit())
+
+	assert.EqualValues(t, []string{
+		"BEGIN",
+		`INSERT INTO "users" ("id", "name") VALUES ($1, $2)`,
+		`INSERT INTO "txns" ("sink", "txn") VALUES ($1, $2) ON CONFLICT ("sink") DO UPDATE SET "txn" = EXCLUDED."txn"`,
+		"COMMIT",
+	}, rec.log())
+	assert.EqualValues(t, []driver.Value{"1", "a", "users", "tx-1"}, rec.allArgs())
+}
+
+func TestSink_Recover(t *testing.T) {
+	db, rec := openRecorder(t)
+	sink, err := NewSink(db, Config{
+		Table:             "users",
+		Columns:           []string{"id", "name"},
+		TransactionsTable: "txns",
+		TransactionKey:    "users-sink",
+		Dialect:           MySQL(),
+		Binder:            userBinder,
+	})
+	assert.Nil(t, err)
+
+	committed, err := sink.Recover("tx-1", true)
+	assert.Nil(t, err)
+	assert.False(t, committed)
+
+	rec.row = []driver.Value{"tx-1"}
+	committed, err = sink.Recover("tx-1", true)
+	assert.Nil(t, err)
+	assert.True(t, committed)
+
+	committed, err = sink.Recover("tx-2", true)
+	assert.Nil(t, err)
+	assert.False(t, committed)
+	assert.EqualValues(t, "SELECT `txn` FROM `txns` WHERE `sink` = ?", rec.log()[0])
+	assert.EqualValues(t, "users-sink", rec.allArgs()[0])
+
+	withoutTable, err := NewSink(db, Config{Table: "users", Columns: []string{"id", "name"}, Dialect: MySQL(), Binder: userBinder})
+	assert.Nil(t, err)
+	_, err = withoutTable.Begin("tx-3")
+	assert.NotNil(t, err)
+}
+
+type userRow struct {
+	id, name string
+}
+
+func user(id, name string) streams.Entry {
+	return streams.Entry{Key: id, Value: userRow{id: id, name: name}}
+}
+
+func userBinder(entry streams.Entry) ([]interface{}, error) {
+	u, ok := entry.Value.(userRow)
+	if !ok {
+		return nil, errors.New("not a user")
+	}
+	return []interface{}{u.id, u.name}, nil
+}
+
+// recorder is a minimal database/sql driver that records the executed statements
+type recorder struct {
+	mutex   sync.Mutex
+	queries []string
+	args    []driver.Value
+	failOn  string
+
+	// row is the single row returned by queries, they return no rows when it isn't set
+	row []driver.Value
+}
+
+var recorders = struct {
+	sync.Mutex
+	m map[string]*recorder
+}{m: make(map[string]*recorder)}
+
+func init() {
+	gosql.Register("recorder", &recorderDriver{})
+}
+
+func openRecorder(t *testing.T) (*gosql.DB, *recorder) {
+	rec := &recorder{}
+	recorders.Lock()
+	recorders.m[t.Name()] = rec
+	recorders.Unlock()
+
+	db, err := gosql.Open("recorder", t.Name())
+	assert.Nil(t, err)
+	return db, rec
+}
+
+func (r *recorder) record(query string, args []driver.Value) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.queries = append(r.queries, query)
+	r.args = append(r.args, args...)
+	if r.failOn != "" && strings.HasPrefix(query, r.failOn) {
+		return errors.New("statement failed")
+	}
+	return nil
+}
+
+func (r *recorder) log() []string {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.queries
+}
+
+func (r *recorder) allArgs() []driver.Value {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.args
+}
+
+type recorderDriver struct{}
+
+func (d *recorderDriver) Open(name string) (driver.Conn, error) {
+	recorders.Lock()
+	defer recorders.Unlock()
+	return &recorderConn{rec: recorders.m[name]}, nil
+}
+
+type recorderConn struct {
+	rec *recorder
+}
+
+func (c *recorderConn) Prepare(query string) (driver.Stmt, error) {
+	return &recorderStmt{rec: c.rec, query: query}, nil
+}
+
+func (c *recorderConn) Close() error {
+	return nil
+}
+
+func (c *recorderConn) Begin() (driver.Tx, error) {
+	return &recorderTx{rec: c.rec}, c.rec.record("BEGIN", nil)
+}
+
+type recorderTx struct {
+	rec *recorder
+}
+
+func (tx *recorderTx) Commit() error {
+	return tx.rec.record("COMMIT", nil)
+}
+
+func (tx *recorderTx) Rollback() error {
+	return tx.rec.record("ROLLBACK", nil)
+}
+
+type recorderStmt struct {
+	rec   *recorder
+	query string
+}
+
+func (s *recorderStmt) Close() error {
+	return nil
+}
+
+func (s *recorderStmt) NumInput() int {
+	return -1
+}
+
+func (s *recorderStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return driver.RowsAffected(1), s.rec.record(s.query, args)
+}
+
+func (s *recorderStmt) Query(args []driver.Value) (driver.Rows, error) {
+	if err := s.rec.record(s.query, args); err != nil {
+		return nil, err
+	}
+	s.rec.mutex.Lock()
+	defer s.rec.mutex.Unlock()
+	return &recorderRows{row: s.rec.row}, nil
+}
+
+type recorderRows struct {
+	row  []driver.Value
+	read bool
+}
+
+func (r *recorderRows) Columns() []string {
+	return make([]string, len(r.row))
+}
+
+func (r *recorderRows) Close() error {
+	return nil
+}
+
+func (r *recorderRows) Next(dest []driver.Value) error {
+	if r.read || r.row == nil {
+		return io.EOF
+	}
+	r.read = true
+	copy(dest, r.row)
+	return nil
+}
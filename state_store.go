@@ -0,0 +1,147 @@
+package go_streams
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// StateStore keeps the state of stateful operators (e.g: Dedupe) by key,
+// values stored with a positive ttl expire once the ttl is over.
+type StateStore interface {
+	// Get returns the value of the key, found is false when the key doesn't exist or expired
+	Get(key string) (value interface{}, found bool, err error)
+
+	// Put sets the value of the key, a ttl of 0 means the value never expires
+	Put(key string, value interface{}, ttl time.Duration) error
+
+	// Delete removes the key
+	Delete(key string) error
+}
+
+type storedValue struct {
+	key     string
+	value   interface{}
+	expires time.Time
+}
+
+func (this *storedValue) expired(now time.Time) bool {
+	return !this.expires.IsZero() && !now.Before(this.expires)
+}
+
+func expiryOf(ttl time.Duration, now time.Time) time.Time {
+	if ttl <= 0 {
+		return time.Time{}
+	}
+	return now.Add(ttl)
+}
+
+// memoryStateStore is an in memory LRU store, the least recently used keys are evicted
+// once it holds more than maxKeys keys.
+type memoryStateStore struct {
+	maxKeys int
+	values  map[string]*list.Element
+	lru     *list.List
+	mutex   *sync.Mutex
+}
+
+// NewMemoryStateStore creates an in memory store bounded to maxKeys keys (0 means unbounded)
+func NewMemoryStateStore(maxKeys int) StateStore {
+	return &memoryStateStore{
+		maxKeys: maxKeys,
+		values:  make(map[string]*list.Element),
+		lru:     list.New(),
+		mutex:   &sync.Mutex{},
+	}
+}
+
+func (this *memoryStateStore) Get(key string) (interface{}, bool, error) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	elem, found := this.values[key]
+	if !found {
+		return nil, false, nil
+	}
+	stored := elem.Value.(*storedValue)
+	if stored.expired(time.Now()) {
+		this.remove(elem)
+		return nil, false, nil
+	}
+	this.lru.MoveToFront(elem)
+	return stored.value, true, nil
+}
+
+func (this *memoryStateStore) Put(key string, value interface{}, ttl time.Duration) error {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	stored := &storedValue{key: key, value: value, expires: expiryOf(ttl, time.Now())}
+	if elem, found := this.values[key]; found {
+		elem.Value = stored
+		this.lru.MoveToFront(elem)
+		return nil
+	}
+
+	this.values[key] = this.lru.PushFront(stored)
+	if this.maxKeys > 0 && this.lru.Len() > this.maxKeys {
+		this.remove(this.lru.Back())
+	}
+	return nil
+}
+
+func (this *memoryStateStore) Delete(key string) error {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	if elem, found := this.values[key]; found {
+		this.remove(elem)
+	}
+	return nil
+}
+
+func (this *memoryStateStore) remove(elem *list.Element) {
+	this.lru.Remove(elem)
+	delete(this.values, elem.Value.(*storedValue).key)
+}
+
+type storedValueState struct {
+	Key     string
+	Value   interface{}
+	Expires time.Time
+}
+
+// Snapshot encodes the keys that didn't expire from the least to the most recently used, see Snapshotter
+func (this *memoryStateStore) Snapshot() ([]byte, error) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	now := time.Now()
+	values := make([]storedValueState, 0, this.lru.Len())
+	for elem := this.lru.Back(); elem != nil; elem = elem.Prev() {
+		stored := elem.Value.(*storedValue)
+		if !stored.expired(now) {
+			values = append(values, storedValueState{Key: stored.key, Value: stored.value, Expires: stored.expires})
+		}
+	}
+	return encodeState(&values)
+}
+
+// Restore replaces the keys by a snapshot, the keys exceeding maxKeys are evicted
+func (this *memoryStateStore) Restore(data []byte) error {
+	var values []storedValueState
+	if err := decodeState(data, &values); err != nil {
+		return err
+	}
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.values = make(map[string]*list.Element, len(values))
+	this.lru = list.New()
+	for idx := range values {
+		stored := &storedValue{key: values[idx].Key, value: values[idx].Value, expires: values[idx].Expires}
+		this.values[stored.key] = this.lru.PushFront(stored)
+		if this.maxKeys > 0 && this.lru.Len() > this.maxKeys {
+			this.remove(this.lru.Back())
+		}
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+package go_streams
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestMemoryStateStore_EvictsLeastRecentlyUsed(t *testing.T) {
+	store := NewMemoryStateStore(2)
+	assert.Nil(t, store.Put("a", 1, 0))
+	assert.Nil(t, store.Put("b", 2, 0))
+
+	_, found, _ := store.Get("a")
+	assert.True(t, found)
+	assert.Nil(t, store.Put("c", 3, 0))
+
+	_, found, _ = store.Get("b")
+	assert.False(t, found)
+	value, found, _ := store.Get("a")
+	assert.True(t, found)
+	assert.EqualValues(t, 1, value)
+}
+
+func TestMemoryStateStore_Expires(t *testing.T) {
+	store := NewMemoryStateStore(0)
+	assert.Nil(t, store.Put("a", 1, 10*time.Millisecond))
+	_, found, _ := store.Get("a")
+	assert.True(t, found)
+
+	time.Sleep(20 * time.Millisecond)
+	_, found, _ = store.Get("a")
+	assert.False(t, found)
+}
+
+func TestFileStateStore_PersistsAcrossOpens(t *testing.T) {
+	dir, err := ioutil.TempDir("", "state")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "state.log")
+
+	store, err := NewFileStateStore(path)
+	assert.Nil(t, err)
+	assert.Nil(t, store.Put("a", "1", 0))
+	assert.Nil(t, store.Put("b", "2", 0))
+	assert.Nil(t, store.Put("expired", "3", time.Nanosecond))
+	assert.Nil(t, store.Delete("b"))
+	assert.Nil(t, store.Close())
+
+	store, err = NewFileStateStore(path)
+	assert.Nil(t, err)
+	defer store.Close()
+
+	value, found, err := store.Get("a")
+	assert.Nil(t, err)
+	assert.True(t, found)
+	assert.EqualValues(t, "1", value)
+	for _, key := range []string{"b", "expired"} {
+		_, found, _ = store.Get(key)
+		assert.False(t, found)
+	}
+}
+
+func TestFileStateStore_DropsPartialRecord(t *testing.T) {
+	dir, err := ioutil.TempDir("", "state")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "state.log")
+
+	store, err := NewFileStateStore(path)
+	assert.Nil(t, err)
+	assert.Nil(t, store.Put("a", "1", 0))
+	assert.Nil(t, store.Close())
+
+	// the tail of a write that didn't complete
+	data, err := encodeRecord(fileRecord{Key: "torn", Value: "2"})
+	assert.Nil(t, err)
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+	assert.Nil(t, err)
+	_, err = file.Write(data[:len(data)/2])
+	assert.Nil(t, err)
+	assert.Nil(t, file.Close())
+
+	store, err = NewFileStateStore(path)
+	assert.Nil(t, err)
+	assert.Nil(t, store.Put("b", "3", 0))
+	assert.Nil(t, store.Close())
+
+	store, err = NewFileStateStore(path)
+	assert.Nil(t, err)
+	defer store.Close()
+	for key, expected := range map[string]interface{}{"a": "1", "b": "3"} {
+		value, found, _ := store.Get(key)
+		assert.True(t, found)
+		assert.EqualValues(t, expected, value)
+	}
+	_, found, _ := store.Get("torn")
+	assert.False(t, found)
+}
+
+func TestFileStateStore_Compacts(t *testing.T) {
+	dir, err := ioutil.TempDir("", "state")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "state.log")
+
+	store, err := NewFileStateStore(path)
+	assert.Nil(t, err)
+	for i := 0; i < 3*compactThreshold; i++ {
+		assert.Nil(t, store.Put(fmt.Sprintf("%d", i%10), i, 0))
+	}
+	assert.True(t, store.records < compactThreshold)
+	assert.Nil(t, store.Close())
+
+	store, err = NewFileStateStore(path)
+	assert.Nil(t, err)
+	defer store.Close()
+	value, found, _ := store.Get("1")
+	assert.True(t, found)
+	assert.EqualValues(t, 3*compactThreshold-1, value)
+}
+
+func TestDedupe_DropsRepeatedKeys(t *testing.T) {
+	errs := make(ErrorChannel, 100)
+	source := NewSequentialIntegerSource(10, 0)
+	sink := NewArraySink()
+	stream := NewStream(source).Dedupe(func(value interface{}) string {
+		return fmt.Sprintf("%d", value.(int)%3)
+	}, time.Minute).Sink(sink)
+	stream.Process(NewDirectProcessor(), errs)
+
+	assert.EqualValues(t, []interface{}{0, 1, 2}, sink.Array())
+}
+
+func TestDedupe_LetsKeysPassOnceExpired(t *testing.T) {
+	op := NewDedupe(func(value interface{}) string { return value.(string) }, 10*time.Millisecond, NewMemoryStateStore(10))
+
+	entries, _ := op.Apply(Entry{Value: "a"})
+	assert.Len(t, entries, 1)
+	entries, _ = op.Apply(Entry{Value: "a"})
+	assert.Empty(t, entries)
+
+	time.Sleep(20 * time.Millisecond)
+	entries, _ = op.Apply(Entry{Value: "a"})
+	assert.Len(t, entries, 1)
+}
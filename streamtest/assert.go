@@ -0,0 +1,34 @@
+package streamtest
+
+import (
+	"reflect"
+	"testing"
+)
+
+// AssertSinked fails the test unless the sink received exactly the expected values, in order
+func AssertSinked(t testing.TB, sink *CollectorSink, expected ...interface{}) bool {
+	t.Helper()
+	actual := sink.Values()
+	if len(actual) == 0 && len(expected) == 0 {
+		return true
+	}
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("sinked values mismatch:\n\texpected: %v\n\tactual:   %v", expected, actual)
+		return false
+	}
+	return true
+}
+
+// AssertCommitted fails the test unless the source committed exactly the expected keys, in order
+func AssertCommitted(t testing.TB, source *SliceSource, expected ...string) bool {
+	t.Helper()
+	actual := source.Committed()
+	if len(actual) == 0 && len(expected) == 0 {
+		return true
+	}
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("committed keys mismatch:\n\texpected: %v\n\tactual:   %v", expected, actual)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,43 @@
+package streamtest
+
+import (
+	"sync"
+	"time"
+
+	streams "github.com/matang28/go-streams"
+)
+
+// Clock is a controllable clock for testing time based operators (e.g: windows, debounce),
+// it stamps entries with its current time and drives the ticks of timed operators.
+type Clock struct {
+	mutex *sync.Mutex
+	now   time.Time
+}
+
+func NewClock(start time.Time) *Clock {
+	return &Clock{mutex: &sync.Mutex{}, now: start}
+}
+
+func (this *Clock) Now() time.Time {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	return this.now
+}
+
+// Advance moves the clock forward
+func (this *Clock) Advance(d time.Duration) time.Time {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.now = this.now.Add(d)
+	return this.now
+}
+
+// Entry creates an entry stamped with the current time of the clock
+func (this *Clock) Entry(key string, value interface{}) streams.Entry {
+	return streams.Entry{Key: key, Value: value, Timestamp: this.Now()}
+}
+
+// Tick ticks the operator at the current time of the clock
+func (this *Clock) Tick(operator streams.TimedOperator) ([]streams.Entry, error) {
+	return operator.Tick(this.Now())
+}
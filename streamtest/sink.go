@@ -0,0 +1,87 @@
+package streamtest
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	streams "github.com/matang28/go-streams"
+)
+
+// CollectorSink keeps the entries it receives, it is safe to read them while the stream runs
+type CollectorSink struct {
+	mutex   *sync.Mutex
+	cond    *sync.Cond
+	entries []streams.Entry
+	fail    error
+}
+
+func NewCollectorSink() *CollectorSink {
+	sink := &CollectorSink{mutex: &sync.Mutex{}}
+	sink.cond = sync.NewCond(sink.mutex)
+	return sink
+}
+
+// FailWith makes the sink fail every write with the given error (nil to succeed again)
+func (this *CollectorSink) FailWith(err error) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.fail = err
+}
+
+func (this *CollectorSink) Single(entry streams.Entry) error {
+	return this.Batch(entry)
+}
+
+func (this *CollectorSink) Batch(entry ...streams.Entry) error {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	if this.fail != nil {
+		return this.fail
+	}
+	this.entries = append(this.entries, entry...)
+	this.cond.Broadcast()
+	return nil
+}
+
+func (this *CollectorSink) Ping() error {
+	return nil
+}
+
+// Entries returns a copy of the received entries
+func (this *CollectorSink) Entries() []streams.Entry {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	return append([]streams.Entry{}, this.entries...)
+}
+
+// Values returns the values of the received entries
+func (this *CollectorSink) Values() []interface{} {
+	entries := this.Entries()
+	values := make([]interface{}, len(entries))
+	for idx := range entries {
+		values[idx] = entries[idx].Value
+	}
+	return values
+}
+
+// Await waits until the sink received at least n entries, it fails once the timeout is over
+func (this *CollectorSink) Await(n int, timeout time.Duration) error {
+	timer := time.AfterFunc(timeout, func() {
+		this.mutex.Lock()
+		defer this.mutex.Unlock()
+		this.cond.Broadcast()
+	})
+	defer timer.Stop()
+
+	deadline := time.Now().Add(timeout)
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	for len(this.entries) < n {
+		if !time.Now().Before(deadline) {
+			return fmt.Errorf("received %d entries out of %d within %s", len(this.entries), n, timeout)
+		}
+		this.cond.Wait()
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package streamtest
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	streams "github.com/matang28/go-streams"
+)
+
+// SliceSource is an in memory source that sends fixed entries and then stops,
+// the keys it commits are recorded. It can seek while it didn't stop (see streams.SeekableSource).
+type SliceSource struct {
+	name    string
+	entries []streams.Entry
+	mutex   *sync.Mutex
+	next    int
+	commits []string
+	closeCh chan struct{}
+	once    *sync.Once
+}
+
+// NewSliceSource creates a source sending the given entries
+func NewSliceSource(entries ...streams.Entry) *SliceSource {
+	return &SliceSource{
+		name:    fmt.Sprintf("sliceSource-%d", time.Now().UnixNano()),
+		entries: entries,
+		mutex:   &sync.Mutex{},
+		closeCh: make(chan struct{}),
+		once:    &sync.Once{},
+	}
+}
+
+// NewSliceSourceOf creates a source sending the given values, their keys are their index
+func NewSliceSourceOf(values ...interface{}) *SliceSource {
+	entries := make([]streams.Entry, len(values))
+	for idx, value := range values {
+		entries[idx] = streams.Entry{Key: fmt.Sprintf("%d", idx), Value: value}
+	}
+	return NewSliceSource(entries...)
+}
+
+// Named sets the name of the source
+func (this *SliceSource) Named(name string) *SliceSource {
+	this.name = name
+	return this
+}
+
+func (this *SliceSource) Start(channel streams.EntryChannel, errorChannel streams.ErrorChannel) {
+	defer func() {
+		close(channel)
+		errorChannel <- streams.NewEofError(this)
+	}()
+
+	for {
+		this.mutex.Lock()
+		if this.next >= len(this.entries) {
+			this.mutex.Unlock()
+			return
+		}
+		entry := this.entries[this.next]
+		this.next++
+		this.mutex.Unlock()
+
+		select {
+		case <-this.closeCh:
+			return
+		case channel <- entry:
+		}
+	}
+}
+
+func (this *SliceSource) Stop() error {
+	this.once.Do(func() { close(this.closeCh) })
+	return nil
+}
+
+func (this *SliceSource) Ping() error {
+	return nil
+}
+
+func (this *SliceSource) CommitEntry(keys ...string) error {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.commits = append(this.commits, keys...)
+	return nil
+}
+
+// SeekToTimestamp moves to the first entry whose timestamp isn't before the time (the end when there is none)
+func (this *SliceSource) SeekToTimestamp(at time.Time) error {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.next = len(this.entries)
+	for idx := range this.entries {
+		if !this.entries[idx].Timestamp.Before(at) {
+			this.next = idx
+			break
+		}
+	}
+	return nil
+}
+
+// SeekToKey moves to the first entry of the key
+func (this *SliceSource) SeekToKey(key string) error {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	for idx := range this.entries {
+		if this.entries[idx].Key == key {
+			this.next = idx
+			return nil
+		}
+	}
+	return fmt.Errorf("key '%s' not found", key)
+}
+
+func (this *SliceSource) Rewind() error {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.next = 0
+	return nil
+}
+
+// Committed returns the committed keys in the order they were committed
+func (this *SliceSource) Committed() []string {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	return append([]string{}, this.commits...)
+}
+
+func (this *SliceSource) Name() string {
+	return this.name
+}
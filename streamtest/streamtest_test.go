@@ -0,0 +1,84 @@
+package streamtest
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	streams "github.com/matang28/go-streams"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSliceSource_WithCollectorSink(t *testing.T) {
+	source := NewSliceSourceOf(1, 2, 3, 4)
+	sink := NewCollectorSink()
+
+	streams.NewStream(source).
+		Filter(func(entry interface{}) bool { return entry.(int)%2 == 0 }).
+		Sink(sink).
+		Process(streams.NewDirectProcessor(), make(streams.ErrorChannel, 10))
+
+	AssertSinked(t, sink, 2, 4)
+	AssertCommitted(t, source, "1", "3")
+}
+
+func TestCollectorSink_Await(t *testing.T) {
+	sink := NewCollectorSink()
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		_ = sink.Batch(streams.Entry{Key: "1"}, streams.Entry{Key: "2"})
+	}()
+
+	assert.Nil(t, sink.Await(2, time.Second))
+	assert.NotNil(t, sink.Await(3, 20*time.Millisecond))
+}
+
+func TestCollectorSink_FailWith(t *testing.T) {
+	source := NewSliceSourceOf("a", "b")
+	sink := NewCollectorSink()
+	sink.FailWith(fmt.Errorf("unavailable"))
+
+	errs := make(streams.ErrorChannel, 10)
+	streams.NewStream(source).Sink(sink).Process(streams.NewDirectProcessor(), errs)
+
+	AssertSinked(t, sink)
+	AssertCommitted(t, source)
+	assert.NotNil(t, <-errs)
+}
+
+func TestClock_DrivesTimedOperators(t *testing.T) {
+	clock := NewClock(time.Now())
+	debounce := streams.NewDebounce(time.Minute, nil)
+
+	_, _ = debounce.Apply(clock.Entry("1", "a"))
+	_, _ = debounce.Apply(clock.Entry("2", "b"))
+	entries, _ := clock.Tick(debounce)
+	assert.Empty(t, entries)
+
+	clock.Advance(2 * time.Minute)
+	entries, _ = clock.Tick(debounce)
+	assert.Len(t, entries, 1)
+	assert.EqualValues(t, "b", entries[0].Value)
+}
+
+func TestSliceSource_Seek(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	var entries []streams.Entry
+	for idx := 0; idx < 5; idx++ {
+		entries = append(entries, streams.Entry{Key: fmt.Sprint(idx), Value: idx, Timestamp: start.Add(time.Duration(idx) * time.Hour)})
+	}
+	replay := func(seek func(source *SliceSource) error) *CollectorSink {
+		source, sink := NewSliceSource(entries...), NewCollectorSink()
+		assert.Nil(t, seek(source))
+		streams.NewStream(source).Sink(sink).Process(streams.NewDirectProcessor(), make(streams.ErrorChannel, 10))
+		return sink
+	}
+
+	AssertSinked(t, replay(func(source *SliceSource) error { return source.SeekToKey("3") }), 3, 4)
+	AssertSinked(t, replay(func(source *SliceSource) error { return source.SeekToTimestamp(start.Add(90 * time.Minute)) }), 2, 3, 4)
+	AssertSinked(t, replay(func(source *SliceSource) error {
+		_ = source.SeekToKey("4")
+		return source.Rewind()
+	}), 0, 1, 2, 3, 4)
+	assert.NotNil(t, NewSliceSource(entries...).SeekToKey("missing"))
+}
@@ -0,0 +1,145 @@
+package go_streams
+
+import "time"
+
+// RestartMode decides when the engine restarts the source of a stream
+type RestartMode string
+
+const (
+	// RestartNever lets the stream stop when its source fails (the default)
+	RestartNever RestartMode = "never"
+
+	// RestartOnFailure restarts the source when it fails, up to MaxRetries times
+	RestartOnFailure RestartMode = "on-failure"
+
+	// RestartAlways restarts the source whenever it ends, unless the stream is stopped
+	RestartAlways RestartMode = "always"
+)
+
+const defaultMaxBackoff = time.Minute
+
+// RestartPolicy describes how the engine supervises the source of a stream.
+// A source fails when it panics or when Start returns without closing its channel
+// (e.g: after sending a FatalError), restarted sources are started again with Start
+// so sources that are supervised must support being started again after they fail.
+type RestartPolicy struct {
+	Mode RestartMode
+
+	// MaxRetries is the number of restarts allowed by RestartOnFailure, 0 means unlimited
+	MaxRetries int
+
+	// Backoff is the delay before the first restart, it doubles on every restart
+	Backoff time.Duration
+
+	// MaxBackoff caps the delay between restarts (defaults to 1 minute)
+	MaxBackoff time.Duration
+}
+
+func NeverRestart() RestartPolicy {
+	return RestartPolicy{Mode: RestartNever}
+}
+
+func RestartOnFailureWith(maxRetries int, backoff time.Duration) RestartPolicy {
+	return RestartPolicy{Mode: RestartOnFailure, MaxRetries: maxRetries, Backoff: backoff}
+}
+
+func AlwaysRestart(backoff time.Duration) RestartPolicy {
+	return RestartPolicy{Mode: RestartAlways, Backoff: backoff}
+}
+
+// shouldRestart returns true if the source should be restarted for the given attempt (starting at 1)
+func (this RestartPolicy) shouldRestart(failed bool, attempt int) bool {
+	switch this.Mode {
+	case RestartAlways:
+		return true
+	case RestartOnFailure:
+		return failed && (this.MaxRetries == 0 || attempt <= this.MaxRetries)
+	default:
+		return false
+	}
+}
+
+// delay returns the backoff before the given attempt (starting at 1)
+func (this RestartPolicy) delay(attempt int) time.Duration {
+	maxBackoff := this.MaxBackoff
+	if maxBackoff <= 0 {
+		maxBackoff = defaultMaxBackoff
+	}
+
+	delay := this.Backoff
+	for i := 1; i < attempt && delay < maxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > maxBackoff {
+		delay = maxBackoff
+	}
+	return delay
+}
+
+// EventType is the type of an engine event
+type EventType string
+
+const (
+	// SourceFailed is emitted when the source of a stream failed
+	SourceFailed EventType = "source_failed"
+
+	// SourceRestarting is emitted before the source of a stream is restarted
+	SourceRestarting EventType = "source_restarting"
+
+	// SourceGaveUp is emitted when the restart policy doesn't allow more restarts, the stream stops
+	SourceGaveUp EventType = "source_gave_up"
+
+	// StreamStarted is emitted when the engine starts processing a stream
+	StreamStarted EventType = "stream_started"
+
+	// StreamEnded is emitted when a stream ended, its Status tells why (stopped, completed or failed)
+	StreamEnded EventType = "stream_ended"
+
+	// StreamErrored is emitted for every error reported by a stream
+	StreamErrored EventType = "stream_errored"
+
+	// EntriesCommitted is emitted when the keys of a stream were committed to its source
+	EntriesCommitted EventType = "entries_committed"
+
+	// BatchFlushed is emitted when the buffered processor processed a batch of Count entries
+	BatchFlushed EventType = "batch_flushed"
+
+	// CircuitStateChanged is emitted when the circuit breaker of a sink of the stream changed to the Circuit state
+	CircuitStateChanged EventType = "circuit_state_changed"
+)
+
+// isSupervisionEvent tells whether the event is about the restarts of a source
+func isSupervisionEvent(t EventType) bool {
+	return t == SourceFailed || t == SourceRestarting || t == SourceGaveUp
+}
+
+// Event describes something that happened to a stream managed by the engine
+type Event struct {
+	Stream string
+	Type   EventType
+	Time   time.Time
+
+	// Attempt is the restart attempt (starting at 1) of SourceRestarting events
+	Attempt int
+
+	// Delay is the backoff before the restart of SourceRestarting events
+	Delay time.Duration
+
+	// Err is the failure reason, nil for sources that ended without failing
+	Err error
+
+	// Status is the final status of the stream of StreamEnded events
+	Status StreamStatus
+
+	// Keys are the committed keys of EntriesCommitted events
+	Keys []string
+
+	// Count is the number of entries of BatchFlushed events
+	Count int
+
+	// Circuit is the new state of CircuitStateChanged events
+	Circuit CircuitState
+}
+
+// EventHandler is a function that takes events emitted by the engine
+type EventHandler func(event Event)
@@ -0,0 +1,167 @@
+package go_streams
+
+import (
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestEngine_RestartOnFailure_RestartsPanickingSource(t *testing.T) {
+	engine := NewEngine(NewDirectProcessorFactory(), 10*time.Second)
+	source := &flakySource{name: "flaky", failures: 2, entries: 3}
+	sink := NewArraySink()
+	events := &eventRecorder{mutex: &sync.Mutex{}}
+	engine.OnEvent(events.record)
+
+	e := engine.Add(NewStream(source).Sink(sink).Supervise(RestartOnFailureWith(3, time.Millisecond)))
+	assert.Nil(t, e)
+	engine.SetErrorHandler(func(err error) {})
+
+	engine.Start()
+
+	assert.EqualValues(t, []interface{}{0, 1, 2}, sink.Array())
+	assert.EqualValues(t, []EventType{SourceFailed, SourceRestarting, SourceFailed, SourceRestarting}, events.types())
+	assert.EqualValues(t, StreamCompleted, engine.Streams()[0].Status)
+}
+
+func TestEngine_RestartNever_FatalErrorFailsStream(t *testing.T) {
+	engine := NewEngine(NewDirectProcessorFactory(), 10*time.Second)
+	source := &flakySource{name: "fatal", failures: 1, fatal: true}
+	events := &eventRecorder{mutex: &sync.Mutex{}}
+	engine.OnEvent(events.record)
+	errs := make(chan error, 10)
+	engine.SetErrorHandler(func(err error) { errs <- err })
+
+	e := engine.Add(NewStream(source).Sink(NewArraySink()))
+	assert.Nil(t, e)
+
+	engine.Start()
+
+	assert.EqualValues(t, []EventType{SourceFailed, SourceGaveUp}, events.types())
+	var fatal *FatalError
+	assert.True(t, errors.As(events.all()[0].Err, &fatal))
+	assert.True(t, errors.As(<-errs, &fatal))
+	assert.EqualValues(t, StreamFailed, engine.Streams()[0].Status)
+}
+
+func TestEngine_RestartOnFailure_GivesUpAfterMaxRetries(t *testing.T) {
+	engine := NewEngine(NewDirectProcessorFactory(), 10*time.Second)
+	source := &flakySource{name: "broken", failures: 10}
+	events := &eventRecorder{mutex: &sync.Mutex{}}
+	engine.OnEvent(events.record)
+	engine.SetRestartPolicy(RestartOnFailureWith(2, time.Millisecond))
+
+	e := engine.Add(NewStream(source).Sink(NewArraySink()))
+	assert.Nil(t, e)
+
+	engine.Start()
+
+	assert.EqualValues(t, 3, source.started())
+	assert.EqualValues(t, SourceGaveUp, events.types()[len(events.types())-1])
+	assert.EqualValues(t, 2, events.all()[3].Attempt)
+	assert.EqualValues(t, StreamFailed, engine.Streams()[0].Status)
+}
+
+func TestEngine_RestartAlways_RestartsCompletedSource(t *testing.T) {
+	engine := NewEngine(NewDirectProcessorFactory(), 10*time.Second)
+	source := &flakySource{name: "always", entries: 2}
+	sink := NewArraySink()
+
+	e := engine.Add(NewStream(source).Sink(sink).Supervise(AlwaysRestart(time.Millisecond)))
+	assert.Nil(t, e)
+
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		assert.Nil(t, engine.StopStream(source.Name()))
+	}()
+	engine.Start()
+
+	assert.True(t, source.started() > 2)
+	assert.True(t, len(sink.Array()) > 4)
+	assert.EqualValues(t, StreamStopped, engine.Streams()[0].Status)
+}
+
+func TestRestartPolicy_Delay(t *testing.T) {
+	policy := RestartPolicy{Mode: RestartAlways, Backoff: time.Second, MaxBackoff: 5 * time.Second}
+	assert.EqualValues(t, time.Second, policy.delay(1))
+	assert.EqualValues(t, 2*time.Second, policy.delay(2))
+	assert.EqualValues(t, 4*time.Second, policy.delay(3))
+	assert.EqualValues(t, 5*time.Second, policy.delay(4))
+
+	assert.False(t, NeverRestart().shouldRestart(true, 1))
+	assert.True(t, RestartOnFailureWith(0, time.Second).shouldRestart(true, 100))
+	assert.False(t, RestartOnFailureWith(2, time.Second).shouldRestart(false, 1))
+	assert.False(t, RestartOnFailureWith(2, time.Second).shouldRestart(true, 3))
+}
+
+// flakySource fails the first 'failures' starts (by panicking or sending a fatal error)
+// and then sends 'entries' entries and stops.
+type flakySource struct {
+	name     string
+	failures int
+	fatal    bool
+	entries  int
+
+	mutex  sync.Mutex
+	starts int
+}
+
+func (this *flakySource) Start(channel EntryChannel, errorChannel ErrorChannel) {
+	this.mutex.Lock()
+	this.starts++
+	starts := this.starts
+	this.mutex.Unlock()
+
+	if starts <= this.failures {
+		if this.fatal {
+			errorChannel <- NewFatalError(fmt.Errorf("connection lost"))
+			return
+		}
+		panic("boom")
+	}
+
+	for i := 0; i < this.entries; i++ {
+		channel <- Entry{Key: fmt.Sprintf("%d", i), Value: i}
+	}
+	close(channel)
+	errorChannel <- NewEofError(this)
+}
+
+func (this *flakySource) started() int {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	return this.starts
+}
+
+func (this *flakySource) Ping() error                      { return nil }
+func (this *flakySource) Stop() error                      { return nil }
+func (this *flakySource) CommitEntry(keys ...string) error { return nil }
+func (this *flakySource) Name() string                     { return this.name }
+
+type eventRecorder struct {
+	mutex  *sync.Mutex
+	events []Event
+}
+
+func (this *eventRecorder) record(event Event) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.events = append(this.events, event)
+}
+
+func (this *eventRecorder) all() []Event {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	return append([]Event{}, this.events...)
+}
+
+func (this *eventRecorder) types() []EventType {
+	var out []EventType
+	for _, e := range this.all() {
+		out = append(out, e.Type)
+	}
+	return out
+}
@@ -0,0 +1,158 @@
+package go_streams
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sourceBinder is implemented by the operators that stop the source of their stream (see bindSinks)
+type sourceBinder interface {
+	bindSource(source Source)
+}
+
+// take lets entries pass until its condition is hit, then it stops the source and drops the entries
+// the source sent meanwhile. Once the source stopped the stream completes.
+type take struct {
+	mutex *sync.Mutex
+
+	// pass tells whether the entry is kept and whether it is the last one, it is called with the mutex held
+	pass    func(entry Entry) (keep bool, last bool)
+	source  Source
+	stopped bool
+}
+
+func newTake(pass func(entry Entry) (bool, bool)) *take {
+	return &take{mutex: &sync.Mutex{}, pass: pass}
+}
+
+// NewTake creates an operator that lets the first n entries pass and stops the source
+func NewTake(n int) Operator {
+	taken := 0
+	return newTake(func(Entry) (bool, bool) {
+		if taken >= n {
+			return false, true
+		}
+		taken++
+		return true, taken == n
+	})
+}
+
+// NewTakeWhile creates an operator that lets entries pass while the function keeps them,
+// the source is stopped at the first entry it didn't keep (the entry is dropped).
+func NewTakeWhile(fn FilterFunc) Operator {
+	return newTake(func(entry Entry) (bool, bool) {
+		return fn(entry.Value), false
+	})
+}
+
+// NewTakeUntil creates an operator that lets entries pass until the context is done, then it stops the source
+func NewTakeUntil(ctx context.Context) TimedOperator {
+	return &takeUntil{
+		take:    newTake(func(Entry) (bool, bool) { return ctx.Err() == nil, false }),
+		expired: func(time.Time) bool { return ctx.Err() != nil },
+	}
+}
+
+// NewTakeFor creates an operator that lets entries pass for the duration since the stream started, then it stops the source
+func NewTakeFor(d time.Duration) TimedOperator {
+	var deadline time.Time
+	started := func(now time.Time) time.Time {
+		if deadline.IsZero() {
+			deadline = now.Add(d)
+		}
+		return deadline
+	}
+	return &takeUntil{
+		take:    newTake(func(Entry) (bool, bool) { return time.Now().Before(started(time.Now())), false }),
+		expired: func(now time.Time) bool { return !now.Before(started(now)) },
+		start:   func() { started(time.Now()) },
+	}
+}
+
+func (this *take) bindSource(source Source) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.source = source
+}
+
+func (this *take) Apply(entry Entry) ([]Entry, error) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	if this.stopped {
+		return nil, nil
+	}
+	keep, last := this.pass(entry)
+	if !keep || last {
+		this.stop()
+	}
+	if !keep {
+		return nil, nil
+	}
+	return []Entry{entry}, nil
+}
+
+// stop stops the source once, in the background as sources may wait for their entries to be taken.
+// The caller holds the mutex.
+func (this *take) stop() {
+	if this.stopped {
+		return
+	}
+	this.stopped = true
+	if this.source != nil {
+		go this.source.Stop()
+	}
+}
+
+// takeUntil is a take that also stops the source on the ticks of the processors, without waiting for an entry
+type takeUntil struct {
+	*take
+	expired func(now time.Time) bool
+
+	// start starts the clock of the operator once the stream started (if any)
+	start func()
+}
+
+func (this *takeUntil) bindSource(source Source) {
+	this.take.bindSource(source)
+	if this.start != nil {
+		this.mutex.Lock()
+		defer this.mutex.Unlock()
+		this.start()
+	}
+}
+
+func (this *takeUntil) Tick(now time.Time) ([]Entry, error) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	if this.expired(now) {
+		this.stop()
+	}
+	return nil, nil
+}
+
+func (this *takeUntil) Drain() ([]Entry, error) {
+	return nil, nil
+}
+
+// skip drops the first n entries
+type skip struct {
+	mutex   *sync.Mutex
+	n       int
+	skipped int
+}
+
+// NewSkip creates an operator that drops the first n entries
+func NewSkip(n int) Operator {
+	return &skip{mutex: &sync.Mutex{}, n: n}
+}
+
+func (this *skip) Apply(entry Entry) ([]Entry, error) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	if this.skipped < this.n {
+		this.skipped++
+		return nil, nil
+	}
+	return []Entry{entry}, nil
+}
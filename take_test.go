@@ -0,0 +1,45 @@
+package go_streams
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStream_Take_StopsTheSource(t *testing.T) {
+	sink := NewArraySink()
+	NewStream(NewSequentialIntegerSource(0, 0)).Skip(2).Take(3).Sink(sink).
+		Process(NewDirectProcessor(), make(ErrorChannel, 10))
+	assert.EqualValues(t, []interface{}{2, 3, 4}, sink.array)
+}
+
+func TestStream_TakeWhile(t *testing.T) {
+	sink := NewArraySink()
+	NewStream(NewSequentialIntegerSource(0, 0)).TakeWhile(func(value interface{}) bool {
+		return value.(int) < 4
+	}).Sink(sink).Process(NewBufferedProcessor(3, time.Second), make(ErrorChannel, 10))
+	assert.EqualValues(t, []interface{}{0, 1, 2, 3}, sink.array)
+}
+
+func TestStream_TakeUntil(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	sink := NewArraySink()
+	NewStream(NewSequentialIntegerSource(0, time.Millisecond)).TakeUntil(ctx).Sink(sink).
+		Process(NewDirectProcessor(), make(ErrorChannel, 10))
+	assert.Empty(t, sink.array)
+}
+
+func TestStream_TakeFor(t *testing.T) {
+	engine := NewEngine(NewDirectProcessorFactory(), 10*time.Second)
+	sink := NewArraySink()
+	assert.Nil(t, engine.Add(NewStream(NewSequentialIntegerSource(0, time.Millisecond)).TakeFor(30*time.Millisecond).Sink(sink)))
+
+	started := time.Now()
+	engine.Start()
+	assert.True(t, time.Since(started) < time.Second)
+	assert.NotEmpty(t, sink.array)
+	assert.EqualValues(t, 0, sink.array[0])
+}
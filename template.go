@@ -0,0 +1,244 @@
+package go_streams
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// TemplateInstanceParam is the parameter holding the name of the instance, e.g: to name the sources of the instances
+const TemplateInstanceParam = "instance"
+
+// placeholder matches the ${name} placeholders of the template parameters
+var placeholder = regexp.MustCompile(`\$\{(\w+)\}`)
+
+// TemplateErrorHandler is called with the errors of the streams instantiated from a template
+type TemplateErrorHandler func(instance string, err error)
+
+// StreamTemplate declares the shape of a stream once and instantiates streams that differ by their parameters
+// (e.g: one stream per tenant). The string parameters of the components may hold ${name} placeholders, a parameter
+// that is a single placeholder takes the value as is (e.g: a number) and ${instance} is the name of the instance.
+// The filters and maps of the registry are shared by the instances, each instance has its own source, operators
+// and sinks (they may hold state) and its own metrics. Instances added to an engine with limits share its worker pool.
+type StreamTemplate struct {
+	definition   StreamDefinition
+	registry     *Registry
+	errorHandler TemplateErrorHandler
+
+	mutex     *sync.Mutex
+	instances map[string]*Metrics
+}
+
+// NewStreamTemplate checks that the components of the definition are registered, a nil registry uses the
+// built-in components only
+func NewStreamTemplate(definition StreamDefinition, registry *Registry) (*StreamTemplate, error) {
+	if registry == nil {
+		registry = NewRegistry()
+	}
+	if err := definition.validate(registry); err != nil {
+		return nil, err
+	}
+	return &StreamTemplate{definition: definition, registry: registry, mutex: &sync.Mutex{}, instances: make(map[string]*Metrics)}, nil
+}
+
+// OnError sets the error handler of the instances created afterwards
+func (this *StreamTemplate) OnError(handler TemplateErrorHandler) *StreamTemplate {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.errorHandler = handler
+	return this
+}
+
+// Instantiate creates the stream of an instance, every placeholder of the template should have a parameter
+func (this *StreamTemplate) Instantiate(instance string, params Params) (Stream, error) {
+	values := make(Params, len(params)+1)
+	for name, value := range params {
+		values[name] = value
+	}
+	values[TemplateInstanceParam] = instance
+
+	definition, err := this.definition.expand(values)
+	if err != nil {
+		return nil, fmt.Errorf("instance '%s': %s", instance, err.Error())
+	}
+
+	this.mutex.Lock()
+	if _, found := this.instances[instance]; found {
+		this.mutex.Unlock()
+		return nil, fmt.Errorf("instance '%s' already exists", instance)
+	}
+	metrics := NewMetrics()
+	this.instances[instance] = metrics
+	handler := this.errorHandler
+	this.mutex.Unlock()
+
+	stream, err := definition.build(this.registry, SinkMetrics(metrics))
+	if err != nil {
+		this.mutex.Lock()
+		delete(this.instances, instance)
+		this.mutex.Unlock()
+		return nil, fmt.Errorf("instance '%s': %s", instance, err.Error())
+	}
+	if handler != nil {
+		stream = stream.OnError(func(err error) { handler(instance, err) })
+	}
+	return stream, nil
+}
+
+// Metrics returns the metrics of the sinks of an instance
+func (this *StreamTemplate) Metrics(instance string) (*Metrics, bool) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	metrics, found := this.instances[instance]
+	return metrics, found
+}
+
+// Instances returns the names of the instances sorted
+func (this *StreamTemplate) Instances() []string {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	instances := make([]string, 0, len(this.instances))
+	for instance := range this.instances {
+		instances = append(instances, instance)
+	}
+	sort.Strings(instances)
+	return instances
+}
+
+// validate checks that the components of the definition are registered
+func (this *StreamDefinition) validate(registry *Registry) error {
+	if _, found := registry.source(this.Source.Type); !found {
+		return fmt.Errorf("unknown source: '%s'", this.Source.Type)
+	}
+	for idx, stage := range this.Pipeline {
+		kind, name, found := "", "", true
+		switch {
+		case stage.Filter != "":
+			kind, name = "filter", stage.Filter
+			_, found = registry.filter(name)
+		case stage.Map != "":
+			kind, name = "map", stage.Map
+			_, found = registry.mapFunc(name)
+		case stage.Operator != nil:
+			kind, name = "operator", stage.Operator.Type
+			_, found = registry.operator(name)
+		case stage.Sink != nil:
+			kind, name = "sink", stage.Sink.Type
+			_, found = registry.sink(name)
+		}
+		if !found {
+			return fmt.Errorf("stage #%d: unknown %s: '%s'", idx, kind, name)
+		}
+	}
+	if this.DeadLetter != nil {
+		if _, found := registry.sink(this.DeadLetter.Type); !found {
+			return fmt.Errorf("unknown dead letter sink: '%s'", this.DeadLetter.Type)
+		}
+	}
+	return nil
+}
+
+// expand returns a copy of the definition whose component parameters have their placeholders replaced
+func (this StreamDefinition) expand(values Params) (StreamDefinition, error) {
+	var err error
+	if this.Source, err = this.Source.expand(values); err != nil {
+		return this, err
+	}
+
+	pipeline := make([]StageConfig, len(this.Pipeline))
+	for idx, stage := range this.Pipeline {
+		pipeline[idx] = stage
+		if stage.Operator != nil {
+			operator, err := stage.Operator.expand(values)
+			if err != nil {
+				return this, err
+			}
+			pipeline[idx].Operator = &operator
+		}
+		if stage.Sink != nil {
+			sink, err := stage.Sink.expand(values)
+			if err != nil {
+				return this, err
+			}
+			pipeline[idx].Sink = &sink
+		}
+	}
+	this.Pipeline = pipeline
+
+	if this.DeadLetter != nil {
+		deadLetter, err := this.DeadLetter.expand(values)
+		if err != nil {
+			return this, err
+		}
+		this.DeadLetter = &deadLetter
+	}
+	return this, nil
+}
+
+func (this ComponentConfig) expand(values Params) (ComponentConfig, error) {
+	params, err := expandValue(map[string]interface{}(this.Params), values)
+	if err != nil {
+		return this, fmt.Errorf("%s: %s", this.Type, err.Error())
+	}
+	if this.Params != nil {
+		this.Params = Params(params.(map[string]interface{}))
+	}
+	return this, nil
+}
+
+// expandValue replaces the placeholders of the strings held by the value (e.g: in nested maps and lists)
+func expandValue(value interface{}, values Params) (interface{}, error) {
+	switch v := value.(type) {
+	case string:
+		return expandString(v, values)
+	case map[string]interface{}:
+		expanded := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			e, err := expandValue(item, values)
+			if err != nil {
+				return nil, err
+			}
+			expanded[key] = e
+		}
+		return expanded, nil
+	case []interface{}:
+		expanded := make([]interface{}, len(v))
+		for idx, item := range v {
+			e, err := expandValue(item, values)
+			if err != nil {
+				return nil, err
+			}
+			expanded[idx] = e
+		}
+		return expanded, nil
+	}
+	return value, nil
+}
+
+func expandString(s string, values Params) (interface{}, error) {
+	// a single placeholder keeps the type of its value
+	if match := placeholder.FindStringSubmatch(s); match != nil && match[0] == s {
+		value, found := values[match[1]]
+		if !found {
+			return nil, fmt.Errorf("template parameter '%s' isn't set", match[1])
+		}
+		return value, nil
+	}
+
+	var missing []string
+	expanded := placeholder.ReplaceAllStringFunc(s, func(match string) string {
+		name := placeholder.FindStringSubmatch(match)[1]
+		value, found := values[name]
+		if !found {
+			missing = append(missing, name)
+			return match
+		}
+		return fmt.Sprintf("%v", value)
+	})
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("template parameters '%s' aren't set", strings.Join(missing, "', '"))
+	}
+	return expanded, nil
+}
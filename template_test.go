@@ -0,0 +1,102 @@
+package go_streams
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func tenantRegistry(sinks map[string]*ArraySink, mutex *sync.Mutex) *Registry {
+	registry := NewRegistry()
+	registry.RegisterFilter("even", func(entry interface{}) bool { return entry.(int)%2 == 0 })
+	registry.RegisterSink("tenant", func(params Params) (Sink, error) {
+		target, err := params.String("target", "")
+		if err != nil {
+			return nil, err
+		}
+		mutex.Lock()
+		defer mutex.Unlock()
+		sinks[target] = NewArraySink()
+		return sinks[target], nil
+	})
+	return registry
+}
+
+func tenantDefinition() StreamDefinition {
+	return StreamDefinition{
+		Source: ComponentConfig{Type: "sequential", Params: Params{"limit": "${limit}"}},
+		Pipeline: []StageConfig{
+			{Filter: "even"},
+			{Sink: &ComponentConfig{Type: "tenant", Params: Params{"target": "orders-${instance}"}}},
+		},
+	}
+}
+
+func TestStreamTemplate_Instantiate(t *testing.T) {
+	sinks, mutex := make(map[string]*ArraySink), &sync.Mutex{}
+	template, err := NewStreamTemplate(tenantDefinition(), tenantRegistry(sinks, mutex))
+	assert.Nil(t, err)
+
+	var errs []string
+	template.OnError(func(instance string, err error) {
+		errs = append(errs, fmt.Sprintf("%s: %s", instance, err.Error()))
+	})
+
+	engine := NewEngine(NewDirectProcessorFactory(), 10*time.Second)
+	for tenant, limit := range map[string]int{"a": 4, "b": 6} {
+		stream, err := template.Instantiate(tenant, Params{"limit": limit})
+		assert.Nil(t, err)
+		assert.Nil(t, engine.Add(stream))
+		stream.GetErrorHandler()(fmt.Errorf("boom"))
+	}
+	engine.Start()
+
+	assert.EqualValues(t, []interface{}{0, 2, 4}, sinks["orders-a"].Array())
+	assert.EqualValues(t, []interface{}{0, 2, 4, 6}, sinks["orders-b"].Array())
+	assert.EqualValues(t, []string{"a", "b"}, template.Instances())
+	metrics, found := template.Metrics("b")
+	assert.True(t, found)
+	assert.EqualValues(t, 4, metrics.Snapshot().Entries)
+	assert.ElementsMatch(t, []string{"a: boom", "b: boom"}, errs)
+}
+
+func TestStreamTemplate_Errors(t *testing.T) {
+	sinks, mutex := make(map[string]*ArraySink), &sync.Mutex{}
+	registry := tenantRegistry(sinks, mutex)
+
+	definition := tenantDefinition()
+	definition.Pipeline = append(definition.Pipeline, StageConfig{Map: "missing"})
+	_, err := NewStreamTemplate(definition, registry)
+	assert.EqualError(t, err, "stage #2: unknown map: 'missing'")
+
+	template, err := NewStreamTemplate(tenantDefinition(), registry)
+	assert.Nil(t, err)
+	_, err = template.Instantiate("a", nil)
+	assert.EqualError(t, err, "instance 'a': sequential: template parameter 'limit' isn't set")
+
+	_, err = template.Instantiate("a", Params{"limit": 1})
+	assert.Nil(t, err)
+	_, err = template.Instantiate("a", Params{"limit": 1})
+	assert.EqualError(t, err, "instance 'a' already exists")
+}
+
+func TestExpandValue(t *testing.T) {
+	values := Params{"tenant": "acme", "n": 3}
+	expanded, err := expandValue(map[string]interface{}{
+		"topic": "orders.${tenant}.${n}",
+		"n":     "${n}",
+		"tags":  []interface{}{"${tenant}", 1},
+	}, values)
+	assert.Nil(t, err)
+	assert.EqualValues(t, map[string]interface{}{
+		"topic": "orders.acme.3",
+		"n":     3,
+		"tags":  []interface{}{"acme", 1},
+	}, expanded)
+
+	_, err = expandValue("${a}-${b}", values)
+	assert.EqualError(t, err, "template parameters 'a', 'b' aren't set")
+}
@@ -0,0 +1,159 @@
+package go_streams
+
+import (
+	"sync"
+	"time"
+)
+
+// maxIdleBuckets is the number of per key buckets kept before idle ones are evicted
+const maxIdleBuckets = 1024
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// throttle is a token bucket operator, it blocks the stream until a token is available
+// so it backpressures the source instead of dropping entries.
+type throttle struct {
+	capacity float64
+	rate     float64 // tokens per second
+	key      KeyFunc
+	buckets  map[string]*tokenBucket
+	mutex    *sync.Mutex
+}
+
+// NewThrottle creates an operator that lets at most n entries pass per interval (with bursts up to n),
+// when key is set each key gets its own bucket.
+func NewThrottle(n int, per time.Duration, key KeyFunc) Operator {
+	if n <= 0 || per <= 0 {
+		panic("throttle requires a positive rate")
+	}
+	return &throttle{
+		capacity: float64(n),
+		rate:     float64(n) / per.Seconds(),
+		key:      key,
+		buckets:  make(map[string]*tokenBucket),
+		mutex:    &sync.Mutex{},
+	}
+}
+
+func (this *throttle) Apply(entry Entry) ([]Entry, error) {
+	key := ""
+	if this.key != nil {
+		key = this.key(entry.Value)
+	}
+
+	if wait := this.take(key, time.Now()); wait > 0 {
+		time.Sleep(wait)
+	}
+	return []Entry{entry}, nil
+}
+
+// take takes a token from the bucket of the key and returns how long to wait before it is available
+func (this *throttle) take(key string, now time.Time) time.Duration {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	bucket, found := this.buckets[key]
+	if !found {
+		this.evictIdle(now)
+		bucket = &tokenBucket{tokens: this.capacity, last: now}
+		this.buckets[key] = bucket
+	}
+
+	bucket.tokens += now.Sub(bucket.last).Seconds() * this.rate
+	if bucket.tokens > this.capacity {
+		bucket.tokens = this.capacity
+	}
+	bucket.last = now
+	bucket.tokens--
+
+	if bucket.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-bucket.tokens / this.rate * float64(time.Second))
+}
+
+// evictIdle removes the buckets that are full again, they behave like new ones
+func (this *throttle) evictIdle(now time.Time) {
+	if len(this.buckets) < maxIdleBuckets {
+		return
+	}
+	for key, bucket := range this.buckets {
+		if bucket.tokens+now.Sub(bucket.last).Seconds()*this.rate >= this.capacity {
+			delete(this.buckets, key)
+		}
+	}
+}
+
+type pendingEntry struct {
+	entry Entry
+	at    time.Time
+}
+
+// debounce holds back entries until no other entry (with the same key) came for the given duration,
+// only the latest entry of a burst is emitted.
+type debounce struct {
+	duration time.Duration
+	key      KeyFunc
+	pending  map[string]*pendingEntry
+	order    []string
+	mutex    *sync.Mutex
+}
+
+// NewDebounce creates an operator that collapses bursts of entries into their latest entry,
+// the entry is emitted once no other entry came for d. When key is set bursts are collapsed per key.
+func NewDebounce(d time.Duration, key KeyFunc) TimedOperator {
+	return &debounce{
+		duration: d,
+		key:      key,
+		pending:  make(map[string]*pendingEntry),
+		mutex:    &sync.Mutex{},
+	}
+}
+
+func (this *debounce) Apply(entry Entry) ([]Entry, error) {
+	key := ""
+	if this.key != nil {
+		key = this.key(entry.Value)
+	}
+
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	if _, found := this.pending[key]; !found {
+		this.order = append(this.order, key)
+	}
+	this.pending[key] = &pendingEntry{entry: entry, at: time.Now()}
+	return nil, nil
+}
+
+func (this *debounce) Tick(now time.Time) ([]Entry, error) {
+	return this.emit(func(pending *pendingEntry) bool {
+		return now.Sub(pending.at) >= this.duration
+	}), nil
+}
+
+func (this *debounce) Drain() ([]Entry, error) {
+	return this.emit(func(*pendingEntry) bool { return true }), nil
+}
+
+// emit returns the pending entries that are due in the order their bursts started
+func (this *debounce) emit(due func(*pendingEntry) bool) []Entry {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	var entries []Entry
+	order := this.order[:0]
+	for _, key := range this.order {
+		pending := this.pending[key]
+		if due(pending) {
+			entries = append(entries, pending.entry)
+			delete(this.pending, key)
+		} else {
+			order = append(order, key)
+		}
+	}
+	this.order = order
+	return entries
+}
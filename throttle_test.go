@@ -0,0 +1,77 @@
+package go_streams
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestThrottle_LimitsRate(t *testing.T) {
+	errs := make(ErrorChannel, 100)
+	source := NewSequentialIntegerSource(10, 0)
+	sink := NewArraySink()
+	stream := NewStream(source).Throttle(5, 100*time.Millisecond).Sink(sink)
+
+	started := time.Now()
+	stream.Process(NewDirectProcessor(), errs)
+
+	assert.Len(t, sink.Array(), 11)
+	// the first 5 entries are a burst, the others wait for their token
+	assert.True(t, time.Since(started) >= 100*time.Millisecond)
+}
+
+func TestThrottle_PerKeyBuckets(t *testing.T) {
+	op := NewThrottle(1, time.Second, func(value interface{}) string {
+		return fmt.Sprintf("%v", value)
+	}).(*throttle)
+
+	now := time.Now()
+	assert.EqualValues(t, 0, op.take("a", now))
+	assert.EqualValues(t, 0, op.take("b", now))
+	assert.EqualValues(t, time.Second, op.take("a", now))
+	assert.EqualValues(t, 0, op.take("a", now.Add(3*time.Second)))
+}
+
+func TestDebounce_EmitsLatestEntryOfBurst(t *testing.T) {
+	op := NewDebounce(50*time.Millisecond, func(value interface{}) string {
+		return value.(string)[:1]
+	})
+
+	for _, value := range []string{"a1", "b1", "a2", "a3"} {
+		entries, err := op.Apply(Entry{Key: value, Value: value})
+		assert.Nil(t, err)
+		assert.Empty(t, entries)
+	}
+	ticked, err := op.Tick(time.Now())
+	assert.Nil(t, err)
+	assert.Empty(t, ticked)
+
+	entries, _ := op.Tick(time.Now().Add(time.Second))
+	assert.Len(t, entries, 2)
+	assert.EqualValues(t, "a3", entries[0].Value)
+	assert.EqualValues(t, "b1", entries[1].Value)
+	drained, _ := op.Drain()
+	assert.Empty(t, drained)
+}
+
+func TestDebounce_DirectProcessor(t *testing.T) {
+	errs := make(ErrorChannel, 100)
+	source := NewSequentialIntegerSource(10, 0)
+	sink := NewArraySink()
+	stream := NewStream(source).Debounce(time.Hour).Sink(sink)
+	stream.Process(NewDirectProcessor(), errs)
+
+	// the burst is drained once the source ends
+	assert.EqualValues(t, []interface{}{10}, sink.Array())
+}
+
+func TestDebounce_BufferedProcessor(t *testing.T) {
+	errs := make(ErrorChannel, 100)
+	source := NewSequentialIntegerSource(10, 0)
+	sink := NewArraySink()
+	stream := NewStream(source).Debounce(time.Hour).Sink(sink)
+	stream.Process(NewBufferedProcessor(4, time.Second), errs)
+
+	assert.EqualValues(t, []interface{}{10}, sink.Array())
+}
@@ -0,0 +1,135 @@
+package go_streams
+
+import (
+	"errors"
+	"time"
+)
+
+// TimeoutFilter aborts the filter calls that take longer than the timeout, the entry is handled like one
+// whose filter panicked (e.g: it goes to the dead letter sink). Go can't interrupt a call so the aborted call
+// keeps running in the background and its result is dropped.
+func TimeoutFilter(fn FilterFunc, timeout time.Duration) FilterFunc {
+	return func(entry interface{}) bool {
+		var keep bool
+		if err := callWithTimeout(FilterStage, timeout, func() { keep = fn(entry) }); err != nil {
+			panic(err)
+		}
+		return keep
+	}
+}
+
+// TimeoutMap aborts the map calls that take longer than the timeout, the entry is handled like one
+// whose map panicked (e.g: it goes to the dead letter sink). The aborted call keeps running in the background.
+func TimeoutMap(fn MapFunc, timeout time.Duration) MapFunc {
+	return func(entry interface{}) interface{} {
+		var value interface{}
+		if err := callWithTimeout(MapStage, timeout, func() { value = fn(entry) }); err != nil {
+			panic(err)
+		}
+		return value
+	}
+}
+
+// TimeoutSink fails the writes of its sink that take longer than the timeout with a TimeoutError,
+// the entries go to the dead letter sink of the stream when it has one. The aborted write keeps running
+// in the background so the sink may still write the entries. AckingSinks shouldn't be wrapped,
+// use Stream.WithTimeout for them.
+type TimeoutSink struct {
+	sink    Sink
+	timeout time.Duration
+}
+
+func NewTimeoutSink(sink Sink, timeout time.Duration) *TimeoutSink {
+	return &TimeoutSink{sink: sink, timeout: timeout}
+}
+
+func (this *TimeoutSink) Single(entry Entry) error {
+	var err error
+	if timeoutErr := callWithTimeout(SinkStage, this.timeout, func() { err = this.sink.Single(entry) }); timeoutErr != nil {
+		return timeoutErr
+	}
+	return err
+}
+
+// Batch writes a copy of the entries, the processors reuse their batches while an aborted write may still read them
+func (this *TimeoutSink) Batch(entry ...Entry) error {
+	entries := append(make([]Entry, 0, len(entry)), entry...)
+	var err error
+	if timeoutErr := callWithTimeout(SinkStage, this.timeout, func() { err = this.sink.Batch(entries...) }); timeoutErr != nil {
+		return timeoutErr
+	}
+	return err
+}
+
+func (this *TimeoutSink) Ping() error {
+	return this.sink.Ping()
+}
+
+// Flush flushes the sink when it implements Flusher
+func (this *TimeoutSink) Flush() error {
+	if flusher, ok := this.sink.(Flusher); ok {
+		return flusher.Flush()
+	}
+	return nil
+}
+
+// Close closes the sink when it implements Closer
+func (this *TimeoutSink) Close() error {
+	if closer, ok := this.sink.(Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// withTimeout applies the timeout of the stream to a bound handler, operators aren't timed since some
+// of them wait on purpose (e.g: Throttle)
+func withTimeout(handler interface{}, timeout time.Duration) interface{} {
+	if timeout <= 0 {
+		return handler
+	}
+	switch h := handler.(type) {
+	case FilterFunc:
+		return TimeoutFilter(h, timeout)
+	case MapFunc:
+		return TimeoutMap(h, timeout)
+	case Operator:
+		return h
+	case Sink:
+		return NewTimeoutSink(h, timeout)
+	}
+	return handler
+}
+
+// callWithTimeout runs the function and waits for it up to the timeout, its panics are raised again by the caller
+func callWithTimeout(stage Stage, timeout time.Duration, fn func()) error {
+	done := make(chan interface{}, 1)
+	go func() {
+		panicked := true
+		defer func() {
+			if panicked {
+				done <- recover()
+			}
+		}()
+		fn()
+		panicked = false
+		done <- nil
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case p := <-done:
+		if p != nil {
+			panic(p)
+		}
+		return nil
+	case <-timer.C:
+		return NewTimeoutError(stage, timeout)
+	}
+}
+
+// isTimeout tells whether the error is a timed out call
+func isTimeout(err error) bool {
+	var te *TimeoutError
+	return errors.As(err, &te)
+}
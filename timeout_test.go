@@ -0,0 +1,115 @@
+package go_streams
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStream_WithTimeout_DeadLettersHungMaps(t *testing.T) {
+	errs := make(ErrorChannel, 100)
+	release := make(chan struct{})
+	defer close(release)
+	sink := NewArraySink()
+	var letters []Entry
+	stream := NewStream(NewSequentialIntegerSource(5, time.Millisecond)).Map(func(entry interface{}) interface{} {
+		if entry.(int) == 2 {
+			<-release
+		}
+		return entry
+	}).Sink(sink).WithTimeout(20 * time.Millisecond).DeadLetter(NewCallbackSink(func(entries ...Entry) error {
+		letters = append(letters, entries...)
+		return nil
+	}))
+	stream.Process(NewDirectProcessor(), errs)
+
+	assert.EqualValues(t, []interface{}{0, 1, 3, 4, 5}, sink.Array())
+	assert.Len(t, letters, 1)
+	assert.EqualValues(t, 2, letters[0].Value)
+	assert.EqualValues(t, MapStage, letters[0].Metadata[MetadataDeadLetterStage])
+	assert.EqualValues(t, "map step timed out after 20ms", letters[0].Metadata[MetadataDeadLetterError])
+
+	te := reportedTimeout(errs)
+	assert.NotNil(t, te)
+	assert.EqualValues(t, MapStage, te.Stage)
+}
+
+func TestStream_WithTimeout_ReportsHungSinks(t *testing.T) {
+	errs := make(ErrorChannel, 100)
+	release := make(chan struct{})
+	defer close(release)
+	stream := NewStream(NewSequentialIntegerSource(3, time.Millisecond)).Sink(NewCallbackSink(func(entries ...Entry) error {
+		<-release
+		return nil
+	})).WithTimeout(10 * time.Millisecond)
+	stream.Process(NewBufferedProcessor(10, 50*time.Millisecond), errs)
+
+	te := reportedTimeout(errs)
+	assert.NotNil(t, te)
+	assert.EqualValues(t, SinkStage, te.Stage)
+	assert.EqualValues(t, 10*time.Millisecond, te.Timeout)
+}
+
+// reportedTimeout returns the first timeout of the reported errors
+func reportedTimeout(errs ErrorChannel) *TimeoutError {
+	for {
+		select {
+		case err := <-errs:
+			var te *TimeoutError
+			if errors.As(err, &te) {
+				return te
+			}
+		default:
+			return nil
+		}
+	}
+}
+
+func TestTimeoutSink(t *testing.T) {
+	sink := NewTimeoutSink(NewCallbackSink(func(entries ...Entry) error {
+		if entries[0].Key == "slow" {
+			time.Sleep(100 * time.Millisecond)
+		}
+		if entries[0].Key == "failing" {
+			return fmt.Errorf("sink is down")
+		}
+		return nil
+	}), 20*time.Millisecond)
+
+	assert.Nil(t, sink.Single(Entry{Key: "fast"}))
+	assert.EqualError(t, sink.Single(Entry{Key: "failing"}), "sink is down")
+	assert.IsType(t, &TimeoutError{}, sink.Batch(Entry{Key: "slow"}))
+}
+
+func TestTimeoutSink_AbortedBatchKeepsItsEntries(t *testing.T) {
+	written := make(chan []interface{}, 1)
+	sink := NewTimeoutSink(NewCallbackSink(func(entries ...Entry) error {
+		time.Sleep(50 * time.Millisecond)
+		var values []interface{}
+		for _, entry := range entries {
+			values = append(values, entry.Value)
+		}
+		written <- values
+		return nil
+	}), 10*time.Millisecond)
+
+	batch := []Entry{{Key: "1", Value: 1}, {Key: "2", Value: 2}}
+	assert.IsType(t, &TimeoutError{}, sink.Batch(batch...))
+	// the processor reuses the batch while the aborted write is still running
+	batch[0], batch[1] = Entry{Key: "3", Value: 3}, Entry{Key: "4", Value: 4}
+	assert.EqualValues(t, []interface{}{1, 2}, <-written)
+}
+
+func TestTimeoutMap_RaisesPanics(t *testing.T) {
+	fn := TimeoutMap(func(entry interface{}) interface{} {
+		panic("demo")
+	}, time.Second)
+
+	_, err := recoverMap(fn, Entry{Key: "1"})
+	var pe *PanicError
+	assert.True(t, errors.As(err, &pe))
+	assert.EqualValues(t, "demo", pe.Value)
+}
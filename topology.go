@@ -0,0 +1,444 @@
+package go_streams
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Topology is a DAG of named nodes (sources, filters, maps, operators and sinks) connected explicitly,
+// e.g: a diamond where a node branches out and both branches are merged into the same sink:
+//
+//	topology := NewTopology().
+//	    Source("orders", source).
+//	    Map("parse", parse, "orders").
+//	    Filter("paid", isPaid, "parse").
+//	    Map("refunds", toRefund, "parse").
+//	    Sink("ledger", ledger, "paid", "refunds")
+//
+// Build validates the topology and compiles it into one stream per source: the path of nodes with a single input
+// and a single output becomes the handlers of the stream as with the fluent API, the rest of the DAG is run by an
+// operator that passes every entry to the outputs of a node one after the other. An entry is committed once it was
+// written by one of the sinks it reached (or when all its branches dropped it). Nodes merging several sources are called
+// by their streams concurrently, and their sinks are closed once all the streams ended.
+type Topology struct {
+	nodes  []*topologyNode
+	byName map[string]*topologyNode
+	err    error
+}
+
+type topologyNode struct {
+	name    string
+	handler interface{}
+	source  Source
+	inputs  []string
+	outputs []*topologyNode
+}
+
+func NewTopology() *Topology {
+	return &Topology{byName: make(map[string]*topologyNode)}
+}
+
+// Source adds a source node
+func (this *Topology) Source(name string, source Source) *Topology {
+	return this.add(&topologyNode{name: name, source: source})
+}
+
+// Filter adds a node that keeps the entries of its inputs the function keeps
+func (this *Topology) Filter(name string, fn FilterFunc, inputs ...string) *Topology {
+	return this.add(&topologyNode{name: name, handler: fn, inputs: inputs})
+}
+
+// Map adds a node that maps the entries of its inputs
+func (this *Topology) Map(name string, fn MapFunc, inputs ...string) *Topology {
+	return this.add(&topologyNode{name: name, handler: fn, inputs: inputs})
+}
+
+// Via adds a node that passes the entries of its inputs through the operator
+func (this *Topology) Via(name string, operator Operator, inputs ...string) *Topology {
+	return this.add(&topologyNode{name: name, handler: operator, inputs: inputs})
+}
+
+// Sink adds a node that writes the entries of its inputs, the nodes it is an input of get the written entries.
+// Acking sinks can only be written by a single path of nodes from a source.
+func (this *Topology) Sink(name string, sink Sink, inputs ...string) *Topology {
+	return this.add(&topologyNode{name: name, handler: sink, inputs: inputs})
+}
+
+func (this *Topology) add(node *topologyNode) *Topology {
+	if this.err != nil {
+		return this
+	}
+	if node.name == "" {
+		this.err = fmt.Errorf("topology nodes require a name")
+		return this
+	}
+	if _, found := this.byName[node.name]; found {
+		this.err = fmt.Errorf("topology node '%s' is declared twice", node.name)
+		return this
+	}
+	if node.source == nil && len(node.inputs) == 0 {
+		this.err = fmt.Errorf("topology node '%s' has no inputs", node.name)
+		return this
+	}
+	for idx := range node.inputs {
+		for _, previous := range node.inputs[:idx] {
+			if previous == node.inputs[idx] {
+				this.err = fmt.Errorf("input '%s' of topology node '%s' is declared twice", previous, node.name)
+				return this
+			}
+		}
+	}
+	this.nodes = append(this.nodes, node)
+	this.byName[node.name] = node
+	return this
+}
+
+// Build validates the topology (no unknown inputs or cycles, every node leads to a sink)
+// and returns its streams, e.g: to add them to an engine (see Engine.AddTopology).
+func (this *Topology) Build() ([]Stream, error) {
+	if err := this.validate(); err != nil {
+		return nil, err
+	}
+
+	shared := &topologyClosers{mutex: &sync.Mutex{}}
+	var streams []Stream
+	for _, node := range this.nodes {
+		if node.source == nil {
+			continue
+		}
+		stream, err := this.compile(node, shared)
+		if err != nil {
+			return nil, err
+		}
+		streams = append(streams, stream)
+	}
+	return streams, nil
+}
+
+// validate connects the nodes and checks the DAG
+func (this *Topology) validate() error {
+	if this.err != nil {
+		return this.err
+	}
+	sources := 0
+	for _, node := range this.nodes {
+		node.outputs = nil
+		if node.source != nil {
+			sources++
+		}
+	}
+	if sources == 0 {
+		return fmt.Errorf("topology has no source")
+	}
+	for _, node := range this.nodes {
+		for _, input := range node.inputs {
+			from, found := this.byName[input]
+			if !found {
+				return fmt.Errorf("input '%s' of topology node '%s' doesn't exist", input, node.name)
+			}
+			from.outputs = append(from.outputs, node)
+		}
+	}
+
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	states := make(map[*topologyNode]int, len(this.nodes))
+	leadsToSink := make(map[*topologyNode]bool, len(this.nodes))
+	var visit func(node *topologyNode, path []string) error
+	visit = func(node *topologyNode, path []string) error {
+		switch states[node] {
+		case visiting:
+			return fmt.Errorf("topology has a cycle: %s", joinPath(append(path, node.name)))
+		case visited:
+			return nil
+		}
+		states[node] = visiting
+		_, isSink := node.handler.(Sink)
+		leadsToSink[node] = isSink
+		for _, output := range node.outputs {
+			if err := visit(output, append(path, node.name)); err != nil {
+				return err
+			}
+			leadsToSink[node] = leadsToSink[node] || leadsToSink[output]
+		}
+		states[node] = visited
+		return nil
+	}
+	for _, node := range this.nodes {
+		if err := visit(node, nil); err != nil {
+			return err
+		}
+	}
+	for _, node := range this.nodes {
+		if !leadsToSink[node] {
+			return fmt.Errorf("topology node '%s' doesn't lead to a sink", node.name)
+		}
+	}
+	return nil
+}
+
+// compile returns the stream of a source node
+func (this *Topology) compile(source *topologyNode, shared *topologyClosers) (Stream, error) {
+	stream := NewStream(source.source)
+	node := source
+	for len(node.outputs) == 1 && len(node.outputs[0].inputs) == 1 {
+		node = node.outputs[0]
+		stream.ops = append(stream.ops, node.handler)
+	}
+	if len(node.outputs) == 0 {
+		return stream, nil
+	}
+
+	stage := &topologyStage{roots: node.outputs, shared: shared}
+	seen := make(map[*topologyNode]bool)
+	var collect func(nodes []*topologyNode) error
+	collect = func(nodes []*topologyNode) error {
+		for _, n := range nodes {
+			if seen[n] {
+				continue
+			}
+			seen[n] = true
+			if _, acking := n.handler.(AckingSink); acking {
+				return fmt.Errorf("acking sink '%s' can't be written by several paths of the topology", n.name)
+			}
+			stage.nodes = append(stage.nodes, n)
+			if _, timed := n.handler.(TimedOperator); timed {
+				stage.timed = true
+			}
+			if err := collect(n.outputs); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := collect(node.outputs); err != nil {
+		return nil, err
+	}
+	shared.add(stage.nodes)
+
+	if stage.timed {
+		stream.ops = append(stream.ops, &timedTopologyStage{topologyStage: stage})
+	} else {
+		stream.ops = append(stream.ops, stage)
+	}
+	stream.ops = append(stream.ops, &topologyCommit{})
+	return stream, nil
+}
+
+func joinPath(names []string) string {
+	path := ""
+	for idx, name := range names {
+		if idx > 0 {
+			path += " -> "
+		}
+		path += name
+	}
+	return path
+}
+
+// topologyStage passes the entries through the nodes that come after the linear path of a stream
+type topologyStage struct {
+	roots  []*topologyNode
+	nodes  []*topologyNode
+	timed  bool
+	shared *topologyClosers
+}
+
+// topologyRun holds the outcome of passing an entry through the nodes
+type topologyRun struct {
+	written bool
+	err     error
+}
+
+func (this *topologyRun) fail(node *topologyNode, key string, err error) {
+	if err == nil {
+		return
+	}
+	if this.err == nil {
+		this.err = NewTopologyError(node.name, err)
+		return
+	}
+	LogWith(Fields{FieldStage: OperatorStage, FieldKey: key, "node": node.name}).Error("Topology node failed: %s", err.Error())
+}
+
+func (this *topologyStage) Apply(entry Entry) ([]Entry, error) {
+	run := &topologyRun{}
+	for _, node := range this.roots {
+		this.visit(node, entry, run)
+	}
+	if !run.written {
+		return nil, run.err
+	}
+	return []Entry{entry}, run.err
+}
+
+func (this *topologyStage) visit(node *topologyNode, entry Entry, run *topologyRun) {
+	switch handler := node.handler.(type) {
+	case FilterFunc:
+		keep, err := recoverFilter(handler, entry)
+		run.fail(node, entry.Key, err)
+		if !keep || err != nil {
+			return
+		}
+
+	case MapFunc:
+		value, err := recoverMap(handler, entry)
+		run.fail(node, entry.Key, err)
+		if err != nil {
+			return
+		}
+		entry.Value = value
+
+	case Operator:
+		entries, err := recoverOperator(handler, entry)
+		run.fail(node, entry.Key, err)
+		for _, e := range entries {
+			for _, output := range node.outputs {
+				this.visit(output, e, run)
+			}
+		}
+		return
+
+	case Sink:
+		if _, err := recoverSinkSingle(handler, entry); err != nil {
+			run.fail(node, entry.Key, err)
+		} else {
+			run.written = true
+		}
+	}
+
+	for _, output := range node.outputs {
+		this.visit(output, entry, run)
+	}
+}
+
+// emit passes the entries emitted by the timed operators to their outputs
+func (this *topologyStage) emit(fn func(TimedOperator) ([]Entry, error)) ([]Entry, error) {
+	run := &topologyRun{}
+	for _, node := range this.nodes {
+		op, ok := node.handler.(TimedOperator)
+		if !ok {
+			continue
+		}
+		entries, err := fn(op)
+		run.fail(node, "", err)
+		for _, e := range entries {
+			for _, output := range node.outputs {
+				this.visit(output, e, run)
+			}
+		}
+	}
+	return nil, run.err
+}
+
+func (this *topologyStage) bindSource(source Source) {
+	for _, node := range this.nodes {
+		if binder, ok := node.handler.(sourceBinder); ok {
+			binder.bindSource(source)
+		}
+	}
+}
+
+func (this *topologyStage) bindEvents(stream Stream) {
+	for _, node := range this.nodes {
+		if binder, ok := node.handler.(eventBinder); ok {
+			binder.bindEvents(stream)
+		}
+	}
+}
+
+func (this *topologyStage) bindPool(pool *WorkerPool) {
+	for _, node := range this.nodes {
+		if binder, ok := node.handler.(poolBinder); ok {
+			binder.bindPool(pool)
+		}
+	}
+}
+
+// Flush flushes the nodes that implement Flusher
+func (this *topologyStage) Flush() error {
+	var failed error
+	for _, node := range this.nodes {
+		if flusher, ok := node.handler.(Flusher); ok {
+			if err := flusher.Flush(); err != nil && failed == nil {
+				failed = NewTopologyError(node.name, err)
+			}
+		}
+	}
+	return failed
+}
+
+// Close closes the nodes that implement Closer once all the streams running them closed their stage
+func (this *topologyStage) Close() error {
+	return this.shared.release(this.nodes)
+}
+
+// timedTopologyStage is the stage of a topology with timed operators
+type timedTopologyStage struct {
+	*topologyStage
+}
+
+func (this *timedTopologyStage) Tick(now time.Time) ([]Entry, error) {
+	return this.emit(func(op TimedOperator) ([]Entry, error) { return op.Tick(now) })
+}
+
+func (this *timedTopologyStage) Drain() ([]Entry, error) {
+	return this.emit(TimedOperator.Drain)
+}
+
+// topologyCommit is the last handler of the streams of a topology, it lets the processor
+// commit the entries the topology stage wrote
+type topologyCommit struct{}
+
+func (this *topologyCommit) Single(entry Entry) error {
+	return nil
+}
+
+func (this *topologyCommit) Batch(entry ...Entry) error {
+	return nil
+}
+
+func (this *topologyCommit) Ping() error {
+	return nil
+}
+
+// topologyClosers counts the stages running every node so the nodes are closed once
+type topologyClosers struct {
+	mutex *sync.Mutex
+	refs  map[*topologyNode]int
+}
+
+func (this *topologyClosers) add(nodes []*topologyNode) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	if this.refs == nil {
+		this.refs = make(map[*topologyNode]int)
+	}
+	for _, node := range nodes {
+		this.refs[node]++
+	}
+}
+
+func (this *topologyClosers) release(nodes []*topologyNode) error {
+	this.mutex.Lock()
+	var closers []*topologyNode
+	for _, node := range nodes {
+		if this.refs[node]--; this.refs[node] == 0 {
+			closers = append(closers, node)
+		}
+	}
+	this.mutex.Unlock()
+
+	var failed error
+	for _, node := range closers {
+		if closer, ok := node.handler.(Closer); ok {
+			if err := closer.Close(); err != nil && failed == nil {
+				failed = NewTopologyError(node.name, err)
+			}
+		}
+	}
+	return failed
+}
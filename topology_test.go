@@ -0,0 +1,110 @@
+package go_streams
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTopology_Diamond(t *testing.T) {
+	source := NewSequentialIntegerSource(5, 0).(*sequentialIntegerSource)
+	merged, odds := NewArraySink(), NewArraySink()
+
+	streams, err := NewTopology().
+		Source("numbers", source).
+		Map("tens", func(v interface{}) interface{} { return v.(int) * 10 }, "numbers").
+		Filter("small", func(v interface{}) bool { return v.(int) < 30 }, "tens").
+		Map("negative", func(v interface{}) interface{} { return -v.(int) }, "tens").
+		Sink("merged", merged, "small", "negative").
+		Filter("odd", func(v interface{}) bool { return (v.(int)/10)%2 != 0 }, "merged").
+		Sink("odds", odds, "odd").
+		Build()
+	assert.Nil(t, err)
+	assert.Len(t, streams, 1)
+
+	streams[0].Process(NewDirectProcessor(), make(ErrorChannel, 10))
+
+	assert.EqualValues(t, []interface{}{0, 0, 10, -10, 20, -20, -30, -40, -50}, merged.Array())
+	assert.EqualValues(t, []interface{}{10, -10, -30, -50}, odds.Array())
+	assert.EqualValues(t, "5", source.latestCommit)
+}
+
+func TestTopology_LinearPathsUseTheStreamHandlers(t *testing.T) {
+	sink := NewArraySink()
+	streams, err := NewTopology().
+		Source("numbers", NewSequentialIntegerSource(3, 0)).
+		Filter("even", func(v interface{}) bool { return v.(int)%2 == 0 }, "numbers").
+		Sink("sink", sink, "even").
+		Build()
+	assert.Nil(t, err)
+
+	handlers := streams[0].GetHandlers()
+	assert.Len(t, handlers, 2)
+	assert.IsType(t, FilterFunc(nil), handlers[0])
+	assert.EqualValues(t, sink, handlers[1])
+}
+
+func TestTopology_MergesSourcesInTheEngine(t *testing.T) {
+	engine := NewEngine(NewDirectProcessorFactory(), 10*time.Second)
+	sink := newLifecycleSink()
+	strings := NewArraySink()
+
+	assert.Nil(t, engine.AddTopology(NewTopology().
+		Source("first", NewSequentialIntegerSource(2, 0)).
+		Source("second", NewSequentialIntegerSource(3, time.Millisecond)).
+		Sink("all", sink, "first", "second").
+		Map("string", func(v interface{}) interface{} { return fmt.Sprint(v) }, "second").
+		Sink("strings", strings, "string")))
+	assert.Len(t, engine.Streams(), 2)
+	engine.Start()
+
+	values := make([]int, 0, len(sink.flushed))
+	for _, entry := range sink.flushed {
+		values = append(values, entry.Value.(int))
+	}
+	sort.Ints(values)
+	assert.EqualValues(t, []int{0, 0, 1, 1, 2, 2, 3}, values)
+	assert.EqualValues(t, []interface{}{"0", "1", "2", "3"}, strings.Array())
+	// the sink is run by the stages of both streams but closed once
+	assert.EqualValues(t, 1, sink.closed)
+}
+
+func TestTopology_Validation(t *testing.T) {
+	source, sink := NewSequentialIntegerSource(0, 0), NewArraySink()
+	identity := func(v interface{}) interface{} { return v }
+
+	for expected, topology := range map[string]*Topology{
+		"topology has no source":                              NewTopology(),
+		"topology nodes require a name":                       NewTopology().Source("", source),
+		"topology node 'a' is declared twice":                 NewTopology().Source("a", source).Sink("a", sink, "a"),
+		"topology node 'sink' has no inputs":                  NewTopology().Source("a", source).Sink("sink", sink),
+		"input 'b' of topology node 'sink' doesn't exist":     NewTopology().Source("a", source).Sink("sink", sink, "b"),
+		"input 'a' of topology node 'sink' is declared twice": NewTopology().Source("a", source).Sink("sink", sink, "a", "a"),
+		"topology node 'dangling' doesn't lead to a sink":     NewTopology().Source("a", source).Sink("sink", sink, "a").Map("dangling", identity, "a"),
+		"topology has a cycle: a -> b -> c -> b":              NewTopology().Source("a", source).Map("b", identity, "a", "c").Map("c", identity, "b").Sink("sink", sink, "c"),
+		"acking sink 'acking' can't be written by several paths of the topology": NewTopology().Source("a", source).
+			Sink("sink", sink, "a").Sink("acking", &ackingArraySink{ArraySink: NewArraySink()}, "a"),
+	} {
+		_, err := topology.Build()
+		assert.EqualError(t, err, expected)
+	}
+}
+
+func TestTopology_ReportsNodeErrors(t *testing.T) {
+	sink := NewArraySink()
+	streams, err := NewTopology().
+		Source("numbers", NewSequentialIntegerSource(0, 0)).
+		Sink("failing", NewCallbackSink(func(entries ...Entry) error { return fmt.Errorf("sink is down") }), "numbers").
+		Sink("sink", sink, "numbers").
+		Build()
+	assert.Nil(t, err)
+
+	stage := streams[0].GetHandlers()[0].(Operator)
+	entries, err := stage.Apply(Entry{Key: "1", Value: 1})
+	assert.Len(t, entries, 1)
+	assert.EqualError(t, err, "topology node 'failing' failed: sink is down")
+	assert.EqualValues(t, []interface{}{1}, sink.Array())
+}
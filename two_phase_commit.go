@@ -0,0 +1,338 @@
+package go_streams
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	defaultTransactionBatchSize = 1000
+	defaultTransactionInterval  = time.Second
+)
+
+// Transaction is a transaction of a TransactionalSink
+type Transaction interface {
+	// Write stages entries, they aren't visible until the transaction is committed
+	Write(entries ...Entry) error
+
+	// Prepare makes the staged writes durable so the transaction can be committed after a crash
+	// (e.g: PREPARE TRANSACTION or a marker written in the transaction), sinks without one return nil.
+	Prepare() error
+
+	Commit() error
+	Abort() error
+}
+
+// TransactionalSink is a sink whose writes can be made in transactions (see NewTwoPhaseCommitSink)
+type TransactionalSink interface {
+	Sink
+
+	// Begin starts a transaction, the id is unique and recorded by Prepare when the sink supports recovery
+	Begin(id string) (Transaction, error)
+
+	// Recover completes a transaction left in doubt by a crash: a prepared transaction is committed when
+	// commit is true and aborted otherwise. It returns whether the writes of the transaction are committed.
+	Recover(id string, commit bool) (committed bool, err error)
+}
+
+// TwoPhaseConfig configures a TwoPhaseCommitSink
+type TwoPhaseConfig struct {
+	// Name identifies the sink in the log, it should be stable across restarts
+	Name string
+
+	// Log persists the transaction in progress so it is recovered on restart (e.g: a file state store)
+	Log StateStore
+
+	// BatchSize is the number of entries written per transaction (defaults to 1000)
+	BatchSize int
+
+	// Interval is the time after which the buffered entries are committed anyway (defaults to 1s)
+	Interval time.Duration
+}
+
+// transactionPhase is the phase of a logged transaction
+type transactionPhase string
+
+const (
+	// phasePreparing is logged before the sink prepares, a recovered transaction is aborted
+	phasePreparing transactionPhase = "preparing"
+
+	// phaseCommitting is logged once the sink prepared, a recovered transaction is committed
+	// and so are the keys of its entries at the source
+	phaseCommitting transactionPhase = "committing"
+)
+
+type transactionRecord struct {
+	ID    string           `json:"id"`
+	Phase transactionPhase `json:"phase"`
+	Keys  []string         `json:"keys"`
+}
+
+// TwoPhaseCommitSink writes the entries of a stream to a transactional sink exactly once: entries are
+// written in a transaction that is prepared, committed and only then committed at the source. The
+// transaction in progress is logged so a crash in between is recovered when the stream restarts, the
+// source is committed with the sink or its entries are written again. It is an AckingSink that should be
+// bound to a single stream, whose source replays the entries that weren't committed when it restarts.
+type TwoPhaseCommitSink struct {
+	sink   TransactionalSink
+	config TwoPhaseConfig
+
+	mutex   *sync.Mutex
+	commit  Committer
+	buffer  []Entry
+	inDoubt *transactionRecord
+	seq     int64
+
+	startOnce *sync.Once
+	closeOnce *sync.Once
+	closeCh   chan struct{}
+}
+
+func NewTwoPhaseCommitSink(sink TransactionalSink, config TwoPhaseConfig) (*TwoPhaseCommitSink, error) {
+	if config.Name == "" || config.Log == nil {
+		return nil, fmt.Errorf("two phase commit sink requires a name and a log")
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = defaultTransactionBatchSize
+	}
+	if config.Interval <= 0 {
+		config.Interval = defaultTransactionInterval
+	}
+	this := &TwoPhaseCommitSink{
+		sink:      sink,
+		config:    config,
+		mutex:     &sync.Mutex{},
+		startOnce: &sync.Once{},
+		closeOnce: &sync.Once{},
+		closeCh:   make(chan struct{}),
+	}
+
+	record, err := this.readLog()
+	if err != nil {
+		return nil, err
+	}
+	this.inDoubt = record
+	return this, nil
+}
+
+// Single writes the entry in its own transaction, streams bind the sink and write in batches instead
+func (this *TwoPhaseCommitSink) Single(entry Entry) error {
+	return this.Batch(entry)
+}
+
+// Batch writes the entries in a single transaction, streams bind the sink and write in batches instead
+func (this *TwoPhaseCommitSink) Batch(entry ...Entry) error {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	if err := this.recover(); err != nil {
+		return err
+	}
+	return this.transact(append([]Entry{}, entry...), nil)
+}
+
+func (this *TwoPhaseCommitSink) Ping() error {
+	return this.sink.Ping()
+}
+
+// Bind returns the sink of the stream, its entries are committed at the source once their transaction committed
+func (this *TwoPhaseCommitSink) Bind(commit Committer) Sink {
+	this.mutex.Lock()
+	this.commit = commit
+	this.mutex.Unlock()
+
+	this.startOnce.Do(func() {
+		go this.commitPeriodically()
+	})
+	return &boundTransactionalSink{TwoPhaseCommitSink: this}
+}
+
+// Flush commits the buffered entries
+func (this *TwoPhaseCommitSink) Flush() error {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	return this.flush()
+}
+
+// Close stops the periodic commits and commits the buffered entries
+func (this *TwoPhaseCommitSink) Close() error {
+	this.closeOnce.Do(func() {
+		close(this.closeCh)
+	})
+	return this.Flush()
+}
+
+func (this *TwoPhaseCommitSink) write(entries []Entry) error {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	// the slice of the processor is reused by its next batch
+	this.buffer = append(this.buffer, entries...)
+	if len(this.buffer) < this.config.BatchSize {
+		return nil
+	}
+	return this.flush()
+}
+
+// flush writes the buffered entries in a transaction, they are kept to be written again when it fails.
+// The caller holds the mutex.
+func (this *TwoPhaseCommitSink) flush() error {
+	if err := this.recover(); err != nil {
+		return err
+	}
+	if len(this.buffer) == 0 {
+		return nil
+	}
+
+	keys := make([]string, len(this.buffer))
+	for idx := range this.buffer {
+		keys[idx] = this.buffer[idx].Key
+	}
+	if err := this.transact(this.buffer, keys); err != nil {
+		return err
+	}
+	this.buffer = nil
+	return nil
+}
+
+// transact writes the entries in a transaction and commits the keys at the source, the caller holds the mutex
+func (this *TwoPhaseCommitSink) transact(entries []Entry, keys []string) error {
+	this.seq++
+	record := &transactionRecord{ID: fmt.Sprintf("%s-%d-%d", this.config.Name, time.Now().UnixNano(), this.seq), Phase: phasePreparing, Keys: keys}
+	tx, err := this.sink.Begin(record.ID)
+	if err != nil {
+		return err
+	}
+	abort := func(err error) error {
+		if abortErr := tx.Abort(); abortErr != nil {
+			this.logger().Warn("Failed to abort transaction '%s': %s", record.ID, abortErr.Error())
+		}
+		return err
+	}
+
+	if err := tx.Write(entries...); err != nil {
+		return abort(err)
+	}
+	if err := this.writeLog(record); err != nil {
+		return abort(err)
+	}
+	if err := tx.Prepare(); err != nil {
+		return this.abandon(record, abort(err))
+	}
+
+	record.Phase = phaseCommitting
+	if err := this.writeLog(record); err != nil {
+		record.Phase = phasePreparing
+		return this.abandon(record, abort(err))
+	}
+	if err := tx.Commit(); err != nil {
+		this.inDoubt = record
+		return err
+	}
+	return this.complete(record)
+}
+
+// abandon removes an aborted transaction from the log, it stays in doubt (to be aborted) when it can't be removed.
+// The caller holds the mutex.
+func (this *TwoPhaseCommitSink) abandon(record *transactionRecord, err error) error {
+	if logErr := this.config.Log.Delete(this.config.Name); logErr != nil {
+		this.inDoubt = record
+	}
+	return err
+}
+
+// recover completes the transaction left in doubt by a failure or a crash, the buffered entries
+// are dropped when it turns out committed. The caller holds the mutex.
+func (this *TwoPhaseCommitSink) recover() error {
+	record := this.inDoubt
+	if record == nil {
+		return nil
+	}
+	committed, err := this.sink.Recover(record.ID, record.Phase == phaseCommitting)
+	if err != nil {
+		return fmt.Errorf("failed to recover transaction '%s': %s", record.ID, err.Error())
+	}
+	this.logger().Info("Recovered transaction '%s' (committed: %t)", record.ID, committed)
+
+	if !committed {
+		record.Keys = nil
+	} else if n := len(record.Keys); n > 0 && len(this.buffer) >= n && this.buffer[n-1].Key == record.Keys[n-1] {
+		this.buffer = this.buffer[len(record.Keys):]
+	}
+	return this.complete(record)
+}
+
+// complete commits the keys of a committed transaction at the source and removes it from the log,
+// the transaction stays in doubt until the source committed so it is committed again. The caller holds the mutex.
+func (this *TwoPhaseCommitSink) complete(record *transactionRecord) error {
+	if len(record.Keys) > 0 && this.commit != nil {
+		if err := this.commit(record.Keys...); err != nil {
+			this.inDoubt = record
+			return err
+		}
+	}
+	if err := this.config.Log.Delete(this.config.Name); err != nil {
+		this.inDoubt = record
+		return err
+	}
+	this.inDoubt = nil
+	return nil
+}
+
+func (this *TwoPhaseCommitSink) readLog() (*transactionRecord, error) {
+	value, found, err := this.config.Log.Get(this.config.Name)
+	if err != nil || !found {
+		return nil, err
+	}
+	encoded, ok := value.(string)
+	if !ok {
+		return nil, fmt.Errorf("invalid transaction log of '%s'", this.config.Name)
+	}
+	record := &transactionRecord{}
+	if err := json.Unmarshal([]byte(encoded), record); err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+func (this *TwoPhaseCommitSink) writeLog(record *transactionRecord) error {
+	encoded, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	return this.config.Log.Put(this.config.Name, string(encoded), 0)
+}
+
+func (this *TwoPhaseCommitSink) commitPeriodically() {
+	ticker := time.NewTicker(this.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-this.closeCh:
+			return
+		case <-ticker.C:
+			if err := this.Flush(); err != nil {
+				this.logger().Error("Failed to commit transaction: %s", err.Error())
+			}
+		}
+	}
+}
+
+func (this *TwoPhaseCommitSink) logger() Logger {
+	return LogWith(Fields{FieldStage: SinkStage, "sink": this.config.Name})
+}
+
+// boundTransactionalSink is the sink of the stream bound to a TwoPhaseCommitSink
+type boundTransactionalSink struct {
+	*TwoPhaseCommitSink
+}
+
+func (this *boundTransactionalSink) Single(entry Entry) error {
+	return this.write([]Entry{entry})
+}
+
+func (this *boundTransactionalSink) Batch(entry ...Entry) error {
+	return this.write(entry)
+}
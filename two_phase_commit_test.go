@@ -0,0 +1,198 @@
+package go_streams
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTwoPhaseCommitSink_CommitsTheSinkThenTheSource(t *testing.T) {
+	sink := newTransactionRecorder()
+	tpc, err := NewTwoPhaseCommitSink(sink, TwoPhaseConfig{Name: "recorder", Log: NewMemoryStateStore(0), BatchSize: 2, Interval: time.Hour})
+	assert.Nil(t, err)
+	bound := tpc.Bind(sink.commitSource)
+
+	assert.Nil(t, bound.Single(Entry{Key: "1", Value: 1}))
+	assert.Empty(t, sink.log())
+	assert.Nil(t, bound.Batch(Entry{Key: "2", Value: 2}))
+	assert.Nil(t, bound.Single(Entry{Key: "3", Value: 3}))
+	assert.Nil(t, tpc.Close())
+
+	assert.EqualValues(t, []string{"begin", "write 1 2", "prepare", "commit", "source 1 2", "begin", "write 3", "prepare", "commit", "source 3"}, sink.log())
+	assert.EqualValues(t, []interface{}{1, 2, 3}, sink.values())
+}
+
+func TestTwoPhaseCommitSink_RetriesFailedTransactions(t *testing.T) {
+	sink := newTransactionRecorder()
+	log := NewMemoryStateStore(0)
+	tpc, err := NewTwoPhaseCommitSink(sink, TwoPhaseConfig{Name: "recorder", Log: log, Interval: time.Hour})
+	assert.Nil(t, err)
+	bound := tpc.Bind(sink.commitSource)
+
+	sink.failPrepare = true
+	assert.Nil(t, bound.Batch(Entry{Key: "1", Value: 1}, Entry{Key: "2", Value: 2}))
+	assert.NotNil(t, tpc.Flush())
+	assert.Empty(t, sink.values())
+	_, found, _ := log.Get("recorder")
+	assert.False(t, found)
+
+	sink.failPrepare = false
+	assert.Nil(t, tpc.Flush())
+	assert.EqualValues(t, []interface{}{1, 2}, sink.values())
+	assert.EqualValues(t, []string{"begin", "write 1 2", "abort", "begin", "write 1 2", "prepare", "commit", "source 1 2"}, sink.log())
+}
+
+func TestTwoPhaseCommitSink_RecoversInDoubtTransactions(t *testing.T) {
+	for _, phase := range []transactionPhase{phaseCommitting, phasePreparing} {
+		sink := newTransactionRecorder()
+		sink.prepared["tx"] = []Entry{{Key: "1", Value: 1}, {Key: "2", Value: 2}}
+		log := NewMemoryStateStore(0)
+		assert.Nil(t, log.Put("recorder", fmt.Sprintf(`{"id":"tx","phase":"%s","keys":["1","2"]}`, phase), 0))
+
+		// the source replays the entries of the transaction after the restart
+		tpc, err := NewTwoPhaseCommitSink(sink, TwoPhaseConfig{Name: "recorder", Log: log, Interval: time.Hour})
+		assert.Nil(t, err)
+		bound := tpc.Bind(sink.commitSource)
+		assert.Nil(t, bound.Batch(Entry{Key: "1", Value: 1}, Entry{Key: "2", Value: 2}, Entry{Key: "3", Value: 3}))
+		assert.Nil(t, tpc.Flush())
+
+		_, found, _ := log.Get("recorder")
+		assert.False(t, found)
+		assert.EqualValues(t, []interface{}{1, 2, 3}, sink.values())
+		if phase == phaseCommitting {
+			assert.EqualValues(t, []string{"recover tx true", "source 1 2", "begin", "write 3", "prepare", "commit", "source 3"}, sink.log())
+		} else {
+			assert.EqualValues(t, []string{"recover tx false", "begin", "write 1 2 3", "prepare", "commit", "source 1 2 3"}, sink.log())
+		}
+	}
+}
+
+// transactionRecorder is a transactional sink that records its calls and the commits of the source
+type transactionRecorder struct {
+	mutex       *sync.Mutex
+	events      []string
+	written     []interface{}
+	prepared    map[string][]Entry
+	failPrepare bool
+}
+
+func newTransactionRecorder() *transactionRecorder {
+	return &transactionRecorder{mutex: &sync.Mutex{}, prepared: make(map[string][]Entry)}
+}
+
+func (this *transactionRecorder) Single(entry Entry) error {
+	return this.Batch(entry)
+}
+
+func (this *transactionRecorder) Batch(entry ...Entry) error {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	for _, e := range entry {
+		this.written = append(this.written, e.Value)
+	}
+	return nil
+}
+
+func (this *transactionRecorder) Ping() error {
+	return nil
+}
+
+func (this *transactionRecorder) Begin(id string) (Transaction, error) {
+	this.record("begin")
+	return &recordedTransaction{sink: this, id: id}, nil
+}
+
+func (this *transactionRecorder) Recover(id string, commit bool) (bool, error) {
+	this.record(fmt.Sprintf("recover %s %t", id, commit))
+	if !commit {
+		this.mutex.Lock()
+		defer this.mutex.Unlock()
+		delete(this.prepared, id)
+		return false, nil
+	}
+	return this.publish(id), nil
+}
+
+// publish makes the writes of a prepared transaction visible, it returns whether the transaction was prepared
+func (this *transactionRecorder) publish(id string) bool {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	entries, found := this.prepared[id]
+	delete(this.prepared, id)
+	for _, e := range entries {
+		this.written = append(this.written, e.Value)
+	}
+	return found
+}
+
+func (this *transactionRecorder) commitSource(keys ...string) error {
+	this.record("source" + joinKeys(keys))
+	return nil
+}
+
+func (this *transactionRecorder) record(event string) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.events = append(this.events, event)
+}
+
+func (this *transactionRecorder) log() []string {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	return append([]string{}, this.events...)
+}
+
+func (this *transactionRecorder) values() []interface{} {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	return append([]interface{}{}, this.written...)
+}
+
+type recordedTransaction struct {
+	sink   *transactionRecorder
+	id     string
+	staged []Entry
+}
+
+func (this *recordedTransaction) Write(entries ...Entry) error {
+	keys := make([]string, len(entries))
+	for idx := range entries {
+		keys[idx] = entries[idx].Key
+	}
+	this.sink.record("write" + joinKeys(keys))
+	this.staged = append(this.staged, entries...)
+	return nil
+}
+
+func (this *recordedTransaction) Prepare() error {
+	if this.sink.failPrepare {
+		return fmt.Errorf("prepare failed")
+	}
+	this.sink.record("prepare")
+	this.sink.mutex.Lock()
+	defer this.sink.mutex.Unlock()
+	this.sink.prepared[this.id] = this.staged
+	return nil
+}
+
+func (this *recordedTransaction) Commit() error {
+	this.sink.record("commit")
+	this.sink.publish(this.id)
+	return nil
+}
+
+func (this *recordedTransaction) Abort() error {
+	this.sink.record("abort")
+	return nil
+}
+
+func joinKeys(keys []string) string {
+	joined := ""
+	for _, key := range keys {
+		joined += " " + key
+	}
+	return joined
+}
@@ -0,0 +1,87 @@
+package go_streams
+
+import "fmt"
+
+// MetadataViolations is attached to the entries rejected by Stream.Validate, it holds their violations joined by "; "
+const MetadataViolations = "violations"
+
+// Violation is a reason why a value doesn't match a schema, Path points at the invalid part of the value (e.g: "/items/0/price")
+type Violation struct {
+	Path    string
+	Message string
+}
+
+func (this Violation) String() string {
+	if this.Path == "" {
+		return this.Message
+	}
+	return fmt.Sprintf("%s: %s", this.Path, this.Message)
+}
+
+// Schema validates the values of entries (see Stream.Validate and the schema package for JSON Schema)
+type Schema interface {
+	// Validate returns the violations of the value, a valid value has none
+	Validate(value interface{}) []Violation
+}
+
+// SchemaFunc adapts a function into a Schema, e.g: to validate protobuf messages against their descriptor
+type SchemaFunc func(value interface{}) []Violation
+
+func (this SchemaFunc) Validate(value interface{}) []Violation {
+	return this(value)
+}
+
+// validateStage rejects the entries whose value doesn't match the schema to a side output,
+// or to the dead letter sink of the stream when no output is set.
+type validateStage struct {
+	schema Schema
+	output string
+	stream *baseStream
+}
+
+func (this *validateStage) Apply(entry Entry) ([]Entry, error) {
+	violations := this.schema.Validate(entry.Value)
+	if len(violations) == 0 {
+		return []Entry{entry}, nil
+	}
+	invalid := NewValidationError(entry.Key, violations)
+
+	metadata := make(map[string]string, len(entry.Metadata)+3)
+	for k, v := range entry.Metadata {
+		metadata[k] = v
+	}
+	metadata[MetadataViolations] = invalid.violations()
+
+	var sink Sink
+	if this.output != "" {
+		sink, _ = this.stream.sideOutputs.get(this.output)
+	} else if sink = this.stream.GetDeadLetter(); sink != nil {
+		metadata[MetadataDeadLetterStage] = string(OperatorStage)
+		metadata[MetadataDeadLetterError] = invalid.Error()
+	}
+	if sink == nil {
+		return nil, invalid
+	}
+
+	entry.Metadata = metadata
+	if _, err := recoverSinkSingle(sink, entry); err != nil {
+		return nil, fmt.Errorf("failed to reject invalid entry: %s (%s)", err.Error(), invalid.Error())
+	}
+	return nil, nil
+}
+
+// Flush flushes the side output sinks that implement Flusher
+func (this *validateStage) Flush() error {
+	if this.output == "" {
+		return nil
+	}
+	return (&sideOutputStage{outputs: this.stream.sideOutputs}).Flush()
+}
+
+// Close closes the side output sinks that implement Closer
+func (this *validateStage) Close() error {
+	if this.output == "" {
+		return nil
+	}
+	return (&sideOutputStage{outputs: this.stream.sideOutputs}).Close()
+}
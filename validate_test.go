@@ -0,0 +1,74 @@
+package go_streams
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// evenSchema rejects the odd integers
+var evenSchema = SchemaFunc(func(value interface{}) []Violation {
+	if value.(int)%2 == 1 {
+		return []Violation{{Path: "/value", Message: "must be even"}, {Message: "is odd"}}
+	}
+	return nil
+})
+
+func TestStream_Validate_RejectsToTheSideOutput(t *testing.T) {
+	valid := NewArraySink()
+	var rejected []Entry
+	errs := make(ErrorChannel, 10)
+
+	NewStream(NewSequentialIntegerSource(3, 0)).
+		Validate(evenSchema, "invalid").
+		SideOutput("invalid", NewCallbackSink(func(entries ...Entry) error {
+			rejected = append(rejected, entries...)
+			return nil
+		})).
+		Sink(valid).
+		Process(NewDirectProcessor(), errs)
+
+	assert.EqualValues(t, []interface{}{0, 2}, valid.Array())
+	assert.Len(t, rejected, 2)
+	assert.EqualValues(t, 3, rejected[1].Value)
+	assert.EqualValues(t, "/value: must be even; is odd", rejected[1].Metadata[MetadataViolations])
+}
+
+func TestStream_Validate_RejectsToTheDeadLetterSink(t *testing.T) {
+	var letters []Entry
+	stream := NewStream(NewSequentialIntegerSource(0, 0)).Validate(evenSchema, "").DeadLetter(NewCallbackSink(func(entries ...Entry) error {
+		letters = append(letters, entries...)
+		return nil
+	}))
+
+	entries, err := stream.GetHandlers()[0].(Operator).Apply(Entry{Key: "1", Value: 1, Metadata: map[string]string{"origin": "test"}})
+	assert.Nil(t, err)
+	assert.Empty(t, entries)
+	assert.Len(t, letters, 1)
+	assert.EqualValues(t, map[string]string{
+		"origin":                "test",
+		MetadataViolations:      "/value: must be even; is odd",
+		MetadataDeadLetterStage: string(OperatorStage),
+		MetadataDeadLetterError: "entry '1' is invalid: /value: must be even; is odd",
+	}, letters[0].Metadata)
+}
+
+func TestStream_Validate_ReportsUnwrittenRejections(t *testing.T) {
+	stream := NewStream(NewSequentialIntegerSource(0, 0)).Validate(evenSchema, "")
+	entries, err := stream.GetHandlers()[0].(Operator).Apply(Entry{Key: "1", Value: 1})
+	assert.Empty(t, entries)
+	invalid, ok := err.(*ValidationError)
+	assert.True(t, ok)
+	assert.Len(t, invalid.Violations, 2)
+
+	stream = NewStream(NewSequentialIntegerSource(0, 0)).Validate(evenSchema, "invalid").SideOutput("invalid", NewCallbackSink(func(entries ...Entry) error {
+		return fmt.Errorf("sink is down")
+	}))
+	_, err = stream.GetHandlers()[0].(Operator).Apply(Entry{Key: "1", Value: 1})
+	assert.EqualValues(t, "failed to reject invalid entry: sink is down (entry '1' is invalid: /value: must be even; is odd)", err.Error())
+
+	entries, err = stream.GetHandlers()[0].(Operator).Apply(Entry{Key: "2", Value: 2})
+	assert.Nil(t, err)
+	assert.Len(t, entries, 1)
+}
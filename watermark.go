@@ -0,0 +1,122 @@
+package go_streams
+
+import (
+	"sync"
+	"time"
+)
+
+// Watermarks tracks the event time progress of a stream with bounded out-of-orderness: the watermark
+// is the latest event time seen minus the allowed delay, entries older than the watermark are late.
+// Streams merged from several inputs track a watermark per input and progress at the pace of the
+// slowest one, inputs that sent nothing for the idle timeout don't hold the watermark back.
+type Watermarks struct {
+	delay       time.Duration
+	idleTimeout time.Duration
+	mutex       *sync.Mutex
+	inputs      map[string]*inputWatermark
+	current     time.Time
+}
+
+type inputWatermark struct {
+	latest   time.Time
+	lastSeen time.Time
+}
+
+// NewWatermarks creates a watermark tracker, a zero idle timeout never ignores inputs
+func NewWatermarks(delay time.Duration, idleTimeout time.Duration) *Watermarks {
+	return &Watermarks{
+		delay:       delay,
+		idleTimeout: idleTimeout,
+		mutex:       &sync.Mutex{},
+		inputs:      make(map[string]*inputWatermark),
+	}
+}
+
+// Observe records the event time of an entry of the input and returns the updated watermark
+func (this *Watermarks) Observe(input string, at time.Time, now time.Time) time.Time {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	state, found := this.inputs[input]
+	if !found {
+		state = &inputWatermark{}
+		this.inputs[input] = state
+	}
+	if at.After(state.latest) {
+		state.latest = at
+	}
+	state.lastSeen = now
+	return this.advance(now)
+}
+
+// Advance recomputes the watermark, idle inputs are ignored once their timeout passed
+func (this *Watermarks) Advance(now time.Time) time.Time {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	return this.advance(now)
+}
+
+// Current returns the watermark, it never goes back
+func (this *Watermarks) Current() time.Time {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	return this.current
+}
+
+func (this *Watermarks) advance(now time.Time) time.Time {
+	var slowest, latest time.Time
+	active := 0
+	for _, input := range this.inputs {
+		if input.latest.After(latest) {
+			latest = input.latest
+		}
+		if this.idleTimeout > 0 && now.Sub(input.lastSeen) >= this.idleTimeout {
+			continue
+		}
+		if active == 0 || input.latest.Before(slowest) {
+			slowest = input.latest
+		}
+		active++
+	}
+	// all the inputs are idle, the watermark follows the latest one
+	if active == 0 {
+		slowest = latest
+	}
+
+	if !slowest.IsZero() {
+		if watermark := slowest.Add(-this.delay); watermark.After(this.current) {
+			this.current = watermark
+		}
+	}
+	return this.current
+}
+
+type watermarksState struct {
+	Inputs  map[string]inputWatermarkState
+	Current time.Time
+}
+
+type inputWatermarkState struct {
+	Latest   time.Time
+	LastSeen time.Time
+}
+
+func (this *Watermarks) snapshot() watermarksState {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	state := watermarksState{Inputs: make(map[string]inputWatermarkState, len(this.inputs)), Current: this.current}
+	for name, input := range this.inputs {
+		state.Inputs[name] = inputWatermarkState{Latest: input.latest, LastSeen: input.lastSeen}
+	}
+	return state
+}
+
+func (this *Watermarks) restore(state watermarksState) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.inputs = make(map[string]*inputWatermark, len(state.Inputs))
+	for name, input := range state.Inputs {
+		this.inputs[name] = &inputWatermark{latest: input.Latest, lastSeen: input.LastSeen}
+	}
+	this.current = state.Current
+}
@@ -0,0 +1,344 @@
+// Package websocket holds a source consuming streaming APIs over websocket connections (e.g: exchange feeds),
+// it works with any websocket library through the Conn interface (e.g: an adapter of gorilla/websocket).
+package websocket
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	streams "github.com/matang28/go-streams"
+)
+
+const (
+	defaultPingInterval = 30 * time.Second
+	defaultBackoff      = 100 * time.Millisecond
+	defaultMaxBackoff   = 30 * time.Second
+	resumeKey           = "resume"
+)
+
+// Conn is an open websocket connection
+type Conn interface {
+	// ReadMessage blocks until the next data message, control frames are handled by the implementation
+	ReadMessage() ([]byte, error)
+
+	// Ping sends a ping and blocks until its pong arrived
+	Ping() error
+
+	// Close closes the connection, a blocked ReadMessage should return an error
+	Close() error
+}
+
+// Dialer opens a connection that resumes the stream after the resume token (empty on the first connection),
+// e.g: by passing it in the url or in the subscribe message.
+type Dialer func(resume string) (Conn, error)
+
+// GapError is reported when the sequence numbers of the messages skipped some messages
+type GapError struct {
+	Expected uint64
+	Got      uint64
+}
+
+func (e *GapError) Error() string {
+	return fmt.Sprintf("gap detected in the stream: expected sequence %d, got %d", e.Expected, e.Got)
+}
+
+type Config struct {
+	// Name of the source (defaults to a generated name)
+	Name string
+
+	// Decode turns a message into an entry value (defaults to the raw message), a nil value skips the message (e.g: heartbeats)
+	Decode func(message []byte) (interface{}, error)
+
+	// ResumeToken returns the token of a value the stream can be resumed after (empty when it has none)
+	ResumeToken func(value interface{}) string
+
+	// Sequence returns the sequence number of a value for the gap detection (ok is false when it has none)
+	Sequence func(value interface{}) (seq uint64, ok bool)
+
+	// Store persists the resume token of the committed entries so a restarted source resumes after them
+	Store streams.StateStore
+
+	// PingInterval is the interval between pings (defaults to 30s), the connection is reopened when
+	// a pong didn't arrive within PongTimeout (defaults to the ping interval).
+	PingInterval time.Duration
+	PongTimeout  time.Duration
+
+	// Backoff is the delay before the first reconnection (defaults to 100ms), it doubles on every
+	// consecutive failure up to MaxBackoff (defaults to 30s).
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+}
+
+type pendingToken struct {
+	seq   uint64
+	token string
+}
+
+// Source reads the messages of a websocket connection and reconnects with backoff when it fails, reconnections resume
+// after the latest received token. Committing an entry persists its resume token and the tokens of the entries before it.
+type Source struct {
+	config Config
+	dial   Dialer
+	name   string
+
+	mutex   *sync.Mutex
+	conn    Conn
+	seq     uint64
+	resume  string
+	lastSeq uint64
+	pending []pendingToken
+
+	closeCh chan bool
+	once    *sync.Once
+}
+
+func NewSource(dial Dialer, config Config) (*Source, error) {
+	if dial == nil {
+		return nil, fmt.Errorf("websocket source requires a dialer")
+	}
+	if config.Decode == nil {
+		config.Decode = func(message []byte) (interface{}, error) { return message, nil }
+	}
+	if config.PingInterval <= 0 {
+		config.PingInterval = defaultPingInterval
+	}
+	if config.PongTimeout <= 0 {
+		config.PongTimeout = config.PingInterval
+	}
+	if config.Backoff <= 0 {
+		config.Backoff = defaultBackoff
+	}
+	if config.MaxBackoff < config.Backoff {
+		config.MaxBackoff = defaultMaxBackoff
+	}
+
+	name := config.Name
+	if name == "" {
+		name = fmt.Sprintf("websocketSource-%d", time.Now().UnixNano())
+	}
+	source := &Source{config: config, dial: dial, name: name, mutex: &sync.Mutex{}, closeCh: make(chan bool), once: &sync.Once{}}
+
+	if config.Store != nil {
+		resume, found, err := config.Store.Get(name + "/" + resumeKey)
+		if err != nil {
+			return nil, err
+		}
+		if found {
+			source.resume, _ = resume.(string)
+		}
+	}
+	return source, nil
+}
+
+func (this *Source) Start(channel streams.EntryChannel, errorChannel streams.ErrorChannel) {
+	log := streams.LogWith(streams.Fields{streams.FieldStream: this.name})
+	log.Info("Starting websocket source")
+	defer func() {
+		close(channel)
+		errorChannel <- streams.NewEofError(this)
+		log.Info("Websocket source stopped")
+	}()
+
+	backoff := this.config.Backoff
+	for {
+		err := this.connect()
+		if err == nil {
+			backoff = this.config.Backoff
+			err = this.read(channel, errorChannel)
+		}
+		if this.stopped() {
+			return
+		}
+		errorChannel <- err
+		log.Warn("Websocket connection failed, reconnecting in %s: %s", backoff, err.Error())
+		select {
+		case <-this.closeCh:
+			return
+		case <-time.After(backoff):
+		}
+		if backoff *= 2; backoff > this.config.MaxBackoff {
+			backoff = this.config.MaxBackoff
+		}
+	}
+}
+
+func (this *Source) connect() error {
+	this.mutex.Lock()
+	resume := this.resume
+	this.mutex.Unlock()
+
+	conn, err := this.dial(resume)
+	if err != nil {
+		return err
+	}
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	if this.stopped() {
+		return conn.Close()
+	}
+	this.conn = conn
+	return nil
+}
+
+// read emits the messages of the connection until it fails
+func (this *Source) read(channel streams.EntryChannel, errorChannel streams.ErrorChannel) error {
+	conn := this.conn
+	defer conn.Close()
+	stopPings := this.pingPeriodically(conn)
+	defer stopPings()
+
+	for {
+		message, err := conn.ReadMessage()
+		if err != nil {
+			return err
+		}
+		value, err := this.config.Decode(message)
+		if err != nil {
+			errorChannel <- err
+			continue
+		}
+		if value == nil {
+			continue
+		}
+		if err := this.detectGap(value); err != nil {
+			errorChannel <- err
+		}
+		channel <- this.newEntry(value)
+	}
+}
+
+// pingPeriodically closes the connection when a pong didn't arrive in time, so its read fails
+func (this *Source) pingPeriodically(conn Conn) func() {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(this.config.PingInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+
+			pong := make(chan error, 1)
+			go func() { pong <- conn.Ping() }()
+			select {
+			case <-done:
+				return
+			case err := <-pong:
+				if err == nil {
+					continue
+				}
+			case <-time.After(this.config.PongTimeout):
+			}
+			streams.LogWith(streams.Fields{streams.FieldStream: this.name}).Warn("Websocket pong didn't arrive, closing the connection")
+			_ = conn.Close()
+			return
+		}
+	}()
+	return func() { close(done) }
+}
+
+func (this *Source) detectGap(value interface{}) error {
+	if this.config.Sequence == nil {
+		return nil
+	}
+	seq, ok := this.config.Sequence(value)
+	if !ok {
+		return nil
+	}
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	expected := this.lastSeq + 1
+	first := this.lastSeq == 0
+	this.lastSeq = seq
+	if !first && seq != expected {
+		return &GapError{Expected: expected, Got: seq}
+	}
+	return nil
+}
+
+func (this *Source) newEntry(value interface{}) streams.Entry {
+	token := ""
+	if this.config.ResumeToken != nil {
+		token = this.config.ResumeToken(value)
+	}
+
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.seq++
+	if token != "" {
+		this.resume = token
+		this.pending = append(this.pending, pendingToken{seq: this.seq, token: token})
+	}
+	return streams.Entry{Key: strconv.FormatUint(this.seq, 10), Value: value, Timestamp: time.Now()}
+}
+
+func (this *Source) stopped() bool {
+	select {
+	case <-this.closeCh:
+		return true
+	default:
+		return false
+	}
+}
+
+func (this *Source) Stop() error {
+	var err error
+	this.once.Do(func() {
+		streams.LogWith(streams.Fields{streams.FieldStream: this.name}).Info("Stopping websocket source")
+		this.mutex.Lock()
+		defer this.mutex.Unlock()
+		close(this.closeCh)
+		if this.conn != nil {
+			err = this.conn.Close()
+		}
+	})
+	return err
+}
+
+func (this *Source) Ping() error {
+	return nil
+}
+
+// CommitEntry persists the resume token of the latest of the keys
+func (this *Source) CommitEntry(keys ...string) error {
+	var latest uint64
+	for _, key := range keys {
+		seq, err := strconv.ParseUint(key, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid websocket entry key: '%s'", key)
+		}
+		if seq > latest {
+			latest = seq
+		}
+	}
+
+	this.mutex.Lock()
+	token := ""
+	done := 0
+	for done < len(this.pending) && this.pending[done].seq <= latest {
+		token = this.pending[done].token
+		done++
+	}
+	this.pending = this.pending[done:]
+	this.mutex.Unlock()
+
+	if token == "" || this.config.Store == nil {
+		return nil
+	}
+	return this.config.Store.Put(this.name+"/"+resumeKey, token, 0)
+}
+
+// Resume returns the resume token of the latest received entry
+func (this *Source) Resume() string {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	return this.resume
+}
+
+func (this *Source) Name() string {
+	return this.name
+}
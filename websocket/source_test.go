@@ -0,0 +1,134 @@
+package websocket
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	streams "github.com/matang28/go-streams"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeConn reads the messages of its channel, it fails once the channel is closed
+type fakeConn struct {
+	messages chan string
+	pongs    bool
+	closed   chan struct{}
+	once     *sync.Once
+}
+
+func newFakeConn(pongs bool, messages ...string) *fakeConn {
+	conn := &fakeConn{messages: make(chan string, len(messages)), pongs: pongs, closed: make(chan struct{}), once: &sync.Once{}}
+	for _, message := range messages {
+		conn.messages <- message
+	}
+	return conn
+}
+
+func (this *fakeConn) ReadMessage() ([]byte, error) {
+	select {
+	case message, ok := <-this.messages:
+		if !ok {
+			return nil, fmt.Errorf("connection reset")
+		}
+		return []byte(message), nil
+	case <-this.closed:
+		return nil, fmt.Errorf("connection closed")
+	}
+}
+
+func (this *fakeConn) Ping() error {
+	if !this.pongs {
+		<-this.closed
+	}
+	return nil
+}
+
+func (this *fakeConn) Close() error {
+	this.once.Do(func() { close(this.closed) })
+	return nil
+}
+
+func seqOf(value interface{}) (uint64, bool) {
+	seq, err := strconv.ParseUint(string(value.([]byte)), 10, 64)
+	return seq, err == nil
+}
+
+func TestSource_ReconnectsAndResumes(t *testing.T) {
+	first := newFakeConn(true, "1", "2")
+	close(first.messages)
+	second := newFakeConn(true, "4")
+
+	mutex := &sync.Mutex{}
+	var resumes []string
+	conns := []*fakeConn{first, second}
+	dial := func(resume string) (Conn, error) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		resumes = append(resumes, resume)
+		conn := conns[0]
+		conns = conns[1:]
+		return conn, nil
+	}
+
+	store := streams.NewMemoryStateStore(10)
+	source, err := NewSource(dial, Config{
+		Name:        "feed",
+		ResumeToken: func(value interface{}) string { return string(value.([]byte)) },
+		Sequence:    seqOf,
+		Store:       store,
+		Backoff:     time.Millisecond,
+	})
+	assert.Nil(t, err)
+
+	channel := make(streams.EntryChannel, 10)
+	errs := make(streams.ErrorChannel, 10)
+	go source.Start(channel, errs)
+
+	entries := []streams.Entry{<-channel, <-channel, <-channel}
+	assert.EqualValues(t, []byte("4"), entries[2].Value)
+	assert.EqualValues(t, "connection reset", (<-errs).Error())
+	assert.EqualValues(t, &GapError{Expected: 3, Got: 4}, <-errs)
+	mutex.Lock()
+	assert.EqualValues(t, []string{"", "2"}, resumes)
+	mutex.Unlock()
+
+	assert.Nil(t, source.CommitEntry(entries[1].Key))
+	token, found, err := store.Get("feed/" + resumeKey)
+	assert.Nil(t, err)
+	assert.True(t, found)
+	assert.EqualValues(t, "2", token)
+
+	assert.Nil(t, source.Stop())
+	for range channel {
+	}
+	assert.IsType(t, &streams.EofError{}, <-errs)
+
+	// a new source resumes after the committed token
+	restarted, err := NewSource(dial, Config{Name: "feed", Store: store})
+	assert.Nil(t, err)
+	assert.EqualValues(t, "2", restarted.Resume())
+}
+
+func TestSource_ReconnectsWhenPongsStop(t *testing.T) {
+	dials := make(chan *fakeConn, 2)
+	dial := func(resume string) (Conn, error) {
+		conn := newFakeConn(false)
+		dials <- conn
+		return conn, nil
+	}
+	source, err := NewSource(dial, Config{PingInterval: 5 * time.Millisecond, PongTimeout: 5 * time.Millisecond, Backoff: time.Millisecond})
+	assert.Nil(t, err)
+
+	channel := make(streams.EntryChannel)
+	errs := make(streams.ErrorChannel, 10)
+	go source.Start(channel, errs)
+
+	<-dials
+	<-dials
+	assert.Nil(t, source.Stop())
+	for range channel {
+	}
+}
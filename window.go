@@ -0,0 +1,284 @@
+package go_streams
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// AggregateFunc adds a value to the accumulator of a window, the accumulator is nil for the first value
+type AggregateFunc func(acc interface{}, value interface{}) interface{}
+
+// LatePolicy decides what happens to the entries whose window was already emitted
+type LatePolicy string
+
+const (
+	// LateDrop drops the late entries
+	LateDrop LatePolicy = "drop"
+
+	// LateSideOutput writes the late entries to the late sink of the window
+	LateSideOutput LatePolicy = "sideOutput"
+
+	// LateUpdate adds the late entries to their window and emits the updated result,
+	// windows are kept for the allowed lateness after they were emitted.
+	LateUpdate LatePolicy = "update"
+)
+
+// MetadataLate is attached to the late entries written to the late sink
+const MetadataLate = "late"
+
+type WindowConfig struct {
+	// Size of the tumbling windows, windows are aligned to the event time (e.g: every minute)
+	Size time.Duration
+
+	// Key splits the windows per key (optional)
+	Key KeyFunc
+
+	// Aggregate accumulates the values of a window
+	Aggregate AggregateFunc
+
+	// MaxOutOfOrderness is how far behind the latest event time entries may arrive before they are late
+	MaxOutOfOrderness time.Duration
+
+	// Input returns the input of merged streams (e.g: a metadata attribute), each input has
+	// its own watermark and the windows progress at the pace of the slowest one (optional).
+	Input func(entry Entry) string
+
+	// IdleTimeout ignores the watermark of inputs that sent nothing for that long (0 never ignores them)
+	IdleTimeout time.Duration
+
+	// Late is the late data policy (defaults to LateDrop)
+	Late LatePolicy
+
+	// LateSink receives the late entries of the LateSideOutput policy
+	LateSink Sink
+
+	// AllowedLateness is how long emitted windows accept late entries with the LateUpdate policy
+	AllowedLateness time.Duration
+}
+
+// WindowResult is the value of the entries emitted by a window
+type WindowResult struct {
+	Key   string
+	Start time.Time
+	End   time.Time
+	Value interface{}
+
+	// Count is the number of entries in the window
+	Count int
+
+	// Update is set when the result updates a window that was emitted already (see LateUpdate)
+	Update bool
+}
+
+type window struct {
+	result  WindowResult
+	lastKey string
+	emitted bool
+}
+
+// EventTimeWindow aggregates the entries of tumbling event time windows, a window is emitted once
+// the watermark passed its end. Entries without a timestamp are stamped with their arrival time.
+// The emitted entries carry the key of the latest entry of their window, async commit strategies
+// shouldn't be used with it since the keys of the other entries are never completed.
+type EventTimeWindow struct {
+	config     WindowConfig
+	watermarks *Watermarks
+	mutex      *sync.Mutex
+	windows    map[string]*window
+}
+
+func NewEventTimeWindow(config WindowConfig) (*EventTimeWindow, error) {
+	if config.Size <= 0 {
+		return nil, fmt.Errorf("window size should be positive")
+	}
+	if config.Aggregate == nil {
+		return nil, fmt.Errorf("window requires an aggregate function")
+	}
+	switch config.Late {
+	case "":
+		config.Late = LateDrop
+	case LateDrop, LateUpdate:
+	case LateSideOutput:
+		if config.LateSink == nil {
+			return nil, fmt.Errorf("the side output late policy requires a late sink")
+		}
+	default:
+		return nil, fmt.Errorf("unknown late policy: '%s'", config.Late)
+	}
+	return &EventTimeWindow{
+		config:     config,
+		watermarks: NewWatermarks(config.MaxOutOfOrderness, config.IdleTimeout),
+		mutex:      &sync.Mutex{},
+		windows:    make(map[string]*window),
+	}, nil
+}
+
+// Watermark returns the current watermark of the window
+func (this *EventTimeWindow) Watermark() time.Time {
+	return this.watermarks.Current()
+}
+
+func (this *EventTimeWindow) Apply(entry Entry) ([]Entry, error) {
+	now := time.Now()
+	if entry.Timestamp.IsZero() {
+		entry.Timestamp = now
+	}
+	input := ""
+	if this.config.Input != nil {
+		input = this.config.Input(entry)
+	}
+	key := ""
+	if this.config.Key != nil {
+		key = this.config.Key(entry.Value)
+	}
+
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	watermark := this.watermarks.Current()
+	start := entry.Timestamp.Truncate(this.config.Size)
+	end := start.Add(this.config.Size)
+	id := fmt.Sprintf("%s@%d", key, start.UnixNano())
+
+	var emitted []Entry
+	if w, found := this.windows[id]; (found && w.emitted) || (!found && !end.After(watermark)) {
+		late, err := this.late(entry, w)
+		if err != nil {
+			return nil, err
+		}
+		emitted = append(emitted, late...)
+	} else {
+		if !found {
+			w = &window{result: WindowResult{Key: key, Start: start, End: end}}
+			this.windows[id] = w
+		}
+		w.add(this.config.Aggregate, entry)
+	}
+
+	// observed after the entry was added so it can't be late for the watermark it advanced
+	this.watermarks.Observe(input, entry.Timestamp, now)
+	return append(emitted, this.fire(this.watermarks.Current())...), nil
+}
+
+// late applies the late policy to an entry whose window was emitted (w is nil when it was evicted)
+func (this *EventTimeWindow) late(entry Entry, w *window) ([]Entry, error) {
+	switch this.config.Late {
+	case LateUpdate:
+		if w != nil {
+			w.add(this.config.Aggregate, entry)
+			w.result.Update = true
+			return []Entry{w.entry()}, nil
+		}
+	case LateSideOutput:
+		metadata := make(map[string]string, len(entry.Metadata)+1)
+		for k, v := range entry.Metadata {
+			metadata[k] = v
+		}
+		metadata[MetadataLate] = "true"
+		entry.Metadata = metadata
+		if _, err := recoverSinkSingle(this.config.LateSink, entry); err != nil {
+			return nil, err
+		}
+		return nil, nil
+	}
+	logger.Debug("Dropping late entry: %s", entry.Key)
+	return nil, nil
+}
+
+func (this *EventTimeWindow) Tick(now time.Time) ([]Entry, error) {
+	watermark := this.watermarks.Advance(now)
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	return this.fire(watermark), nil
+}
+
+// Drain emits the windows that are still open
+func (this *EventTimeWindow) Drain() ([]Entry, error) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	entries := this.fire(time.Unix(1<<62, 0))
+	this.windows = make(map[string]*window)
+	return entries, nil
+}
+
+// fire emits the windows that ended before the watermark and evicts the ones that can't be updated
+// anymore, the caller holds the mutex.
+func (this *EventTimeWindow) fire(watermark time.Time) []Entry {
+	var due []*window
+	for id, w := range this.windows {
+		if w.emitted {
+			if this.config.Late != LateUpdate || !w.result.End.Add(this.config.AllowedLateness).After(watermark) {
+				delete(this.windows, id)
+			}
+			continue
+		}
+		if !w.result.End.After(watermark) {
+			due = append(due, w)
+		}
+	}
+
+	sort.Slice(due, func(i, j int) bool {
+		if !due[i].result.End.Equal(due[j].result.End) {
+			return due[i].result.End.Before(due[j].result.End)
+		}
+		return due[i].result.Key < due[j].result.Key
+	})
+
+	entries := make([]Entry, len(due))
+	for idx, w := range due {
+		w.emitted = true
+		entries[idx] = w.entry()
+	}
+	return entries
+}
+
+func (this *window) add(aggregate AggregateFunc, entry Entry) {
+	this.result.Value = aggregate(this.result.Value, entry.Value)
+	this.result.Count++
+	this.lastKey = entry.Key
+}
+
+func (this *window) entry() Entry {
+	return Entry{Key: this.lastKey, Value: this.result, Timestamp: this.result.End}
+}
+
+type windowsState struct {
+	Windows    map[string]windowState
+	Watermarks watermarksState
+}
+
+type windowState struct {
+	Result  WindowResult
+	LastKey string
+	Emitted bool
+}
+
+// Snapshot encodes the open windows (and the emitted ones that can still be updated) with the watermarks,
+// the accumulators are encoded with encoding/gob (see Snapshotter).
+func (this *EventTimeWindow) Snapshot() ([]byte, error) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	state := windowsState{Windows: make(map[string]windowState, len(this.windows)), Watermarks: this.watermarks.snapshot()}
+	for id, w := range this.windows {
+		state.Windows[id] = windowState{Result: w.result, LastKey: w.lastKey, Emitted: w.emitted}
+	}
+	return encodeState(&state)
+}
+
+// Restore replaces the windows and the watermarks by a snapshot
+func (this *EventTimeWindow) Restore(data []byte) error {
+	var state windowsState
+	if err := decodeState(data, &state); err != nil {
+		return err
+	}
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.windows = make(map[string]*window, len(state.Windows))
+	for id, w := range state.Windows {
+		this.windows[id] = &window{result: w.Result, lastKey: w.LastKey, emitted: w.Emitted}
+	}
+	this.watermarks.restore(state.Watermarks)
+	return nil
+}
@@ -0,0 +1,121 @@
+package go_streams
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var windowEpoch = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func at(seconds int) time.Time {
+	return windowEpoch.Add(time.Duration(seconds) * time.Second)
+}
+
+func sum(acc interface{}, value interface{}) interface{} {
+	if acc == nil {
+		return value
+	}
+	return acc.(int) + value.(int)
+}
+
+func results(entries []Entry) []WindowResult {
+	var out []WindowResult
+	for _, entry := range entries {
+		out = append(out, entry.Value.(WindowResult))
+	}
+	return out
+}
+
+func TestWatermarks_SlowestInput(t *testing.T) {
+	now := time.Now()
+	watermarks := NewWatermarks(time.Second, time.Minute)
+	assert.EqualValues(t, at(9), watermarks.Observe("a", at(10), now))
+	assert.EqualValues(t, at(9), watermarks.Observe("b", at(5), now))
+	assert.EqualValues(t, at(9), watermarks.Observe("a", at(20), now))
+	assert.EqualValues(t, at(11), watermarks.Observe("b", at(12), now))
+
+	// b is idle, the watermark follows a
+	assert.EqualValues(t, at(19), watermarks.Advance(now.Add(2*time.Minute)))
+}
+
+func TestEventTimeWindow_EmitsOnWatermark(t *testing.T) {
+	op, err := NewEventTimeWindow(WindowConfig{Size: 10 * time.Second, Aggregate: sum, MaxOutOfOrderness: 2 * time.Second})
+	assert.Nil(t, err)
+
+	out, _ := op.Apply(Entry{Key: "1", Value: 1, Timestamp: at(1)})
+	assert.Empty(t, out)
+	out, _ = op.Apply(Entry{Key: "2", Value: 2, Timestamp: at(11)})
+	assert.Empty(t, out)
+
+	// out of order but within the bound
+	out, _ = op.Apply(Entry{Key: "3", Value: 3, Timestamp: at(9)})
+	assert.Empty(t, out)
+
+	out, _ = op.Apply(Entry{Key: "4", Value: 4, Timestamp: at(12)})
+	assert.EqualValues(t, []WindowResult{{Start: at(0), End: at(10), Value: 4, Count: 2}}, results(out))
+	assert.EqualValues(t, "3", out[0].Key)
+	assert.EqualValues(t, at(10), op.Watermark())
+
+	out, _ = op.Drain()
+	assert.EqualValues(t, []WindowResult{{Start: at(10), End: at(20), Value: 6, Count: 2}}, results(out))
+}
+
+func TestEventTimeWindow_LatePolicies(t *testing.T) {
+	var late []Entry
+	sideOutput, err := NewEventTimeWindow(WindowConfig{Size: 10 * time.Second, Aggregate: sum, Late: LateSideOutput,
+		LateSink: NewCallbackSink(func(entries ...Entry) error {
+			late = append(late, entries...)
+			return nil
+		})})
+	assert.Nil(t, err)
+	dropping, _ := NewEventTimeWindow(WindowConfig{Size: 10 * time.Second, Aggregate: sum})
+	updating, _ := NewEventTimeWindow(WindowConfig{Size: 10 * time.Second, Aggregate: sum, Late: LateUpdate, AllowedLateness: time.Minute})
+
+	for _, op := range []*EventTimeWindow{sideOutput, dropping, updating} {
+		_, _ = op.Apply(Entry{Key: "1", Value: 1, Timestamp: at(1)})
+		out, _ := op.Apply(Entry{Key: "2", Value: 2, Timestamp: at(15)})
+		assert.Len(t, out, 1)
+	}
+
+	out, _ := sideOutput.Apply(Entry{Key: "3", Value: 3, Timestamp: at(5)})
+	assert.Empty(t, out)
+	assert.Len(t, late, 1)
+	assert.EqualValues(t, "true", late[0].Metadata[MetadataLate])
+
+	out, _ = dropping.Apply(Entry{Key: "3", Value: 3, Timestamp: at(5)})
+	assert.Empty(t, out)
+
+	out, _ = updating.Apply(Entry{Key: "3", Value: 3, Timestamp: at(5)})
+	assert.EqualValues(t, []WindowResult{{Start: at(0), End: at(10), Value: 4, Count: 2, Update: true}}, results(out))
+}
+
+func TestEventTimeWindow_PerKeyInStream(t *testing.T) {
+	op, err := NewEventTimeWindow(WindowConfig{Size: time.Hour, Aggregate: sum, Key: func(value interface{}) string {
+		if value.(int)%2 == 0 {
+			return "even"
+		}
+		return "odd"
+	}})
+	assert.Nil(t, err)
+
+	sink := NewArraySink()
+	NewStream(NewSequentialIntegerSource(4, 0)).Via(op).Sink(sink).Process(NewDirectProcessor(), make(ErrorChannel, 10))
+
+	// every entry was stamped with its arrival time, the windows are emitted when the stream ends
+	var total int
+	for _, value := range sink.Array() {
+		total += value.(WindowResult).Value.(int)
+	}
+	assert.EqualValues(t, 10, total)
+}
+
+func TestNewEventTimeWindow_Errors(t *testing.T) {
+	_, err := NewEventTimeWindow(WindowConfig{Aggregate: sum})
+	assert.NotNil(t, err)
+	_, err = NewEventTimeWindow(WindowConfig{Size: time.Second})
+	assert.NotNil(t, err)
+	_, err = NewEventTimeWindow(WindowConfig{Size: time.Second, Aggregate: sum, Late: LateSideOutput})
+	assert.NotNil(t, err)
+}